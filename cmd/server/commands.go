@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// Actions of the migrate command
+const (
+	migrateUp     = "up"
+	migrateDown   = "down"
+	migrateStatus = "status"
+)
+
+// cliActor is the audit actor of changes made from the command line
+const cliActor = "cli"
+
+// serveOptions selects the one-off command serve runs instead of serving;
+// the zero value serves
+type serveOptions struct {
+	issueToken       string
+	issueTokenTenant string
+	issueTokenRole   string
+	issueAPIKey      string
+	// migrate is one of migrateUp, migrateDown or migrateStatus
+	migrate      string
+	migrateSteps int
+	// migrateTenant moves the tenant to migrationTarget
+	migrateTenant   string
+	migrationTarget string
+	// task runs once the tenant service is set up
+	task func(ctx context.Context, tenants *service.TenantService, auditLogger *audit.Logger) error
+	// archive runs once the message archive is set up, before the tenant service
+	archive func(ctx context.Context, archives *service.ArchiveService, auditLogger *audit.Logger) error
+}
+
+// newRootCommand builds the salva command line. Without a subcommand it
+// serves, like salva serve.
+func newRootCommand() *cobra.Command {
+	serveCmd := newServeCommand()
+	root := &cobra.Command{
+		Use:          "salva",
+		Short:        "Multi-tenant messaging server",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         serveCmd.RunE,
+	}
+	root.Flags().AddFlagSet(serveCmd.Flags())
+	root.AddCommand(serveCmd, newMigrateCommand(), newTenantCommand(), newPublishCommand(), newArchiveCommand())
+	root.SetArgs(legacyArgs(os.Args[1:], root))
+	return root
+}
+
+// newServeCommand builds salva serve. Its flags are the one-off commands
+// that predate the subcommands and keep working on salva and salva serve.
+func newServeCommand() *cobra.Command {
+	var opts serveOptions
+	var migrate bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the API server and the tenant consumers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if migrate {
+				opts.migrate = migrateUp
+			}
+			serve(opts)
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&opts.issueToken, "issue-token", "", "Issue an access/refresh token pair for the given subject and exit")
+	flags.StringVar(&opts.issueTokenTenant, "issue-token-tenant", "", "Bind the token issued by --issue-token to this tenant, making it a tenant-admin token")
+	flags.StringVar(&opts.issueTokenRole, "issue-token-role", "", "Role of the token issued by --issue-token: admin, tenant-operator or reader (default admin, or tenant-operator with --issue-token-tenant)")
+	flags.StringVar(&opts.issueAPIKey, "issue-api-key", "", "Create an API key for the given tenant and exit")
+	flags.BoolVar(&migrate, "migrate", false, "Apply pending database migrations and exit, like salva migrate up")
+	flags.StringVar(&opts.migrateTenant, "migrate-tenant", "", "Move the given tenant to --migration-target, resuming an unfinished move, and exit")
+	flags.StringVar(&opts.migrationTarget, "migration-target", "", "Name of the deployment in tenant_migration.targets to move a tenant to")
+	return cmd
+}
+
+// legacyArgs rewrites the single-dash long flags of the earlier flag-based
+// command line, e.g. -migrate, to their double-dash form
+func legacyArgs(args []string, root *cobra.Command) []string {
+	rewritten := make([]string, len(args))
+	for i, arg := range args {
+		rewritten[i] = arg
+		if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
+			continue
+		}
+		name, _, _ := strings.Cut(arg[1:], "=")
+		if len(name) > 1 && root.Flags().Lookup(name) != nil {
+			rewritten[i] = "-" + arg
+		}
+	}
+	return rewritten
+}
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, revert or show the database migrations",
+		Long:  "Apply, revert or show the database migrations. Without a subcommand it applies the pending ones, like migrate up.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{migrate: migrateUp})
+			return nil
+		},
+	}
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{migrate: migrateUp})
+			return nil
+		},
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the last applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if steps < 1 {
+				return errors.New("--steps must be at least 1")
+			}
+			serve(serveOptions{migrate: migrateDown, migrateSteps: steps})
+			return nil
+		},
+	}
+	down.Flags().IntVar(&steps, "steps", 1, "Number of migrations to revert")
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Print the applied and pending migrations as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{migrate: migrateStatus})
+			return nil
+		},
+	}
+
+	cmd.AddCommand(up, down, status)
+	return cmd
+}
+
+func newTenantCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Create, delete and list tenants",
+	}
+
+	var name string
+	var timeout time.Duration
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a tenant and wait until it is provisioned",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{task: func(ctx context.Context, tenants *service.TenantService, auditLogger *audit.Logger) error {
+				return createTenant(ctx, tenants, auditLogger, name, timeout)
+			}})
+			return nil
+		},
+	}
+	create.Flags().StringVar(&name, "name", "", "Name of the tenant")
+	create.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "How long to wait for the tenant to be provisioned")
+	create.MarkFlagRequired("name")
+
+	remove := &cobra.Command{
+		Use:   "delete <tenant-id>",
+		Short: "Delete a tenant with its queues and stored messages",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenantID := args[0]
+			if _, err := uuid.Parse(tenantID); err != nil {
+				return fmt.Errorf("invalid tenant ID %q", tenantID)
+			}
+			serve(serveOptions{task: func(ctx context.Context, tenants *service.TenantService, auditLogger *audit.Logger) error {
+				before := tenants.AuditValues(tenantID)
+				if err := tenants.DeleteTenant(tenantID); err != nil {
+					return err
+				}
+				auditLogger.RecordChange(cliActor, audit.ActionTenantDelete, tenantID, nil, before, nil)
+				return nil
+			}})
+			return nil
+		},
+	}
+
+	var filter string
+	var asJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the tenants",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{task: func(ctx context.Context, tenants *service.TenantService, auditLogger *audit.Logger) error {
+				return listTenants(ctx, tenants, filter, asJSON, os.Stdout)
+			}})
+			return nil
+		},
+	}
+	list.Flags().StringVar(&filter, "name", "", "Only list tenants whose name contains this, ignoring case")
+	list.Flags().BoolVar(&asJSON, "json", false, "Print one JSON object per tenant instead of a table")
+
+	cmd.AddCommand(create, remove, list)
+	return cmd
+}
+
+// createTenant creates the tenant and waits for its provisioning job. The
+// consumer this process starts ends with it; running servers pick the
+// tenant up through handover, or when they restart.
+func createTenant(ctx context.Context, tenants *service.TenantService, auditLogger *audit.Logger, name string, timeout time.Duration) error {
+	tenant := domain.Tenant{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	job, err := tenants.CreateTenant(&tenant)
+	if err != nil {
+		return err
+	}
+	auditLogger.RecordChange(cliActor, audit.ActionTenantCreate, tenant.ID, map[string]interface{}{
+		"name":   tenant.Name,
+		"job_id": job.ID,
+	}, nil, map[string]interface{}{"name": tenant.Name})
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for job.Status == domain.ProvisioningStatusPending {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("tenant %s is still being provisioned, see job %s", tenant.ID, job.ID)
+		case <-ticker.C:
+		}
+		if job, err = tenants.GetProvisioningJob(job.ID); err != nil {
+			return err
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(job)
+	if job.Status == domain.ProvisioningStatusFailed {
+		return fmt.Errorf("tenant provisioning failed: %s", job.Error)
+	}
+	return nil
+}
+
+// listTenants prints every tenant, following the list cursor page by page
+func listTenants(ctx context.Context, tenants *service.TenantService, name string, asJSON bool, out io.Writer) error {
+	table := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	encoder := json.NewEncoder(out)
+	if !asJSON {
+		fmt.Fprintln(table, "ID\tNAME\tQUEUE\tWORKERS\tDEPTH\tSTATUS")
+	}
+
+	cursor := ""
+	for {
+		page, next, err := tenants.ListTenants(ctx, name, cursor, "", 100)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range page {
+			if asJSON {
+				encoder.Encode(tenant)
+				continue
+			}
+			depth := "-"
+			if tenant.QueueDepth != nil {
+				depth = fmt.Sprint(*tenant.QueueDepth)
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\t%s\n", tenant.ID, tenant.Name, tenant.QueueName, tenant.Workers, depth, tenant.Status)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	return table.Flush()
+}
+
+func newPublishCommand() *cobra.Command {
+	var tenantID, file string
+	var req domain.PublishRequest
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Publish the JSON payload in a file to a tenant",
+		Long:  "Publish the JSON payload in a file to a tenant, like POST /tenants/{id}/messages. A file of - reads the payload from stdin.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var payload []byte
+			var err error
+			if file == "-" {
+				payload, err = io.ReadAll(os.Stdin)
+			} else {
+				payload, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return err
+			}
+			if !json.Valid(payload) {
+				return fmt.Errorf("%s does not hold a JSON payload", file)
+			}
+			req.Payload = payload
+
+			serve(serveOptions{task: func(ctx context.Context, tenants *service.TenantService, auditLogger *audit.Logger) error {
+				result, err := tenants.PublishMessage(ctx, tenantID, req)
+				if err != nil {
+					return err
+				}
+				return json.NewEncoder(os.Stdout).Encode(result)
+			}})
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&tenantID, "tenant", "", "ID of the tenant to publish to")
+	cmd.Flags().StringVar(&file, "file", "", "File holding the JSON payload, or - for stdin")
+	cmd.Flags().StringVar(&req.MessageType, "type", "", "Message type, which picks the payload schema")
+	cmd.Flags().StringVar(&req.Channel, "channel", "", "Publish to this channel of the tenant instead of its main queue")
+	cmd.Flags().IntVar(&req.DelaySeconds, "delay", 0, "Seconds to hold the message back before it is delivered")
+	cmd.Flags().IntVar(&req.Priority, "priority", 0, "Message priority from 0 to 9")
+	cmd.MarkFlagRequired("tenant")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func newArchiveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Archive old messages to object storage, list and restore archives",
+	}
+
+	var tenantID string
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Archive the messages older than archive.older_than now",
+		Long:  "Archive the messages older than archive.older_than now, of one tenant with --tenant or else of all tenants, and print the archives created.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{archive: func(ctx context.Context, archives *service.ArchiveService, auditLogger *audit.Logger) error {
+				if tenantID == "" {
+					total, err := archives.ArchiveAll(ctx)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Archived %d messages\n", total)
+					return nil
+				}
+				created, err := archives.ArchiveTenant(ctx, tenantID)
+				encoder := json.NewEncoder(os.Stdout)
+				for _, archive := range created {
+					encoder.Encode(archive)
+				}
+				return err
+			}})
+			return nil
+		},
+	}
+	run.Flags().StringVar(&tenantID, "tenant", "", "Only archive this tenant's messages")
+
+	var listTenant string
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List a tenant's archives, one JSON object per line",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(serveOptions{archive: func(ctx context.Context, archives *service.ArchiveService, auditLogger *audit.Logger) error {
+				found, err := archives.ListArchives(ctx, listTenant)
+				if err != nil {
+					return err
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				for _, archive := range found {
+					encoder.Encode(archive)
+				}
+				return nil
+			}})
+			return nil
+		},
+	}
+	list.Flags().StringVar(&listTenant, "tenant", "", "ID of the tenant")
+	list.MarkFlagRequired("tenant")
+
+	var restoreTenant string
+	var hold time.Duration
+	restore := &cobra.Command{
+		Use:   "restore <archive-id>",
+		Short: "Load an archive back into the tenant's partition",
+		Long:  "Load an archive back into the tenant's partition, skipping messages still stored. The archive's day is not archived again for --hold.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archiveID := args[0]
+			serve(serveOptions{archive: func(ctx context.Context, archives *service.ArchiveService, auditLogger *audit.Logger) error {
+				archive, err := archives.Restore(ctx, restoreTenant, archiveID, hold)
+				if err != nil {
+					return err
+				}
+				auditLogger.Record(cliActor, audit.ActionArchiveRestore, restoreTenant, map[string]interface{}{
+					"archive_id": archive.ID,
+					"day":        archive.Day,
+					"restored":   archive.RestoredCount,
+				})
+				return json.NewEncoder(os.Stdout).Encode(archive)
+			}})
+			return nil
+		},
+	}
+	restore.Flags().StringVar(&restoreTenant, "tenant", "", "ID of the tenant the archive belongs to")
+	restore.Flags().DurationVar(&hold, "hold", 24*time.Hour, "How long the restored messages are not archived again")
+	restore.MarkFlagRequired("tenant")
+
+	cmd.AddCommand(run, list, restore)
+	return cmd
+}
@@ -24,9 +24,37 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/messages": {
+        "/admin/access-log": {
             "get": {
-                "description": "Get a list of messages with cursor-based pagination",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the default sample rate and the per-route overrides of this instance's access log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the access log sampling",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.AccessLogSettings"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the default sample rate and the per-route overrides, keyed \"METHOD /route/:template\" (e.g. \"GET /tenants/:id/events\"). Rates go from 0 (off) to 1 (every request). Applies to this instance until it restarts.",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,23 +62,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "List messages with cursor pagination",
+                "summary": "Change the access log sampling",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Cursor for pagination",
-                        "name": "cursor",
-                        "in": "query"
+                        "description": "Sampling settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/middleware.AccessLogSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.AccessLogSettings"
+                        }
                     },
-                    {
-                        "type": "integer",
-                        "description": "Limit of messages per page (default 10)",
-                        "name": "limit",
-                        "in": "query"
+                    "400": {
+                        "description": "Invalid request body or rate",
+                        "schema": {
+                            "type": "object"
+                        }
                     }
+                }
+            }
+        },
+        "/admin/consumers": {
+            "get": {
+                "description": "List which instance consumes each tenant and any handover in progress, to follow a blue/green deploy",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
                 ],
+                "summary": "List tenant consumer ownership",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -60,17 +111,51 @@ const docTemplate = `{
                                 "data": {
                                     "type": "array",
                                     "items": {
-                                        "$ref": "#/definitions/domain.Message"
+                                        "$ref": "#/definitions/domain.ConsumerOwner"
                                     }
                                 },
-                                "next_cursor": {
+                                "instance_id": {
                                     "type": "string"
                                 }
                             }
                         }
                     },
-                    "400": {
-                        "description": "Invalid cursor or limit",
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/credentials/rotate": {
+            "post": {
+                "description": "Read database.url, the RabbitMQ URLs and security.jwt_secret again (including DATABASE_URL_FILE, RABBITMQ_URL_FILE and JWT_SECRET_FILE) and switch to those that changed. New connections use the new credentials; pooled database connections are closed once idle, tenant consumers are drained and restarted on new broker connections, and tokens signed with the old JWT secret stay valid until they expire. Same as sending SIGHUP.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Rotate credentials without a restart",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rotated": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Rotation already in progress",
                         "schema": {
                             "type": "object"
                         }
@@ -84,9 +169,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenants": {
+        "/admin/migrations": {
+            "get": {
+                "description": "List applied and pending schema migrations and whether the last one failed halfway (dirty). New consumers are not started while migrations are pending or the schema is dirty.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get schema migration status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.MigrationStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/partitions": {
+            "get": {
+                "description": "List tenant partitions of the messages table with row counts and sizes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List messages partitions",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Count rows exactly instead of using planner estimates",
+                        "name": "exact",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.Partition"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Create a new tenant with a unique ID and start a consumer for the tenant",
+                "description": "Create the messages partition for a tenant ahead of time",
                 "consumes": [
                     "application/json"
                 ],
@@ -94,19 +245,19 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
+                    "admin"
                 ],
-                "summary": "Create a new tenant",
+                "summary": "Pre-create a tenant partition",
                 "parameters": [
                     {
-                        "description": "Tenant creation request",
+                        "description": "Partition request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "name": {
+                                "tenant_id": {
                                     "type": "string"
                                 }
                             }
@@ -115,10 +266,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/domain.Tenant"
-                        }
+                        "description": "Created"
                     },
                     "400": {
                         "description": "Invalid request body",
@@ -135,9 +283,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenants/{id}": {
-            "delete": {
-                "description": "Delete a tenant by ID and stop its consumer",
+        "/admin/partitions/detach": {
+            "post": {
+                "description": "Detach a tenant partition from the messages table, keeping the data in a standalone table",
                 "consumes": [
                     "application/json"
                 ],
@@ -145,21 +293,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
+                    "admin"
                 ],
-                "summary": "Delete a tenant",
+                "summary": "Detach a tenant partition",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Tenant ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Partition request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "tenant_id": {
+                                    "type": "string"
+                                }
+                            }
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Partition not found",
+                        "schema": {
+                            "type": "object"
+                        }
                     },
                     "500": {
                         "description": "Internal server error",
@@ -170,50 +337,57 @@ const docTemplate = `{
                 }
             }
         },
-        "/tenants/{id}/config/concurrency": {
-            "put": {
-                "description": "Update the number of workers for a tenant's consumer",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/processors": {
+            "get": {
+                "description": "List the processors registered in-process or configured as gRPC sidecars, which tenants can select",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tenants"
+                    "admin"
                 ],
-                "summary": "Update the concurrency for a tenant",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Tenant ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Concurrency configuration",
-                        "name": "config",
-                        "in": "body",
-                        "required": true,
+                "summary": "List available processors",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "workers": {
-                                    "type": "integer"
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
                                 }
                             }
                         }
                     }
+                }
+            }
+        },
+        "/admin/queues/rename": {
+            "post": {
+                "description": "After rabbitmq.queue_name_template changes, move every tenant to its new queue name: declare the new queue, switch the consumer, shovel the remaining messages and delete the old queue once empty. Only tenants whose name changed are listed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
                 ],
+                "summary": "Move tenants to the current queue name template",
                 "responses": {
                     "200": {
-                        "description": "OK"
-                    },
-                    "400": {
-                        "description": "Invalid request body",
+                        "description": "OK",
                         "schema": {
-                            "type": "object"
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.QueueRename"
+                                    }
+                                }
+                            }
                         }
                     },
                     "500": {
@@ -221,46 +395,7593 @@ const docTemplate = `{
                         "schema": {
                             "type": "object"
                         }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.JSONB": {
-            "type": "object",
-            "additionalProperties": {}
         },
-        "domain.Message": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "payload": {
-                    "$ref": "#/definitions/domain.JSONB"
-                },
-                "tenant_id": {
+        "/admin/tenant-migrations": {
+            "post": {
+                "description": "Start moving a tenant's config, messages and queued messages to a deployment listed in tenant_migration.targets, then verify the message counts. An unfinished migration of the same tenant to the same target is resumed instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Move a tenant to another deployment",
+                "parameters": [
+                    {
+                        "description": "Migration request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "target": {
+                                    "type": "string"
+                                },
+                                "tenant_id": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantMigration"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or unknown target",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenant-migrations/{id}": {
+            "get": {
+                "description": "Get the current step, progress and verification counts of a tenant migration",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get tenant migration status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Migration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantMigration"
+                        }
+                    },
+                    "404": {
+                        "description": "Migration not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenant-migrations/{id}/resume": {
+            "post": {
+                "description": "Resume a failed or interrupted tenant migration at the step it stopped in",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resume a tenant migration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Migration ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantMigration"
+                        }
+                    },
+                    "404": {
+                        "description": "Migration not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}/deliveries": {
+            "get": {
+                "description": "List the tenant's deliveries that have not been acked yet, with their age and worker",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List unacked deliveries of a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only deliveries older than this duration (e.g. 30s)",
+                        "name": "older_than",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.DeliveryInfo"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid duration",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}/deliveries/stuck": {
+            "post": {
+                "description": "Nack the tenant's deliveries stuck beyond a threshold, either requeueing them or discarding them to the tenant DLQ. Deliveries a worker has started are left to the worker and counted as skipped, unless force is set and the worker started them longer than older_than ago; the hung worker's own ack or nack is then ignored, so a requeued message may be processed twice.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Requeue or discard stuck deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "action is requeue or discard; older_than defaults to the configured threshold",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "action": {
+                                    "type": "string"
+                                },
+                                "delivery_tags": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "integer"
+                                    }
+                                },
+                                "force": {
+                                    "type": "boolean"
+                                },
+                                "older_than": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "settled": {
+                                    "type": "integer"
+                                },
+                                "skipped": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}/quota": {
+            "get": {
+                "description": "Get the most messages and bytes the tenant may keep stored and the deepest its main queue may get before publishing is rejected. Without a quota of its own (default) the quotas config applies. 0 leaves a limit off.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a tenant's quota",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantQuota"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set the tenant's own quota, replacing the quotas config for it. 0 leaves a limit off. Other instances apply it within 30 seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set a tenant's quota",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quota",
+                        "name": "quota",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantQuota"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantQuota"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or quota",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Drop the tenant's own quota so the quotas config applies to it again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset a tenant's quota",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantQuota"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/tenants/{id}/worker-bounds": {
+            "get": {
+                "description": "Get the fewest and most workers the tenant can set with PUT /tenants/{id}/config/concurrency. Without bounds of its own (default) a tenant can set 1 to concurrency.max_tenant_workers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a tenant's worker bounds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WorkerBounds"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set the fewest and most workers the tenant can set. max_workers may exceed concurrency.max_tenant_workers but not concurrency.max_workers. Workers already outside the bounds stay until the tenant next sets them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set a tenant's worker bounds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Worker bounds",
+                        "name": "bounds",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.WorkerBounds"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WorkerBounds"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or bounds",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Drop the tenant's own worker bounds so it can again set 1 to concurrency.max_tenant_workers workers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset a tenant's worker bounds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WorkerBounds"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit": {
+            "get": {
+                "description": "List audited administrative operations, newest first, with who made them, when, and the values they changed (old and new). Filters combine; pass next_cursor as cursor for the next page. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only entries by this actor (token subject, cli or anonymous)",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries of this action, e.g. tenant.delete",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries about this tenant",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries at or after this time (RFC 3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries before this time (RFC 3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last entry of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Entries per page (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/audit.Entry"
+                                    }
+                                },
+                                "next_cursor": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, cursor or limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access and refresh token. The used refresh token is revoked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh an access token",
+                "parameters": [
+                    {
+                        "description": "Refresh request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "refresh_token": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or revoked refresh token",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a token's ID to the revocation list so it is rejected before it expires",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Revoke a token",
+                "parameters": [
+                    {
+                        "description": "Token to revoke",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "token": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid token",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports": {
+            "post": {
+                "description": "Start an asynchronous export of messages, optionally limited to one tenant. The parquet format writes one file per tenant and day under tenant_id=.../date=... directories for Athena/BigQuery.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "exports"
+                ],
+                "summary": "Start a message export",
+                "parameters": [
+                    {
+                        "description": "Export request; format is ndjson (default) or parquet",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "format": {
+                                    "type": "string"
+                                },
+                                "tenant_id": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ExportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Source IP not allowed for the tenant",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports/{id}": {
+            "get": {
+                "description": "Get the status and progress of an export job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "exports"
+                ],
+                "summary": "Get export status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ExportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export ID",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Source IP not allowed for the export's tenant",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Export not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports/{id}/download": {
+            "get": {
+                "description": "Download a completed export as newline-delimited JSON, or as a zip of the partitioned files for Parquet exports",
+                "produces": [
+                    "application/x-ndjson",
+                    "application/zip"
+                ],
+                "tags": [
+                    "exports"
+                ],
+                "summary": "Download an export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export ID",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Source IP not allowed for the export's tenant",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Export not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "Export not completed",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/exports/{id}/resume": {
+            "post": {
+                "description": "Resume a failed or interrupted export from its last checkpoint",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "exports"
+                ],
+                "summary": "Resume an export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ExportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export ID",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Source IP not allowed for the export's tenant",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Export not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Run the liveness and readiness checks together. The status is ok, not_ready while the instance is alive but should not get traffic, or failed when it should be restarted. Answers 503 only when liveness fails, so it can serve as a liveness probe; use /readyz for readiness.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness and readiness at a glance",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "live": {
+                                    "$ref": "#/definitions/health.Result"
+                                },
+                                "ready": {
+                                    "$ref": "#/definitions/health.Result"
+                                },
+                                "status": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "live": {
+                                    "$ref": "#/definitions/health.Result"
+                                },
+                                "ready": {
+                                    "$ref": "#/definitions/health.Result"
+                                },
+                                "status": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz/details": {
+            "get": {
+                "description": "Report each component (database pool, every broker connection, tenant consumers, singleton scheduler, exports) as ok, degraded or down, with its latency, current error and the last error seen. Degraded components work with reduced capacity; the response is 503 only when a component is down.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Detailed component health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez/{check}": {
+            "get": {
+                "description": "Run the liveness checks, or only the one named by the optional check path segment. Fails only when the process should be restarted: a tenant consumer stopped taking messages longer than kubernetes.consumer_stall_timeout ago while its broker channel stayed open. A dropped broker or database connection does not fail it, as both are reconnected.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check name",
+                        "name": "check",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Result"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown check",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Result"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages": {
+            "get": {
+                "description": "Get a list of messages with cursor-based pagination",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "List messages with cursor pagination",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Opaque next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages of this tenant; a token bound to a tenant can only ask for its own",
+                        "name": "tenant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages created after this RFC 3339 time",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages created before this RFC 3339 time",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages whose payload contains this JSON document, e.g. {\\",
+                        "name": "payload",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit of messages per page (default 10, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated fields to return (id,tenant_id,payload,status,message_type,schema_version,tags,channel,content_type,raw_payload,deliver_at,created_at)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Omit the payload and raw_payload fields from every message",
+                        "name": "exclude_payload",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched page",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Tail mode: wait for messages newer than the cursor (oldest first) instead of paging back; without a cursor, starts after the newest message",
+                        "name": "follow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "How long a follow request waits for new messages, e.g. 30s (default 30s, max 60s)",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.Message"
+                                    }
+                                },
+                                "next_cursor": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page unchanged since the given ETag"
+                    },
+                    "400": {
+                        "description": "Invalid cursor, limit, fields or filter",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token is bound to another tenant, or source IP not allowed for the tenant",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Server busy, retry after the Retry-After delay",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{id}": {
+            "get": {
+                "description": "Get a stored message by ID. A token bound to a tenant only finds the messages of its own tenant, and the message is only returned to sources in its tenant's IP allowlist.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid message ID",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Source IP not allowed for the message's tenant",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Message not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Server busy, retry after the Retry-After delay",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz/{check}": {
+            "get": {
+                "description": "Run the readiness checks, or only the one named by the optional check path segment: database (pings the pool, fails during a failover), rabbitmq or nats (connection open), consumers (fails when every tenant consumer here stopped taking messages), migrations, runtime_config and shutdown",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Check name",
+                        "name": "check",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Result"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown check",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Result"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants": {
+            "get": {
+                "description": "List tenants ordered by name with their worker count, the depth of their main queue and whether any instance consumes it (active) or not (idle); status is unknown when the broker cannot be reached. A token bound to a tenant only sees that tenant.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List tenants with cursor pagination",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only tenants whose name contains this text (case-insensitive)",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant ID of the last tenant of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tenants per page (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.TenantSummary"
+                                    }
+                                },
+                                "next_cursor": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid cursor or limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register a new tenant with a unique ID. Its partition, queues and consumer are created in the background; poll GET /tenants/provisioning/{id} with the returned job ID until the status is ready or failed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Create a new tenant",
+                "parameters": [
+                    {
+                        "description": "Tenant creation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "name": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ProvisioningJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Database schema is behind or dirty",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/provisioning/{id}": {
+            "get": {
+                "description": "Get a tenant provisioning job: pending while the partition, queues and consumer are created, then ready, or failed with the error",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get tenant provisioning status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provisioning job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ProvisioningJob"
+                        }
+                    },
+                    "404": {
+                        "description": "Provisioning job not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}": {
+            "delete": {
+                "description": "Delete a tenant by ID and stop its consumer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "get": {
+                "description": "Get a tenant as listed by GET /tenants, with its quota and what it uses of it: the rows and bytes of its messages partition and the depth of its main queue (null when the broker cannot be asked).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tenant ID",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/archives": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the objects the tenant's old messages were archived to, one per UTC day, newest day first, with when each was last restored.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a tenant's message archives",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.MessageArchive"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/archives/{archive_id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Load the archived messages back into the tenant's partition, skipping messages still stored. The archive's day is not archived again for hold_seconds (default a day).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Restore a message archive",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Archive ID",
+                        "name": "archive_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Restore options",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ArchiveRestoreRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.MessageArchive"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Archive or partition not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/channels": {
+            "get": {
+                "description": "List the tenant's named channels, each consumed from its own queue with its own workers",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a tenant's channels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.Channel"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a named channel with its own queue (tenant_{id}_channel_{name}) and worker pool. Its messages go through the tenant's processing and are stored with the channel name. Workers default to 3, or 1 for ordered channels.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Create a tenant channel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Channel",
+                        "name": "channel",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "name": {
+                                    "type": "string"
+                                },
+                                "ordered": {
+                                    "type": "boolean"
+                                },
+                                "partition_key": {
+                                    "type": "string"
+                                },
+                                "workers": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Channel"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or channel",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "Channel already exists",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/channels/{name}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant channel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Channel name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Channel"
+                        }
+                    },
+                    "404": {
+                        "description": "Channel not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Change the channel's workers, ordered mode and partition key. The tenant's consumer is restarted to apply them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant channel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Channel name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Channel configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "ordered": {
+                                    "type": "boolean"
+                                },
+                                "partition_key": {
+                                    "type": "string"
+                                },
+                                "workers": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Channel"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or configuration",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Channel not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop consuming the channel and delete its queue, including messages still in it. Stored messages keep their channel name.",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a tenant channel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Channel name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Channel not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/concurrency": {
+            "put": {
+                "description": "Update the number of workers and the prefetch count (unacked deliveries held by the consumer) of a tenant's consumer. A prefetch_count of 0 or none uses 4 per worker; ordered tenants always use 1. Workers must be within the tenant's worker bounds and what other tenants leave of the instance's concurrency.max_workers. Workers are added or retired without stopping the consumer.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update the concurrency for a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Concurrency configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "prefetch_count": {
+                                    "type": "integer"
+                                },
+                                "workers": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Invalid request body, workers or prefetch count",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "Tenant is in ordered mode",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/dedup": {
+            "get": {
+                "description": "Get how long dedup keys are remembered to drop duplicates (0 means disabled) and whether the key is the AMQP message-id or a hash of the payload.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's dedup config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DedupConfig"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Messages whose dedup key was already seen within the window are acked and dropped. The key is the AMQP message-id (message_id, the default; messages without one are never dropped) or a SHA-256 of the payload (payload_hash). 0 disables deduplication.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's dedup config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Dedup configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.DedupConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DedupConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or key",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/dlq-retry": {
+            "get": {
+                "description": "Get whether dead-lettered messages are retried automatically and the delay before each retry",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's DLQ retry policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DLQRetryPolicy"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Override the config default. The n-th retry moves a message from the DLQ back to the tenant queue schedule_seconds[n-1] after it entered the DLQ; after the last delay the message stays in the DLQ.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's DLQ retry policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "DLQ retry policy",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "enabled": {
+                                    "type": "boolean"
+                                },
+                                "schedule_seconds": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "integer"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DLQRetryPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/ordering": {
+            "put": {
+                "description": "Enable or disable ordered mode. Ordered tenants are processed by a single worker with prefetch 1, so messages are handled in queue order even after retries.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Switch strictly-ordered processing mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ordering configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "ordered": {
+                                    "type": "boolean"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/partition-key": {
+            "put": {
+                "description": "Hash the given payload field (dotted path) to one of N lanes, N being the worker count. Messages with the same key are processed in order, different keys in parallel. An empty field disables keyed lanes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Set the partition key for keyed processing lanes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partition key configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "field": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/priority": {
+            "get": {
+                "description": "Get the highest AMQP priority the tenant's main queue orders messages by, 0 for a plain queue, and the queue the tenant is consumed from.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's max priority",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PriorityConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Make the tenant's main queue a RabbitMQ priority queue ordering messages by priorities up to max_priority (1-9), or a plain queue with 0. Queue arguments cannot change, so the tenant moves to a queue named after its max priority, e.g. tenant_{id}_queue_p9: the consumer switches to it and the remaining messages are shoveled over, as with a queue rename. Sending the same value again completes an interrupted move.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Set a tenant's max priority",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Priority configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "max_priority": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.QueueRename"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or max priority",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/processors": {
+            "get": {
+                "description": "Get the processors run on each of the tenant's messages, in order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's processors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "processors": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/processor.Stage"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the chain of processors run on each of the tenant's messages after schema validation and before storage. Each stage is a processor name, or {\"name\": ..., \"config\": {...}} for a processor that takes settings, such as the built-in strip_fields, ingest_metadata and flatten. An empty list disables processing.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's processors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Processor stages, in run order",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "processors": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/processor.Stage"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "processors": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/processor.Stage"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, unknown processor or invalid processor config",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/rate-limit": {
+            "get": {
+                "description": "Get the token-bucket limit on the tenant's published messages (0 messages per second means unlimited) and whether its consumers are paced at the same rate.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's rate limit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RateLimit"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Publishing more than burst messages at once, or faster than messages_per_second, is rejected with 429 and a Retry-After header. burst defaults to one second worth of messages and 0 messages per second disables the limit. With consume the tenant's consumers take messages no faster than the rate either; the rest wait in the queue. Limits are enforced per instance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's rate limit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rate limit",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.RateLimit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RateLimit"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, rate or burst",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/retention": {
+            "get": {
+                "description": "Get how long the tenant's stored messages are kept (max_age_seconds) and how many of them (max_rows), 0 meaning forever, and whether expired messages are archived instead of deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's retention policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetentionPolicy"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "The retention janitor expires the tenant's messages stored longer than max_age_seconds ago and all but its max_rows newest ones; 0 disables either limit. Expired messages are deleted, or with archive moved to the tenant's messages_archive_\u003ctenant\u003e table.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's retention policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Retention policy",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetentionPolicy"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetentionPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or policy",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/retry": {
+            "get": {
+                "description": "Get how many times a message that failed for a reason that may pass is delivered and how long it waits between attempts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's retry policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetryPolicy"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Override the config default. The n-th retry waits backoff_seconds * 2^(n-1), capped at max_backoff_seconds and spread by up to jitter of it; after max_attempts deliveries the message is dead-lettered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's retry policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Retry policy",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "backoff_seconds": {
+                                    "type": "integer"
+                                },
+                                "jitter": {
+                                    "type": "number"
+                                },
+                                "max_attempts": {
+                                    "type": "integer"
+                                },
+                                "max_backoff_seconds": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RetryPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or policy",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/config/slo": {
+            "put": {
+                "description": "Override the config default: target share of messages that must be persisted within threshold_ms of being published",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Update a tenant's SLO",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "SLO objective",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "target": {
+                                    "type": "number"
+                                },
+                                "threshold_ms": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SLOObjective"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumer-groups": {
+            "get": {
+                "description": "List the tenant's consumer groups with their committed offset and lag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "List a tenant's consumer groups",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.ConsumerGroup"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a consumer group that reads the tenant's processed messages with its own offset. It starts after the newest message (latest, default) or at the first one (earliest).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "Create a consumer group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Consumer group",
+                        "name": "group",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "name": {
+                                    "type": "string"
+                                },
+                                "start": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ConsumerGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name or start",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "Consumer group already exists",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumer-groups/{group}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "Get a consumer group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consumer group name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ConsumerGroup"
+                        }
+                    },
+                    "404": {
+                        "description": "Consumer group not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "Delete a consumer group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consumer group name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Consumer group not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumer-groups/{group}/commit": {
+            "post": {
+                "description": "Move the group's offset to a message, usually the next_offset of a processed batch. Commits never move the offset backwards; an older offset is ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "Commit a consumer group offset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consumer group name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ID of the last processed message",
+                        "name": "offset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "offset": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ConsumerGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or unknown message",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Consumer group not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumer-groups/{group}/messages": {
+            "get": {
+                "description": "Get the next messages after the group's offset, oldest first. Commit next_offset once they are processed; until then the same batch is returned again. With auto_commit the offset moves past the batch right away, so concurrent members of the group get disjoint batches but a member that fails loses its batch.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "Read messages as a consumer group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consumer group name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of messages (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Commit the batch's offset immediately",
+                        "name": "auto_commit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ConsumerGroupBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit or auto_commit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Consumer group not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Server busy, retry after the Retry-After delay",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/consumer-groups/{group}/seek": {
+            "post": {
+                "description": "Set the group's offset to earliest, latest or a message ID; the group then reads on from the message after it. Unlike a commit this may move the offset backwards to replay messages.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consumer-groups"
+                ],
+                "summary": "Reset a consumer group offset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consumer group name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "earliest, latest or a message ID",
+                        "name": "seek",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "to": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ConsumerGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or unknown message",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Consumer group not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/dlq": {
+            "get": {
+                "description": "Page through the tenant's DLQ in queue order with each message's headers, payload (JSON projection for protobuf and Avro), dead-letter reason and retry count. Messages are read without being removed; a page can reach at most 1000 messages deep.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List dead-lettered messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Position of the first message (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Messages per page (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DeadLetterPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid offset or limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not consumed by this instance",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/dlq/redrive": {
+            "post": {
+                "description": "Move the tenant's dead-lettered messages matching a CEL expression (same variables as filter rules; empty matches all) back to the queue they came from. An optional transform merges a JSON merge patch into each payload (null removes a field) and drops headers. Messages that do not match or cannot be transformed stay in the DLQ. dry_run reports the outcome with a preview of the first transformed messages and leaves the DLQ unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Redrive dead-lettered messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Match expression, transform, limit and dry run",
+                        "name": "redrive",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.DLQRedrive"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DLQRedriveResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, match expression or patch",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not consumed by this instance",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/dlq/replay": {
+            "post": {
+                "description": "Move the tenant's dead-lettered messages with the given IDs, or all of them, back to the queue they came from unchanged, with the retry headers cleared. The other messages stay in the DLQ in their original order. dry_run reports what would be replayed and leaves the DLQ unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Replay dead-lettered messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message IDs or all, and dry run",
+                        "name": "replay",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.DLQReplay"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DLQRedriveResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not consumed by this instance",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/dlq/retries": {
+            "get": {
+                "description": "List the most recent decisions of the DLQ retry scheduler for the tenant: each retry of a message and when it gave up",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List DLQ retry attempts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only attempts for this AMQP message ID",
+                        "name": "message_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of attempts (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.DLQRetryAttempt"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/events": {
+            "get": {
+                "description": "List the tenant's events (tenant.created, tenant.config_changed, message.dead_lettered, consumer.restarted) after a cursor, oldest first. Pass the seq of the last event as after to get the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a tenant's system events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only events with a greater seq (default 0)",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of events (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/events.Event"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid after or limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/events/stream": {
+            "get": {
+                "description": "Server-Sent Events feed of the tenant's events. Each SSE event is named by the event type, carries the event as JSON and uses its seq as id, so a reconnecting client resumes after Last-Event-ID. Without it the stream starts with new events, or after the given seq.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Stream a tenant's system events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Replay events with a greater seq first",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this seq; takes precedence over after",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "text/event-stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid cursor",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/expired": {
+            "get": {
+                "description": "Count the tenant's messages that expired in the queue (queue TTL) before they could be processed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Count expired messages of a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "expired": {
+                                    "type": "integer"
+                                },
+                                "tenant_id": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/filters": {
+            "get": {
+                "description": "Get the CEL filter rules evaluated on the tenant's messages before they are stored, in evaluation order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's message filters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/filter.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the tenant's filter rules. Each rule is a CEL expression over message.id, message.type, message.headers and message.payload returning a bool; matching messages are dropped, tagged with target, or routed to the tenant's route queue named target instead of stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Replace a tenant's message filters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Filter rules, in evaluation order",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/filter.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/filter.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or rule",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/filters/dry-run": {
+            "post": {
+                "description": "Show which rules match a sample message and what would happen to it, using the given rules or the tenant's stored rules, and the given flags or the tenant's runtime config. Nothing is stored or routed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Preview message filters on a sample message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sample message, optional rules and optional flags",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "flags": {
+                                    "type": "object"
+                                },
+                                "headers": {
+                                    "type": "object"
+                                },
+                                "id": {
+                                    "type": "string"
+                                },
+                                "payload": {
+                                    "type": "object"
+                                },
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/filter.Rule"
+                                    }
+                                },
+                                "type": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/filter.Decision"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or rule",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "422": {
+                        "description": "A rule exceeded its evaluation limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/ip-allowlist": {
+            "get": {
+                "description": "Get the source CIDRs allowed to call the tenant's endpoints. An empty list allows all.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's IP allowlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "cidrs": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tenant ID",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the source CIDRs allowed to call the tenant's endpoints. An empty list allows all.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Replace a tenant's IP allowlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Allowed CIDRs or IPs",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "cidrs": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "cidrs": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tenant ID, request body or CIDR",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the tenant's API keys that have not expired, including revoked ones. Key values are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a tenant's API keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.APIKey"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create an API key for the tenant. Requests sending it in the X-API-Key header are bound to the tenant like a tenant-admin token. Only its SHA-256 hash is stored; the key is only returned here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and lifetime (default: no expiry)",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "name": {
+                                    "type": "string"
+                                },
+                                "ttl": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or lifetime",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/keys/{key_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reject the API key from now on",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "key_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/keys/{key_id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a replacement for the API key with the same name and expiry. The old key stays valid for the grace period (default 0, up to 7 days) so clients can switch over.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Rotate an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "key_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "How long the old key stays valid, e.g. 1h",
+                        "name": "rotation",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "grace": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid grace period",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found, expired or revoked",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/messages": {
+            "post": {
+                "description": "Publish a JSON payload to the tenant's main queue, or to one of its channels, so producers need no AMQP access. The message is consumed like any other and the generated message ID is returned. delay_seconds (up to 7 days), or deliver_at for a time up to 7 days ahead, holds the message in a scheduled queue until it is due. priority (0-9) lets the message overtake lower priorities in the tenant's worker pool, and at the broker if the tenant has a max priority. The tenant's messages partition must exist. A payload that does not match the schema of its message type, or the tenant's schema, is rejected with 422. Publishing faster than the tenant's rate limit is rejected with 429. A tenant at its quota of stored messages or bytes is rejected with 413, and at its queue depth quota with 429.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Publish a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payload, message type, channel and delay",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.PublishRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PublishResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, payload or delay",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant or channel not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "Tenant has no messages partition",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "413": {
+                        "description": "Tenant stores as many messages or bytes as its quota allows, usage is measured again after the Retry-After delay",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "422": {
+                        "description": "Payload does not match its schema",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "429": {
+                        "description": "Tenant rate limit exceeded or queue depth quota reached, retry after the Retry-After delay",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/messages/purge": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete the tenant's messages stored before ` + "`" + `before` + "`" + ` and all but its ` + "`" + `keep_latest` + "`" + ` newest ones, or with neither apply its retention policy now. With archive, which defaults to the policy's, the messages are moved to the tenant's archive table instead. Queued messages are not affected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Purge a tenant's stored messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Messages to purge",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PurgeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PurgeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, or nothing to purge",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant or partition not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/quarantine": {
+            "get": {
+                "description": "List the tenant's most recently quarantined poison messages: messages whose processing panicked or whose payload could not be decoded or validated too many times. Each carries its last error, original headers and payload, and its first and last failure times.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List quarantined messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of messages (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.QuarantinedMessage"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/recovery": {
+            "get": {
+                "description": "Get the tenant's latest recovery with the processed share of the backlog, the processing rate and an estimate of the time left",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get backlog recovery progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Recovery"
+                        }
+                    },
+                    "404": {
+                        "description": "No recovery",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Park the backlog of the tenant's main queue in a recovery queue and drain it with extra workers. oldest_first (default) pauses the main queue until the backlog is cleared; newest_first keeps processing new messages and works through the backlog newest first. Parking runs in the background; poll GET /tenants/{id}/recovery for progress.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Start a backlog recovery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Recovery workers (1-100) and order (oldest_first or newest_first)",
+                        "name": "recovery",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "order": {
+                                    "type": "string"
+                                },
+                                "workers": {
+                                    "type": "integer"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Recovery"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, workers or order",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not consumed by this instance",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "409": {
+                        "description": "Recovery already running, no backlog or tenant in ordered mode",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the configured transport",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop the tenant's unfinished recovery. Backlog messages not processed yet go back to the end of the main queue.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Cancel a backlog recovery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Recovery"
+                        }
+                    },
+                    "404": {
+                        "description": "No unfinished recovery",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/redaction-rules": {
+            "get": {
+                "description": "Get the field paths that are masked or hashed before the tenant's messages are stored",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's redaction rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/redact.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the field paths that are masked or hashed before the tenant's messages are stored. \"*\" matches any key or array element.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Replace a tenant's redaction rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Redaction rules",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/redact.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/redact.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or rule",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/redaction-rules/dry-run": {
+            "post": {
+                "description": "Show what would be redacted from a sample payload, using the given rules or the tenant's stored rules. Nothing is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Preview redaction of a payload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sample payload and optional rules",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "payload": {
+                                    "type": "object"
+                                },
+                                "rules": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/redact.Rule"
+                                    }
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "matched": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                },
+                                "redacted": {
+                                    "type": "object"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or rule",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/resume": {
+            "post": {
+                "description": "Consume the tenant again, starting with the messages its queue took while suspended. Other instances resume consuming it within 10 seconds. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Resume a suspended tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantSuspension"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/runtime-config": {
+            "get": {
+                "description": "Get the tenant's runtime config values and feature flags by key, as seen by this instance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's runtime config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/runtime-config/{key}": {
+            "put": {
+                "description": "Store a JSON value (at most 4 KiB) under the key. Every instance picks the change up through the configured backend's watch; CEL filters see it in the flags map.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Set a tenant runtime config value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key (1-128 letters, digits, '_', '.' or '-')",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON value",
+                        "name": "value",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dynconfig.Entry"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid key or value",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Runtime config is disabled",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove the key from the tenant's runtime config on every instance",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a tenant runtime config value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Key not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "503": {
+                        "description": "Runtime config is disabled",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/schema": {
+            "get": {
+                "description": "Get the latest version of the tenant-wide JSON Schema, which validates messages whose type has no schema of its own",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schemas"
+                ],
+                "summary": "Get a tenant's payload schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PayloadSchema"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant has no schema",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Register a JSON Schema as the next version of the tenant-wide schema. Messages whose type has no schema of its own, including untyped ones, are validated against it: consumed messages that fail go to the tenant's DLQ with reason schema_validation and published ones are rejected with 422. Set {} to accept every payload again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schemas"
+                ],
+                "summary": "Set a tenant's payload schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Schema",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PayloadSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON Schema",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/schemas": {
+            "get": {
+                "description": "List every registered schema version per message type, without the schema bodies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schemas"
+                ],
+                "summary": "List a tenant's payload schemas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.PayloadSchema"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/schemas/{type}": {
+            "post": {
+                "description": "Register a JSON Schema as the next version for a message type. Messages whose AMQP type property matches are validated against the latest version; invalid ones go to the tenant's DLQ.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schemas"
+                ],
+                "summary": "Register a payload schema version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Schema",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PayloadSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON Schema",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/schemas/{type}/usage": {
+            "get": {
+                "description": "Count the tenant's stored messages of a type per period and schema version; a null version means the message was not validated",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schemas"
+                ],
+                "summary": "Get schema version usage over time",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period: day (default), week or month",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages since this RFC3339 time (default 30 days ago)",
+                        "name": "since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.SchemaUsage"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid bucket or since",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/schemas/{type}/versions/{version}": {
+            "get": {
+                "description": "Get one version of a message type's JSON Schema",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schemas"
+                ],
+                "summary": "Get a payload schema version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Schema version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PayloadSchema"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid version",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Schema not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/slo": {
+            "get": {
+                "description": "Get the tenant's objective, compliance over the SLO window and error budget burn rates, as tracked by the instance consuming the tenant",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's SLO compliance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SLOStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/stats": {
+            "get": {
+                "description": "Get the depth of the tenant's main queue and DLQ and its consumers from the broker (null when it cannot be asked or the transport is not RabbitMQ), its workers on the instance answering and how many are processing a message, and what that instance consumed of it in the last minute, 5 minutes and hour with the mean processing latency over the hour and the time of the last message.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get a tenant's consumption stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/suspend": {
+            "post": {
+                "description": "Stop consuming the tenant, e.g. for a billing hold or to isolate an incident, after its in-flight messages are done. Unlike deleting it, its queue keeps taking messages and its stored data is kept. Other instances stop consuming it within 10 seconds. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Suspend a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the tenant is suspended",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "reason": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantSuspension"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/suspension": {
+            "get": {
+                "description": "Report whether consuming the tenant is suspended, since when and why.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Get whether a tenant is suspended",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantSuspension"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the sub-tokens minted for the tenant that have not expired, including revoked ones. Token values are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a tenant's sub-tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.TenantToken"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issue a short-lived access token for the tenant that can only call the routes of its scopes (messages:read, events:read, slo:read, dlq:read), e.g. for a dashboard. It has no refresh token. Requires a full token of the tenant or an operator token; the token is only returned here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Mint a scoped sub-token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name, scopes and lifetime (default 1h)",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "name": {
+                                    "type": "string"
+                                },
+                                "scopes": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                },
+                                "ttl": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TenantToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, scope or lifetime",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/tokens/{token_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reject the sub-token from now on, before it expires",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Revoke a sub-token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token ID",
+                        "name": "token_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Token not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the tenant's webhooks. Secrets are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a tenant's webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.Webhook"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Register an endpoint the tenant's processed messages are POSTed to, signed with the returned secret. The secret is only returned here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Endpoint URL",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "url": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or URL, or an internal address",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Tenant not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/webhooks/{webhook_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete the webhook together with its deliveries; pending deliveries are dropped",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{id}/webhooks/{webhook_id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the webhook's latest deliveries, newest first, with their attempts and the status code and error of the last attempt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "List a webhook's deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Only deliveries with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum deliveries (default: 100, max: 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/domain.WebhookDelivery"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status or limit",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Token bound to another tenant or scoped",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/ws": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upgrade to a WebSocket streaming the events of the subscribed tenants as JSON: message.stored for every stored message, consumer.scaled and consumer.restarted when workers change, message.dead_lettered, and the other system events. Subscribe with the tenant_id query parameter or by sending {\"action\":\"subscribe\",\"tenant_ids\":[...]}; {\"action\":\"unsubscribe\",...} stops a tenant. Tokens bound to a tenant can only subscribe to it, and the tenant's IP allowlist applies. A client that reads too slowly misses events and is told how many with a console.dropped message.",
+                "tags": [
+                    "tenants"
+                ],
+                "summary": "Open the real-time console",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tenants to subscribe to right away",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Not a WebSocket request",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "403": {
+                        "description": "Origin not allowed",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "audit.Entry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "id": {
+                    "type": "string"
+                },
+                "new": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "old": {
+                    "description": "Old and New are the values the operation changed, before and after",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.TokenPair": {
+            "type": "object",
+            "properties": {
+                "access_expires_at": {
+                    "type": "string"
+                },
+                "access_token": {
+                    "type": "string"
+                },
+                "refresh_expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ArchiveRestoreRequest": {
+            "type": "object",
+            "properties": {
+                "hold_seconds": {
+                    "description": "HoldSeconds is how long the restored messages are not archived again,\ndefaults to a day",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Channel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ordered": {
+                    "description": "Ordered processes the channel's messages on a single lane in strict queue order",
+                    "type": "boolean"
+                },
+                "partition_key": {
+                    "description": "PartitionKey is a payload field path; messages with the same value are\nprocessed in order on the same lane",
+                    "type": "string"
+                },
+                "queue_name": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ConsumerGroup": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "lag": {
+                    "description": "Lag is the number of stored messages after the offset",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "offset": {
+                    "description": "Offset is the ID of the last committed message, empty if the group\nreads from the tenant's first message",
+                    "type": "string"
+                },
+                "offset_created_at": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ConsumerGroupBatch": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "description": "Committed is true if the offset already moved past the batch",
+                    "type": "boolean"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Message"
+                    }
+                },
+                "next_offset": {
+                    "description": "NextOffset is the offset to commit once Data is processed, the current\noffset if the batch is empty",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ConsumerOwner": {
+            "type": "object",
+            "properties": {
+                "handover_to": {
+                    "type": "string"
+                },
+                "instance_id": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DLQRedrive": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "description": "DryRun reports what would be redriven and leaves the DLQ unchanged",
+                    "type": "boolean"
+                },
+                "limit": {
+                    "description": "Limit caps how many messages are redriven",
+                    "type": "integer"
+                },
+                "match": {
+                    "description": "Match is a CEL expression like a filter rule's; empty matches every message",
+                    "type": "string"
+                },
+                "transform": {
+                    "$ref": "#/definitions/domain.DLQTransform"
+                }
+            }
+        },
+        "domain.DLQRedrivePreview": {
+            "type": "object",
+            "properties": {
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "domain.DLQRedriveResult": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "description": "Errors describe the first failed messages, which stay in the DLQ",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "preview": {
+                    "description": "Preview holds the first transformed messages of a dry run",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DLQRedrivePreview"
+                    }
+                },
+                "redriven": {
+                    "type": "integer"
+                },
+                "scanned": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DLQReplay": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "description": "All replays every message instead",
+                    "type": "boolean"
+                },
+                "dry_run": {
+                    "description": "DryRun reports what would be replayed and leaves the DLQ unchanged",
+                    "type": "boolean"
+                },
+                "message_ids": {
+                    "description": "MessageIDs are the messages to replay",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "domain.DLQRetryAttempt": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DLQRetryPolicy": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Default is true when the tenant has no override and the config defaults apply",
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "schedule_seconds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "domain.DLQTransform": {
+            "type": "object",
+            "properties": {
+                "patch": {
+                    "description": "Patch is a JSON merge patch (RFC 7396) applied to the payload; null removes a field",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "remove_headers": {
+                    "description": "RemoveHeaders are AMQP headers dropped from the message",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "domain.DeadLetter": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "Channel is the tenant channel the message came from, empty for the main queue",
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "dead_lettered_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is the JSON payload, or the JSON projection of a protobuf or\nAvro payload; it is omitted when the payload cannot be decoded",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "reason": {
+                    "description": "Reason is why the broker dead-lettered the message, e.g. rejected",
+                    "type": "string"
+                },
+                "retry_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DeadLetterPage": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DeadLetter"
+                    }
+                },
+                "next_offset": {
+                    "description": "NextOffset is the offset of the next page, 0 on the last page",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Total is the number of messages in the DLQ",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DedupConfig": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "Key is message_id or payload_hash",
+                    "type": "string"
+                },
+                "window_seconds": {
+                    "description": "WindowSeconds is how long a key is remembered, 0 disables deduplication",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DeliveryInfo": {
+            "type": "object",
+            "properties": {
+                "age_seconds": {
+                    "type": "number"
+                },
+                "delivery_tag": {
+                    "type": "integer"
+                },
+                "received_at": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "worker": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ExportJob": {
+            "type": "object",
+            "properties": {
+                "chunk_count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "download_url": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "exported_rows": {
+                    "type": "integer"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.JSONB": {
+            "type": "object",
+            "additionalProperties": {}
+        },
+        "domain.Message": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "Channel is the tenant channel the message arrived on, empty for the main queue",
+                    "type": "string"
+                },
+                "content_type": {
+                    "description": "ContentType is set for protobuf and Avro payloads; Payload then holds\ntheir JSON projection and RawPayload the original bytes",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deliver_at": {
+                    "description": "DeliverAt is when a scheduled message was due, nil if it was not delayed",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_type": {
+                    "description": "MessageType is the AMQP type property the payload schema is chosen by",
+                    "type": "string"
+                },
+                "payload": {
+                    "$ref": "#/definitions/domain.JSONB"
+                },
+                "raw_payload": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "schema_version": {
+                    "description": "SchemaVersion is the schema version the payload validated against, nil if none",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags were added by the tenant's filter rules",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.MessageArchive": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "day": {
+                    "description": "Day is the UTC day the messages were stored, as YYYY-MM-DD",
+                    "type": "string"
+                },
+                "held_until": {
+                    "description": "HeldUntil keeps the restored messages from being archived again",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_count": {
+                    "type": "integer"
+                },
+                "object_key": {
+                    "type": "string"
+                },
+                "restored_at": {
+                    "description": "RestoredAt is when the archive was last loaded back into the tenant's partition",
+                    "type": "string"
+                },
+                "restored_count": {
+                    "type": "integer"
+                },
+                "size_bytes": {
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Migration": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.MigrationStatus": {
+            "type": "object",
+            "properties": {
+                "dirty": {
+                    "type": "boolean"
+                },
+                "latest": {
+                    "type": "integer"
+                },
+                "migrations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Migration"
+                    }
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "version": {
+                    "description": "Version is the last applied migration, 0 if none",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Partition": {
+            "type": "object",
+            "properties": {
+                "attached": {
+                    "type": "boolean"
+                },
+                "estimated_rows": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "size_bytes": {
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.PayloadSchema": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "schema": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.PriorityConfig": {
+            "type": "object",
+            "properties": {
+                "max_priority": {
+                    "type": "integer"
+                },
+                "queue": {
+                    "description": "Queue is the main queue the tenant is consumed from",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ProvisioningJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.PublishRequest": {
+            "type": "object",
+            "required": [
+                "payload"
+            ],
+            "properties": {
+                "channel": {
+                    "description": "Channel publishes to one of the tenant's channels instead of the main queue",
+                    "type": "string"
+                },
+                "delay_seconds": {
+                    "description": "DelaySeconds holds the message back this long before it is delivered",
+                    "type": "integer"
+                },
+                "deliver_at": {
+                    "description": "DeliverAt holds the message back until this time instead, at most 7\ndays ahead; a time already passed delivers it right away",
+                    "type": "string"
+                },
+                "message_type": {
+                    "description": "MessageType is set as the AMQP type property, which picks the payload schema",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is the JSON message body",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "priority": {
+                    "description": "Priority from 0 to 9 lets the message overtake lower ones in the\ntenant's worker pool and, on a priority queue, at the broker",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.PublishResult": {
+            "type": "object",
+            "properties": {
+                "deliver_at": {
+                    "description": "DeliverAt is when a delayed message reaches the queue",
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "queue": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.PurgeRequest": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "description": "Archive moves the messages to the archive table; defaults to the policy's",
+                    "type": "boolean"
+                },
+                "before": {
+                    "description": "Before purges messages stored before this time",
+                    "type": "string"
+                },
+                "keep_latest": {
+                    "description": "KeepLatest purges all but this many newest messages",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.PurgeResult": {
+            "type": "object",
+            "properties": {
+                "archive_table": {
+                    "description": "ArchiveTable holds the archived messages",
+                    "type": "string"
+                },
+                "archived": {
+                    "type": "boolean"
+                },
+                "purged": {
+                    "description": "Purged is the number of messages deleted or archived",
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.QuarantinedMessage": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "first_failed_at": {
+                    "type": "string"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_failed_at": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is the payload when it is JSON; otherwise RawPayload holds it",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "quarantined_at": {
+                    "type": "string"
+                },
+                "raw_payload": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "reason": {
+                    "description": "Reason is the kind of the last failure: panic, decode or schema_validation",
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.QueueRename": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "moved": {
+                    "description": "Moved is the number of messages shoveled from the old queue",
+                    "type": "integer"
+                },
+                "new_name": {
+                    "type": "string"
+                },
+                "old_name": {
+                    "type": "string"
+                },
+                "renamed": {
+                    "description": "Renamed is set once the old queue has been deleted",
+                    "type": "boolean"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.QuotaUsage": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "integer"
+                },
+                "queue_depth": {
+                    "description": "QueueDepth is nil when the broker could not be asked",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.RateLimit": {
+            "type": "object",
+            "properties": {
+                "burst": {
+                    "description": "Burst is how many messages may be published at once above the rate",
+                    "type": "integer"
+                },
+                "consume": {
+                    "description": "Consume also paces the tenant's consumers at the same rate; messages\nbeyond the prefetch wait in the queue",
+                    "type": "boolean"
+                },
+                "messages_per_second": {
+                    "description": "MessagesPerSecond is the sustained rate, 0 disables the limit",
+                    "type": "number"
+                }
+            }
+        },
+        "domain.Recovery": {
+            "type": "object",
+            "properties": {
+                "backlog": {
+                    "description": "Backlog is the number of messages parked when the recovery started",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "eta_seconds": {
+                    "description": "ETASeconds estimates when the backlog is cleared at the current rate",
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "order": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "description": "Progress is Processed / Backlog, between 0 and 1",
+                    "type": "number"
+                },
+                "rate": {
+                    "description": "Rate is the number of backlog messages processed per second so far",
+                    "type": "number"
+                },
+                "remaining": {
+                    "description": "Remaining is the number of parked messages not processed yet",
+                    "type": "integer"
+                },
+                "running_at": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.RetentionPolicy": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "description": "Archive moves expired messages to the tenant's archive table instead\nof deleting them",
+                    "type": "boolean"
+                },
+                "max_age_seconds": {
+                    "description": "MaxAgeSeconds expires messages stored longer ago, 0 keeps them",
+                    "type": "integer"
+                },
+                "max_rows": {
+                    "description": "MaxRows expires all but the newest messages, 0 keeps them",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.RetryPolicy": {
+            "type": "object",
+            "properties": {
+                "backoff_seconds": {
+                    "type": "integer"
+                },
+                "default": {
+                    "description": "Default is true when the tenant has no override and the config defaults apply",
+                    "type": "boolean"
+                },
+                "jitter": {
+                    "description": "Jitter spreads each wait by up to this share of it in either direction, 0 to 1",
+                    "type": "number"
+                },
+                "max_attempts": {
+                    "description": "MaxAttempts counts the first delivery, so 1 dead-letters on the first failure",
+                    "type": "integer"
+                },
+                "max_backoff_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SLOObjective": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Default is true when the tenant has no override and the config defaults apply",
+                    "type": "boolean"
+                },
+                "target": {
+                    "type": "number"
+                },
+                "threshold_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SLOStatus": {
+            "type": "object",
+            "properties": {
+                "burn_rate": {
+                    "description": "BurnRate is how fast the error budget is spent per lookback window;\n1 spends exactly the budget over the SLO window",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "compliance": {
+                    "description": "Compliance is Good/Total, nil when no message was processed in the window",
+                    "type": "number"
+                },
+                "error_budget_remaining": {
+                    "description": "ErrorBudgetRemaining is the share of the window's error budget not yet spent",
+                    "type": "number"
+                },
+                "good": {
+                    "type": "integer"
+                },
+                "objective": {
+                    "$ref": "#/definitions/domain.SLOObjective"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "window_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SchemaUsage": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TenantDetail": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "migrated": {
+                    "description": "Migrated is set for tenants moved to another deployment",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "queue_depth": {
+                    "description": "QueueDepth is the number of ready messages in the main queue, nil if\nthe broker could not be asked",
+                    "type": "integer"
+                },
+                "queue_name": {
+                    "type": "string"
+                },
+                "quota": {
+                    "$ref": "#/definitions/domain.TenantQuota"
+                },
+                "status": {
+                    "description": "Status is active while any instance consumes the main queue",
+                    "type": "string"
+                },
+                "suspended_at": {
+                    "description": "SuspendedAt is set while the tenant is suspended",
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/domain.QuotaUsage"
+                },
+                "workers": {
+                    "description": "Workers is the configured worker count of the main queue",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TenantMigration": {
+            "type": "object",
+            "properties": {
+                "copied_rows": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "shoveled_messages": {
+                    "type": "integer"
+                },
+                "source_count": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "target_count": {
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TenantQuota": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Default is true when the tenant has no quota of its own and the config defaults apply",
+                    "type": "boolean"
+                },
+                "max_bytes": {
+                    "description": "MaxBytes caps the size of the partition including its indexes",
+                    "type": "integer"
+                },
+                "max_messages": {
+                    "description": "MaxMessages caps the rows of the tenant's messages partition",
+                    "type": "integer"
+                },
+                "max_queue_depth": {
+                    "description": "MaxQueueDepth caps the ready messages in the tenant's main queue",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TenantStats": {
+            "type": "object",
+            "properties": {
+                "active_workers": {
+                    "description": "ActiveWorkers are the workers processing a message right now, on RabbitMQ",
+                    "type": "integer"
+                },
+                "avg_latency_ms": {
+                    "description": "AvgLatencyMs is the mean processing time over the last hour",
+                    "type": "number"
+                },
+                "consumed_1h": {
+                    "type": "integer"
+                },
+                "consumed_1m": {
+                    "type": "integer"
+                },
+                "consumed_5m": {
+                    "type": "integer"
+                },
+                "consumers": {
+                    "description": "Consumers is the number of consumers of the main queue over all instances",
+                    "type": "integer"
+                },
+                "dlq_depth": {
+                    "type": "integer"
+                },
+                "last_consumed_at": {
+                    "type": "string"
+                },
+                "queue_depth": {
+                    "description": "QueueDepth and DLQDepth are ready messages, nil if the broker could not be asked",
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "workers": {
+                    "description": "Workers is the tenant's worker count here, 0 if not consumed here",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TenantSummary": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "migrated": {
+                    "description": "Migrated is set for tenants moved to another deployment",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "queue_depth": {
+                    "description": "QueueDepth is the number of ready messages in the main queue, nil if\nthe broker could not be asked",
+                    "type": "integer"
+                },
+                "queue_name": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is active while any instance consumes the main queue",
+                    "type": "string"
+                },
+                "workers": {
+                    "description": "Workers is the configured worker count of the main queue",
+                    "type": "integer"
+                },
+                "suspended_at": {
+                    "description": "SuspendedAt is set while the tenant is suspended",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TenantSuspension": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "suspended": {
+                    "type": "boolean"
+                },
+                "suspended_at": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TenantToken": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "description": "LastStatusCode is the HTTP status of the last attempt, 0 if it got no response",
+                    "type": "integer"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.WorkerBounds": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Default is set when the tenant has no bounds of its own",
+                    "type": "boolean"
+                },
+                "max_workers": {
+                    "type": "integer"
+                },
+                "min_workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dynconfig.Entry": {
+            "type": "object",
+            "properties": {
+                "key": {
                     "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
-        "domain.Tenant": {
+        "events.Event": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string"
                 },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
                 "id": {
                     "type": "string"
                 },
+                "seq": {
+                    "type": "integer"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "filter.Decision": {
+            "type": "object",
+            "properties": {
+                "drop": {
+                    "type": "boolean"
+                },
+                "matched": {
+                    "description": "Matched names the rules that matched, in order",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "route": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "filter.Rule": {
+            "type": "object",
+            "required": [
+                "action",
+                "expression",
+                "name"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "drop",
+                        "tag",
+                        "route"
+                    ]
+                },
+                "expression": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.ComponentStatus": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "error": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_error_at": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Details": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.ComponentStatus"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "middleware.AccessLogSettings": {
+            "type": "object",
+            "properties": {
+                "routes": {
+                    "description": "Routes override the sample rate per route, keyed \"METHOD /route/:template\";\n0 turns a route off and 1 logs every request",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "sample_rate": {
+                    "description": "SampleRate is the share of requests logged on routes without an override",
+                    "type": "number"
+                }
+            }
+        },
+        "processor.Stage": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "name": {
                     "type": "string"
                 }
             }
+        },
+        "redact.Rule": {
+            "type": "object",
+            "required": [
+                "action",
+                "path"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "mask",
+                        "hash"
+                    ]
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "APIKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
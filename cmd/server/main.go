@@ -2,23 +2,57 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	_ "multi-tenant-messaging/cmd/server/docs" // Import generated docs
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/auth"
+	"multi-tenant-messaging/internal/codec"
 	"multi-tenant-messaging/internal/config"
+	"multi-tenant-messaging/internal/console"
 	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/dynconfig"
+	"multi-tenant-messaging/internal/events"
+	"multi-tenant-messaging/internal/grpcapi"
 	"multi-tenant-messaging/internal/handler"
+	"multi-tenant-messaging/internal/health"
+	"multi-tenant-messaging/internal/journal"
+	"multi-tenant-messaging/internal/kube"
+	"multi-tenant-messaging/internal/logging"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/middleware"
+	"multi-tenant-messaging/internal/migrate"
 	"multi-tenant-messaging/internal/repository"
 	"multi-tenant-messaging/internal/service"
+	"multi-tenant-messaging/internal/transport"
+	"multi-tenant-messaging/migrations"
+	"multi-tenant-messaging/pkg/processor"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // @title Multi-Tenant Messaging System API
@@ -35,64 +69,1282 @@ import (
 
 // @host localhost:8080
 // @BasePath /
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// serve sets up the server and serves until a shutdown signal. The one-off
+// commands selected by opts run instead, as soon as what they need is set up.
+func serve(opts serveOptions) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := logging.Setup(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		log.Fatalf("Invalid logging config: %v", err)
+	}
 
-	db, err := repository.NewDatabase(cfg.Database.URL)
+	var queryTracer pgx.QueryTracer
+	if cfg.Metrics.Enabled || cfg.Tracing.Enabled {
+		queryTracer = repository.NewQueryTracer(cfg.Database.QuerySpans)
+	}
+	// Postgres dan RabbitMQ bisa belum siap saat semua container start bersamaan
+	startupWait := repository.Backoff{Initial: cfg.Startup.InitialBackoff, Max: cfg.Startup.MaxBackoff, Deadline: cfg.Startup.Timeout}
+	db, err := repository.NewDatabase(cfg.Database.URL, queryTracer, startupWait)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logging.Fatal("Failed to connect to database", "error", err)
 	}
 	defer db.Close()
+	db.SetFailoverCheckInterval(cfg.Database.FailoverCheckInterval)
+
+	migrationService := migrate.New(db.DB, migrations.FS)
+	if opts.migrate != "" {
+		if err := migrateSchema(cfg, migrationService, opts.migrate, opts.migrateSteps); err != nil {
+			logging.Fatal("Failed to migrate database", "error", err)
+		}
+		return
+	}
+	if cfg.Database.AutoMigrate {
+		if err := migrationService.Up(); err != nil {
+			slog.Error("Failed to apply migrations", "error", err)
+		}
+	}
+	if err := migrationService.Ready(); err != nil {
+		slog.Warn("Consumers will not start", "error", err)
+	}
+
+	if err := db.ConfigureRowLevelSecurity(cfg.Database.RowLevelSecurity); err != nil {
+		logging.Fatal("Failed to configure row level security", "error", err)
+	}
+
+	// ID yang sama dipakai untuk handover, lease dan label metrics runtime
+	identity := kube.IdentityFromEnv()
+	instanceID := cfg.Handover.InstanceID
+	if instanceID == "" {
+		instanceID = identity.PodName
+	}
+	if instanceID == "" {
+		hostname, _ := os.Hostname()
+		instanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	stopTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Enabled {
+		stopTracing, err = metrics.StartTracing(context.Background(), metrics.TracingOptions{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			Headers:     cfg.Tracing.Headers,
+			ServiceName: cfg.Tracing.ServiceName,
+			InstanceID:  instanceID,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			logging.Fatal("Failed to start tracing", "error", err)
+		}
+	}
+	if cfg.Metrics.Enabled {
+		if err := metrics.RegisterRuntime(instanceID, identity.Labels(), db.DB); err != nil {
+			logging.Fatal("Failed to register runtime metrics", "error", err)
+		}
+		if err := configureTenantLabels(cfg.Metrics.TenantLabels); err != nil {
+			logging.Fatal("Invalid metrics.tenant_labels", "error", err)
+		}
+	}
+
+	// Dengan transport Kafka, NATS atau AMQP 1.0 RabbitMQ tidak disambungkan sama sekali, rabbit tetap nil
+	var rabbit *repository.RabbitMQ
+	var messaging transport.Transport
+	switch cfg.Transport.Kind {
+	case transport.KindKafka:
+		kafka := cfg.Transport.Kafka
+		messaging = transport.NewKafka(transport.KafkaOptions{
+			Brokers:           kafka.Brokers,
+			TopicStrategy:     kafka.TopicStrategy,
+			TopicPrefix:       kafka.TopicPrefix,
+			SharedTopic:       kafka.SharedTopic,
+			GroupPrefix:       kafka.GroupPrefix,
+			Partitions:        kafka.Partitions,
+			ReplicationFactor: kafka.ReplicationFactor,
+		})
+	case transport.KindNATS:
+		nats := cfg.Transport.NATS
+		messaging, err = transport.NewNATS(transport.NATSOptions{
+			URL:           nats.URL,
+			SubjectPrefix: nats.SubjectPrefix,
+			StreamPrefix:  nats.StreamPrefix,
+			DurablePrefix: nats.DurablePrefix,
+			Replicas:      nats.Replicas,
+			AckWait:       nats.AckWait,
+		})
+		if err != nil {
+			logging.Fatal("Failed to connect to NATS", "error", err)
+		}
+	case transport.KindAMQP10:
+		amqp10 := cfg.Transport.AMQP10
+		dialCtx, cancelDial := context.WithTimeout(context.Background(), cfg.Startup.Timeout)
+		messaging, err = transport.NewAMQP10(dialCtx, transport.AMQP10Options{
+			URL:           amqp10.URL,
+			AddressPrefix: amqp10.AddressPrefix,
+		})
+		cancelDial()
+		if err != nil {
+			logging.Fatal("Failed to connect to AMQP 1.0 broker", "error", err)
+		}
+	default:
+		rabbit, err = repository.DialRabbitMQ(brokerNodes(cfg.RabbitMQ), cfg.RabbitMQ.Connections, startupWait)
+		if err != nil {
+			logging.Fatal("Failed to connect to RabbitMQ", "error", err)
+		}
+		defer rabbit.Close()
+		messaging = transport.NewRabbitMQ(rabbit)
+	}
+	defer messaging.Close()
+
+	revocationStore := auth.NewPostgresRevocationStore(db)
+	tokenService := auth.NewTokenService(cfg.Security.JWTSecret, cfg.Security.AccessTokenTTL, cfg.Security.RefreshTokenTTL, cfg.Security.RotationGracePeriod, revocationStore)
+	if opts.issueToken != "" {
+		if cfg.Security.JWTSecret == "" {
+			logging.Fatal("security.jwt_secret must be set to issue tokens")
+		}
+		pair, err := tokenService.IssuePair(opts.issueToken, opts.issueTokenTenant, opts.issueTokenRole)
+		if err != nil {
+			logging.Fatal("Failed to issue token", "error", err)
+		}
+		json.NewEncoder(os.Stdout).Encode(pair)
+		return
+	}
+	apiKeyService := service.NewAPIKeyService(db)
+	if opts.issueAPIKey != "" {
+		if !cfg.Security.APIKeys {
+			logging.Fatal("security.api_keys must be enabled to issue API keys")
+		}
+		key, err := apiKeyService.Create(opts.issueAPIKey, "initial", "cli", 0)
+		if err != nil {
+			logging.Fatal("Failed to issue API key", "error", err)
+		}
+		json.NewEncoder(os.Stdout).Encode(key)
+		return
+	}
+	authHandler := handler.NewAuthHandler(tokenService)
 
-	rabbit, err := repository.NewRabbitMQ(cfg.RabbitMQ.URL)
+	auditSink, err := newAuditSink(cfg.Audit)
 	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+		logging.Fatal("Failed to set up audit sink", "error", err)
+	}
+	auditLogger := audit.NewLogger(db, auditSink)
+	defer auditLogger.Close()
+
+	eventEmitter, err := events.NewEmitter(db, rabbit, cfg.Events.Queue)
+	if err != nil {
+		logging.Fatal("Failed to set up events", "error", err)
+	}
+	defer eventEmitter.Close()
+
+	var inflightJournal *journal.Journal
+	if cfg.Journal.Enabled {
+		inflightJournal, err = journal.Open(cfg.Journal.Path, cfg.Journal.RecoveryWindow)
+		if err != nil {
+			logging.Fatal("Failed to open in-flight journal", "error", err)
+		}
+		defer inflightJournal.Close()
 	}
-	defer rabbit.Close()
 
 	tenantManager := domain.NewTenantManager()
-	tenantService := service.NewTenantService(db, rabbit, tenantManager)
-	tenantHandler := handler.NewTenantHandler(tenantService)
-	messageHandler := handler.NewMessageHandler(db)
+	// Context untuk goroutine latar belakang, dibatalkan saat shutdown
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+
+	// Job latar belakang yang cukup berjalan di satu instance
+	singletons := kube.NewSingletons()
+	if election := cfg.Kubernetes.LeaderElection; election.Enabled && kube.InCluster() {
+		namespace := election.Namespace
+		if namespace == "" {
+			namespace = identity.Namespace
+		}
+		singletons, err = kube.NewLeaseSingletons(kube.LeaseConfig{
+			Name:          election.LeaseName,
+			Namespace:     namespace,
+			Identity:      instanceID,
+			LeaseDuration: election.LeaseDuration,
+			RenewDeadline: election.RenewDeadline,
+			RetryPeriod:   election.RetryPeriod,
+		})
+		if err != nil {
+			logging.Fatal("Failed to set up leader election", "error", err)
+		}
+	}
+	singletons.Add("purge-events", func(ctx context.Context) {
+		purgeEvents(ctx, eventEmitter, cfg.Events.Retention)
+	})
+
+	runtimeBackend, err := newRuntimeConfigBackend(cfg.DynamicConfig, db)
+	if err != nil {
+		logging.Fatal("Failed to set up runtime config", "error", err)
+	}
+	var runtimeConfig *dynconfig.Store
+	if runtimeBackend != nil {
+		if closer, ok := runtimeBackend.(io.Closer); ok {
+			defer closer.Close()
+		}
+		runtimeConfig = dynconfig.NewStore(runtimeBackend)
+		go runtimeConfig.Run(appCtx)
+		// Consumer tidak dimulai sebelum flag terbaca, tapi backend yang down tidak menghalangi start
+		readyCtx, cancelReady := context.WithTimeout(appCtx, 10*time.Second)
+		if err := runtimeConfig.WaitReady(readyCtx); err != nil {
+			slog.Warn("Runtime config not loaded yet, continuing without it", "error", err)
+		}
+		cancelReady()
+	}
+
+	redactionKey := []byte(cfg.Security.RedactionKey)
+	if len(redactionKey) == 0 {
+		// Kunci acak tetap aman, hanya saja hash tidak bisa dicocokkan setelah restart
+		redactionKey = make([]byte, 32)
+		if _, err := rand.Read(redactionKey); err != nil {
+			logging.Fatal("Failed to generate redaction key", "error", err)
+		}
+		slog.Warn("security.redaction_key is not set, hashed fields will not match across restarts")
+	}
+	redactionService := service.NewRedactionService(db, redactionKey)
+	dedupService := service.NewDedupService(db, cfg.Dedup.CacheSize)
+	singletons.Add("dedup-janitor", func(ctx context.Context) {
+		dedupService.RunJanitor(ctx, time.Minute)
+	})
+	quarantineService := service.NewQuarantineService(db, cfg.Quarantine.Threshold, cfg.Quarantine.Window, cfg.Quarantine.Retention)
+	singletons.Add("quarantine-janitor", func(ctx context.Context) {
+		quarantineService.RunJanitor(ctx, time.Hour)
+	})
+	retentionService := service.NewRetentionService(db, cfg.Retention.BatchSize)
+	singletons.Add("retention-janitor", func(ctx context.Context) {
+		retentionService.RunJanitor(ctx, cfg.Retention.Interval)
+	})
+	var archiveService *service.ArchiveService
+	if cfg.Archive.Enabled {
+		archiveService, err = service.NewArchiveService(db, service.ArchiveOptions{
+			Endpoint:  cfg.Archive.Endpoint,
+			Region:    cfg.Archive.Region,
+			Bucket:    cfg.Archive.Bucket,
+			Prefix:    cfg.Archive.Prefix,
+			AccessKey: cfg.Archive.AccessKey,
+			SecretKey: cfg.Archive.SecretKey,
+			UseSSL:    cfg.Archive.UseSSL,
+			OlderThan: cfg.Archive.OlderThan,
+		})
+		if err != nil {
+			logging.Fatal("Failed to set up message archive", "error", err)
+		}
+		singletons.Add("message-archiver", func(ctx context.Context) {
+			archiveService.Run(ctx, cfg.Archive.Interval)
+		})
+	}
+	if opts.archive != nil {
+		if archiveService == nil {
+			logging.Fatal("archive.enabled must be set to archive messages")
+		}
+		if err := opts.archive(appCtx, archiveService, auditLogger); err != nil {
+			logging.Fatal("Command failed", "error", err)
+		}
+		return
+	}
+	rateLimitService := service.NewRateLimitService(db)
+	claimCheckResolver := service.NewClaimCheckResolver(service.ClaimCheckOptions{
+		AllowedHosts:  cfg.ClaimCheck.AllowedHosts,
+		TenantPrefix:  cfg.ClaimCheck.TenantPrefix,
+		MaxConcurrent: cfg.ClaimCheck.MaxConcurrent,
+		MaxBlobBytes:  cfg.ClaimCheck.MaxBlobBytes,
+		FetchTimeout:  cfg.ClaimCheck.FetchTimeout,
+	})
+	var schemaRegistry *codec.SchemaRegistry
+	if registry := cfg.Codecs.SchemaRegistry; registry.URL != "" {
+		schemaRegistry = codec.NewSchemaRegistry(registry.URL, registry.Username, registry.Password, registry.Timeout)
+	}
+	codecs := codec.NewRegistry(schemaRegistry)
+	schemaService := service.NewSchemaService(db)
+	sloService := service.NewSLOService(db, cfg.SLO.Target, cfg.SLO.Threshold, cfg.SLO.Window)
+	if cfg.Metrics.Enabled {
+		metrics.Registry.MustRegister(sloService)
+	}
+	for name, sidecar := range cfg.Processors.Sidecars {
+		p, err := processor.NewGRPCProcessor(sidecar.Address, sidecar.Timeout)
+		if err != nil {
+			logging.Fatal("Invalid processor sidecar", "name", name, "error", err)
+		}
+		defer p.Close()
+		processor.Register(name, p)
+	}
+	processorService := service.NewProcessorService(db)
+	filterService := service.NewFilterService(db, runtimeConfig, cfg.Filters.EvalTimeout, cfg.Filters.CostLimit)
+	retryService := service.NewRetryService(db, cfg.Retry.MaxAttempts, cfg.Retry.Backoff, cfg.Retry.MaxBackoff, cfg.Retry.Jitter)
+	concurrencyService := service.NewConcurrencyService(db, cfg.Concurrency.MaxWorkers, cfg.Concurrency.MaxTenantWorkers)
+	quotaService := service.NewQuotaService(db, cfg.Quotas.MaxMessages, cfg.Quotas.MaxBytes, cfg.Quotas.MaxQueueDepth, cfg.Quotas.UsageTTL)
+	dlqRetryService := service.NewDLQRetryService(db, cfg.DLQRetry.Enabled, cfg.DLQRetry.Schedule, cfg.DLQRetry.Interval)
+	outboxService := service.NewOutboxService(db, cfg.Outbox.Enabled, cfg.Outbox.RelayInterval, cfg.Outbox.BatchSize, cfg.Outbox.Retention)
+	webhookService := service.NewWebhookService(db, service.WebhookOptions{
+		Timeout:              cfg.Webhooks.Timeout,
+		MaxAttempts:          cfg.Webhooks.MaxAttempts,
+		Backoff:              cfg.Webhooks.Backoff,
+		MaxBackoff:           cfg.Webhooks.MaxBackoff,
+		PollInterval:         cfg.Webhooks.PollInterval,
+		Workers:              cfg.Webhooks.Workers,
+		Retention:            cfg.Webhooks.Retention,
+		AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+	})
+	tenantService := service.NewTenantService(service.TenantServiceDeps{
+		DB:                db,
+		RabbitMQ:          rabbit,
+		Transport:         messaging,
+		TenantManager:     tenantManager,
+		Redactions:        redactionService,
+		Dedup:             dedupService,
+		RateLimits:        rateLimitService,
+		ClaimChecks:       claimCheckResolver,
+		Codecs:            codecs,
+		Schemas:           schemaService,
+		SLOs:              sloService,
+		Processors:        processorService,
+		Filters:           filterService,
+		DLQRetries:        dlqRetryService,
+		Outbox:            outboxService,
+		Webhooks:          webhookService,
+		Events:            eventEmitter,
+		Journal:           inflightJournal,
+		Runtime:           runtimeConfig,
+		Migrations:        migrationService,
+		Retries:           retryService,
+		Quarantines:       quarantineService,
+		Concurrency:       concurrencyService,
+		Quotas:            quotaService,
+		MessageTTL:        cfg.RabbitMQ.MessageTTL,
+		QueueTemplate:     cfg.RabbitMQ.QueueNameTemplate,
+		PartitionOnDelete: cfg.Database.PartitionOnDelete,
+	})
+	if rabbit != nil {
+		rabbit.OnReconnect(tenantService.ReconnectConsumers)
+	}
+	if opts.task != nil {
+		if err := opts.task(appCtx, tenantService, auditLogger); err != nil {
+			logging.Fatal("Command failed", "error", err)
+		}
+		return
+	}
+	tenantHandler := handler.NewTenantHandler(tenantService, auditLogger)
+	tenantTokenService := service.NewTenantTokenService(db, tokenService, cfg.Security.SubTokenMaxTTL)
+	tokenHandler := handler.NewTokenHandler(tenantTokenService, auditLogger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, auditLogger)
+	webhookHandler := handler.NewWebhookHandler(webhookService, auditLogger)
+	retentionHandler := handler.NewRetentionHandler(retentionService, auditLogger)
+	archiveHandler := handler.NewArchiveHandler(archiveService, auditLogger)
+	allowlistService := service.NewAllowlistService(db)
+	messageHandler := handler.NewMessageHandler(db, allowlistService)
+	messageService := service.NewMessageService(db)
+
+	tenantMigrationService := service.NewTenantMigrationService(db, rabbit, tenantService, cfg.TenantMigration.Targets, cfg.Export.ChunkSize)
+	if opts.migrateTenant != "" {
+		run := metrics.StartBatch(cfg.Metrics.PushgatewayURL, "salva_migrate_tenant", map[string]string{"tenant_id": opts.migrateTenant, "target": opts.migrationTarget})
+		migration, err := tenantMigrationService.MigrateTenant(appCtx, opts.migrateTenant, opts.migrationTarget)
+		if err == nil && migration.Status != domain.TenantMigrationStatusCompleted {
+			err = fmt.Errorf("tenant migration %s", migration.Status)
+		}
+		if migration != nil {
+			run.SetItems("copied_rows", migration.CopiedRows)
+			run.SetItems("shoveled_messages", migration.ShoveledMessages)
+		}
+		run.Finish(err)
+		if migration == nil {
+			logging.Fatal("Failed to migrate tenant", "error", err)
+		}
+		json.NewEncoder(os.Stdout).Encode(migration)
+		if migration.Status != domain.TenantMigrationStatusCompleted {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := tenantMigrationService.ResumeInterrupted(); err != nil {
+		slog.Error("Failed to resume interrupted tenant migrations", "error", err)
+	}
+	tenantMigrationHandler := handler.NewTenantMigrationHandler(tenantMigrationService)
+	singletons.Add("tenant-provisioning", tenantService.WatchProvisioning)
+	singletons.Add("publish-outbox", tenantService.RelayOutbox)
+	go webhookService.Run(appCtx)
+	go tenantService.RunSuspensionSync(appCtx)
+
+	var handoverService *service.HandoverService
+	if cfg.Handover.Enabled {
+		handoverService = service.NewHandoverService(db, tenantService, instanceID, cfg.Handover.Version,
+			cfg.Handover.HeartbeatInterval, cfg.Handover.Takeover, cfg.Handover.DrainTimeout)
+		go handoverService.Run(appCtx)
+	} else if cfg.Startup.RestoreTenants {
+		restored, err := tenantService.RestoreTenants(appCtx)
+		if err != nil {
+			slog.Error("Failed to restore tenants", "error", err)
+		}
+		slog.Info("Restored tenant consumers", "tenants", restored)
+	}
+
+	exportService := service.NewExportService(db, cfg.Export.Dir, cfg.Export.ChunkSize)
+	if err := exportService.ResumeInterrupted(); err != nil {
+		slog.Error("Failed to resume interrupted exports", "error", err)
+	}
+	exportHandler := handler.NewExportHandler(exportService, allowlistService)
+	partitionService := service.NewPartitionService(db)
+	adminHandler := handler.NewAdminHandler(tenantService, partitionService, migrationService, cfg.Delivery.StuckThreshold)
+	credentialService := service.NewCredentialService(db, rabbit, tenantService, tokenService, credentialsOf(cfg), loadCredentials, cfg.Security.RotationDrainTimeout)
+	credentialsHandler := handler.NewCredentialsHandler(credentialService)
+	allowlistHandler := handler.NewAllowlistHandler(allowlistService)
+	concurrencyHandler := handler.NewConcurrencyHandler(concurrencyService, auditLogger)
+	quotaHandler := handler.NewQuotaHandler(quotaService, auditLogger)
+	auditHandler := handler.NewAuditHandler(auditLogger)
+	redactionHandler := handler.NewRedactionHandler(redactionService)
+	dedupHandler := handler.NewDedupHandler(dedupService)
+	rateLimitHandler := handler.NewRateLimitHandler(rateLimitService)
+	schemaHandler := handler.NewSchemaHandler(schemaService)
+	sloHandler := handler.NewSLOHandler(sloService)
+	processorHandler := handler.NewProcessorHandler(processorService)
+	filterHandler := handler.NewFilterHandler(filterService)
+	retryHandler := handler.NewRetryHandler(retryService)
+	quarantineHandler := handler.NewQuarantineHandler(quarantineService)
+	dlqRetryHandler := handler.NewDLQRetryHandler(dlqRetryService)
+	eventHandler := handler.NewEventHandler(eventEmitter)
+	consoleHub := console.NewHub(cfg.Console.SendBuffer)
+	eventEmitter.Listen(consoleHub)
+	consoleHandler := handler.NewConsoleHandler(consoleHub, allowlistService, handler.ConsoleOptions{
+		OriginPatterns:   cfg.Console.OriginPatterns,
+		WriteTimeout:     cfg.Console.WriteTimeout,
+		PingInterval:     cfg.Console.PingInterval,
+		MaxSubscriptions: cfg.Console.MaxSubscriptions,
+	})
+	runtimeConfigHandler := handler.NewRuntimeConfigHandler(runtimeConfig)
+	consumerGroupHandler := handler.NewConsumerGroupHandler(service.NewConsumerGroupService(db))
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logging.Fatal("Invalid server.trusted_proxies", "error", err)
+	}
+
+	// Permukaan operator pindah ke listener sendiri bila server.admin.listen diisi
+	operatorRouter := router
+	var adminServer *http.Server
+	if cfg.Server.Admin.Listen != "" {
+		operatorRouter, adminServer, err = newAdminServer(cfg.Server.Admin)
+		if err != nil {
+			logging.Fatal("Failed to set up admin listener", "error", err)
+		}
+	}
+
+	router.Use(middleware.RequestID())
+	if cfg.Metrics.Enabled || cfg.Tracing.Enabled {
+		router.Use(middleware.RequestMetrics())
+	}
+	if cfg.Metrics.Enabled {
+		// OpenMetrics diperlukan agar exemplar trace_id ikut ter-scrape
+		operatorRouter.GET(cfg.Metrics.Path, gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})))
+	}
+
+	accessLog, err := middleware.NewAccessLog(middleware.AccessLogSettings{
+		SampleRate: cfg.Server.AccessLog.SampleRate,
+		Routes:     cfg.Server.AccessLog.Routes,
+	}, cfg.Server.AccessLog.MaxBodyBytes, cfg.Server.AccessLog.RedactFields)
+	if err != nil {
+		logging.Fatal("Invalid server.access_log", "error", err)
+	}
+	router.Use(accessLog.Gin())
+	if operatorRouter != router {
+		operatorRouter.Use(accessLog.Gin())
+	}
+	accessLogHandler := handler.NewAccessLogHandler(accessLog)
+
+	healthChecker := health.NewChecker()
+	if nats, ok := messaging.(*transport.NATS); ok {
+		healthChecker.AddReadiness("nats", func(context.Context) error {
+			if !nats.Connected() {
+				return errors.New("reconnecting to NATS")
+			}
+			return nil
+		})
+	}
+	if amqp10, ok := messaging.(*transport.AMQP10); ok {
+		healthChecker.AddReadiness("amqp10", func(context.Context) error {
+			if !amqp10.Connected() {
+				return errors.New("reconnecting to AMQP 1.0 broker")
+			}
+			return nil
+		})
+	}
+	if rabbit != nil {
+		healthChecker.AddReadiness("rabbitmq", func(context.Context) error {
+			// Koneksi yang putus disambung ulang ke node lain, restart tidak diperlukan
+			if !rabbit.Connected() {
+				return errors.New("reconnecting to broker")
+			}
+			return nil
+		})
+	}
+	healthChecker.AddReadiness("database", db.Ready)
+	healthChecker.AddReadiness("migrations", func(context.Context) error {
+		return migrationService.Ready()
+	})
+	healthChecker.AddReadiness("runtime_config", func(context.Context) error {
+		if !runtimeConfig.Loaded() {
+			return errors.New("runtime config not loaded yet")
+		}
+		return nil
+	})
+	healthChecker.AddReadiness("consumers", func(context.Context) error {
+		// Sebagian consumer berhenti cukup dilaporkan di /healthz/details; instance tidak siap jika semuanya berhenti
+		tenants, stopped := tenantService.StoppedConsumers()
+		if len(tenants) > 0 && len(stopped) == len(tenants) {
+			return fmt.Errorf("all %d tenant consumers stopped taking messages", len(tenants))
+		}
+		return nil
+	})
+	if timeout := cfg.Kubernetes.ConsumerStallTimeout; timeout > 0 {
+		healthChecker.AddLiveness("consumers", func(context.Context) error {
+			// Consumer yang tidak pulih sendiri dalam timeout hanya bisa diperbaiki dengan restart
+			if stalled := tenantService.StalledConsumers(timeout); len(stalled) > 0 {
+				return fmt.Errorf("consumers of %d tenants stopped taking messages more than %s ago", len(stalled), timeout)
+			}
+			return nil
+		})
+	}
+	addHealthComponents(healthChecker, db, rabbit, tenantService, singletons, exportService)
+	healthHandler := handler.NewHealthHandler(healthChecker)
+	router.GET("/healthz", healthHandler.Health)
+	router.GET("/healthz/details", healthHandler.Details)
+	router.GET("/livez", healthHandler.Live)
+	router.GET("/livez/:check", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+	router.GET("/readyz/:check", healthHandler.Ready)
 
 	// Swagger endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Auth endpoints
+	router.POST("/auth/refresh", authHandler.Refresh)
+
 	// API endpoints
-	router.POST("/tenants", tenantHandler.CreateTenant)
-	router.DELETE("/tenants/:id", tenantHandler.DeleteTenant)
-	router.PUT("/tenants/:id/config/concurrency", tenantHandler.UpdateConcurrency)
-	router.GET("/messages", messageHandler.ListMessages)
+	api := router.Group("/")
+	var authenticate gin.HandlerFunc
+	if cfg.Security.JWTSecret != "" {
+		authenticate = middleware.JWTAuth(tokenService)
+		singletons.Add("purge-revoked-tokens", func(ctx context.Context) {
+			purgeRevokedTokens(ctx, revocationStore, tenantTokenService)
+		})
+	}
+	if cfg.Security.APIKeys {
+		// Request tanpa X-API-Key tetap memakai JWT bila jwt_secret diisi
+		authenticate = middleware.APIKeyAuth(apiKeyService, authenticate)
+	}
+	if authenticate != nil {
+		api.Use(authenticate)
+	} else {
+		slog.Warn("security.jwt_secret is not set and security.api_keys is off, API authentication is disabled")
+	}
+	api.POST("/auth/revoke", authHandler.Revoke)
+	if cfg.Console.Enabled {
+		api.GET("/ws", consoleHandler.Serve)
+	}
+
+	// Batas bersama untuk endpoint baca pesan, agar worker tetap kebagian koneksi DB
+	messagesLimit := func(c *gin.Context) { c.Next() }
+	if shedding := cfg.Server.LoadShedding; shedding.MaxInFlight > 0 {
+		messagesLimit = middleware.ConcurrencyLimit(shedding.MaxInFlight, shedding.MaxQueued, shedding.QueueTimeout)
+	}
+	// ID di path divalidasi dulu, UUID yang rusak dijawab 400 alih-alih 500 dari Postgres
+	validID := middleware.UUIDParam("id")
+	api.GET("/tenants", tenantHandler.ListTenants)
+	api.POST("/tenants", tenantHandler.CreateTenant)
+	api.GET("/tenants/provisioning/:id", validID, tenantHandler.GetProvisioningJob)
+	api.GET("/audit", auditHandler.ListAudit)
+
+	// Tenant-scoped endpoints, restricted by the tenant's IP allowlist
+	tenantAPI := api.Group("/tenants/:id", validID, middleware.TenantBound(), middleware.IPAllowlist(allowlistService), middleware.ConfigChangeEvents(eventEmitter))
+	tenantAPI.GET("", tenantHandler.GetTenant)
+	tenantAPI.GET("/stats", tenantHandler.GetStats)
+	tenantAPI.DELETE("", tenantHandler.DeleteTenant)
+	tenantAPI.GET("/suspension", tenantHandler.GetSuspension)
+	tenantAPI.POST("/suspend", tenantHandler.SuspendTenant)
+	tenantAPI.POST("/resume", tenantHandler.ResumeTenant)
+	tenantAPI.PUT("/config/concurrency", tenantHandler.UpdateConcurrency)
+	tenantAPI.PUT("/config/ordering", tenantHandler.UpdateOrdering)
+	tenantAPI.PUT("/config/partition-key", tenantHandler.UpdatePartitionKey)
+	tenantAPI.GET("/config/priority", tenantHandler.GetPriority)
+	tenantAPI.PUT("/config/priority", tenantHandler.UpdatePriority)
+	tenantAPI.GET("/expired", tenantHandler.GetExpiredCount)
+	tenantAPI.POST("/recovery", tenantHandler.StartRecovery)
+	tenantAPI.GET("/recovery", tenantHandler.GetRecovery)
+	tenantAPI.DELETE("/recovery", tenantHandler.CancelRecovery)
+	tenantAPI.GET("/channels", tenantHandler.ListChannels)
+	tenantAPI.POST("/channels", tenantHandler.CreateChannel)
+	tenantAPI.GET("/channels/:name", tenantHandler.GetChannel)
+	tenantAPI.PUT("/channels/:name", tenantHandler.UpdateChannel)
+	tenantAPI.DELETE("/channels/:name", tenantHandler.DeleteChannel)
+	tenantAPI.GET("/consumer-groups", consumerGroupHandler.ListGroups)
+	tenantAPI.POST("/consumer-groups", consumerGroupHandler.CreateGroup)
+	tenantAPI.GET("/consumer-groups/:group", consumerGroupHandler.GetGroup)
+	tenantAPI.DELETE("/consumer-groups/:group", consumerGroupHandler.DeleteGroup)
+	tenantAPI.GET("/consumer-groups/:group/messages", messagesLimit, consumerGroupHandler.FetchMessages)
+	tenantAPI.POST("/consumer-groups/:group/commit", consumerGroupHandler.CommitOffset)
+	tenantAPI.POST("/consumer-groups/:group/seek", consumerGroupHandler.SeekOffset)
+	tenantAPI.GET("/runtime-config", runtimeConfigHandler.GetRuntimeConfig)
+	tenantAPI.PUT("/runtime-config/:key", runtimeConfigHandler.SetRuntimeConfig)
+	tenantAPI.DELETE("/runtime-config/:key", runtimeConfigHandler.DeleteRuntimeConfig)
+	tenantAPI.GET("/events", eventHandler.ListEvents)
+	tenantAPI.GET("/events/stream", eventHandler.StreamEvents)
+	tenantAPI.GET("/slo", sloHandler.GetSLO)
+	tenantAPI.PUT("/config/slo", sloHandler.UpdateSLO)
+	tenantAPI.GET("/config/processors", processorHandler.GetProcessors)
+	tenantAPI.PUT("/config/processors", processorHandler.UpdateProcessors)
+	tenantAPI.GET("/filters", filterHandler.GetRules)
+	tenantAPI.PUT("/filters", filterHandler.SetRules)
+	tenantAPI.POST("/filters/dry-run", filterHandler.DryRun)
+	tenantAPI.GET("/config/retry", retryHandler.GetPolicy)
+	tenantAPI.PUT("/config/retry", retryHandler.UpdatePolicy)
+	tenantAPI.GET("/config/dlq-retry", dlqRetryHandler.GetPolicy)
+	tenantAPI.PUT("/config/dlq-retry", dlqRetryHandler.UpdatePolicy)
+	tenantAPI.GET("/dlq/retries", dlqRetryHandler.ListAttempts)
+	tenantAPI.POST("/messages", tenantHandler.PublishMessage)
+	tenantAPI.POST("/messages/purge", retentionHandler.PurgeMessages)
+	tenantAPI.GET("/dlq", tenantHandler.ListDLQ)
+	tenantAPI.GET("/quarantine", quarantineHandler.ListQuarantine)
+	tenantAPI.POST("/dlq/redrive", tenantHandler.RedriveDLQ)
+	tenantAPI.POST("/dlq/replay", tenantHandler.ReplayDLQ)
+	if cfg.Security.JWTSecret != "" {
+		tenantAPI.GET("/tokens", tokenHandler.ListTokens)
+		tenantAPI.POST("/tokens", tokenHandler.MintToken)
+		tenantAPI.DELETE("/tokens/:token_id", tokenHandler.RevokeToken)
+	}
+	if cfg.Security.APIKeys {
+		tenantAPI.GET("/keys", apiKeyHandler.ListKeys)
+		tenantAPI.POST("/keys", apiKeyHandler.CreateKey)
+		tenantAPI.POST("/keys/:key_id/rotate", apiKeyHandler.RotateKey)
+		tenantAPI.DELETE("/keys/:key_id", apiKeyHandler.RevokeKey)
+	}
+	tenantAPI.GET("/webhooks", webhookHandler.ListWebhooks)
+	tenantAPI.POST("/webhooks", webhookHandler.CreateWebhook)
+	tenantAPI.DELETE("/webhooks/:webhook_id", webhookHandler.DeleteWebhook)
+	tenantAPI.GET("/webhooks/:webhook_id/deliveries", webhookHandler.ListDeliveries)
+	tenantAPI.GET("/config/dedup", dedupHandler.GetDedupConfig)
+	tenantAPI.PUT("/config/dedup", dedupHandler.UpdateDedupConfig)
+	tenantAPI.GET("/config/rate-limit", rateLimitHandler.GetRateLimit)
+	tenantAPI.PUT("/config/rate-limit", rateLimitHandler.UpdateRateLimit)
+	tenantAPI.GET("/config/retention", retentionHandler.GetRetention)
+	tenantAPI.PUT("/config/retention", retentionHandler.UpdateRetention)
+	if archiveService != nil {
+		tenantAPI.GET("/archives", archiveHandler.ListArchives)
+		tenantAPI.POST("/archives/:archive_id/restore", archiveHandler.RestoreArchive)
+	}
+	tenantAPI.GET("/ip-allowlist", allowlistHandler.GetAllowlist)
+	tenantAPI.PUT("/ip-allowlist", allowlistHandler.SetAllowlist)
+	tenantAPI.GET("/redaction-rules", redactionHandler.GetRules)
+	tenantAPI.PUT("/redaction-rules", redactionHandler.SetRules)
+	tenantAPI.POST("/redaction-rules/dry-run", redactionHandler.DryRun)
+	tenantAPI.GET("/schema", schemaHandler.GetTenantSchema)
+	tenantAPI.PUT("/schema", schemaHandler.SetTenantSchema)
+	tenantAPI.GET("/schemas", schemaHandler.ListSchemas)
+	tenantAPI.POST("/schemas/:type", schemaHandler.RegisterSchema)
+	tenantAPI.GET("/schemas/:type/versions/:version", schemaHandler.GetSchema)
+	tenantAPI.GET("/schemas/:type/usage", schemaHandler.GetSchemaUsage)
+
+	api.GET("/messages", messagesLimit, messageHandler.ListMessages)
+	api.GET("/messages/:id", messagesLimit, messageHandler.GetMessage)
+	api.POST("/exports", exportHandler.CreateExport)
+	api.GET("/exports/:id", validID, exportHandler.GetExport)
+	api.POST("/exports/:id/resume", validID, exportHandler.ResumeExport)
+	api.GET("/exports/:id/download", validID, exportHandler.DownloadExport)
+
+	// Admin endpoints
+	admin := api.Group("/admin")
+	if adminServer != nil {
+		admin = operatorRouter.Group("/admin")
+	}
+	admin.GET("/tenants/:id/deliveries", validID, adminHandler.ListDeliveries)
+	admin.POST("/tenants/:id/deliveries/stuck", validID, adminHandler.SettleStuckDeliveries)
+	admin.GET("/migrations", adminHandler.GetMigrations)
+	admin.GET("/processors", processorHandler.ListProcessors)
+	admin.POST("/tenant-migrations", tenantMigrationHandler.CreateMigration)
+	admin.GET("/tenant-migrations/:id", validID, tenantMigrationHandler.GetMigration)
+	admin.POST("/tenant-migrations/:id/resume", validID, tenantMigrationHandler.ResumeMigration)
+	admin.POST("/queues/rename", adminHandler.RenameQueues)
+	admin.POST("/credentials/rotate", credentialsHandler.RotateCredentials)
+	admin.GET("/access-log", accessLogHandler.GetSettings)
+	admin.PUT("/access-log", accessLogHandler.UpdateSettings)
+	admin.GET("/partitions", adminHandler.ListPartitions)
+	admin.POST("/partitions", adminHandler.CreatePartition)
+	admin.POST("/partitions/detach", adminHandler.DetachPartition)
+	admin.GET("/tenants/:id/ip-allowlist", validID, allowlistHandler.GetAllowlist)
+	admin.PUT("/tenants/:id/ip-allowlist", validID, allowlistHandler.SetAllowlist)
+	admin.GET("/tenants/:id/worker-bounds", validID, concurrencyHandler.GetWorkerBounds)
+	admin.PUT("/tenants/:id/worker-bounds", validID, concurrencyHandler.UpdateWorkerBounds)
+	admin.DELETE("/tenants/:id/worker-bounds", validID, concurrencyHandler.ResetWorkerBounds)
+	admin.GET("/tenants/:id/quota", validID, quotaHandler.GetQuota)
+	admin.PUT("/tenants/:id/quota", validID, quotaHandler.UpdateQuota)
+	admin.DELETE("/tenants/:id/quota", validID, quotaHandler.ResetQuota)
+	if handoverService != nil {
+		admin.GET("/consumers", handler.NewHandoverHandler(handoverService).ListConsumers)
+	}
 
 	server := &http.Server{
 		Addr:    cfg.Server.Port,
 		Handler: router,
 	}
+	// Event streams never end on their own; close them so Shutdown does not wait for them
+	server.RegisterOnShutdown(eventHandler.Close)
+	server.RegisterOnShutdown(consoleHub.Close)
+
+	var challengeServer *http.Server
+	if cfg.Server.Autocert.Enabled {
+		challengeServer, err = configureAutocert(server, cfg.Server.Autocert, accessLog)
+		if err != nil {
+			logging.Fatal("Failed to configure autocert", "error", err)
+		}
+	}
+
+	go singletons.Run(appCtx)
 
+	// SIGHUP membaca ulang credential tanpa restart
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		log.Printf("Server running on %s", cfg.Server.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+		for range hup {
+			if _, err := credentialService.Rotate(appCtx); err != nil {
+				slog.Error("Failed to rotate credentials", "error", err)
+			}
 		}
 	}()
 
+	if adminServer != nil {
+		go func() {
+			slog.Info("Admin listener running", "addr", adminServer.Addr)
+			var err error
+			if adminServer.TLSConfig != nil {
+				err = adminServer.ListenAndServeTLS("", "")
+			} else {
+				err = adminServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				logging.Fatal("Admin listener error", "error", err)
+			}
+		}()
+	}
+
+	listeners, err := openListeners(cfg.Server)
+	if err != nil {
+		logging.Fatal("Failed to listen", "error", err)
+	}
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			slog.Info("Server running", "network", listener.Addr().Network(), "addr", listener.Addr().String())
+			var err error
+			if server.TLSConfig != nil {
+				err = server.ServeTLS(listener, "", "")
+			} else {
+				err = server.Serve(listener)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				logging.Fatal("Server error", "error", err)
+			}
+		}(listener)
+	}
+
+	var grpcServer *grpcapi.Server
+	if cfg.Server.GRPC.Listen != "" {
+		var apiTokens *auth.TokenService
+		if cfg.Security.JWTSecret != "" {
+			apiTokens = tokenService
+		}
+		var apiKeys *service.APIKeyService
+		if cfg.Security.APIKeys {
+			apiKeys = apiKeyService
+		}
+		grpcServer, err = newGRPCServer(cfg.Server.GRPC, tenantService, messageService, apiTokens, apiKeys, allowlistService, auditLogger, healthChecker)
+		if err != nil {
+			logging.Fatal("Failed to set up gRPC server", "error", err)
+		}
+		grpcListener, err := net.Listen("tcp", cfg.Server.GRPC.Listen)
+		if err != nil {
+			logging.Fatal("Failed to listen for gRPC", "error", err)
+		}
+		go func() {
+			slog.Info("gRPC server running", "addr", grpcListener.Addr().String())
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				logging.Fatal("gRPC server error", "error", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server...")
+
+	healthChecker.Shutdown()
+	if cfg.Kubernetes.ShutdownDelay > 0 {
+		// Beri waktu endpoint controller mengeluarkan pod sebelum listener ditutup
+		time.Sleep(cfg.Kubernetes.ShutdownDelay)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+	}
+	if challengeServer != nil {
+		challengeServer.Shutdown(ctx)
+	}
+	if adminServer != nil {
+		adminServer.Shutdown(ctx)
+	}
+	if grpcServer != nil {
+		grpcServer.Shutdown(ctx)
+	}
+
+	// Export yang sedang berjalan dilanjutkan dari checkpoint saat start berikutnya
+	exportService.Shutdown()
+	tenantMigrationService.Shutdown()
+	stopApp()
+	if handoverService != nil {
+		// Selesaikan pesan yang sedang diproses lalu lepas tenant untuk instance lain
+		if err := handoverService.Release(ctx); err != nil {
+			slog.Error("Failed to release tenants", "error", err)
+		}
+	} else {
+		// Hentikan consumer dan tunggu pesan yang sedang diproses di-ack sebelum koneksi ditutup
+		tenantService.DrainConsumers(ctx)
+	}
+
+	// Waktu sendiri supaya span terakhir tetap terkirim walau drain memakai seluruh timeout
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := stopTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Server exiting")
+}
+
+// migrateSchema applies the pending migrations, reverts the last steps of
+// them or prints the schema status, depending on action
+func migrateSchema(cfg *config.Config, migrations *migrate.Runner, action string, steps int) error {
+	switch action {
+	case migrateStatus:
+		status, err := migrations.Status()
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(status)
+	case migrateDown:
+		run := metrics.StartBatch(cfg.Metrics.PushgatewayURL, "salva_migrate_down", nil)
+		err := migrations.Down(steps)
+		run.Finish(err)
+		return err
+	default:
+		run := metrics.StartBatch(cfg.Metrics.PushgatewayURL, "salva_migrate", nil)
+		err := migrations.Up()
+		run.Finish(err)
+		if err == nil {
+			slog.Info("Database schema is up to date")
+		}
+		return err
+	}
+}
+
+// openListeners opens the API's TCP port, its Unix socket and the sockets
+// passed by systemd socket activation, whichever are configured
+func openListeners(cfg config.ServerConfig) ([]net.Listener, error) {
+	listeners, err := systemdListeners()
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) ([]net.Listener, error) {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+		return nil, err
+	}
+
+	if cfg.Port != "" {
+		listener, err := net.Listen("tcp", cfg.Port)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	if cfg.UnixSocket != "" {
+		mode, err := strconv.ParseUint(cfg.UnixSocketMode, 8, 32)
+		if err != nil {
+			return fail(fmt.Errorf("invalid server.unix_socket_mode %q", cfg.UnixSocketMode))
+		}
+		// Socket sisa proses sebelumnya menghalangi bind
+		if info, err := os.Stat(cfg.UnixSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(cfg.UnixSocket)
+		}
+		listener, err := net.Listen("unix", cfg.UnixSocket)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, listener)
+		if err := os.Chmod(cfg.UnixSocket, os.FileMode(mode)); err != nil {
+			return fail(err)
+		}
+	}
+
+	if len(listeners) == 0 {
+		return nil, errors.New("no listener: set server.port, server.unix_socket or start via systemd socket activation")
+	}
+	return listeners, nil
+}
+
+// systemdListeners returns the sockets systemd passed to this process
+// (LISTEN_FDS), starting at file descriptor 3. Restarting the service keeps
+// them open in systemd, so no connection is refused in between.
+func systemdListeners() ([]net.Listener, error) {
+	const firstFD = 3
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if pid != os.Getpid() || count <= 0 {
+		return nil, nil
 	}
+	// Jangan wariskan ke proses anak
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
 
-	log.Println("Server exiting")
+	listeners := make([]net.Listener, 0, count)
+	for fd := firstFD; fd < firstFD+count; fd++ {
+		syscall.CloseOnExec(fd)
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("systemd-socket-%d", fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("systemd socket %d: %w", fd, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// newAdminServer creates the router and server of the separate admin
+// listener. Requests need a client certificate signed by tls.client_ca_file
+// or one of the admin tokens; pprof is served there only.
+func newAdminServer(cfg config.AdminConfig) (*gin.Engine, *http.Server, error) {
+	router := gin.New()
+	router.Use(gin.Logger(), gin.Recovery(), middleware.AdminAuth(cfg.Tokens))
+	if cfg.Pprof {
+		router.GET("/debug/pprof/*profile", func(c *gin.Context) {
+			switch c.Param("profile") {
+			case "/cmdline":
+				pprof.Cmdline(c.Writer, c.Request)
+			case "/profile":
+				pprof.Profile(c.Writer, c.Request)
+			case "/symbol":
+				pprof.Symbol(c.Writer, c.Request)
+			case "/trace":
+				pprof.Trace(c.Writer, c.Request)
+			default:
+				// Index juga melayani profil bernama seperti heap dan goroutine
+				pprof.Index(c.Writer, c.Request)
+			}
+		})
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if cfg.TLS.CertFile == "" {
+		return router, server, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load admin certificate: %w", err)
+	}
+	server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if cfg.TLS.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read admin client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates in %s", cfg.TLS.ClientCAFile)
+		}
+		server.TLSConfig.ClientCAs = pool
+		server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if len(cfg.Tokens) > 0 {
+			// Klien tanpa sertifikat masih bisa memakai token
+			server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return router, server, nil
+}
+
+// newGRPCServer creates the gRPC server of the tenant and message operations,
+// over TLS when a certificate is configured
+func newGRPCServer(cfg config.GRPCConfig, tenantService *service.TenantService, messageService *service.MessageService, tokens *auth.TokenService, apiKeys *service.APIKeyService, allowlists *service.AllowlistService, auditLogger *audit.Logger, checker *health.Checker) (*grpcapi.Server, error) {
+	var opts []grpc.ServerOption
+	if cfg.CertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gRPC certificate: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	return grpcapi.NewServer(tenantService, messageService, tokens, apiKeys, allowlists, auditLogger, checker, cfg.Reflection, opts...)
+}
+
+// configureAutocert sets up ACME certificates for server and starts the
+// HTTP-01 challenge listener, which also redirects plain HTTP to HTTPS
+func configureAutocert(server *http.Server, cfg config.AutocertConfig, accessLog *middleware.AccessLog) (*http.Server, error) {
+	if len(cfg.Domains) == 0 {
+		return nil, fmt.Errorf("server.autocert.domains must not be empty")
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		Email:      cfg.Email,
+	}
+	server.TLSConfig = manager.TLSConfig()
+
+	challengeServer := &http.Server{
+		Addr:              cfg.HTTPAddr,
+		Handler:           accessLog.Wrap("acme", manager.HTTPHandler(nil)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("ACME challenge listener running", "addr", cfg.HTTPAddr)
+		if err := challengeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.Fatal("ACME challenge listener error", "error", err)
+		}
+	}()
+
+	return challengeServer, nil
+}
+
+// configureTenantLabels applies the tenant label cardinality policy
+func configureTenantLabels(cfg config.TenantLabelsPolicyConfig) error {
+	overrides := make(map[string]metrics.TenantLabelPolicy, len(cfg.Overrides))
+	for metric, override := range cfg.Overrides {
+		// Override top_k tanpa K memakai K default
+		if override.TopK == 0 {
+			override.TopK = cfg.TopK
+		}
+		overrides[metric] = metrics.TenantLabelPolicy{Mode: override.Policy, TopK: override.TopK}
+	}
+	return metrics.ConfigureTenantLabels(metrics.TenantLabelPolicy{Mode: cfg.Policy, TopK: cfg.TopK}, overrides)
+}
+
+// newAuditSink creates the configured audit forwarding sink, or nil if none
+func newAuditSink(cfg config.AuditConfig) (audit.Sink, error) {
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "syslog":
+		return audit.NewSyslogSink(cfg.Syslog.Network, cfg.Syslog.Address, cfg.Format)
+	case "http":
+		if cfg.HTTP.URL == "" {
+			return nil, fmt.Errorf("audit.http.url is required for the http sink")
+		}
+		return audit.NewHTTPSink(cfg.HTTP.URL, cfg.Format), nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", cfg.Sink)
+	}
+}
+
+// credentialsOf picks the credentials that can be rotated at runtime out of cfg
+func credentialsOf(cfg *config.Config) service.Credentials {
+	return service.Credentials{
+		DatabaseURL: cfg.Database.URL,
+		Broker:      strings.Join([]string{cfg.RabbitMQ.URL, strings.Join(cfg.RabbitMQ.URLs, ","), cfg.RabbitMQ.SRVName}, "|"),
+		BrokerNodes: brokerNodes(cfg.RabbitMQ),
+		JWTSecret:   cfg.Security.JWTSecret,
+	}
+}
+
+// loadCredentials reads the config again for a credential rotation
+func loadCredentials() (service.Credentials, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return service.Credentials{}, err
+	}
+	return credentialsOf(cfg), nil
+}
+
+// brokerNodes returns the RabbitMQ nodes to dial: the SRV record's targets,
+// or rabbitmq.urls, or rabbitmq.url alone
+func brokerNodes(cfg config.RabbitMQConfig) repository.NodeSource {
+	if cfg.SRVName != "" {
+		return repository.SRVNodes(cfg.SRVName, cfg.URL)
+	}
+	if len(cfg.URLs) > 0 {
+		return repository.StaticNodes(cfg.URLs...)
+	}
+	return repository.StaticNodes(cfg.URL)
+}
+
+// newRuntimeConfigBackend creates the backend tenant runtime config is stored
+// in, or nil when it is disabled
+func newRuntimeConfigBackend(cfg config.DynamicConfig, db *repository.Database) (dynconfig.Backend, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "postgres":
+		return dynconfig.NewPostgresBackend(db), nil
+	case "etcd":
+		if len(cfg.Etcd.Endpoints) == 0 {
+			return nil, fmt.Errorf("dynamic_config.etcd.endpoints is required for the etcd backend")
+		}
+		return dynconfig.NewEtcdBackend(cfg.Etcd.Endpoints, cfg.Etcd.Username, cfg.Etcd.Password, cfg.Prefix, cfg.Etcd.DialTimeout)
+	case "consul":
+		if cfg.Consul.Address == "" {
+			return nil, fmt.Errorf("dynamic_config.consul.address is required for the consul backend")
+		}
+		return dynconfig.NewConsulBackend(cfg.Consul.Address, cfg.Consul.Token, cfg.Prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown dynamic_config backend %q", cfg.Backend)
+	}
+}
+
+// purgeRevokedTokens periodically drops revocation entries of expired tokens
+func purgeRevokedTokens(ctx context.Context, store *auth.PostgresRevocationStore, tenantTokens *service.TenantTokenService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := store.PurgeExpired(); err != nil {
+			slog.Error("Failed to purge revoked tokens", "error", err)
+		} else if n > 0 {
+			slog.Info("Purged expired revoked tokens", "count", n)
+		}
+		if _, err := tenantTokens.PurgeExpired(); err != nil {
+			slog.Error("Failed to purge expired tenant tokens", "error", err)
+		}
+	}
+}
+
+// addHealthComponents registers the components of GET /healthz/details
+func addHealthComponents(checker *health.Checker, db *repository.Database, rabbit *repository.RabbitMQ, tenantService *service.TenantService, singletons *kube.Singletons, exports *service.ExportService) {
+	checker.AddComponent("database", func(ctx context.Context) (map[string]any, error) {
+		stats := db.DB.Stats()
+		details := map[string]any{
+			"open":       stats.OpenConnections,
+			"in_use":     stats.InUse,
+			"idle":       stats.Idle,
+			"max_open":   stats.MaxOpenConnections,
+			"wait_count": stats.WaitCount,
+			"wait_ms":    stats.WaitDuration.Milliseconds(),
+		}
+		if err := db.Ready(ctx); err != nil {
+			return details, err
+		}
+		if err := db.DB.PingContext(ctx); err != nil {
+			return details, err
+		}
+		if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			return details, health.Degraded(errors.New("connection pool exhausted"))
+		}
+		return details, nil
+	})
+
+	var connections []repository.ConnectionState
+	if rabbit != nil {
+		connections = rabbit.Connections()
+	}
+	for i := range connections {
+		checker.AddComponent(fmt.Sprintf("rabbitmq_connection_%d", i), func(context.Context) (map[string]any, error) {
+			states := rabbit.Connections()
+			if i >= len(states) {
+				return nil, errors.New("connection no longer in the pool")
+			}
+			state := states[i]
+			details := map[string]any{"node": state.Node, "channels": state.Channels}
+			if state.Open {
+				return details, nil
+			}
+			// Kanal baru masih bisa dibuka di koneksi lain yang terbuka
+			for _, other := range states {
+				if other.Open {
+					return details, health.Degraded(errors.New("reconnecting, channels go to the other connections"))
+				}
+			}
+			return details, errors.New("reconnecting, no broker connection is open")
+		})
+	}
+
+	checker.AddComponent("consumers", func(context.Context) (map[string]any, error) {
+		tenants, stopped := tenantService.StoppedConsumers()
+		details := map[string]any{"tenants": len(tenants), "stopped": len(stopped)}
+		switch {
+		case len(stopped) == 0:
+			return details, nil
+		case len(stopped) == len(tenants):
+			return details, fmt.Errorf("all %d tenant consumers stopped taking messages", len(tenants))
+		default:
+			return details, health.Degraded(fmt.Errorf("%d of %d tenant consumers stopped taking messages", len(stopped), len(tenants)))
+		}
+	})
+
+	checker.AddComponent("scheduler", func(context.Context) (map[string]any, error) {
+		leader := singletons.Leader()
+		details := map[string]any{"leader": leader, "is_leader": singletons.IsLeader()}
+		if stopped := singletons.Stopped(); len(stopped) > 0 {
+			details["stopped_jobs"] = stopped
+		}
+		if leader == "" {
+			return details, health.Degraded(errors.New("no instance holds the singleton lease"))
+		}
+		return details, nil
+	})
+
+	checker.AddComponent("exports", func(ctx context.Context) (map[string]any, error) {
+		status, err := exports.Status(ctx)
+		details := map[string]any{"running": status.Running}
+		if status.LastFailure != nil {
+			details["last_failure"] = status.LastFailure
+		}
+		return details, err
+	})
+}
+
+// purgeEvents periodically drops system events older than retention
+func purgeEvents(ctx context.Context, emitter *events.Emitter, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := emitter.Purge(retention); err != nil {
+			slog.Error("Failed to purge events", "error", err)
+		} else if n > 0 {
+			slog.Info("Purged events", "count", n)
+		}
+	}
 }
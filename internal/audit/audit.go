@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Audited actions
+const (
+	ActionTenantCreate       = "tenant.create"
+	ActionTenantDelete       = "tenant.delete"
+	ActionTenantSuspend      = "tenant.suspend"
+	ActionTenantResume       = "tenant.resume"
+	ActionConcurrencyUpdate  = "tenant.concurrency_update"
+	ActionOrderingUpdate     = "tenant.ordering_update"
+	ActionPartitionKeyUpdate = "tenant.partition_key_update"
+	ActionPriorityUpdate     = "tenant.priority_update"
+	ActionWorkerBoundsUpdate = "tenant.worker_bounds_update"
+	ActionQuotaUpdate        = "tenant.quota_update"
+	ActionChannelCreate      = "tenant.channel_create"
+	ActionChannelUpdate      = "tenant.channel_update"
+	ActionChannelDelete      = "tenant.channel_delete"
+	ActionRecoveryStart      = "tenant.recovery_start"
+	ActionRecoveryCancel     = "tenant.recovery_cancel"
+	ActionDLQRedrive         = "tenant.dlq_redrive"
+	ActionDLQReplay          = "tenant.dlq_replay"
+	ActionTokenMint          = "tenant.token_mint"
+	ActionTokenRevoke        = "tenant.token_revoke"
+	ActionAPIKeyCreate       = "tenant.api_key_create"
+	ActionAPIKeyRotate       = "tenant.api_key_rotate"
+	ActionAPIKeyRevoke       = "tenant.api_key_revoke"
+	ActionWebhookCreate      = "tenant.webhook_create"
+	ActionWebhookDelete      = "tenant.webhook_delete"
+	ActionMessagePurge       = "tenant.messages_purge"
+	ActionArchiveRestore     = "tenant.archive_restore"
+)
+
+// AnonymousActor is recorded when authentication is disabled
+const AnonymousActor = "anonymous"
+
+// Entry is a single audit record
+type Entry struct {
+	ID       string                 `json:"id"`
+	Actor    string                 `json:"actor"`
+	Action   string                 `json:"action"`
+	TenantID string                 `json:"tenant_id,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	// Old and New are the values the operation changed, before and after
+	Old       map[string]interface{} `json:"old,omitempty"`
+	New       map[string]interface{} `json:"new,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Sink receives audit entries in near-real-time, e.g. a syslog or HTTP collector
+type Sink interface {
+	Send(entry Entry) error
+	Close() error
+}
+
+// Logger stores audit entries in Postgres and forwards them to an optional sink
+type Logger struct {
+	db      *repository.Database
+	sink    Sink
+	entries chan Entry
+	wg      sync.WaitGroup
+}
+
+// NewLogger creates a Logger. sink may be nil to only store entries.
+func NewLogger(db *repository.Database, sink Sink) *Logger {
+	l := &Logger{db: db, sink: sink}
+	if sink != nil {
+		l.entries = make(chan Entry, 1024)
+		l.wg.Add(1)
+		go l.forward()
+	}
+	return l
+}
+
+// Record stores an audit entry and queues it for the sink. Failures are
+// logged rather than returned so auditing never fails the audited operation.
+func (l *Logger) Record(actor, action, tenantID string, details map[string]interface{}) {
+	l.RecordChange(actor, action, tenantID, details, nil, nil)
+}
+
+// RecordChange records an entry like Record, along with the values the
+// operation changed, as they were before it and after it. Keys whose value
+// did not change are left out of both.
+func (l *Logger) RecordChange(actor, action, tenantID string, details, before, after map[string]interface{}) {
+	if actor == "" {
+		actor = AnonymousActor
+	}
+	before, after = changedValues(before, after)
+	entry := Entry{
+		ID:        uuid.New().String(),
+		Actor:     actor,
+		Action:    action,
+		TenantID:  tenantID,
+		Details:   details,
+		Old:       before,
+		New:       after,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var tenant interface{}
+	if tenantID != "" {
+		tenant = tenantID
+	}
+	if _, err := l.db.DB.Exec(`
+		INSERT INTO audit_logs (id, actor, action, tenant_id, details, old_values, new_values, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, entry.ID, entry.Actor, entry.Action, tenant, encodeValues(entry, details), encodeValues(entry, before), encodeValues(entry, after), entry.CreatedAt); err != nil {
+		slog.Error("Failed to store audit entry", "action", entry.Action, "tenant_id", tenantID, "error", err)
+	}
+
+	if l.entries != nil {
+		select {
+		case l.entries <- entry:
+		default:
+			slog.Warn("Audit sink queue full, dropping forward of entry", "entry_id", entry.ID)
+		}
+	}
+}
+
+// Close flushes queued entries to the sink and closes it
+func (l *Logger) Close() {
+	if l.entries == nil {
+		return
+	}
+	close(l.entries)
+	l.wg.Wait()
+	if err := l.sink.Close(); err != nil {
+		slog.Error("Failed to close audit sink", "error", err)
+	}
+}
+
+// changedValues drops the keys whose value is the same before and after. A
+// nil side, e.g. before a tenant was created, is kept as nil.
+func changedValues(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	if before == nil || after == nil {
+		return before, after
+	}
+	changedBefore := make(map[string]interface{})
+	changedAfter := make(map[string]interface{})
+	for key, value := range before {
+		if next, ok := after[key]; !ok || !reflect.DeepEqual(value, next) {
+			changedBefore[key] = value
+		}
+	}
+	for key, value := range after {
+		if prev, ok := before[key]; !ok || !reflect.DeepEqual(prev, value) {
+			changedAfter[key] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// encodeValues encodes values for a JSONB column, NULL for nil
+func encodeValues(entry Entry, values map[string]interface{}) interface{} {
+	if values == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		slog.Error("Failed to encode audit values", "action", entry.Action, "error", err)
+		return nil
+	}
+	return encoded
+}
+
+func (l *Logger) forward() {
+	defer l.wg.Done()
+	for entry := range l.entries {
+		if err := l.sink.Send(entry); err != nil {
+			slog.Error("Failed to forward audit entry", "entry_id", entry.ID, "error", err)
+		}
+	}
+}
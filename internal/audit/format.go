@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Output formats for sinks
+const (
+	FormatJSON = "json"
+	FormatCEF  = "cef"
+)
+
+// Format renders an entry as a single line in the given format
+func Format(entry Entry, format string) ([]byte, error) {
+	switch format {
+	case FormatCEF:
+		return []byte(formatCEF(entry)), nil
+	case FormatJSON, "":
+		return json.Marshal(entry)
+	default:
+		return nil, fmt.Errorf("unknown audit format %q", format)
+	}
+}
+
+// formatCEF renders an entry as ArcSight Common Event Format
+func formatCEF(entry Entry) string {
+	details, _ := json.Marshal(entry.Details)
+
+	extensions := []string{
+		"rt=" + fmt.Sprint(entry.CreatedAt.UnixMilli()),
+		"suser=" + cefExtension(entry.Actor),
+		"externalId=" + cefExtension(entry.ID),
+	}
+	if entry.TenantID != "" {
+		extensions = append(extensions, "cs1Label=tenantId", "cs1="+cefExtension(entry.TenantID))
+	}
+	if entry.Details != nil {
+		extensions = append(extensions, "msg="+cefExtension(string(details)))
+	}
+	if entry.Old != nil {
+		values, _ := json.Marshal(entry.Old)
+		extensions = append(extensions, "cs2Label=oldValues", "cs2="+cefExtension(string(values)))
+	}
+	if entry.New != nil {
+		values, _ := json.Marshal(entry.New)
+		extensions = append(extensions, "cs3Label=newValues", "cs3="+cefExtension(string(values)))
+	}
+
+	return fmt.Sprintf("CEF:0|salva|multi-tenant-messaging|1.0|%s|%s|%d|%s",
+		cefHeader(entry.Action), cefHeader(entry.Action), cefSeverity(entry.Action), strings.Join(extensions, " "))
+}
+
+func cefSeverity(action string) int {
+	if action == ActionTenantDelete || action == ActionMessagePurge {
+		return 7
+	}
+	return 3
+}
+
+func cefHeader(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`).Replace(value)
+}
+
+func cefExtension(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
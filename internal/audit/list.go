@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidFilter is returned for an invalid audit list cursor, limit or tenant ID
+var ErrInvalidFilter = errors.New("invalid audit filter")
+
+// Audit list page sizes
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Filter selects audit entries; empty fields match every entry
+type Filter struct {
+	Actor    string
+	Action   string
+	TenantID string
+	Since    time.Time
+	Until    time.Time
+	// Cursor is the ID of the last entry of the previous page
+	Cursor string
+	Limit  int
+}
+
+// List returns a page of the entries matching filter, newest first. The
+// returned cursor is empty on the last page.
+func (l *Logger) List(ctx context.Context, filter Filter) ([]Entry, string, error) {
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxListLimit)
+	}
+	if filter.Cursor != "" {
+		if _, err := uuid.Parse(filter.Cursor); err != nil {
+			return nil, "", fmt.Errorf("%w: invalid cursor", ErrInvalidFilter)
+		}
+	}
+	if filter.TenantID != "" {
+		if _, err := uuid.Parse(filter.TenantID); err != nil {
+			return nil, "", fmt.Errorf("%w: invalid tenant_id", ErrInvalidFilter)
+		}
+	}
+
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TenantID != "" {
+		add("tenant_id = $%d", filter.TenantID)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+	if filter.Cursor != "" {
+		add("(created_at, id) < (SELECT created_at, id FROM audit_logs WHERE id = $%d)", filter.Cursor)
+	}
+	args = append(args, limit)
+
+	rows, err := l.db.DB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, actor, action, COALESCE(tenant_id::text, ''), details, old_values, new_values, created_at
+		FROM audit_logs
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var entry Entry
+		var details, before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.TenantID, &details, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		for _, column := range []struct {
+			data   []byte
+			values *map[string]interface{}
+		}{{details, &entry.Details}, {before, &entry.Old}, {after, &entry.New}} {
+			if column.data == nil {
+				continue
+			}
+			if err := json.Unmarshal(column.data, column.values); err != nil {
+				return nil, "", err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(entries) == limit {
+		next = entries[len(entries)-1].ID
+	}
+	return entries, next, nil
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"time"
+)
+
+// SyslogSink writes entries to a local or remote syslog daemon
+type SyslogSink struct {
+	writer *syslog.Writer
+	format string
+}
+
+// NewSyslogSink dials syslog. An empty network and address uses the local daemon.
+func NewSyslogSink(network, address, format string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_NOTICE|syslog.LOG_AUTH, "salva-audit")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &SyslogSink{writer: w, format: format}, nil
+}
+
+func (s *SyslogSink) Send(entry Entry) error {
+	line, err := Format(entry, s.format)
+	if err != nil {
+		return err
+	}
+	return s.writer.Notice(string(line))
+}
+
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
+
+// HTTPSink POSTs each entry to an HTTP collector
+type HTTPSink struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+func NewHTTPSink(url, format string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		format: format,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (s *HTTPSink) Send(entry Entry) error {
+	body, err := Format(entry, s.format)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+	if s.format == FormatCEF {
+		contentType = "text/plain"
+	}
+	resp, err := s.client.Post(s.url, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPSink) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"time"
+
+	"multi-tenant-messaging/internal/repository"
+)
+
+// RevocationStore keeps the jti of revoked tokens until they expire
+type RevocationStore interface {
+	Revoke(jti string, expiresAt time.Time) error
+	// RevokeOnce revokes jti and reports whether this call did, so of two
+	// concurrent calls for the same jti only one gets true
+	RevokeOnce(jti string, expiresAt time.Time) (bool, error)
+	IsRevoked(jti string) (bool, error)
+}
+
+// PostgresRevocationStore stores revoked token IDs in the revoked_tokens table
+type PostgresRevocationStore struct {
+	db *repository.Database
+}
+
+func NewPostgresRevocationStore(db *repository.Database) *PostgresRevocationStore {
+	return &PostgresRevocationStore{db: db}
+}
+
+func (s *PostgresRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	_, err := s.db.DB.Exec(`
+		INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt)
+	return err
+}
+
+func (s *PostgresRevocationStore) RevokeOnce(jti string, expiresAt time.Time) (bool, error) {
+	res, err := s.db.DB.Exec(`
+		INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	return inserted == 1, err
+}
+
+func (s *PostgresRevocationStore) IsRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := s.db.DB.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti,
+	).Scan(&revoked)
+	return revoked, err
+}
+
+// PurgeExpired removes entries whose tokens have expired anyway
+func (s *PostgresRevocationStore) PurgeExpired() (int64, error) {
+	res, err := s.db.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Roles carried in the "role" claim
+const (
+	// RoleAdmin may call every route, for every tenant
+	RoleAdmin = "admin"
+	// RoleTenantOperator manages the resources of the tenant it is bound to
+	RoleTenantOperator = "tenant-operator"
+	// RoleReader may only read, of its tenant if it is bound to one
+	RoleReader = "reader"
+)
+
+// ErrInvalidRole is returned when a token is requested with an unknown role
+// or a role that does not fit its tenant binding
+var ErrInvalidRole = errors.New("invalid role")
+
+// adminRoutes are only open to admins, as "METHOD /gin/route": creating,
+// deleting, suspending and resuming tenants, looking at other tenants'
+// provisioning jobs and reading the audit log. Routes under /admin/ and
+// /exports are admin-only as well.
+var adminRoutes = []string{
+	"POST /tenants",
+	"DELETE /tenants/:id",
+	"POST /tenants/:id/suspend",
+	"POST /tenants/:id/resume",
+	"GET /tenants/provisioning/:id",
+	"GET /audit",
+}
+
+// readerWrites are the non-GET routes a reader may still call
+var readerWrites = []string{"POST /auth/revoke"}
+
+// ValidateRole checks that role exists and fits a token bound to tenantID:
+// admins are never bound to a tenant and tenant operators always are. An
+// empty role is valid and derived from the binding.
+func ValidateRole(role, tenantID string) error {
+	switch role {
+	case "", RoleReader:
+		return nil
+	case RoleAdmin:
+		if tenantID != "" {
+			return fmt.Errorf("%w: %s tokens cannot be bound to a tenant", ErrInvalidRole, RoleAdmin)
+		}
+		return nil
+	case RoleTenantOperator:
+		if tenantID == "" {
+			return fmt.Errorf("%w: %s tokens must be bound to a tenant", ErrInvalidRole, RoleTenantOperator)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+}
+
+// EffectiveRole is the token's role. Tokens issued without one are admins
+// when not bound to a tenant and tenant operators otherwise.
+func (c *Claims) EffectiveRole() string {
+	if c.Role != "" {
+		return c.Role
+	}
+	if c.TenantID == "" {
+		return RoleAdmin
+	}
+	return RoleTenantOperator
+}
+
+// RoleAllows reports whether the token's role may call route with method.
+// Which tenant's resources it reaches is checked by the tenant binding.
+func (c *Claims) RoleAllows(method, route string) bool {
+	role := c.EffectiveRole()
+	if role == RoleAdmin {
+		return true
+	}
+	if isAdminRoute(method, route) {
+		return false
+	}
+	if role == RoleReader {
+		return method == "GET" || method == "HEAD" || slices.Contains(readerWrites, method+" "+route)
+	}
+	return role == RoleTenantOperator
+}
+
+func isAdminRoute(method, route string) bool {
+	if strings.HasPrefix(route, "/admin/") || route == "/exports" || strings.HasPrefix(route, "/exports/") {
+		return true
+	}
+	return slices.Contains(adminRoutes, method+" "+route)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRole(t *testing.T) {
+	tenantID := "5f0c6d8e-8c1e-4f0b-9a57-2f4d1a3b7c11"
+
+	assert.NoError(t, ValidateRole("", ""))
+	assert.NoError(t, ValidateRole("", tenantID))
+	assert.NoError(t, ValidateRole(RoleReader, ""))
+	assert.NoError(t, ValidateRole(RoleReader, tenantID))
+	assert.NoError(t, ValidateRole(RoleAdmin, ""))
+	assert.NoError(t, ValidateRole(RoleTenantOperator, tenantID))
+
+	assert.ErrorIs(t, ValidateRole(RoleAdmin, tenantID), ErrInvalidRole)
+	assert.ErrorIs(t, ValidateRole(RoleTenantOperator, ""), ErrInvalidRole)
+	assert.ErrorIs(t, ValidateRole("owner", ""), ErrInvalidRole)
+}
+
+func TestEffectiveRole(t *testing.T) {
+	assert.Equal(t, RoleAdmin, (&Claims{}).EffectiveRole())
+	assert.Equal(t, RoleTenantOperator, (&Claims{TenantID: "t"}).EffectiveRole())
+	assert.Equal(t, RoleReader, (&Claims{TenantID: "t", Role: RoleReader}).EffectiveRole())
+}
+
+func TestRoleAllows(t *testing.T) {
+	admin := &Claims{Role: RoleAdmin}
+	operator := &Claims{TenantID: "t", Role: RoleTenantOperator}
+	reader := &Claims{TenantID: "t", Role: RoleReader}
+
+	tests := []struct {
+		method, route           string
+		admin, operator, reader bool
+	}{
+		{"GET", "/tenants/:id", true, true, true},
+		{"PUT", "/tenants/:id/config/concurrency", true, true, false},
+		{"POST", "/tenants/:id/messages", true, true, false},
+		{"POST", "/auth/revoke", true, true, true},
+		{"POST", "/tenants", true, false, false},
+		{"DELETE", "/tenants/:id", true, false, false},
+		{"POST", "/tenants/:id/suspend", true, false, false},
+		{"GET", "/audit", true, false, false},
+		{"GET", "/admin/deliveries/stuck", true, false, false},
+		{"POST", "/exports", true, false, false},
+		{"GET", "/exports/:id/download", true, false, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.admin, admin.RoleAllows(tt.method, tt.route), "admin %s %s", tt.method, tt.route)
+		assert.Equal(t, tt.operator, operator.RoleAllows(tt.method, tt.route), "operator %s %s", tt.method, tt.route)
+		assert.Equal(t, tt.reader, reader.RoleAllows(tt.method, tt.route), "reader %s %s", tt.method, tt.route)
+	}
+
+	// A token with an unknown role may not call anything
+	assert.False(t, (&Claims{TenantID: "t", Role: "owner"}).RoleAllows("GET", "/tenants/:id"))
+}
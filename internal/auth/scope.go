@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+)
+
+// Scopes a tenant admin can grant to the sub-tokens it mints
+const (
+	ScopeMessagesRead = "messages:read"
+	ScopeEventsRead   = "events:read"
+	ScopeSLORead      = "slo:read"
+	ScopeDLQRead      = "dlq:read"
+)
+
+// ErrUnknownScope is returned when a sub-token is requested with a scope that does not exist
+var ErrUnknownScope = errors.New("unknown scope")
+
+// scopeRoutes lists the routes, as "METHOD /gin/route", each scope allows
+var scopeRoutes = map[string][]string{
+	ScopeMessagesRead: {"GET /messages", "GET /messages/:id"},
+	ScopeEventsRead:   {"GET /tenants/:id/events", "GET /tenants/:id/events/stream", "GET /ws"},
+	ScopeSLORead:      {"GET /tenants/:id/slo"},
+	ScopeDLQRead:      {"GET /tenants/:id/dlq/retries"},
+}
+
+// Scopes returns every scope a sub-token can be granted, sorted
+func Scopes() []string {
+	scopes := make([]string, 0, len(scopeRoutes))
+	for scope := range scopeRoutes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// Scoped reports whether the token is a sub-token limited to its scopes
+func (c *Claims) Scoped() bool {
+	return len(c.Scopes) > 0
+}
+
+// Allows reports whether the token may call route with method. Full tokens
+// may call every route.
+func (c *Claims) Allows(method, route string) bool {
+	if !c.Scoped() {
+		return true
+	}
+	for _, scope := range c.Scopes {
+		for _, allowed := range scopeRoutes[scope] {
+			if allowed == method+" "+route {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopes(t *testing.T) {
+	assert.Equal(t, []string{ScopeDLQRead, ScopeEventsRead, ScopeMessagesRead, ScopeSLORead}, Scopes())
+}
+
+func TestScopedAllows(t *testing.T) {
+	full := &Claims{TenantID: "t"}
+	assert.False(t, full.Scoped())
+	assert.True(t, full.Allows("DELETE", "/tenants/:id"))
+
+	messages := &Claims{TenantID: "t", Scopes: []string{ScopeMessagesRead}}
+	assert.True(t, messages.Scoped())
+	assert.True(t, messages.Allows("GET", "/messages"))
+	assert.True(t, messages.Allows("GET", "/messages/:id"))
+	assert.False(t, messages.Allows("POST", "/tenants/:id/messages"))
+	assert.False(t, messages.Allows("GET", "/tenants/:id/events"))
+
+	several := &Claims{TenantID: "t", Scopes: []string{ScopeEventsRead, ScopeSLORead}}
+	assert.True(t, several.Allows("GET", "/tenants/:id/events/stream"))
+	assert.True(t, several.Allows("GET", "/tenants/:id/slo"))
+	assert.False(t, several.Allows("GET", "/tenants/:id/dlq/retries"))
+
+	unknown := &Claims{TenantID: "t", Scopes: []string{"tenants:write"}}
+	assert.False(t, unknown.Allows("GET", "/tenants/:id"))
+}
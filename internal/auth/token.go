@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Token types carried in the "typ" claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed, expired or wrongly signed tokens
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenRevoked is returned when the token's jti is on the revocation list
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrTokenReused is returned when a refresh token is used a second time,
+	// e.g. by two concurrent refreshes; it wraps ErrTokenRevoked
+	ErrTokenReused = fmt.Errorf("%w: refresh token was already used", ErrTokenRevoked)
+)
+
+// Claims are the JWT claims issued by this service
+type Claims struct {
+	Type string `json:"typ"`
+	// TenantID binds the token to a single tenant; empty for operators
+	TenantID string `json:"tenant_id,omitempty"`
+	// Role limits the routes the token may call; see EffectiveRole
+	Role string `json:"role,omitempty"`
+	// Scopes restrict a minted sub-token to the listed routes; empty for
+	// full tokens
+	Scopes []string `json:"scopes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair is returned when tokens are issued or refreshed
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type TokenService struct {
+	mu     sync.RWMutex
+	secret []byte
+	// previous is still accepted until previousUntil so tokens signed before
+	// a rotation stay valid until they expire
+	previous      []byte
+	previousUntil time.Time
+
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	// rotationGrace is how long the previous secret is accepted after Rotate
+	rotationGrace time.Duration
+	revoked       RevocationStore
+}
+
+// NewTokenService creates a TokenService. rotationGrace is capped at
+// accessTTL, so a leaked secret is useless once the last access token
+// signed with it has expired.
+func NewTokenService(secret string, accessTTL, refreshTTL, rotationGrace time.Duration, revoked RevocationStore) *TokenService {
+	return &TokenService{
+		secret:        []byte(secret),
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+		rotationGrace: max(min(rotationGrace, accessTTL), 0),
+		revoked:       revoked,
+	}
+}
+
+// Rotate signs new tokens with secret. Tokens signed with the old secret are
+// accepted for the rotation grace period; with none they are rejected at once.
+func (s *TokenService) Rotate(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if secret == string(s.secret) {
+		return
+	}
+	s.previous = s.secret
+	s.previousUntil = time.Now().Add(s.rotationGrace)
+	s.secret = []byte(secret)
+}
+
+// IssuePair issues a new access and refresh token for subject. A non-empty
+// tenantID makes them tenant-admin tokens bound to that tenant. role must
+// pass ValidateRole; empty derives it from the binding.
+func (s *TokenService) IssuePair(subject, tenantID, role string) (*TokenPair, error) {
+	if err := ValidateRole(role, tenantID); err != nil {
+		return nil, err
+	}
+	access, err := s.sign(Claims{Type: TokenTypeAccess, TenantID: tenantID, Role: role}, subject, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(Claims{Type: TokenTypeRefresh, TenantID: tenantID, Role: role}, subject, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      access.Token,
+		RefreshToken:     refresh.Token,
+		AccessExpiresAt:  access.Claims.ExpiresAt.Time,
+		RefreshExpiresAt: refresh.Claims.ExpiresAt.Time,
+	}, nil
+}
+
+// SignedToken is an issued token with its claims
+type SignedToken struct {
+	Token  string
+	Claims Claims
+}
+
+// IssueScoped issues an access token for tenantID limited to scopes. It has
+// no refresh token and cannot be extended.
+func (s *TokenService) IssueScoped(subject, tenantID string, scopes []string, ttl time.Duration) (*SignedToken, error) {
+	for _, scope := range scopes {
+		if _, ok := scopeRoutes[scope]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownScope, scope)
+		}
+	}
+	return s.sign(Claims{Type: TokenTypeAccess, TenantID: tenantID, Scopes: scopes}, subject, ttl)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The used refresh
+// token is revoked so it cannot be replayed; of concurrent refreshes with the
+// same token only the one that revoked it gets a pair, the others get
+// ErrTokenReused.
+func (s *TokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	claims, err := s.Verify(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := s.revoked.RevokeOnce(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !first {
+		return nil, ErrTokenReused
+	}
+	return s.IssuePair(claims.Subject, claims.TenantID, claims.Role)
+}
+
+// Revoke puts a token's jti on the revocation list until it expires
+func (s *TokenService) Revoke(token string) error {
+	claims, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+	return s.revoked.Revoke(claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeID puts the token with the jti on the revocation list until expiresAt
+func (s *TokenService) RevokeID(jti string, expiresAt time.Time) error {
+	return s.revoked.Revoke(jti, expiresAt)
+}
+
+// Verify parses a token, checks its type and that it has not been revoked
+func (s *TokenService) Verify(token, tokenType string) (*Claims, error) {
+	claims, err := s.parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.revoked.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check revocation list: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func (s *TokenService) parse(token string) (*Claims, error) {
+	s.mu.RLock()
+	secrets := [][]byte{s.secret}
+	if s.previous != nil && time.Now().Before(s.previousUntil) {
+		secrets = append(secrets, s.previous)
+	}
+	s.mu.RUnlock()
+
+	for _, secret := range secrets {
+		claims := &Claims{}
+		parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+		if err == nil && parsed.Valid && claims.ID != "" {
+			return claims, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+func (s *TokenService) sign(claims Claims, subject string, ttl time.Duration) (*SignedToken, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	s.mu.RLock()
+	secret := s.secret
+	s.mu.RUnlock()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedToken{Token: signed, Claims: claims}, nil
+}
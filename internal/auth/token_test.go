@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRevocations is a RevocationStore for tests
+type memoryRevocations struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func newMemoryRevocations() *memoryRevocations {
+	return &memoryRevocations{revoked: make(map[string]time.Time)}
+}
+
+func (m *memoryRevocations) Revoke(jti string, expiresAt time.Time) error {
+	_, err := m.RevokeOnce(jti, expiresAt)
+	return err
+}
+
+func (m *memoryRevocations) RevokeOnce(jti string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.revoked[jti]; ok {
+		return false, nil
+	}
+	m.revoked[jti] = expiresAt
+	return true, nil
+}
+
+func (m *memoryRevocations) IsRevoked(jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.revoked[jti]
+	return ok, nil
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	tokens := NewTokenService("secret", time.Minute, time.Hour, 0, newMemoryRevocations())
+
+	pair, err := tokens.IssuePair("alice", "tenant-a", RoleReader)
+	require.NoError(t, err)
+
+	claims, err := tokens.Verify(pair.AccessToken, TokenTypeAccess)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+	assert.Equal(t, "tenant-a", claims.TenantID)
+	assert.Equal(t, RoleReader, claims.Role)
+
+	// Access and refresh tokens are not interchangeable
+	_, err = tokens.Verify(pair.RefreshToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = tokens.Verify(pair.AccessToken, TokenTypeRefresh)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = tokens.IssuePair("alice", "tenant-a", RoleAdmin)
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestVerifyRejectsForeignTokens(t *testing.T) {
+	tokens := NewTokenService("secret", time.Minute, time.Hour, 0, newMemoryRevocations())
+
+	other := NewTokenService("other", time.Minute, time.Hour, 0, newMemoryRevocations())
+	pair, err := other.IssuePair("alice", "", "")
+	require.NoError(t, err)
+	_, err = tokens.Verify(pair.AccessToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	expired := NewTokenService("secret", -time.Minute, time.Hour, 0, newMemoryRevocations())
+	pair, err = expired.IssuePair("alice", "", "")
+	require.NoError(t, err)
+	_, err = tokens.Verify(pair.AccessToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Only HS256 is accepted, so an unsigned token is not
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{
+		Type:             TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "jti", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = tokens.Verify(unsigned, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRevokeAndRefresh(t *testing.T) {
+	tokens := NewTokenService("secret", time.Minute, time.Hour, 0, newMemoryRevocations())
+	pair, err := tokens.IssuePair("alice", "tenant-a", "")
+	require.NoError(t, err)
+
+	require.NoError(t, tokens.Revoke(pair.AccessToken))
+	_, err = tokens.Verify(pair.AccessToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	refreshed, err := tokens.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+	claims, err := tokens.Verify(refreshed.AccessToken, TokenTypeAccess)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", claims.TenantID)
+
+	// A refresh token is single use
+	_, err = tokens.Refresh(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	tokens := NewTokenService("secret", time.Minute, time.Hour, 0, newMemoryRevocations())
+	pair, err := tokens.IssuePair("alice", "", "")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = tokens.Refresh(pair.RefreshToken)
+		}()
+	}
+	wg.Wait()
+
+	// Hanya satu refresh yang mendapat pasangan token baru
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, ErrTokenRevoked)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestIssueScoped(t *testing.T) {
+	tokens := NewTokenService("secret", time.Minute, time.Hour, 0, newMemoryRevocations())
+
+	scoped, err := tokens.IssueScoped("alice", "tenant-a", []string{ScopeMessagesRead}, time.Minute)
+	require.NoError(t, err)
+	claims, err := tokens.Verify(scoped.Token, TokenTypeAccess)
+	require.NoError(t, err)
+	assert.True(t, claims.Allows("GET", "/messages"))
+	assert.False(t, claims.Allows("GET", "/tenants/:id"))
+
+	_, err = tokens.IssueScoped("alice", "tenant-a", []string{"tenants:write"}, time.Minute)
+	assert.ErrorIs(t, err, ErrUnknownScope)
+}
+
+func TestRotate(t *testing.T) {
+	revocations := newMemoryRevocations()
+	withGrace := NewTokenService("old", time.Minute, time.Hour, time.Minute, revocations)
+	pair, err := withGrace.IssuePair("alice", "", "")
+	require.NoError(t, err)
+	withGrace.Rotate("new")
+	_, err = withGrace.Verify(pair.AccessToken, TokenTypeAccess)
+	assert.NoError(t, err, "tokens of the previous secret are accepted during the grace period")
+
+	noGrace := NewTokenService("old", time.Minute, time.Hour, 0, revocations)
+	pair, err = noGrace.IssuePair("alice", "", "")
+	require.NoError(t, err)
+	noGrace.Rotate("new")
+	_, err = noGrace.Verify(pair.AccessToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
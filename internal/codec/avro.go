@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+// AvroCodec decodes Avro binary payloads with schemas from the schema registry
+type AvroCodec struct {
+	schemas *SchemaRegistry
+}
+
+// NewAvroCodec creates an AvroCodec
+func NewAvroCodec(schemas *SchemaRegistry) *AvroCodec {
+	return &AvroCodec{schemas: schemas}
+}
+
+// Decode decodes payload with the writer schema and encodes the result as JSON
+func (c *AvroCodec) Decode(ctx context.Context, schemaID int, payload []byte) ([]byte, error) {
+	compiled, err := c.schemas.compiled(ctx, schemaID, func(schema *Schema) (any, error) {
+		return parseAvro(schema)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := avro.Unmarshal(compiled.(avro.Schema), payload, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	return json.Marshal(value)
+}
+
+// parseAvro parses the schema after the named types it references
+func parseAvro(schema *Schema) (avro.Schema, error) {
+	if schema.Type != SchemaTypeAvro {
+		return nil, fmt.Errorf("schema type is %s, not %s", schema.Type, SchemaTypeAvro)
+	}
+
+	cache := &avro.SchemaCache{}
+	var parseRefs func(refs map[string]*Schema) error
+	parseRefs = func(refs map[string]*Schema) error {
+		for name, ref := range refs {
+			if err := parseRefs(ref.References); err != nil {
+				return err
+			}
+			if _, err := avro.ParseWithCache(ref.Schema, "", cache); err != nil {
+				return fmt.Errorf("reference %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+	if err := parseRefs(schema.References); err != nil {
+		return nil, err
+	}
+	return avro.ParseWithCache(schema.Schema, "", cache)
+}
@@ -0,0 +1,125 @@
+// Package codec decodes message payloads published as protobuf or Avro into
+// a canonical JSON projection, so schemas, filters, processors and the
+// messages API keep working on JSON.
+//
+// The codec is chosen by the delivery's content type. Binary payloads use the
+// Confluent wire format (a zero magic byte and a 4-byte schema ID, followed
+// for protobuf by the message indexes), and their schemas are fetched from a
+// Confluent-compatible schema registry. Payloads with any other content type
+// are treated as JSON, as before.
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Content types of the binary codecs
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+// ErrDecode is returned when a payload cannot be decoded with its content
+// type; retrying will not help
+var ErrDecode = errors.New("payload cannot be decoded")
+
+// contentTypeAliases maps the content types publishers commonly use onto the
+// canonical ones
+var contentTypeAliases = map[string]string{
+	"application/x-protobuf":             ContentTypeProtobuf,
+	"application/protobuf":               ContentTypeProtobuf,
+	"application/vnd.google.protobuf":    ContentTypeProtobuf,
+	"application/avro":                   ContentTypeAvro,
+	"avro/binary":                        ContentTypeAvro,
+	"application/vnd.apache.avro+binary": ContentTypeAvro,
+}
+
+// Codec turns a payload in the Confluent wire format into JSON
+type Codec interface {
+	Decode(ctx context.Context, schemaID int, payload []byte) ([]byte, error)
+}
+
+// Decoded is a payload in its canonical JSON form. Raw and ContentType are
+// set for binary payloads so the original bytes can be stored alongside.
+type Decoded struct {
+	JSON        []byte
+	Raw         []byte
+	ContentType string
+}
+
+// Registry picks the codec for a delivery's content type. A nil Registry
+// treats every payload as JSON.
+type Registry struct {
+	codecs map[string]Codec
+}
+
+// NewRegistry creates a Registry with the protobuf and Avro codecs backed by
+// schemas. Without a schema registry binary payloads cannot be decoded.
+func NewRegistry(schemas *SchemaRegistry) *Registry {
+	r := &Registry{codecs: make(map[string]Codec)}
+	if schemas != nil {
+		r.Register(ContentTypeProtobuf, NewProtobufCodec(schemas))
+		r.Register(ContentTypeAvro, NewAvroCodec(schemas))
+	}
+	return r
+}
+
+// Register makes codec decode payloads of contentType
+func (r *Registry) Register(contentType string, codec Codec) {
+	r.codecs[contentType] = codec
+}
+
+// Decode returns the JSON projection of body. JSON and unknown content types
+// are returned unchanged.
+func (r *Registry) Decode(ctx context.Context, contentType string, body []byte) (Decoded, error) {
+	canonical, isBinary := canonicalContentType(contentType)
+	if !isBinary {
+		return Decoded{JSON: body}, nil
+	}
+
+	var codec Codec
+	if r != nil {
+		codec = r.codecs[canonical]
+	}
+	if codec == nil {
+		return Decoded{}, fmt.Errorf("%w: no codec for %s, configure codecs.schema_registry", ErrDecode, canonical)
+	}
+
+	schemaID, payload, err := splitWireFormat(body)
+	if err != nil {
+		return Decoded{}, err
+	}
+	decoded, err := codec.Decode(ctx, schemaID, payload)
+	if err != nil {
+		return Decoded{}, err
+	}
+	return Decoded{JSON: decoded, Raw: body, ContentType: canonical}, nil
+}
+
+// canonicalContentType strips parameters from contentType and reports
+// whether it names a binary codec
+func canonicalContentType(contentType string) (string, bool) {
+	if contentType == "" {
+		return "", false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	canonical, ok := contentTypeAliases[mediaType]
+	return canonical, ok
+}
+
+// splitWireFormat reads the Confluent header: magic byte 0 and a big-endian
+// 4-byte schema ID
+func splitWireFormat(body []byte) (int, []byte, error) {
+	if len(body) < 5 || body[0] != 0 {
+		return 0, nil, fmt.Errorf("%w: missing schema registry header", ErrDecode)
+	}
+	return int(binary.BigEndian.Uint32(body[1:5])), body[5:], nil
+}
@@ -0,0 +1,122 @@
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// rootProtoFile is the name the fetched schema is compiled under
+const rootProtoFile = "schema.proto"
+
+// ProtobufCodec decodes protobuf payloads with .proto schemas from the
+// schema registry
+type ProtobufCodec struct {
+	schemas *SchemaRegistry
+}
+
+// NewProtobufCodec creates a ProtobufCodec
+func NewProtobufCodec(schemas *SchemaRegistry) *ProtobufCodec {
+	return &ProtobufCodec{schemas: schemas}
+}
+
+// Decode reads the message indexes after the schema ID to find the message
+// type in the schema, then decodes payload into JSON with proto field names
+func (c *ProtobufCodec) Decode(ctx context.Context, schemaID int, payload []byte) ([]byte, error) {
+	compiled, err := c.schemas.compiled(ctx, schemaID, func(schema *Schema) (any, error) {
+		return compileProto(ctx, schema)
+	})
+	if err != nil {
+		return nil, err
+	}
+	file := compiled.(protoreflect.FileDescriptor)
+
+	indexes, payload, err := readMessageIndexes(payload)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, err := messageAt(file, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+}
+
+// compileProto compiles the schema with its references as imports
+func compileProto(ctx context.Context, schema *Schema) (protoreflect.FileDescriptor, error) {
+	if schema.Type != SchemaTypeProtobuf {
+		return nil, fmt.Errorf("schema type is %s, not %s", schema.Type, SchemaTypeProtobuf)
+	}
+
+	sources := map[string]string{rootProtoFile: schema.Schema}
+	var collect func(refs map[string]*Schema)
+	collect = func(refs map[string]*Schema) {
+		for name, ref := range refs {
+			if _, seen := sources[name]; !seen {
+				sources[name] = ref.Schema
+				collect(ref.References)
+			}
+		}
+	}
+	collect(schema.References)
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+	files, err := compiler.Compile(ctx, rootProtoFile)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// readMessageIndexes reads the zigzag varint array naming the message type
+// by its index path; a single 0 byte stands for the first message
+func readMessageIndexes(payload []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, nil, fmt.Errorf("%w: invalid protobuf message indexes", ErrDecode)
+	}
+	payload = payload[n:]
+	if count == 0 {
+		return []int{0}, payload, nil
+	}
+
+	indexes := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		index, n := binary.Varint(payload)
+		if n <= 0 || index < 0 {
+			return nil, nil, fmt.Errorf("%w: invalid protobuf message indexes", ErrDecode)
+		}
+		indexes = append(indexes, int(index))
+		payload = payload[n:]
+	}
+	return indexes, payload, nil
+}
+
+// messageAt follows indexes through the file's top-level and nested messages
+func messageAt(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	messages := file.Messages()
+	var descriptor protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index >= messages.Len() {
+			return nil, fmt.Errorf("%w: message index %v not in schema", ErrDecode, indexes)
+		}
+		descriptor = messages.Get(index)
+		messages = descriptor.Messages()
+	}
+	return descriptor, nil
+}
@@ -0,0 +1,148 @@
+package codec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema types reported by the schema registry
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// Schema is a schema fetched from the registry. References are the schemas
+// it imports, keyed by the name it imports them under.
+type Schema struct {
+	Type       string
+	Schema     string
+	References map[string]*Schema
+}
+
+// schemaResponse is the registry's answer for /schemas/ids/{id} and
+// /subjects/{subject}/versions/{version}
+type schemaResponse struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+	References []struct {
+		Name    string `json:"name"`
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+	} `json:"references"`
+}
+
+// SchemaRegistry fetches schemas from a Confluent-compatible schema registry.
+// Schema IDs never change their schema, so fetched schemas are kept.
+type SchemaRegistry struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	mu    sync.Mutex
+	byID  map[int]*Schema
+	codec sync.Map // schema ID -> compiled schema of a codec
+}
+
+// NewSchemaRegistry creates a client for the registry at baseURL
+func NewSchemaRegistry(baseURL, username, password string, timeout time.Duration) *SchemaRegistry {
+	return &SchemaRegistry{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: timeout},
+		byID:     make(map[int]*Schema),
+	}
+}
+
+// Schema returns the schema registered under id, with its references
+func (r *SchemaRegistry) Schema(ctx context.Context, id int) (*Schema, error) {
+	r.mu.Lock()
+	schema, ok := r.byID[id]
+	r.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := r.fetch(ctx, fmt.Sprintf("/schemas/ids/%d", id), 0)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	r.mu.Lock()
+	r.byID[id] = schema
+	r.mu.Unlock()
+	return schema, nil
+}
+
+// compiled returns what build made of the schema with id, building it once
+func (r *SchemaRegistry) compiled(ctx context.Context, id int, build func(*Schema) (any, error)) (any, error) {
+	if value, ok := r.codec.Load(id); ok {
+		return value, nil
+	}
+	schema, err := r.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	value, err := build(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema %d: %v", ErrDecode, id, err)
+	}
+	r.codec.Store(id, value)
+	return value, nil
+}
+
+// maxReferenceDepth bounds how deep schema references are followed
+const maxReferenceDepth = 16
+
+func (r *SchemaRegistry) fetch(ctx context.Context, path string, depth int) (*Schema, error) {
+	if depth > maxReferenceDepth {
+		return nil, fmt.Errorf("schema references nested deeper than %d", maxReferenceDepth)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		// Schema yang tidak ada tidak akan muncul jika diulang
+		return nil, fmt.Errorf("%w: not found in the schema registry", ErrDecode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("schema registry returned %s", resp.Status)
+	}
+
+	var body schemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid schema registry response: %w", err)
+	}
+
+	schema := &Schema{Type: body.SchemaType, Schema: body.Schema, References: make(map[string]*Schema)}
+	if schema.Type == "" {
+		// Registry tidak mengisi schemaType untuk Avro
+		schema.Type = SchemaTypeAvro
+	}
+	for _, ref := range body.References {
+		referenced, err := r.fetch(ctx, fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(ref.Subject), ref.Version), depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("reference %s: %w", ref.Name, err)
+		}
+		schema.References[ref.Name] = referenced
+	}
+	return schema, nil
+}
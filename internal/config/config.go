@@ -3,27 +3,579 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Workers  int            `mapstructure:"workers"`
-	Server   ServerConfig   `mapstructure:"server"`
+	// Transport selects the broker tenant messages go through
+	Transport TransportConfig `mapstructure:"transport"`
+	RabbitMQ  RabbitMQConfig  `mapstructure:"rabbitmq"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Workers   int             `mapstructure:"workers"`
+	// Concurrency caps the workers tenants can ask for
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Export      ExportConfig      `mapstructure:"export"`
+	Delivery    DeliveryConfig    `mapstructure:"delivery"`
+	Security    SecurityConfig    `mapstructure:"security"`
+	Audit       AuditConfig       `mapstructure:"audit"`
+	Dedup       DedupConfig       `mapstructure:"dedup"`
+	ClaimCheck  ClaimCheckConfig  `mapstructure:"claim_check"`
+	Handover    HandoverConfig    `mapstructure:"handover"`
+	// TenantMigration lists the deployments tenants can be moved to
+	TenantMigration TenantMigrationConfig `mapstructure:"tenant_migration"`
+	Metrics         MetricsConfig         `mapstructure:"metrics"`
+	Tracing         TracingConfig         `mapstructure:"tracing"`
+	Logging         LoggingConfig         `mapstructure:"logging"`
+	SLO             SLOConfig             `mapstructure:"slo"`
+	Processors      ProcessorsConfig      `mapstructure:"processors"`
+	Filters         FiltersConfig         `mapstructure:"filters"`
+	Retry           RetryConfig           `mapstructure:"retry"`
+	DLQRetry        DLQRetryConfig        `mapstructure:"dlq_retry"`
+	Quarantine      QuarantineConfig      `mapstructure:"quarantine"`
+	Quotas          QuotasConfig          `mapstructure:"quotas"`
+	Outbox          OutboxConfig          `mapstructure:"outbox"`
+	Webhooks        WebhooksConfig        `mapstructure:"webhooks"`
+	Retention       RetentionConfig       `mapstructure:"retention"`
+	Archive         ArchiveConfig         `mapstructure:"archive"`
+	Events          EventsConfig          `mapstructure:"events"`
+	Console         ConsoleConfig         `mapstructure:"console"`
+	Journal         JournalConfig         `mapstructure:"journal"`
+	DynamicConfig   DynamicConfig         `mapstructure:"dynamic_config"`
+	Kubernetes      KubernetesConfig      `mapstructure:"kubernetes"`
+	Startup         StartupConfig         `mapstructure:"startup"`
+	Codecs          CodecsConfig          `mapstructure:"codecs"`
 }
 
 type RabbitMQConfig struct {
 	URL string `mapstructure:"url"`
+	// URLs lists the nodes of a cluster, dialed in order when a connection drops
+	URLs []string `mapstructure:"urls"`
+	// SRVName is a DNS SRV record listing the nodes; url supplies scheme,
+	// credentials and vhost
+	SRVName string `mapstructure:"srv_name"`
+	// Connections is the number of pooled connections tenant channels are spread over
+	Connections int `mapstructure:"connections"`
+	// MessageTTL expires messages left in a tenant queue longer than this (0 = never)
+	MessageTTL time.Duration `mapstructure:"message_ttl"`
+	// QueueNameTemplate names tenant queues; {tenant_id} is replaced by the tenant ID
+	QueueNameTemplate string `mapstructure:"queue_name_template"`
 }
 
-type DatabaseConfig struct {
+// TransportConfig selects RabbitMQ, Kafka, NATS JetStream or AMQP 1.0. On the
+// other transports RabbitMQ is not connected to and the features built on RabbitMQ
+// queues are unavailable.
+type TransportConfig struct {
+	// Kind is rabbitmq, kafka, nats or amqp10
+	Kind   string       `mapstructure:"kind"`
+	Kafka  KafkaConfig  `mapstructure:"kafka"`
+	NATS   NATSConfig   `mapstructure:"nats"`
+	AMQP10 AMQP10Config `mapstructure:"amqp10"`
+}
+
+type KafkaConfig struct {
+	Brokers []string `mapstructure:"brokers"`
+	// TopicStrategy is tenant_topic, a topic per tenant queue, or
+	// partition_key, one shared topic keyed by tenant ID
+	TopicStrategy string `mapstructure:"topic_strategy"`
+	TopicPrefix   string `mapstructure:"topic_prefix"`
+	SharedTopic   string `mapstructure:"shared_topic"`
+	// GroupPrefix starts the consumer group of each tenant
+	GroupPrefix       string `mapstructure:"group_prefix"`
+	Partitions        int    `mapstructure:"partitions"`
+	ReplicationFactor int    `mapstructure:"replication_factor"`
+}
+
+type NATSConfig struct {
+	URL           string `mapstructure:"url"`
+	SubjectPrefix string `mapstructure:"subject_prefix"`
+	// StreamPrefix starts the stream of each tenant queue
+	StreamPrefix string `mapstructure:"stream_prefix"`
+	// DurablePrefix starts the durable consumer of each tenant
+	DurablePrefix string `mapstructure:"durable_prefix"`
+	Replicas      int    `mapstructure:"replicas"`
+	// AckWait is how long a message may be processed before it is redelivered
+	AckWait time.Duration `mapstructure:"ack_wait"`
+}
+
+// AMQP10Config connects to an AMQP 1.0 broker such as Azure Service Bus or
+// ActiveMQ Artemis
+type AMQP10Config struct {
+	// URL carries the SASL PLAIN credentials as user info
 	URL string `mapstructure:"url"`
+	// AddressPrefix starts the address of each tenant queue
+	AddressPrefix string `mapstructure:"address_prefix"`
+}
+
+type DatabaseConfig struct {
+	URL              string `mapstructure:"url"`
+	RowLevelSecurity bool   `mapstructure:"row_level_security"`
+	// QuerySpans opens a trace span per query in addition to the query metrics
+	QuerySpans bool `mapstructure:"query_spans"`
+	// FailoverCheckInterval is how often a writable primary is looked for
+	// while consumers are paused for a failover
+	FailoverCheckInterval time.Duration `mapstructure:"failover_check_interval"`
+	// PartitionOnDelete is what deleting a tenant does with its messages
+	// partition: drop, detach (kept as a standalone table) or keep
+	PartitionOnDelete string `mapstructure:"partition_on_delete"`
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool `mapstructure:"auto_migrate"`
 }
 
 type ServerConfig struct {
+	// Port is the TCP address of the API; empty serves it only on the Unix
+	// socket or the sockets passed by systemd
 	Port string `mapstructure:"port"`
+	// UnixSocket is the path of a Unix domain socket the API also listens on
+	UnixSocket string `mapstructure:"unix_socket"`
+	// UnixSocketMode is the octal file mode of the socket, e.g. "0660"
+	UnixSocketMode string             `mapstructure:"unix_socket_mode"`
+	TrustedProxies []string           `mapstructure:"trusted_proxies"`
+	Autocert       AutocertConfig     `mapstructure:"autocert"`
+	LoadShedding   LoadSheddingConfig `mapstructure:"load_shedding"`
+	AccessLog      AccessLogConfig    `mapstructure:"access_log"`
+	Admin          AdminConfig        `mapstructure:"admin"`
+	GRPC           GRPCConfig         `mapstructure:"grpc"`
+	// ShutdownTimeout bounds a graceful shutdown: closing the listeners and
+	// draining the messages the consumers are processing
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+// AdminConfig moves /admin/*, the metrics endpoint and pprof to a separate
+// listener with its own authentication
+type AdminConfig struct {
+	// Listen is the admin listener's address, e.g. "127.0.0.1:9090"; empty
+	// keeps the admin routes on the API listener behind the JWT
+	Listen string `mapstructure:"listen"`
+	// Tokens are static bearer tokens accepted by the admin listener
+	Tokens []string       `mapstructure:"tokens"`
+	TLS    AdminTLSConfig `mapstructure:"tls"`
+	Pprof  bool           `mapstructure:"pprof"`
+}
+
+// AdminTLSConfig serves the admin listener over TLS; with ClientCAFile,
+// client certificates signed by that CA authenticate operators (mTLS)
+type AdminTLSConfig struct {
+	CertFile     string `mapstructure:"cert_file"`
+	KeyFile      string `mapstructure:"key_file"`
+	ClientCAFile string `mapstructure:"client_ca_file"`
+}
+
+// GRPCConfig configures the gRPC listener serving the tenant and message
+// operations to internal services
+type GRPCConfig struct {
+	// Listen is the gRPC listener's address, e.g. ":9000"; empty disables it
+	Listen string `mapstructure:"listen"`
+	// Reflection serves the gRPC server reflection service
+	Reflection bool `mapstructure:"reflection"`
+	// CertFile and KeyFile serve the listener over TLS
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+}
+
+// AccessLogConfig sets up sampled request/response logging. The sample
+// rates can be changed at runtime via PUT /admin/access-log.
+type AccessLogConfig struct {
+	// SampleRate is the share of requests logged (0 = none, 1 = all)
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// Routes override SampleRate per route, e.g. "POST /tenants": 1
+	Routes       map[string]float64 `mapstructure:"routes"`
+	MaxBodyBytes int                `mapstructure:"max_body_bytes"`
+	// RedactFields are JSON fields whose values are masked in logged bodies
+	RedactFields []string `mapstructure:"redact_fields"`
+}
+
+// LoadSheddingConfig limits the concurrent requests to the messages endpoints
+// so they cannot take all database connections from the consumer workers
+type LoadSheddingConfig struct {
+	// MaxInFlight is the number of requests served at once (0 = unlimited)
+	MaxInFlight int `mapstructure:"max_in_flight"`
+	// MaxQueued requests wait for a free slot; beyond that they get 503
+	MaxQueued    int           `mapstructure:"max_queued"`
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+}
+
+// AutocertConfig enables TLS with certificates obtained via ACME (Let's Encrypt)
+type AutocertConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Domains  []string `mapstructure:"domains"`
+	CacheDir string   `mapstructure:"cache_dir"`
+	Email    string   `mapstructure:"email"`
+	HTTPAddr string   `mapstructure:"http_addr"`
+}
+
+type ExportConfig struct {
+	Dir       string `mapstructure:"dir"`
+	ChunkSize int    `mapstructure:"chunk_size"`
+}
+
+type DeliveryConfig struct {
+	StuckThreshold time.Duration `mapstructure:"stuck_threshold"`
+}
+
+type SecurityConfig struct {
+	JWTSecret       string        `mapstructure:"jwt_secret"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+	// RotationGracePeriod is how long tokens signed with the previous JWT
+	// secret stay valid after a rotation, at most AccessTokenTTL
+	RotationGracePeriod time.Duration `mapstructure:"rotation_grace_period"`
+	// RotationDrainTimeout bounds how long consumers are drained before they
+	// move to new broker connections on credential rotation
+	RotationDrainTimeout time.Duration `mapstructure:"rotation_drain_timeout"`
+	// SubTokenMaxTTL caps the lifetime of sub-tokens tenants mint for themselves
+	SubTokenMaxTTL time.Duration `mapstructure:"sub_token_max_ttl"`
+	// APIKeys accepts tenant API keys in the X-API-Key header, next to JWTs
+	// when JWTSecret is set
+	APIKeys bool `mapstructure:"api_keys"`
+	// RedactionKey keys the HMAC of hashed payload fields
+	RedactionKey string `mapstructure:"redaction_key"`
+}
+
+type AuditConfig struct {
+	// Sink is "", "syslog" or "http"
+	Sink   string            `mapstructure:"sink"`
+	Format string            `mapstructure:"format"`
+	Syslog AuditSyslogConfig `mapstructure:"syslog"`
+	HTTP   AuditHTTPConfig   `mapstructure:"http"`
+}
+
+type AuditSyslogConfig struct {
+	Network string `mapstructure:"network"`
+	Address string `mapstructure:"address"`
+}
+
+type AuditHTTPConfig struct {
+	URL string `mapstructure:"url"`
+}
+
+type DedupConfig struct {
+	CacheSize int `mapstructure:"cache_size"`
+}
+
+// ClaimCheckConfig controls fetching of blobs referenced by claim-check envelopes
+type ClaimCheckConfig struct {
+	AllowedHosts []string `mapstructure:"allowed_hosts"`
+	// TenantPrefix is the URL path blobs of a tenant must start with; {tenant_id} is replaced
+	TenantPrefix  string        `mapstructure:"tenant_prefix"`
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
+	MaxBlobBytes  int64         `mapstructure:"max_blob_bytes"`
+	FetchTimeout  time.Duration `mapstructure:"fetch_timeout"`
+}
+
+// HandoverConfig controls tenant-by-tenant consumer handover between instances
+type HandoverConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// InstanceID defaults to <hostname>-<pid>
+	InstanceID        string        `mapstructure:"instance_id"`
+	Version           string        `mapstructure:"version"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	// Takeover makes this instance request tenants from older instances running another version
+	Takeover     bool          `mapstructure:"takeover"`
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+}
+
+// TenantMigrationConfig holds the connection details of other deployments,
+// keyed by the name used in migration requests
+type TenantMigrationConfig struct {
+	Targets map[string]MigrationTarget `mapstructure:"targets"`
+}
+
+// MigrationTarget is another deployment's database and broker
+type MigrationTarget struct {
+	DatabaseURL string `mapstructure:"database_url"`
+	RabbitMQURL string `mapstructure:"rabbitmq_url"`
+}
+
+// MetricsConfig controls the Prometheus/OpenMetrics scrape endpoint
+type MetricsConfig struct {
+	Enabled      bool                     `mapstructure:"enabled"`
+	Path         string                   `mapstructure:"path"`
+	TenantLabels TenantLabelsPolicyConfig `mapstructure:"tenant_labels"`
+	// PushgatewayURL receives the metrics of one-shot commands such as migrate up
+	PushgatewayURL string `mapstructure:"pushgateway_url"`
+}
+
+// TracingConfig exports OpenTelemetry spans of API requests, published and
+// consumed messages and database queries to an OTLP/HTTP collector
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint is the collector's host:port, e.g. "localhost:4318"
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure sends spans over plain HTTP instead of HTTPS
+	Insecure bool `mapstructure:"insecure"`
+	// Headers are sent with every export, e.g. an API key of the tracing backend
+	Headers     map[string]string `mapstructure:"headers"`
+	ServiceName string            `mapstructure:"service_name"`
+	// SampleRatio is the share of new traces recorded (0-1); requests and
+	// messages carrying a traceparent follow the caller's decision
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// LoggingConfig sets up the structured log written to stderr
+type LoggingConfig struct {
+	// Level is debug, info, warn or error
+	Level string `mapstructure:"level"`
+	// Format is json or console
+	Format string `mapstructure:"format"`
+}
+
+// TenantLabelsPolicyConfig bounds the cardinality of tenant_id-labeled metrics
+type TenantLabelsPolicyConfig struct {
+	TenantLabelPolicyConfig `mapstructure:",squash"`
+	// Overrides replaces the policy for individual metrics, keyed by metric name
+	Overrides map[string]TenantLabelPolicyConfig `mapstructure:"overrides"`
+}
+
+// TenantLabelPolicyConfig is one tenant label policy: all, top_k or none
+type TenantLabelPolicyConfig struct {
+	Policy string `mapstructure:"policy"`
+	TopK   int    `mapstructure:"top_k"`
+}
+
+// SLOConfig holds the default per-tenant SLO, overridable per tenant
+type SLOConfig struct {
+	// Target is the share of messages that must be persisted within Threshold
+	Target    float64       `mapstructure:"target"`
+	Threshold time.Duration `mapstructure:"threshold"`
+	// Window is how far back compliance and the slow burn rate look
+	Window time.Duration `mapstructure:"window"`
+}
+
+// ProcessorsConfig lists processors running as gRPC sidecars, keyed by the
+// name tenants select them by
+type ProcessorsConfig struct {
+	Sidecars map[string]ProcessorSidecar `mapstructure:"sidecars"`
+}
+
+// ProcessorSidecar is the address of a sidecar serving the processor API
+type ProcessorSidecar struct {
+	Address string        `mapstructure:"address"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// FiltersConfig bounds the evaluation of each tenant filter expression
+type FiltersConfig struct {
+	EvalTimeout time.Duration `mapstructure:"eval_timeout"`
+	// CostLimit caps the CEL cost of one expression evaluation (0 = unlimited)
+	CostLimit uint64 `mapstructure:"cost_limit"`
+}
+
+// OutboxConfig sends published messages through the publish_outbox table
+type OutboxConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RelayInterval is how often the relay looks for unpublished messages
+	RelayInterval time.Duration `mapstructure:"relay_interval"`
+	// BatchSize is how many messages the relay publishes per transaction
+	BatchSize int `mapstructure:"batch_size"`
+	// Retention is how long published messages stay in the outbox
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// WebhooksConfig controls the delivery of stored messages to tenant webhooks
+type WebhooksConfig struct {
+	// Timeout bounds one delivery attempt
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	// Backoff is the wait after the first failed attempt, doubled up to MaxBackoff
+	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+	// PollInterval is how often each instance looks for due deliveries
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Workers is how many deliveries each instance makes at once
+	Workers int `mapstructure:"workers"`
+	// Retention is how long delivered and failed deliveries are kept
+	Retention time.Duration `mapstructure:"retention"`
+	// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+// RetentionConfig controls the janitor expiring stored messages by the
+// tenants' retention policies
+type RetentionConfig struct {
+	// Interval is how often the policies are enforced
+	Interval time.Duration `mapstructure:"interval"`
+	// BatchSize is how many messages are deleted per statement
+	BatchSize int `mapstructure:"batch_size"`
+}
+
+// ArchiveConfig controls the archiving of old messages to S3 or MinIO
+type ArchiveConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is how often old messages are archived
+	Interval time.Duration `mapstructure:"interval"`
+	// OlderThan is the age from which messages are archived, in whole UTC days
+	OlderThan time.Duration `mapstructure:"older_than"`
+	// Endpoint is the host[:port] of S3 or MinIO
+	Endpoint string `mapstructure:"endpoint"`
+	Region   string `mapstructure:"region"`
+	Bucket   string `mapstructure:"bucket"`
+	// Prefix starts every object key
+	Prefix string `mapstructure:"prefix"`
+	// AccessKey and SecretKey default to the AWS or MinIO environment
+	// variables, then to the instance's IAM role
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+}
+
+// ConcurrencyConfig bounds the workers set with UpdateConcurrency, so one
+// tenant cannot starve the process
+type ConcurrencyConfig struct {
+	// MaxWorkers is the budget shared by the tenants an instance consumes, 0 for none
+	MaxWorkers int `mapstructure:"max_workers"`
+	// MaxTenantWorkers caps a tenant's workers unless it has bounds of its own
+	MaxTenantWorkers int `mapstructure:"max_tenant_workers"`
+}
+
+// RetryConfig is the default policy for retrying messages that failed for a
+// reason that may pass through the tenant's backoff queues, overridable per tenant
+type RetryConfig struct {
+	// MaxAttempts counts the first delivery, so 4 means up to 3 retries
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Backoff is the wait before the first retry, doubled for each later one
+	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+	// Jitter spreads each wait by up to this share of it in either direction
+	Jitter float64 `mapstructure:"jitter"`
+}
+
+// QuotasConfig is the default quota of tenants without their own; 0 leaves a
+// limit off
+type QuotasConfig struct {
+	MaxMessages   int64 `mapstructure:"max_messages"`
+	MaxBytes      int64 `mapstructure:"max_bytes"`
+	MaxQueueDepth int64 `mapstructure:"max_queue_depth"`
+	// UsageTTL is how long a measured usage is trusted when publishing
+	UsageTTL time.Duration `mapstructure:"usage_ttl"`
+}
+
+// DLQRetryConfig is the default policy for automatically retrying dead-lettered
+// messages, overridable per tenant
+type DLQRetryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Schedule is the delay before each retry, counted from when the message entered the DLQ
+	Schedule []time.Duration `mapstructure:"schedule"`
+	// Interval is how often each tenant's DLQ is scanned for due messages
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// QuarantineConfig controls when poison messages, those whose processing
+// panics or whose payload cannot be decoded or validated, are quarantined
+type QuarantineConfig struct {
+	// Threshold is how many such failures quarantine a message, 0 turns quarantine off
+	Threshold int `mapstructure:"threshold"`
+	// Window forgets the failures of a message that has not failed for this long
+	Window time.Duration `mapstructure:"window"`
+	// Retention is how long quarantined messages are kept, 0 keeps them
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// EventsConfig controls the tenant system event stream
+type EventsConfig struct {
+	// Queue, if set, receives every event as JSON in addition to the events table
+	Queue string `mapstructure:"queue"`
+	// Retention is how long events are kept in the events table
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// ConsoleConfig controls the real-time console WebSocket at /ws
+type ConsoleConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SendBuffer is how many events a connection buffers before it misses some
+	SendBuffer int `mapstructure:"send_buffer"`
+	// WriteTimeout disconnects clients that stop reading
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// PingInterval is how often idle connections are checked
+	PingInterval time.Duration `mapstructure:"ping_interval"`
+	// MaxSubscriptions caps the tenants one connection subscribes to
+	MaxSubscriptions int `mapstructure:"max_subscriptions"`
+	// OriginPatterns lists the hosts browsers may connect from besides the server's own
+	OriginPatterns []string `mapstructure:"origin_patterns"`
+}
+
+// JournalConfig enables the local journal of in-flight deliveries
+type JournalConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+	// RecoveryWindow is how long after a crash redeliveries of messages that
+	// were already processed are recognised
+	RecoveryWindow time.Duration `mapstructure:"recovery_window"`
+}
+
+// DynamicConfig selects where per-tenant runtime config and feature flags are
+// stored and watched
+type DynamicConfig struct {
+	// Backend is "postgres", "etcd", "consul" or "" to disable runtime config
+	Backend string `mapstructure:"backend"`
+	// Prefix is the key prefix in etcd and Consul
+	Prefix string              `mapstructure:"prefix"`
+	Etcd   DynamicEtcdConfig   `mapstructure:"etcd"`
+	Consul DynamicConsulConfig `mapstructure:"consul"`
+}
+
+type DynamicEtcdConfig struct {
+	Endpoints   []string      `mapstructure:"endpoints"`
+	Username    string        `mapstructure:"username"`
+	Password    string        `mapstructure:"password"`
+	DialTimeout time.Duration `mapstructure:"dial_timeout"`
+}
+
+type DynamicConsulConfig struct {
+	Address string `mapstructure:"address"`
+	Token   string `mapstructure:"token"`
+}
+
+// CodecsConfig configures decoding of protobuf and Avro payloads
+type CodecsConfig struct {
+	SchemaRegistry SchemaRegistryConfig `mapstructure:"schema_registry"`
+}
+
+// SchemaRegistryConfig points at a Confluent-compatible schema registry;
+// binary payloads cannot be decoded while URL is empty
+type SchemaRegistryConfig struct {
+	URL      string        `mapstructure:"url"`
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// StartupConfig controls how long Postgres and RabbitMQ are waited for when
+// the service starts before they are up
+type StartupConfig struct {
+	// Timeout gives up and exits after this long (0 = single attempt)
+	Timeout        time.Duration `mapstructure:"timeout"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	// RestoreTenants starts consuming every stored tenant at startup unless
+	// consumer handover assigns the tenants
+	RestoreTenants bool `mapstructure:"restore_tenants"`
+}
+
+// KubernetesConfig tunes the behaviour when running in a Kubernetes pod
+type KubernetesConfig struct {
+	LeaderElection LeaderElectionConfig `mapstructure:"leader_election"`
+	// ShutdownDelay keeps serving after readiness turns to failing on SIGTERM,
+	// so the endpoints controller removes the pod before the server stops
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+	// ConsumerStallTimeout fails liveness once a tenant consumer has not
+	// taken messages for this long without being stopped, 0 to never fail it
+	ConsumerStallTimeout time.Duration `mapstructure:"consumer_stall_timeout"`
+}
+
+// LeaderElectionConfig elects the instance running singleton background jobs
+// through a coordination.k8s.io Lease; only used in-cluster
+type LeaderElectionConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	LeaseName string `mapstructure:"lease_name"`
+	// Namespace defaults to the pod's namespace
+	Namespace     string        `mapstructure:"namespace"`
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+	RenewDeadline time.Duration `mapstructure:"renew_deadline"`
+	RetryPeriod   time.Duration `mapstructure:"retry_period"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -32,6 +584,125 @@ func LoadConfig() (*Config, error) {
 	viper.AddConfigPath("./configs")
 	viper.AutomaticEnv()
 
+	viper.SetDefault("server.autocert.cache_dir", "./autocert")
+	viper.SetDefault("server.autocert.http_addr", ":80")
+	viper.SetDefault("server.load_shedding.max_queued", 100)
+	viper.SetDefault("server.load_shedding.queue_timeout", 2*time.Second)
+	viper.SetDefault("server.unix_socket_mode", "0660")
+	viper.SetDefault("server.shutdown_timeout", 30*time.Second)
+	viper.SetDefault("server.admin.pprof", true)
+	viper.SetDefault("server.grpc.reflection", true)
+	viper.SetDefault("server.access_log.sample_rate", 0)
+	viper.SetDefault("server.access_log.max_body_bytes", 4096)
+	viper.SetDefault("server.access_log.redact_fields", []string{"password", "secret", "token", "access_token", "refresh_token", "jwt_secret", "api_key", "key", "authorization"})
+	viper.SetDefault("export.dir", "./exports")
+	viper.SetDefault("export.chunk_size", 1000)
+	viper.SetDefault("delivery.stuck_threshold", 5*time.Minute)
+	viper.SetDefault("security.access_token_ttl", 15*time.Minute)
+	viper.SetDefault("security.refresh_token_ttl", 7*24*time.Hour)
+	viper.SetDefault("security.rotation_grace_period", 15*time.Minute)
+	viper.SetDefault("security.rotation_drain_timeout", 30*time.Second)
+	viper.SetDefault("security.sub_token_max_ttl", 24*time.Hour)
+	viper.SetDefault("security.api_keys", false)
+	viper.SetDefault("audit.format", "json")
+	viper.SetDefault("dedup.cache_size", 100000)
+	viper.SetDefault("transport.kind", "rabbitmq")
+	viper.SetDefault("transport.kafka.brokers", []string{"localhost:9092"})
+	viper.SetDefault("transport.kafka.topic_strategy", "tenant_topic")
+	viper.SetDefault("transport.kafka.topic_prefix", "salva.")
+	viper.SetDefault("transport.kafka.shared_topic", "salva.messages")
+	viper.SetDefault("transport.kafka.group_prefix", "salva-")
+	viper.SetDefault("transport.kafka.partitions", 3)
+	viper.SetDefault("transport.kafka.replication_factor", 1)
+	viper.SetDefault("transport.nats.url", "nats://localhost:4222")
+	viper.SetDefault("transport.nats.subject_prefix", "salva.")
+	viper.SetDefault("transport.nats.stream_prefix", "SALVA_")
+	viper.SetDefault("transport.nats.durable_prefix", "salva-")
+	viper.SetDefault("transport.nats.replicas", 1)
+	viper.SetDefault("transport.nats.ack_wait", 30*time.Second)
+	viper.SetDefault("transport.amqp10.url", "amqp://localhost:5672")
+	viper.SetDefault("transport.amqp10.address_prefix", "salva.")
+	viper.SetDefault("rabbitmq.connections", 2)
+	viper.SetDefault("database.failover_check_interval", "1s")
+	viper.SetDefault("database.partition_on_delete", "detach")
+	viper.SetDefault("database.auto_migrate", false)
+	viper.SetDefault("rabbitmq.queue_name_template", "tenant_{tenant_id}_queue")
+	viper.SetDefault("claim_check.tenant_prefix", "/{tenant_id}/")
+	viper.SetDefault("claim_check.max_concurrent", 4)
+	viper.SetDefault("claim_check.max_blob_bytes", 64<<20)
+	viper.SetDefault("claim_check.fetch_timeout", 30*time.Second)
+	viper.SetDefault("handover.heartbeat_interval", 5*time.Second)
+	viper.SetDefault("handover.takeover", true)
+	viper.SetDefault("handover.drain_timeout", 30*time.Second)
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("metrics.tenant_labels.policy", "all")
+	viper.SetDefault("metrics.tenant_labels.top_k", 100)
+	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.format", "json")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.service_name", "salva")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("slo.target", 0.99)
+	viper.SetDefault("slo.threshold", 5*time.Second)
+	viper.SetDefault("slo.window", time.Hour)
+	viper.SetDefault("filters.eval_timeout", 10*time.Millisecond)
+	viper.SetDefault("filters.cost_limit", 10000)
+	viper.SetDefault("concurrency.max_workers", 1000)
+	viper.SetDefault("concurrency.max_tenant_workers", 100)
+	viper.SetDefault("retry.max_attempts", 4)
+	viper.SetDefault("retry.backoff", 30*time.Second)
+	viper.SetDefault("retry.max_backoff", 30*time.Minute)
+	viper.SetDefault("dlq_retry.schedule", []time.Duration{time.Minute, 10 * time.Minute, time.Hour})
+	viper.SetDefault("dlq_retry.interval", 30*time.Second)
+	viper.SetDefault("quotas.usage_ttl", 30*time.Second)
+	viper.SetDefault("quarantine.threshold", 3)
+	viper.SetDefault("quarantine.window", 24*time.Hour)
+	viper.SetDefault("quarantine.retention", 720*time.Hour)
+	viper.SetDefault("outbox.enabled", false)
+	viper.SetDefault("outbox.relay_interval", time.Second)
+	viper.SetDefault("outbox.batch_size", 100)
+	viper.SetDefault("outbox.retention", 24*time.Hour)
+	viper.SetDefault("webhooks.timeout", 10*time.Second)
+	viper.SetDefault("webhooks.max_attempts", 8)
+	viper.SetDefault("webhooks.backoff", 10*time.Second)
+	viper.SetDefault("webhooks.max_backoff", time.Hour)
+	viper.SetDefault("webhooks.poll_interval", time.Second)
+	viper.SetDefault("webhooks.workers", 8)
+	viper.SetDefault("webhooks.retention", 7*24*time.Hour)
+	viper.SetDefault("retention.interval", 10*time.Minute)
+	viper.SetDefault("retention.batch_size", 10000)
+	viper.SetDefault("archive.interval", time.Hour)
+	viper.SetDefault("archive.older_than", 30*24*time.Hour)
+	viper.SetDefault("archive.endpoint", "s3.amazonaws.com")
+	viper.SetDefault("archive.bucket", "salva-archive")
+	viper.SetDefault("archive.prefix", "messages")
+	viper.SetDefault("archive.use_ssl", true)
+	viper.SetDefault("events.retention", 7*24*time.Hour)
+	viper.SetDefault("console.enabled", true)
+	viper.SetDefault("console.send_buffer", 256)
+	viper.SetDefault("console.write_timeout", 10*time.Second)
+	viper.SetDefault("console.ping_interval", 30*time.Second)
+	viper.SetDefault("console.max_subscriptions", 100)
+	viper.SetDefault("console.origin_patterns", []string{})
+	viper.SetDefault("journal.path", "./data/inflight.journal")
+	viper.SetDefault("journal.recovery_window", time.Hour)
+	viper.SetDefault("dynamic_config.backend", "postgres")
+	viper.SetDefault("dynamic_config.prefix", "salva/tenants")
+	viper.SetDefault("dynamic_config.etcd.endpoints", []string{"localhost:2379"})
+	viper.SetDefault("dynamic_config.etcd.dial_timeout", 5*time.Second)
+	viper.SetDefault("dynamic_config.consul.address", "http://127.0.0.1:8500")
+	viper.SetDefault("kubernetes.leader_election.lease_name", "salva-singletons")
+	viper.SetDefault("kubernetes.leader_election.lease_duration", 15*time.Second)
+	viper.SetDefault("kubernetes.leader_election.renew_deadline", 10*time.Second)
+	viper.SetDefault("kubernetes.leader_election.retry_period", 2*time.Second)
+	viper.SetDefault("kubernetes.consumer_stall_timeout", 5*time.Minute)
+	viper.SetDefault("startup.timeout", time.Minute)
+	viper.SetDefault("codecs.schema_registry.timeout", 5*time.Second)
+	viper.SetDefault("startup.initial_backoff", time.Second)
+	viper.SetDefault("startup.max_backoff", 15*time.Second)
+	viper.SetDefault("startup.restore_tenants", true)
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config: %v", err)
 	}
@@ -47,6 +718,148 @@ func LoadConfig() (*Config, error) {
 	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
 		config.Database.URL = dbURL
 	}
+	if version := os.Getenv("HANDOVER_VERSION"); version != "" {
+		config.Handover.Version = version
+	}
+	if jwtSecret := os.Getenv("JWT_SECRET"); jwtSecret != "" {
+		config.Security.JWTSecret = jwtSecret
+	}
+	if redactionKey := os.Getenv("REDACTION_KEY"); redactionKey != "" {
+		config.Security.RedactionKey = redactionKey
+	}
+
+	// Secret yang di-mount sebagai file (mis. oleh Vault agent) dibaca ulang saat rotasi
+	for env, target := range map[string]*string{
+		"RABBITMQ_URL_FILE":  &config.RabbitMQ.URL,
+		"DATABASE_URL_FILE":  &config.Database.URL,
+		"JWT_SECRET_FILE":    &config.Security.JWTSecret,
+		"REDACTION_KEY_FILE": &config.Security.RedactionKey,
+	} {
+		path := os.Getenv(env)
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", env, err)
+		}
+		*target = strings.TrimSpace(string(content))
+	}
+
+	// Satu token per baris
+	if path := os.Getenv("ADMIN_TOKENS_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ADMIN_TOKENS_FILE: %v", err)
+		}
+		config.Server.Admin.Tokens = nil
+		for _, line := range strings.Split(string(content), "\n") {
+			if token := strings.TrimSpace(line); token != "" {
+				config.Server.Admin.Tokens = append(config.Server.Admin.Tokens, token)
+			}
+		}
+	}
+
+	if admin := config.Server.Admin; admin.Listen != "" {
+		if len(admin.Tokens) == 0 && admin.TLS.ClientCAFile == "" {
+			return nil, fmt.Errorf("server.admin needs tokens or tls.client_ca_file")
+		}
+		if (admin.TLS.CertFile == "") != (admin.TLS.KeyFile == "") || (admin.TLS.ClientCAFile != "" && admin.TLS.CertFile == "") {
+			return nil, fmt.Errorf("server.admin.tls needs cert_file and key_file")
+		}
+	}
+
+	if grpc := config.Server.GRPC; (grpc.CertFile == "") != (grpc.KeyFile == "") {
+		return nil, fmt.Errorf("server.grpc needs both cert_file and key_file for TLS")
+	}
+
+	if !strings.Contains(config.RabbitMQ.QueueNameTemplate, "{tenant_id}") {
+		return nil, fmt.Errorf("rabbitmq.queue_name_template must contain {tenant_id}")
+	}
+	switch config.Database.PartitionOnDelete {
+	case "drop", "detach", "keep":
+	default:
+		return nil, fmt.Errorf("database.partition_on_delete must be drop, detach or keep")
+	}
+	if ratio := config.Tracing.SampleRatio; ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("tracing.sample_ratio must be between 0 and 1")
+	}
+	if config.RabbitMQ.Connections < 1 {
+		return nil, fmt.Errorf("rabbitmq.connections must be at least 1")
+	}
+	if c := config.Concurrency; c.MaxWorkers < 0 || c.MaxTenantWorkers < 1 || (c.MaxWorkers > 0 && c.MaxTenantWorkers > c.MaxWorkers) {
+		return nil, fmt.Errorf("concurrency.max_tenant_workers must be positive and not above concurrency.max_workers unless that is 0")
+	}
+	if retry := config.Retry; retry.MaxAttempts < 1 || retry.Backoff < time.Second || retry.MaxBackoff < retry.Backoff {
+		return nil, fmt.Errorf("retry.max_attempts must be positive, retry.backoff at least 1s and retry.max_backoff not less than it")
+	}
+	if config.Retry.Jitter < 0 || config.Retry.Jitter > 1 {
+		return nil, fmt.Errorf("retry.jitter must be between 0 and 1")
+	}
+	if config.Security.RotationGracePeriod < 0 {
+		return nil, fmt.Errorf("security.rotation_grace_period must not be negative")
+	}
+	if quotas := config.Quotas; quotas.MaxMessages < 0 || quotas.MaxBytes < 0 || quotas.MaxQueueDepth < 0 || quotas.UsageTTL <= 0 {
+		return nil, fmt.Errorf("quotas limits must not be negative and quotas.usage_ttl must be positive")
+	}
+	if quarantine := config.Quarantine; quarantine.Threshold < 0 || quarantine.Window <= 0 || quarantine.Retention < 0 {
+		return nil, fmt.Errorf("quarantine.threshold and retention must not be negative and quarantine.window must be positive")
+	}
+	if config.Kubernetes.ConsumerStallTimeout < 0 {
+		return nil, fmt.Errorf("kubernetes.consumer_stall_timeout must not be negative")
+	}
+	if config.Outbox.RelayInterval <= 0 || config.Outbox.BatchSize < 1 {
+		return nil, fmt.Errorf("outbox.relay_interval and batch_size must be positive")
+	}
+	if hooks := config.Webhooks; hooks.Timeout <= 0 || hooks.MaxAttempts < 1 || hooks.Backoff <= 0 || hooks.PollInterval <= 0 || hooks.Workers < 1 {
+		return nil, fmt.Errorf("webhooks.timeout, max_attempts, backoff, poll_interval and workers must be positive")
+	}
+	if console := config.Console; console.Enabled && (console.SendBuffer < 1 || console.WriteTimeout <= 0 || console.PingInterval <= 0 || console.MaxSubscriptions < 1) {
+		return nil, fmt.Errorf("console.send_buffer, write_timeout, ping_interval and max_subscriptions must be positive")
+	}
+	if config.Retention.Interval <= 0 || config.Retention.BatchSize < 1 {
+		return nil, fmt.Errorf("retention.interval and batch_size must be positive")
+	}
+	if archive := config.Archive; archive.Enabled && (archive.Interval <= 0 || archive.OlderThan <= 0 || archive.Endpoint == "" || archive.Bucket == "") {
+		return nil, fmt.Errorf("archive.interval and older_than must be positive and endpoint and bucket set")
+	}
+	if config.Webhooks.MaxBackoff < config.Webhooks.Backoff {
+		return nil, fmt.Errorf("webhooks.max_backoff must not be less than webhooks.backoff")
+	}
+	switch config.Transport.Kind {
+	case "rabbitmq":
+	case "kafka":
+		kafka := config.Transport.Kafka
+		if len(kafka.Brokers) == 0 {
+			return nil, fmt.Errorf("transport.kafka.brokers must not be empty")
+		}
+		if kafka.TopicStrategy != "tenant_topic" && kafka.TopicStrategy != "partition_key" {
+			return nil, fmt.Errorf("transport.kafka.topic_strategy must be tenant_topic or partition_key")
+		}
+		if kafka.Partitions < 1 || kafka.ReplicationFactor < 1 {
+			return nil, fmt.Errorf("transport.kafka.partitions and replication_factor must be at least 1")
+		}
+	case "nats":
+		nats := config.Transport.NATS
+		if nats.URL == "" {
+			return nil, fmt.Errorf("transport.nats.url must not be empty")
+		}
+		if nats.Replicas < 1 {
+			return nil, fmt.Errorf("transport.nats.replicas must be at least 1")
+		}
+		if nats.AckWait <= 0 {
+			return nil, fmt.Errorf("transport.nats.ack_wait must be positive")
+		}
+	case "amqp10":
+		if config.Transport.AMQP10.URL == "" {
+			return nil, fmt.Errorf("transport.amqp10.url must not be empty")
+		}
+	default:
+		return nil, fmt.Errorf("transport.kind must be rabbitmq, kafka, nats or amqp10")
+	}
+	if config.Transport.Kind != "rabbitmq" && config.Events.Queue != "" {
+		return nil, fmt.Errorf("events.queue needs transport.kind rabbitmq")
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,185 @@
+// Package console fans the events of this instance out to the clients of the
+// real-time console. Clients subscribe to tenants and get the events of
+// those tenants only; a client that falls behind loses events rather than
+// holding up the consumers emitting them.
+package console
+
+import (
+	"sync"
+
+	"multi-tenant-messaging/internal/events"
+)
+
+// Hub tracks the connected clients and the tenants each one subscribed to.
+// It is an events.Listener.
+type Hub struct {
+	bufferSize int
+
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+	// watched counts the subscribed clients of every tenant
+	watched map[string]int
+	closed  bool
+}
+
+// NewHub creates a Hub whose clients buffer up to bufferSize events each
+func NewHub(bufferSize int) *Hub {
+	if bufferSize < 1 {
+		bufferSize = 256
+	}
+	return &Hub{
+		bufferSize: bufferSize,
+		clients:    make(map[*Client]struct{}),
+		watched:    make(map[string]int),
+	}
+}
+
+// Register adds a client without subscriptions. It returns nil once the
+// hub is closed.
+func (h *Hub) Register() *Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	c := &Client{
+		hub:     h,
+		tenants: make(map[string]bool),
+		send:    make(chan events.Event, h.bufferSize),
+		done:    make(chan struct{}),
+	}
+	h.clients[c] = struct{}{}
+	return c
+}
+
+// Unregister removes the client and its subscriptions
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	for tenantID := range c.tenants {
+		h.unwatch(tenantID)
+	}
+	delete(h.clients, c)
+	close(c.done)
+}
+
+// Subscribe adds tenantIDs to the client's subscriptions
+func (h *Hub) Subscribe(c *Client, tenantIDs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	for _, tenantID := range tenantIDs {
+		if !c.tenants[tenantID] {
+			c.tenants[tenantID] = true
+			h.watched[tenantID]++
+		}
+	}
+}
+
+// Unsubscribe removes tenantIDs from the client's subscriptions
+func (h *Hub) Unsubscribe(c *Client, tenantIDs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	for _, tenantID := range tenantIDs {
+		if c.tenants[tenantID] {
+			delete(c.tenants, tenantID)
+			h.unwatch(tenantID)
+		}
+	}
+}
+
+func (h *Hub) unwatch(tenantID string) {
+	if h.watched[tenantID]--; h.watched[tenantID] <= 0 {
+		delete(h.watched, tenantID)
+	}
+}
+
+// Wants reports whether any client subscribed to the tenant
+func (h *Hub) Wants(tenantID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.watched[tenantID] > 0
+}
+
+// Receive queues event for every client subscribed to its tenant. It never
+// blocks: a client whose buffer is full misses the event.
+func (h *Hub) Receive(event events.Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.watched[event.TenantID] == 0 {
+		return
+	}
+	for c := range h.clients {
+		if c.tenants[event.TenantID] {
+			c.offer(event)
+		}
+	}
+}
+
+// Close disconnects every client and refuses new ones, e.g. on shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.clients {
+		close(c.done)
+		delete(h.clients, c)
+	}
+	h.watched = make(map[string]int)
+}
+
+// Client is one console connection
+type Client struct {
+	hub *Hub
+	// tenants is guarded by the hub's lock
+	tenants map[string]bool
+	send    chan events.Event
+	done    chan struct{}
+
+	mu      sync.Mutex
+	dropped int64
+}
+
+// Events returns the events queued for the client
+func (c *Client) Events() <-chan events.Event {
+	return c.send
+}
+
+// Done is closed once the client is unregistered or the hub is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Subscriptions returns the number of tenants the client subscribed to
+func (c *Client) Subscriptions() int {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	return len(c.tenants)
+}
+
+// TakeDropped returns how many events the client missed since the last call
+func (c *Client) TakeDropped() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dropped := c.dropped
+	c.dropped = 0
+	return dropped
+}
+
+func (c *Client) offer(event events.Event) {
+	select {
+	case c.send <- event:
+	default:
+		c.mu.Lock()
+		c.dropped++
+		c.mu.Unlock()
+	}
+}
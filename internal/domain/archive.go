@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// MessageArchive is an object in the archive bucket holding a tenant's
+// messages of one day, one gzipped JSON row per line
+type MessageArchive struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	// Day is the UTC day the messages were stored, as YYYY-MM-DD
+	Day          string    `json:"day"`
+	Bucket       string    `json:"bucket"`
+	ObjectKey    string    `json:"object_key"`
+	MessageCount int64     `json:"message_count"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+	// RestoredAt is when the archive was last loaded back into the tenant's partition
+	RestoredAt    *time.Time `json:"restored_at,omitempty"`
+	RestoredCount int64      `json:"restored_count,omitempty"`
+	// HeldUntil keeps the restored messages from being archived again
+	HeldUntil *time.Time `json:"held_until,omitempty"`
+}
+
+// ArchiveRestoreRequest restores an archive into the tenant's partition
+type ArchiveRestoreRequest struct {
+	// HoldSeconds is how long the restored messages are not archived again,
+	// defaults to a day
+	HoldSeconds int `json:"hold_seconds"`
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Channel is a named queue of a tenant, e.g. orders or notifications, with
+// its own consumer and worker pool. Its messages go through the same
+// processing as the tenant's main queue and are stored with the channel name.
+type Channel struct {
+	TenantID  string `json:"tenant_id"`
+	Name      string `json:"name"`
+	QueueName string `json:"queue_name"`
+	Workers   int    `json:"workers"`
+	// Ordered processes the channel's messages on a single lane in strict queue order
+	Ordered bool `json:"ordered"`
+	// PartitionKey is a payload field path; messages with the same value are
+	// processed in order on the same lane
+	PartitionKey string    `json:"partition_key,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ConsumerConfig is the consumer configuration of the channel
+func (c Channel) ConsumerConfig() TenantConfig {
+	workers := c.Workers
+	if c.Ordered {
+		workers = 1
+	}
+	return TenantConfig{
+		TenantID:     c.TenantID,
+		Workers:      workers,
+		Ordered:      c.Ordered,
+		PartitionKey: c.PartitionKey,
+		QueueName:    c.QueueName,
+		Channel:      c.Name,
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Consumer group start positions
+const (
+	ConsumerGroupStartEarliest = "earliest"
+	ConsumerGroupStartLatest   = "latest"
+)
+
+// ConsumerGroup is an external application reading a tenant's processed
+// messages oldest first. Every group keeps its own committed offset, so
+// several applications can read the same messages independently.
+type ConsumerGroup struct {
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+	// Offset is the ID of the last committed message, empty if the group
+	// reads from the tenant's first message
+	Offset          string     `json:"offset"`
+	OffsetCreatedAt *time.Time `json:"offset_created_at,omitempty"`
+	// Lag is the number of stored messages after the offset
+	Lag       int64     `json:"lag"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConsumerGroupBatch is a batch of messages read by a consumer group
+type ConsumerGroupBatch struct {
+	Data []Message `json:"data"`
+	// NextOffset is the offset to commit once Data is processed, the current
+	// offset if the batch is empty
+	NextOffset string `json:"next_offset"`
+	// Committed is true if the offset already moved past the batch
+	Committed bool `json:"committed"`
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DLQ retry attempt outcomes
+const (
+	// DLQRetryRetried means the message was moved back to the tenant queue
+	DLQRetryRetried = "retried"
+	// DLQRetryGaveUp means the schedule is exhausted and the message stays in the DLQ
+	DLQRetryGaveUp = "gave_up"
+)
+
+// DLQRetryPolicy controls automatic retries of a tenant's dead-lettered
+// messages. The n-th retry happens ScheduleSeconds[n-1] after the message
+// (re)entered the DLQ; after the last one the scheduler gives up.
+type DLQRetryPolicy struct {
+	Enabled         bool  `json:"enabled"`
+	ScheduleSeconds []int `json:"schedule_seconds"`
+	// Default is true when the tenant has no override and the config defaults apply
+	Default bool `json:"default"`
+}
+
+// DLQRetryAttempt records one decision of the retry scheduler for a message
+type DLQRetryAttempt struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	MessageID string    `json:"message_id"`
+	Attempt   int       `json:"attempt"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Error report reasons, one per kind of permanent failure
+const (
+	ErrorReasonSchemaValidation = "schema_validation"
+	ErrorReasonDecode           = "decode"
+	ErrorReasonRejected         = "rejected"
+	ErrorReasonFilterLimit      = "filter_limit"
+	// ErrorReasonClaimCheckNotAllowed is a claim-check reference to a host or
+	// path the tenant may not read from
+	ErrorReasonClaimCheckNotAllowed = "claim_check_not_allowed"
+	// ErrorReasonClaimCheckChecksum is a claim-check blob that does not match its sha256
+	ErrorReasonClaimCheckChecksum = "claim_check_checksum"
+	// ErrorReasonMaxAttempts is a message that kept failing until its backoff
+	// attempts ran out
+	ErrorReasonMaxAttempts = "max_attempts"
+	// ErrorReasonPanic is a message whose processing panicked
+	ErrorReasonPanic = "panic"
+)
+
+// ErrorReport tells a producer that one of its messages failed permanently
+// and was dead-lettered
+type ErrorReport struct {
+	TenantID    string    `json:"tenant_id"`
+	MessageID   string    `json:"message_id"`
+	MessageType string    `json:"message_type,omitempty"`
+	Channel     string    `json:"channel,omitempty"`
+	Reason      string    `json:"reason"`
+	Error       string    `json:"error"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+// DLQTransform fixes a dead-lettered message before it is redriven
+type DLQTransform struct {
+	// Patch is a JSON merge patch (RFC 7396) applied to the payload; null removes a field
+	Patch json.RawMessage `json:"patch,omitempty"`
+	// RemoveHeaders are AMQP headers dropped from the message
+	RemoveHeaders []string `json:"remove_headers,omitempty"`
+}
+
+// DLQRedrive selects messages in a tenant's DLQ and moves them back to the
+// tenant queue, optionally transformed
+type DLQRedrive struct {
+	// Match is a CEL expression like a filter rule's; empty matches every message
+	Match     string        `json:"match,omitempty"`
+	Transform *DLQTransform `json:"transform,omitempty"`
+	// Limit caps how many messages are redriven
+	Limit int `json:"limit,omitempty"`
+	// DryRun reports what would be redriven and leaves the DLQ unchanged
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// DLQRedriveResult counts what a redrive did with the messages it scanned
+type DLQRedriveResult struct {
+	Scanned  int  `json:"scanned"`
+	Redriven int  `json:"redriven"`
+	Skipped  int  `json:"skipped"`
+	Failed   int  `json:"failed"`
+	DryRun   bool `json:"dry_run"`
+	// Errors describe the first failed messages, which stay in the DLQ
+	Errors []string `json:"errors,omitempty"`
+	// Preview holds the first transformed messages of a dry run
+	Preview []DLQRedrivePreview `json:"preview,omitempty"`
+}
+
+// DLQRedrivePreview is a message as a redrive would publish it
+type DLQRedrivePreview struct {
+	MessageID string          `json:"message_id"`
+	Headers   map[string]any  `json:"headers"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// DLQReplay selects messages in a tenant's DLQ by ID to move back unchanged
+type DLQReplay struct {
+	// MessageIDs are the messages to replay
+	MessageIDs []string `json:"message_ids,omitempty"`
+	// All replays every message instead
+	All bool `json:"all,omitempty"`
+	// DryRun reports what would be replayed and leaves the DLQ unchanged
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// DeadLetter is a message waiting in a tenant's DLQ
+type DeadLetter struct {
+	MessageID   string `json:"message_id"`
+	MessageType string `json:"message_type,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// Channel is the tenant channel the message came from, empty for the main queue
+	Channel string `json:"channel,omitempty"`
+	// Reason is why the broker dead-lettered the message, e.g. rejected
+	Reason         string         `json:"reason"`
+	RetryCount     int            `json:"retry_count"`
+	DeadLetteredAt *time.Time     `json:"dead_lettered_at,omitempty"`
+	Headers        map[string]any `json:"headers"`
+	// Payload is the JSON payload, or the JSON projection of a protobuf or
+	// Avro payload; it is omitted when the payload cannot be decoded
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// DeadLetterPage is a page of a tenant's DLQ in queue order
+type DeadLetterPage struct {
+	Messages []DeadLetter `json:"messages"`
+	// Total is the number of messages in the DLQ
+	Total int `json:"total"`
+	// NextOffset is the offset of the next page, 0 on the last page
+	NextOffset int `json:"next_offset"`
+}
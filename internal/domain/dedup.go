@@ -0,0 +1,19 @@
+package domain
+
+// What messages are deduplicated on
+const (
+	// DedupKeyMessageID keys on the AMQP message-id; messages without one are
+	// never deduplicated
+	DedupKeyMessageID = "message_id"
+	// DedupKeyPayloadHash keys on the SHA-256 of the message body, for
+	// publishers that do not set a message-id
+	DedupKeyPayloadHash = "payload_hash"
+)
+
+// DedupConfig is a tenant's deduplication setting
+type DedupConfig struct {
+	// WindowSeconds is how long a key is remembered, 0 disables deduplication
+	WindowSeconds int `json:"window_seconds"`
+	// Key is message_id or payload_hash
+	Key string `json:"key"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Delivery states
+const (
+	DeliveryStateQueued     = "queued"
+	DeliveryStateProcessing = "processing"
+)
+
+// DeliveryInfo describes an unacked RabbitMQ delivery held by a tenant consumer
+type DeliveryInfo struct {
+	TenantID    string     `json:"tenant_id"`
+	DeliveryTag uint64     `json:"delivery_tag"`
+	State       string     `json:"state"`
+	Worker      int        `json:"worker,omitempty"`
+	ReceivedAt  time.Time  `json:"received_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	AgeSeconds  float64    `json:"age_seconds"`
+}
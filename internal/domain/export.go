@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Export job statuses
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// Export formats
+const (
+	ExportFormatNDJSON = "ndjson"
+	// ExportFormatParquet writes one Parquet file per tenant and day
+	ExportFormatParquet = "parquet"
+)
+
+// ExportJob represents an asynchronous message export
+type ExportJob struct {
+	ID           string    `json:"id"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	Format       string    `json:"format"`
+	Status       string    `json:"status"`
+	ExportedRows int64     `json:"exported_rows"`
+	ChunkCount   int       `json:"chunk_count"`
+	Error        string    `json:"error,omitempty"`
+	DownloadURL  string    `json:"download_url,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
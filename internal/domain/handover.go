@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// ConsumerOwner describes which instance consumes a tenant's queue
+type ConsumerOwner struct {
+	TenantID   string    `json:"tenant_id"`
+	InstanceID string    `json:"instance_id"`
+	Version    string    `json:"version"`
+	HandoverTo string    `json:"handover_to,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
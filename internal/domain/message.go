@@ -3,15 +3,63 @@ package domain
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// Message statuses
+const (
+	MessageStatusProcessed = "processed"
+	MessageStatusExpired   = "expired"
+)
+
 // Message represents a message in the system
 type Message struct {
-	ID        string    `json:"id"`
-	TenantID  string    `json:"tenant_id"`
-	Payload   JSONB     `json:"payload"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Payload  JSONB  `json:"payload"`
+	Status   string `json:"status"`
+	// MessageType is the AMQP type property the payload schema is chosen by
+	MessageType string `json:"message_type"`
+	// SchemaVersion is the schema version the payload validated against, nil if none
+	SchemaVersion *int `json:"schema_version"`
+	// Tags were added by the tenant's filter rules
+	Tags Tags `json:"tags"`
+	// Channel is the tenant channel the message arrived on, empty for the main queue
+	Channel string `json:"channel"`
+	// ContentType is set for protobuf and Avro payloads; Payload then holds
+	// their JSON projection and RawPayload the original bytes
+	ContentType string `json:"content_type,omitempty"`
+	RawPayload  []byte `json:"raw_payload,omitempty"`
+	// DeliverAt is when a scheduled message was due, nil if it was not delayed
+	DeliverAt *time.Time `json:"deliver_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Tags is a list of message tags stored as a JSONB array
+type Tags []string
+
+// Scan implements the sql.Scanner interface
+func (t *Tags) Scan(value interface{}) error {
+	*t = Tags{}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", value)
+	}
+}
+
+// Value implements the driver.Valuer interface
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
 }
 
 // JSONB is a type for handling JSONB fields in PostgreSQL
@@ -37,3 +85,43 @@ func (j JSONB) Value() (driver.Value, error) {
 	}
 	return json.Marshal(j)
 }
+
+// PublishRequest is a message published to a tenant's queue over HTTP
+type PublishRequest struct {
+	// Payload is the JSON message body
+	Payload json.RawMessage `json:"payload" binding:"required"`
+	// MessageType is set as the AMQP type property, which picks the payload schema
+	MessageType string `json:"message_type"`
+	// Channel publishes to one of the tenant's channels instead of the main queue
+	Channel string `json:"channel"`
+	// DelaySeconds holds the message back this long before it is delivered
+	DelaySeconds int `json:"delay_seconds"`
+	// DeliverAt holds the message back until this time instead, at most 7
+	// days ahead; a time already passed delivers it right away
+	DeliverAt *time.Time `json:"deliver_at"`
+	// Priority from 0 to 9 lets the message overtake lower ones in the
+	// tenant's worker pool and, on a priority queue, at the broker
+	Priority int `json:"priority"`
+}
+
+// PublishResult identifies a published message
+type PublishResult struct {
+	MessageID string `json:"message_id"`
+	Queue     string `json:"queue"`
+	// DeliverAt is when a delayed message reaches the queue
+	DeliverAt *time.Time `json:"deliver_at,omitempty"`
+}
+
+// MessageQuery selects the stored messages of a page
+type MessageQuery struct {
+	// TenantID only selects messages of this tenant
+	TenantID string `json:"tenant_id"`
+	// CreatedAfter and CreatedBefore bound the creation time, exclusive
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+	// Payload only selects messages whose payload contains this JSON document
+	Payload json.RawMessage `json:"payload"`
+	// Cursor is the next_cursor of the previous page
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+// Migration is a schema migration known to this build
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// MigrationStatus compares the database schema with the migrations of this build
+type MigrationStatus struct {
+	// Version is the last applied migration, 0 if none
+	Version    uint        `json:"version"`
+	Dirty      bool        `json:"dirty"`
+	Latest     uint        `json:"latest"`
+	Pending    int         `json:"pending"`
+	Migrations []Migration `json:"migrations"`
+}
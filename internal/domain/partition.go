@@ -0,0 +1,11 @@
+package domain
+
+// Partition describes a tenant partition of the messages table
+type Partition struct {
+	Name          string `json:"name"`
+	TenantID      string `json:"tenant_id"`
+	Attached      bool   `json:"attached"`
+	EstimatedRows int64  `json:"estimated_rows"`
+	Rows          *int64 `json:"rows,omitempty"`
+	SizeBytes     int64  `json:"size_bytes"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Tenant provisioning statuses
+const (
+	ProvisioningStatusPending = "pending"
+	ProvisioningStatusReady   = "ready"
+	ProvisioningStatusFailed  = "failed"
+)
+
+// ProvisioningJob tracks the asynchronous creation of a tenant's partition,
+// queues and consumer
+type ProvisioningJob struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QuarantinedMessage is a poison message taken off the broker after failing
+// the same way too often, so it cannot loop through the retry queues or the
+// DLQ forever
+type QuarantinedMessage struct {
+	ID          string         `json:"id"`
+	TenantID    string         `json:"tenant_id"`
+	MessageID   string         `json:"message_id"`
+	Channel     string         `json:"channel,omitempty"`
+	MessageType string         `json:"message_type,omitempty"`
+	ContentType string         `json:"content_type,omitempty"`
+	Headers     map[string]any `json:"headers"`
+	// Payload is the payload when it is JSON; otherwise RawPayload holds it
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	RawPayload []byte          `json:"raw_payload,omitempty"`
+	// Reason is the kind of the last failure: panic, decode or schema_validation
+	Reason        string    `json:"reason"`
+	Error         string    `json:"error"`
+	Failures      int       `json:"failures"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
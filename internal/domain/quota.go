@@ -0,0 +1,37 @@
+package domain
+
+// Quotas a tenant can exceed, as reported in QuotaError and the
+// salva_quota_exceeded_total metric
+const (
+	QuotaMessages   = "messages"
+	QuotaBytes      = "bytes"
+	QuotaQueueDepth = "queue_depth"
+)
+
+// TenantQuota caps what a tenant keeps stored and queued; publishing beyond
+// a limit is rejected. 0 leaves a limit off.
+type TenantQuota struct {
+	// MaxMessages caps the rows of the tenant's messages partition
+	MaxMessages int64 `json:"max_messages"`
+	// MaxBytes caps the size of the partition including its indexes
+	MaxBytes int64 `json:"max_bytes"`
+	// MaxQueueDepth caps the ready messages in the tenant's main queue
+	MaxQueueDepth int64 `json:"max_queue_depth"`
+	// Default is true when the tenant has no quota of its own and the config defaults apply
+	Default bool `json:"default"`
+}
+
+// QuotaUsage is what a tenant uses of its quota
+type QuotaUsage struct {
+	Messages int64 `json:"messages"`
+	Bytes    int64 `json:"bytes"`
+	// QueueDepth is nil when the broker could not be asked
+	QueueDepth *int64 `json:"queue_depth"`
+}
+
+// TenantDetail is a tenant with its quota and what it uses of it
+type TenantDetail struct {
+	TenantSummary
+	Quota TenantQuota `json:"quota"`
+	Usage QuotaUsage  `json:"usage"`
+}
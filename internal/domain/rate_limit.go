@@ -0,0 +1,12 @@
+package domain
+
+// RateLimit is a tenant's token-bucket limit on published messages
+type RateLimit struct {
+	// MessagesPerSecond is the sustained rate, 0 disables the limit
+	MessagesPerSecond float64 `json:"messages_per_second"`
+	// Burst is how many messages may be published at once above the rate
+	Burst int `json:"burst"`
+	// Consume also paces the tenant's consumers at the same rate; messages
+	// beyond the prefetch wait in the queue
+	Consume bool `json:"consume"`
+}
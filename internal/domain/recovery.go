@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Recovery orders
+const (
+	RecoveryOldestFirst = "oldest_first"
+	RecoveryNewestFirst = "newest_first"
+)
+
+// Recovery statuses
+const (
+	RecoveryStatusParking   = "parking"
+	RecoveryStatusRunning   = "running"
+	RecoveryStatusCompleted = "completed"
+	RecoveryStatusCancelled = "cancelled"
+	RecoveryStatusFailed    = "failed"
+)
+
+// Recovery drains the backlog of a tenant's main queue after an outage. The
+// backlog is parked in a recovery queue and processed with Workers extra
+// workers, oldest or newest messages first.
+type Recovery struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Order    string `json:"order"`
+	Workers  int    `json:"workers"`
+	Status   string `json:"status"`
+	// Backlog is the number of messages parked when the recovery started
+	Backlog int64 `json:"backlog"`
+	// Remaining is the number of parked messages not processed yet
+	Remaining int64 `json:"remaining"`
+	Processed int64 `json:"processed"`
+	// Progress is Processed / Backlog, between 0 and 1
+	Progress float64 `json:"progress"`
+	// Rate is the number of backlog messages processed per second so far
+	Rate float64 `json:"rate"`
+	// ETASeconds estimates when the backlog is cleared at the current rate
+	ETASeconds *int64     `json:"eta_seconds,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	RunningAt  *time.Time `json:"running_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// RetentionPolicy is how long a tenant's stored messages are kept
+type RetentionPolicy struct {
+	// MaxAgeSeconds expires messages stored longer ago, 0 keeps them
+	MaxAgeSeconds int `json:"max_age_seconds"`
+	// MaxRows expires all but the newest messages, 0 keeps them
+	MaxRows int64 `json:"max_rows"`
+	// Archive moves expired messages to the tenant's archive table instead
+	// of deleting them
+	Archive bool `json:"archive"`
+}
+
+// Enabled reports whether the policy expires any messages
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAgeSeconds > 0 || p.MaxRows > 0
+}
+
+// PurgeRequest selects the messages a manual purge removes. Without Before
+// and KeepLatest the tenant's retention policy is applied.
+type PurgeRequest struct {
+	// Before purges messages stored before this time
+	Before *time.Time `json:"before"`
+	// KeepLatest purges all but this many newest messages
+	KeepLatest int64 `json:"keep_latest"`
+	// Archive moves the messages to the archive table; defaults to the policy's
+	Archive *bool `json:"archive"`
+}
+
+// PurgeResult reports a purge
+type PurgeResult struct {
+	TenantID string `json:"tenant_id"`
+	// Purged is the number of messages deleted or archived
+	Purged   int64 `json:"purged"`
+	Archived bool  `json:"archived"`
+	// ArchiveTable holds the archived messages
+	ArchiveTable string `json:"archive_table,omitempty"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+// RetryPolicy controls how a tenant's messages that failed for a reason that
+// may pass are retried before being dead-lettered. The n-th retry waits
+// BackoffSeconds * 2^(n-1), capped at MaxBackoffSeconds.
+type RetryPolicy struct {
+	// MaxAttempts counts the first delivery, so 1 dead-letters on the first failure
+	MaxAttempts       int `json:"max_attempts"`
+	BackoffSeconds    int `json:"backoff_seconds"`
+	MaxBackoffSeconds int `json:"max_backoff_seconds"`
+	// Jitter spreads each wait by up to this share of it in either direction, 0 to 1
+	Jitter float64 `json:"jitter"`
+	// Default is true when the tenant has no override and the config defaults apply
+	Default bool `json:"default"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PayloadSchema is a version of a tenant's JSON Schema for one message type
+type PayloadSchema struct {
+	TenantID    string          `json:"tenant_id"`
+	MessageType string          `json:"message_type"`
+	Version     int             `json:"version"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// SchemaUsage counts the messages of a type stored in a period per schema
+// version; a nil version means the message was not validated
+type SchemaUsage struct {
+	Period  time.Time `json:"period"`
+	Version *int      `json:"version"`
+	Count   int64     `json:"count"`
+}
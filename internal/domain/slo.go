@@ -0,0 +1,27 @@
+package domain
+
+// SLOObjective is a latency objective: Target of a tenant's messages must be
+// persisted within ThresholdMs of being published
+type SLOObjective struct {
+	Target      float64 `json:"target"`
+	ThresholdMs int64   `json:"threshold_ms"`
+	// Default is true when the tenant has no override and the config defaults apply
+	Default bool `json:"default"`
+}
+
+// SLOStatus is a tenant's SLO compliance over the tracking window, as seen by
+// the instance consuming the tenant
+type SLOStatus struct {
+	TenantID      string       `json:"tenant_id"`
+	Objective     SLOObjective `json:"objective"`
+	WindowSeconds int          `json:"window_seconds"`
+	Total         int64        `json:"total"`
+	Good          int64        `json:"good"`
+	// Compliance is Good/Total, nil when no message was processed in the window
+	Compliance *float64 `json:"compliance"`
+	// ErrorBudgetRemaining is the share of the window's error budget not yet spent
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	// BurnRate is how fast the error budget is spent per lookback window;
+	// 1 spends exactly the budget over the SLO window
+	BurnRate map[string]float64 `json:"burn_rate"`
+}
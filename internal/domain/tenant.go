@@ -2,18 +2,123 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 )
 
+// ErrNotResizable is returned when a tenant's consumer cannot change its
+// workers and prefetch while it runs and has to be restarted instead
+var ErrNotResizable = errors.New("consumer cannot be resized in place")
+
 type Tenant struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
 	CreatedAt string `json:"created_at"`
 }
 
+// Tenant statuses in the tenant list
+const (
+	TenantStatusActive = "active"
+	TenantStatusIdle   = "idle"
+	// TenantStatusUnknown is reported when the broker could not be asked
+	TenantStatusUnknown = "unknown"
+	// TenantStatusSuspended is reported while consuming the tenant is suspended
+	TenantStatusSuspended = "suspended"
+)
+
+// TenantSummary is a tenant in the tenant list
+type TenantSummary struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	QueueName string `json:"queue_name"`
+	// Workers is the configured worker count of the main queue
+	Workers int `json:"workers"`
+	// QueueDepth is the number of ready messages in the main queue, nil if
+	// the broker could not be asked
+	QueueDepth *int64 `json:"queue_depth"`
+	// Status is active while any instance consumes the main queue
+	Status string `json:"status"`
+	// Migrated is set for tenants moved to another deployment
+	Migrated bool `json:"migrated,omitempty"`
+	// SuspendedAt is set while the tenant is suspended
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TenantSuspension reports whether consuming a tenant is suspended
+type TenantSuspension struct {
+	TenantID    string     `json:"tenant_id"`
+	Suspended   bool       `json:"suspended"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+}
+
+// TenantStats is an operator's view of how a tenant is being consumed.
+// Depths and consumers come from the broker; the rest is what the instance
+// answering has seen.
+type TenantStats struct {
+	TenantID string `json:"tenant_id"`
+	// QueueDepth and DLQDepth are ready messages, nil if the broker could not be asked
+	QueueDepth *int64 `json:"queue_depth"`
+	DLQDepth   *int64 `json:"dlq_depth"`
+	// Consumers is the number of consumers of the main queue over all instances
+	Consumers *int `json:"consumers"`
+	// Workers is the tenant's worker count here, 0 if not consumed here
+	Workers int `json:"workers"`
+	// ActiveWorkers are the workers processing a message right now, on RabbitMQ
+	ActiveWorkers int   `json:"active_workers"`
+	Consumed1m    int64 `json:"consumed_1m"`
+	Consumed5m    int64 `json:"consumed_5m"`
+	Consumed1h    int64 `json:"consumed_1h"`
+	// AvgLatencyMs is the mean processing time over the last hour
+	AvgLatencyMs   float64    `json:"avg_latency_ms"`
+	LastConsumedAt *time.Time `json:"last_consumed_at"`
+}
+
 type TenantConfig struct {
 	TenantID string `json:"tenant_id"`
 	Workers  int    `json:"workers"`
+	// Ordered processes messages on a single lane in strict queue order
+	Ordered bool `json:"ordered"`
+	// PartitionKey is a payload field path; messages with the same value are
+	// processed in order on the same lane
+	PartitionKey string `json:"partition_key,omitempty"`
+	// QueueName is the main queue the tenant is consumed from
+	QueueName string `json:"queue_name"`
+	// Channel names the tenant channel the consumer serves, empty for the main queue
+	Channel string `json:"channel,omitempty"`
+	// Prefetch limits the consumer's unacked deliveries, 0 for a default
+	// per worker. Ordered consumers always use 1.
+	Prefetch int `json:"prefetch,omitempty"`
+}
+
+// QueueRename reports moving a tenant to a new main queue name
+type QueueRename struct {
+	TenantID string `json:"tenant_id"`
+	OldName  string `json:"old_name"`
+	NewName  string `json:"new_name"`
+	// Moved is the number of messages shoveled from the old queue
+	Moved int `json:"moved"`
+	// Renamed is set once the old queue has been deleted
+	Renamed bool   `json:"renamed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WorkerBounds limit the workers a tenant can set for its main queue
+type WorkerBounds struct {
+	MinWorkers int `json:"min_workers"`
+	MaxWorkers int `json:"max_workers"`
+	// Default is set when the tenant has no bounds of its own
+	Default bool `json:"default"`
+}
+
+// PriorityConfig is the highest AMQP priority a tenant's main queue orders
+// messages by, 0 for a plain queue
+type PriorityConfig struct {
+	MaxPriority int `json:"max_priority"`
+	// Queue is the main queue the tenant is consumed from
+	Queue string `json:"queue,omitempty"`
 }
 
 type TenantManager struct {
@@ -23,7 +128,18 @@ type TenantManager struct {
 
 type TenantContext struct {
 	CancelFunc context.CancelFunc
-	Config     TenantConfig
+	// Drain stops taking new messages and waits for in-flight ones to settle
+	Drain func(ctx context.Context) error
+	// Disconnected reports whether the consumer's channel was closed under it,
+	// e.g. because its broker connection dropped
+	Disconnected func() bool
+	// Stopped reports whether a consume loop of the consumer ended while the
+	// tenant is neither stopped nor drained and its channel is still open
+	Stopped func() bool
+	// Resize changes the workers and prefetch of the main queue consumer
+	// without stopping it, or returns ErrNotResizable
+	Resize func(workers, prefetch int) error
+	Config TenantConfig
 }
 
 func NewTenantManager() *TenantManager {
@@ -47,12 +163,54 @@ func (tm *TenantManager) RemoveTenant(tenantID string) {
 	}
 }
 
-func (tm *TenantManager) UpdateConfig(tenantID string, workers int) {
+// DrainTenant runs the tenant's Drain hook and then removes it. It reports
+// whether the tenant was active; the tenant is removed even if draining fails.
+func (tm *TenantManager) DrainTenant(ctx context.Context, tenantID string) (bool, error) {
+	tm.mu.RLock()
+	tc, exists := tm.activeTenants[tenantID]
+	tm.mu.RUnlock()
+	if !exists {
+		return false, nil
+	}
+
+	var err error
+	if tc.Drain != nil {
+		err = tc.Drain(ctx)
+	}
+	tm.RemoveTenant(tenantID)
+	return true, err
+}
+
+// TenantIDs returns the IDs of all active tenants
+func (tm *TenantManager) TenantIDs() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	ids := make([]string, 0, len(tm.activeTenants))
+	for id := range tm.activeTenants {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Resize runs the tenant's Resize hook and records the new workers and
+// prefetch in its config. It returns ErrNotResizable for tenants that are
+// not consumed here or have no hook.
+func (tm *TenantManager) Resize(tenantID string, workers, prefetch int) error {
+	tm.mu.RLock()
+	tc, exists := tm.activeTenants[tenantID]
+	tm.mu.RUnlock()
+	if !exists || tc.Resize == nil {
+		return ErrNotResizable
+	}
+	if err := tc.Resize(workers, prefetch); err != nil {
+		return err
+	}
+
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	if ctx, exists := tm.activeTenants[tenantID]; exists {
-		ctx.Config.Workers = workers
-	}
+	tc.Config.Workers = workers
+	tc.Config.Prefetch = prefetch
+	return nil
 }
 
 func (tm *TenantManager) GetConfig(tenantID string) (TenantConfig, bool) {
@@ -64,3 +222,20 @@ func (tm *TenantManager) GetConfig(tenantID string) (TenantConfig, bool) {
 	}
 	return ctx.Config, true
 }
+
+// Disconnected reports whether the tenant's consumer lost its broker channel
+func (tm *TenantManager) Disconnected(tenantID string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	ctx, exists := tm.activeTenants[tenantID]
+	return exists && ctx.Disconnected != nil && ctx.Disconnected()
+}
+
+// Stopped reports whether the tenant's consumer no longer takes messages
+// although it was not stopped
+func (tm *TenantManager) Stopped(tenantID string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	ctx, exists := tm.activeTenants[tenantID]
+	return exists && ctx.Stopped != nil && ctx.Stopped()
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Tenant migration steps, run in this order. A failed migration resumes at
+// the step it failed in.
+const (
+	TenantMigrationStepConfig    = "config"
+	TenantMigrationStepData      = "data"
+	TenantMigrationStepCutover   = "cutover"
+	TenantMigrationStepShovel    = "shovel"
+	TenantMigrationStepFinalSync = "final_sync"
+	TenantMigrationStepVerify    = "verify"
+	TenantMigrationStepDone      = "done"
+)
+
+// Tenant migration statuses
+const (
+	TenantMigrationStatusRunning   = "running"
+	TenantMigrationStatusFailed    = "failed"
+	TenantMigrationStatusCompleted = "completed"
+)
+
+// TenantMigration moves a tenant's config, messages and queued messages to
+// another deployment
+type TenantMigration struct {
+	ID               string    `json:"id"`
+	TenantID         string    `json:"tenant_id"`
+	Target           string    `json:"target"`
+	Step             string    `json:"step"`
+	Status           string    `json:"status"`
+	CopiedRows       int64     `json:"copied_rows"`
+	ShoveledMessages int64     `json:"shoveled_messages"`
+	SourceCount      *int64    `json:"source_count,omitempty"`
+	TargetCount      *int64    `json:"target_count,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint the tenant's stored messages are POSTed to. Secret
+// signs the deliveries and is only set in the response that creates it.
+type Webhook struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	URL       string    `json:"url"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is the delivery of one message to one webhook
+type WebhookDelivery struct {
+	ID            string     `json:"id"`
+	WebhookID     string     `json:"webhook_id"`
+	MessageID     string     `json:"message_id"`
+	MessageType   string     `json:"message_type,omitempty"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// LastStatusCode is the HTTP status of the last attempt, 0 if it got no response
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
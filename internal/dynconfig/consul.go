@@ -0,0 +1,180 @@
+package dynconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// consulWait is how long a blocking query waits for a change
+const consulWait = 5 * time.Minute
+
+// ConsulBackend stores each value under <prefix>/<tenant ID>/<key> in the
+// Consul KV store and watches the prefix with blocking queries
+type ConsulBackend struct {
+	address string
+	token   string
+	prefix  string
+	client  *http.Client
+}
+
+// NewConsulBackend creates a ConsulBackend for the agent at address, e.g.
+// http://127.0.0.1:8500. token is sent as ACL token when set.
+func NewConsulBackend(address, token, prefix string) *ConsulBackend {
+	return &ConsulBackend{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		prefix:  strings.Trim(prefix, "/") + "/",
+		// Lebih lama dari wait blocking query beserta jitter dari Consul
+		client: &http.Client{Timeout: consulWait + consulWait/16 + 10*time.Second},
+	}
+}
+
+type consulPair struct {
+	Key   string
+	Value []byte
+}
+
+func (b *ConsulBackend) List(ctx context.Context) ([]Entry, error) {
+	pairs, _, err := b.list(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for key, value := range pairs {
+		if entry, ok := b.entry(key); ok {
+			entry.Value = value
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (b *ConsulBackend) Put(ctx context.Context, entry Entry) error {
+	return b.do(ctx, http.MethodPut, b.prefix+entry.TenantID+"/"+entry.Key, nil, entry.Value)
+}
+
+func (b *ConsulBackend) Delete(ctx context.Context, tenantID, key string) error {
+	return b.do(ctx, http.MethodDelete, b.prefix+tenantID+"/"+key, nil, nil)
+}
+
+func (b *ConsulBackend) DeleteTenant(ctx context.Context, tenantID string) error {
+	return b.do(ctx, http.MethodDelete, b.prefix+tenantID+"/", url.Values{"recurse": {"true"}}, nil)
+}
+
+// Watch compares the prefix's contents after every blocking query returns
+// and reports the differences
+func (b *ConsulBackend) Watch(ctx context.Context, fn func(Change)) error {
+	current, index, err := b.list(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	for {
+		next, nextIndex, err := b.list(ctx, index)
+		if err != nil {
+			return err
+		}
+		// Index yang mundur berarti state Consul di-reset, mulai ulang dari awal
+		if nextIndex < index {
+			nextIndex = 0
+		}
+		index = nextIndex
+
+		for key, value := range next {
+			if old, ok := current[key]; ok && bytes.Equal(old, value) {
+				continue
+			}
+			if entry, ok := b.entry(key); ok {
+				entry.Value = value
+				fn(Change{Entry: entry})
+			}
+		}
+		for key := range current {
+			if _, ok := next[key]; ok {
+				continue
+			}
+			if entry, ok := b.entry(key); ok {
+				fn(Change{Entry: entry, Deleted: true})
+			}
+		}
+		current = next
+	}
+}
+
+// list reads the prefix, blocking until its index passes index when it is
+// not 0, and returns the values by key with the new index
+func (b *ConsulBackend) list(ctx context.Context, index uint64) (map[string][]byte, uint64, error) {
+	query := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWait.String())
+	}
+	resp, err := b.request(ctx, http.MethodGet, b.prefix, query, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	pairs := make(map[string][]byte)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return pairs, newIndex, nil
+	case http.StatusOK:
+	default:
+		return nil, 0, fmt.Errorf("consul: GET %s: %s", b.prefix, resp.Status)
+	}
+
+	var list []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, 0, fmt.Errorf("consul: invalid KV response: %w", err)
+	}
+	for _, pair := range list {
+		pairs[pair.Key] = pair.Value
+	}
+	return pairs, newIndex, nil
+}
+
+func (b *ConsulBackend) do(ctx context.Context, method, key string, query url.Values, body []byte) error {
+	resp, err := b.request(ctx, method, key, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("consul: %s %s: %s", method, key, resp.Status)
+	}
+	return nil
+}
+
+func (b *ConsulBackend) request(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	endpoint := b.address + "/v1/kv/" + key
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if b.token != "" {
+		req.Header.Set("X-Consul-Token", b.token)
+	}
+	return b.client.Do(req)
+}
+
+// entry parses a key written by Put
+func (b *ConsulBackend) entry(key string) (Entry, bool) {
+	tenantID, name, ok := strings.Cut(strings.TrimPrefix(key, b.prefix), "/")
+	if !ok || tenantID == "" || name == "" {
+		return Entry{}, false
+	}
+	return Entry{TenantID: tenantID, Key: name}, true
+}
@@ -0,0 +1,298 @@
+// Package dynconfig holds per-tenant runtime settings and feature flags that
+// can change without a deploy. Values are JSON and live in a backend
+// (Postgres, etcd or Consul) that every instance watches, so a change made
+// through one instance reaches all of them within moments.
+//
+// Keys are free-form names such as "flags.new_pricing"; the tenant's values
+// are exposed to its CEL filters as the "flags" map.
+package dynconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrInvalidKey is returned for a key that is not 1-128 letters, digits, '_', '.' or '-'
+var ErrInvalidKey = errors.New("invalid key, expected 1-128 letters, digits, '_', '.' or '-'")
+
+// ErrKeyNotFound is returned when a tenant has no value for a key
+var ErrKeyNotFound = errors.New("runtime config key not found")
+
+// ErrInvalidValue is returned for a value that is not JSON or is too large
+var ErrInvalidValue = errors.New("invalid runtime config value")
+
+// ErrDisabled is returned when writing to a nil Store
+var ErrDisabled = errors.New("runtime config is disabled")
+
+// MaxValueSize bounds a value so it fits in a Postgres notification
+const MaxValueSize = 4096
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// Entry is one tenant value
+type Entry struct {
+	TenantID string          `json:"tenant_id"`
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// Change is a value set or deleted in the backend
+type Change struct {
+	Entry
+	Deleted bool
+}
+
+// Backend stores the values and reports changes to them
+type Backend interface {
+	// List returns every stored value
+	List(ctx context.Context) ([]Entry, error)
+	Put(ctx context.Context, entry Entry) error
+	Delete(ctx context.Context, tenantID, key string) error
+	// DeleteTenant removes all values of a tenant
+	DeleteTenant(ctx context.Context, tenantID string) error
+	// Watch calls fn for every change until ctx is done or the watch fails.
+	// Changes made while no watch was running are not reported; the caller
+	// lists again after Watch returns.
+	Watch(ctx context.Context, fn func(Change)) error
+}
+
+// Store caches the backend's values and keeps them current. A nil Store has
+// no values and rejects writes.
+type Store struct {
+	backend Backend
+
+	mu     sync.RWMutex
+	values map[string]map[string]json.RawMessage
+	ready  chan struct{}
+	once   sync.Once
+}
+
+// NewStore creates a Store over backend; Run fills and updates it
+func NewStore(backend Backend) *Store {
+	return &Store{
+		backend: backend,
+		values:  make(map[string]map[string]json.RawMessage),
+		ready:   make(chan struct{}),
+	}
+}
+
+// Run loads all values and applies the backend's changes until ctx is done.
+// After a failed watch the values are listed again, so nothing missed in
+// between is lost.
+func (s *Store) Run(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := s.sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Runtime config watch stopped", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+// sync lists the values, then watches until the watch fails. Changes arriving
+// before the list is applied are buffered so none is lost.
+func (s *Store) sync(ctx context.Context) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		bufMu    sync.Mutex
+		buffered []Change
+		listed   bool
+	)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- s.backend.Watch(watchCtx, func(change Change) {
+			bufMu.Lock()
+			defer bufMu.Unlock()
+			if !listed {
+				buffered = append(buffered, change)
+				return
+			}
+			s.apply(change)
+		})
+	}()
+
+	entries, err := s.backend.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runtime config: %w", err)
+	}
+	values := make(map[string]map[string]json.RawMessage)
+	for _, e := range entries {
+		if values[e.TenantID] == nil {
+			values[e.TenantID] = make(map[string]json.RawMessage)
+		}
+		values[e.TenantID][e.Key] = e.Value
+	}
+
+	bufMu.Lock()
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	for _, change := range buffered {
+		s.apply(change)
+	}
+	buffered = nil
+	listed = true
+	bufMu.Unlock()
+	s.once.Do(func() { close(s.ready) })
+
+	return <-watchErr
+}
+
+func (s *Store) apply(change Change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if change.Deleted {
+		delete(s.values[change.TenantID], change.Key)
+		if len(s.values[change.TenantID]) == 0 {
+			delete(s.values, change.TenantID)
+		}
+		return
+	}
+	if s.values[change.TenantID] == nil {
+		s.values[change.TenantID] = make(map[string]json.RawMessage)
+	}
+	s.values[change.TenantID][change.Key] = change.Value
+}
+
+// Loaded reports whether the first list has been loaded
+func (s *Store) Loaded() bool {
+	if s == nil {
+		return true
+	}
+	select {
+	case <-s.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitReady blocks until the first list has been loaded or ctx is done
+func (s *Store) WaitReady(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Values returns a copy of the tenant's values
+func (s *Store) Values(tenantID string) map[string]json.RawMessage {
+	values := make(map[string]json.RawMessage)
+	if s == nil {
+		return values
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key, value := range s.values[tenantID] {
+		values[key] = value
+	}
+	return values
+}
+
+// Flags returns the tenant's values decoded, for use in CEL filters
+func (s *Store) Flags(tenantID string) map[string]any {
+	flags := make(map[string]any)
+	if s == nil {
+		return flags
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key, raw := range s.values[tenantID] {
+		var value any
+		if json.Unmarshal(raw, &value) == nil {
+			flags[key] = value
+		}
+	}
+	return flags
+}
+
+// Get returns one value of the tenant
+func (s *Store) Get(tenantID, key string) (json.RawMessage, error) {
+	if s == nil {
+		return nil, ErrKeyNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.values[tenantID][key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// Set stores a value in the backend. It is visible locally at once and on
+// the other instances once their watch reports it.
+func (s *Store) Set(ctx context.Context, tenantID, key string, value json.RawMessage) error {
+	if s == nil {
+		return ErrDisabled
+	}
+	if !keyPattern.MatchString(key) {
+		return ErrInvalidKey
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("%w: %q is not valid JSON", ErrInvalidValue, key)
+	}
+	if len(value) > MaxValueSize {
+		return fmt.Errorf("%w: %q is larger than %d bytes", ErrInvalidValue, key, MaxValueSize)
+	}
+
+	entry := Entry{TenantID: tenantID, Key: key, Value: value}
+	if err := s.backend.Put(ctx, entry); err != nil {
+		return err
+	}
+	s.apply(Change{Entry: entry})
+	return nil
+}
+
+// Delete removes a value from the backend
+func (s *Store) Delete(ctx context.Context, tenantID, key string) error {
+	if s == nil {
+		return ErrDisabled
+	}
+	if _, err := s.Get(tenantID, key); err != nil {
+		return err
+	}
+	if err := s.backend.Delete(ctx, tenantID, key); err != nil {
+		return err
+	}
+	s.apply(Change{Entry: Entry{TenantID: tenantID, Key: key}, Deleted: true})
+	return nil
+}
+
+// DeleteTenant removes all values of a deleted tenant
+func (s *Store) DeleteTenant(ctx context.Context, tenantID string) error {
+	if s == nil {
+		return nil
+	}
+	if err := s.backend.DeleteTenant(ctx, tenantID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.values, tenantID)
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,98 @@
+package dynconfig
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdBackend stores each value under <prefix>/<tenant ID>/<key>
+type EtcdBackend struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcdBackend connects to the etcd cluster at endpoints
+func NewEtcdBackend(endpoints []string, username, password, prefix string, dialTimeout time.Duration) (*EtcdBackend, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		Username:    username,
+		Password:    password,
+		DialTimeout: dialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdBackend{client: client, prefix: strings.TrimSuffix(prefix, "/") + "/"}, nil
+}
+
+// Close closes the etcd client
+func (b *EtcdBackend) Close() error {
+	return b.client.Close()
+}
+
+func (b *EtcdBackend) List(ctx context.Context) ([]Entry, error) {
+	resp, err := b.client.Get(ctx, b.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, kv := range resp.Kvs {
+		if entry, ok := b.entry(string(kv.Key)); ok {
+			entry.Value = kv.Value
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (b *EtcdBackend) Put(ctx context.Context, entry Entry) error {
+	_, err := b.client.Put(ctx, b.prefix+entry.TenantID+"/"+entry.Key, string(entry.Value))
+	return err
+}
+
+func (b *EtcdBackend) Delete(ctx context.Context, tenantID, key string) error {
+	_, err := b.client.Delete(ctx, b.prefix+tenantID+"/"+key)
+	return err
+}
+
+func (b *EtcdBackend) DeleteTenant(ctx context.Context, tenantID string) error {
+	_, err := b.client.Delete(ctx, b.prefix+tenantID+"/", clientv3.WithPrefix())
+	return err
+}
+
+func (b *EtcdBackend) Watch(ctx context.Context, fn func(Change)) error {
+	for resp := range b.client.Watch(clientv3.WithRequireLeader(ctx), b.prefix, clientv3.WithPrefix()) {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			entry, ok := b.entry(string(ev.Kv.Key))
+			if !ok {
+				continue
+			}
+			if ev.Type == clientv3.EventTypeDelete {
+				fn(Change{Entry: entry, Deleted: true})
+				continue
+			}
+			entry.Value = ev.Kv.Value
+			fn(Change{Entry: entry})
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.New("etcd watch closed")
+}
+
+// entry parses a key written by Put
+func (b *EtcdBackend) entry(key string) (Entry, bool) {
+	tenantID, name, ok := strings.Cut(strings.TrimPrefix(key, b.prefix), "/")
+	if !ok || tenantID == "" || name == "" {
+		return Entry{}, false
+	}
+	return Entry{TenantID: tenantID, Key: name}, true
+}
@@ -0,0 +1,118 @@
+package dynconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// notifyChannel is the Postgres channel changes are announced on
+const notifyChannel = "tenant_runtime_config"
+
+// PostgresBackend stores values in the tenant_runtime_config table and
+// announces changes with NOTIFY, which every instance LISTENs to
+type PostgresBackend struct {
+	db *repository.Database
+}
+
+// NewPostgresBackend creates a PostgresBackend
+func NewPostgresBackend(db *repository.Database) *PostgresBackend {
+	return &PostgresBackend{db: db}
+}
+
+func (b *PostgresBackend) List(ctx context.Context) ([]Entry, error) {
+	rows, err := b.db.DB.QueryContext(ctx, "SELECT tenant_id, key, value FROM tenant_runtime_config")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.TenantID, &e.Key, &e.Value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (b *PostgresBackend) Put(ctx context.Context, entry Entry) error {
+	return b.write(ctx, Change{Entry: entry}, `
+		INSERT INTO tenant_runtime_config (tenant_id, key, value) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()
+	`, entry.TenantID, entry.Key, []byte(entry.Value))
+}
+
+func (b *PostgresBackend) Delete(ctx context.Context, tenantID, key string) error {
+	return b.write(ctx, Change{Entry: Entry{TenantID: tenantID, Key: key}, Deleted: true},
+		"DELETE FROM tenant_runtime_config WHERE tenant_id = $1 AND key = $2", tenantID, key)
+}
+
+// DeleteTenant is a no-op: the rows go with the tenant through ON DELETE CASCADE
+func (b *PostgresBackend) DeleteTenant(ctx context.Context, tenantID string) error {
+	return nil
+}
+
+// write runs the statement and announces the change in the same transaction,
+// so listeners are only notified of committed changes
+func (b *PostgresBackend) write(ctx context.Context, change Change, query string, args ...interface{}) error {
+	payload, err := json.Marshal(notification{Entry: change.Entry, Deleted: change.Deleted})
+	if err != nil {
+		return err
+	}
+
+	tx, err := b.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", notifyChannel, string(payload)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type notification struct {
+	Entry
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Watch holds one pool connection for LISTEN until ctx is done
+func (b *PostgresBackend) Watch(ctx context.Context, fn func(Change)) error {
+	conn, err := b.db.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+			return err
+		}
+		// Koneksi kembali ke pool, jadi LISTEN harus dilepas
+		defer pgConn.Exec(context.Background(), "UNLISTEN "+notifyChannel)
+
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			var msg notification
+			if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+				return fmt.Errorf("invalid runtime config notification: %w", err)
+			}
+			fn(Change{Entry: msg.Entry, Deleted: msg.Deleted})
+		}
+	})
+}
@@ -0,0 +1,250 @@
+// Package events records structured system events per tenant, such as a
+// tenant being created or a message being dead-lettered, so integrations can
+// follow what happens to a tenant without polling every endpoint.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Event types
+const (
+	TypeTenantCreated       = "tenant.created"
+	TypeTenantConfigChanged = "tenant.config_changed"
+	TypeTenantSuspended     = "tenant.suspended"
+	TypeTenantResumed       = "tenant.resumed"
+	TypeMessageDeadLettered = "message.dead_lettered"
+	TypeConsumerRestarted   = "consumer.restarted"
+	TypeConsumerScaled      = "consumer.scaled"
+	// TypeMessageStored is transient: it only reaches listeners and is
+	// neither stored nor published
+	TypeMessageStored = "message.stored"
+)
+
+// Listener receives the events emitted on this instance as they happen
+type Listener interface {
+	// Wants reports whether the listener takes the tenant's events
+	Wants(tenantID string) bool
+	Receive(event Event)
+}
+
+// Event is a single system event. Seq orders the events of all tenants and
+// is the cursor for reading them.
+type Event struct {
+	Seq       int64                  `json:"seq"`
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	TenantID  string                 `json:"tenant_id"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Emitter stores events in Postgres and publishes them to an optional AMQP queue
+type Emitter struct {
+	db      *repository.Database
+	rabbit  *repository.RabbitMQ
+	ch      *amqp.Channel
+	queue   string
+	pending chan Event
+	wg      sync.WaitGroup
+	// listeners are added at startup, before events are emitted
+	listeners []Listener
+}
+
+// NewEmitter creates an Emitter. When queue is not empty every event is also
+// published to that durable queue on a channel of rabbit's connection.
+func NewEmitter(db *repository.Database, rabbit *repository.RabbitMQ, queue string) (*Emitter, error) {
+	e := &Emitter{db: db, rabbit: rabbit, queue: queue}
+	if queue == "" {
+		return e, nil
+	}
+
+	ch, err := rabbit.OpenChannel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events channel: %w", err)
+	}
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to declare events queue %s: %w", queue, err)
+	}
+	e.ch = ch
+	e.pending = make(chan Event, 1024)
+	e.wg.Add(1)
+	go e.publish()
+	return e, nil
+}
+
+// Emit records an event for the tenant. Failures are logged rather than
+// returned so events never fail the operation they describe. A nil Emitter
+// discards events.
+func (e *Emitter) Emit(eventType, tenantID string, data map[string]interface{}) {
+	if e == nil {
+		return
+	}
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		TenantID:  tenantID,
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Failed to encode event data", "event_type", eventType, "error", err)
+		dataJSON = []byte("null")
+	}
+	if err := e.db.DB.QueryRow(`
+		INSERT INTO system_events (id, type, tenant_id, data, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING seq
+	`, event.ID, event.Type, event.TenantID, dataJSON, event.CreatedAt).Scan(&event.Seq); err != nil {
+		slog.Error("Failed to store event", "event_type", eventType, "tenant_id", tenantID, "error", err)
+	}
+
+	if e.pending != nil {
+		select {
+		case e.pending <- event:
+		default:
+			slog.Warn("Events queue full, dropping publish of event", "event_id", event.ID)
+		}
+	}
+	e.deliver(event)
+}
+
+// Listen passes every event emitted from now on to l
+func (e *Emitter) Listen(l Listener) {
+	e.listeners = append(e.listeners, l)
+}
+
+// Watched reports whether a listener takes the tenant's events, so callers
+// can skip building transient events nobody receives
+func (e *Emitter) Watched(tenantID string) bool {
+	if e == nil {
+		return false
+	}
+	for _, l := range e.listeners {
+		if l.Wants(tenantID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify passes a transient event to the listeners without storing or
+// publishing it. It is meant for events too frequent to keep, such as
+// TypeMessageStored.
+func (e *Emitter) Notify(eventType, tenantID string, data map[string]interface{}) {
+	if e == nil {
+		return
+	}
+	e.deliver(Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		TenantID:  tenantID,
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+func (e *Emitter) deliver(event Event) {
+	for _, l := range e.listeners {
+		if l.Wants(event.TenantID) {
+			l.Receive(event)
+		}
+	}
+}
+
+// List returns up to limit of the tenant's events after the given seq, oldest first
+func (e *Emitter) List(ctx context.Context, tenantID string, after int64, limit int) ([]Event, error) {
+	rows, err := e.db.DB.QueryContext(ctx, `
+		SELECT seq, id, type, tenant_id, data, created_at FROM system_events
+		WHERE tenant_id = $1 AND seq > $2
+		ORDER BY seq LIMIT $3
+	`, tenantID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		var data []byte
+		if err := rows.Scan(&event.Seq, &event.ID, &event.Type, &event.TenantID, &data, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &event.Data); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// LatestSeq returns the seq of the tenant's newest event, 0 if it has none
+func (e *Emitter) LatestSeq(ctx context.Context, tenantID string) (int64, error) {
+	var seq int64
+	err := e.db.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(seq), 0) FROM system_events WHERE tenant_id = $1", tenantID,
+	).Scan(&seq)
+	return seq, err
+}
+
+// Purge deletes events older than retention and returns how many were deleted
+func (e *Emitter) Purge(retention time.Duration) (int64, error) {
+	result, err := e.db.DB.Exec("DELETE FROM system_events WHERE created_at < $1", time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Close flushes queued events to the events queue and closes its channel
+func (e *Emitter) Close() {
+	if e.pending == nil {
+		return
+	}
+	close(e.pending)
+	e.wg.Wait()
+	e.ch.Close()
+}
+
+func (e *Emitter) publish() {
+	defer e.wg.Done()
+	for event := range e.pending {
+		body, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("Failed to encode event", "event_id", event.ID, "error", err)
+			continue
+		}
+		msg := amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    event.ID,
+			Type:         event.Type,
+			Timestamp:    event.CreatedAt,
+			Body:         body,
+		}
+		err = e.ch.Publish("", e.queue, false, false, msg)
+		if err != nil && e.ch.IsClosed() {
+			// Channel ikut mati saat koneksi broker putus, buka lagi di koneksi yang baru
+			if ch, openErr := e.rabbit.OpenChannel(); openErr == nil {
+				e.ch = ch
+				err = e.ch.Publish("", e.queue, false, false, msg)
+			}
+		}
+		if err != nil {
+			slog.Error("Failed to publish event", "event_id", event.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package filter evaluates per-tenant CEL filter rules on messages before
+// they are stored.
+//
+// Each rule is a CEL expression over a "message" map with the keys id, type,
+// headers and payload (the decoded JSON body, or null), for example
+//
+//	message.type == "audit" && message.payload.level == "debug"
+//
+// The tenant's runtime config values are available as the "flags" map, e.g.
+// flags["drop_debug"] == true.
+//
+// Rules run in order. A matching tag rule adds its target to the message's
+// tags and evaluation continues; the first matching drop or route rule ends it.
+// CEL has no I/O, and every evaluation is bounded by a cost limit and timeout.
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/interpreter"
+)
+
+// Filter actions
+const (
+	ActionDrop  = "drop"
+	ActionTag   = "tag"
+	ActionRoute = "route"
+)
+
+// ErrLimitExceeded is returned when an expression runs past its cost limit or timeout
+var ErrLimitExceeded = errors.New("filter exceeded its evaluation limit")
+
+var targetPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Rule applies Action to messages matching Expression. Target is the tag to
+// add, or the name of the route the message is published to instead of stored.
+type Rule struct {
+	Name       string `json:"name" binding:"required"`
+	Expression string `json:"expression" binding:"required"`
+	Action     string `json:"action" binding:"required,oneof=drop tag route"`
+	Target     string `json:"target,omitempty"`
+}
+
+// Validate checks a rule's action and target; the expression is checked by Compile
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionDrop:
+		if r.Target != "" {
+			return fmt.Errorf("filter %q: drop takes no target", r.Name)
+		}
+	case ActionTag, ActionRoute:
+		if !targetPattern.MatchString(r.Target) {
+			return fmt.Errorf("filter %q: target must be 1-64 letters, digits, '_', '.' or '-'", r.Name)
+		}
+	default:
+		return fmt.Errorf("filter %q: invalid action %q", r.Name, r.Action)
+	}
+	return nil
+}
+
+// Limits bounds a single expression evaluation
+type Limits struct {
+	Timeout   time.Duration
+	CostLimit uint64
+}
+
+// Input is the message a rule set is evaluated on
+type Input struct {
+	ID      string
+	Type    string
+	Headers map[string]any
+	Body    []byte
+	// Flags are the tenant's runtime config values
+	Flags map[string]any
+}
+
+// Decision is the outcome of a rule set. Drop and Route are exclusive.
+type Decision struct {
+	Drop  bool     `json:"drop"`
+	Route string   `json:"route,omitempty"`
+	Tags  []string `json:"tags"`
+	// Matched names the rules that matched, in order
+	Matched []string `json:"matched"`
+}
+
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+// Set is a compiled, ordered list of rules
+type Set struct {
+	rules  []compiledRule
+	limits Limits
+}
+
+var env, envErr = cel.NewEnv(
+	cel.Variable("message", cel.MapType(cel.StringType, cel.DynType)),
+	cel.Variable("flags", cel.MapType(cel.StringType, cel.DynType)),
+)
+
+// Compile validates and compiles rules. Expressions must return a bool.
+func Compile(rules []Rule, limits Limits) (*Set, error) {
+	if envErr != nil {
+		return nil, envErr
+	}
+	set := &Set{limits: limits}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		ast, issues := env.Compile(rule.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("filter %q: %w", rule.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("filter %q: expression must return a bool, not %s", rule.Name, ast.OutputType())
+		}
+
+		opts := []cel.ProgramOption{cel.InterruptCheckFrequency(100)}
+		if limits.CostLimit > 0 {
+			opts = append(opts, cel.CostLimit(limits.CostLimit))
+		}
+		program, err := env.Program(ast, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q: %w", rule.Name, err)
+		}
+		set.rules = append(set.rules, compiledRule{Rule: rule, program: program})
+	}
+	return set, nil
+}
+
+// Evaluate runs the rules on in. A rule that fails to evaluate, e.g. because
+// a field it reads is missing, does not match.
+func (s *Set) Evaluate(ctx context.Context, in Input) (Decision, error) {
+	decision := Decision{Tags: []string{}, Matched: []string{}}
+	if s == nil || len(s.rules) == 0 {
+		return decision, nil
+	}
+
+	var payload any
+	if json.Unmarshal(in.Body, &payload) != nil {
+		payload = nil
+	}
+	headers := in.Headers
+	if headers == nil {
+		headers = map[string]any{}
+	}
+	flags := in.Flags
+	if flags == nil {
+		flags = map[string]any{}
+	}
+	activation := map[string]any{
+		"message": map[string]any{
+			"id":      in.ID,
+			"type":    in.Type,
+			"headers": headers,
+			"payload": payload,
+		},
+		"flags": flags,
+	}
+
+	for _, rule := range s.rules {
+		matched, err := s.eval(ctx, rule, activation)
+		if err != nil {
+			return decision, err
+		}
+		if !matched {
+			continue
+		}
+		decision.Matched = append(decision.Matched, rule.Name)
+		switch rule.Action {
+		case ActionTag:
+			decision.Tags = append(decision.Tags, rule.Target)
+		case ActionDrop:
+			decision.Drop = true
+			return decision, nil
+		case ActionRoute:
+			decision.Route = rule.Target
+			return decision, nil
+		}
+	}
+	return decision, nil
+}
+
+func (s *Set) eval(ctx context.Context, rule compiledRule, activation map[string]any) (bool, error) {
+	if s.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.limits.Timeout)
+		defer cancel()
+	}
+
+	out, _, err := rule.program.ContextEval(ctx, activation)
+	if err != nil {
+		if ctx.Err() != nil || isCostError(err) {
+			return false, fmt.Errorf("%w: %s", ErrLimitExceeded, rule.Name)
+		}
+		return false, nil
+	}
+	matched, _ := out.Value().(bool)
+	return matched, nil
+}
+
+func isCostError(err error) bool {
+	var cancelled interpreter.EvalCancelledError
+	return errors.As(err, &cancelled)
+}
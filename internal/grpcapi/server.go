@@ -0,0 +1,508 @@
+// Package grpcapi serves the tenant and message operations over gRPC for
+// internal services, next to the HTTP API and on the same service layer
+package grpcapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/auth"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/health"
+	"multi-tenant-messaging/internal/migrate"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+//go:embed tenants.proto
+var protoSource string
+
+const (
+	// protoFile is the path tenants.proto is registered and reflected under
+	protoFile = "salva/v1/tenants.proto"
+	// ServiceName is the full name of the tenant service
+	ServiceName = "salva.v1.TenantService"
+	// readinessInterval is how often the health service reruns the readiness checks
+	readinessInterval = 5 * time.Second
+)
+
+// fileDescriptor compiles tenants.proto once. The messages are served as
+// dynamic messages, so no generated code has to be kept in sync with the
+// .proto. The file is registered globally for the reflection service.
+var fileDescriptor = sync.OnceValues(func() (protoreflect.FileDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{protoFile: protoSource}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), protoFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", protoFile, err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(files[0]); err != nil {
+		return nil, err
+	}
+	return files[0], nil
+})
+
+// rpc is a method of the tenant service
+type rpc struct {
+	// httpMethod and route name the HTTP route of the same operation; a
+	// scoped token must be allowed to call it
+	httpMethod, route string
+	// tenantScoped methods are refused to tokens bound to another tenant
+	// than the request's tenant_id
+	tenantScoped bool
+	call         func(s *Server, ctx context.Context, req proto.Message) (any, error)
+}
+
+var rpcs = map[string]rpc{
+	"CreateTenant":      {"POST", "/tenants", false, (*Server).createTenant},
+	"DeleteTenant":      {"DELETE", "/tenants/:id", true, (*Server).deleteTenant},
+	"UpdateConcurrency": {"PUT", "/tenants/:id/config/concurrency", true, (*Server).updateConcurrency},
+	"ListMessages":      {"GET", "/messages", false, (*Server).listMessages},
+	"PublishMessage":    {"POST", "/tenants/:id/messages", true, (*Server).publishMessage},
+}
+
+// claimsKey is the context key holding the verified *auth.Claims of a call
+type claimsKey struct{}
+
+// Server is the gRPC server of the tenant service, with the health service
+// and optionally server reflection
+type Server struct {
+	server      *grpc.Server
+	health      *grpchealth.Server
+	checker     *health.Checker
+	tenants     *service.TenantService
+	messages    *service.MessageService
+	tokens      *auth.TokenService
+	apiKeys     *service.APIKeyService
+	allowlists  *service.AllowlistService
+	auditLogger *audit.Logger
+	stop        context.CancelFunc
+	ctx         context.Context
+}
+
+// NewServer creates the gRPC server. Calls need a bearer token verified by
+// tokens or, unless apiKeys is nil, an API key in the x-api-key metadata;
+// with both nil calls are not authenticated. Tenant-scoped calls must come
+// from an address in the tenant's allowlist. The health service reports the
+// readiness checks of checker.
+func NewServer(tenants *service.TenantService, messages *service.MessageService, tokens *auth.TokenService, apiKeys *service.APIKeyService, allowlists *service.AllowlistService, auditLogger *audit.Logger, checker *health.Checker, enableReflection bool, opts ...grpc.ServerOption) (*Server, error) {
+	file, err := fileDescriptor()
+	if err != nil {
+		return nil, err
+	}
+	descriptor := file.Services().ByName("TenantService")
+	if descriptor == nil {
+		return nil, fmt.Errorf("%s is not defined in %s", ServiceName, protoFile)
+	}
+
+	s := &Server{
+		health:      grpchealth.NewServer(),
+		checker:     checker,
+		tenants:     tenants,
+		messages:    messages,
+		tokens:      tokens,
+		apiKeys:     apiKeys,
+		allowlists:  allowlists,
+		auditLogger: auditLogger,
+	}
+	s.ctx, s.stop = context.WithCancel(context.Background())
+
+	desc := grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		// Semua method dilayani lewat closure, tidak ada interface yang harus dipenuhi
+		HandlerType: (*any)(nil),
+		Metadata:    protoFile,
+	}
+	methods := descriptor.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		r, ok := rpcs[string(method.Name())]
+		if !ok {
+			return nil, fmt.Errorf("method %s has no implementation", method.FullName())
+		}
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: string(method.Name()),
+			Handler:    s.handler(method, r),
+		})
+	}
+
+	s.server = grpc.NewServer(append(opts, grpc.ChainUnaryInterceptor(s.authenticate))...)
+	s.server.RegisterService(&desc, s)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	if enableReflection {
+		reflection.Register(s.server)
+	}
+	return s, nil
+}
+
+// Serve accepts calls on lis until Shutdown
+func (s *Server) Serve(lis net.Listener) error {
+	go s.watchReadiness()
+	return s.server.Serve(lis)
+}
+
+// Shutdown reports the services as not serving and waits for running calls
+// to finish until ctx is done, then closes the remaining connections
+func (s *Server) Shutdown(ctx context.Context) {
+	s.stop()
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
+
+// watchReadiness keeps the health status of the server and of the tenant
+// service in line with the readiness checks
+func (s *Server) watchReadiness() {
+	ticker := time.NewTicker(readinessInterval)
+	defer ticker.Stop()
+
+	for {
+		result, _ := s.checker.Ready(s.ctx, "")
+		if s.ctx.Err() != nil {
+			return
+		}
+		serving := healthpb.HealthCheckResponse_SERVING
+		if !result.Healthy() {
+			serving = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		s.health.SetServingStatus("", serving)
+		s.health.SetServingStatus(ServiceName, serving)
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handler decodes the request of method as a dynamic message, runs r and
+// encodes its result as the method's response
+func (s *Server) handler(method protoreflect.MethodDescriptor, r rpc) grpc.MethodHandler {
+	fullMethod := fmt.Sprintf("/%s/%s", ServiceName, method.Name())
+	return func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := dynamicpb.NewMessage(method.Input())
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handle := func(ctx context.Context, req any) (any, error) {
+			result, err := r.call(s, ctx, req.(proto.Message))
+			if err != nil {
+				return nil, statusError(err)
+			}
+			return encode(method.Output(), result)
+		}
+		if interceptor == nil {
+			return handle(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: s, FullMethod: fullMethod}, handle)
+	}
+}
+
+// authenticate verifies the bearer token or API key of calls to the tenant
+// service like the HTTP API does, and the caller's address against the
+// tenant's allowlist; the health and reflection services are open
+func (s *Server) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	name, found := strings.CutPrefix(info.FullMethod, "/"+ServiceName+"/")
+	r, ok := rpcs[name]
+	if !found || !ok {
+		return handler(ctx, req)
+	}
+
+	if s.tokens != nil || s.apiKeys != nil {
+		claims, err := s.callerClaims(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !claims.Allows(r.httpMethod, r.route) {
+			return nil, status.Error(codes.PermissionDenied, "token scopes do not allow this request")
+		}
+		if !claims.RoleAllows(r.httpMethod, r.route) {
+			return nil, status.Errorf(codes.PermissionDenied, "role %s does not allow this request", claims.EffectiveRole())
+		}
+		if r.tenantScoped && claims.TenantID != "" && claims.TenantID != stringField(req.(proto.Message), "tenant_id") {
+			return nil, status.Error(codes.PermissionDenied, service.ErrForeignTenant.Error())
+		}
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
+	}
+	if r.tenantScoped {
+		tenantID := stringField(req.(proto.Message), "tenant_id")
+		if _, err := uuid.Parse(tenantID); tenantID != "" && err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid tenant_id format")
+		}
+		if err := s.checkAllowlist(ctx, tenantID); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// checkAllowlist refuses calls whose peer address is not in the tenant's
+// allowlist, as middleware.IPAllowlist does for the HTTP API
+func (s *Server) checkAllowlist(ctx context.Context, tenantID string) error {
+	if s.allowlists == nil || tenantID == "" {
+		return nil
+	}
+	var ip net.IP
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		ip = net.ParseIP(host)
+	}
+
+	allowed, err := s.allowlists.IsAllowed(tenantID, ip)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !allowed {
+		return status.Error(codes.PermissionDenied, "source IP not allowed for tenant")
+	}
+	return nil
+}
+
+// callerClaims verifies the API key or bearer token in the call's metadata
+func (s *Server) callerClaims(ctx context.Context) (*auth.Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	var claims *auth.Claims
+	var err error
+	if key := first("x-api-key"); key != "" && s.apiKeys != nil {
+		claims, err = s.apiKeys.Resolve(ctx, key)
+	} else {
+		token, found := strings.CutPrefix(first("authorization"), "Bearer ")
+		if !found || token == "" || s.tokens == nil {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token or API key")
+		}
+		claims, err = s.tokens.Verify(token, auth.TokenTypeAccess)
+	}
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return claims, nil
+}
+
+func (s *Server) createTenant(ctx context.Context, req proto.Message) (any, error) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := decode(req, &request); err != nil {
+		return nil, err
+	}
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	tenant := domain.Tenant{
+		ID:        uuid.New().String(),
+		Name:      request.Name,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	job, err := s.tenants.CreateTenant(&tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.RecordChange(callActor(ctx), audit.ActionTenantCreate, tenant.ID, map[string]interface{}{
+		"name":   tenant.Name,
+		"job_id": job.ID,
+	}, nil, map[string]interface{}{"name": tenant.Name})
+	return job, nil
+}
+
+func (s *Server) deleteTenant(ctx context.Context, req proto.Message) (any, error) {
+	tenantID := stringField(req, "tenant_id")
+	before := s.tenants.AuditValues(tenantID)
+	if err := s.tenants.DeleteTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.RecordChange(callActor(ctx), audit.ActionTenantDelete, tenantID, nil, before, nil)
+	return struct{}{}, nil
+}
+
+func (s *Server) updateConcurrency(ctx context.Context, req proto.Message) (any, error) {
+	var request struct {
+		TenantID      string `json:"tenant_id"`
+		Workers       int    `json:"workers"`
+		PrefetchCount int    `json:"prefetch_count"`
+	}
+	if err := decode(req, &request); err != nil {
+		return nil, err
+	}
+	before := s.tenants.AuditValues(request.TenantID)
+	if err := s.tenants.UpdateConcurrency(request.TenantID, request.Workers, request.PrefetchCount); err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.RecordChange(callActor(ctx), audit.ActionConcurrencyUpdate, request.TenantID, map[string]interface{}{
+		"workers":        request.Workers,
+		"prefetch_count": request.PrefetchCount,
+	}, before, s.tenants.AuditValues(request.TenantID))
+	return struct{}{}, nil
+}
+
+func (s *Server) listMessages(ctx context.Context, req proto.Message) (any, error) {
+	var query domain.MessageQuery
+	if err := decode(req, &query); err != nil {
+		return nil, err
+	}
+
+	var bound string
+	if claims := callClaims(ctx); claims != nil {
+		bound = claims.TenantID
+	}
+	listed := bound
+	if listed == "" {
+		listed = query.TenantID
+	}
+	if _, err := uuid.Parse(listed); listed == "" || err == nil {
+		if err := s.checkAllowlist(ctx, listed); err != nil {
+			return nil, err
+		}
+	}
+	messages, next, err := s.messages.ListMessages(ctx, bound, query)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"messages":    messages,
+		"next_cursor": next,
+	}, nil
+}
+
+func (s *Server) publishMessage(ctx context.Context, req proto.Message) (any, error) {
+	var request struct {
+		TenantID string `json:"tenant_id"`
+		domain.PublishRequest
+	}
+	if err := decode(req, &request); err != nil {
+		return nil, err
+	}
+	return s.tenants.PublishMessage(ctx, request.TenantID, request.PublishRequest)
+}
+
+// callClaims returns the verified token claims of the call, if any
+func callClaims(ctx context.Context) *auth.Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*auth.Claims)
+	return claims
+}
+
+// callActor returns the token subject of the call for auditing
+func callActor(ctx context.Context) string {
+	if claims := callClaims(ctx); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
+
+// stringField returns the string field name of msg
+func stringField(msg proto.Message, name string) string {
+	m := msg.ProtoReflect()
+	field := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if field == nil {
+		return ""
+	}
+	return m.Get(field).String()
+}
+
+// decode copies msg into v through its JSON form with proto field names,
+// which match the JSON fields of the domain types
+func decode(msg proto.Message, v any) error {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// encode builds a message of type desc from the JSON form of v. Fields the
+// message does not define are dropped.
+func encode(desc protoreflect.MessageDescriptor, v any) (proto.Message, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return msg, nil
+}
+
+// statusError maps the service errors to gRPC status codes
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Internal
+	switch {
+	case errors.Is(err, service.ErrInvalidPublish), errors.Is(err, service.ErrInvalidMessageQuery), errors.Is(err, service.ErrInvalidConcurrency), errors.Is(err, service.ErrSchemaValidation):
+		code = codes.InvalidArgument
+	case errors.Is(err, service.ErrTenantNotFound), errors.Is(err, service.ErrChannelNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.ErrForeignTenant):
+		code = codes.PermissionDenied
+	case errors.Is(err, service.ErrPartitionNotFound), errors.Is(err, service.ErrOrderedTenant):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrRateLimited), errors.Is(err, service.ErrQuotaExceeded):
+		code = codes.ResourceExhausted
+	case errors.Is(err, service.ErrUnsupportedTransport):
+		code = codes.Unimplemented
+	case errors.Is(err, migrate.ErrSchemaBehind), errors.Is(err, migrate.ErrSchemaDirty):
+		code = codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
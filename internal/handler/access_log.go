@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"multi-tenant-messaging/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogHandler changes the access log sampling at runtime
+type AccessLogHandler struct {
+	accessLog *middleware.AccessLog
+}
+
+// NewAccessLogHandler creates a new AccessLogHandler
+func NewAccessLogHandler(accessLog *middleware.AccessLog) *AccessLogHandler {
+	return &AccessLogHandler{accessLog: accessLog}
+}
+
+// GetSettings godoc
+// @Summary Get the access log sampling
+// @Description Get the default sample rate and the per-route overrides of this instance's access log
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} middleware.AccessLogSettings
+// @Router /admin/access-log [get]
+func (h *AccessLogHandler) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.accessLog.Settings())
+}
+
+// UpdateSettings godoc
+// @Summary Change the access log sampling
+// @Description Replace the default sample rate and the per-route overrides, keyed "METHOD /route/:template" (e.g. "GET /tenants/:id/events"). Rates go from 0 (off) to 1 (every request). Applies to this instance until it restarts.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param settings body middleware.AccessLogSettings true "Sampling settings"
+// @Success 200 {object} middleware.AccessLogSettings
+// @Failure 400 {object} object "Invalid request body or rate"
+// @Router /admin/access-log [put]
+func (h *AccessLogHandler) UpdateSettings(c *gin.Context) {
+	var settings middleware.AccessLogSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.accessLog.Set(settings)
+	c.JSON(http.StatusOK, h.accessLog.Settings())
+}
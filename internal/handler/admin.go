@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"multi-tenant-messaging/internal/migrate"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler handles operator requests
+type AdminHandler struct {
+	tenantService    *service.TenantService
+	partitionService *service.PartitionService
+	migrationService *migrate.Runner
+	stuckThreshold   time.Duration
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(tenantService *service.TenantService, partitionService *service.PartitionService, migrationService *migrate.Runner, stuckThreshold time.Duration) *AdminHandler {
+	return &AdminHandler{
+		tenantService:    tenantService,
+		partitionService: partitionService,
+		migrationService: migrationService,
+		stuckThreshold:   stuckThreshold,
+	}
+}
+
+// GetMigrations godoc
+// @Summary Get schema migration status
+// @Description List applied and pending schema migrations and whether the last one failed halfway (dirty). New consumers are not started while migrations are pending or the schema is dirty.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} domain.MigrationStatus
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/migrations [get]
+func (h *AdminHandler) GetMigrations(c *gin.Context) {
+	status, err := h.migrationService.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListDeliveries godoc
+// @Summary List unacked deliveries of a tenant
+// @Description List the tenant's deliveries that have not been acked yet, with their age and worker
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param older_than query string false "Only deliveries older than this duration (e.g. 30s)"
+// @Success 200 {object} object{data=[]domain.DeliveryInfo}
+// @Failure 400 {object} object "Invalid duration"
+// @Failure 404 {object} object "Tenant not found"
+// @Router /admin/tenants/{id}/deliveries [get]
+func (h *AdminHandler) ListDeliveries(c *gin.Context) {
+	var olderThan time.Duration
+	if value := c.Query("older_than"); value != "" {
+		var err error
+		olderThan, err = time.ParseDuration(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid older_than parameter"})
+			return
+		}
+	}
+
+	deliveries, err := h.tenantService.ListDeliveries(c.Param("id"), olderThan)
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+// SettleStuckDeliveries godoc
+// @Summary Requeue or discard stuck deliveries
+// @Description Nack the tenant's deliveries stuck beyond a threshold, either requeueing them or discarding them to the tenant DLQ. Deliveries a worker has started are left to the worker and counted as skipped, unless force is set and the worker started them longer than older_than ago; the hung worker's own ack or nack is then ignored, so a requeued message may be processed twice.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body object{action=string,older_than=string,delivery_tags=[]int,force=bool} true "action is requeue or discard; older_than defaults to the configured threshold"
+// @Success 200 {object} object{settled=int,skipped=int}
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/deliveries/stuck [post]
+func (h *AdminHandler) SettleStuckDeliveries(c *gin.Context) {
+	var request struct {
+		Action       string   `json:"action" binding:"required,oneof=requeue discard"`
+		OlderThan    string   `json:"older_than"`
+		DeliveryTags []uint64 `json:"delivery_tags"`
+		Force        bool     `json:"force"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	olderThan := h.stuckThreshold
+	if request.OlderThan != "" {
+		var err error
+		olderThan, err = time.ParseDuration(request.OlderThan)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid older_than value"})
+			return
+		}
+	}
+
+	settled, skipped, err := h.tenantService.SettleStuckDeliveries(c.Param("id"), olderThan, request.DeliveryTags, request.Action == "requeue", request.Force)
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settled": settled, "skipped": skipped})
+}
+
+func respondTenantError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrTenantNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// RenameQueues godoc
+// @Summary Move tenants to the current queue name template
+// @Description After rabbitmq.queue_name_template changes, move every tenant to its new queue name: declare the new queue, switch the consumer, shovel the remaining messages and delete the old queue once empty. Only tenants whose name changed are listed.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} object{data=[]domain.QueueRename}
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /admin/queues/rename [post]
+func (h *AdminHandler) RenameQueues(c *gin.Context) {
+	renames, err := h.tenantService.RenameQueues(c.Request.Context())
+	if errors.Is(err, service.ErrUnsupportedTransport) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": renames})
+}
+
+// ListPartitions godoc
+// @Summary List messages partitions
+// @Description List tenant partitions of the messages table with row counts and sizes
+// @Tags admin
+// @Produce  json
+// @Param exact query bool false "Count rows exactly instead of using planner estimates"
+// @Success 200 {object} object{data=[]domain.Partition}
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/partitions [get]
+func (h *AdminHandler) ListPartitions(c *gin.Context) {
+	exact := c.Query("exact") == "true"
+	partitions, err := h.partitionService.ListPartitions(exact)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": partitions})
+}
+
+// CreatePartition godoc
+// @Summary Pre-create a tenant partition
+// @Description Create the messages partition for a tenant ahead of time
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param request body object{tenant_id=string} true "Partition request"
+// @Success 201
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/partitions [post]
+func (h *AdminHandler) CreatePartition(c *gin.Context) {
+	tenantID, ok := bindPartitionRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.partitionService.CreatePartition(tenantID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusCreated)
+}
+
+// DetachPartition godoc
+// @Summary Detach a tenant partition
+// @Description Detach a tenant partition from the messages table, keeping the data in a standalone table
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param request body object{tenant_id=string} true "Partition request"
+// @Success 200
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 404 {object} object "Partition not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/partitions/detach [post]
+func (h *AdminHandler) DetachPartition(c *gin.Context) {
+	tenantID, ok := bindPartitionRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.partitionService.DetachPartition(tenantID); err != nil {
+		if errors.Is(err, service.ErrPartitionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func bindPartitionRequest(c *gin.Context) (string, bool) {
+	var request struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	if _, err := uuid.Parse(request.TenantID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant_id format"})
+		return "", false
+	}
+	return request.TenantID, true
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler handles a tenant's API keys
+type APIKeyHandler struct {
+	apiKeys     *service.APIKeyService
+	auditLogger *audit.Logger
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler
+func NewAPIKeyHandler(apiKeys *service.APIKeyService, auditLogger *audit.Logger) *APIKeyHandler {
+	return &APIKeyHandler{apiKeys: apiKeys, auditLogger: auditLogger}
+}
+
+// CreateKey godoc
+// @Summary Create an API key
+// @Description Create an API key for the tenant. Requests sending it in the X-API-Key header are bound to the tenant like a tenant-admin token. Only its SHA-256 hash is stored; the key is only returned here.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param key body object{name=string,ttl=string} true "Name and lifetime (default: no expiry)"
+// @Success 201 {object} domain.APIKey
+// @Failure 400 {object} object "Invalid request body or lifetime"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/keys [post]
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request struct {
+		Name string `json:"name" binding:"required,max=128"`
+		TTL  string `json:"ttl"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl"})
+			return
+		}
+	}
+
+	key, err := h.apiKeys.Create(tenantID, request.Name, requestActor(c), ttl)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionAPIKeyCreate, tenantID, map[string]interface{}{
+		"key_id":     key.ID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"expires_at": key.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListKeys godoc
+// @Summary List a tenant's API keys
+// @Description List the tenant's API keys that have not expired, including revoked ones. Key values are not returned.
+// @Tags tenants
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.APIKey}
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/keys [get]
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.apiKeys.List(c.Param("id"))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// RotateKey godoc
+// @Summary Rotate an API key
+// @Description Create a replacement for the API key with the same name and expiry. The old key stays valid for the grace period (default 0, up to 7 days) so clients can switch over.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param key_id path string true "API key ID"
+// @Param rotation body object{grace=string} false "How long the old key stays valid, e.g. 1h"
+// @Success 201 {object} domain.APIKey
+// @Failure 400 {object} object "Invalid grace period"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "API key not found, expired or revoked"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/keys/{key_id}/rotate [post]
+func (h *APIKeyHandler) RotateKey(c *gin.Context) {
+	tenantID := c.Param("id")
+	keyID := c.Param("key_id")
+
+	var request struct {
+		Grace string `json:"grace"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var grace time.Duration
+	if request.Grace != "" {
+		var err error
+		if grace, err = time.ParseDuration(request.Grace); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid grace"})
+			return
+		}
+	}
+
+	key, err := h.apiKeys.Rotate(tenantID, keyID, requestActor(c), grace)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionAPIKeyRotate, tenantID, map[string]interface{}{
+		"key_id":     keyID,
+		"new_key_id": key.ID,
+		"prefix":     key.Prefix,
+		"grace":      grace.String(),
+	})
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeKey godoc
+// @Summary Revoke an API key
+// @Description Reject the API key from now on
+// @Tags tenants
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param key_id path string true "API key ID"
+// @Success 204
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "API key not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/keys/{key_id} [delete]
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	tenantID := c.Param("id")
+	keyID := c.Param("key_id")
+
+	if err := h.apiKeys.Revoke(tenantID, keyID); err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionAPIKeyRevoke, tenantID, map[string]interface{}{
+		"key_id": keyID,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKeyRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAPIKeyNotFound), errors.Is(err, service.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
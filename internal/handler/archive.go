@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ArchiveHandler handles the archives of a tenant's old messages
+type ArchiveHandler struct {
+	archiveService *service.ArchiveService
+	auditLogger    *audit.Logger
+}
+
+// NewArchiveHandler creates a new ArchiveHandler
+func NewArchiveHandler(archiveService *service.ArchiveService, auditLogger *audit.Logger) *ArchiveHandler {
+	return &ArchiveHandler{archiveService: archiveService, auditLogger: auditLogger}
+}
+
+// ListArchives godoc
+// @Summary List a tenant's message archives
+// @Description List the objects the tenant's old messages were archived to, one per UTC day, newest day first, with when each was last restored.
+// @Tags tenants
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.MessageArchive}
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/archives [get]
+func (h *ArchiveHandler) ListArchives(c *gin.Context) {
+	archives, err := h.archiveService.ListArchives(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": archives})
+}
+
+// RestoreArchive godoc
+// @Summary Restore a message archive
+// @Description Load the archived messages back into the tenant's partition, skipping messages still stored. The archive's day is not archived again for hold_seconds (default a day).
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param archive_id path string true "Archive ID"
+// @Param request body domain.ArchiveRestoreRequest false "Restore options"
+// @Success 200 {object} domain.MessageArchive
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Archive or partition not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/archives/{archive_id}/restore [post]
+func (h *ArchiveHandler) RestoreArchive(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var req domain.ArchiveRestoreRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	archive, err := h.archiveService.Restore(c.Request.Context(), tenantID, c.Param("archive_id"), time.Duration(req.HoldSeconds)*time.Second)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArchiveRestore):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrArchiveNotFound), errors.Is(err, service.ErrPartitionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionArchiveRestore, tenantID, map[string]interface{}{
+		"archive_id": archive.ID,
+		"day":        archive.Day,
+		"restored":   archive.RestoredCount,
+	})
+
+	c.JSON(http.StatusOK, archive)
+}
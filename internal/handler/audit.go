@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"multi-tenant-messaging/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles reading the audit log
+type AuditHandler struct {
+	auditLogger *audit.Logger
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(auditLogger *audit.Logger) *AuditHandler {
+	return &AuditHandler{auditLogger: auditLogger}
+}
+
+// ListAudit godoc
+// @Summary List the audit log
+// @Description List audited administrative operations, newest first, with who made them, when, and the values they changed (old and new). Filters combine; pass next_cursor as cursor for the next page. Admin only.
+// @Tags admin
+// @Produce  json
+// @Param actor query string false "Only entries by this actor (token subject, cli or anonymous)"
+// @Param action query string false "Only entries of this action, e.g. tenant.delete"
+// @Param tenant_id query string false "Only entries about this tenant"
+// @Param since query string false "Only entries at or after this time (RFC 3339)"
+// @Param until query string false "Only entries before this time (RFC 3339)"
+// @Param cursor query string false "ID of the last entry of the previous page"
+// @Param limit query int false "Entries per page (default 50, max 500)"
+// @Success 200 {object} object{data=[]audit.Entry,next_cursor=string}
+// @Failure 400 {object} object "Invalid filter, cursor or limit"
+// @Failure 500 {object} object "Internal server error"
+// @Router /audit [get]
+func (h *AuditHandler) ListAudit(c *gin.Context) {
+	filter := audit.Filter{
+		Actor:    c.Query("actor"),
+		Action:   c.Query("action"),
+		TenantID: c.Query("tenant_id"),
+		Cursor:   c.Query("cursor"),
+	}
+	var err error
+	if filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "0")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	for name, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		if *bound, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + " parameter"})
+			return
+		}
+	}
+
+	entries, next, err := h.auditLogger.List(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        entries,
+		"next_cursor": next,
+	})
+}
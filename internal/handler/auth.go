@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler handles token requests
+type AuthHandler struct {
+	tokens *auth.TokenService
+}
+
+// NewAuthHandler creates a new AuthHandler
+func NewAuthHandler(tokens *auth.TokenService) *AuthHandler {
+	return &AuthHandler{tokens: tokens}
+}
+
+// Refresh godoc
+// @Summary Refresh an access token
+// @Description Exchange a refresh token for a new access and refresh token. The used refresh token is revoked.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body object{refresh_token=string} true "Refresh request"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 401 {object} object "Invalid or revoked refresh token"
+// @Failure 500 {object} object "Internal server error"
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pair, err := h.tokens.Refresh(request.RefreshToken)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pair)
+}
+
+// Revoke godoc
+// @Summary Revoke a token
+// @Description Add a token's ID to the revocation list so it is rejected before it expires
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param request body object{token=string} true "Token to revoke"
+// @Success 204
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 401 {object} object "Invalid token"
+// @Failure 500 {object} object "Internal server error"
+// @Router /auth/revoke [post]
+func (h *AuthHandler) Revoke(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tokens.Revoke(request.Token); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondAuthError(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
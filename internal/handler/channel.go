@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// channelConfig is the request body for creating or updating a channel
+type channelConfig struct {
+	Workers      int    `json:"workers" binding:"omitempty,min=1"`
+	Ordered      bool   `json:"ordered"`
+	PartitionKey string `json:"partition_key"`
+}
+
+func (cfg channelConfig) channel(tenantID, name string) domain.Channel {
+	workers := cfg.Workers
+	if workers == 0 {
+		workers = 3 // Default workers
+		if cfg.Ordered {
+			workers = 1
+		}
+	}
+	return domain.Channel{
+		TenantID:     tenantID,
+		Name:         name,
+		Workers:      workers,
+		Ordered:      cfg.Ordered,
+		PartitionKey: cfg.PartitionKey,
+	}
+}
+
+// ListChannels godoc
+// @Summary List a tenant's channels
+// @Description List the tenant's named channels, each consumed from its own queue with its own workers
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.Channel}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/channels [get]
+func (h *TenantHandler) ListChannels(c *gin.Context) {
+	channels, err := h.tenantService.ListChannels(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": channels})
+}
+
+// CreateChannel godoc
+// @Summary Create a tenant channel
+// @Description Create a named channel with its own queue (tenant_{id}_channel_{name}) and worker pool. Its messages go through the tenant's processing and are stored with the channel name. Workers default to 3, or 1 for ordered channels.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param channel body object{name=string,workers=int,ordered=bool,partition_key=string} true "Channel"
+// @Success 201 {object} domain.Channel
+// @Failure 400 {object} object "Invalid request body or channel"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 409 {object} object "Channel already exists"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /tenants/{id}/channels [post]
+func (h *TenantHandler) CreateChannel(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request struct {
+		Name string `json:"name" binding:"required"`
+		channelConfig
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel, err := h.tenantService.CreateChannel(request.channel(tenantID, request.Name))
+	if err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionChannelCreate, tenantID, map[string]interface{}{
+		"channel": channel.Name,
+		"workers": channel.Workers,
+		"ordered": channel.Ordered,
+	})
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+// GetChannel godoc
+// @Summary Get a tenant channel
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param name path string true "Channel name"
+// @Success 200 {object} domain.Channel
+// @Failure 404 {object} object "Channel not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/channels/{name} [get]
+func (h *TenantHandler) GetChannel(c *gin.Context) {
+	channel, err := h.tenantService.GetChannel(c.Param("id"), c.Param("name"))
+	if err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// UpdateChannel godoc
+// @Summary Update a tenant channel
+// @Description Change the channel's workers, ordered mode and partition key. The tenant's consumer is restarted to apply them.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param name path string true "Channel name"
+// @Param config body object{workers=int,ordered=bool,partition_key=string} true "Channel configuration"
+// @Success 200 {object} domain.Channel
+// @Failure 400 {object} object "Invalid request body or configuration"
+// @Failure 404 {object} object "Channel not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/channels/{name} [put]
+func (h *TenantHandler) UpdateChannel(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var config channelConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel, err := h.tenantService.UpdateChannel(config.channel(tenantID, c.Param("name")))
+	if err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionChannelUpdate, tenantID, map[string]interface{}{
+		"channel":       channel.Name,
+		"workers":       channel.Workers,
+		"ordered":       channel.Ordered,
+		"partition_key": channel.PartitionKey,
+	})
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChannel godoc
+// @Summary Delete a tenant channel
+// @Description Stop consuming the channel and delete its queue, including messages still in it. Stored messages keep their channel name.
+// @Tags tenants
+// @Param id path string true "Tenant ID"
+// @Param name path string true "Channel name"
+// @Success 204
+// @Failure 404 {object} object "Channel not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/channels/{name} [delete]
+func (h *TenantHandler) DeleteChannel(c *gin.Context) {
+	tenantID := c.Param("id")
+	name := c.Param("name")
+
+	if err := h.tenantService.DeleteChannel(tenantID, name); err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionChannelDelete, tenantID, map[string]interface{}{
+		"channel": name,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondChannelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidChannel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrChannelNotFound), errors.Is(err, service.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrChannelExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedTransport):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"multi-tenant-messaging/internal/auth"
+	"multi-tenant-messaging/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestClaims returns the verified JWT claims of the request, if any
+func requestClaims(c *gin.Context) *auth.Claims {
+	value, exists := c.Get(middleware.ClaimsKey)
+	if !exists {
+		return nil
+	}
+	claims, _ := value.(*auth.Claims)
+	return claims
+}
+
+// claimsTenantID returns the tenant the request's token is bound to, or ""
+func claimsTenantID(c *gin.Context) string {
+	if claims := requestClaims(c); claims != nil {
+		return claims.TenantID
+	}
+	return ""
+}
+
+// requestActor returns the JWT subject of the request for auditing
+func requestActor(c *gin.Context) string {
+	if claims := requestClaims(c); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
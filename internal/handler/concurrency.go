@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyHandler handles the per-tenant worker bounds operators set
+type ConcurrencyHandler struct {
+	concurrencyService *service.ConcurrencyService
+	auditLogger        *audit.Logger
+}
+
+// NewConcurrencyHandler creates a new ConcurrencyHandler
+func NewConcurrencyHandler(concurrencyService *service.ConcurrencyService, auditLogger *audit.Logger) *ConcurrencyHandler {
+	return &ConcurrencyHandler{concurrencyService: concurrencyService, auditLogger: auditLogger}
+}
+
+// GetWorkerBounds godoc
+// @Summary Get a tenant's worker bounds
+// @Description Get the fewest and most workers the tenant can set with PUT /tenants/{id}/config/concurrency. Without bounds of its own (default) a tenant can set 1 to concurrency.max_tenant_workers.
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.WorkerBounds
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/worker-bounds [get]
+func (h *ConcurrencyHandler) GetWorkerBounds(c *gin.Context) {
+	bounds, err := h.concurrencyService.GetBounds(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bounds)
+}
+
+// UpdateWorkerBounds godoc
+// @Summary Set a tenant's worker bounds
+// @Description Set the fewest and most workers the tenant can set. max_workers may exceed concurrency.max_tenant_workers but not concurrency.max_workers. Workers already outside the bounds stay until the tenant next sets them.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param bounds body domain.WorkerBounds true "Worker bounds"
+// @Success 200 {object} domain.WorkerBounds
+// @Failure 400 {object} object "Invalid request body or bounds"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/worker-bounds [put]
+func (h *ConcurrencyHandler) UpdateWorkerBounds(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var bounds domain.WorkerBounds
+	if err := c.ShouldBindJSON(&bounds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, _ := h.concurrencyService.GetBounds(tenantID)
+	bounds, err := h.concurrencyService.SetBounds(tenantID, bounds)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWorkerBounds) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionWorkerBoundsUpdate, tenantID, map[string]interface{}{
+		"min_workers": bounds.MinWorkers,
+		"max_workers": bounds.MaxWorkers,
+	}, workerBoundsValues(before), workerBoundsValues(bounds))
+
+	c.JSON(http.StatusOK, bounds)
+}
+
+// ResetWorkerBounds godoc
+// @Summary Reset a tenant's worker bounds
+// @Description Drop the tenant's own worker bounds so it can again set 1 to concurrency.max_tenant_workers workers.
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.WorkerBounds
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/worker-bounds [delete]
+func (h *ConcurrencyHandler) ResetWorkerBounds(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	before, _ := h.concurrencyService.GetBounds(tenantID)
+	bounds, err := h.concurrencyService.ResetBounds(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionWorkerBoundsUpdate, tenantID, map[string]interface{}{
+		"min_workers": bounds.MinWorkers,
+		"max_workers": bounds.MaxWorkers,
+		"default":     true,
+	}, workerBoundsValues(before), workerBoundsValues(bounds))
+
+	c.JSON(http.StatusOK, bounds)
+}
+
+// workerBoundsValues are the bounds as recorded in the audit log
+func workerBoundsValues(bounds domain.WorkerBounds) map[string]interface{} {
+	return map[string]interface{}{
+		"min_workers": bounds.MinWorkers,
+		"max_workers": bounds.MaxWorkers,
+		"default":     bounds.Default,
+	}
+}
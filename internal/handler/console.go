@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"multi-tenant-messaging/internal/console"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// Console commands clients send
+const (
+	consoleSubscribe   = "subscribe"
+	consoleUnsubscribe = "unsubscribe"
+)
+
+// Console replies; events are sent as they are emitted, see events.Event
+const (
+	consoleSubscribed   = "console.subscribed"
+	consoleUnsubscribed = "console.unsubscribed"
+	consoleDropped      = "console.dropped"
+	consoleError        = "console.error"
+)
+
+// ConsoleOptions configures the real-time console
+type ConsoleOptions struct {
+	// OriginPatterns lists the hosts browsers may connect from besides the
+	// server's own, e.g. "console.example.com" or "*.example.com"
+	OriginPatterns []string
+	// WriteTimeout disconnects clients that stop reading
+	WriteTimeout time.Duration
+	// PingInterval is how often idle connections are checked
+	PingInterval time.Duration
+	// MaxSubscriptions caps the tenants a connection subscribes to
+	MaxSubscriptions int
+}
+
+// consoleCommand is a message from a console client
+type consoleCommand struct {
+	Action    string   `json:"action"`
+	TenantIDs []string `json:"tenant_ids"`
+}
+
+// consoleReply answers a command or reports missed events
+type consoleReply struct {
+	Type      string   `json:"type"`
+	TenantIDs []string `json:"tenant_ids,omitempty"`
+	Dropped   int64    `json:"dropped,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// ConsoleHandler serves the WebSocket of the real-time console
+type ConsoleHandler struct {
+	hub        *console.Hub
+	allowlists *service.AllowlistService
+	opts       ConsoleOptions
+}
+
+// NewConsoleHandler creates a new ConsoleHandler
+func NewConsoleHandler(hub *console.Hub, allowlists *service.AllowlistService, opts ConsoleOptions) *ConsoleHandler {
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.MaxSubscriptions <= 0 {
+		opts.MaxSubscriptions = 100
+	}
+	return &ConsoleHandler{hub: hub, allowlists: allowlists, opts: opts}
+}
+
+// Serve godoc
+// @Summary Open the real-time console
+// @Description Upgrade to a WebSocket streaming the events of the subscribed tenants as JSON: message.stored for every stored message, consumer.scaled and consumer.restarted when workers change, message.dead_lettered, and the other system events. Subscribe with the tenant_id query parameter or by sending {"action":"subscribe","tenant_ids":[...]}; {"action":"unsubscribe",...} stops a tenant. Tokens bound to a tenant can only subscribe to it, and the tenant's IP allowlist applies. A client that reads too slowly misses events and is told how many with a console.dropped message.
+// @Tags tenants
+// @Security BearerAuth
+// @Param tenant_id query []string false "Tenants to subscribe to right away" collectionFormat(multi)
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} object "Not a WebSocket request"
+// @Failure 403 {object} object "Origin not allowed"
+// @Router /ws [get]
+func (h *ConsoleHandler) Serve(c *gin.Context) {
+	tenantID := claimsTenantID(c)
+	clientIP := net.ParseIP(c.ClientIP())
+
+	conn, err := websocket.Accept(c.Writer, c.Request, &websocket.AcceptOptions{OriginPatterns: h.opts.OriginPatterns})
+	if err != nil {
+		// Accept sudah menulis response penolakan
+		return
+	}
+	defer conn.CloseNow()
+
+	client := h.hub.Register()
+	if client == nil {
+		conn.Close(websocket.StatusGoingAway, "server shutting down")
+		return
+	}
+	defer h.hub.Unregister(client)
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	authorize := func(id string) error {
+		if tenantID != "" && id != tenantID {
+			return errors.New("token is bound to another tenant")
+		}
+		allowed, err := h.allowlists.IsAllowed(id, clientIP)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errors.New("source IP not allowed for tenant")
+		}
+		return nil
+	}
+	replies := make(chan consoleReply, 8)
+	handle := func(cmd consoleCommand) consoleReply {
+		switch cmd.Action {
+		case consoleSubscribe:
+			if client.Subscriptions()+len(cmd.TenantIDs) > h.opts.MaxSubscriptions {
+				return consoleReply{Type: consoleError, Error: fmt.Sprintf("at most %d subscriptions per connection", h.opts.MaxSubscriptions)}
+			}
+			for _, id := range cmd.TenantIDs {
+				if err := authorize(id); err != nil {
+					return consoleReply{Type: consoleError, TenantIDs: []string{id}, Error: err.Error()}
+				}
+			}
+			h.hub.Subscribe(client, cmd.TenantIDs...)
+			return consoleReply{Type: consoleSubscribed, TenantIDs: cmd.TenantIDs}
+		case consoleUnsubscribe:
+			h.hub.Unsubscribe(client, cmd.TenantIDs...)
+			return consoleReply{Type: consoleUnsubscribed, TenantIDs: cmd.TenantIDs}
+		default:
+			return consoleReply{Type: consoleError, Error: fmt.Sprintf("unknown action %q", cmd.Action)}
+		}
+	}
+	if initial := c.QueryArray("tenant_id"); len(initial) > 0 {
+		replies <- handle(consoleCommand{Action: consoleSubscribe, TenantIDs: initial})
+	}
+
+	// Pembaca juga yang memproses pong dan frame close dari client
+	go func() {
+		defer cancel()
+		for {
+			var cmd consoleCommand
+			if err := wsjson.Read(ctx, conn, &cmd); err != nil {
+				if websocket.CloseStatus(err) == -1 && ctx.Err() == nil {
+					slog.Debug("Console connection failed", "error", err)
+				}
+				return
+			}
+			select {
+			case replies <- handle(cmd):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(h.opts.PingInterval)
+	defer ping.Stop()
+	for {
+		var message interface{}
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.Done():
+			conn.Close(websocket.StatusGoingAway, "server shutting down")
+			return
+		case reply := <-replies:
+			message = reply
+		case event := <-client.Events():
+			message = event
+		case <-ping.C:
+			pingCtx, cancelPing := context.WithTimeout(ctx, h.opts.WriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if err := h.write(ctx, conn, message); err != nil {
+			return
+		}
+		if dropped := client.TakeDropped(); dropped > 0 {
+			if err := h.write(ctx, conn, consoleReply{Type: consoleDropped, Dropped: dropped}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// write sends message, giving up on clients that do not read it in time
+func (h *ConsoleHandler) write(ctx context.Context, conn *websocket.Conn, message interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, h.opts.WriteTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, conn, message)
+}
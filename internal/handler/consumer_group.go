@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsumerGroupHandler handles consumer group requests
+type ConsumerGroupHandler struct {
+	consumerGroupService *service.ConsumerGroupService
+}
+
+// NewConsumerGroupHandler creates a new ConsumerGroupHandler
+func NewConsumerGroupHandler(consumerGroupService *service.ConsumerGroupService) *ConsumerGroupHandler {
+	return &ConsumerGroupHandler{consumerGroupService: consumerGroupService}
+}
+
+// ListGroups godoc
+// @Summary List a tenant's consumer groups
+// @Description List the tenant's consumer groups with their committed offset and lag
+// @Tags consumer-groups
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.ConsumerGroup}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/consumer-groups [get]
+func (h *ConsumerGroupHandler) ListGroups(c *gin.Context) {
+	groups, err := h.consumerGroupService.ListGroups(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": groups})
+}
+
+// CreateGroup godoc
+// @Summary Create a consumer group
+// @Description Create a consumer group that reads the tenant's processed messages with its own offset. It starts after the newest message (latest, default) or at the first one (earliest).
+// @Tags consumer-groups
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param group body object{name=string,start=string} true "Consumer group"
+// @Success 201 {object} domain.ConsumerGroup
+// @Failure 400 {object} object "Invalid request body, name or start"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 409 {object} object "Consumer group already exists"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/consumer-groups [post]
+func (h *ConsumerGroupHandler) CreateGroup(c *gin.Context) {
+	var request struct {
+		Name  string `json:"name" binding:"required"`
+		Start string `json:"start"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.consumerGroupService.CreateGroup(c.Param("id"), request.Name, request.Start)
+	if err != nil {
+		respondConsumerGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// GetGroup godoc
+// @Summary Get a consumer group
+// @Tags consumer-groups
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param group path string true "Consumer group name"
+// @Success 200 {object} domain.ConsumerGroup
+// @Failure 404 {object} object "Consumer group not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/consumer-groups/{group} [get]
+func (h *ConsumerGroupHandler) GetGroup(c *gin.Context) {
+	group, err := h.consumerGroupService.GetGroup(c.Param("id"), c.Param("group"))
+	if err != nil {
+		respondConsumerGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a consumer group
+// @Tags consumer-groups
+// @Param id path string true "Tenant ID"
+// @Param group path string true "Consumer group name"
+// @Success 204
+// @Failure 404 {object} object "Consumer group not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/consumer-groups/{group} [delete]
+func (h *ConsumerGroupHandler) DeleteGroup(c *gin.Context) {
+	if err := h.consumerGroupService.DeleteGroup(c.Param("id"), c.Param("group")); err != nil {
+		respondConsumerGroupError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// FetchMessages godoc
+// @Summary Read messages as a consumer group
+// @Description Get the next messages after the group's offset, oldest first. Commit next_offset once they are processed; until then the same batch is returned again. With auto_commit the offset moves past the batch right away, so concurrent members of the group get disjoint batches but a member that fails loses its batch.
+// @Tags consumer-groups
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param group path string true "Consumer group name"
+// @Param limit query int false "Maximum number of messages (default 100)"
+// @Param auto_commit query bool false "Commit the batch's offset immediately"
+// @Success 200 {object} domain.ConsumerGroupBatch
+// @Failure 400 {object} object "Invalid limit or auto_commit"
+// @Failure 404 {object} object "Consumer group not found"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 503 {object} object "Server busy, retry after the Retry-After delay"
+// @Router /tenants/{id}/consumer-groups/{group}/messages [get]
+func (h *ConsumerGroupHandler) FetchMessages(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	autoCommit, err := strconv.ParseBool(c.DefaultQuery("auto_commit", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid auto_commit parameter"})
+		return
+	}
+
+	batch, err := h.consumerGroupService.Fetch(c.Request.Context(), c.Param("id"), c.Param("group"), limit, autoCommit)
+	if err != nil {
+		respondConsumerGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// CommitOffset godoc
+// @Summary Commit a consumer group offset
+// @Description Move the group's offset to a message, usually the next_offset of a processed batch. Commits never move the offset backwards; an older offset is ignored.
+// @Tags consumer-groups
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param group path string true "Consumer group name"
+// @Param offset body object{offset=string} true "ID of the last processed message"
+// @Success 200 {object} domain.ConsumerGroup
+// @Failure 400 {object} object "Invalid request body or unknown message"
+// @Failure 404 {object} object "Consumer group not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/consumer-groups/{group}/commit [post]
+func (h *ConsumerGroupHandler) CommitOffset(c *gin.Context) {
+	var request struct {
+		Offset string `json:"offset" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.consumerGroupService.Commit(c.Param("id"), c.Param("group"), request.Offset)
+	if err != nil {
+		respondConsumerGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// SeekOffset godoc
+// @Summary Reset a consumer group offset
+// @Description Set the group's offset to earliest, latest or a message ID; the group then reads on from the message after it. Unlike a commit this may move the offset backwards to replay messages.
+// @Tags consumer-groups
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param group path string true "Consumer group name"
+// @Param seek body object{to=string} true "earliest, latest or a message ID"
+// @Success 200 {object} domain.ConsumerGroup
+// @Failure 400 {object} object "Invalid request body or unknown message"
+// @Failure 404 {object} object "Consumer group not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/consumer-groups/{group}/seek [post]
+func (h *ConsumerGroupHandler) SeekOffset(c *gin.Context) {
+	var request struct {
+		To string `json:"to" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.consumerGroupService.Seek(c.Param("id"), c.Param("group"), request.To)
+	if err != nil {
+		respondConsumerGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func respondConsumerGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidConsumerGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrConsumerGroupNotFound), errors.Is(err, service.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrConsumerGroupExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
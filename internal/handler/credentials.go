@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialsHandler handles credential rotation requests
+type CredentialsHandler struct {
+	credentialService *service.CredentialService
+}
+
+// NewCredentialsHandler creates a new CredentialsHandler
+func NewCredentialsHandler(credentialService *service.CredentialService) *CredentialsHandler {
+	return &CredentialsHandler{credentialService: credentialService}
+}
+
+// RotateCredentials godoc
+// @Summary Rotate credentials without a restart
+// @Description Read database.url, the RabbitMQ URLs and security.jwt_secret again (including DATABASE_URL_FILE, RABBITMQ_URL_FILE and JWT_SECRET_FILE) and switch to those that changed. New connections use the new credentials; pooled database connections are closed once idle, tenant consumers are drained and restarted on new broker connections, and tokens signed with the old JWT secret stay valid until they expire. Same as sending SIGHUP.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} object{rotated=[]string}
+// @Failure 409 {object} object "Rotation already in progress"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/credentials/rotate [post]
+func (h *CredentialsHandler) RotateCredentials(c *gin.Context) {
+	rotated, err := h.credentialService.Rotate(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrRotationInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "rotated": rotated})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rotated": rotated})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DedupHandler handles tenant deduplication config requests
+type DedupHandler struct {
+	dedupService *service.DedupService
+}
+
+// NewDedupHandler creates a new DedupHandler
+func NewDedupHandler(dedupService *service.DedupService) *DedupHandler {
+	return &DedupHandler{dedupService: dedupService}
+}
+
+// GetDedupConfig godoc
+// @Summary Get a tenant's dedup config
+// @Description Get how long dedup keys are remembered to drop duplicates (0 means disabled) and whether the key is the AMQP message-id or a hash of the payload.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.DedupConfig
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/dedup [get]
+func (h *DedupHandler) GetDedupConfig(c *gin.Context) {
+	config, err := h.dedupService.GetConfig(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// UpdateDedupConfig godoc
+// @Summary Update a tenant's dedup config
+// @Description Messages whose dedup key was already seen within the window are acked and dropped. The key is the AMQP message-id (message_id, the default; messages without one are never dropped) or a SHA-256 of the payload (payload_hash). 0 disables deduplication.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body domain.DedupConfig true "Dedup configuration"
+// @Success 200 {object} domain.DedupConfig
+// @Failure 400 {object} object "Invalid request body or key"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/dedup [put]
+func (h *DedupHandler) UpdateDedupConfig(c *gin.Context) {
+	var req struct {
+		WindowSeconds *int   `json:"window_seconds" binding:"required,min=0"`
+		Key           string `json:"key"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config, err := h.dedupService.SetConfig(c.Param("id"), domain.DedupConfig{WindowSeconds: *req.WindowSeconds, Key: req.Key})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDedupConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
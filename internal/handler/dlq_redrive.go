@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedriveDLQ godoc
+// @Summary Redrive dead-lettered messages
+// @Description Move the tenant's dead-lettered messages matching a CEL expression (same variables as filter rules; empty matches all) back to the queue they came from. An optional transform merges a JSON merge patch into each payload (null removes a field) and drops headers. Messages that do not match or cannot be transformed stay in the DLQ. dry_run reports the outcome with a preview of the first transformed messages and leaves the DLQ unchanged.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param redrive body domain.DLQRedrive true "Match expression, transform, limit and dry run"
+// @Success 200 {object} domain.DLQRedriveResult
+// @Failure 400 {object} object "Invalid request body, match expression or patch"
+// @Failure 404 {object} object "Tenant not consumed by this instance"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /tenants/{id}/dlq/redrive [post]
+func (h *TenantHandler) RedriveDLQ(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request domain.DLQRedrive
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.tenantService.RedriveDeadLetters(c.Request.Context(), tenantID, request)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRedrive):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTenantNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrUnsupportedTransport):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if !result.DryRun {
+		h.auditLogger.Record(requestActor(c), audit.ActionDLQRedrive, tenantID, map[string]interface{}{
+			"match":    request.Match,
+			"redriven": result.Redriven,
+			"skipped":  result.Skipped,
+			"failed":   result.Failed,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListDLQ godoc
+// @Summary List dead-lettered messages
+// @Description Page through the tenant's DLQ in queue order with each message's headers, payload (JSON projection for protobuf and Avro), dead-letter reason and retry count. Messages are read without being removed; a page can reach at most 1000 messages deep.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param offset query int false "Position of the first message (default 0)"
+// @Param limit query int false "Messages per page (default 50)"
+// @Success 200 {object} domain.DeadLetterPage
+// @Failure 400 {object} object "Invalid offset or limit"
+// @Failure 404 {object} object "Tenant not consumed by this instance"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /tenants/{id}/dlq [get]
+func (h *TenantHandler) ListDLQ(c *gin.Context) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset parameter"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	page, err := h.tenantService.ListDeadLetters(c.Request.Context(), c.Param("id"), offset, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidDLQPage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTenantNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrUnsupportedTransport):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ReplayDLQ godoc
+// @Summary Replay dead-lettered messages
+// @Description Move the tenant's dead-lettered messages with the given IDs, or all of them, back to the queue they came from unchanged, with the retry headers cleared. The other messages stay in the DLQ in their original order. dry_run reports what would be replayed and leaves the DLQ unchanged.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param replay body domain.DLQReplay true "Message IDs or all, and dry run"
+// @Success 200 {object} domain.DLQRedriveResult
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 404 {object} object "Tenant not consumed by this instance"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /tenants/{id}/dlq/replay [post]
+func (h *TenantHandler) ReplayDLQ(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request domain.DLQReplay
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.tenantService.ReplayDeadLetters(c.Request.Context(), tenantID, request)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRedrive):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTenantNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrUnsupportedTransport):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if !result.DryRun {
+		h.auditLogger.Record(requestActor(c), audit.ActionDLQReplay, tenantID, map[string]interface{}{
+			"message_ids": request.MessageIDs,
+			"all":         request.All,
+			"replayed":    result.Redriven,
+			"failed":      result.Failed,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
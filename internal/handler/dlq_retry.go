@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DLQRetryHandler handles tenant DLQ retry policy requests
+type DLQRetryHandler struct {
+	dlqRetryService *service.DLQRetryService
+}
+
+// NewDLQRetryHandler creates a new DLQRetryHandler
+func NewDLQRetryHandler(dlqRetryService *service.DLQRetryService) *DLQRetryHandler {
+	return &DLQRetryHandler{dlqRetryService: dlqRetryService}
+}
+
+// GetPolicy godoc
+// @Summary Get a tenant's DLQ retry policy
+// @Description Get whether dead-lettered messages are retried automatically and the delay before each retry
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.DLQRetryPolicy
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/dlq-retry [get]
+func (h *DLQRetryHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.dlqRetryService.GetPolicy(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update a tenant's DLQ retry policy
+// @Description Override the config default. The n-th retry moves a message from the DLQ back to the tenant queue schedule_seconds[n-1] after it entered the DLQ; after the last delay the message stays in the DLQ.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{enabled=bool,schedule_seconds=[]int} true "DLQ retry policy"
+// @Success 200 {object} domain.DLQRetryPolicy
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/dlq-retry [put]
+func (h *DLQRetryHandler) UpdatePolicy(c *gin.Context) {
+	var config struct {
+		Enabled         *bool `json:"enabled" binding:"required"`
+		ScheduleSeconds []int `json:"schedule_seconds" binding:"max=20,dive,min=0"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.dlqRetryService.SetPolicy(c.Param("id"), *config.Enabled, config.ScheduleSeconds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ListAttempts godoc
+// @Summary List DLQ retry attempts
+// @Description List the most recent decisions of the DLQ retry scheduler for the tenant: each retry of a message and when it gave up
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param message_id query string false "Only attempts for this AMQP message ID"
+// @Param limit query int false "Maximum number of attempts (default 100)"
+// @Success 200 {object} object{data=[]domain.DLQRetryAttempt}
+// @Failure 400 {object} object "Invalid limit"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/dlq/retries [get]
+func (h *DLQRetryHandler) ListAttempts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	attempts, err := h.dlqRetryService.ListAttempts(c.Param("id"), c.Query("message_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": attempts})
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/events"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventPollInterval is how often the stream checks for new events; events
+	// are read from Postgres so every instance sees those emitted by the others
+	eventPollInterval = time.Second
+	// eventKeepAlive is how often an idle stream sends a comment so proxies keep it open
+	eventKeepAlive = 15 * time.Second
+)
+
+// EventHandler handles tenant system event requests
+type EventHandler struct {
+	emitter *events.Emitter
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewEventHandler creates a new EventHandler
+func NewEventHandler(emitter *events.Emitter) *EventHandler {
+	return &EventHandler{emitter: emitter, closed: make(chan struct{})}
+}
+
+// Close ends all open event streams, e.g. on shutdown
+func (h *EventHandler) Close() {
+	h.once.Do(func() { close(h.closed) })
+}
+
+// ListEvents godoc
+// @Summary List a tenant's system events
+// @Description List the tenant's events (tenant.created, tenant.config_changed, message.dead_lettered, consumer.restarted) after a cursor, oldest first. Pass the seq of the last event as after to get the next page.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param after query int false "Only events with a greater seq (default 0)"
+// @Param limit query int false "Maximum number of events (default 100)"
+// @Success 200 {object} object{data=[]events.Event}
+// @Failure 400 {object} object "Invalid after or limit"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/events [get]
+func (h *EventHandler) ListEvents(c *gin.Context) {
+	after, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after parameter"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	list, err := h.emitter.List(c.Request.Context(), c.Param("id"), after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// StreamEvents godoc
+// @Summary Stream a tenant's system events
+// @Description Server-Sent Events feed of the tenant's events. Each SSE event is named by the event type, carries the event as JSON and uses its seq as id, so a reconnecting client resumes after Last-Event-ID. Without it the stream starts with new events, or after the given seq.
+// @Tags tenants
+// @Produce  text/event-stream
+// @Param id path string true "Tenant ID"
+// @Param after query int false "Replay events with a greater seq first"
+// @Param Last-Event-ID header int false "Resume after this seq; takes precedence over after"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} object "Invalid cursor"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/events/stream [get]
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	tenantID := c.Param("id")
+	ctx := c.Request.Context()
+
+	cursor := c.GetHeader("Last-Event-ID")
+	if cursor == "" {
+		cursor = c.Query("after")
+	}
+	var after int64
+	if cursor != "" {
+		var err error
+		if after, err = strconv.ParseInt(cursor, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event cursor"})
+			return
+		}
+	} else {
+		// Mulai dari event terbaru supaya client baru tidak menerima seluruh riwayat
+		latest, err := h.emitter.LatestSeq(ctx, tenantID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		after = latest
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	poll := time.NewTicker(eventPollInterval)
+	defer poll.Stop()
+	lastWrite := time.Now()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-h.closed:
+			return false
+		case <-poll.C:
+		}
+
+		list, err := h.emitter.List(ctx, tenantID, after, 100)
+		if err != nil {
+			return false
+		}
+		for _, event := range list {
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(event.Seq, 10),
+				Event: event.Type,
+				Data:  event,
+			})
+			after = event.Seq
+			lastWrite = time.Now()
+		}
+		if time.Since(lastWrite) >= eventKeepAlive {
+			io.WriteString(w, ": keep-alive\n\n")
+			lastWrite = time.Now()
+		}
+		return true
+	})
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportHandler handles message export requests
+type ExportHandler struct {
+	exportService *service.ExportService
+	allowlists    *service.AllowlistService
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(exportService *service.ExportService, allowlists *service.AllowlistService) *ExportHandler {
+	return &ExportHandler{exportService: exportService, allowlists: allowlists}
+}
+
+// CreateExport godoc
+// @Summary Start a message export
+// @Description Start an asynchronous export of messages, optionally limited to one tenant. The parquet format writes one file per tenant and day under tenant_id=.../date=... directories for Athena/BigQuery.
+// @Tags exports
+// @Accept  json
+// @Produce  json
+// @Param request body object{tenant_id=string,format=string} false "Export request; format is ndjson (default) or parquet"
+// @Success 202 {object} domain.ExportJob
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 403 {object} object "Source IP not allowed for the tenant"
+// @Failure 500 {object} object "Internal server error"
+// @Router /exports [post]
+func (h *ExportHandler) CreateExport(c *gin.Context) {
+	var request struct {
+		TenantID string `json:"tenant_id"`
+		Format   string `json:"format"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if request.TenantID != "" {
+		if _, err := uuid.Parse(request.TenantID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant_id format"})
+			return
+		}
+	}
+	if !allowClientIP(c, h.allowlists, request.TenantID) {
+		return
+	}
+
+	job, err := h.exportService.CreateExport(request.TenantID, request.Format)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExportFormat) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetExport godoc
+// @Summary Get export status
+// @Description Get the status and progress of an export job
+// @Tags exports
+// @Produce  json
+// @Param id path string true "Export ID"
+// @Success 200 {object} domain.ExportJob
+// @Failure 400 {object} object "Invalid export ID"
+// @Failure 403 {object} object "Source IP not allowed for the export's tenant"
+// @Failure 404 {object} object "Export not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /exports/{id} [get]
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	job, err := h.exportService.GetExport(c.Param("id"))
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+	if !allowClientIP(c, h.allowlists, job.TenantID) {
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ResumeExport godoc
+// @Summary Resume an export
+// @Description Resume a failed or interrupted export from its last checkpoint
+// @Tags exports
+// @Produce  json
+// @Param id path string true "Export ID"
+// @Success 202 {object} domain.ExportJob
+// @Failure 400 {object} object "Invalid export ID"
+// @Failure 403 {object} object "Source IP not allowed for the export's tenant"
+// @Failure 404 {object} object "Export not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /exports/{id}/resume [post]
+func (h *ExportHandler) ResumeExport(c *gin.Context) {
+	job, err := h.exportService.GetExport(c.Param("id"))
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+	if !allowClientIP(c, h.allowlists, job.TenantID) {
+		return
+	}
+
+	job, err = h.exportService.ResumeExport(job.ID)
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// DownloadExport godoc
+// @Summary Download an export
+// @Description Download a completed export as newline-delimited JSON, or as a zip of the partitioned files for Parquet exports
+// @Tags exports
+// @Produce  application/x-ndjson
+// @Produce  application/zip
+// @Param id path string true "Export ID"
+// @Success 200 {file} file
+// @Failure 400 {object} object "Invalid export ID"
+// @Failure 403 {object} object "Source IP not allowed for the export's tenant"
+// @Failure 404 {object} object "Export not found"
+// @Failure 409 {object} object "Export not completed"
+// @Router /exports/{id}/download [get]
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	id := c.Param("id")
+	job, err := h.exportService.GetExport(id)
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+	if !allowClientIP(c, h.allowlists, job.TenantID) {
+		return
+	}
+
+	if job.Format == domain.ExportFormatParquet {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", "attachment; filename=export_"+id+".zip")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", "attachment; filename=export_"+id+".ndjson")
+	}
+	if err := h.exportService.WriteExport(id, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			respondExportError(c, err)
+		}
+		return
+	}
+}
+
+func respondExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/filter"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FilterHandler handles tenant message filter requests
+type FilterHandler struct {
+	filterService *service.FilterService
+}
+
+// NewFilterHandler creates a new FilterHandler
+func NewFilterHandler(filterService *service.FilterService) *FilterHandler {
+	return &FilterHandler{filterService: filterService}
+}
+
+// GetRules godoc
+// @Summary Get a tenant's message filters
+// @Description Get the CEL filter rules evaluated on the tenant's messages before they are stored, in evaluation order
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{rules=[]filter.Rule}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/filters [get]
+func (h *FilterHandler) GetRules(c *gin.Context) {
+	rules, err := h.filterService.GetRules(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// SetRules godoc
+// @Summary Replace a tenant's message filters
+// @Description Replace the tenant's filter rules. Each rule is a CEL expression over message.id, message.type, message.headers and message.payload returning a bool; matching messages are dropped, tagged with target, or routed to the tenant's route queue named target instead of stored.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body object{rules=[]filter.Rule} true "Filter rules, in evaluation order"
+// @Success 200 {object} object{rules=[]filter.Rule}
+// @Failure 400 {object} object "Invalid request body or rule"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/filters [put]
+func (h *FilterHandler) SetRules(c *gin.Context) {
+	var request struct {
+		Rules []filter.Rule `json:"rules" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Rules == nil {
+		request.Rules = []filter.Rule{}
+	}
+
+	rules, err := h.filterService.SetRules(c.Param("id"), request.Rules)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// DryRun godoc
+// @Summary Preview message filters on a sample message
+// @Description Show which rules match a sample message and what would happen to it, using the given rules or the tenant's stored rules, and the given flags or the tenant's runtime config. Nothing is stored or routed.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body object{id=string,type=string,headers=object,payload=object,rules=[]filter.Rule,flags=object} true "Sample message, optional rules and optional flags"
+// @Success 200 {object} filter.Decision
+// @Failure 400 {object} object "Invalid request body or rule"
+// @Failure 422 {object} object "A rule exceeded its evaluation limit"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/filters/dry-run [post]
+func (h *FilterHandler) DryRun(c *gin.Context) {
+	var request struct {
+		ID      string                 `json:"id"`
+		Type    string                 `json:"type"`
+		Headers map[string]interface{} `json:"headers"`
+		Payload json.RawMessage        `json:"payload" binding:"required"`
+		Rules   []filter.Rule          `json:"rules" binding:"dive"`
+		Flags   map[string]interface{} `json:"flags"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision, err := h.filterService.DryRun(c.Request.Context(), c.Param("id"), request.Rules, filter.Input{
+		ID:      request.ID,
+		Type:    request.Type,
+		Headers: request.Headers,
+		Body:    request.Payload,
+		Flags:   request.Flags,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidFilter):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, filter.ErrLimitExceeded):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandoverHandler exposes consumer ownership across instances
+type HandoverHandler struct {
+	handoverService *service.HandoverService
+}
+
+// NewHandoverHandler creates a new HandoverHandler
+func NewHandoverHandler(handoverService *service.HandoverService) *HandoverHandler {
+	return &HandoverHandler{handoverService: handoverService}
+}
+
+// ListConsumers godoc
+// @Summary List tenant consumer ownership
+// @Description List which instance consumes each tenant and any handover in progress, to follow a blue/green deploy
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} object{instance_id=string,data=[]domain.ConsumerOwner}
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/consumers [get]
+func (h *HandoverHandler) ListConsumers(c *gin.Context) {
+	owners, err := h.handoverService.ListOwners()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"instance_id": h.handoverService.InstanceID(), "data": owners})
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"multi-tenant-messaging/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Health godoc
+// @Summary Liveness and readiness at a glance
+// @Description Run the liveness and readiness checks together. The status is ok, not_ready while the instance is alive but should not get traffic, or failed when it should be restarted. Answers 503 only when liveness fails, so it can serve as a liveness probe; use /readyz for readiness.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} object{status=string,live=health.Result,ready=health.Result}
+// @Failure 503 {object} object{status=string,live=health.Result,ready=health.Result}
+// @Router /healthz [get]
+func (h *HealthHandler) Health(c *gin.Context) {
+	live, _ := h.checker.Live(c.Request.Context(), "")
+	ready, _ := h.checker.Ready(c.Request.Context(), "")
+
+	status, code := "ok", http.StatusOK
+	switch {
+	case !live.Healthy():
+		status, code = "failed", http.StatusServiceUnavailable
+	case !ready.Healthy():
+		status = "not_ready"
+	}
+	c.JSON(code, gin.H{"status": status, "live": live, "ready": ready})
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Run the liveness checks, or only the one named by the optional check path segment. Fails only when the process should be restarted: a tenant consumer stopped taking messages longer than kubernetes.consumer_stall_timeout ago while its broker channel stayed open. A dropped broker or database connection does not fail it, as both are reconnected.
+// @Tags health
+// @Produce  json
+// @Param check path string false "Check name"
+// @Success 200 {object} health.Result
+// @Failure 404 {object} object "Unknown check"
+// @Failure 503 {object} health.Result
+// @Router /livez/{check} [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	result, found := h.checker.Live(c.Request.Context(), c.Param("check"))
+	respondHealth(c, result, found)
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Run the readiness checks, or only the one named by the optional check path segment: database (pings the pool, fails during a failover), rabbitmq or nats (connection open), consumers (fails when every tenant consumer here stopped taking messages), migrations, runtime_config and shutdown
+// @Tags health
+// @Produce  json
+// @Param check path string false "Check name"
+// @Success 200 {object} health.Result
+// @Failure 404 {object} object "Unknown check"
+// @Failure 503 {object} health.Result
+// @Router /readyz/{check} [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	result, found := h.checker.Ready(c.Request.Context(), c.Param("check"))
+	respondHealth(c, result, found)
+}
+
+// Details godoc
+// @Summary Detailed component health
+// @Description Report each component (database pool, every broker connection, tenant consumers, singleton scheduler, exports) as ok, degraded or down, with its latency, current error and the last error seen. Degraded components work with reduced capacity; the response is 503 only when a component is down.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Details
+// @Failure 503 {object} health.Details
+// @Router /healthz/details [get]
+func (h *HealthHandler) Details(c *gin.Context) {
+	details := h.checker.Details(c.Request.Context())
+	if details.Status == health.StatusDown {
+		c.JSON(http.StatusServiceUnavailable, details)
+		return
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+func respondHealth(c *gin.Context, result health.Result, found bool) {
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown check " + c.Param("check")})
+		return
+	}
+	if !result.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
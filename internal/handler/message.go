@@ -1,24 +1,44 @@
 package handler
 
 import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"multi-tenant-messaging/internal/domain"
 	"multi-tenant-messaging/internal/repository"
+	"multi-tenant-messaging/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+const (
+	// followPollInterval is how often a follow request checks for new rows
+	followPollInterval = 500 * time.Millisecond
+	// followMaxWait bounds how long a follow request is held open
+	followMaxWait = 60 * time.Second
+)
+
+// messageFields lists the message fields that can be requested via ?fields
+var messageFields = []string{"id", "tenant_id", "payload", "status", "message_type", "schema_version", "tags", "channel", "content_type", "raw_payload", "deliver_at", "created_at"}
+
 // MessageHandler handles message related requests
 type MessageHandler struct {
-	db *repository.Database
+	db         *repository.Database
+	allowlists *service.AllowlistService
 }
 
 // NewMessageHandler creates a new MessageHandler
-func NewMessageHandler(db *repository.Database) *MessageHandler {
-	return &MessageHandler{db: db}
+func NewMessageHandler(db *repository.Database, allowlists *service.AllowlistService) *MessageHandler {
+	return &MessageHandler{db: db, allowlists: allowlists}
 }
 
 // ListMessages godoc
@@ -27,84 +47,455 @@ func NewMessageHandler(db *repository.Database) *MessageHandler {
 // @Tags messages
 // @Accept  json
 // @Produce  json
-// @Param cursor query string false "Cursor for pagination"
-// @Param limit query int false "Limit of messages per page (default 10)"
+// @Param cursor query string false "Opaque next_cursor of the previous page"
+// @Param tenant_id query string false "Only messages of this tenant; a token bound to a tenant can only ask for its own"
+// @Param created_after query string false "Only messages created after this RFC 3339 time"
+// @Param created_before query string false "Only messages created before this RFC 3339 time"
+// @Param payload query string false "Only messages whose payload contains this JSON document, e.g. {\"status\":\"paid\"}"
+// @Param limit query int false "Limit of messages per page (default 10, max 1000)"
+// @Param fields query string false "Comma-separated fields to return (id,tenant_id,payload,status,message_type,schema_version,tags,channel,content_type,raw_payload,deliver_at,created_at)"
+// @Param exclude_payload query bool false "Omit the payload and raw_payload fields from every message"
+// @Param If-None-Match header string false "ETag of a previously fetched page"
+// @Param follow query bool false "Tail mode: wait for messages newer than the cursor (oldest first) instead of paging back; without a cursor, starts after the newest message"
+// @Param wait query string false "How long a follow request waits for new messages, e.g. 30s (default 30s, max 60s)"
 // @Success 200 {object} object{data=[]domain.Message,next_cursor=string}
-// @Failure 400 {object} object "Invalid cursor or limit"
+// @Success 304 "Page unchanged since the given ETag"
+// @Failure 400 {object} object "Invalid cursor, limit, fields or filter"
+// @Failure 403 {object} object "Token is bound to another tenant, or source IP not allowed for the tenant"
 // @Failure 500 {object} object "Internal server error"
+// @Failure 503 {object} object "Server busy, retry after the Retry-After delay"
 // @Router /messages [get]
 func (h *MessageHandler) ListMessages(c *gin.Context) {
 	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > service.MaxMessageLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid limit parameter, expected 1 to %d", service.MaxMessageLimit)})
+		return
+	}
+
+	fields, err := parseMessageFields(c.Query("fields"), c.Query("exclude_payload"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	cursor := c.Query("cursor")
+	// id dan created_at selalu diambil karena dibutuhkan untuk next_cursor dan ETag
+	columns := []string{"id", "created_at"}
+	for _, field := range fields {
+		if field != "id" && field != "created_at" {
+			columns = append(columns, field)
+		}
+	}
+	selectClause := "SELECT " + strings.Join(columns, ", ")
 
-	var query string
-	var args []interface{}
-	var orderClause = "ORDER BY created_at DESC, id DESC"
+	var cursor *service.MessageCursor
+	if value := c.Query("cursor"); value != "" {
+		// Cursor memuat created_at dan id, jadi tetap berlaku walau pesannya dihapus
+		parsed, err := service.ParseMessageCursor(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cursor = &parsed
+	}
 
-	if cursor == "" {
-		query = `
-			SELECT id, tenant_id, payload, created_at 
-			FROM messages 
-			` + orderClause + `
-			LIMIT $1
-		`
-		args = []interface{}{limit}
-	} else {
-		// Validasi cursor sebagai UUID
-		if _, err := uuid.Parse(cursor); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor format"})
+	filter, err := parseMessageFilter(c)
+	if err != nil {
+		if errors.Is(err, service.ErrForeignTenant) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Allowlist berlaku untuk tenant yang diikat token atau diminta lewat tenant_id
+	listedTenant := claimsTenantID(c)
+	if listedTenant == "" {
+		listedTenant = c.Query("tenant_id")
+	}
+	if !allowClientIP(c, h.allowlists, listedTenant) {
+		return
+	}
+
+	if follow, _ := strconv.ParseBool(c.Query("follow")); follow {
+		wait := 30 * time.Second
+		if value := c.Query("wait"); value != "" {
+			wait, err = time.ParseDuration(value)
+			if err != nil || wait < 0 || wait > followMaxWait {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wait parameter"})
+				return
+			}
+		}
+		h.followMessages(c, selectClause, columns, fields, filter, cursor, limit, wait)
+		return
+	}
 
-		query = `
-			SELECT id, tenant_id, payload, created_at 
-			FROM messages 
-			WHERE (created_at, id) < (
-				SELECT created_at, id FROM messages WHERE id = $1
-			)
-			` + orderClause + `
-			LIMIT $2
-		`
-		args = []interface{}{cursor, limit}
+	page := filter
+	if cursor != nil {
+		page = filter.Before(*cursor)
 	}
+	query, args := page.Query(selectClause, "created_at DESC, id DESC", limit)
+
+	messages := make([]domain.Message, 0)
 
-	rows, err := h.db.DB.Query(query, args...)
+	err = h.db.WithTenant(c.Request.Context(), claimsTenantID(c), func(q repository.Querier) error {
+		rows, err := q.QueryContext(c.Request.Context(), query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var msg domain.Message
+			if err := rows.Scan(messageScanDest(&msg, columns)...); err != nil {
+				return err
+			}
+			messages = append(messages, msg)
+		}
+		return rows.Err()
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer rows.Close()
 
-	messages := make([]domain.Message, 0)
-	var lastID string
+	nextCursor := ""
+	if len(messages) > 0 && len(messages) == limit {
+		nextCursor = service.CursorOf(messages[len(messages)-1]).String()
+	}
+
+	etag := messagePageETag(c.Request.URL.RawQuery, messages)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	var data interface{} = messages
+	if len(fields) < len(messageFields) {
+		data = projectMessages(messages, fields)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        data,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetMessage godoc
+// @Summary Get a message
+// @Description Get a stored message by ID. A token bound to a tenant only finds the messages of its own tenant, and the message is only returned to sources in its tenant's IP allowlist.
+// @Tags messages
+// @Produce  json
+// @Param id path string true "Message ID"
+// @Success 200 {object} domain.Message
+// @Failure 400 {object} object "Invalid message ID"
+// @Failure 403 {object} object "Source IP not allowed for the message's tenant"
+// @Failure 404 {object} object "Message not found"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 503 {object} object "Server busy, retry after the Retry-After delay"
+// @Router /messages/{id} [get]
+func (h *MessageHandler) GetMessage(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	var msg domain.Message
+	tenantID := claimsTenantID(c)
+	err := h.db.WithTenant(c.Request.Context(), tenantID, func(q repository.Querier) error {
+		filter := service.MessageFilter{}.With("id = %s", id)
+		if tenantID != "" {
+			filter = filter.With("tenant_id = %s", tenantID)
+		}
+		query, args := filter.Query("SELECT "+strings.Join(messageFields, ", "), "created_at DESC", 1)
+		return q.QueryRowContext(c.Request.Context(), query, args...).Scan(messageScanDest(&msg, messageFields)...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !allowClientIP(c, h.allowlists, msg.TenantID) {
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
 
-	for rows.Next() {
-		var msg domain.Message
-		if err := rows.Scan(&msg.ID, &msg.TenantID, &msg.Payload, &msg.CreatedAt); err != nil {
+// followMessages answers a follow request: it returns the messages created
+// after cursor, oldest first, as soon as there are any, or an empty page once
+// wait has passed. next_cursor always points at the last message seen so the
+// client can follow again without gaps.
+func (h *MessageHandler) followMessages(c *gin.Context, selectClause string, columns, fields []string, filter service.MessageFilter, cursor *service.MessageCursor, limit int, wait time.Duration) {
+	ctx := c.Request.Context()
+	tenantID := claimsTenantID(c)
+
+	if cursor == nil {
+		// Mulai setelah pesan terbaru supaya hanya pesan baru yang dikirim
+		err := h.db.WithTenant(ctx, tenantID, func(q repository.Querier) error {
+			var newest service.MessageCursor
+			query, args := filter.Query("SELECT created_at, id", "created_at DESC, id DESC", 1)
+			err := q.QueryRowContext(ctx, query, args...).Scan(&newest.CreatedAt, &newest.ID)
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			if err == nil {
+				cursor = &newest
+			}
+			return err
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		messages = append(messages, msg)
-		lastID = msg.ID
 	}
 
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	deadline := time.Now().Add(wait)
+	poll := time.NewTicker(followPollInterval)
+	defer poll.Stop()
+
+	messages := make([]domain.Message, 0)
+	for {
+		err := h.db.WithTenant(ctx, tenantID, func(q repository.Querier) error {
+			// Cursor tetap kosong hanya jika belum ada pesan sama sekali
+			page := filter
+			if cursor != nil {
+				page = filter.After(*cursor)
+			}
+			query, args := page.Query(selectClause, "created_at ASC, id ASC", limit)
+			rows, err := q.QueryContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var msg domain.Message
+				if err := rows.Scan(messageScanDest(&msg, columns)...); err != nil {
+					return err
+				}
+				messages = append(messages, msg)
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(messages) > 0 || !time.Now().Before(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
 	}
 
 	nextCursor := ""
-	if len(messages) > 0 && len(messages) == limit {
-		nextCursor = lastID
+	if len(messages) > 0 {
+		nextCursor = service.CursorOf(messages[len(messages)-1]).String()
+	} else if cursor != nil {
+		nextCursor = cursor.String()
+	}
+	var data interface{} = messages
+	if len(fields) < len(messageFields) {
+		data = projectMessages(messages, fields)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":        messages,
+		"data":        data,
 		"next_cursor": nextCursor,
 	})
 }
+
+// parseMessageFilter reads the tenant_id, created_after, created_before and
+// payload filters. Requests with a token bound to a tenant are always
+// restricted to that tenant.
+func parseMessageFilter(c *gin.Context) (service.MessageFilter, error) {
+	query := domain.MessageQuery{
+		TenantID: c.Query("tenant_id"),
+		Payload:  json.RawMessage(c.Query("payload")),
+	}
+	for _, bound := range []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"created_after", &query.CreatedAfter},
+		{"created_before", &query.CreatedBefore},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return service.MessageFilter{}, fmt.Errorf("invalid %s, expected an RFC 3339 time", bound.param)
+		}
+		*bound.dest = &t
+	}
+	return service.NewMessageFilter(claimsTenantID(c), query)
+}
+
+// parseMessageFields returns the requested message fields in canonical order
+func parseMessageFields(fieldsParam, excludePayloadParam string) ([]string, error) {
+	excludePayload := false
+	if excludePayloadParam != "" {
+		var err error
+		excludePayload, err = strconv.ParseBool(excludePayloadParam)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude_payload parameter")
+		}
+	}
+
+	requested := make(map[string]bool)
+	if fieldsParam == "" {
+		for _, field := range messageFields {
+			requested[field] = true
+		}
+	} else {
+		for _, field := range strings.Split(fieldsParam, ",") {
+			field = strings.TrimSpace(field)
+			if !isMessageField(field) {
+				return nil, fmt.Errorf("invalid field: %q", field)
+			}
+			requested[field] = true
+		}
+	}
+	if excludePayload {
+		delete(requested, "payload")
+		delete(requested, "raw_payload")
+	}
+
+	fields := make([]string, 0, len(requested))
+	for _, field := range messageFields {
+		if requested[field] {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields selected")
+	}
+	return fields, nil
+}
+
+func isMessageField(field string) bool {
+	for _, f := range messageFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// messageScanDest returns scan destinations on msg matching columns
+func messageScanDest(msg *domain.Message, columns []string) []interface{} {
+	dest := make([]interface{}, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "id":
+			dest[i] = &msg.ID
+		case "tenant_id":
+			dest[i] = &msg.TenantID
+		case "payload":
+			dest[i] = &msg.Payload
+		case "status":
+			dest[i] = &msg.Status
+		case "message_type":
+			dest[i] = &msg.MessageType
+		case "schema_version":
+			dest[i] = &msg.SchemaVersion
+		case "tags":
+			dest[i] = &msg.Tags
+		case "channel":
+			dest[i] = &msg.Channel
+		case "content_type":
+			dest[i] = &msg.ContentType
+		case "raw_payload":
+			dest[i] = &msg.RawPayload
+		case "deliver_at":
+			dest[i] = &msg.DeliverAt
+		case "created_at":
+			dest[i] = &msg.CreatedAt
+		}
+	}
+	return dest
+}
+
+// projectMessages keeps only the requested fields of every message
+func projectMessages(messages []domain.Message, fields []string) []gin.H {
+	projected := make([]gin.H, 0, len(messages))
+	for _, msg := range messages {
+		item := gin.H{}
+		for _, field := range fields {
+			switch field {
+			case "id":
+				item["id"] = msg.ID
+			case "tenant_id":
+				item["tenant_id"] = msg.TenantID
+			case "payload":
+				item["payload"] = msg.Payload
+			case "status":
+				item["status"] = msg.Status
+			case "message_type":
+				item["message_type"] = msg.MessageType
+			case "schema_version":
+				item["schema_version"] = msg.SchemaVersion
+			case "tags":
+				item["tags"] = msg.Tags
+			case "channel":
+				item["channel"] = msg.Channel
+			case "content_type":
+				item["content_type"] = msg.ContentType
+			case "raw_payload":
+				item["raw_payload"] = msg.RawPayload
+			case "deliver_at":
+				item["deliver_at"] = msg.DeliverAt
+			case "created_at":
+				item["created_at"] = msg.CreatedAt
+			}
+		}
+		projected = append(projected, item)
+	}
+	return projected
+}
+
+// messagePageETag computes a weak ETag for a page of messages from the
+// newest created_at on the page, the row count and the query string
+func messagePageETag(rawQuery string, messages []domain.Message) string {
+	var maxCreatedAt time.Time
+	for _, msg := range messages {
+		if msg.CreatedAt.After(maxCreatedAt) {
+			maxCreatedAt = msg.CreatedAt
+		}
+	}
+
+	var lastID string
+	if len(messages) > 0 {
+		lastID = messages[len(messages)-1].ID
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d|%d|%s", rawQuery, maxCreatedAt.UnixNano(), len(messages), lastID)))
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/service"
+	"multi-tenant-messaging/pkg/processor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProcessorHandler handles message processor requests
+type ProcessorHandler struct {
+	processorService *service.ProcessorService
+}
+
+// NewProcessorHandler creates a new ProcessorHandler
+func NewProcessorHandler(processorService *service.ProcessorService) *ProcessorHandler {
+	return &ProcessorHandler{processorService: processorService}
+}
+
+// ListProcessors godoc
+// @Summary List available processors
+// @Description List the processors registered in-process or configured as gRPC sidecars, which tenants can select
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} object{data=[]string}
+// @Router /admin/processors [get]
+func (h *ProcessorHandler) ListProcessors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": processor.Names()})
+}
+
+// GetProcessors godoc
+// @Summary Get a tenant's processors
+// @Description Get the processors run on each of the tenant's messages, in order
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{processors=[]processor.Stage}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/processors [get]
+func (h *ProcessorHandler) GetProcessors(c *gin.Context) {
+	stages, err := h.processorService.GetProcessors(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"processors": stages})
+}
+
+// UpdateProcessors godoc
+// @Summary Update a tenant's processors
+// @Description Replace the chain of processors run on each of the tenant's messages after schema validation and before storage. Each stage is a processor name, or {"name": ..., "config": {...}} for a processor that takes settings, such as the built-in strip_fields, ingest_metadata and flatten. An empty list disables processing.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{processors=[]processor.Stage} true "Processor stages, in run order"
+// @Success 200 {object} object{processors=[]processor.Stage}
+// @Failure 400 {object} object "Invalid request body, unknown processor or invalid processor config"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/processors [put]
+func (h *ProcessorHandler) UpdateProcessors(c *gin.Context) {
+	var config struct {
+		Processors []processor.Stage `json:"processors" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.processorService.SetProcessors(c.Param("id"), config.Processors); err != nil {
+		if errors.Is(err, service.ErrUnknownProcessor) || errors.Is(err, service.ErrInvalidProcessorConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"processors": config.Processors})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishMessage godoc
+// @Summary Publish a message
+// @Description Publish a JSON payload to the tenant's main queue, or to one of its channels, so producers need no AMQP access. The message is consumed like any other and the generated message ID is returned. delay_seconds (up to 7 days), or deliver_at for a time up to 7 days ahead, holds the message in a scheduled queue until it is due. priority (0-9) lets the message overtake lower priorities in the tenant's worker pool, and at the broker if the tenant has a max priority. The tenant's messages partition must exist. A payload that does not match the schema of its message type, or the tenant's schema, is rejected with 422. Publishing faster than the tenant's rate limit is rejected with 429. A tenant at its quota of stored messages or bytes is rejected with 413, and at its queue depth quota with 429.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param message body domain.PublishRequest true "Payload, message type, channel and delay"
+// @Success 202 {object} domain.PublishResult
+// @Failure 400 {object} object "Invalid request body, payload or delay"
+// @Failure 404 {object} object "Tenant or channel not found"
+// @Failure 409 {object} object "Tenant has no messages partition"
+// @Failure 413 {object} object "Tenant stores as many messages or bytes as its quota allows, usage is measured again after the Retry-After delay"
+// @Failure 422 {object} object "Payload does not match its schema"
+// @Failure 429 {object} object "Tenant rate limit exceeded or queue depth quota reached, retry after the Retry-After delay"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/messages [post]
+func (h *TenantHandler) PublishMessage(c *gin.Context) {
+	var request domain.PublishRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.tenantService.PublishMessage(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		var limited *service.RateLimitError
+		var overQuota *service.QuotaError
+		switch {
+		case errors.Is(err, service.ErrInvalidPublish):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTenantNotFound), errors.Is(err, service.ErrChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPartitionNotFound):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSchemaValidation):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.As(err, &limited):
+			c.Header("Retry-After", strconv.Itoa(limited.RetryAfterSeconds()))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":               err.Error(),
+				"code":                "rate_limited",
+				"retry_after_seconds": limited.RetryAfterSeconds(),
+			})
+		case errors.As(err, &overQuota):
+			status := http.StatusTooManyRequests
+			if overQuota.Storage() {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.Header("Retry-After", strconv.Itoa(overQuota.RetryAfterSeconds()))
+			c.JSON(status, gin.H{
+				"error":               err.Error(),
+				"code":                "quota_exceeded",
+				"quota":               overQuota.Quota,
+				"usage":               overQuota.Usage,
+				"limit":               overQuota.Limit,
+				"retry_after_seconds": overQuota.RetryAfterSeconds(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, result)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuarantineHandler handles requests for a tenant's quarantined messages
+type QuarantineHandler struct {
+	quarantineService *service.QuarantineService
+}
+
+// NewQuarantineHandler creates a new QuarantineHandler
+func NewQuarantineHandler(quarantineService *service.QuarantineService) *QuarantineHandler {
+	return &QuarantineHandler{quarantineService: quarantineService}
+}
+
+// ListQuarantine godoc
+// @Summary List quarantined messages
+// @Description List the tenant's most recently quarantined poison messages: messages whose processing panicked or whose payload could not be decoded or validated too many times. Each carries its last error, original headers and payload, and its first and last failure times.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param limit query int false "Maximum number of messages (default 100)"
+// @Success 200 {object} object{data=[]domain.QuarantinedMessage}
+// @Failure 400 {object} object "Invalid limit"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/quarantine [get]
+func (h *QuarantineHandler) ListQuarantine(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	messages, err := h.quarantineService.List(c.Param("id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": messages})
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaHandler handles the per-tenant quotas operators set
+type QuotaHandler struct {
+	quotaService *service.QuotaService
+	auditLogger  *audit.Logger
+}
+
+// NewQuotaHandler creates a new QuotaHandler
+func NewQuotaHandler(quotaService *service.QuotaService, auditLogger *audit.Logger) *QuotaHandler {
+	return &QuotaHandler{quotaService: quotaService, auditLogger: auditLogger}
+}
+
+// GetQuota godoc
+// @Summary Get a tenant's quota
+// @Description Get the most messages and bytes the tenant may keep stored and the deepest its main queue may get before publishing is rejected. Without a quota of its own (default) the quotas config applies. 0 leaves a limit off.
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.TenantQuota
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/quota [get]
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	quota, err := h.quotaService.GetQuota(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
+
+// UpdateQuota godoc
+// @Summary Set a tenant's quota
+// @Description Set the tenant's own quota, replacing the quotas config for it. 0 leaves a limit off. Other instances apply it within 30 seconds.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param quota body domain.TenantQuota true "Quota"
+// @Success 200 {object} domain.TenantQuota
+// @Failure 400 {object} object "Invalid request body or quota"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/quota [put]
+func (h *QuotaHandler) UpdateQuota(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var quota domain.TenantQuota
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, _ := h.quotaService.GetQuota(tenantID)
+	quota, err := h.quotaService.SetQuota(tenantID, quota)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidQuota) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionQuotaUpdate, tenantID, map[string]interface{}{
+		"max_messages":    quota.MaxMessages,
+		"max_bytes":       quota.MaxBytes,
+		"max_queue_depth": quota.MaxQueueDepth,
+	}, quotaValues(before), quotaValues(quota))
+
+	c.JSON(http.StatusOK, quota)
+}
+
+// ResetQuota godoc
+// @Summary Reset a tenant's quota
+// @Description Drop the tenant's own quota so the quotas config applies to it again.
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.TenantQuota
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenants/{id}/quota [delete]
+func (h *QuotaHandler) ResetQuota(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	before, _ := h.quotaService.GetQuota(tenantID)
+	quota, err := h.quotaService.ResetQuota(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionQuotaUpdate, tenantID, map[string]interface{}{
+		"max_messages":    quota.MaxMessages,
+		"max_bytes":       quota.MaxBytes,
+		"max_queue_depth": quota.MaxQueueDepth,
+		"default":         true,
+	}, quotaValues(before), quotaValues(quota))
+
+	c.JSON(http.StatusOK, quota)
+}
+
+// quotaValues is the quota as recorded in the audit log
+func quotaValues(quota domain.TenantQuota) map[string]interface{} {
+	return map[string]interface{}{
+		"max_messages":    quota.MaxMessages,
+		"max_bytes":       quota.MaxBytes,
+		"max_queue_depth": quota.MaxQueueDepth,
+		"default":         quota.Default,
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitHandler handles tenant rate limit config requests
+type RateLimitHandler struct {
+	rateLimitService *service.RateLimitService
+}
+
+// NewRateLimitHandler creates a new RateLimitHandler
+func NewRateLimitHandler(rateLimitService *service.RateLimitService) *RateLimitHandler {
+	return &RateLimitHandler{rateLimitService: rateLimitService}
+}
+
+// GetRateLimit godoc
+// @Summary Get a tenant's rate limit
+// @Description Get the token-bucket limit on the tenant's published messages (0 messages per second means unlimited) and whether its consumers are paced at the same rate.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.RateLimit
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/rate-limit [get]
+func (h *RateLimitHandler) GetRateLimit(c *gin.Context) {
+	config, err := h.rateLimitService.GetConfig(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// UpdateRateLimit godoc
+// @Summary Update a tenant's rate limit
+// @Description Publishing more than burst messages at once, or faster than messages_per_second, is rejected with 429 and a Retry-After header. burst defaults to one second worth of messages and 0 messages per second disables the limit. With consume the tenant's consumers take messages no faster than the rate either; the rest wait in the queue. Limits are enforced per instance.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body domain.RateLimit true "Rate limit"
+// @Success 200 {object} domain.RateLimit
+// @Failure 400 {object} object "Invalid request body, rate or burst"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/rate-limit [put]
+func (h *RateLimitHandler) UpdateRateLimit(c *gin.Context) {
+	var req struct {
+		MessagesPerSecond *float64 `json:"messages_per_second" binding:"required,min=0"`
+		Burst             int      `json:"burst" binding:"min=0"`
+		Consume           bool     `json:"consume"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config, err := h.rateLimitService.SetConfig(c.Param("id"), domain.RateLimit{MessagesPerSecond: *req.MessagesPerSecond, Burst: req.Burst, Consume: req.Consume})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRateLimit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartRecovery godoc
+// @Summary Start a backlog recovery
+// @Description Park the backlog of the tenant's main queue in a recovery queue and drain it with extra workers. oldest_first (default) pauses the main queue until the backlog is cleared; newest_first keeps processing new messages and works through the backlog newest first. Parking runs in the background; poll GET /tenants/{id}/recovery for progress.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param recovery body object{workers=int,order=string} true "Recovery workers (1-100) and order (oldest_first or newest_first)"
+// @Success 202 {object} domain.Recovery
+// @Failure 400 {object} object "Invalid request body, workers or order"
+// @Failure 404 {object} object "Tenant not consumed by this instance"
+// @Failure 409 {object} object "Recovery already running, no backlog or tenant in ordered mode"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /tenants/{id}/recovery [post]
+func (h *TenantHandler) StartRecovery(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request struct {
+		Workers int    `json:"workers" binding:"required"`
+		Order   string `json:"order"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recovery, err := h.tenantService.StartRecovery(tenantID, request.Order, request.Workers)
+	if err != nil {
+		respondRecoveryError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionRecoveryStart, tenantID, map[string]interface{}{
+		"recovery_id": recovery.ID,
+		"workers":     recovery.Workers,
+		"order":       recovery.Order,
+		"backlog":     recovery.Backlog,
+	})
+
+	c.JSON(http.StatusAccepted, recovery)
+}
+
+// GetRecovery godoc
+// @Summary Get backlog recovery progress
+// @Description Get the tenant's latest recovery with the processed share of the backlog, the processing rate and an estimate of the time left
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.Recovery
+// @Failure 404 {object} object "No recovery"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/recovery [get]
+func (h *TenantHandler) GetRecovery(c *gin.Context) {
+	recovery, err := h.tenantService.GetRecovery(c.Param("id"))
+	if err != nil {
+		respondRecoveryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recovery)
+}
+
+// CancelRecovery godoc
+// @Summary Cancel a backlog recovery
+// @Description Stop the tenant's unfinished recovery. Backlog messages not processed yet go back to the end of the main queue.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.Recovery
+// @Failure 404 {object} object "No unfinished recovery"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/recovery [delete]
+func (h *TenantHandler) CancelRecovery(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	recovery, err := h.tenantService.CancelRecovery(tenantID)
+	if err != nil {
+		respondRecoveryError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionRecoveryCancel, tenantID, map[string]interface{}{
+		"recovery_id": recovery.ID,
+		"processed":   recovery.Processed,
+	})
+
+	c.JSON(http.StatusOK, recovery)
+}
+
+func respondRecoveryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRecovery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRecoveryNotFound), errors.Is(err, service.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRecoveryRunning), errors.Is(err, service.ErrNoBacklog), errors.Is(err, service.ErrOrderedTenant):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedTransport):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+
+	"multi-tenant-messaging/internal/redact"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedactionHandler handles tenant redaction rule requests
+type RedactionHandler struct {
+	redactionService *service.RedactionService
+}
+
+// NewRedactionHandler creates a new RedactionHandler
+func NewRedactionHandler(redactionService *service.RedactionService) *RedactionHandler {
+	return &RedactionHandler{redactionService: redactionService}
+}
+
+// GetRules godoc
+// @Summary Get a tenant's redaction rules
+// @Description Get the field paths that are masked or hashed before the tenant's messages are stored
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{rules=[]redact.Rule}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/redaction-rules [get]
+func (h *RedactionHandler) GetRules(c *gin.Context) {
+	rules, err := h.redactionService.GetRules(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// SetRules godoc
+// @Summary Replace a tenant's redaction rules
+// @Description Replace the field paths that are masked or hashed before the tenant's messages are stored. "*" matches any key or array element.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body object{rules=[]redact.Rule} true "Redaction rules"
+// @Success 200 {object} object{rules=[]redact.Rule}
+// @Failure 400 {object} object "Invalid request body or rule"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/redaction-rules [put]
+func (h *RedactionHandler) SetRules(c *gin.Context) {
+	var request struct {
+		Rules []redact.Rule `json:"rules" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, rule := range request.Rules {
+		if err := rule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	rules, err := h.redactionService.SetRules(c.Param("id"), request.Rules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// DryRun godoc
+// @Summary Preview redaction of a payload
+// @Description Show what would be redacted from a sample payload, using the given rules or the tenant's stored rules. Nothing is stored.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body object{payload=object,rules=[]redact.Rule} true "Sample payload and optional rules"
+// @Success 200 {object} object{redacted=object,matched=[]string}
+// @Failure 400 {object} object "Invalid request body or rule"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/redaction-rules/dry-run [post]
+func (h *RedactionHandler) DryRun(c *gin.Context) {
+	var request struct {
+		Payload map[string]interface{} `json:"payload" binding:"required"`
+		Rules   []redact.Rule          `json:"rules" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rules := request.Rules
+	if rules == nil {
+		var err error
+		rules, err = h.redactionService.GetRules(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	matched := h.redactionService.Preview(c.Param("id"), request.Payload, rules)
+	c.JSON(http.StatusOK, gin.H{
+		"redacted": request.Payload,
+		"matched":  matched,
+	})
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionHandler handles tenant retention policies and manual purges
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+	auditLogger      *audit.Logger
+}
+
+// NewRetentionHandler creates a new RetentionHandler
+func NewRetentionHandler(retentionService *service.RetentionService, auditLogger *audit.Logger) *RetentionHandler {
+	return &RetentionHandler{retentionService: retentionService, auditLogger: auditLogger}
+}
+
+// GetRetention godoc
+// @Summary Get a tenant's retention policy
+// @Description Get how long the tenant's stored messages are kept (max_age_seconds) and how many of them (max_rows), 0 meaning forever, and whether expired messages are archived instead of deleted.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.RetentionPolicy
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/retention [get]
+func (h *RetentionHandler) GetRetention(c *gin.Context) {
+	policy, err := h.retentionService.GetPolicy(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateRetention godoc
+// @Summary Update a tenant's retention policy
+// @Description The retention janitor expires the tenant's messages stored longer than max_age_seconds ago and all but its max_rows newest ones; 0 disables either limit. Expired messages are deleted, or with archive moved to the tenant's messages_archive_<tenant> table.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param policy body domain.RetentionPolicy true "Retention policy"
+// @Success 200 {object} domain.RetentionPolicy
+// @Failure 400 {object} object "Invalid request body or policy"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/retention [put]
+func (h *RetentionHandler) UpdateRetention(c *gin.Context) {
+	var policy domain.RetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.retentionService.SetPolicy(c.Param("id"), policy)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// PurgeMessages godoc
+// @Summary Purge a tenant's stored messages
+// @Description Delete the tenant's messages stored before `before` and all but its `keep_latest` newest ones, or with neither apply its retention policy now. With archive, which defaults to the policy's, the messages are moved to the tenant's archive table instead. Queued messages are not affected.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param request body domain.PurgeRequest false "Messages to purge"
+// @Success 200 {object} domain.PurgeResult
+// @Failure 400 {object} object "Invalid request body, or nothing to purge"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Tenant or partition not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/messages/purge [post]
+func (h *RetentionHandler) PurgeMessages(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var req domain.PurgeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.retentionService.Purge(c.Request.Context(), tenantID, req)
+	if err != nil {
+		respondRetentionError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionMessagePurge, tenantID, map[string]interface{}{
+		"before":      req.Before,
+		"keep_latest": req.KeepLatest,
+		"purged":      result.Purged,
+		"archived":    result.Archived,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+func respondRetentionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRetention), errors.Is(err, service.ErrInvalidPurge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTenantNotFound), errors.Is(err, service.ErrPartitionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
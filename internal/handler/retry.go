@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetryHandler handles tenant retry policy requests
+type RetryHandler struct {
+	retryService *service.RetryService
+}
+
+// NewRetryHandler creates a new RetryHandler
+func NewRetryHandler(retryService *service.RetryService) *RetryHandler {
+	return &RetryHandler{retryService: retryService}
+}
+
+// GetPolicy godoc
+// @Summary Get a tenant's retry policy
+// @Description Get how many times a message that failed for a reason that may pass is delivered and how long it waits between attempts
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.RetryPolicy
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/retry [get]
+func (h *RetryHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.retryService.GetPolicy(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update a tenant's retry policy
+// @Description Override the config default. The n-th retry waits backoff_seconds * 2^(n-1), capped at max_backoff_seconds and spread by up to jitter of it; after max_attempts deliveries the message is dead-lettered.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{max_attempts=int,backoff_seconds=int,max_backoff_seconds=int,jitter=number} true "Retry policy"
+// @Success 200 {object} domain.RetryPolicy
+// @Failure 400 {object} object "Invalid request body or policy"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/retry [put]
+func (h *RetryHandler) UpdatePolicy(c *gin.Context) {
+	var config struct {
+		MaxAttempts       int     `json:"max_attempts" binding:"required"`
+		BackoffSeconds    int     `json:"backoff_seconds" binding:"required"`
+		MaxBackoffSeconds int     `json:"max_backoff_seconds" binding:"required"`
+		Jitter            float64 `json:"jitter"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.retryService.SetPolicy(c.Param("id"), domain.RetryPolicy{
+		MaxAttempts:       config.MaxAttempts,
+		BackoffSeconds:    config.BackoffSeconds,
+		MaxBackoffSeconds: config.MaxBackoffSeconds,
+		Jitter:            config.Jitter,
+	})
+	if errors.Is(err, service.ErrInvalidRetryPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"multi-tenant-messaging/internal/dynconfig"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeConfigHandler handles tenant runtime config and feature flag requests
+type RuntimeConfigHandler struct {
+	store *dynconfig.Store
+}
+
+// NewRuntimeConfigHandler creates a new RuntimeConfigHandler
+func NewRuntimeConfigHandler(store *dynconfig.Store) *RuntimeConfigHandler {
+	return &RuntimeConfigHandler{store: store}
+}
+
+// GetRuntimeConfig godoc
+// @Summary Get a tenant's runtime config
+// @Description Get the tenant's runtime config values and feature flags by key, as seen by this instance
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /tenants/{id}/runtime-config [get]
+func (h *RuntimeConfigHandler) GetRuntimeConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.store.Values(c.Param("id")))
+}
+
+// SetRuntimeConfig godoc
+// @Summary Set a tenant runtime config value
+// @Description Store a JSON value (at most 4 KiB) under the key. Every instance picks the change up through the configured backend's watch; CEL filters see it in the flags map.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param key path string true "Key (1-128 letters, digits, '_', '.' or '-')"
+// @Param value body object true "JSON value"
+// @Success 200 {object} dynconfig.Entry
+// @Failure 400 {object} object "Invalid key or value"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 503 {object} object "Runtime config is disabled"
+// @Router /tenants/{id}/runtime-config/{key} [put]
+func (h *RuntimeConfigHandler) SetRuntimeConfig(c *gin.Context) {
+	value, err := io.ReadAll(io.LimitReader(c.Request.Body, dynconfig.MaxValueSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, key := c.Param("id"), c.Param("key")
+	if err := h.store.Set(c.Request.Context(), tenantID, key, value); err != nil {
+		respondRuntimeConfigError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dynconfig.Entry{TenantID: tenantID, Key: key, Value: json.RawMessage(value)})
+}
+
+// DeleteRuntimeConfig godoc
+// @Summary Delete a tenant runtime config value
+// @Description Remove the key from the tenant's runtime config on every instance
+// @Tags tenants
+// @Param id path string true "Tenant ID"
+// @Param key path string true "Key"
+// @Success 204 "No Content"
+// @Failure 404 {object} object "Key not found"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 503 {object} object "Runtime config is disabled"
+// @Router /tenants/{id}/runtime-config/{key} [delete]
+func (h *RuntimeConfigHandler) DeleteRuntimeConfig(c *gin.Context) {
+	if err := h.store.Delete(c.Request.Context(), c.Param("id"), c.Param("key")); err != nil {
+		respondRuntimeConfigError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondRuntimeConfigError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dynconfig.ErrInvalidKey), errors.Is(err, dynconfig.ErrInvalidValue):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, dynconfig.ErrKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, dynconfig.ErrDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
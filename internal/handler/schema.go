@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SchemaHandler handles payload schema registry requests
+type SchemaHandler struct {
+	schemaService *service.SchemaService
+}
+
+// NewSchemaHandler creates a new SchemaHandler
+func NewSchemaHandler(schemaService *service.SchemaService) *SchemaHandler {
+	return &SchemaHandler{schemaService: schemaService}
+}
+
+// ListSchemas godoc
+// @Summary List a tenant's payload schemas
+// @Description List every registered schema version per message type, without the schema bodies
+// @Tags schemas
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.PayloadSchema}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/schemas [get]
+func (h *SchemaHandler) ListSchemas(c *gin.Context) {
+	schemas, err := h.schemaService.ListSchemas(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": schemas})
+}
+
+// RegisterSchema godoc
+// @Summary Register a payload schema version
+// @Description Register a JSON Schema as the next version for a message type. Messages whose AMQP type property matches are validated against the latest version; invalid ones go to the tenant's DLQ.
+// @Tags schemas
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param type path string true "Message type"
+// @Param schema body object true "JSON Schema"
+// @Success 201 {object} domain.PayloadSchema
+// @Failure 400 {object} object "Invalid JSON Schema"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/schemas/{type} [post]
+func (h *SchemaHandler) RegisterSchema(c *gin.Context) {
+	var schema json.RawMessage
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	registered, err := h.schemaService.RegisterSchema(c.Param("id"), c.Param("type"), schema)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSchema) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, registered)
+}
+
+// GetTenantSchema godoc
+// @Summary Get a tenant's payload schema
+// @Description Get the latest version of the tenant-wide JSON Schema, which validates messages whose type has no schema of its own
+// @Tags schemas
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.PayloadSchema
+// @Failure 404 {object} object "Tenant has no schema"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/schema [get]
+func (h *SchemaHandler) GetTenantSchema(c *gin.Context) {
+	schema, err := h.schemaService.GetTenantSchema(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrSchemaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
+
+// SetTenantSchema godoc
+// @Summary Set a tenant's payload schema
+// @Description Register a JSON Schema as the next version of the tenant-wide schema. Messages whose type has no schema of its own, including untyped ones, are validated against it: consumed messages that fail go to the tenant's DLQ with reason schema_validation and published ones are rejected with 422. Set {} to accept every payload again.
+// @Tags schemas
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param schema body object true "JSON Schema"
+// @Success 200 {object} domain.PayloadSchema
+// @Failure 400 {object} object "Invalid JSON Schema"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/schema [put]
+func (h *SchemaHandler) SetTenantSchema(c *gin.Context) {
+	var schema json.RawMessage
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	registered, err := h.schemaService.SetTenantSchema(c.Param("id"), schema)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSchema) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, registered)
+}
+
+// GetSchema godoc
+// @Summary Get a payload schema version
+// @Description Get one version of a message type's JSON Schema
+// @Tags schemas
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param type path string true "Message type"
+// @Param version path int true "Schema version"
+// @Success 200 {object} domain.PayloadSchema
+// @Failure 400 {object} object "Invalid version"
+// @Failure 404 {object} object "Schema not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/schemas/{type}/versions/{version} [get]
+func (h *SchemaHandler) GetSchema(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	schema, err := h.schemaService.GetSchema(c.Param("id"), c.Param("type"), version)
+	if err != nil {
+		if errors.Is(err, service.ErrSchemaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
+
+// GetSchemaUsage godoc
+// @Summary Get schema version usage over time
+// @Description Count the tenant's stored messages of a type per period and schema version; a null version means the message was not validated
+// @Tags schemas
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param type path string true "Message type"
+// @Param bucket query string false "Period: day (default), week or month"
+// @Param since query string false "Only messages since this RFC3339 time (default 30 days ago)"
+// @Success 200 {object} object{data=[]domain.SchemaUsage}
+// @Failure 400 {object} object "Invalid bucket or since"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/schemas/{type}/usage [get]
+func (h *SchemaHandler) GetSchemaUsage(c *gin.Context) {
+	bucket := c.DefaultQuery("bucket", "day")
+	if bucket != "day" && bucket != "week" && bucket != "month" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bucket, expected day, week or month"})
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -30)
+	if value := c.Query("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since parameter"})
+			return
+		}
+	}
+
+	usage, err := h.schemaService.Usage(c.Param("id"), c.Param("type"), bucket, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": usage})
+}
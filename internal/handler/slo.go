@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SLOHandler handles per-tenant SLO requests
+type SLOHandler struct {
+	sloService *service.SLOService
+}
+
+// NewSLOHandler creates a new SLOHandler
+func NewSLOHandler(sloService *service.SLOService) *SLOHandler {
+	return &SLOHandler{sloService: sloService}
+}
+
+// GetSLO godoc
+// @Summary Get a tenant's SLO compliance
+// @Description Get the tenant's objective, compliance over the SLO window and error budget burn rates, as tracked by the instance consuming the tenant
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.SLOStatus
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/slo [get]
+func (h *SLOHandler) GetSLO(c *gin.Context) {
+	status, err := h.sloService.Status(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// UpdateSLO godoc
+// @Summary Update a tenant's SLO
+// @Description Override the config default: target share of messages that must be persisted within threshold_ms of being published
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{target=number,threshold_ms=int} true "SLO objective"
+// @Success 200 {object} domain.SLOObjective
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/slo [put]
+func (h *SLOHandler) UpdateSLO(c *gin.Context) {
+	var config struct {
+		Target      float64 `json:"target" binding:"required,gt=0,lt=1"`
+		ThresholdMs int64   `json:"threshold_ms" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	objective, err := h.sloService.SetObjective(c.Param("id"), config.Target, time.Duration(config.ThresholdMs)*time.Millisecond)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, objective)
+}
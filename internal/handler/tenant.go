@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"multi-tenant-messaging/internal/audit"
 	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/migrate"
 	"multi-tenant-messaging/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -14,23 +18,28 @@ import (
 // TenantHandler handles tenant related requests
 type TenantHandler struct {
 	tenantService *service.TenantService
+	auditLogger   *audit.Logger
 }
 
 // NewTenantHandler creates a new TenantHandler
-func NewTenantHandler(tenantService *service.TenantService) *TenantHandler {
-	return &TenantHandler{tenantService: tenantService}
+func NewTenantHandler(tenantService *service.TenantService, auditLogger *audit.Logger) *TenantHandler {
+	return &TenantHandler{
+		tenantService: tenantService,
+		auditLogger:   auditLogger,
+	}
 }
 
 // CreateTenant godoc
 // @Summary Create a new tenant
-// @Description Create a new tenant with a unique ID and start a consumer for the tenant
+// @Description Register a new tenant with a unique ID. Its partition, queues and consumer are created in the background; poll GET /tenants/provisioning/{id} with the returned job ID until the status is ready or failed.
 // @Tags tenants
 // @Accept  json
 // @Produce  json
 // @Param request body object{name=string} true "Tenant creation request"
-// @Success 201 {object} domain.Tenant
+// @Success 202 {object} domain.ProvisioningJob
 // @Failure 400 {object} object "Invalid request body"
 // @Failure 500 {object} object "Internal server error"
+// @Failure 503 {object} object "Database schema is behind or dirty"
 // @Router /tenants [post]
 func (h *TenantHandler) CreateTenant(c *gin.Context) {
 	var request struct {
@@ -48,12 +57,122 @@ func (h *TenantHandler) CreateTenant(c *gin.Context) {
 		CreatedAt: time.Now().Format(time.RFC3339),
 	}
 
-	if err := h.tenantService.CreateTenant(&tenant); err != nil {
+	job, err := h.tenantService.CreateTenant(&tenant)
+	if err != nil {
+		if errors.Is(err, migrate.ErrSchemaBehind) || errors.Is(err, migrate.ErrSchemaDirty) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionTenantCreate, tenant.ID, map[string]interface{}{
+		"name":   tenant.Name,
+		"job_id": job.ID,
+	}, nil, map[string]interface{}{"name": tenant.Name})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListTenants godoc
+// @Summary List tenants with cursor pagination
+// @Description List tenants ordered by name with their worker count, the depth of their main queue and whether any instance consumes it (active) or not (idle); status is unknown when the broker cannot be reached. A token bound to a tenant only sees that tenant.
+// @Tags tenants
+// @Produce  json
+// @Param name query string false "Only tenants whose name contains this text (case-insensitive)"
+// @Param cursor query string false "Tenant ID of the last tenant of the previous page"
+// @Param limit query int false "Tenants per page (default 20, max 100)"
+// @Success 200 {object} object{data=[]domain.TenantSummary,next_cursor=string}
+// @Failure 400 {object} object "Invalid cursor or limit"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants [get]
+func (h *TenantHandler) ListTenants(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	tenants, next, err := h.tenantService.ListTenants(c.Request.Context(), c.Query("name"), c.Query("cursor"), claimsTenantID(c), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTenantList) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, tenant)
+	c.JSON(http.StatusOK, gin.H{
+		"data":        tenants,
+		"next_cursor": next,
+	})
+}
+
+// GetProvisioningJob godoc
+// @Summary Get tenant provisioning status
+// @Description Get a tenant provisioning job: pending while the partition, queues and consumer are created, then ready, or failed with the error
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Provisioning job ID"
+// @Success 200 {object} domain.ProvisioningJob
+// @Failure 404 {object} object "Provisioning job not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/provisioning/{id} [get]
+func (h *TenantHandler) GetProvisioningJob(c *gin.Context) {
+	job, err := h.tenantService.GetProvisioningJob(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrProvisioningNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetTenant godoc
+// @Summary Get a tenant
+// @Description Get a tenant as listed by GET /tenants, with its quota and what it uses of it: the rows and bytes of its messages partition and the depth of its main queue (null when the broker cannot be asked).
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.TenantDetail
+// @Failure 400 {object} object "Invalid tenant ID"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id} [get]
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	tenant, err := h.tenantService.GetTenant(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// GetStats godoc
+// @Summary Get a tenant's consumption stats
+// @Description Get the depth of the tenant's main queue and DLQ and its consumers from the broker (null when it cannot be asked or the transport is not RabbitMQ), its workers on the instance answering and how many are processing a message, and what that instance consumed of it in the last minute, 5 minutes and hour with the mean processing latency over the hour and the time of the last message.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.TenantStats
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/stats [get]
+func (h *TenantHandler) GetStats(c *gin.Context) {
+	stats, err := h.tenantService.GetStats(c.Param("id"))
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
 }
 
 // DeleteTenant godoc
@@ -68,41 +187,306 @@ func (h *TenantHandler) CreateTenant(c *gin.Context) {
 // @Router /tenants/{id} [delete]
 func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 	tenantID := c.Param("id")
+	before := h.tenantService.AuditValues(tenantID)
 	if err := h.tenantService.DeleteTenant(tenantID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionTenantDelete, tenantID, nil, before, nil)
+
 	c.Status(http.StatusNoContent)
 }
 
+// GetSuspension godoc
+// @Summary Get whether a tenant is suspended
+// @Description Report whether consuming the tenant is suspended, since when and why.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.TenantSuspension
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/suspension [get]
+func (h *TenantHandler) GetSuspension(c *gin.Context) {
+	suspension, err := h.tenantService.GetSuspension(c.Param("id"))
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suspension)
+}
+
+// SuspendTenant godoc
+// @Summary Suspend a tenant
+// @Description Stop consuming the tenant, e.g. for a billing hold or to isolate an incident, after its in-flight messages are done. Unlike deleting it, its queue keeps taking messages and its stored data is kept. Other instances stop consuming it within 10 seconds. Admin only.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body object{reason=string} false "Why the tenant is suspended"
+// @Success 200 {object} domain.TenantSuspension
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/suspend [post]
+func (h *TenantHandler) SuspendTenant(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	before := h.tenantService.AuditValues(tenantID)
+	suspension, err := h.tenantService.SuspendTenant(c.Request.Context(), tenantID, request.Reason)
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionTenantSuspend, tenantID, map[string]interface{}{
+		"reason": request.Reason,
+	}, before, h.tenantService.AuditValues(tenantID))
+
+	c.JSON(http.StatusOK, suspension)
+}
+
+// ResumeTenant godoc
+// @Summary Resume a suspended tenant
+// @Description Consume the tenant again, starting with the messages its queue took while suspended. Other instances resume consuming it within 10 seconds. Admin only.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.TenantSuspension
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/resume [post]
+func (h *TenantHandler) ResumeTenant(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	before := h.tenantService.AuditValues(tenantID)
+	suspension, err := h.tenantService.ResumeTenant(c.Request.Context(), tenantID)
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionTenantResume, tenantID, nil, before, h.tenantService.AuditValues(tenantID))
+
+	c.JSON(http.StatusOK, suspension)
+}
+
 // UpdateConcurrency godoc
 // @Summary Update the concurrency for a tenant
-// @Description Update the number of workers for a tenant's consumer
+// @Description Update the number of workers and the prefetch count (unacked deliveries held by the consumer) of a tenant's consumer. A prefetch_count of 0 or none uses 4 per worker; ordered tenants always use 1. Workers must be within the tenant's worker bounds and what other tenants leave of the instance's concurrency.max_workers. Workers are added or retired without stopping the consumer.
 // @Tags tenants
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Tenant ID"
-// @Param config body object{workers=int} true "Concurrency configuration"
+// @Param config body object{workers=int,prefetch_count=int} true "Concurrency configuration"
 // @Success 200
-// @Failure 400 {object} object "Invalid request body"
+// @Failure 400 {object} object "Invalid request body, workers or prefetch count"
+// @Failure 409 {object} object "Tenant is in ordered mode"
 // @Failure 500 {object} object "Internal server error"
 // @Router /tenants/{id}/config/concurrency [put]
 func (h *TenantHandler) UpdateConcurrency(c *gin.Context) {
 	tenantID := c.Param("id")
 
 	var config struct {
-		Workers int `json:"workers"`
+		Workers       int `json:"workers"`
+		PrefetchCount int `json:"prefetch_count"`
 	}
 	if err := c.ShouldBindJSON(&config); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.tenantService.UpdateConcurrency(tenantID, config.Workers); err != nil {
+	before := h.tenantService.AuditValues(tenantID)
+	if err := h.tenantService.UpdateConcurrency(tenantID, config.Workers, config.PrefetchCount); err != nil {
+		if errors.Is(err, service.ErrInvalidConcurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrOrderedTenant) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLogger.RecordChange(requestActor(c), audit.ActionConcurrencyUpdate, tenantID, map[string]interface{}{
+		"workers":        config.Workers,
+		"prefetch_count": config.PrefetchCount,
+	}, before, h.tenantService.AuditValues(tenantID))
+
+	c.Status(http.StatusOK)
+}
+
+// GetExpiredCount godoc
+// @Summary Count expired messages of a tenant
+// @Description Count the tenant's messages that expired in the queue (queue TTL) before they could be processed
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{tenant_id=string,expired=int}
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/expired [get]
+func (h *TenantHandler) GetExpiredCount(c *gin.Context) {
+	tenantID := c.Param("id")
+	count, err := h.tenantService.ExpiredCount(tenantID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"tenant_id": tenantID,
+		"expired":   count,
+	})
+}
+
+// UpdateOrdering godoc
+// @Summary Switch strictly-ordered processing mode
+// @Description Enable or disable ordered mode. Ordered tenants are processed by a single worker with prefetch 1, so messages are handled in queue order even after retries.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{ordered=bool} true "Ordering configuration"
+// @Success 200
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/ordering [put]
+func (h *TenantHandler) UpdateOrdering(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var config struct {
+		Ordered *bool `json:"ordered" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tenantService.SetOrdered(tenantID, *config.Ordered); err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionOrderingUpdate, tenantID, map[string]interface{}{
+		"ordered": *config.Ordered,
+	})
+
+	c.Status(http.StatusOK)
+}
+
+// UpdatePartitionKey godoc
+// @Summary Set the partition key for keyed processing lanes
+// @Description Hash the given payload field (dotted path) to one of N lanes, N being the worker count. Messages with the same key are processed in order, different keys in parallel. An empty field disables keyed lanes.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{field=string} true "Partition key configuration"
+// @Success 200
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/partition-key [put]
+func (h *TenantHandler) UpdatePartitionKey(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var config struct {
+		Field string `json:"field"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tenantService.SetPartitionKey(tenantID, config.Field); err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionPartitionKeyUpdate, tenantID, map[string]interface{}{
+		"field": config.Field,
+	})
+
 	c.Status(http.StatusOK)
 }
+
+// GetPriority godoc
+// @Summary Get a tenant's max priority
+// @Description Get the highest AMQP priority the tenant's main queue orders messages by, 0 for a plain queue, and the queue the tenant is consumed from.
+// @Tags tenants
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} domain.PriorityConfig
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/config/priority [get]
+func (h *TenantHandler) GetPriority(c *gin.Context) {
+	config, err := h.tenantService.GetPriority(c.Param("id"))
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// UpdatePriority godoc
+// @Summary Set a tenant's max priority
+// @Description Make the tenant's main queue a RabbitMQ priority queue ordering messages by priorities up to max_priority (1-9), or a plain queue with 0. Queue arguments cannot change, so the tenant moves to a queue named after its max priority, e.g. tenant_{id}_queue_p9: the consumer switches to it and the remaining messages are shoveled over, as with a queue rename. Sending the same value again completes an interrupted move.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param config body object{max_priority=int} true "Priority configuration"
+// @Success 200 {object} domain.QueueRename
+// @Failure 400 {object} object "Invalid request body or max priority"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /tenants/{id}/config/priority [put]
+func (h *TenantHandler) UpdatePriority(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var config struct {
+		MaxPriority *int `json:"max_priority" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rename, err := h.tenantService.SetPriority(c.Request.Context(), tenantID, *config.MaxPriority)
+	switch {
+	case errors.Is(err, service.ErrInvalidPriority):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrUnsupportedTransport):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		respondTenantError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionPriorityUpdate, tenantID, map[string]interface{}{
+		"max_priority": *config.MaxPriority,
+		"queue":        rename.NewName,
+	})
+
+	c.JSON(http.StatusOK, rename)
+}
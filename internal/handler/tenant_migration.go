@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TenantMigrationHandler handles moving tenants to other deployments
+type TenantMigrationHandler struct {
+	migrationService *service.TenantMigrationService
+}
+
+// NewTenantMigrationHandler creates a new TenantMigrationHandler
+func NewTenantMigrationHandler(migrationService *service.TenantMigrationService) *TenantMigrationHandler {
+	return &TenantMigrationHandler{migrationService: migrationService}
+}
+
+// CreateMigration godoc
+// @Summary Move a tenant to another deployment
+// @Description Start moving a tenant's config, messages and queued messages to a deployment listed in tenant_migration.targets, then verify the message counts. An unfinished migration of the same tenant to the same target is resumed instead.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param request body object{tenant_id=string,target=string} true "Migration request"
+// @Success 202 {object} domain.TenantMigration
+// @Failure 400 {object} object "Invalid request body or unknown target"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Failure 501 {object} object "Not supported by the configured transport"
+// @Router /admin/tenant-migrations [post]
+func (h *TenantMigrationHandler) CreateMigration(c *gin.Context) {
+	var request struct {
+		TenantID string `json:"tenant_id" binding:"required"`
+		Target   string `json:"target" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := uuid.Parse(request.TenantID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant_id format"})
+		return
+	}
+
+	migration, err := h.migrationService.CreateMigration(request.TenantID, request.Target)
+	if err != nil {
+		respondTenantMigrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, migration)
+}
+
+// GetMigration godoc
+// @Summary Get tenant migration status
+// @Description Get the current step, progress and verification counts of a tenant migration
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Migration ID"
+// @Success 200 {object} domain.TenantMigration
+// @Failure 404 {object} object "Migration not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenant-migrations/{id} [get]
+func (h *TenantMigrationHandler) GetMigration(c *gin.Context) {
+	migration, err := h.migrationService.GetMigration(c.Param("id"))
+	if err != nil {
+		respondTenantMigrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, migration)
+}
+
+// ResumeMigration godoc
+// @Summary Resume a tenant migration
+// @Description Resume a failed or interrupted tenant migration at the step it stopped in
+// @Tags admin
+// @Produce  json
+// @Param id path string true "Migration ID"
+// @Success 202 {object} domain.TenantMigration
+// @Failure 404 {object} object "Migration not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /admin/tenant-migrations/{id}/resume [post]
+func (h *TenantMigrationHandler) ResumeMigration(c *gin.Context) {
+	migration, err := h.migrationService.ResumeMigration(c.Param("id"))
+	if err != nil {
+		respondTenantMigrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, migration)
+}
+
+func respondTenantMigrationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownMigrationTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTenantNotFound), errors.Is(err, service.ErrTenantMigrationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedTransport):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenHandler handles a tenant's self-service sub-tokens
+type TokenHandler struct {
+	tenantTokens *service.TenantTokenService
+	auditLogger  *audit.Logger
+}
+
+// NewTokenHandler creates a new TokenHandler
+func NewTokenHandler(tenantTokens *service.TenantTokenService, auditLogger *audit.Logger) *TokenHandler {
+	return &TokenHandler{tenantTokens: tenantTokens, auditLogger: auditLogger}
+}
+
+// MintToken godoc
+// @Summary Mint a scoped sub-token
+// @Description Issue a short-lived access token for the tenant that can only call the routes of its scopes (messages:read, events:read, slo:read, dlq:read), e.g. for a dashboard. It has no refresh token. Requires a full token of the tenant or an operator token; the token is only returned here.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param token body object{name=string,scopes=[]string,ttl=string} true "Name, scopes and lifetime (default 1h)"
+// @Success 201 {object} domain.TenantToken
+// @Failure 400 {object} object "Invalid request body, scope or lifetime"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/tokens [post]
+func (h *TokenHandler) MintToken(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request struct {
+		Name   string   `json:"name" binding:"required,max=128"`
+		Scopes []string `json:"scopes" binding:"required"`
+		TTL    string   `json:"ttl"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl"})
+			return
+		}
+	}
+
+	token, err := h.tenantTokens.Mint(tenantID, request.Name, requestActor(c), request.Scopes, ttl)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionTokenMint, tenantID, map[string]interface{}{
+		"token_id":   token.ID,
+		"name":       token.Name,
+		"scopes":     token.Scopes,
+		"expires_at": token.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// ListTokens godoc
+// @Summary List a tenant's sub-tokens
+// @Description List the sub-tokens minted for the tenant that have not expired, including revoked ones. Token values are not returned.
+// @Tags tenants
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.TenantToken}
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/tokens [get]
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.tenantTokens.List(c.Param("id"))
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+// RevokeToken godoc
+// @Summary Revoke a sub-token
+// @Description Reject the sub-token from now on, before it expires
+// @Tags tenants
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param token_id path string true "Token ID"
+// @Success 204
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Token not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/tokens/{token_id} [delete]
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	tenantID := c.Param("id")
+	tokenID := c.Param("token_id")
+
+	if err := h.tenantTokens.Revoke(tenantID, tokenID); err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionTokenRevoke, tenantID, map[string]interface{}{
+		"token_id": tokenID,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTokenRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
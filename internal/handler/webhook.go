@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"multi-tenant-messaging/internal/audit"
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler handles a tenant's webhooks and their deliveries
+type WebhookHandler struct {
+	webhooks    *service.WebhookService
+	auditLogger *audit.Logger
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(webhooks *service.WebhookService, auditLogger *audit.Logger) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks, auditLogger: auditLogger}
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Register an endpoint the tenant's processed messages are POSTed to, signed with the returned secret. The secret is only returned here.
+// @Tags tenants
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param webhook body object{url=string} true "Endpoint URL"
+// @Success 201 {object} domain.Webhook
+// @Failure 400 {object} object "Invalid request body or URL, or an internal address"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Tenant not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	tenantID := c.Param("id")
+
+	var request struct {
+		URL string `json:"url" binding:"required,max=2048"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, err := h.webhooks.Create(tenantID, request.URL, requestActor(c))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionWebhookCreate, tenantID, map[string]interface{}{
+		"webhook_id": hook.ID,
+		"url":        hook.URL,
+	})
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+// ListWebhooks godoc
+// @Summary List a tenant's webhooks
+// @Description List the tenant's webhooks. Secrets are not returned.
+// @Tags tenants
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} object{data=[]domain.Webhook}
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhooks.List(c.Param("id"))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": hooks})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete the webhook together with its deliveries; pending deliveries are dropped
+// @Tags tenants
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 204
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Webhook not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/webhooks/{webhook_id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	tenantID := c.Param("id")
+	webhookID := c.Param("webhook_id")
+
+	if err := h.webhooks.Delete(tenantID, webhookID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.auditLogger.Record(requestActor(c), audit.ActionWebhookDelete, tenantID, map[string]interface{}{
+		"webhook_id": webhookID,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List a webhook's deliveries
+// @Description List the webhook's latest deliveries, newest first, with their attempts and the status code and error of the last attempt
+// @Tags tenants
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Tenant ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param status query string false "Only deliveries with this status" Enums(pending, delivered, failed)
+// @Param limit query int false "Maximum deliveries (default: 100, max: 1000)"
+// @Success 200 {object} object{data=[]domain.WebhookDelivery}
+// @Failure 400 {object} object "Invalid status or limit"
+// @Failure 403 {object} object "Token bound to another tenant or scoped"
+// @Failure 404 {object} object "Webhook not found"
+// @Failure 500 {object} object "Internal server error"
+// @Router /tenants/{id}/webhooks/{webhook_id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(c.Param("id"), c.Param("webhook_id"), c.Query("status"), limit)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook), errors.Is(err, service.ErrWebhookAddressBlocked):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,254 @@
+// Package health runs the named checks behind the liveness and readiness
+// probes. Liveness checks only fail when restarting the process is the fix;
+// readiness checks fail while the instance should not get traffic. The
+// detailed report covers the components behind them, telling degraded
+// components apart from ones that are down.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds a single check
+const checkTimeout = 2 * time.Second
+
+// Check reports a problem with one concern
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker holds the liveness and readiness checks
+type Checker struct {
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+	// components make up the detailed report
+	components []*component
+	stopping   atomic.Bool
+}
+
+// NewChecker creates a Checker whose readiness includes a "shutdown" check
+// that fails once Shutdown is called
+func NewChecker() *Checker {
+	c := &Checker{}
+	c.AddReadiness("shutdown", func(context.Context) error {
+		if c.stopping.Load() {
+			return errShuttingDown
+		}
+		return nil
+	})
+	return c
+}
+
+var errShuttingDown = errors.New("shutting down")
+
+// AddLiveness registers a liveness check
+func (c *Checker) AddLiveness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadiness registers a readiness check
+func (c *Checker) AddReadiness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, namedCheck{name: name, check: check})
+}
+
+// Shutdown makes the instance unready so it is taken out of the endpoints
+// before the server stops
+func (c *Checker) Shutdown() {
+	c.stopping.Store(true)
+}
+
+// Result is the outcome of a set of checks, with "ok" or the error per check
+type Result struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Healthy reports whether every check passed
+func (r Result) Healthy() bool {
+	return r.Status == "ok"
+}
+
+// Live runs the liveness checks, or only the named one if name is not empty.
+// found is false when no check has that name.
+func (c *Checker) Live(ctx context.Context, name string) (result Result, found bool) {
+	c.mu.RLock()
+	checks := c.liveness
+	c.mu.RUnlock()
+	return run(ctx, checks, name)
+}
+
+// Ready runs the readiness checks, or only the named one if name is not empty
+func (c *Checker) Ready(ctx context.Context, name string) (result Result, found bool) {
+	c.mu.RLock()
+	checks := c.readiness
+	c.mu.RUnlock()
+	return run(ctx, checks, name)
+}
+
+func run(ctx context.Context, checks []namedCheck, name string) (Result, bool) {
+	result := Result{Status: "ok", Checks: make(map[string]string)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	found := name == ""
+	for _, nc := range checks {
+		if name != "" && nc.name != name {
+			continue
+		}
+		found = true
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			status := "ok"
+			if err := nc.check(checkCtx); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			result.Checks[nc.name] = status
+			if status != "ok" {
+				result.Status = "failed"
+			}
+			mu.Unlock()
+		}(nc)
+	}
+	wg.Wait()
+	return result, found
+}
+
+// Component statuses of the detailed report. A degraded component still
+// works with reduced capacity; a down one does not work at all.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// degradedError marks an error of a component that is degraded, not down
+type degradedError struct{ error }
+
+func (e degradedError) Unwrap() error { return e.error }
+
+// Degraded marks err as a degradation rather than an outage
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return degradedError{err}
+}
+
+// ComponentCheck reports the state of a component. details are included in
+// the report as they are; a nil error means ok, an error wrapped with
+// Degraded means degraded and any other error means down.
+type ComponentCheck func(ctx context.Context) (details map[string]any, err error)
+
+type component struct {
+	name  string
+	check ComponentCheck
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// ComponentStatus is one component of the detailed report. LastError is the
+// most recent failure seen, kept after the component recovers.
+type ComponentStatus struct {
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	LatencyMs   float64        `json:"latency_ms"`
+	LastError   string         `json:"last_error,omitempty"`
+	LastErrorAt *time.Time     `json:"last_error_at,omitempty"`
+	Details     map[string]any `json:"details,omitempty"`
+}
+
+// Details is the detailed health report; Status is that of the worst component
+type Details struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// AddComponent registers a component of the detailed report
+func (c *Checker) AddComponent(name string, check ComponentCheck) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.components = append(c.components, &component{name: name, check: check})
+}
+
+// Details runs every component check
+func (c *Checker) Details(ctx context.Context) Details {
+	c.mu.RLock()
+	components := c.components
+	c.mu.RUnlock()
+
+	details := Details{Status: StatusOK, Components: make(map[string]ComponentStatus)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, comp := range components {
+		wg.Add(1)
+		go func(comp *component) {
+			defer wg.Done()
+			status := comp.run(ctx)
+			mu.Lock()
+			details.Components[comp.name] = status
+			if severity(status.Status) > severity(details.Status) {
+				details.Status = status.Status
+			}
+			mu.Unlock()
+		}(comp)
+	}
+	wg.Wait()
+	return details
+}
+
+func (comp *component) run(ctx context.Context) ComponentStatus {
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	started := time.Now()
+	info, err := comp.check(checkCtx)
+	status := ComponentStatus{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+		Details:   info,
+	}
+
+	comp.mu.Lock()
+	defer comp.mu.Unlock()
+	if err != nil {
+		status.Status = StatusDown
+		if errors.As(err, new(degradedError)) {
+			status.Status = StatusDegraded
+		}
+		status.Error = err.Error()
+		comp.lastError, comp.lastErrorAt = err.Error(), time.Now()
+	}
+	if comp.lastError != "" {
+		at := comp.lastErrorAt
+		status.LastError, status.LastErrorAt = comp.lastError, &at
+	}
+	return status
+}
+
+func severity(status string) int {
+	switch status {
+	case StatusDown:
+		return 2
+	case StatusDegraded:
+		return 1
+	}
+	return 0
+}
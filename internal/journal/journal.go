@@ -0,0 +1,314 @@
+// Package journal records the deliveries the consumers are working on in a
+// local append-only file. After a crash it tells which of the redelivered
+// messages were already processed, so they are acked instead of processed a
+// second time.
+//
+// Each line of the file is a JSON record. A delivery is begun when it is
+// handed to the worker pool, marked processed once its message is persisted
+// (or dropped or routed by the tenant's filters) and done once it is settled.
+// Deliveries left processed but not done by a crash are recovered on open.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record operations
+const (
+	opBegin     = "begin"
+	opProcessed = "processed"
+	opDone      = "done"
+)
+
+// compactAfter is how many records are appended before the file is rewritten
+// with only the open entries
+const compactAfter = 10000
+
+type record struct {
+	Op          string    `json:"op"`
+	Seq         uint64    `json:"seq"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	MessageID   string    `json:"message_id,omitempty"`
+	DeliveryTag uint64    `json:"delivery_tag,omitempty"`
+	At          time.Time `json:"at,omitempty"`
+}
+
+// entry is a begun delivery that is not done yet
+type entry struct {
+	record
+	processed bool
+}
+
+// Journal is the in-flight delivery journal. A nil Journal records nothing.
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	nextSeq uint64
+	open    map[uint64]*entry
+	// recovered holds the entries processed but not settled before a crash,
+	// keyed by tenant and message ID
+	recovered      map[string]*entry
+	recoveryWindow time.Duration
+	appended       int
+}
+
+// Open loads the journal at path, creating it if needed. Processed entries of
+// the previous run younger than recoveryWindow are kept until their message
+// is redelivered; other open entries only need their redelivery processed.
+func Open(path string, recoveryWindow time.Duration) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &Journal{
+		path:           path,
+		open:           make(map[uint64]*entry),
+		recovered:      make(map[string]*entry),
+		recoveryWindow: recoveryWindow,
+	}
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+
+	// Entri yang belum selesai berasal dari run sebelumnya: yang sudah diproses
+	// dipertahankan untuk rekonsiliasi, sisanya akan diproses ulang dari redelivery
+	unprocessed := 0
+	for seq, e := range j.open {
+		delete(j.open, seq)
+		if !e.processed || e.MessageID == "" || time.Since(e.At) > recoveryWindow {
+			unprocessed++
+			continue
+		}
+		j.recovered[recoveredKey(e.TenantID, e.MessageID)] = e
+	}
+	if unprocessed > 0 || len(j.recovered) > 0 {
+		slog.Info("Journal: deliveries were in flight at the last stop",
+			"in_flight", unprocessed+len(j.recovered), "processed", len(j.recovered))
+	}
+
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// replay reads the journal file into the open entries
+func (j *Journal) replay() error {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// Baris terakhir bisa terpotong jika proses mati saat menulis
+			slog.Warn("Journal: skipping unreadable record", "error", err)
+			continue
+		}
+		if r.Seq >= j.nextSeq {
+			j.nextSeq = r.Seq + 1
+		}
+		switch r.Op {
+		case opBegin:
+			j.open[r.Seq] = &entry{record: r}
+		case opProcessed:
+			if e, ok := j.open[r.Seq]; ok {
+				e.processed = true
+			}
+		case opDone:
+			delete(j.open, r.Seq)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the file with only the open entries and the recovered ones
+// still inside the recovery window, and reopens it for appending. It must be
+// called with mu held or before the journal is shared.
+func (j *Journal) compact() error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	write := func(e *entry) {
+		enc.Encode(e.record)
+		if e.processed {
+			enc.Encode(record{Op: opProcessed, Seq: e.Seq})
+		}
+	}
+	for key, e := range j.recovered {
+		if time.Since(e.At) > j.recoveryWindow {
+			delete(j.recovered, key)
+			continue
+		}
+		write(e)
+	}
+	for _, e := range j.open {
+		write(e)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.appended = 0
+	return nil
+}
+
+// append writes a record; it must be called with mu held. Records are
+// written straight to the file so they survive the process crashing.
+func (j *Journal) append(r record) {
+	line, _ := json.Marshal(r)
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		slog.Error("Journal: failed to write record", "error", err)
+		return
+	}
+	j.appended++
+	if j.appended >= compactAfter {
+		if err := j.compact(); err != nil {
+			slog.Error("Journal: failed to compact", "error", err)
+		}
+	}
+}
+
+// Begin records a delivery handed to the worker pool and returns its sequence
+// number for Processed and Done
+func (j *Journal) Begin(tenantID, messageID string, deliveryTag uint64) uint64 {
+	if j == nil {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	seq := j.nextSeq
+	j.nextSeq++
+	r := record{Op: opBegin, Seq: seq, TenantID: tenantID, MessageID: messageID, DeliveryTag: deliveryTag, At: time.Now().UTC()}
+	j.open[seq] = &entry{record: r}
+	j.append(r)
+	return seq
+}
+
+// Processed records that the delivery's message was persisted
+func (j *Journal) Processed(seq uint64) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e, ok := j.open[seq]
+	if !ok {
+		return
+	}
+	e.processed = true
+	j.append(record{Op: opProcessed, Seq: seq})
+}
+
+// Done records that the delivery was acked or nacked
+func (j *Journal) Done(seq uint64) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.open[seq]; !ok {
+		return
+	}
+	delete(j.open, seq)
+	j.append(record{Op: opDone, Seq: seq})
+}
+
+// Forget ends the open entries of a tenant whose consumer stopped; their
+// unsettled deliveries go back to the queue. Processed ones are kept as
+// recovered, since their redelivery only needs to be acked.
+func (j *Journal) Forget(tenantID string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for seq, e := range j.open {
+		if e.TenantID != tenantID {
+			continue
+		}
+		delete(j.open, seq)
+		if e.processed && e.MessageID != "" {
+			j.recovered[recoveredKey(e.TenantID, e.MessageID)] = e
+			continue
+		}
+		j.append(record{Op: opDone, Seq: seq})
+	}
+}
+
+// Recovered reports whether the message was processed before a crash without
+// its delivery being acked. The entry is settled, so a second redelivery of
+// the same message is processed normally.
+func (j *Journal) Recovered(tenantID, messageID string) bool {
+	if j == nil || messageID == "" {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key := recoveredKey(tenantID, messageID)
+	e, ok := j.recovered[key]
+	if !ok {
+		return false
+	}
+	delete(j.recovered, key)
+	j.append(record{Op: opDone, Seq: e.Seq})
+	return true
+}
+
+// Close syncs and closes the journal file
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+func recoveredKey(tenantID, messageID string) string {
+	return tenantID + "/" + messageID
+}
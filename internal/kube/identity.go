@@ -0,0 +1,62 @@
+// Package kube integrates the server with Kubernetes: the pod identity exposed
+// through the downward API and Lease-based election of the instance that runs
+// the singleton background jobs.
+package kube
+
+import "os"
+
+// Identity is the pod the instance runs in, read from environment variables
+// set through the downward API:
+//
+//	env:
+//	  - name: POD_NAME
+//	    valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	  - name: POD_NAMESPACE
+//	    valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	  - name: NODE_NAME
+//	    valueFrom: {fieldRef: {fieldPath: spec.nodeName}}
+type Identity struct {
+	PodName   string
+	Namespace string
+	NodeName  string
+}
+
+// serviceAccountNamespace holds the pod's namespace when the service account
+// token is mounted
+const serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// IdentityFromEnv reads the downward API variables. The namespace falls back
+// to the mounted service account's.
+func IdentityFromEnv() Identity {
+	id := Identity{
+		PodName:   os.Getenv("POD_NAME"),
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		NodeName:  os.Getenv("NODE_NAME"),
+	}
+	if id.Namespace == "" {
+		if ns, err := os.ReadFile(serviceAccountNamespace); err == nil {
+			id.Namespace = string(ns)
+		}
+	}
+	return id
+}
+
+// InCluster reports whether the process runs in a Kubernetes pod
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// Labels returns the identity's non-empty fields as metric labels
+func (id Identity) Labels() map[string]string {
+	labels := make(map[string]string)
+	if id.PodName != "" {
+		labels["pod"] = id.PodName
+	}
+	if id.Namespace != "" {
+		labels["namespace"] = id.Namespace
+	}
+	if id.NodeName != "" {
+		labels["node"] = id.NodeName
+	}
+	return labels
+}
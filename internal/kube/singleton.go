@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaseConfig names the Lease object the instances compete for
+type LeaseConfig struct {
+	Name          string
+	Namespace     string
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+type singletonJob struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// Singletons runs background jobs that must only run on one instance at a
+// time. Without an elector every instance runs them, as before.
+type Singletons struct {
+	jobs    []singletonJob
+	elector *leaderelection.LeaderElector
+	// running waits for the jobs of a lost term before campaigning again
+	running sync.WaitGroup
+
+	mu sync.Mutex
+	// stopped names the jobs that returned while their term was still on
+	stopped map[string]bool
+}
+
+// NewSingletons creates Singletons that run their jobs right away
+func NewSingletons() *Singletons {
+	return &Singletons{}
+}
+
+// NewLeaseSingletons creates Singletons whose jobs run only on the instance
+// holding the Lease. It needs the in-cluster service account, with get, create
+// and update permission on coordination.k8s.io leases in the namespace.
+func NewLeaseSingletons(cfg LeaseConfig) (*Singletons, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	s := &Singletons{}
+	s.elector, err = leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: cfg.Name, Namespace: cfg.Namespace},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: cfg.Identity},
+		},
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            cfg.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				slog.Info("Acquired lease, starting singleton jobs", "lease", cfg.Namespace+"/"+cfg.Name)
+				s.start(ctx)
+			},
+			OnStoppedLeading: func() {
+				slog.Info("Lost lease, singleton jobs stop", "lease", cfg.Namespace+"/"+cfg.Name)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != cfg.Identity {
+					slog.Info("Singleton jobs run on another instance", "leader", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid leader election config: %w", err)
+	}
+	return s, nil
+}
+
+// Add registers a job. run must return once its context is done. Jobs must
+// be added before Run.
+func (s *Singletons) Add(name string, run func(ctx context.Context)) {
+	s.jobs = append(s.jobs, singletonJob{name: name, run: run})
+}
+
+// Run runs the jobs, or campaigns for the Lease and runs them while holding
+// it, until ctx is done
+func (s *Singletons) Run(ctx context.Context) {
+	if s.elector == nil {
+		s.start(ctx)
+		s.running.Wait()
+		return
+	}
+
+	for ctx.Err() == nil {
+		s.elector.Run(ctx)
+		// Pastikan job term sebelumnya berhenti sebelum mencoba memimpin lagi
+		s.running.Wait()
+	}
+}
+
+// IsLeader reports whether this instance runs the singleton jobs
+func (s *Singletons) IsLeader() bool {
+	return s.elector == nil || s.elector.IsLeader()
+}
+
+// Leader returns the identity of the instance running the singleton jobs,
+// "" while none holds the Lease. Without an elector it is "self".
+func (s *Singletons) Leader() string {
+	if s.elector == nil {
+		return "self"
+	}
+	return s.elector.GetLeader()
+}
+
+// Stopped returns the jobs that returned early in the current term, sorted
+func (s *Singletons) Stopped() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stopped := make([]string, 0, len(s.stopped))
+	for name := range s.stopped {
+		stopped = append(stopped, name)
+	}
+	sort.Strings(stopped)
+	return stopped
+}
+
+func (s *Singletons) start(ctx context.Context) {
+	s.mu.Lock()
+	s.stopped = make(map[string]bool)
+	s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		s.running.Add(1)
+		go func(job singletonJob) {
+			defer s.running.Done()
+			job.run(ctx)
+			if ctx.Err() == nil {
+				s.mu.Lock()
+				s.stopped[job.name] = true
+				s.mu.Unlock()
+			}
+			if s.elector != nil {
+				slog.Info("Singleton job stopped", "job", job.name)
+			}
+		}(job)
+	}
+}
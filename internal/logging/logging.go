@@ -0,0 +1,88 @@
+// Package logging sets up the service's structured logger. Log calls go
+// through log/slog; fields stored in a context with With, such as tenant_id,
+// message_id and request_id, are added to every record logged with that
+// context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Output formats
+const (
+	// FormatJSON writes one JSON object per line
+	FormatJSON = "json"
+	// FormatConsole writes key=value lines for reading in a terminal
+	FormatConsole = "console"
+)
+
+// New creates a logger writing to w at level (debug, info, warn or error) in
+// format
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatConsole:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected json or console", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Setup makes a logger writing to stderr the default of log/slog and of the
+// standard log package
+func Setup(level, format string) error {
+	logger, err := New(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+type fieldsKey struct{}
+
+// With returns a copy of ctx carrying the key-value pairs args, logged with
+// every record of a *Context log call on ctx
+func With(ctx context.Context, args ...any) context.Context {
+	fields, _ := ctx.Value(fieldsKey{}).([]any)
+	return context.WithValue(ctx, fieldsKey{}, append(fields[:len(fields):len(fields)], args...))
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler adds the fields stored by With to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields, ok := ctx.Value(fieldsKey{}).([]any); ok {
+		record.Add(fields...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// consumptionMinutes is how far back Consumption looks
+const consumptionMinutes = 60
+
+type consumptionBucket struct {
+	minute  int64
+	count   int64
+	latency time.Duration
+}
+
+// consumptionWindow counts a tenant's processed messages and their
+// processing time in one-minute buckets over the last hour
+type consumptionWindow struct {
+	buckets [consumptionMinutes]consumptionBucket
+	last    time.Time
+}
+
+// TenantConsumption is what this instance processed of a tenant. Unlike the
+// Prometheus series it is kept for every tenant, not only the labeled ones.
+type TenantConsumption struct {
+	LastMinute   int64
+	Last5Minutes int64
+	LastHour     int64
+	// AvgLatency is the mean processing time over the last hour
+	AvgLatency time.Duration
+	// LastConsumedAt is zero until a message was processed here
+	LastConsumedAt time.Time
+}
+
+var consumption = struct {
+	mu      sync.Mutex
+	tenants map[string]*consumptionWindow
+}{tenants: make(map[string]*consumptionWindow)}
+
+func observeConsumption(tenantID string, latency time.Duration) {
+	now := time.Now()
+	minute := now.Unix() / 60
+
+	consumption.mu.Lock()
+	defer consumption.mu.Unlock()
+	w := consumption.tenants[tenantID]
+	if w == nil {
+		w = &consumptionWindow{}
+		consumption.tenants[tenantID] = w
+	}
+	b := &w.buckets[minute%consumptionMinutes]
+	if b.minute != minute {
+		*b = consumptionBucket{minute: minute}
+	}
+	b.count++
+	b.latency += latency
+	w.last = now
+}
+
+// Consumption returns what this instance processed of the tenant in the
+// last minute, 5 minutes and hour
+func Consumption(tenantID string) TenantConsumption {
+	minute := time.Now().Unix() / 60
+
+	consumption.mu.Lock()
+	defer consumption.mu.Unlock()
+	w := consumption.tenants[tenantID]
+	if w == nil {
+		return TenantConsumption{}
+	}
+	stats := TenantConsumption{LastConsumedAt: w.last}
+	var latency time.Duration
+	for _, b := range w.buckets {
+		age := minute - b.minute
+		if age < 0 || age >= consumptionMinutes {
+			continue
+		}
+		stats.LastHour += b.count
+		latency += b.latency
+		if age < 5 {
+			stats.Last5Minutes += b.count
+		}
+		if age < 1 {
+			stats.LastMinute += b.count
+		}
+	}
+	if stats.LastHour > 0 {
+		stats.AvgLatency = latency / time.Duration(stats.LastHour)
+	}
+	return stats
+}
+
+// ForgetConsumption drops the tenant's counts, e.g. once it is deleted
+func ForgetConsumption(tenantID string) {
+	consumption.mu.Lock()
+	delete(consumption.tenants, tenantID)
+	consumption.mu.Unlock()
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Registry holds every collector the service exposes on the metrics endpoint
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_http_requests_total",
+		Help: "API requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_http_request_errors_total",
+		Help: "API requests that ended in a 5xx response.",
+	}, []string{"route", "method"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "salva_http_request_duration_seconds",
+		Help:    "API request latency by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	httpShed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_http_requests_shed_total",
+		Help: "API requests rejected with 503 by the concurrency limit.",
+	}, []string{"route"})
+
+	stageRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_worker_stage_total",
+		Help: "Message processing stage runs.",
+	}, []string{"stage"})
+	stageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_worker_stage_errors_total",
+		Help: "Message processing stage runs that failed.",
+	}, []string{"stage"})
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "salva_worker_stage_duration_seconds",
+		Help:    "Message processing stage latency.",
+		Buckets: []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 5, 30},
+	}, []string{"stage"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "salva_db_query_duration_seconds",
+		Help:    "Database query latency by operation (insert, list, ddl, other).",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 5},
+	}, []string{"operation"})
+	queryRows = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "salva_db_query_rows",
+		Help:    "Rows returned or affected per database query.",
+		Buckets: []float64{0, 1, 10, 100, 1000, 10000},
+	}, []string{"operation"})
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_db_query_errors_total",
+		Help: "Database queries that returned an error.",
+	}, []string{"operation"})
+
+	dlqRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_dlq_retries_total",
+		Help: "DLQ retry scheduler decisions by outcome (retried, gave_up).",
+	}, []string{"tenant_id", "outcome"})
+
+	messageLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "salva_message_processing_duration_seconds",
+		Help:    "Time from receiving a delivery to committing it to the database.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"tenant_id"})
+	messageRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_message_retries_total",
+		Help: "Message processing retries by reason (backoff, requeue, failover).",
+	}, []string{"tenant_id", "reason"})
+	messageDeadLetters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_message_dead_lettered_total",
+		Help: "Messages sent to the dead letter queue by failure reason.",
+	}, []string{"tenant_id", "reason"})
+	messageQuarantines = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_message_quarantined_total",
+		Help: "Poison messages quarantined by failure reason.",
+	}, []string{"tenant_id", "reason"})
+	messageInsertErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_message_insert_errors_total",
+		Help: "Messages whose database insert failed.",
+	}, []string{"tenant_id"})
+	quotaExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "salva_quota_exceeded_total",
+		Help: "Publishes rejected by the tenant's quota (messages, bytes, queue_depth).",
+	}, []string{"tenant_id", "quota"})
+	intakePaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "salva_intake_paused",
+		Help: "1 while the tenant's consumers take no deliveries because it is at its quota.",
+	}, []string{"tenant_id"})
+)
+
+func init() {
+	Registry.MustRegister(httpRequests, httpErrors, httpDuration, httpShed, stageRuns, stageErrors, stageDuration,
+		queryDuration, queryRows, queryErrors, dlqRetries, messageLatency, messageRetries, messageDeadLetters, messageQuarantines, messageInsertErrors, quotaExceeded, intakePaused)
+}
+
+// ObserveRequest records one API request. traceID, if set, is attached as an
+// exemplar so a dashboard can jump from the sample to the trace.
+func ObserveRequest(route, method, code string, failed bool, elapsed time.Duration, traceID string) {
+	exemplar := exemplarLabels(traceID)
+	addWithExemplar(httpRequests.WithLabelValues(route, method, code), exemplar)
+	if failed {
+		addWithExemplar(httpErrors.WithLabelValues(route, method), exemplar)
+	}
+	observeWithExemplar(httpDuration.WithLabelValues(route, method), elapsed.Seconds(), exemplar)
+}
+
+// ObserveShed records a request shed by the concurrency limit
+func ObserveShed(route string) {
+	httpShed.WithLabelValues(route).Inc()
+}
+
+// ObserveStage runs fn as the named worker stage and records its rate, errors
+// and duration, using the trace ID carried by ctx as exemplar
+func ObserveStage(ctx context.Context, stage string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	exemplar := exemplarLabels(TraceIDFromContext(ctx))
+	addWithExemplar(stageRuns.WithLabelValues(stage), exemplar)
+	if err != nil {
+		addWithExemplar(stageErrors.WithLabelValues(stage), exemplar)
+	}
+	observeWithExemplar(stageDuration.WithLabelValues(stage), time.Since(start).Seconds(), exemplar)
+	return err
+}
+
+// ObserveQuery records one database query of the given operation
+func ObserveQuery(ctx context.Context, operation string, elapsed time.Duration, rows int64, err error) {
+	exemplar := exemplarLabels(TraceIDFromContext(ctx))
+	observeWithExemplar(queryDuration.WithLabelValues(operation), elapsed.Seconds(), exemplar)
+	if err != nil {
+		addWithExemplar(queryErrors.WithLabelValues(operation), exemplar)
+		return
+	}
+	queryRows.WithLabelValues(operation).Observe(float64(rows))
+}
+
+func exemplarLabels(traceID string) prometheus.Labels {
+	if traceID == "" {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": traceID}
+}
+
+func addWithExemplar(c prometheus.Counter, exemplar prometheus.Labels) {
+	if exemplar == nil {
+		c.Inc()
+		return
+	}
+	c.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+}
+
+func observeWithExemplar(o prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if exemplar == nil {
+		o.Observe(v)
+		return
+	}
+	o.(prometheus.ExemplarObserver).ObserveWithExemplar(v, exemplar)
+}
+
+// ObserveDLQRetry counts one decision of the DLQ retry scheduler
+func ObserveDLQRetry(tenantID, outcome string) {
+	dlqRetries.WithLabelValues(Tenants.Label("salva_dlq_retries_total", tenantID), outcome).Inc()
+}
+
+// ObserveMessageProcessed records the latency of a message stored, or
+// settled without storing, receivedAt after its delivery arrived
+func ObserveMessageProcessed(ctx context.Context, tenantID string, receivedAt time.Time) {
+	latency := time.Since(receivedAt)
+	label := Tenants.Label("salva_message_processing_duration_seconds", tenantID)
+	observeWithExemplar(messageLatency.WithLabelValues(label), latency.Seconds(), exemplarLabels(TraceIDFromContext(ctx)))
+	observeConsumption(tenantID, latency)
+}
+
+// ObserveMessageRetry counts one more processing attempt of a message
+func ObserveMessageRetry(ctx context.Context, tenantID, reason string) {
+	label := Tenants.Label("salva_message_retries_total", tenantID)
+	addWithExemplar(messageRetries.WithLabelValues(label, reason), exemplarLabels(TraceIDFromContext(ctx)))
+}
+
+// ObserveMessageDeadLettered counts a message rejected to the dead letter queue
+func ObserveMessageDeadLettered(ctx context.Context, tenantID, reason string) {
+	label := Tenants.Label("salva_message_dead_lettered_total", tenantID)
+	addWithExemplar(messageDeadLetters.WithLabelValues(label, reason), exemplarLabels(TraceIDFromContext(ctx)))
+}
+
+// ObserveMessageQuarantined counts a poison message taken off the broker
+func ObserveMessageQuarantined(ctx context.Context, tenantID, reason string) {
+	label := Tenants.Label("salva_message_quarantined_total", tenantID)
+	addWithExemplar(messageQuarantines.WithLabelValues(label, reason), exemplarLabels(TraceIDFromContext(ctx)))
+}
+
+// ObserveMessageInsertError counts a message whose insert transaction failed
+func ObserveMessageInsertError(ctx context.Context, tenantID string) {
+	label := Tenants.Label("salva_message_insert_errors_total", tenantID)
+	addWithExemplar(messageInsertErrors.WithLabelValues(label), exemplarLabels(TraceIDFromContext(ctx)))
+}
+
+// ObserveQuotaExceeded counts a publish rejected by the tenant's quota
+func ObserveQuotaExceeded(tenantID, quota string) {
+	quotaExceeded.WithLabelValues(Tenants.Label("salva_quota_exceeded_total", tenantID), quota).Inc()
+}
+
+// SetIntakePaused records whether the tenant's consumers are paused at its quota
+func SetIntakePaused(tenantID string, paused bool) {
+	value := 0.0
+	if paused {
+		value = 1
+	}
+	intakePaused.WithLabelValues(Tenants.Label("salva_intake_paused", tenantID)).Set(value)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// BatchRun measures one run of a one-shot command (migrate,
+// --migrate-tenant) and pushes it to a Prometheus Pushgateway, since the
+// process exits before it could be scraped
+type BatchRun struct {
+	pusher  *push.Pusher
+	started time.Time
+
+	duration    prometheus.Gauge
+	success     prometheus.Gauge
+	lastSuccess prometheus.Gauge
+	items       *prometheus.GaugeVec
+}
+
+// StartBatch starts measuring a run of job. grouping adds labels such as
+// tenant_id to the Pushgateway group. It returns nil when url is empty; a
+// nil BatchRun ignores every call.
+func StartBatch(url, job string, grouping map[string]string) *BatchRun {
+	if url == "" {
+		return nil
+	}
+
+	r := &BatchRun{
+		started: time.Now(),
+		duration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "salva_batch_duration_seconds",
+			Help: "Duration of the last run of the batch job.",
+		}),
+		success: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "salva_batch_success",
+			Help: "Whether the last run of the batch job succeeded (1) or failed (0).",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "salva_batch_last_success_timestamp_seconds",
+			Help: "Unix time the batch job last succeeded.",
+		}),
+		items: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "salva_batch_items",
+			Help: "Items handled by the last run of the batch job, by kind.",
+		}, []string{"kind"}),
+	}
+
+	r.pusher = push.New(url, job).Collector(r.duration).Collector(r.success).Collector(r.items)
+	for name, value := range grouping {
+		r.pusher = r.pusher.Grouping(name, value)
+	}
+	return r
+}
+
+// SetItems records how many items of kind the run handled
+func (r *BatchRun) SetItems(kind string, count int64) {
+	if r == nil {
+		return
+	}
+	r.items.WithLabelValues(kind).Set(float64(count))
+}
+
+// Finish pushes the run's metrics. A failed run keeps the last success
+// timestamp of earlier runs in the Pushgateway.
+func (r *BatchRun) Finish(err error) {
+	if r == nil {
+		return
+	}
+
+	r.duration.Set(time.Since(r.started).Seconds())
+	if err == nil {
+		r.success.Set(1)
+		r.lastSuccess.SetToCurrentTime()
+		r.pusher = r.pusher.Collector(r.lastSuccess)
+	}
+	// Add (POST) hanya mengganti metrik dengan nama yang sama di group
+	if pushErr := r.pusher.Add(); pushErr != nil {
+		slog.Error("Failed to push batch metrics to the Pushgateway", "error", pushErr)
+	}
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var amqpChannels = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "salva_amqp_channels_open",
+	Help: "AMQP channels currently open by this instance.",
+})
+
+// ChannelOpened and ChannelClosed track the number of open AMQP channels
+func ChannelOpened() { amqpChannels.Inc() }
+func ChannelClosed() { amqpChannels.Dec() }
+
+// RegisterRuntime registers Go runtime, process, database pool and AMQP
+// channel collectors, all labeled with instanceID and the pod labels (pod,
+// namespace, node) so a leaking replica stands out in a multi-replica deployment
+func RegisterRuntime(instanceID string, podLabels map[string]string, db *sql.DB) error {
+	labels := prometheus.Labels{"instance_id": instanceID}
+	for name, value := range podLabels {
+		labels[name] = value
+	}
+	reg := prometheus.WrapRegistererWith(labels, Registry)
+	for _, c := range []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "main"),
+		amqpChannels,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tenant label policies
+const (
+	// TenantLabelsAll labels every tenant
+	TenantLabelsAll = "all"
+	// TenantLabelsTopK labels the K busiest tenants and folds the rest into OtherTenants
+	TenantLabelsTopK = "top_k"
+	// TenantLabelsNone drops the tenant label, aggregating all tenants
+	TenantLabelsNone = "none"
+)
+
+// OtherTenants is the tenant_id label value of tenants outside the top K
+const OtherTenants = "other"
+
+// tenantRankInterval is how often the busiest tenants are re-ranked. Between
+// rankings a tenant keeps its label so series do not flap.
+const tenantRankInterval = time.Minute
+
+// TenantLabelPolicy decides which tenants get their own tenant_id series
+type TenantLabelPolicy struct {
+	Mode string
+	TopK int
+}
+
+func (p TenantLabelPolicy) validate() error {
+	switch p.Mode {
+	case TenantLabelsAll, TenantLabelsNone:
+		return nil
+	case TenantLabelsTopK:
+		if p.TopK < 1 {
+			return fmt.Errorf("top_k must be at least 1")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown tenant label policy %q, expected all, top_k or none", p.Mode)
+	}
+}
+
+// TenantLabeler maps tenant IDs to tenant_id label values under a default
+// policy and per-metric overrides, ranking tenants by message volume
+type TenantLabeler struct {
+	mu        sync.Mutex
+	policy    TenantLabelPolicy
+	overrides map[string]TenantLabelPolicy
+
+	counts   map[string]int64
+	scores   map[string]float64
+	rank     map[string]int
+	rankedAt time.Time
+}
+
+// Tenants is the labeler every tenant-labeled metric goes through
+var Tenants = &TenantLabeler{
+	policy: TenantLabelPolicy{Mode: TenantLabelsAll},
+	counts: make(map[string]int64),
+	scores: make(map[string]float64),
+	rank:   make(map[string]int),
+}
+
+// ConfigureTenantLabels sets the default policy and the per-metric overrides,
+// keyed by metric name
+func ConfigureTenantLabels(policy TenantLabelPolicy, overrides map[string]TenantLabelPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	for metric, override := range overrides {
+		if err := override.validate(); err != nil {
+			return fmt.Errorf("%s: %w", metric, err)
+		}
+	}
+
+	Tenants.mu.Lock()
+	defer Tenants.mu.Unlock()
+	Tenants.policy = policy
+	Tenants.overrides = overrides
+	return nil
+}
+
+// Observe counts one message for the tenant's top-K ranking
+func (l *TenantLabeler) Observe(tenantID string) {
+	l.mu.Lock()
+	l.counts[tenantID]++
+	l.mu.Unlock()
+}
+
+// Label returns the tenant_id label value to use for tenantID on metric
+func (l *TenantLabeler) Label(metric, tenantID string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	policy := l.policy
+	if override, ok := l.overrides[metric]; ok {
+		policy = override
+	}
+	switch policy.Mode {
+	case TenantLabelsNone:
+		return ""
+	case TenantLabelsTopK:
+		if time.Since(l.rankedAt) >= tenantRankInterval {
+			l.rerank()
+		}
+		if rank, ok := l.rank[tenantID]; ok && rank < policy.TopK {
+			return tenantID
+		}
+		return OtherTenants
+	default:
+		return tenantID
+	}
+}
+
+// rerank orders tenants by an exponentially decayed message count, so the
+// ranking follows recent traffic without reacting to a single burst
+func (l *TenantLabeler) rerank() {
+	for tenantID, score := range l.scores {
+		score /= 2
+		if score < 0.01 {
+			delete(l.scores, tenantID)
+			continue
+		}
+		l.scores[tenantID] = score
+	}
+	for tenantID, n := range l.counts {
+		l.scores[tenantID] += float64(n)
+	}
+	l.counts = make(map[string]int64)
+
+	tenantIDs := make([]string, 0, len(l.scores))
+	for tenantID := range l.scores {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Slice(tenantIDs, func(i, j int) bool {
+		if l.scores[tenantIDs[i]] != l.scores[tenantIDs[j]] {
+			return l.scores[tenantIDs[i]] > l.scores[tenantIDs[j]]
+		}
+		return tenantIDs[i] < tenantIDs[j]
+	})
+	l.rank = make(map[string]int, len(tenantIDs))
+	for i, tenantID := range tenantIDs {
+		l.rank[tenantID] = i
+	}
+	l.rankedAt = time.Now()
+}
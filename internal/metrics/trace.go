@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceparentHeader is the W3C trace context header, used both on HTTP
+// requests and as an AMQP message header
+const TraceparentHeader = "traceparent"
+
+type traceIDKey struct{}
+
+// WithTraceID returns a copy of ctx carrying traceID
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID of the span in ctx, or else the
+// one stored by WithTraceID, or ""
+func TraceIDFromContext(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// ParseTraceparent returns the trace ID of a W3C traceparent value
+// ("00-<trace-id>-<parent-id>-<flags>"), or "" if it is malformed
+func ParseTraceparent(value string) string {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ""
+	}
+	traceID := strings.ToLower(parts[1])
+	if _, err := hex.DecodeString(traceID); err != nil || traceID == strings.Repeat("0", 32) {
+		return ""
+	}
+	return traceID
+}
+
+// NewTraceparent starts a new sampled trace and returns its trace ID and
+// traceparent header value
+func NewTraceparent() (traceID, traceparent string) {
+	b := make([]byte, 24)
+	rand.Read(b)
+	traceID = hex.EncodeToString(b[:16])
+	return traceID, "00-" + traceID + "-" + hex.EncodeToString(b[16:]) + "-01"
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the service's own spans
+const TracerName = "multi-tenant-messaging"
+
+// TracingOptions configures the OTLP span exporter
+type TracingOptions struct {
+	Endpoint    string
+	Insecure    bool
+	Headers     map[string]string
+	ServiceName string
+	InstanceID  string
+	SampleRatio float64
+}
+
+// StartTracing installs a tracer provider exporting spans over OTLP/HTTP and
+// the W3C trace context propagator, so traceparent headers on API requests
+// and AMQP messages join their spans to the caller's trace. The returned
+// function flushes the spans still buffered and stops the exporter.
+func StartTracing(ctx context.Context, opts TracingOptions) (func(context.Context) error, error) {
+	exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+	}
+	if len(opts.Headers) > 0 {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithHeaders(opts.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", opts.ServiceName),
+		attribute.String("service.instance.id", opts.InstanceID),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the service's own spans. Without StartTracing
+// it is a no-op tracer.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// EndSpan marks span failed when err is not nil and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// HeaderCarrier carries trace context in AMQP message headers
+type HeaderCarrier map[string]interface{}
+
+// Get returns the header value of key if it is a string
+func (h HeaderCarrier) Get(key string) string {
+	value, _ := h[key].(string)
+	return value
+}
+
+// Set stores a header value
+func (h HeaderCarrier) Set(key, value string) {
+	h[key] = value
+}
+
+// Keys lists the header names
+func (h HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InjectHeaders writes the trace context of ctx into AMQP headers
+func InjectHeaders(ctx context.Context, headers map[string]interface{}) {
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(headers))
+}
+
+// ExtractHeaders returns ctx joined to the trace carried in AMQP headers
+func ExtractHeaders(ctx context.Context, headers map[string]interface{}) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier(headers))
+}
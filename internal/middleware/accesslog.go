@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveHeaders are never logged in clear
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+// AccessLogSettings are the parts of the access log that can change at runtime
+type AccessLogSettings struct {
+	// SampleRate is the share of requests logged on routes without an override
+	SampleRate float64 `json:"sample_rate"`
+	// Routes override the sample rate per route, keyed "METHOD /route/:template";
+	// 0 turns a route off and 1 logs every request
+	Routes map[string]float64 `json:"routes"`
+}
+
+// Validate checks that every rate is between 0 and 1
+func (s AccessLogSettings) Validate() error {
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+	for route, rate := range s.Routes {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sample rate of %s must be between 0 and 1", route)
+		}
+	}
+	return nil
+}
+
+// AccessLog writes sampled requests and responses, with bodies cut to a size
+// limit and sensitive fields masked, as JSON lines to stdout. It serves the
+// Gin router and plain net/http handlers alike. A nil AccessLog logs nothing.
+type AccessLog struct {
+	logger  *slog.Logger
+	maxBody int
+	fields  *regexp.Regexp
+
+	mu       sync.RWMutex
+	settings AccessLogSettings
+}
+
+// NewAccessLog creates an AccessLog. Values of JSON fields named in
+// redactFields are masked in bodies wherever they occur.
+func NewAccessLog(settings AccessLogSettings, maxBody int, redactFields []string) (*AccessLog, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	l := &AccessLog{
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		maxBody: maxBody,
+	}
+	if len(redactFields) > 0 {
+		quoted := make([]string, len(redactFields))
+		for i, field := range redactFields {
+			quoted[i] = regexp.QuoteMeta(field)
+		}
+		// Juga bekerja pada body yang terpotong dan bukan JSON yang valid lagi
+		l.fields = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+	l.Set(settings)
+	return l, nil
+}
+
+// Settings returns the current sampling settings
+func (l *AccessLog) Settings() AccessLogSettings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	routes := make(map[string]float64, len(l.settings.Routes))
+	for route, rate := range l.settings.Routes {
+		routes[route] = rate
+	}
+	return AccessLogSettings{SampleRate: l.settings.SampleRate, Routes: routes}
+}
+
+// Set replaces the sampling settings
+func (l *AccessLog) Set(settings AccessLogSettings) {
+	routes := make(map[string]float64, len(settings.Routes))
+	for route, rate := range settings.Routes {
+		// Kunci dari viper sudah menjadi huruf kecil
+		method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+		routes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = rate
+	}
+	settings.Routes = routes
+	l.mu.Lock()
+	l.settings = settings
+	l.mu.Unlock()
+}
+
+func (l *AccessLog) sampled(route string) bool {
+	l.mu.RLock()
+	rate, ok := l.settings.Routes[route]
+	if !ok {
+		rate = l.settings.SampleRate
+	}
+	l.mu.RUnlock()
+	return rate > 0 && (rate >= 1 || rand.Float64() < rate)
+}
+
+// Gin logs the sampled requests of the router, per route template
+func (l *AccessLog) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		route = c.Request.Method + " " + route
+		if !l.sampled(route) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		request := l.captureRequest(c.Request)
+		recorder := &ginRecorder{ResponseWriter: c.Writer, body: limitedBuffer{limit: l.maxBody}}
+		c.Writer = recorder
+
+		c.Next()
+
+		l.write("gin", route, c.Request, c.ClientIP(), c.Writer.Status(), time.Since(start), request, &recorder.body, c.Writer.Header())
+	}
+}
+
+// Wrap logs the sampled requests of a plain net/http handler, per path.
+// stack names the server in the log lines.
+func (l *AccessLog) Wrap(stack string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		if !l.sampled(route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		request := l.captureRequest(r)
+		recorder := &httpRecorder{ResponseWriter: w, status: http.StatusOK, body: limitedBuffer{limit: l.maxBody}}
+
+		next.ServeHTTP(recorder, r)
+
+		l.write(stack, route, r, r.RemoteAddr, recorder.status, time.Since(start), request, &recorder.body, w.Header())
+	})
+}
+
+// captureRequest reads up to the body limit from r and puts it back in front
+// of the rest of the body for the handler
+func (l *AccessLog) captureRequest(r *http.Request) *limitedBuffer {
+	captured := &limitedBuffer{limit: l.maxBody}
+	if r.Body == nil || r.Body == http.NoBody || l.maxBody <= 0 {
+		return captured
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, int64(l.maxBody)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err == nil {
+		captured.Write(head)
+	}
+	if r.ContentLength > int64(captured.size) {
+		captured.size = int(r.ContentLength)
+	}
+	return captured
+}
+
+func (l *AccessLog) write(stack, route string, r *http.Request, clientIP string, status int, duration time.Duration, request, response *limitedBuffer, responseHeader http.Header) {
+	attrs := []any{
+		slog.String("stack", stack),
+		slog.String("route", route),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("query", r.URL.RawQuery),
+		slog.Int("status", status),
+		slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+		slog.String("client_ip", clientIP),
+		slog.Any("request_headers", l.headers(r.Header)),
+		slog.Any("request_body", l.body(request)),
+		slog.Any("response_headers", l.headers(responseHeader)),
+		slog.Any("response_body", l.body(response)),
+	}
+	if traceID := metrics.TraceIDFromContext(r.Context()); traceID != "" {
+		attrs = append(attrs, slog.String("trace_id", traceID))
+	}
+	if requestID := RequestIDFromContext(r.Context()); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	l.logger.Info("access", attrs...)
+}
+
+func (l *AccessLog) headers(header http.Header) map[string]string {
+	logged := make(map[string]string, len(header))
+	for name, values := range header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			logged[name] = redact.MaskValue
+			continue
+		}
+		logged[name] = strings.Join(values, ", ")
+	}
+	return logged
+}
+
+// body returns the captured body with sensitive fields masked; binary bodies
+// are only described
+func (l *AccessLog) body(captured *limitedBuffer) map[string]any {
+	data := captured.buf.Bytes()
+	logged := map[string]any{"size": captured.size, "truncated": captured.size > len(data)}
+	if len(data) == 0 {
+		return logged
+	}
+	if captured.size > len(data) {
+		// Karakter multi-byte bisa terpotong di batas
+		for cut := 1; cut < utf8.UTFMax && !utf8.Valid(data) && cut < len(data); cut++ {
+			if utf8.Valid(data[:len(data)-cut]) {
+				data = data[:len(data)-cut]
+			}
+		}
+	}
+	if !utf8.Valid(data) {
+		logged["binary"] = true
+		return logged
+	}
+	text := string(data)
+	if l.fields != nil {
+		text = l.fields.ReplaceAllString(text, `${1}"`+redact.MaskValue+`"`)
+	}
+	logged["content"] = text
+	return logged
+}
+
+// limitedBuffer keeps the first limit bytes written to it and counts the rest
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	size  int
+}
+
+func (b *limitedBuffer) Write(p []byte) {
+	b.size += len(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+}
+
+type ginRecorder struct {
+	gin.ResponseWriter
+	body limitedBuffer
+}
+
+func (w *ginRecorder) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *ginRecorder) WriteString(s string) (int, error) {
+	w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+type httpRecorder struct {
+	http.ResponseWriter
+	status int
+	body   limitedBuffer
+}
+
+func (w *httpRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *httpRecorder) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *httpRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
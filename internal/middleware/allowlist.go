@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"multi-tenant-messaging/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPAllowlist rejects requests to tenant-scoped routes (":id" param) whose
+// client IP is not in the tenant's allowlist
+func IPAllowlist(allowlists *service.AllowlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.Param("id")
+		if tenantID == "" {
+			c.Next()
+			return
+		}
+
+		allowed, err := allowlists.IsAllowed(tenantID, net.ParseIP(c.ClientIP()))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "source IP not allowed for tenant"})
+			return
+		}
+
+		c.Next()
+	}
+}
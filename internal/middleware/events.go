@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"multi-tenant-messaging/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigChangeEvents emits tenant.config_changed after every successful PUT
+// to a tenant-scoped route. Those routes all replace a piece of the tenant's
+// configuration, named in the event by its path below /tenants/:id/.
+func ConfigChangeEvents(emitter *events.Emitter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Request.Method != http.MethodPut || c.Writer.Status() >= http.StatusMultipleChoices {
+			return
+		}
+		tenantID := c.Param("id")
+		if tenantID == "" {
+			return
+		}
+		emitter.Emit(events.TypeTenantConfigChanged, tenantID, map[string]interface{}{
+			"setting": strings.TrimPrefix(c.FullPath(), "/tenants/:id/"),
+		})
+	}
+}
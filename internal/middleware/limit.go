@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"multi-tenant-messaging/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimit lets at most maxInFlight requests through at once. Up to
+// maxQueued further requests wait up to queueTimeout for a free slot; the
+// rest are shed with 503 and a Retry-After header. The limit is shared by
+// every route the returned handler is attached to.
+func ConcurrencyLimit(maxInFlight, maxQueued int, queueTimeout time.Duration) gin.HandlerFunc {
+	slots := make(chan struct{}, maxInFlight)
+	var queued atomic.Int64
+	retryAfter := strconv.Itoa(max(1, int(queueTimeout.Round(time.Second).Seconds())))
+
+	shed := func(c *gin.Context) {
+		metrics.ObserveShed(c.FullPath())
+		c.Header("Retry-After", retryAfter)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is busy, retry later"})
+	}
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			if queued.Add(1) > int64(maxQueued) {
+				queued.Add(-1)
+				shed(c)
+				return
+			}
+			timer := time.NewTimer(queueTimeout)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+				queued.Add(-1)
+			case <-timer.C:
+				queued.Add(-1)
+				shed(c)
+				return
+			case <-c.Request.Context().Done():
+				// Klien sudah pergi, tidak perlu dijawab
+				timer.Stop()
+				queued.Add(-1)
+				c.Abort()
+				return
+			}
+		}
+		defer func() { <-slots }()
+
+		c.Next()
+	}
+}
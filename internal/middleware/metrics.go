@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"multi-tenant-messaging/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestMetrics records rate, errors and duration per route and opens a
+// server span per request when tracing is enabled. The trace ID of the
+// request's traceparent header, or of a new trace when it has none, is
+// attached as exemplar and echoed back in the traceparent response header.
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		// Route template, bukan path asli, supaya label tidak meledak per tenant
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := metrics.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+
+		var traceID string
+		if span.SpanContext().IsValid() {
+			traceID = span.SpanContext().TraceID().String()
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		} else {
+			// Tracing mati: tetap teruskan atau buat traceparent untuk exemplar
+			traceID = metrics.ParseTraceparent(c.GetHeader(metrics.TraceparentHeader))
+			if traceID == "" {
+				var traceparent string
+				traceID, traceparent = metrics.NewTraceparent()
+				c.Header(metrics.TraceparentHeader, traceparent)
+			} else {
+				c.Header(metrics.TraceparentHeader, c.GetHeader(metrics.TraceparentHeader))
+			}
+		}
+		c.Request = c.Request.WithContext(metrics.WithTraceID(ctx, traceID))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		metrics.ObserveRequest(route, c.Request.Method, strconv.Itoa(status),
+			status >= http.StatusInternalServerError, time.Since(start), traceID)
+	}
+}
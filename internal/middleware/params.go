@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UUIDParam rejects requests whose path parameter name is not a UUID with
+// 400, so handlers never hand Postgres an ID that fails the uuid cast
+func UUIDParam(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := uuid.Parse(c.Param(name)); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + " format"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"regexp"
+
+	"multi-tenant-messaging/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID accepts request IDs set by a proxy or the client; anything
+// else is replaced so it cannot inject into the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// RequestID keeps the request's X-Request-ID, or generates one, echoes it in
+// the response and adds it as request_id to everything logged with the
+// request's context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := context.WithValue(c.Request.Context(), requestIDKey{}, id)
+		c.Request = c.Request.WithContext(logging.With(ctx, "request_id", id))
+		c.Next()
+	}
+}
+
+// RequestIDFromContext returns the ID set by RequestID, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
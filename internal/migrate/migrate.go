@@ -0,0 +1,207 @@
+// Package migrate applies the embedded schema migrations with golang-migrate
+// and reports the schema status. Versions are kept in golang-migrate's
+// schema_migrations table, so the migrate CLI can be used on the same database.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"multi-tenant-messaging/internal/domain"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// ErrSchemaBehind is returned when the database is missing migrations this build needs
+var ErrSchemaBehind = errors.New("database schema is behind; run migrations")
+
+// ErrSchemaDirty is returned when a migration failed halfway and needs manual repair
+var ErrSchemaDirty = errors.New("database schema is dirty; a migration failed halfway")
+
+// Runner applies and reverts the NNN_name.up.sql and NNN_name.down.sql
+// migrations of source on db
+type Runner struct {
+	db     *sql.DB
+	source fs.FS
+}
+
+func New(db *sql.DB, source fs.FS) *Runner {
+	return &Runner{db: db, source: source}
+}
+
+// open returns a golang-migrate instance on a dedicated connection of the
+// pool, which holds the migration advisory lock. Closing it returns the
+// connection to the pool.
+func (r *Runner) open() (*migrate.Migrate, error) {
+	ctx := context.Background()
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	src, err := iofs.New(r.source, ".")
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, err
+	}
+	m.Log = logger{}
+	return m, nil
+}
+
+// current reads the applied version; a missing table means nothing is applied
+func (r *Runner) current() (uint, bool, error) {
+	var exists bool
+	if err := r.db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil || !exists {
+		return 0, false, err
+	}
+
+	var version uint
+	var dirty bool
+	err := r.db.QueryRow("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// migrations lists the migrations of source in order
+func (r *Runner) migrations() ([]domain.Migration, error) {
+	src, err := iofs.New(r.source, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var list []domain.Migration
+	version, err := src.First()
+	for err == nil {
+		list = append(list, domain.Migration{Version: version, Name: migrationName(src, version)})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// migrationName is the name part of the up file of version
+func migrationName(src source.Driver, version uint) string {
+	body, identifier, err := src.ReadUp(version)
+	if err != nil {
+		return ""
+	}
+	body.Close()
+	return identifier
+}
+
+// Status reports applied and pending migrations and the dirty flag
+func (r *Runner) Status() (*domain.MigrationStatus, error) {
+	migrations, err := r.migrations()
+	if err != nil {
+		return nil, err
+	}
+	version, dirty, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.MigrationStatus{Version: version, Dirty: dirty, Migrations: []domain.Migration{}}
+	for _, migration := range migrations {
+		migration.Applied = migration.Version <= version
+		if !migration.Applied {
+			status.Pending++
+		}
+		status.Latest = migration.Version
+		status.Migrations = append(status.Migrations, migration)
+	}
+	return status, nil
+}
+
+// Ready returns ErrSchemaBehind or ErrSchemaDirty unless the schema is at the
+// latest migration of this build
+func (r *Runner) Ready() error {
+	status, err := r.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w (version %d)", ErrSchemaDirty, status.Version)
+	}
+	if status.Pending > 0 {
+		return fmt.Errorf("%w (at %d, need %d)", ErrSchemaBehind, status.Version, status.Latest)
+	}
+	return nil
+}
+
+// Up applies all pending migrations in order. golang-migrate marks the
+// version dirty while a migration runs, so a crash halfway is visible.
+// Concurrent calls, also from other instances, wait for each other.
+func (r *Runner) Up() error {
+	m, err := r.open()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return schemaError(m.Up())
+}
+
+// Down reverts the last steps applied migrations, newest first, running their
+// NNN_name.down.sql files. Reverting more than are applied stops at none.
+func (r *Runner) Down(steps int) error {
+	m, err := r.open()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Steps(-steps)
+	var short migrate.ErrShortLimit
+	if errors.As(err, &short) {
+		return nil
+	}
+	return schemaError(err)
+}
+
+// schemaError maps golang-migrate's outcomes to the errors of this package
+func schemaError(err error) error {
+	var dirty migrate.ErrDirty
+	switch {
+	case err == nil, errors.Is(err, migrate.ErrNoChange), errors.Is(err, migrate.ErrNilVersion):
+		return nil
+	case errors.As(err, &dirty):
+		return fmt.Errorf("%w (version %d)", ErrSchemaDirty, dirty.Version)
+	default:
+		return err
+	}
+}
+
+// logger reports the migrations golang-migrate applies through slog
+type logger struct{}
+
+func (logger) Printf(format string, v ...interface{}) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (logger) Verbose() bool {
+	return false
+}
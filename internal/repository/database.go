@@ -1,45 +1,90 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"time"
+	"log/slog"
+	"maps"
+	"sync"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 type Database struct {
 	DB *sql.DB
-}
 
-func NewDatabase(url string) (*Database, error) {
-	log.Printf("Connecting to database with URL: %s", url)
+	rowLevelSecurity bool
+	failover         failover
 
-	var db *sql.DB
-	var err error
+	// rotated replaces the connection settings of new connections after Rotate
+	rotatedMu sync.Mutex
+	rotated   *pgconn.Config
+}
 
-	// Coba konek beberapa kali
-	for i := 0; i < 5; i++ {
-		db, err = sql.Open("postgres", url)
-		if err != nil {
-			log.Printf("Connection attempt %d failed: %v", i+1, err)
-			time.Sleep(2 * time.Second)
-			continue
-		}
+// NewDatabase connects through pgx's database/sql driver, retrying with
+// wait while the database is not up yet. tracer, if not nil, is called
+// around every query. url may list several hosts (host1:5432,host2:5432)
+// with target_session_attrs=read-write so connections only land on the
+// current primary.
+func NewDatabase(url string, tracer pgx.QueryTracer, wait Backoff) (*Database, error) {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %w", err)
+	}
+	// Hanya host dan nama database, URL bisa berisi password
+	slog.Info("Connecting to database", "host", config.Host, "database", config.Database)
+	if tracer != nil {
+		config.Tracer = tracer
+	}
 
-		err = db.Ping()
-		if err == nil {
-			log.Println("Successfully connected to database")
-			return &Database{DB: db}, nil
+	d := &Database{}
+	err = wait.Retry("database", func() error {
+		d.DB = stdlib.OpenDB(*config, stdlib.OptionBeforeConnect(d.beforeConnect), stdlib.OptionAfterConnect(d.afterConnect), stdlib.OptionResetSession(d.resetSession))
+		if err := d.DB.Ping(); err != nil {
+			d.DB.Close()
+			return err
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
 
-		log.Printf("Ping attempt %d failed: %v", i+1, err)
-		db.Close()
-		time.Sleep(2 * time.Second)
+	slog.Info("Successfully connected to database")
+	return d, nil
+}
+
+// Rotate makes new connections use url, e.g. after the credentials in it
+// were rotated. Pooled connections are closed once their query finishes.
+func (d *Database) Rotate(url string) error {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return fmt.Errorf("invalid database URL: %w", err)
 	}
 
-	return nil, fmt.Errorf("failed to connect to database after 5 attempts: %v", err)
+	d.rotatedMu.Lock()
+	d.rotated = &config.Config
+	d.rotatedMu.Unlock()
+
+	d.failover.mu.Lock()
+	d.recycleLocked()
+	d.failover.mu.Unlock()
+	slog.Info("Database credentials rotated")
+	return nil
+}
+
+// beforeConnect applies the URL of the last Rotate to a new connection
+func (d *Database) beforeConnect(_ context.Context, config *pgx.ConnConfig) error {
+	d.rotatedMu.Lock()
+	defer d.rotatedMu.Unlock()
+	if d.rotated != nil {
+		config.Config = *d.rotated
+		config.RuntimeParams = maps.Clone(d.rotated.RuntimeParams)
+	}
+	return nil
 }
 
 func (d *Database) Close() {
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// epochKey stores, in a connection's custom data, the failover epoch it was
+// opened in
+const epochKey = "salva.failover_epoch"
+
+// defaultMaxIdleConns is database/sql's default idle pool size
+const defaultMaxIdleConns = 2
+
+// failover tracks a primary switch. While one is in progress down is open;
+// it is closed once a connection reaches a writable primary again. Every
+// failover or credential rotation starts a new epoch, and pooled connections
+// from an older epoch are discarded instead of reused, since they may still
+// point at the old primary or use revoked credentials.
+type failover struct {
+	mu       sync.Mutex
+	epoch    int64
+	down     chan struct{}
+	interval time.Duration
+}
+
+// SetFailoverCheckInterval sets how often the database is polled for a
+// writable primary during a failover
+func (d *Database) SetFailoverCheckInterval(interval time.Duration) {
+	d.failover.mu.Lock()
+	defer d.failover.mu.Unlock()
+	d.failover.interval = interval
+}
+
+// FailedOver reports whether err means the primary went away or was demoted.
+// If so, a failover is started (unless one is already running): the pool
+// drops its connections and the database is polled until a primary accepts
+// writes again.
+func (d *Database) FailedOver(err error) bool {
+	if !isFailoverError(err) {
+		return false
+	}
+
+	f := &d.failover
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down != nil {
+		return true
+	}
+	f.down = make(chan struct{})
+	slog.Warn("Database failover detected", "error", err)
+
+	// Koneksi idle dibiarkan tertutup sampai primary baru ditemukan
+	d.recycleLocked()
+	go d.awaitPrimary(f.down)
+	return true
+}
+
+// WaitWritable blocks while a failover is in progress
+func (d *Database) WaitWritable(ctx context.Context) error {
+	d.failover.mu.Lock()
+	down := d.failover.down
+	d.failover.mu.Unlock()
+	if down == nil {
+		return nil
+	}
+
+	select {
+	case <-down:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ready fails while a failover is in progress or the database does not answer
+func (d *Database) Ready(ctx context.Context) error {
+	d.failover.mu.Lock()
+	failingOver := d.failover.down != nil
+	d.failover.mu.Unlock()
+	if failingOver {
+		return errors.New("database failover in progress")
+	}
+	return d.DB.PingContext(ctx)
+}
+
+// awaitPrimary polls until a new connection lands on a primary, then ends
+// the failover
+func (d *Database) awaitPrimary(down chan struct{}) {
+	d.failover.mu.Lock()
+	interval := d.failover.interval
+	d.failover.mu.Unlock()
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	started := time.Now()
+	for {
+		time.Sleep(interval)
+		var inRecovery bool
+		err := d.DB.QueryRow("SELECT pg_is_in_recovery()").Scan(&inRecovery)
+		if err == nil && !inRecovery {
+			break
+		}
+		if err != nil {
+			slog.Warn("Waiting for a writable database primary", "error", err)
+		}
+	}
+
+	d.DB.SetMaxIdleConns(defaultMaxIdleConns)
+	d.failover.mu.Lock()
+	d.failover.down = nil
+	d.failover.mu.Unlock()
+	close(down)
+	slog.Info("Database primary is writable again", "after", time.Since(started).Round(time.Millisecond))
+}
+
+// recycleLocked starts a new epoch and closes the idle connections; those in
+// use are discarded when they are next taken from the pool. The failover
+// mutex must be held.
+func (d *Database) recycleLocked() {
+	d.failover.epoch++
+	d.DB.SetMaxIdleConns(0)
+	if d.failover.down == nil {
+		d.DB.SetMaxIdleConns(defaultMaxIdleConns)
+	}
+}
+
+// afterConnect stamps a new connection with the current failover epoch
+func (d *Database) afterConnect(_ context.Context, conn *pgx.Conn) error {
+	d.failover.mu.Lock()
+	defer d.failover.mu.Unlock()
+	conn.PgConn().CustomData()[epochKey] = d.failover.epoch
+	return nil
+}
+
+// resetSession discards connections opened before the last failover
+func (d *Database) resetSession(_ context.Context, conn *pgx.Conn) error {
+	d.failover.mu.Lock()
+	defer d.failover.mu.Unlock()
+	if epoch, _ := conn.PgConn().CustomData()[epochKey].(int64); epoch != d.failover.epoch {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// isFailoverError matches errors raised while a primary is shut down,
+// demoted to a read-only standby or unreachable
+func isFailoverError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "25006", // read_only_sql_transaction
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Kelas 08: connection exception
+		return len(pgErr.Code) == 5 && pgErr.Code[:2] == "08"
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		pgconn.SafeToRetry(err)
+}
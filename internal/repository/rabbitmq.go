@@ -2,36 +2,413 @@ package repository
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"multi-tenant-messaging/internal/metrics"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// NodeSource returns the broker node URLs to dial, in order of preference
+type NodeSource func() ([]string, error)
+
+// StaticNodes always returns urls
+func StaticNodes(urls ...string) NodeSource {
+	return func() ([]string, error) {
+		return urls, nil
+	}
+}
+
+// SRVNodes looks up the SRV record name, e.g.
+// _amqp._tcp.rabbitmq.default.svc.cluster.local, on every dial. Each target
+// becomes a URL with the scheme, credentials and vhost of template.
+func SRVNodes(name, template string) NodeSource {
+	return func() ([]string, error) {
+		base, err := url.Parse(template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker URL template: %w", err)
+		}
+		_, records, err := net.LookupSRV("", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", name, err)
+		}
+		// LookupSRV sudah mengurutkan menurut priority dan mengacak menurut weight
+		urls := make([]string, len(records))
+		for i, record := range records {
+			node := *base
+			node.Host = net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+			urls[i] = node.String()
+		}
+		return urls, nil
+	}
+}
+
+// brokerConn is one connection of the pool and the number of channels open on it
+type brokerConn struct {
+	conn     *amqp.Connection
+	node     string
+	channels atomic.Int64
+}
+
+// RabbitMQ holds a small pool of connections to the broker cluster and a
+// shared channel on the first one. Channels opened with OpenChannel go to the
+// connection with the fewest open channels, so tenants do not all contend for
+// one TCP connection. When a connection drops, the next node is dialed until
+// one answers; the functions registered with OnReconnect then run so
+// consumers can be started again on the new connection.
 type RabbitMQ struct {
-	Conn    *amqp.Connection
-	Channel *amqp.Channel
+	nodes NodeSource
+
+	mu     sync.RWMutex
+	conns  []*brokerConn
+	ch     *amqp.Channel
+	closed bool
+
+	listenersMu sync.Mutex
+	onReconnect []func()
 }
 
+// NewRabbitMQ connects to a single broker URL
 func NewRabbitMQ(url string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(url)
+	return DialRabbitMQ(StaticNodes(url), 1, Backoff{})
+}
+
+// DialRabbitMQ opens size connections, each to the first node that answers
+// starting at a different node of nodes, and keeps reconnecting them to the
+// other nodes when they drop. The first connections are retried with wait
+// while no node is up yet.
+func DialRabbitMQ(nodes NodeSource, size int, wait Backoff) (*RabbitMQ, error) {
+	r := &RabbitMQ{nodes: nodes}
+	err := wait.Retry("RabbitMQ", func() error {
+		conns := make([]*brokerConn, 0, max(size, 1))
+		abort := func(err error) error {
+			for _, bc := range conns {
+				bc.conn.Close()
+			}
+			return err
+		}
+		for i := 0; i < max(size, 1); i++ {
+			conn, node, err := r.dial(i)
+			if err != nil {
+				return abort(err)
+			}
+			conns = append(conns, &brokerConn{conn: conn, node: node})
+		}
+
+		ch, err := r.openOn(conns[0])
+		if err != nil {
+			return abort(fmt.Errorf("failed to open channel: %v", err))
+		}
+		r.conns, r.ch = conns, ch
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+		return nil, err
 	}
 
-	ch, err := conn.Channel()
+	for i, bc := range r.conns {
+		slog.Info("Successfully connected to RabbitMQ", "node", nodeHost(bc.node))
+		go r.watch(i)
+	}
+	return r, nil
+}
+
+// WrapRabbitMQ uses an existing connection and channel without reconnecting
+func WrapRabbitMQ(conn *amqp.Connection, ch *amqp.Channel) *RabbitMQ {
+	return &RabbitMQ{conns: []*brokerConn{{conn: conn}}, ch: ch}
+}
+
+// Conn returns the first connection of the pool
+func (r *RabbitMQ) Conn() *amqp.Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.conns[0].conn
+}
+
+// Channel returns the shared channel on the first connection
+func (r *RabbitMQ) Channel() *amqp.Channel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ch
+}
+
+// OpenChannel opens a channel on the pool connection with the fewest open
+// channels
+func (r *RabbitMQ) OpenChannel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	var least *brokerConn
+	for _, bc := range r.conns {
+		if bc.conn.IsClosed() {
+			continue
+		}
+		if least == nil || bc.channels.Load() < least.channels.Load() {
+			least = bc
+		}
+	}
+	r.mu.RUnlock()
+	if least == nil {
+		return nil, amqp.ErrClosed
+	}
+	return r.openOn(least)
+}
+
+// openOn opens a channel on bc and counts it until it is closed
+func (r *RabbitMQ) openOn(bc *brokerConn) (*amqp.Channel, error) {
+	ch, err := OpenChannel(bc.conn)
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %v", err)
+		return nil, err
+	}
+	bc.channels.Add(1)
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		for range closed {
+		}
+		bc.channels.Add(-1)
+	}()
+	return ch, nil
+}
+
+// Connected reports whether every connection of the pool is open
+func (r *RabbitMQ) Connected() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, bc := range r.conns {
+		if bc.conn.IsClosed() {
+			return false
+		}
+	}
+	return true
+}
+
+// ConnectionState describes one connection of the pool
+type ConnectionState struct {
+	Node     string
+	Open     bool
+	Channels int64
+}
+
+// Connections returns the state of every connection of the pool, with the
+// credentials stripped from the node URLs
+func (r *RabbitMQ) Connections() []ConnectionState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	states := make([]ConnectionState, len(r.conns))
+	for i, bc := range r.conns {
+		states[i] = ConnectionState{Node: nodeHost(bc.node), Open: !bc.conn.IsClosed(), Channels: bc.channels.Load()}
 	}
+	return states
+}
 
-	log.Println("Successfully connected to RabbitMQ")
-	return &RabbitMQ{
-		Conn:    conn,
-		Channel: ch,
-	}, nil
+// OnReconnect registers fn to run after a connection was replaced
+func (r *RabbitMQ) OnReconnect(fn func()) {
+	r.listenersMu.Lock()
+	defer r.listenersMu.Unlock()
+	r.onReconnect = append(r.onReconnect, fn)
 }
 
 func (r *RabbitMQ) Close() {
-	r.Channel.Close()
-	r.Conn.Close()
+	r.mu.Lock()
+	r.closed = true
+	conns, ch := r.conns, r.ch
+	r.mu.Unlock()
+
+	if ch != nil {
+		ch.Close()
+	}
+	for _, bc := range conns {
+		bc.conn.Close()
+	}
+}
+
+// Rotate switches to nodes, e.g. after the credentials in their URLs were
+// rotated, by dialing a replacement for every pool connection. New channels
+// are opened on the replacements; the replaced connections are returned so
+// the caller can move its consumers over before closing them. On error
+// nothing is replaced.
+func (r *RabbitMQ) Rotate(nodes NodeSource) ([]*amqp.Connection, error) {
+	r.mu.Lock()
+	previous := r.nodes
+	r.nodes = nodes
+	size := len(r.conns)
+	r.mu.Unlock()
+
+	replacements := make([]*brokerConn, 0, size)
+	abort := func(err error) ([]*amqp.Connection, error) {
+		for _, bc := range replacements {
+			bc.conn.Close()
+		}
+		r.mu.Lock()
+		r.nodes = previous
+		r.mu.Unlock()
+		return nil, err
+	}
+	for i := 0; i < size; i++ {
+		conn, node, err := r.dial(i)
+		if err != nil {
+			return abort(err)
+		}
+		replacements = append(replacements, &brokerConn{conn: conn, node: node})
+	}
+	ch, err := r.openOn(replacements[0])
+	if err != nil {
+		return abort(fmt.Errorf("failed to open channel: %v", err))
+	}
+
+	r.mu.Lock()
+	replaced := make([]*amqp.Connection, size)
+	for i, bc := range r.conns {
+		replaced[i] = bc.conn
+	}
+	copy(r.conns, replacements)
+	r.ch = ch
+	r.mu.Unlock()
+	slog.Info("RabbitMQ credentials rotated")
+	return replaced, nil
+}
+
+// dial tries the nodes in order, starting at the given offset
+func (r *RabbitMQ) dial(start int) (*amqp.Connection, string, error) {
+	r.mu.RLock()
+	nodes := r.nodes
+	r.mu.RUnlock()
+	urls, err := nodes()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(urls) == 0 {
+		return nil, "", fmt.Errorf("failed to connect to RabbitMQ: no broker nodes")
+	}
+
+	var lastErr error
+	for i := range urls {
+		u := urls[(start+i)%len(urls)]
+		conn, err := amqp.Dial(u)
+		if err != nil {
+			slog.Warn("Failed to connect to RabbitMQ", "node", nodeHost(u), "error", err)
+			lastErr = err
+			continue
+		}
+		return conn, u, nil
+	}
+	return nil, "", fmt.Errorf("failed to connect to RabbitMQ: %v", lastErr)
+}
+
+// nodeAfter returns the offset of the node after failed in the current node list
+func (r *RabbitMQ) nodeAfter(failed string) int {
+	r.mu.RLock()
+	nodes := r.nodes
+	r.mu.RUnlock()
+	urls, err := nodes()
+	if err != nil {
+		return 0
+	}
+	for i, u := range urls {
+		if u == failed {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// watch waits for the i-th connection to drop and replaces it, then keeps
+// watching the new one
+func (r *RabbitMQ) watch(i int) {
+	for {
+		r.mu.RLock()
+		bc := r.conns[i]
+		r.mu.RUnlock()
+
+		amqpErr, ok := <-bc.conn.NotifyClose(make(chan *amqp.Error, 1))
+		r.mu.RLock()
+		closed, rotated := r.closed, r.conns[i] != bc
+		r.mu.RUnlock()
+		if closed {
+			return
+		}
+		if rotated {
+			// Ditutup setelah Rotate; awasi koneksi penggantinya
+			continue
+		}
+		if ok {
+			slog.Warn("RabbitMQ connection lost", "node", nodeHost(bc.node), "error", amqpErr)
+		}
+
+		backoff := time.Second
+		for {
+			replacement, err := r.reconnect(i, bc.node)
+			if err == nil {
+				r.mu.Lock()
+				r.conns[i] = replacement
+				r.mu.Unlock()
+				slog.Info("Reconnected to RabbitMQ", "node", nodeHost(replacement.node))
+				break
+			}
+			slog.Warn("Failed to reconnect to RabbitMQ", "error", err, "retry_in", backoff)
+			// Jitter supaya semua instance tidak menyerbu node yang sama bersamaan
+			time.Sleep(backoff + time.Duration(rand.Int63n(int64(backoff/2))))
+			backoff = min(backoff*2, 30*time.Second)
+		}
+
+		r.listenersMu.Lock()
+		listeners := append([]func(){}, r.onReconnect...)
+		r.listenersMu.Unlock()
+		for _, fn := range listeners {
+			fn()
+		}
+	}
+}
+
+// reconnect dials a replacement for the i-th connection, starting after the
+// node that failed. The first connection also gets a new shared channel.
+func (r *RabbitMQ) reconnect(i int, failed string) (*brokerConn, error) {
+	conn, node, err := r.dial(r.nodeAfter(failed))
+	if err != nil {
+		return nil, err
+	}
+	bc := &brokerConn{conn: conn, node: node}
+	if i == 0 {
+		ch, err := r.openOn(bc)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to open channel: %v", err)
+		}
+		r.mu.Lock()
+		r.ch = ch
+		r.mu.Unlock()
+	}
+	return bc, nil
+}
+
+// nodeHost strips credentials from a broker URL for logging
+func nodeHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "broker"
+	}
+	return parsed.Host
+}
+
+// OpenChannel opens a channel on conn and counts it in the open channel
+// gauge until it is closed, by the client or the broker
+func OpenChannel(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	metrics.ChannelOpened()
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		for range closed {
+		}
+		metrics.ChannelClosed()
+	}()
+	return ch, nil
 }
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Querier is implemented by both *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// tenantRole is the role without BYPASSRLS that tenant-scoped transactions
+// switch to, so the tenant_isolation policy applies to them. The service
+// itself connects as a role with BYPASSRLS, since background jobs and
+// operators work across tenants. Migration 048 creates it.
+const tenantRole = "salva_tenant"
+
+// setTenantScope limits the rest of a transaction to the tenant's rows
+const setTenantScope = "SELECT set_config('role', '" + tenantRole + "', true), set_config('app.tenant_id', $1, true)"
+
+// ErrNoBypassRLS is returned when row level security is enabled but the
+// service's database user would be limited by the policy itself
+var ErrNoBypassRLS = errors.New("database user needs BYPASSRLS when row level security is enabled")
+
+// ConfigureRowLevelSecurity enables or disables RLS on the messages table.
+// FORCE is used so the policy also applies to the table owner. Enabling it
+// requires the service's database user to have BYPASSRLS (or be a
+// superuser), otherwise system jobs would see no rows.
+func (d *Database) ConfigureRowLevelSecurity(enabled bool) error {
+	if enabled {
+		var bypass bool
+		err := d.DB.QueryRow("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypass)
+		if err != nil {
+			return fmt.Errorf("failed to check row level security bypass: %w", err)
+		}
+		if !bypass {
+			return ErrNoBypassRLS
+		}
+	}
+
+	stmt := "ALTER TABLE messages DISABLE ROW LEVEL SECURITY"
+	if enabled {
+		stmt = "ALTER TABLE messages ENABLE ROW LEVEL SECURITY; ALTER TABLE messages FORCE ROW LEVEL SECURITY"
+	}
+	if _, err := d.DB.Exec(stmt); err != nil {
+		return fmt.Errorf("failed to configure row level security: %w", err)
+	}
+	d.rowLevelSecurity = enabled
+	return nil
+}
+
+// RowLevelSecurity reports whether RLS limits tenant-bound sessions to their rows
+func (d *Database) RowLevelSecurity() bool {
+	return d.rowLevelSecurity
+}
+
+// WithTenant runs fn scoped to tenantID. When row level security is enabled
+// fn runs in a transaction with app.tenant_id set, so the RLS policy limits
+// it to the tenant's rows; otherwise fn runs directly on the pool.
+func (d *Database) WithTenant(ctx context.Context, tenantID string, fn func(q Querier) error) error {
+	if !d.rowLevelSecurity || tenantID == "" {
+		return fn(d.DB)
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, setTenantScope, tenantID); err != nil {
+		return fmt.Errorf("failed to set tenant scope: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithTenantTx is like WithTenant but always runs fn in a transaction, for
+// callers that need several statements to commit atomically
+func (d *Database) WithTenantTx(ctx context.Context, tenantID string, fn func(q Querier) error) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if d.rowLevelSecurity && tenantID != "" {
+		if _, err := tx.ExecContext(ctx, setTenantScope, tenantID); err != nil {
+			return fmt.Errorf("failed to set tenant scope: %w", err)
+		}
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
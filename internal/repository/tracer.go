@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"multi-tenant-messaging/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Query operations used as metric label and span name
+const (
+	QueryInsert = "insert"
+	QueryList   = "list"
+	QueryDDL    = "ddl"
+	QueryOther  = "other"
+)
+
+// QueryTracer is a pgx.QueryTracer that records latency, rows and errors of
+// every query, and optionally opens a span per query
+type QueryTracer struct {
+	spans  bool
+	tracer trace.Tracer
+}
+
+func NewQueryTracer(spans bool) *QueryTracer {
+	return &QueryTracer{spans: spans, tracer: otel.Tracer("multi-tenant-messaging/repository")}
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	at        time.Time
+	operation string
+	span      trace.Span
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	start := &queryStart{at: time.Now(), operation: QueryOperation(data.SQL)}
+	if t.spans {
+		ctx, start.span = t.tracer.Start(ctx, "db."+start.operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.statement", data.SQL),
+			))
+	}
+	return context.WithValue(ctx, queryStartKey{}, start)
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(*queryStart)
+	if !ok {
+		return
+	}
+	rows := data.CommandTag.RowsAffected()
+	metrics.ObserveQuery(ctx, start.operation, time.Since(start.at), rows, data.Err)
+
+	if start.span != nil {
+		start.span.SetAttributes(attribute.Int64("db.rows", rows))
+		if data.Err != nil {
+			start.span.RecordError(data.Err)
+			start.span.SetStatus(codes.Error, data.Err.Error())
+		}
+		start.span.End()
+	}
+}
+
+// QueryOperation classifies a statement by its leading keyword. Statements
+// starting with WITH count as list unless they insert.
+func QueryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return QueryOther
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "INSERT", "COPY":
+		return QueryInsert
+	case "SELECT":
+		return QueryList
+	case "WITH":
+		if strings.Contains(strings.ToUpper(sql), "INSERT INTO") {
+			return QueryInsert
+		}
+		return QueryList
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "COMMENT":
+		return QueryDDL
+	default:
+		return QueryOther
+	}
+}
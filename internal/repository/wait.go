@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// Backoff retries connecting to a dependency that may not be up yet, e.g.
+// when the service starts together with Postgres and RabbitMQ
+type Backoff struct {
+	// Initial is the delay after the first failed attempt; it doubles up to Max
+	Initial time.Duration
+	Max     time.Duration
+	// Deadline gives up this long after the first attempt (0 = single attempt)
+	Deadline time.Duration
+}
+
+// Retry calls connect until it succeeds or the deadline has passed
+func (b Backoff) Retry(name string, connect func() error) error {
+	deadline := time.Now().Add(b.Deadline)
+	delay := max(b.Initial, 100*time.Millisecond)
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%s not reachable after %d attempts: %w", name, attempt, err)
+		}
+
+		// Jitter supaya beberapa replica tidak mencoba bersamaan
+		wait := min(delay+time.Duration(rand.Int63n(int64(delay/2)+1)), remaining)
+		slog.Warn("Waiting for dependency", "name", name, "attempt", attempt, "error", err, "retry_in", wait.Round(time.Millisecond))
+		time.Sleep(wait)
+		if b.Max > 0 {
+			delay = min(delay*2, b.Max)
+		} else {
+			delay *= 2
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/repository"
+)
+
+// ErrInvalidCIDR is returned when an allowlist entry is neither a CIDR nor an IP
+var ErrInvalidCIDR = errors.New("invalid CIDR")
+
+// allowlistCacheTTL bounds how long another instance's allowlist change may take to apply
+const allowlistCacheTTL = 30 * time.Second
+
+type cachedAllowlist struct {
+	networks []*net.IPNet
+	loadedAt time.Time
+}
+
+// AllowlistService manages per-tenant source IP allowlists
+type AllowlistService struct {
+	db *repository.Database
+
+	mu    sync.RWMutex
+	cache map[string]cachedAllowlist
+}
+
+func NewAllowlistService(db *repository.Database) *AllowlistService {
+	return &AllowlistService{
+		db:    db,
+		cache: make(map[string]cachedAllowlist),
+	}
+}
+
+// GetAllowlist returns the tenant's allowed CIDRs
+func (s *AllowlistService) GetAllowlist(tenantID string) ([]string, error) {
+	rows, err := s.db.DB.Query(
+		"SELECT cidr::text FROM tenant_ip_allowlists WHERE tenant_id = $1 ORDER BY cidr", tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cidrs := make([]string, 0)
+	for rows.Next() {
+		var cidr string
+		if err := rows.Scan(&cidr); err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, rows.Err()
+}
+
+// SetAllowlist replaces the tenant's allowed CIDRs. Plain IPs are accepted
+// as single-host networks. An empty list allows every source address.
+func (s *AllowlistService) SetAllowlist(tenantID string, cidrs []string) ([]string, error) {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM tenant_ip_allowlists WHERE tenant_id = $1", tenantID); err != nil {
+		return nil, err
+	}
+	for _, network := range networks {
+		if _, err := tx.Exec(
+			"INSERT INTO tenant_ip_allowlists (tenant_id, cidr) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			tenantID, network.String(),
+		); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedAllowlist{networks: networks, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return s.GetAllowlist(tenantID)
+}
+
+// IsAllowed reports whether ip may access the tenant's endpoints
+func (s *AllowlistService) IsAllowed(tenantID string, ip net.IP) (bool, error) {
+	networks, err := s.networks(tenantID)
+	if err != nil {
+		return false, err
+	}
+	if len(networks) == 0 {
+		return true, nil
+	}
+	if ip == nil {
+		return false, nil
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *AllowlistService) networks(tenantID string) ([]*net.IPNet, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < allowlistCacheTTL {
+		return cached.networks, nil
+	}
+
+	cidrs, err := s.GetAllowlist(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedAllowlist{networks: networks, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return networks, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"multi-tenant-messaging/internal/auth"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidAPIKeyRequest is returned for out of range key lifetimes and grace periods
+	ErrInvalidAPIKeyRequest = errors.New("invalid API key request")
+	// ErrAPIKeyNotFound is returned when the tenant has no active API key with the ID
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+const (
+	// apiKeyPrefix starts every API key so leaked keys are easy to scan for
+	apiKeyPrefix = "salva_"
+	// apiKeyVisible is how much of a key is kept in clear to tell keys apart
+	apiKeyVisible = 12
+	// maxAPIKeyGrace bounds how long a rotated key stays valid
+	maxAPIKeyGrace = 7 * 24 * time.Hour
+)
+
+// APIKeyService creates, rotates and revokes tenant API keys and resolves
+// the keys requests authenticate with
+type APIKeyService struct {
+	db *repository.Database
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(db *repository.Database) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// hashAPIKey returns the stored form of key. Keys are random, so a plain
+// SHA-256 is enough and lets a key be looked up by its hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create issues a new API key for tenantID. ttl 0 creates a key that does
+// not expire.
+func (s *APIKeyService) Create(tenantID, name, createdBy string, ttl time.Duration) (domain.APIKey, error) {
+	if ttl < 0 {
+		return domain.APIKey{}, fmt.Errorf("%w: ttl must not be negative", ErrInvalidAPIKeyRequest)
+	}
+	var exists bool
+	if err := s.db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)", tenantID).Scan(&exists); err != nil {
+		return domain.APIKey{}, err
+	}
+	if !exists {
+		return domain.APIKey{}, ErrTenantNotFound
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.APIKey{}, err
+	}
+	key := domain.APIKey{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		Name:      name,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		Key:       apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret),
+	}
+	key.Prefix = key.Key[:apiKeyVisible]
+	if ttl > 0 {
+		expiresAt := key.CreatedAt.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if _, err := s.db.DB.Exec(`
+		INSERT INTO tenant_api_keys (id, tenant_id, name, prefix, key_hash, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, key.ID, tenantID, name, key.Prefix, hashAPIKey(key.Key), createdBy, key.CreatedAt, key.ExpiresAt); err != nil {
+		return domain.APIKey{}, err
+	}
+	return key, nil
+}
+
+// List returns the tenant's API keys that have not expired, newest first
+func (s *APIKeyService) List(tenantID string) ([]domain.APIKey, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT id, tenant_id, name, prefix, created_by, created_at, expires_at, revoked_at
+		FROM tenant_api_keys
+		WHERE tenant_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]domain.APIKey, 0)
+	for rows.Next() {
+		var key domain.APIKey
+		var expiresAt, revokedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.TenantID, &key.Name, &key.Prefix, &key.CreatedBy, &key.CreatedAt, &expiresAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Rotate issues a replacement for the tenant's API key keyID with the same
+// name and remaining lifetime. The old key stays valid for grace so clients
+// can switch over, or is revoked right away when grace is 0.
+func (s *APIKeyService) Rotate(tenantID, keyID, createdBy string, grace time.Duration) (domain.APIKey, error) {
+	if grace < 0 || grace > maxAPIKeyGrace {
+		return domain.APIKey{}, fmt.Errorf("%w: grace must be between 0 and %s", ErrInvalidAPIKeyRequest, maxAPIKeyGrace)
+	}
+	if _, err := uuid.Parse(keyID); err != nil {
+		return domain.APIKey{}, ErrAPIKeyNotFound
+	}
+
+	var name string
+	var expiresAt sql.NullTime
+	err := s.db.DB.QueryRow(`
+		SELECT name, expires_at FROM tenant_api_keys
+		WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, tenantID, keyID).Scan(&name, &expiresAt)
+	if err == sql.ErrNoRows {
+		return domain.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	var ttl time.Duration
+	if expiresAt.Valid {
+		ttl = time.Until(expiresAt.Time)
+	}
+	key, err := s.Create(tenantID, name, createdBy, ttl)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	// Kunci lama berlaku sampai masa tenggang habis, tidak lebih lama dari masa berlakunya
+	if _, err := s.db.DB.Exec(`
+		UPDATE tenant_api_keys SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), NOW() + make_interval(secs => $3))
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, keyID, grace.Seconds()); err != nil {
+		return domain.APIKey{}, err
+	}
+	return key, nil
+}
+
+// Revoke rejects the tenant's API key from now on
+func (s *APIKeyService) Revoke(tenantID, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return ErrAPIKeyNotFound
+	}
+	res, err := s.db.DB.Exec(`
+		UPDATE tenant_api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, keyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Resolve returns the claims of a request authenticated with key: the key
+// binds the request to its tenant like a tenant-admin token. Unknown, expired
+// and revoked keys are rejected with auth.ErrInvalidToken.
+func (s *APIKeyService) Resolve(ctx context.Context, key string) (*auth.Claims, error) {
+	var keyID, tenantID string
+	err := s.db.DB.QueryRowContext(ctx, `
+		SELECT id, tenant_id FROM tenant_api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, hashAPIKey(key)).Scan(&keyID, &tenantID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: unknown, expired or revoked API key", auth.ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{Type: auth.TokenTypeAccess, TenantID: tenantID}
+	claims.Subject = "api-key:" + keyID
+	return claims, nil
+}
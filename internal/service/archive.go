@@ -0,0 +1,493 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var (
+	// ErrArchiveNotFound is returned when the tenant has no archive with the ID
+	ErrArchiveNotFound = errors.New("archive not found")
+	// ErrInvalidArchiveRestore is returned for a negative hold
+	ErrInvalidArchiveRestore = errors.New("invalid archive restore")
+)
+
+const (
+	// archiveRestoreBatch is how many archived messages are inserted per statement
+	archiveRestoreBatch = 1000
+	// archiveDefaultHold keeps restored messages from being archived again
+	archiveDefaultHold = 24 * time.Hour
+)
+
+// ArchiveOptions configures the bucket messages are archived to
+type ArchiveOptions struct {
+	// Endpoint is the host[:port] of S3 or MinIO
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix starts every object key
+	Prefix string
+	// AccessKey and SecretKey sign the requests; without them the AWS and
+	// MinIO environment variables and then the instance's IAM role are used
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// OlderThan is the age from which messages are archived, in whole UTC days
+	OlderThan time.Duration
+}
+
+// ArchiveService moves a tenant's old messages out of Postgres into gzipped
+// NDJSON objects, one per tenant and UTC day, recording each object in
+// message_archives. Messages are deleted in the transaction that records the
+// object, after it is uploaded, so a failed upload keeps them stored.
+type ArchiveService struct {
+	db     *repository.Database
+	client *minio.Client
+	opts   ArchiveOptions
+
+	bucketMu    sync.Mutex
+	bucketReady bool
+}
+
+// NewArchiveService creates an ArchiveService; the bucket is created on first use
+func NewArchiveService(db *repository.Database, opts ArchiveOptions) (*ArchiveService, error) {
+	creds := credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, "")
+	if opts.AccessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{},
+		})
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{Creds: creds, Secure: opts.UseSSL, Region: opts.Region})
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive endpoint: %w", err)
+	}
+	return &ArchiveService{db: db, client: client, opts: opts}, nil
+}
+
+// Run archives the messages of all tenants every interval until ctx is done
+func (s *ArchiveService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ArchiveAll(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to archive messages", "error", err)
+			}
+		}
+	}
+}
+
+// ArchiveAll archives the old messages of every tenant and returns the
+// number of messages archived
+func (s *ArchiveService) ArchiveAll(ctx context.Context) (int64, error) {
+	rows, err := s.db.DB.QueryContext(ctx, "SELECT id FROM tenants WHERE migrated_to IS NULL ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	tenantIDs, err := scanIDs(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, tenantID := range tenantIDs {
+		archives, err := s.ArchiveTenant(ctx, tenantID)
+		for _, archive := range archives {
+			slog.Info("Archived messages", "tenant_id", tenantID, "day", archive.Day, "count", archive.MessageCount, "object_key", archive.ObjectKey)
+			total += archive.MessageCount
+		}
+		if errors.Is(err, ErrPartitionNotFound) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return total, ctx.Err()
+			}
+			slog.Error("Failed to archive messages", "tenant_id", tenantID, "error", err)
+		}
+	}
+	return total, nil
+}
+
+// ArchiveTenant archives the tenant's messages stored on the days before
+// OlderThan ago, one object per day, skipping days held by a restore
+func (s *ArchiveService) ArchiveTenant(ctx context.Context, tenantID string) ([]domain.MessageArchive, error) {
+	attached, err := partitionAttached(ctx, s.db.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !attached {
+		return nil, ErrPartitionNotFound
+	}
+	if err := s.ensureBucket(ctx); err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().UTC().Add(-s.opts.OlderThan).Truncate(24 * time.Hour)
+	rows, err := s.db.DB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT (created_at AT TIME ZONE 'UTC')::date AS day FROM "%s"
+		WHERE created_at < $1 AND (created_at AT TIME ZONE 'UTC')::date NOT IN (
+			SELECT day FROM message_archives WHERE tenant_id = $2 AND held_until > NOW()
+		)
+		ORDER BY day
+	`, partitionName(tenantID)), cutoff, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var archives []domain.MessageArchive
+	for _, day := range days {
+		archive, err := s.archiveDay(ctx, tenantID, day)
+		if err != nil {
+			return archives, fmt.Errorf("failed to archive %s: %w", day.Format(time.DateOnly), err)
+		}
+		if archive != nil {
+			archives = append(archives, *archive)
+		}
+	}
+	return archives, nil
+}
+
+// archiveDay moves the tenant's messages of the UTC day to a new object. It
+// returns nil when the day has no messages left.
+func (s *ArchiveService) archiveDay(ctx context.Context, tenantID string, day time.Time) (*domain.MessageArchive, error) {
+	columns, err := messagesTableColumns(ctx, s.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp("", "salva-archive-*.ndjson.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	archive := &domain.MessageArchive{
+		ID:       uuid.New().String(),
+		TenantID: tenantID,
+		Day:      start.Format(time.DateOnly),
+		Bucket:   s.opts.Bucket,
+	}
+	archive.ObjectKey = path.Join(s.opts.Prefix, tenantID, archive.Day, archive.ID+".ndjson.gz")
+
+	uploaded := false
+	err = s.db.WithTenantTx(ctx, tenantID, func(q repository.Querier) error {
+		// Baris dihapus dulu dan baru hilang saat commit, setelah objeknya terunggah
+		partition := partitionName(tenantID)
+		rows, err := q.QueryContext(ctx, fmt.Sprintf(`
+			DELETE FROM "%s" WHERE created_at >= $1 AND created_at < $2 RETURNING to_jsonb("%s".*)
+		`, partition, partition), start, start.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		gz := gzip.NewWriter(file)
+		for rows.Next() {
+			var line []byte
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			if _, err := gz.Write(append(line, '\n')); err != nil {
+				return err
+			}
+			archive.MessageCount++
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if archive.MessageCount == 0 {
+			return nil
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		if archive.SizeBytes, err = file.Seek(0, io.SeekCurrent); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		_, err = s.client.PutObject(ctx, archive.Bucket, archive.ObjectKey, file, archive.SizeBytes, minio.PutObjectOptions{
+			ContentType:     "application/x-ndjson",
+			ContentEncoding: "gzip",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload archive: %w", err)
+		}
+		uploaded = true
+
+		columnsJSON, _ := json.Marshal(columns)
+		return q.QueryRowContext(ctx, `
+			INSERT INTO message_archives (id, tenant_id, day, bucket, object_key, message_count, size_bytes, columns)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at
+		`, archive.ID, tenantID, start, archive.Bucket, archive.ObjectKey, archive.MessageCount, archive.SizeBytes, columnsJSON).Scan(&archive.CreatedAt)
+	})
+	if err != nil {
+		// Pesan tetap tersimpan, objek yang tidak tercatat dihapus agar tidak ada dua salinan
+		if uploaded {
+			if rmErr := s.client.RemoveObject(context.Background(), archive.Bucket, archive.ObjectKey, minio.RemoveObjectOptions{}); rmErr != nil {
+				slog.Warn("Failed to remove unrecorded archive object", "object_key", archive.ObjectKey, "error", rmErr)
+			}
+		}
+		return nil, err
+	}
+	if archive.MessageCount == 0 {
+		return nil, nil
+	}
+	return archive, nil
+}
+
+// ListArchives returns the tenant's archives, newest day first
+func (s *ArchiveService) ListArchives(ctx context.Context, tenantID string) ([]domain.MessageArchive, error) {
+	rows, err := s.db.DB.QueryContext(ctx, `
+		SELECT id, tenant_id, day, bucket, object_key, message_count, size_bytes, created_at, restored_at, restored_count, held_until
+		FROM message_archives WHERE tenant_id = $1 ORDER BY day DESC, created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	archives := []domain.MessageArchive{}
+	for rows.Next() {
+		archive, err := scanArchive(rows)
+		if err != nil {
+			return nil, err
+		}
+		archives = append(archives, *archive)
+	}
+	return archives, rows.Err()
+}
+
+// Restore loads the archive back into the tenant's partition. Messages still
+// stored are skipped, so restoring twice is harmless. The archive's day is
+// not archived again for hold.
+func (s *ArchiveService) Restore(ctx context.Context, tenantID, archiveID string, hold time.Duration) (*domain.MessageArchive, error) {
+	if hold < 0 {
+		return nil, fmt.Errorf("%w: hold must not be negative", ErrInvalidArchiveRestore)
+	}
+	if hold == 0 {
+		hold = archiveDefaultHold
+	}
+	if _, err := uuid.Parse(archiveID); err != nil {
+		return nil, ErrArchiveNotFound
+	}
+	row := s.db.DB.QueryRowContext(ctx, `
+		UPDATE message_archives SET held_until = GREATEST(COALESCE(held_until, NOW()), NOW() + $3::bigint * INTERVAL '1 second')
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING id, tenant_id, day, bucket, object_key, message_count, size_bytes, created_at, restored_at, restored_count, held_until, columns
+	`, archiveID, tenantID, int64(hold/time.Second))
+	var columnsJSON []byte
+	archive, err := scanArchive(row, &columnsJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var archived []string
+	if err := json.Unmarshal(columnsJSON, &archived); err != nil {
+		return nil, fmt.Errorf("invalid archive columns: %w", err)
+	}
+
+	attached, err := partitionAttached(ctx, s.db.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !attached {
+		return nil, ErrPartitionNotFound
+	}
+
+	// Kolom yang sudah tidak ada di messages dilewati, kolom baru memakai default
+	current, err := messagesTableColumns(ctx, s.db.DB)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(current))
+	for _, column := range current {
+		known[column] = true
+	}
+	var quoted []string
+	for _, column := range archived {
+		if known[column] {
+			quoted = append(quoted, fmt.Sprintf(`"%s"`, column))
+		}
+	}
+	columns := strings.Join(quoted, ", ")
+	insert := fmt.Sprintf(`
+		INSERT INTO "%s" (%s) SELECT %s FROM jsonb_populate_recordset(NULL::messages, $1::jsonb)
+		ON CONFLICT DO NOTHING
+	`, partitionName(tenantID), columns, columns)
+
+	object, err := s.client.GetObject(ctx, archive.Bucket, archive.ObjectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive: %w", err)
+	}
+	defer object.Close()
+	gz, err := gzip.NewReader(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	reader := bufio.NewReader(gz)
+
+	var restored int64
+	var batch bytes.Buffer
+	lines := 0
+	flush := func() error {
+		if lines == 0 {
+			return nil
+		}
+		batch.WriteByte(']')
+		err := s.db.WithTenant(ctx, tenantID, func(q repository.Querier) error {
+			result, err := q.ExecContext(ctx, insert, batch.String())
+			if err != nil {
+				return err
+			}
+			n, _ := result.RowsAffected()
+			restored += n
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		batch.Reset()
+		lines = 0
+		return nil
+	}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if lines == 0 {
+				batch.WriteByte('[')
+			} else {
+				batch.WriteByte(',')
+			}
+			batch.Write(line)
+			lines++
+			if lines == archiveRestoreBatch {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	err = s.db.DB.QueryRowContext(ctx, `
+		UPDATE message_archives SET restored_at = NOW(), restored_count = $2 WHERE id = $1 RETURNING restored_at
+	`, archive.ID, restored).Scan(&archive.RestoredAt)
+	if err != nil {
+		return nil, err
+	}
+	archive.RestoredCount = restored
+	return archive, nil
+}
+
+// ensureBucket creates the archive bucket unless it exists
+func (s *ArchiveService) ensureBucket(ctx context.Context) error {
+	s.bucketMu.Lock()
+	defer s.bucketMu.Unlock()
+	if s.bucketReady {
+		return nil
+	}
+	exists, err := s.client.BucketExists(ctx, s.opts.Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check archive bucket: %w", err)
+	}
+	if !exists {
+		if err := s.client.MakeBucket(ctx, s.opts.Bucket, minio.MakeBucketOptions{Region: s.opts.Region}); err != nil {
+			return fmt.Errorf("failed to create archive bucket: %w", err)
+		}
+	}
+	s.bucketReady = true
+	return nil
+}
+
+// messagesTableColumns returns the columns of messages in table order
+func messagesTableColumns(ctx context.Context, q repository.Querier) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'messages' ORDER BY ordinal_position
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// scanArchive scans a message_archives row followed by extra columns
+func scanArchive(row rowScanner, extra ...interface{}) (*domain.MessageArchive, error) {
+	var archive domain.MessageArchive
+	var day time.Time
+	var restoredAt, heldUntil sql.NullTime
+	dest := append([]interface{}{&archive.ID, &archive.TenantID, &day, &archive.Bucket, &archive.ObjectKey, &archive.MessageCount,
+		&archive.SizeBytes, &archive.CreatedAt, &restoredAt, &archive.RestoredCount, &heldUntil}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	archive.Day = day.Format(time.DateOnly)
+	if restoredAt.Valid {
+		archive.RestoredAt = &restoredAt.Time
+	}
+	if heldUntil.Valid {
+		archive.HeldUntil = &heldUntil.Time
+	}
+	return &archive, nil
+}
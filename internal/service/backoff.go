@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// attemptsHeader counts how many times a message went through the tenant's
+// backoff queues. It is distinct from retryCountHeader, which counts the
+// DLQ retry scheduler's retries.
+const attemptsHeader = "x-retry-count"
+
+// backoffDelay returns the wait after the given failed attempt, counted from
+// 1: the policy's backoff doubled per attempt up to its max backoff, spread by
+// its jitter. Waits are whole seconds so jittered messages share few queues.
+func backoffDelay(policy domain.RetryPolicy, attempt int) time.Duration {
+	delay := time.Duration(policy.BackoffSeconds) * time.Second
+	maxDelay := time.Duration(policy.MaxBackoffSeconds) * time.Second
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if policy.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * policy.Jitter * float64(delay))
+	}
+	delay = delay.Round(time.Second)
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return delay
+}
+
+// backoffQueueName is where messages of the tenant's main queue, or of one
+// of its channels, wait out delay, e.g. tenant_{id}_retry_30s
+func backoffQueueName(tenantID, channel string, delay time.Duration) string {
+	if channel != "" {
+		return fmt.Sprintf("%s_retry_%s", channelQueueName(tenantID, channel), delayLabel(delay))
+	}
+	return fmt.Sprintf("tenant_%s_retry_%s", tenantID, delayLabel(delay))
+}
+
+// delayLabel formats delay in its largest whole unit, e.g. 30s, 5m or 1h
+func delayLabel(delay time.Duration) string {
+	switch {
+	case delay%time.Hour == 0:
+		return fmt.Sprintf("%dh", delay/time.Hour)
+	case delay%time.Minute == 0:
+		return fmt.Sprintf("%dm", delay/time.Minute)
+	case delay%time.Second == 0:
+		return fmt.Sprintf("%ds", delay/time.Second)
+	}
+	return fmt.Sprintf("%dms", delay/time.Millisecond)
+}
+
+// retryLater moves a delivery of queue that failed for a reason that may pass
+// to its backoff queue. It reports false, publishing nothing, once the
+// message has used up its attempts; the caller then dead-letters it.
+func (s *TenantService) retryLater(tenantID, channel, queue string, d amqp.Delivery) (bool, error) {
+	policy := s.retries.policy(tenantID)
+	attempt := headerInt(d.Headers[attemptsHeader]) + 1
+	if attempt >= policy.MaxAttempts {
+		return false, nil
+	}
+
+	delay := backoffDelay(policy, attempt)
+	name := backoffQueueName(tenantID, channel, delay)
+	// Dideklarasikan ulang setiap kali agar x-expires tidak menghapusnya selagi dipakai
+	_, err := s.rabbit.Channel().QueueDeclare(name, true, false, false, false, amqp.Table{
+		"x-message-ttl":             delay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+		"x-expires":                 (delay + scheduledQueueIdle).Milliseconds(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to declare backoff queue %s: %w", name, err)
+	}
+
+	// x-death dari percobaan sebelumnya dibuang supaya RabbitMQ tidak menganggapnya siklus
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		if key == "x-death" || strings.HasPrefix(key, "x-first-death-") || strings.HasPrefix(key, "x-last-death-") {
+			continue
+		}
+		headers[key] = value
+	}
+	headers[attemptsHeader] = int64(attempt)
+
+	err = s.rabbit.Channel().Publish("", name, false, false, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     d.MessageId,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		Type:          d.Type,
+		Timestamp:     d.Timestamp,
+		Priority:      d.Priority,
+		Body:          d.Body,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to publish to backoff queue %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// backoffQueueNames lists the backoff queues of the tenant's main queue and
+// of the given channels. Queues of jittered waits are not listed; they expire
+// on their own.
+func (s *TenantService) backoffQueueNames(tenantID string, channels []string) []string {
+	policy := s.retries.policy(tenantID)
+	policy.Jitter = 0
+	seen := make(map[time.Duration]bool)
+	var names []string
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		delay := backoffDelay(policy, attempt)
+		if seen[delay] {
+			continue
+		}
+		seen[delay] = true
+		names = append(names, backoffQueueName(tenantID, "", delay))
+		for _, channel := range channels {
+			names = append(names, backoffQueueName(tenantID, channel, delay))
+		}
+	}
+	return names
+}
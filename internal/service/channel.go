@@ -0,0 +1,182 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"multi-tenant-messaging/internal/domain"
+)
+
+// ErrChannelNotFound is returned when a tenant has no channel with the given name
+var ErrChannelNotFound = errors.New("channel not found")
+
+// ErrChannelExists is returned when creating a channel whose name is taken
+var ErrChannelExists = errors.New("channel already exists")
+
+// ErrInvalidChannel is returned for channel names or settings that cannot be used
+var ErrInvalidChannel = errors.New("invalid channel")
+
+// channelNamePattern keeps channel names usable in queue names and URLs
+var channelNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// channelQueueName is the queue a tenant channel is consumed from
+func channelQueueName(tenantID, name string) string {
+	return fmt.Sprintf("tenant_%s_channel_%s", tenantID, name)
+}
+
+// channelFromQueue returns the channel consumed from queue, empty if queue is
+// not a channel queue of the tenant
+func channelFromQueue(tenantID, queue string) string {
+	name, _ := strings.CutPrefix(queue, fmt.Sprintf("tenant_%s_channel_", tenantID))
+	if name == queue {
+		return ""
+	}
+	return name
+}
+
+// ListChannels returns the tenant's channels ordered by name
+func (s *TenantService) ListChannels(tenantID string) ([]domain.Channel, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT tenant_id, name, queue_name, workers, ordered, partition_key, created_at
+		FROM tenant_channels WHERE tenant_id = $1 ORDER BY name
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make([]domain.Channel, 0)
+	for rows.Next() {
+		var c domain.Channel
+		if err := rows.Scan(&c.TenantID, &c.Name, &c.QueueName, &c.Workers, &c.Ordered, &c.PartitionKey, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+// GetChannel returns one of the tenant's channels
+func (s *TenantService) GetChannel(tenantID, name string) (domain.Channel, error) {
+	var c domain.Channel
+	err := s.db.DB.QueryRow(`
+		SELECT tenant_id, name, queue_name, workers, ordered, partition_key, created_at
+		FROM tenant_channels WHERE tenant_id = $1 AND name = $2
+	`, tenantID, name).Scan(&c.TenantID, &c.Name, &c.QueueName, &c.Workers, &c.Ordered, &c.PartitionKey, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrChannelNotFound
+	}
+	return c, err
+}
+
+// CreateChannel declares the channel's queue, stores it and restarts the
+// tenant's consumer here so the channel is consumed right away
+func (s *TenantService) CreateChannel(channel domain.Channel) (domain.Channel, error) {
+	if !channelNamePattern.MatchString(channel.Name) {
+		return channel, fmt.Errorf("%w: name must be 1-63 lowercase letters, digits, '_' or '-'", ErrInvalidChannel)
+	}
+	if err := validateChannelConfig(channel); err != nil {
+		return channel, err
+	}
+	if err := s.onRabbitMQ(); err != nil {
+		return channel, err
+	}
+
+	var exists bool
+	if err := s.db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)", channel.TenantID).Scan(&exists); err != nil {
+		return channel, err
+	}
+	if !exists {
+		return channel, ErrTenantNotFound
+	}
+
+	channel.QueueName = channelQueueName(channel.TenantID, channel.Name)
+	if err := declareTenantQueue(s.rabbit, channel.TenantID, channel.QueueName, s.messageTTL); err != nil {
+		return channel, err
+	}
+
+	result, err := s.db.DB.Exec(`
+		INSERT INTO tenant_channels (tenant_id, name, queue_name, workers, ordered, partition_key)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (tenant_id, name) DO NOTHING
+	`, channel.TenantID, channel.Name, channel.QueueName, channel.Workers, channel.Ordered, channel.PartitionKey)
+	if err != nil {
+		return channel, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return channel, ErrChannelExists
+	}
+
+	if err := s.restartChannels(channel.TenantID); err != nil {
+		return channel, err
+	}
+	return s.GetChannel(channel.TenantID, channel.Name)
+}
+
+// UpdateChannel changes the channel's consumer settings and restarts the
+// tenant's consumer here to apply them
+func (s *TenantService) UpdateChannel(channel domain.Channel) (domain.Channel, error) {
+	if err := validateChannelConfig(channel); err != nil {
+		return channel, err
+	}
+
+	result, err := s.db.DB.Exec(`
+		UPDATE tenant_channels SET workers = $3, ordered = $4, partition_key = $5
+		WHERE tenant_id = $1 AND name = $2
+	`, channel.TenantID, channel.Name, channel.Workers, channel.Ordered, channel.PartitionKey)
+	if err != nil {
+		return channel, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return channel, ErrChannelNotFound
+	}
+
+	if err := s.restartChannels(channel.TenantID); err != nil {
+		return channel, err
+	}
+	return s.GetChannel(channel.TenantID, channel.Name)
+}
+
+// DeleteChannel stops consuming the channel and deletes its queue, including
+// any messages still in it
+func (s *TenantService) DeleteChannel(tenantID, name string) error {
+	channel, err := s.GetChannel(tenantID, name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.DB.Exec("DELETE FROM tenant_channels WHERE tenant_id = $1 AND name = $2", tenantID, name); err != nil {
+		return err
+	}
+
+	if err := s.restartChannels(tenantID); err != nil {
+		return err
+	}
+	if _, err := s.rabbit.Channel().QueueDelete(channel.QueueName, false, false, false); err != nil {
+		slog.Error("Failed to delete queue", "tenant_id", channel.TenantID, "queue", channel.QueueName, "error", err)
+	}
+	return nil
+}
+
+// restartChannels restarts the tenant's consumer if this instance runs it, so
+// it picks up the current set of channels. Other instances pick them up the
+// next time they start the tenant's consumer.
+func (s *TenantService) restartChannels(tenantID string) error {
+	config, active := s.tenantManager.GetConfig(tenantID)
+	if !active {
+		return nil
+	}
+	return s.restartConsumer(config, "channels")
+}
+
+func validateChannelConfig(channel domain.Channel) error {
+	if channel.Workers < 1 {
+		return fmt.Errorf("%w: workers must be at least 1", ErrInvalidChannel)
+	}
+	if channel.Ordered && channel.Workers != 1 {
+		return fmt.Errorf("%w: ordered channels must use exactly one worker", ErrInvalidChannel)
+	}
+	return nil
+}
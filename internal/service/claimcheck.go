@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrBlobNotAllowed is returned when a claim-check URL points to a host that
+// is not allowlisted or outside the tenant's prefix, and for a message marked
+// as an envelope that is none
+var ErrBlobNotAllowed = errors.New("claim-check blob not allowed")
+
+// ErrBlobChecksum is returned when a fetched blob does not match the sha256 of its reference
+var ErrBlobChecksum = errors.New("claim-check checksum mismatch")
+
+// ClaimCheckContentType marks a message as a claim-check envelope. Bodies
+// published with any other content type are stored as they are, even when
+// they happen to have a claim_check field.
+const ClaimCheckContentType = "application/vnd.salva.claim-check+json"
+
+// claimCheckTenantPlaceholder is replaced by the tenant id in ClaimCheckOptions.TenantPrefix
+const claimCheckTenantPlaceholder = "{tenant_id}"
+
+// ClaimCheck is the reference envelope publishers send instead of an oversized body:
+//
+//	{"claim_check": {"url": "https://bucket.s3.amazonaws.com/key", "sha256": "...", "content_type": "application/json"}}
+type ClaimCheck struct {
+	URL         string `json:"url"`
+	SHA256      string `json:"sha256,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ClaimCheckOptions configures blob fetching
+type ClaimCheckOptions struct {
+	AllowedHosts []string
+	// TenantPrefix is the path every blob of a tenant must live under, with
+	// {tenant_id} standing for the tenant, e.g. "/{tenant_id}/"
+	TenantPrefix   string
+	MaxConcurrent  int
+	MaxBlobBytes   int64
+	FetchTimeout   time.Duration
+	MetadataFields int
+}
+
+// ClaimCheckResolver fetches blobs referenced by claim-check envelopes with
+// bounded concurrency and replaces the envelope with the reference plus
+// metadata extracted from the blob
+type ClaimCheckResolver struct {
+	opts    ClaimCheckOptions
+	allowed map[string]bool
+	slots   chan struct{}
+	client  *http.Client
+}
+
+func NewClaimCheckResolver(opts ClaimCheckOptions) *ClaimCheckResolver {
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = 4
+	}
+	if opts.MaxBlobBytes <= 0 {
+		opts.MaxBlobBytes = 64 << 20
+	}
+	if opts.FetchTimeout <= 0 {
+		opts.FetchTimeout = 30 * time.Second
+	}
+	if opts.MetadataFields <= 0 {
+		opts.MetadataFields = 50
+	}
+	if !strings.Contains(opts.TenantPrefix, claimCheckTenantPlaceholder) {
+		opts.TenantPrefix = "/" + claimCheckTenantPlaceholder + "/"
+	}
+
+	allowed := make(map[string]bool, len(opts.AllowedHosts))
+	for _, host := range opts.AllowedHosts {
+		allowed[host] = true
+	}
+	return &ClaimCheckResolver{
+		opts:    opts,
+		allowed: allowed,
+		slots:   make(chan struct{}, opts.MaxConcurrent),
+		client: &http.Client{
+			Timeout: opts.FetchTimeout,
+			// Redirect tidak diikuti, blob harus ada di host yang diizinkan
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Resolve returns body unchanged unless contentType marks it as a
+// claim-check envelope. For an envelope it fetches and verifies the blob,
+// which must live under the tenant's prefix, and returns the payload to store.
+func (r *ClaimCheckResolver) Resolve(ctx context.Context, tenantID, contentType string, body []byte) ([]byte, error) {
+	if !IsClaimCheck(contentType) {
+		return body, nil
+	}
+	var envelope struct {
+		ClaimCheck *ClaimCheck `json:"claim_check"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.ClaimCheck == nil || envelope.ClaimCheck.URL == "" {
+		return nil, fmt.Errorf("%w: body is not a claim-check envelope", ErrBlobNotAllowed)
+	}
+	ref := envelope.ClaimCheck
+
+	blob, err := r.fetch(ctx, tenantID, ref.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch claim-check blob: %w", err)
+	}
+
+	sum := sha256.Sum256(blob)
+	digest := hex.EncodeToString(sum[:])
+	if ref.SHA256 != "" && ref.SHA256 != digest {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrBlobChecksum, ref.SHA256, digest)
+	}
+	ref.SHA256 = digest
+	ref.Size = int64(len(blob))
+
+	return json.Marshal(map[string]interface{}{
+		"claim_check": ref,
+		"metadata":    r.extractMetadata(blob),
+	})
+}
+
+// IsClaimCheck reports whether contentType is ClaimCheckContentType
+func IsClaimCheck(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == ClaimCheckContentType
+}
+
+// extractMetadata keeps the top-level scalar fields of a JSON object blob
+func (r *ClaimCheckResolver) extractMetadata(blob []byte) map[string]interface{} {
+	metadata := make(map[string]interface{})
+	var object map[string]interface{}
+	if err := json.Unmarshal(blob, &object); err != nil {
+		return metadata
+	}
+	for key, value := range object {
+		if len(metadata) >= r.opts.MetadataFields {
+			break
+		}
+		switch value.(type) {
+		case string, float64, bool:
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+func (r *ClaimCheckResolver) fetch(ctx context.Context, tenantID, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("%w: invalid url %q", ErrBlobNotAllowed, rawURL)
+	}
+	if !r.allowed[u.Hostname()] {
+		return nil, fmt.Errorf("%w: host %s", ErrBlobNotAllowed, u.Hostname())
+	}
+	// Path dengan segmen "." atau ".." ditolak agar tidak keluar dari prefix tenant
+	prefix := strings.ReplaceAll(r.opts.TenantPrefix, claimCheckTenantPlaceholder, tenantID)
+	if path.Clean(u.Path) != u.Path || !strings.HasPrefix(u.Path, prefix) {
+		return nil, fmt.Errorf("%w: path %s is outside %s", ErrBlobNotAllowed, u.Path, prefix)
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("object storage returned %s", resp.Status)
+	}
+
+	blob, err := io.ReadAll(io.LimitReader(resp.Body, r.opts.MaxBlobBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(blob)) > r.opts.MaxBlobBytes {
+		return nil, fmt.Errorf("claim-check blob exceeds %d bytes", r.opts.MaxBlobBytes)
+	}
+	return blob, nil
+}
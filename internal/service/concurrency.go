@@ -0,0 +1,120 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// ErrInvalidWorkerBounds is returned for bounds below one worker, a max below
+// the min, or a max above the instance's worker budget
+var ErrInvalidWorkerBounds = errors.New("invalid worker bounds")
+
+// ConcurrencyService holds the worker budget of an instance and the
+// per-tenant worker bounds UpdateConcurrency enforces
+type ConcurrencyService struct {
+	db *repository.Database
+	// maxWorkers is shared by the tenants consumed here, 0 for no budget
+	maxWorkers int
+	// maxTenantWorkers caps tenants without bounds of their own
+	maxTenantWorkers int
+}
+
+func NewConcurrencyService(db *repository.Database, maxWorkers, maxTenantWorkers int) *ConcurrencyService {
+	if maxTenantWorkers < 1 {
+		maxTenantWorkers = 1
+	}
+	return &ConcurrencyService{db: db, maxWorkers: maxWorkers, maxTenantWorkers: maxTenantWorkers}
+}
+
+// GetBounds returns the tenant's worker bounds, falling back to 1 and
+// concurrency.max_tenant_workers
+func (s *ConcurrencyService) GetBounds(tenantID string) (domain.WorkerBounds, error) {
+	var minWorkers, maxWorkers sql.NullInt64
+	err := s.db.DB.QueryRow(
+		"SELECT min_workers, max_workers FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&minWorkers, &maxWorkers)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.WorkerBounds{}, err
+	}
+	if !maxWorkers.Valid {
+		return domain.WorkerBounds{MinWorkers: 1, MaxWorkers: s.maxTenantWorkers, Default: true}, nil
+	}
+	return domain.WorkerBounds{MinWorkers: int(minWorkers.Int64), MaxWorkers: int(maxWorkers.Int64)}, nil
+}
+
+// SetBounds stores the tenant's worker bounds. They may exceed
+// concurrency.max_tenant_workers but not the instance's worker budget.
+// Workers already set outside them stay until the next UpdateConcurrency.
+func (s *ConcurrencyService) SetBounds(tenantID string, bounds domain.WorkerBounds) (domain.WorkerBounds, error) {
+	if bounds.MinWorkers < 1 || bounds.MaxWorkers < bounds.MinWorkers {
+		return domain.WorkerBounds{}, fmt.Errorf("%w: min_workers must be at least 1 and max_workers not below it", ErrInvalidWorkerBounds)
+	}
+	if s.maxWorkers > 0 && bounds.MaxWorkers > s.maxWorkers {
+		return domain.WorkerBounds{}, fmt.Errorf("%w: max_workers must not exceed the budget of %d", ErrInvalidWorkerBounds, s.maxWorkers)
+	}
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, min_workers, max_workers) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET min_workers = EXCLUDED.min_workers, max_workers = EXCLUDED.max_workers
+	`, tenantID, bounds.MinWorkers, bounds.MaxWorkers)
+	if err != nil {
+		return domain.WorkerBounds{}, err
+	}
+	bounds.Default = false
+	return bounds, nil
+}
+
+// ResetBounds drops the tenant's worker bounds so the defaults apply again
+func (s *ConcurrencyService) ResetBounds(tenantID string) (domain.WorkerBounds, error) {
+	_, err := s.db.DB.Exec(
+		"UPDATE tenant_configs SET min_workers = NULL, max_workers = NULL WHERE tenant_id = $1", tenantID,
+	)
+	if err != nil {
+		return domain.WorkerBounds{}, err
+	}
+	return s.GetBounds(tenantID)
+}
+
+// clampWorkers brings stored workers within the tenant's max, so a value set
+// before the bounds existed cannot start thousands of workers
+func (s *ConcurrencyService) clampWorkers(tenantID string, workers int) int {
+	bounds, err := s.GetBounds(tenantID)
+	if err != nil || workers <= bounds.MaxWorkers {
+		return workers
+	}
+	return bounds.MaxWorkers
+}
+
+// checkWorkers fails with ErrInvalidConcurrency unless the tenant may run
+// workers: within its bounds, and within what the other tenants in manager
+// leave of the instance's budget
+func (s *ConcurrencyService) checkWorkers(manager *domain.TenantManager, tenantID string, workers int) error {
+	bounds, err := s.GetBounds(tenantID)
+	if err != nil {
+		return err
+	}
+	if workers < bounds.MinWorkers || workers > bounds.MaxWorkers {
+		return fmt.Errorf("%w: workers must be between %d and %d", ErrInvalidConcurrency, bounds.MinWorkers, bounds.MaxWorkers)
+	}
+	if s.maxWorkers == 0 {
+		return nil
+	}
+
+	used := 0
+	for _, id := range manager.TenantIDs() {
+		if id == tenantID {
+			continue
+		}
+		if config, exists := manager.GetConfig(id); exists {
+			used += config.Workers
+		}
+	}
+	if left := s.maxWorkers - used; workers > left {
+		return fmt.Errorf("%w: only %d of the %d workers budgeted for this instance are left", ErrInvalidConcurrency, max(left, 0), s.maxWorkers)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// consumerGroupSettle holds back messages younger than this from consumer
+// groups. created_at is set when the storing transaction starts, so a message
+// may become visible after a newer one; reading it later would skip it.
+const consumerGroupSettle = 2 * time.Second
+
+// ErrConsumerGroupNotFound is returned when a tenant has no consumer group with the given name
+var ErrConsumerGroupNotFound = errors.New("consumer group not found")
+
+// ErrConsumerGroupExists is returned when creating a consumer group whose name is taken
+var ErrConsumerGroupExists = errors.New("consumer group already exists")
+
+// ErrInvalidConsumerGroup is returned for invalid group names, start positions or offsets
+var ErrInvalidConsumerGroup = errors.New("invalid consumer group")
+
+// consumerGroupNamePattern matches the names allowed for tenant channels
+var consumerGroupNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ConsumerGroupService manages consumer groups and their offsets into the
+// messages table
+type ConsumerGroupService struct {
+	db *repository.Database
+}
+
+func NewConsumerGroupService(db *repository.Database) *ConsumerGroupService {
+	return &ConsumerGroupService{db: db}
+}
+
+// consumerGroupColumns selects a group and its lag; the lag subquery counts
+// messages after the offset
+const consumerGroupColumns = `
+	g.tenant_id, g.name, g.offset_id, g.offset_created_at, g.created_at, g.updated_at,
+	(SELECT COUNT(*) FROM messages m WHERE m.tenant_id = g.tenant_id
+		AND (g.offset_created_at IS NULL OR (m.created_at, m.id) > (g.offset_created_at, g.offset_id)))
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanConsumerGroup(row rowScanner) (domain.ConsumerGroup, error) {
+	var g domain.ConsumerGroup
+	var offset sql.NullString
+	var offsetCreatedAt sql.NullTime
+	if err := row.Scan(&g.TenantID, &g.Name, &offset, &offsetCreatedAt, &g.CreatedAt, &g.UpdatedAt, &g.Lag); err != nil {
+		return g, err
+	}
+	g.Offset = offset.String
+	if offsetCreatedAt.Valid {
+		g.OffsetCreatedAt = &offsetCreatedAt.Time
+	}
+	return g, nil
+}
+
+// ListGroups returns the tenant's consumer groups ordered by name
+func (s *ConsumerGroupService) ListGroups(tenantID string) ([]domain.ConsumerGroup, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT `+consumerGroupColumns+` FROM consumer_groups g
+		WHERE g.tenant_id = $1 ORDER BY g.name
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]domain.ConsumerGroup, 0)
+	for rows.Next() {
+		g, err := scanConsumerGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetGroup returns one of the tenant's consumer groups
+func (s *ConsumerGroupService) GetGroup(tenantID, name string) (domain.ConsumerGroup, error) {
+	g, err := scanConsumerGroup(s.db.DB.QueryRow(`
+		SELECT `+consumerGroupColumns+` FROM consumer_groups g
+		WHERE g.tenant_id = $1 AND g.name = $2
+	`, tenantID, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return g, ErrConsumerGroupNotFound
+	}
+	return g, err
+}
+
+// CreateGroup creates a consumer group that starts reading at the tenant's
+// first message (earliest) or after its newest message (latest)
+func (s *ConsumerGroupService) CreateGroup(tenantID, name, start string) (domain.ConsumerGroup, error) {
+	if !consumerGroupNamePattern.MatchString(name) {
+		return domain.ConsumerGroup{}, fmt.Errorf("%w: name must be 1-63 lowercase letters, digits, '_' or '-'", ErrInvalidConsumerGroup)
+	}
+	if start == "" {
+		start = domain.ConsumerGroupStartLatest
+	}
+	if start != domain.ConsumerGroupStartEarliest && start != domain.ConsumerGroupStartLatest {
+		return domain.ConsumerGroup{}, fmt.Errorf("%w: start must be %q or %q", ErrInvalidConsumerGroup,
+			domain.ConsumerGroupStartEarliest, domain.ConsumerGroupStartLatest)
+	}
+
+	var exists bool
+	if err := s.db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)", tenantID).Scan(&exists); err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+	if !exists {
+		return domain.ConsumerGroup{}, ErrTenantNotFound
+	}
+
+	offset, err := s.startOffset(tenantID, start)
+	if err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+	result, err := s.db.DB.Exec(`
+		INSERT INTO consumer_groups (tenant_id, name, offset_id, offset_created_at)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (tenant_id, name) DO NOTHING
+	`, tenantID, name, offset.id, offset.createdAt)
+	if err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ConsumerGroup{}, ErrConsumerGroupExists
+	}
+	return s.GetGroup(tenantID, name)
+}
+
+// DeleteGroup deletes a consumer group and its offset
+func (s *ConsumerGroupService) DeleteGroup(tenantID, name string) error {
+	result, err := s.db.DB.Exec("DELETE FROM consumer_groups WHERE tenant_id = $1 AND name = $2", tenantID, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrConsumerGroupNotFound
+	}
+	return nil
+}
+
+// Fetch returns up to limit messages after the group's offset, oldest first.
+// Without autoCommit the offset stays put until Commit, so a consumer that
+// fails before committing reads the batch again. With autoCommit the offset
+// moves past the batch in the same transaction, which gives concurrent members
+// of the group disjoint batches at the cost of at-most-once delivery.
+func (s *ConsumerGroupService) Fetch(ctx context.Context, tenantID, name string, limit int, autoCommit bool) (domain.ConsumerGroupBatch, error) {
+	batch := domain.ConsumerGroupBatch{Data: make([]domain.Message, 0)}
+
+	err := s.db.WithTenantTx(ctx, tenantID, func(q repository.Querier) error {
+		query := "SELECT offset_id, offset_created_at FROM consumer_groups WHERE tenant_id = $1 AND name = $2"
+		if autoCommit {
+			// Kunci baris group supaya member lain menunggu sampai offset dipindahkan
+			query += " FOR UPDATE"
+		}
+		var offset groupOffset
+		if err := q.QueryRowContext(ctx, query, tenantID, name).Scan(&offset.id, &offset.createdAt); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrConsumerGroupNotFound
+			}
+			return err
+		}
+
+		rows, err := q.QueryContext(ctx, `
+			SELECT id, tenant_id, payload, status, message_type, schema_version, tags, channel, created_at
+			FROM messages
+			WHERE tenant_id = $1
+				AND ($2::timestamptz IS NULL OR (created_at, id) > ($2, $3::uuid))
+				AND created_at < NOW() - make_interval(secs => $4)
+			ORDER BY created_at, id
+			LIMIT $5
+		`, tenantID, offset.createdAt, offset.id, consumerGroupSettle.Seconds(), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var msg domain.Message
+			if err := rows.Scan(&msg.ID, &msg.TenantID, &msg.Payload, &msg.Status, &msg.MessageType,
+				&msg.SchemaVersion, &msg.Tags, &msg.Channel, &msg.CreatedAt); err != nil {
+				return err
+			}
+			batch.Data = append(batch.Data, msg)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		batch.NextOffset = offset.id.String
+		if len(batch.Data) == 0 {
+			return nil
+		}
+		last := batch.Data[len(batch.Data)-1]
+		batch.NextOffset = last.ID
+		if !autoCommit {
+			return nil
+		}
+		if _, err := q.ExecContext(ctx, `
+			UPDATE consumer_groups SET offset_id = $3, offset_created_at = $4, updated_at = NOW()
+			WHERE tenant_id = $1 AND name = $2
+		`, tenantID, name, last.ID, last.CreatedAt); err != nil {
+			return err
+		}
+		batch.Committed = true
+		return nil
+	})
+	return batch, err
+}
+
+// Commit moves the group's offset to the given message. Offsets never move
+// backwards through Commit, so a late commit of an older batch is ignored;
+// use Seek to replay messages.
+func (s *ConsumerGroupService) Commit(tenantID, name, messageID string) (domain.ConsumerGroup, error) {
+	offset, err := s.messageOffset(tenantID, messageID)
+	if err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+
+	// Tidak ada baris yang berubah jika group tidak ada atau offset sudah lebih jauh; GetGroup membedakannya
+	if _, err := s.db.DB.Exec(`
+		UPDATE consumer_groups SET offset_id = $3, offset_created_at = $4, updated_at = NOW()
+		WHERE tenant_id = $1 AND name = $2
+			AND (offset_created_at IS NULL OR ($4, $3::uuid) > (offset_created_at, offset_id))
+	`, tenantID, name, offset.id, offset.createdAt); err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+	return s.GetGroup(tenantID, name)
+}
+
+// Seek sets the group's offset to earliest, latest or a message ID, in which
+// case the group reads on from the message after it. Unlike Commit it may
+// move the offset backwards to replay messages.
+func (s *ConsumerGroupService) Seek(tenantID, name, to string) (domain.ConsumerGroup, error) {
+	var offset groupOffset
+	var err error
+	if to == domain.ConsumerGroupStartEarliest || to == domain.ConsumerGroupStartLatest {
+		offset, err = s.startOffset(tenantID, to)
+	} else {
+		offset, err = s.messageOffset(tenantID, to)
+	}
+	if err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+
+	result, err := s.db.DB.Exec(`
+		UPDATE consumer_groups SET offset_id = $3, offset_created_at = $4, updated_at = NOW()
+		WHERE tenant_id = $1 AND name = $2
+	`, tenantID, name, offset.id, offset.createdAt)
+	if err != nil {
+		return domain.ConsumerGroup{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ConsumerGroup{}, ErrConsumerGroupNotFound
+	}
+	return s.GetGroup(tenantID, name)
+}
+
+// groupOffset is a position in the (created_at, id) order of a tenant's
+// messages; NULL columns mean before the first message
+type groupOffset struct {
+	id        sql.NullString
+	createdAt sql.NullTime
+}
+
+// startOffset returns the offset before the tenant's first message (earliest)
+// or at its newest message (latest)
+func (s *ConsumerGroupService) startOffset(tenantID, start string) (groupOffset, error) {
+	var offset groupOffset
+	if start == domain.ConsumerGroupStartEarliest {
+		return offset, nil
+	}
+	err := s.db.DB.QueryRow(`
+		SELECT id, created_at FROM messages WHERE tenant_id = $1
+		ORDER BY created_at DESC, id DESC LIMIT 1
+	`, tenantID).Scan(&offset.id, &offset.createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return groupOffset{}, nil
+	}
+	return offset, err
+}
+
+// messageOffset returns the offset at one of the tenant's messages
+func (s *ConsumerGroupService) messageOffset(tenantID, messageID string) (groupOffset, error) {
+	var offset groupOffset
+	err := s.db.DB.QueryRow(`
+		SELECT id, created_at FROM messages WHERE tenant_id = $1 AND id::text = $2
+	`, tenantID, messageID).Scan(&offset.id, &offset.createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return offset, fmt.Errorf("%w: message %s not found", ErrInvalidConsumerGroup, messageID)
+	}
+	return offset, err
+}
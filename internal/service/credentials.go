@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/auth"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// ErrRotationInProgress is returned when credentials are already being rotated
+var ErrRotationInProgress = errors.New("credential rotation already in progress")
+
+// Credentials are the secrets that can be rotated without a restart
+type Credentials struct {
+	DatabaseURL string
+	// Broker identifies the broker nodes and their credentials; BrokerNodes
+	// is only dialed again when it changed
+	Broker      string
+	BrokerNodes repository.NodeSource
+	JWTSecret   string
+}
+
+// CredentialService swaps database, broker and JWT credentials at runtime.
+// New connections use the new credentials while the old ones are drained.
+type CredentialService struct {
+	mu           sync.Mutex
+	db           *repository.Database
+	rabbit       *repository.RabbitMQ
+	tenants      *TenantService
+	tokens       *auth.TokenService
+	load         func() (Credentials, error)
+	current      Credentials
+	drainTimeout time.Duration
+}
+
+// NewCredentialService creates a CredentialService. current are the
+// credentials in use; load reads the credentials to rotate to.
+func NewCredentialService(db *repository.Database, rabbit *repository.RabbitMQ, tenants *TenantService, tokens *auth.TokenService, current Credentials, load func() (Credentials, error), drainTimeout time.Duration) *CredentialService {
+	return &CredentialService{
+		db:           db,
+		rabbit:       rabbit,
+		tenants:      tenants,
+		tokens:       tokens,
+		load:         load,
+		current:      current,
+		drainTimeout: drainTimeout,
+	}
+}
+
+// Rotate loads the credentials again and switches to those that changed. It
+// returns which of "database", "rabbitmq" and "jwt" were rotated.
+func (s *CredentialService) Rotate(ctx context.Context) ([]string, error) {
+	if !s.mu.TryLock() {
+		return nil, ErrRotationInProgress
+	}
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
+
+	rotated := []string{}
+	if next.DatabaseURL != s.current.DatabaseURL {
+		if err := s.db.Rotate(next.DatabaseURL); err != nil {
+			return rotated, err
+		}
+		s.current.DatabaseURL = next.DatabaseURL
+		rotated = append(rotated, "database")
+	}
+
+	// Tanpa RabbitMQ (transport Kafka atau NATS) kredensial broker tidak dipakai
+	if next.Broker != s.current.Broker && s.rabbit != nil {
+		replaced, err := s.rabbit.Rotate(next.BrokerNodes)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to rotate RabbitMQ credentials: %w", err)
+		}
+		s.current.Broker, s.current.BrokerNodes = next.Broker, next.BrokerNodes
+
+		// Consumer lama diselesaikan dulu sebelum koneksinya ditutup
+		drainCtx, cancel := context.WithTimeout(ctx, s.drainTimeout)
+		s.tenants.RestartConsumers(drainCtx, "credential_rotation")
+		cancel()
+		for _, conn := range replaced {
+			conn.Close()
+		}
+		rotated = append(rotated, "rabbitmq")
+	}
+
+	if next.JWTSecret != s.current.JWTSecret {
+		if s.current.JWTSecret == "" {
+			// Middleware JWT hanya dipasang saat start jika secret sudah ada
+			slog.Warn("security.jwt_secret was empty at startup, restart to enable authentication")
+		} else if next.JWTSecret == "" {
+			slog.Warn("Ignoring empty security.jwt_secret, restart to disable authentication")
+		} else {
+			s.tokens.Rotate(next.JWTSecret)
+			s.current.JWTSecret = next.JWTSecret
+			rotated = append(rotated, "jwt")
+		}
+	}
+
+	slog.Info("Credential rotation finished", "rotated", rotated)
+	return rotated, nil
+}
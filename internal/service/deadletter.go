@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/events"
+	"multi-tenant-messaging/internal/logging"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/repository"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Dead-letter reasons set by RabbitMQ in the x-death header
+const (
+	deathReasonExpired  = "expired"
+	deathReasonRejected = "rejected"
+)
+
+// Headers the DLQ retry scheduler keeps on dead-lettered messages
+const (
+	// deadLetteredAtHeader is when the message last entered the DLQ
+	deadLetteredAtHeader = "x-salva-dead-lettered-at"
+	// retryCountHeader is how many times the scheduler moved the message back to the tenant queue
+	retryCountHeader = "x-salva-retry-count"
+	// retryExhaustedHeader marks messages the scheduler gave up on
+	retryExhaustedHeader = "x-salva-retry-exhausted"
+	// channelHeader names the tenant channel a dead-lettered message came from
+	channelHeader = "x-salva-channel"
+)
+
+// dlqRetryBatch caps how many DLQ messages one scan holds unacked
+const dlqRetryBatch = 1000
+
+// DefaultQueueNameTemplate names tenant queues unless rabbitmq.queue_name_template is set
+const DefaultQueueNameTemplate = "tenant_{tenant_id}_queue"
+
+// renderQueueName fills the tenant ID into a queue name template
+func renderQueueName(template, tenantID string) string {
+	if template == "" {
+		template = DefaultQueueNameTemplate
+	}
+	return strings.ReplaceAll(template, "{tenant_id}", tenantID)
+}
+
+// deadQueueName is where the tenant queue dead-letters to; the router
+// consuming it separates expired messages from failures
+func deadQueueName(tenantID string) string {
+	return fmt.Sprintf("tenant_%s_dead", tenantID)
+}
+
+// dlqName is the tenant's dead letter queue holding failed messages
+func dlqName(tenantID string) string {
+	return fmt.Sprintf("tenant_%s_dlq", tenantID)
+}
+
+// declareTenantQueues declares the tenant queue, its dead-letter routing
+// queue and its DLQ, or the tenant's destination on other transports
+func (s *TenantService) declareTenantQueues(tenantID, queue string) error {
+	if s.onRabbitMQ() != nil {
+		destination, _ := s.transport.Destination(tenantID, queue)
+		return s.transport.Declare(context.Background(), destination)
+	}
+	return declareQueues(s.rabbit, tenantID, queue, s.messageTTL)
+}
+
+// declareQueues declares the tenant's queues on rabbit, which may belong to
+// another deployment. queue is the name of the tenant's main queue, a
+// priority queue if its name marks it as one.
+func declareQueues(rabbit *repository.RabbitMQ, tenantID, queue string, messageTTL time.Duration) error {
+	for _, name := range []string{dlqName(tenantID), deadQueueName(tenantID)} {
+		if _, err := rabbit.Channel().QueueDeclare(name, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare queue %s: %w", name, err)
+		}
+	}
+
+	return declareTenantQueueArgs(rabbit, tenantID, queue, messageTTL, priorityQueueArgs(queue))
+}
+
+// declareTenantQueue declares a queue the tenant is consumed from, the main
+// queue or a channel queue, dead-lettering into the tenant's routing queue
+func declareTenantQueue(rabbit *repository.RabbitMQ, tenantID, queue string, messageTTL time.Duration) error {
+	return declareTenantQueueArgs(rabbit, tenantID, queue, messageTTL, nil)
+}
+
+// declareTenantQueueArgs is declareTenantQueue with extra queue arguments.
+// Queue arguments cannot change once declared, so a queue declared before
+// rabbitmq.message_ttl changed is refused with PRECONDITION_FAILED; it then
+// keeps the TTL it has and is only checked to exist. The declare gets a
+// channel of its own because the broker closes the channel on that error.
+func declareTenantQueueArgs(rabbit *repository.RabbitMQ, tenantID, queue string, messageTTL time.Duration, extra amqp.Table) error {
+	args := amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": deadQueueName(tenantID),
+	}
+	for key, value := range extra {
+		args[key] = value
+	}
+	if messageTTL > 0 {
+		args["x-message-ttl"] = messageTTL.Milliseconds()
+	}
+
+	ch, err := rabbit.OpenChannel()
+	if err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	defer ch.Close()
+	_, err = ch.QueueDeclare(
+		queue,
+		true,  // durable
+		false, // autoDelete
+		false, // exclusive
+		false, // noWait
+		args,
+	)
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+		return keepTenantQueue(rabbit, tenantID, queue, amqpErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	return nil
+}
+
+// keepTenantQueue checks that queue, which the broker refused to declare
+// again with mismatch, exists and leaves it with the arguments it has
+func keepTenantQueue(rabbit *repository.RabbitMQ, tenantID, queue string, mismatch *amqp.Error) error {
+	ch, err := rabbit.OpenChannel()
+	if err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	defer ch.Close()
+	if _, err := ch.QueueDeclarePassive(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	// TTL baru hanya berlaku untuk queue baru; queue lama bisa diubah lewat policy RabbitMQ
+	slog.Warn("Queue keeps the arguments it was declared with", "tenant_id", tenantID, "queue", queue, "reason", mismatch.Reason)
+	return nil
+}
+
+// deleteTenantQueues deletes every queue belonging to the tenant. On other
+// transports the tenant's destination is deleted unless it is shared.
+func (s *TenantService) deleteTenantQueues(tenantID, queue string) {
+	if s.onRabbitMQ() != nil {
+		if destination, key := s.transport.Destination(tenantID, queue); key == "" {
+			if err := s.transport.Delete(context.Background(), destination); err != nil {
+				slog.Error("Failed to delete destination", "tenant_id", tenantID, "destination", destination, "error", err)
+			}
+		}
+		return
+	}
+	queues := []string{queue, deadQueueName(tenantID), dlqName(tenantID), recoveryQueueName(tenantID), errorsQueueName(tenantID)}
+	channels, err := s.ListChannels(tenantID)
+	if err != nil {
+		slog.Error("Failed to list channels", "tenant_id", tenantID, "error", err)
+	}
+	names := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		queues = append(queues, channel.QueueName)
+		names = append(names, channel.Name)
+	}
+	queues = append(queues, s.backoffQueueNames(tenantID, names)...)
+
+	for _, name := range queues {
+		_, err := s.rabbit.Channel().QueueDelete(
+			name,
+			false, // ifUnused
+			false, // ifEmpty
+			false, // noWait
+		)
+		if err != nil {
+			slog.Error("Failed to delete queue", "tenant_id", tenantID, "queue", name, "error", err)
+		}
+	}
+}
+
+// deleteRouteQueues deletes the queues of the tenant's route filters
+func (s *TenantService) deleteRouteQueues(tenantID string) {
+	targets, err := s.filters.RouteTargets(tenantID)
+	if err != nil {
+		slog.Error("Failed to list route queues", "tenant_id", tenantID, "error", err)
+		return
+	}
+	for _, target := range targets {
+		if err := s.transport.Delete(context.Background(), filterRouteQueueName(tenantID, target)); err != nil {
+			slog.Error("Failed to delete queue", "tenant_id", tenantID, "queue", filterRouteQueueName(tenantID, target), "error", err)
+		}
+	}
+}
+
+// routeDeadLetters consumes the tenant's dead-letter routing queue. Messages
+// that expired in the tenant queue are stored with status=expired and
+// counted; everything else is a processing failure and moved to the DLQ.
+func (s *TenantService) routeDeadLetters(ctx context.Context, ch *amqp.Channel, tenantID string) {
+	msgs, err := ch.Consume(
+		deadQueueName(tenantID),
+		"",    // consumer
+		false, // autoAck
+		false, // exclusive
+		false, // noLocal
+		false, // noWait
+		nil,   // args
+	)
+	if err != nil {
+		slog.Error("Failed to consume dead letters", "tenant_id", tenantID, "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-msgs:
+			if !ok {
+				return
+			}
+			if err := s.routeDeadLetter(tenantID, d); err != nil {
+				slog.Error("Failed to route dead letter", "tenant_id", tenantID, "message_id", d.MessageId, "error", err)
+				d.Nack(false, true)
+				continue
+			}
+			d.Ack(false)
+		}
+	}
+}
+
+func (s *TenantService) routeDeadLetter(tenantID string, d amqp.Delivery) error {
+	channel := channelFromQueue(tenantID, deathQueue(d.Headers))
+	if deathReason(d.Headers) == deathReasonExpired {
+		decoded, err := s.codecs.Decode(context.Background(), d.ContentType, d.Body)
+		if err != nil {
+			return err
+		}
+		dedupKey, err := s.dedup.Key(tenantID, d.MessageId, d.Body)
+		if err != nil {
+			return err
+		}
+		ctx := logging.With(context.Background(), "tenant_id", tenantID, "message_id", d.MessageId)
+		return s.storeMessage(ctx, tenantID, channel, d.MessageId, dedupKey, d.Type, 0, nil, decoded.JSON, decoded, deliverAtOf(d.Headers), domain.MessageStatusExpired)
+	}
+
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	headers[deadLetteredAtHeader] = time.Now()
+	if channel != "" {
+		headers[channelHeader] = channel
+	}
+
+	err := s.rabbit.Channel().Publish(
+		"",                // exchange
+		dlqName(tenantID), // routing key
+		false,             // mandatory
+		false,             // immediate
+		amqp.Publishing{
+			Headers:       headers,
+			ContentType:   d.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			MessageId:     d.MessageId,
+			CorrelationId: d.CorrelationId,
+			ReplyTo:       d.ReplyTo,
+			Type:          d.Type,
+			Timestamp:     d.Timestamp,
+			Priority:      d.Priority,
+			Body:          d.Body,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s.events.Emit(events.TypeMessageDeadLettered, tenantID, map[string]interface{}{
+		"message_id":  d.MessageId,
+		"channel":     channel,
+		"type":        d.Type,
+		"reason":      deathReason(d.Headers),
+		"retry_count": headerInt(d.Headers[retryCountHeader]),
+	})
+	return nil
+}
+
+// retryDeadLetters periodically moves the tenant's DLQ messages that are due
+// under its retry policy back to the tenant queue
+func (s *TenantService) retryDeadLetters(ctx context.Context, ch *amqp.Channel, tenantID string) {
+	ticker := time.NewTicker(s.dlqRetries.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.retryDueDeadLetters(ch, tenantID, time.Now()); err != nil {
+				slog.Error("Failed to retry dead letters", "tenant_id", tenantID, "error", err)
+			}
+		}
+	}
+}
+
+// retryDueDeadLetters scans the head of the tenant's DLQ once. Messages that
+// are not due yet are held until the end of the scan and then requeued, which
+// puts them back in their original position.
+func (s *TenantService) retryDueDeadLetters(ch *amqp.Channel, tenantID string, now time.Time) error {
+	policy, err := s.dlqRetries.policy(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load retry policy: %w", err)
+	}
+	if !policy.Enabled {
+		return nil
+	}
+
+	var pending []amqp.Delivery
+	defer func() {
+		for _, d := range pending {
+			d.Nack(false, true)
+		}
+	}()
+
+	for remaining := dlqRetryBatch; remaining > 0; remaining-- {
+		d, ok, err := ch.Get(dlqName(tenantID), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		// Pesan yang dikembalikan ke DLQ saat scan ini ada di belakang antrean
+		if int(d.MessageCount) < remaining {
+			remaining = int(d.MessageCount) + 1
+		}
+
+		settled, err := s.retryDeadLetter(tenantID, d, policy.ScheduleSeconds, now)
+		if err != nil {
+			d.Nack(false, true)
+			return err
+		}
+		if !settled {
+			pending = append(pending, d)
+			continue
+		}
+		d.Ack(false)
+	}
+	return nil
+}
+
+// retryDeadLetter republishes d to the tenant queue when its next retry is
+// due, or marks it exhausted when the schedule has run out. It reports
+// whether d was republished and can be acked.
+func (s *TenantService) retryDeadLetter(tenantID string, d amqp.Delivery, schedule []int, now time.Time) (bool, error) {
+	if exhausted, _ := d.Headers[retryExhaustedHeader].(bool); exhausted {
+		return false, nil
+	}
+
+	attempts := headerInt(d.Headers[retryCountHeader])
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+
+	queue := dlqName(tenantID)
+	outcome := domain.DLQRetryGaveUp
+	if attempts < len(schedule) {
+		deadLetteredAt, _ := d.Headers[deadLetteredAtHeader].(time.Time)
+		if now.Sub(deadLetteredAt) < time.Duration(schedule[attempts])*time.Second {
+			return false, nil
+		}
+		queue = s.retryQueueName(tenantID, d.Headers)
+		outcome = domain.DLQRetryRetried
+		attempts++
+		headers[retryCountHeader] = int64(attempts)
+		delete(headers, deadLetteredAtHeader)
+		// Pesan yang diulang dari DLQ mendapat jatah backoff baru
+		delete(headers, attemptsHeader)
+	} else {
+		headers[retryExhaustedHeader] = true
+	}
+
+	err := s.rabbit.Channel().Publish("", queue, false, false, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     d.MessageId,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		Type:          d.Type,
+		Timestamp:     d.Timestamp,
+		Priority:      d.Priority,
+		Body:          d.Body,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	metrics.ObserveDLQRetry(tenantID, outcome)
+	if err := s.dlqRetries.record(tenantID, d.MessageId, attempts, outcome); err != nil {
+		slog.Error("Failed to record DLQ retry", "tenant_id", tenantID, "message_id", d.MessageId, "error", err)
+	}
+	return true, nil
+}
+
+// retryQueueName is the queue a dead-lettered message is retried on: the
+// channel it came from while that channel exists, otherwise the main queue
+func (s *TenantService) retryQueueName(tenantID string, headers amqp.Table) string {
+	if name, _ := headers[channelHeader].(string); name != "" {
+		if channel, err := s.GetChannel(tenantID, name); err == nil {
+			return channel.QueueName
+		}
+	}
+	return s.currentQueueName(tenantID)
+}
+
+// headerInt reads an integer AMQP header value, 0 if missing
+func headerInt(value interface{}) int {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int16:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// deathReason returns why a message was dead-lettered (most recent death)
+func deathReason(headers amqp.Table) string {
+	if deaths, ok := headers["x-death"].([]interface{}); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp.Table); ok {
+			if reason, ok := death["reason"].(string); ok {
+				return reason
+			}
+		}
+	}
+	if reason, ok := headers["x-first-death-reason"].(string); ok {
+		return reason
+	}
+	return ""
+}
+
+// ExpiredCount returns how many of the tenant's messages expired before processing
+func (s *TenantService) ExpiredCount(tenantID string) (int64, error) {
+	var count int64
+	err := s.db.WithTenant(context.Background(), tenantID, func(q repository.Querier) error {
+		return q.QueryRowContext(context.Background(),
+			"SELECT COUNT(*) FROM messages WHERE tenant_id = $1 AND status = $2",
+			tenantID, domain.MessageStatusExpired,
+		).Scan(&count)
+	})
+	return count, err
+}
+
+// deathQueue returns the queue a message was dead-lettered from (most recent death)
+func deathQueue(headers amqp.Table) string {
+	if deaths, ok := headers["x-death"].([]interface{}); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp.Table); ok {
+			if queue, ok := death["queue"].(string); ok {
+				return queue
+			}
+		}
+	}
+	queue, _ := headers["x-first-death-queue"].(string)
+	return queue
+}
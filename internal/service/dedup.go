@@ -0,0 +1,299 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// ErrInvalidDedupConfig is returned for a negative window or an unknown key
+var ErrInvalidDedupConfig = errors.New("invalid dedup config")
+
+// dedupConfigCacheTTL bounds how long a config change on another instance takes to apply
+const dedupConfigCacheTTL = 30 * time.Second
+
+// payloadHashPrefix marks payload hashes among the stored dedup keys
+const payloadHashPrefix = "sha256:"
+
+// outboxKeyPrefix marks the outbox markers among the stored dedup keys. They
+// are used for relayed messages of tenants without a dedup window.
+const outboxKeyPrefix = "outbox:"
+
+// outboxDedupWindow is how long an outbox marker is remembered; the relay
+// publishes a message again only right after a crash
+const outboxDedupWindow = time.Hour
+
+type dedupEntry struct {
+	key    string
+	seenAt time.Time
+}
+
+// seenCache is a bounded LRU of recently seen tenant/dedup key pairs
+type seenCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+func newSeenCache(capacity int) *seenCache {
+	return &seenCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *seenCache) get(key string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*dedupEntry).seenAt, true
+}
+
+func (c *seenCache) add(key string, seenAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*dedupEntry).seenAt = seenAt
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&dedupEntry{key: key, seenAt: seenAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*dedupEntry).key)
+	}
+}
+
+type cachedConfig struct {
+	config   domain.DedupConfig
+	loadedAt time.Time
+}
+
+// DedupService drops messages whose dedup key, the AMQP message-id or a hash
+// of the payload, was already seen for the tenant within its dedup window.
+// Recent keys are kept in an in-memory LRU; the message_dedup table is the
+// source of truth across restarts and instances.
+type DedupService struct {
+	db   *repository.Database
+	seen *seenCache
+
+	mu      sync.RWMutex
+	configs map[string]cachedConfig
+}
+
+func NewDedupService(db *repository.Database, cacheSize int) *DedupService {
+	if cacheSize <= 0 {
+		cacheSize = 100000
+	}
+	return &DedupService{
+		db:      db,
+		seen:    newSeenCache(cacheSize),
+		configs: make(map[string]cachedConfig),
+	}
+}
+
+// GetConfig returns the tenant's dedup config; a zero window means disabled
+func (s *DedupService) GetConfig(tenantID string) (domain.DedupConfig, error) {
+	config := domain.DedupConfig{Key: domain.DedupKeyMessageID}
+	err := s.db.DB.QueryRow(
+		"SELECT dedup_window_seconds, dedup_key FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&config.WindowSeconds, &config.Key)
+	if err == sql.ErrNoRows {
+		return config, nil
+	}
+	if err != nil {
+		return domain.DedupConfig{}, err
+	}
+	return config, nil
+}
+
+// SetConfig updates the tenant's dedup config; a zero window disables
+// deduplication and an empty key means message_id
+func (s *DedupService) SetConfig(tenantID string, config domain.DedupConfig) (domain.DedupConfig, error) {
+	if config.Key == "" {
+		config.Key = domain.DedupKeyMessageID
+	}
+	if config.WindowSeconds < 0 {
+		return domain.DedupConfig{}, fmt.Errorf("%w: window_seconds must not be negative", ErrInvalidDedupConfig)
+	}
+	if config.Key != domain.DedupKeyMessageID && config.Key != domain.DedupKeyPayloadHash {
+		return domain.DedupConfig{}, fmt.Errorf("%w: key must be %s or %s", ErrInvalidDedupConfig, domain.DedupKeyMessageID, domain.DedupKeyPayloadHash)
+	}
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, dedup_window_seconds, dedup_key) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET dedup_window_seconds = EXCLUDED.dedup_window_seconds, dedup_key = EXCLUDED.dedup_key
+	`, tenantID, config.WindowSeconds, config.Key)
+	if err != nil {
+		return domain.DedupConfig{}, err
+	}
+
+	s.mu.Lock()
+	s.configs[tenantID] = cachedConfig{config: config, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return config, nil
+}
+
+// Key returns what the tenant's message is deduplicated on: its message-id,
+// or a hash of body with the payload_hash key. It is empty when the message
+// is not deduplicated, because the tenant has no window or the message no ID.
+func (s *DedupService) Key(tenantID, messageID string, body []byte) (string, error) {
+	config, err := s.config(tenantID)
+	if err != nil || config.WindowSeconds == 0 {
+		return "", err
+	}
+	if config.Key == domain.DedupKeyPayloadHash {
+		sum := sha256.Sum256(body)
+		return payloadHashPrefix + hex.EncodeToString(sum[:]), nil
+	}
+	return messageID, nil
+}
+
+// IsRecentDuplicate is a cheap in-memory pre-check that avoids a database
+// round trip for duplicates seen by this instance
+func (s *DedupService) IsRecentDuplicate(tenantID, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	window, err := s.keyWindow(tenantID, key)
+	if err != nil || window == 0 {
+		return false, err
+	}
+	seenAt, ok := s.seen.get(tenantID + "|" + key)
+	return ok && time.Since(seenAt) < window, nil
+}
+
+// Claim records key as seen within q's transaction. It returns false when
+// the key was already seen within the window, meaning the message is a
+// duplicate and must not be stored. The caller must call Remember after commit.
+func (s *DedupService) Claim(ctx context.Context, q repository.Querier, tenantID, key string) (bool, error) {
+	if key == "" {
+		return true, nil
+	}
+	window, err := s.keyWindow(tenantID, key)
+	if err != nil || window == 0 {
+		return true, err
+	}
+
+	// Baris lama di luar window boleh ditimpa; di dalam window berarti duplikat
+	var claimed string
+	err = q.QueryRowContext(ctx, `
+		INSERT INTO message_dedup (tenant_id, message_id, seen_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (tenant_id, message_id) DO UPDATE SET seen_at = EXCLUDED.seen_at
+		WHERE message_dedup.seen_at < NOW() - make_interval(secs => $3)
+		RETURNING message_id
+	`, tenantID, key, window.Seconds()).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remember caches key as seen after its message was committed
+func (s *DedupService) Remember(tenantID, key string) {
+	if key == "" {
+		return
+	}
+	s.seen.add(tenantID+"|"+key, time.Now())
+}
+
+// PurgeExpired deletes seen keys that fell out of their tenant's window, and
+// outbox markers older than outboxDedupWindow
+func (s *DedupService) PurgeExpired() (int64, error) {
+	res, err := s.db.DB.Exec(`
+		DELETE FROM message_dedup d
+		USING tenant_configs c
+		WHERE d.tenant_id = c.tenant_id
+			AND d.message_id NOT LIKE $1 || '%'
+			AND d.seen_at < NOW() - make_interval(secs => c.dedup_window_seconds)
+	`, outboxKeyPrefix)
+	if err != nil {
+		return 0, err
+	}
+	purged, _ := res.RowsAffected()
+
+	res, err = s.db.DB.Exec(`
+		DELETE FROM message_dedup
+		WHERE message_id LIKE $1 || '%' AND seen_at < NOW() - make_interval(secs => $2)
+	`, outboxKeyPrefix, outboxDedupWindow.Seconds())
+	if err != nil {
+		return purged, err
+	}
+	markers, _ := res.RowsAffected()
+	return purged + markers, nil
+}
+
+// RunJanitor purges expired seen keys every interval until ctx is done
+func (s *DedupService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.PurgeExpired(); err != nil {
+				slog.Error("Failed to purge dedup entries", "error", err)
+			} else if n > 0 {
+				slog.Info("Purged expired dedup entries", "count", n)
+			}
+		}
+	}
+}
+
+// keyWindow is the window of key: outboxDedupWindow for an outbox marker, the
+// tenant's window otherwise
+func (s *DedupService) keyWindow(tenantID, key string) (time.Duration, error) {
+	if strings.HasPrefix(key, outboxKeyPrefix) {
+		return outboxDedupWindow, nil
+	}
+	return s.window(tenantID)
+}
+
+func (s *DedupService) window(tenantID string) (time.Duration, error) {
+	config, err := s.config(tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(config.WindowSeconds) * time.Second, nil
+}
+
+func (s *DedupService) config(tenantID string) (domain.DedupConfig, error) {
+	s.mu.RLock()
+	cached, ok := s.configs[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < dedupConfigCacheTTL {
+		return cached.config, nil
+	}
+
+	config, err := s.GetConfig(tenantID)
+	if err != nil {
+		return domain.DedupConfig{}, err
+	}
+
+	s.mu.Lock()
+	s.configs[tenantID] = cachedConfig{config: config, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return config, nil
+}
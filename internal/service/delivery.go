@@ -0,0 +1,147 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type trackedDelivery struct {
+	delivery   amqp.Delivery
+	receivedAt time.Time
+	startedAt  time.Time
+	worker     int
+}
+
+// DeliveryTracker keeps track of unacked deliveries per tenant and makes
+// sure each delivery is acked or nacked exactly once
+type DeliveryTracker struct {
+	mu         sync.Mutex
+	deliveries map[string]map[uint64]*trackedDelivery
+}
+
+func NewDeliveryTracker() *DeliveryTracker {
+	return &DeliveryTracker{
+		deliveries: make(map[string]map[uint64]*trackedDelivery),
+	}
+}
+
+// Track registers a delivery received from the tenant's queue
+func (t *DeliveryTracker) Track(tenantID string, d amqp.Delivery) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.deliveries[tenantID] == nil {
+		t.deliveries[tenantID] = make(map[uint64]*trackedDelivery)
+	}
+	t.deliveries[tenantID][d.DeliveryTag] = &trackedDelivery{
+		delivery:   d,
+		receivedAt: time.Now(),
+	}
+}
+
+// Start marks a delivery as picked up by a worker. It returns false when the
+// delivery was already settled (e.g. discarded by an operator) and must be skipped.
+func (t *DeliveryTracker) Start(tenantID string, tag uint64, workerID int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	td, ok := t.deliveries[tenantID][tag]
+	if !ok {
+		return false
+	}
+	td.startedAt = time.Now()
+	td.worker = workerID
+	return true
+}
+
+// Ack acknowledges a delivery unless it was already settled
+func (t *DeliveryTracker) Ack(tenantID string, tag uint64) error {
+	td, ok := t.remove(tenantID, tag)
+	if !ok {
+		return nil
+	}
+	return td.delivery.Ack(false)
+}
+
+// Nack rejects a delivery unless it was already settled
+func (t *DeliveryTracker) Nack(tenantID string, tag uint64, requeue bool) error {
+	td, ok := t.remove(tenantID, tag)
+	if !ok {
+		return nil
+	}
+	return td.delivery.Nack(false, requeue)
+}
+
+// Settle rejects a delivery no worker has started yet, like Nack. It
+// returns false without settling it when a worker already processes it, so
+// the worker's own ack or nack is the only settlement, unless the worker
+// started it before startedBefore: such a worker is taken to be hung and its
+// later ack or nack is ignored. A zero startedBefore never reclaims a
+// delivery from its worker.
+func (t *DeliveryTracker) Settle(tenantID string, tag uint64, requeue bool, startedBefore time.Time) (bool, error) {
+	t.mu.Lock()
+	td, ok := t.deliveries[tenantID][tag]
+	if !ok || (!td.startedAt.IsZero() && !td.startedAt.Before(startedBefore)) {
+		t.mu.Unlock()
+		return false, nil
+	}
+	delete(t.deliveries[tenantID], tag)
+	t.mu.Unlock()
+	return true, td.delivery.Nack(false, requeue)
+}
+
+// List returns the tenant's unacked deliveries older than minAge, oldest first
+func (t *DeliveryTracker) List(tenantID string, minAge time.Duration) []domain.DeliveryInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	infos := make([]domain.DeliveryInfo, 0)
+	for tag, td := range t.deliveries[tenantID] {
+		age := now.Sub(td.receivedAt)
+		if age < minAge {
+			continue
+		}
+		info := domain.DeliveryInfo{
+			TenantID:    tenantID,
+			DeliveryTag: tag,
+			State:       domain.DeliveryStateQueued,
+			ReceivedAt:  td.receivedAt,
+			AgeSeconds:  age.Seconds(),
+		}
+		if !td.startedAt.IsZero() {
+			startedAt := td.startedAt
+			info.State = domain.DeliveryStateProcessing
+			info.StartedAt = &startedAt
+			info.Worker = td.worker
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ReceivedAt.Before(infos[j].ReceivedAt)
+	})
+	return infos
+}
+
+// Forget drops every tracked delivery of a tenant without settling them;
+// used when the tenant's channel is gone and the broker requeues them anyway
+func (t *DeliveryTracker) Forget(tenantID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.deliveries, tenantID)
+}
+
+func (t *DeliveryTracker) remove(tenantID string, tag uint64) (*trackedDelivery, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	td, ok := t.deliveries[tenantID][tag]
+	if !ok {
+		return nil, false
+	}
+	delete(t.deliveries[tenantID], tag)
+	return td, true
+}
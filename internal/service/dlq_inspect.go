@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrInvalidDLQPage is returned for a DLQ page offset or limit out of range
+var ErrInvalidDLQPage = errors.New("invalid DLQ page")
+
+// maxDLQPeek caps how deep into the DLQ a page can reach, as every message
+// up to the end of the page is held unacked while it is read
+const maxDLQPeek = 1000
+
+// ListDeadLetters returns limit messages of the tenant's DLQ starting at
+// offset, in queue order. The messages are read without being removed and
+// go back to the DLQ in their original order.
+func (s *TenantService) ListDeadLetters(ctx context.Context, tenantID string, offset, limit int) (domain.DeadLetterPage, error) {
+	page := domain.DeadLetterPage{Messages: make([]domain.DeadLetter, 0)}
+	if offset < 0 || limit < 1 || offset+limit > maxDLQPeek {
+		return page, fmt.Errorf("%w: offset and limit must reach at most %d messages deep", ErrInvalidDLQPage, maxDLQPeek)
+	}
+	if err := s.onRabbitMQ(); err != nil {
+		return page, err
+	}
+	if _, active := s.tenantManager.GetConfig(tenantID); !active {
+		return page, ErrTenantNotFound
+	}
+
+	ch, err := s.rabbit.OpenChannel()
+	if err != nil {
+		return page, err
+	}
+	defer ch.Close()
+
+	var held []amqp.Delivery
+	defer func() {
+		for _, d := range held {
+			d.Nack(false, true)
+		}
+	}()
+
+	for len(held) < offset+limit {
+		if err := ctx.Err(); err != nil {
+			return page, err
+		}
+		d, ok, err := ch.Get(dlqName(tenantID), false)
+		if err != nil {
+			return page, err
+		}
+		if !ok {
+			break
+		}
+		if len(held) == 0 {
+			page.Total = int(d.MessageCount) + 1
+		}
+		held = append(held, d)
+		if len(held) > offset {
+			page.Messages = append(page.Messages, s.deadLetter(ctx, tenantID, d))
+		}
+	}
+
+	if offset+limit < page.Total {
+		page.NextOffset = offset + limit
+	}
+	return page, nil
+}
+
+// deadLetter describes a DLQ delivery
+func (s *TenantService) deadLetter(ctx context.Context, tenantID string, d amqp.Delivery) domain.DeadLetter {
+	letter := domain.DeadLetter{
+		MessageID:   d.MessageId,
+		MessageType: d.Type,
+		ContentType: d.ContentType,
+		Reason:      deathReason(d.Headers),
+		RetryCount:  headerInt(d.Headers[retryCountHeader]),
+		Headers:     filterHeaders(d.Headers),
+	}
+	letter.Channel, _ = d.Headers[channelHeader].(string)
+	if deadLetteredAt, ok := d.Headers[deadLetteredAtHeader].(time.Time); ok {
+		letter.DeadLetteredAt = &deadLetteredAt
+	}
+
+	decoded, err := s.codecs.Decode(ctx, d.ContentType, d.Body)
+	if err != nil {
+		letter.Error = err.Error()
+		return letter
+	}
+	if !json.Valid(decoded.JSON) {
+		letter.Error = "payload is not valid JSON"
+		return letter
+	}
+	letter.Payload = decoded.JSON
+	return letter
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/codec"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/filter"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrInvalidRedrive is returned when a redrive's match expression or transform is invalid
+var ErrInvalidRedrive = errors.New("invalid redrive")
+
+// redrivenAtHeader is when a message was last redriven from the DLQ
+const redrivenAtHeader = "x-salva-redriven-at"
+
+// Redrive limits
+const (
+	maxRedriveMessages   = 10000
+	maxRedriveErrors     = 10
+	maxRedrivePreviews   = 10
+	redriveMatchRuleName = "match"
+)
+
+// errPatchNeedsJSON is reported for messages whose payload is not a JSON
+// document the patch can be applied to
+var errPatchNeedsJSON = errors.New("patch needs a JSON payload")
+
+// RedriveDeadLetters moves the tenant's dead-lettered messages matching
+// req.Match back to the queue they came from, after applying req.Transform.
+// Messages that do not match or fail to transform stay in the DLQ in their
+// original order. The retry scheduler starts over for redriven messages.
+func (s *TenantService) RedriveDeadLetters(ctx context.Context, tenantID string, req domain.DLQRedrive) (domain.DLQRedriveResult, error) {
+	result := domain.DLQRedriveResult{DryRun: req.DryRun}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = maxRedriveMessages
+	}
+	if limit < 0 || limit > maxRedriveMessages {
+		return result, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRedrive, maxRedriveMessages)
+	}
+
+	var match *filter.Set
+	if req.Match != "" {
+		var limits filter.Limits
+		if s.filters != nil {
+			limits = s.filters.limits
+		}
+		set, err := filter.Compile([]filter.Rule{{
+			Name:       redriveMatchRuleName,
+			Expression: req.Match,
+			Action:     filter.ActionTag,
+			Target:     redriveMatchRuleName,
+		}}, limits)
+		if err != nil {
+			return result, fmt.Errorf("%w: %v", ErrInvalidRedrive, err)
+		}
+		match = set
+	}
+
+	var patch any
+	transform := req.Transform
+	if transform == nil {
+		transform = &domain.DLQTransform{}
+	}
+	if len(transform.Patch) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(transform.Patch))
+		decoder.UseNumber()
+		if err := decoder.Decode(&patch); err != nil {
+			return result, fmt.Errorf("%w: patch: %v", ErrInvalidRedrive, err)
+		}
+	}
+
+	var selector dlqSelector
+	if match != nil {
+		flags := s.runtime.Flags(tenantID)
+		selector = func(ctx context.Context, d amqp.Delivery, decoded codec.Decoded) (bool, error) {
+			decision, err := match.Evaluate(ctx, filter.Input{
+				ID:      d.MessageId,
+				Type:    d.Type,
+				Headers: filterHeaders(d.Headers),
+				Body:    decoded.JSON,
+				Flags:   flags,
+			})
+			if err != nil {
+				return false, err
+			}
+			return len(decision.Matched) > 0, nil
+		}
+	}
+	return s.moveDeadLetters(ctx, tenantID, selector, transform.RemoveHeaders, patch, limit, req.DryRun)
+}
+
+// ReplayDeadLetters moves the tenant's dead-lettered messages with the given
+// IDs, or every message if req.All is set, back to the queue they came from
+// unchanged
+func (s *TenantService) ReplayDeadLetters(ctx context.Context, tenantID string, req domain.DLQReplay) (domain.DLQRedriveResult, error) {
+	if req.All == (len(req.MessageIDs) > 0) {
+		return domain.DLQRedriveResult{DryRun: req.DryRun}, fmt.Errorf("%w: give either message_ids or all", ErrInvalidRedrive)
+	}
+	if len(req.MessageIDs) > maxRedriveMessages {
+		return domain.DLQRedriveResult{DryRun: req.DryRun}, fmt.Errorf("%w: at most %d message_ids", ErrInvalidRedrive, maxRedriveMessages)
+	}
+	if req.All {
+		return s.moveDeadLetters(ctx, tenantID, nil, nil, nil, maxRedriveMessages, req.DryRun)
+	}
+
+	// Setiap ID hanya dipindahkan sekali, duplikat di DLQ tetap tinggal
+	wanted := make(map[string]bool, len(req.MessageIDs))
+	for _, id := range req.MessageIDs {
+		wanted[id] = true
+	}
+	selector := func(_ context.Context, d amqp.Delivery, _ codec.Decoded) (bool, error) {
+		if !wanted[d.MessageId] {
+			return false, nil
+		}
+		delete(wanted, d.MessageId)
+		return true, nil
+	}
+	return s.moveDeadLetters(ctx, tenantID, selector, nil, nil, len(wanted), req.DryRun)
+}
+
+// dlqSelector reports whether a dead-lettered message is moved; nil selects
+// every message
+type dlqSelector func(ctx context.Context, d amqp.Delivery, decoded codec.Decoded) (bool, error)
+
+// moveDeadLetters moves up to limit messages of the tenant's DLQ chosen by
+// selector back to the queue they came from, with removeHeaders dropped and
+// patch merged into their payload. The other messages are returned to the
+// DLQ in their original order. The retry scheduler starts over for moved
+// messages.
+func (s *TenantService) moveDeadLetters(ctx context.Context, tenantID string, selector dlqSelector, removeHeaders []string, patch any, limit int, dryRun bool) (domain.DLQRedriveResult, error) {
+	result := domain.DLQRedriveResult{DryRun: dryRun}
+	if err := s.onRabbitMQ(); err != nil {
+		return result, err
+	}
+	if _, active := s.tenantManager.GetConfig(tenantID); !active {
+		return result, ErrTenantNotFound
+	}
+
+	ch, err := s.rabbit.OpenChannel()
+	if err != nil {
+		return result, err
+	}
+	defer ch.Close()
+
+	// Pesan yang tidak dipindahkan dikembalikan sekaligus agar urutannya tetap
+	var pending []amqp.Delivery
+	defer func() {
+		for _, d := range pending {
+			d.Nack(false, true)
+		}
+	}()
+
+	now := time.Now()
+	for remaining := maxRedriveMessages; remaining > 0 && result.Redriven < limit; remaining-- {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		d, ok, err := ch.Get(dlqName(tenantID), false)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			break
+		}
+		if int(d.MessageCount) < remaining {
+			remaining = int(d.MessageCount) + 1
+		}
+		result.Scanned++
+
+		decoded, err := s.codecs.Decode(ctx, d.ContentType, d.Body)
+		if err != nil {
+			redriveFailed(&result, d.MessageId, err)
+			pending = append(pending, d)
+			continue
+		}
+
+		if selector != nil {
+			selected, err := selector(ctx, d, decoded)
+			if err != nil {
+				redriveFailed(&result, d.MessageId, err)
+				pending = append(pending, d)
+				continue
+			}
+			if !selected {
+				result.Skipped++
+				pending = append(pending, d)
+				continue
+			}
+		}
+
+		msg, err := redriveMessage(d, decoded, removeHeaders, patch, now)
+		if err != nil {
+			redriveFailed(&result, d.MessageId, err)
+			pending = append(pending, d)
+			continue
+		}
+
+		if dryRun {
+			if len(result.Preview) < maxRedrivePreviews {
+				payload := msg.Body
+				if decoded.ContentType != "" {
+					payload = decoded.JSON
+				}
+				result.Preview = append(result.Preview, domain.DLQRedrivePreview{
+					MessageID: d.MessageId,
+					Headers:   filterHeaders(msg.Headers),
+					Payload:   json.RawMessage(payload),
+				})
+			}
+			result.Redriven++
+			pending = append(pending, d)
+			continue
+		}
+
+		if err := s.rabbit.Channel().Publish("", s.retryQueueName(tenantID, d.Headers), false, false, msg); err != nil {
+			d.Nack(false, true)
+			return result, err
+		}
+		d.Ack(false)
+		result.Redriven++
+	}
+
+	if !dryRun && result.Redriven > 0 {
+		slog.Info("Moved messages from the DLQ back", "tenant_id", tenantID, "redriven", result.Redriven, "skipped", result.Skipped, "failed", result.Failed)
+	}
+	return result, nil
+}
+
+// redriveFailed counts a message that stays in the DLQ because of err
+func redriveFailed(result *domain.DLQRedriveResult, messageID string, err error) {
+	result.Failed++
+	if len(result.Errors) < maxRedriveErrors {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", messageID, err))
+	}
+}
+
+// redriveMessage builds the publishing of a redriven message: removeHeaders
+// and the retry scheduler's headers are dropped and patch is merged into the
+// payload
+func redriveMessage(d amqp.Delivery, decoded codec.Decoded, removeHeaders []string, patch any, now time.Time) (amqp.Publishing, error) {
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	for _, key := range removeHeaders {
+		delete(headers, key)
+	}
+	delete(headers, retryCountHeader)
+	delete(headers, retryExhaustedHeader)
+	delete(headers, deadLetteredAtHeader)
+	delete(headers, attemptsHeader)
+	headers[redrivenAtHeader] = now
+
+	body := d.Body
+	if patch != nil {
+		if decoded.ContentType != "" {
+			return amqp.Publishing{}, fmt.Errorf("%w, not %s", errPatchNeedsJSON, decoded.ContentType)
+		}
+		patched, err := applyMergePatch(body, patch)
+		if err != nil {
+			return amqp.Publishing{}, err
+		}
+		body = patched
+	}
+
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     d.MessageId,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		Type:          d.Type,
+		Timestamp:     d.Timestamp,
+		Priority:      d.Priority,
+		Body:          body,
+	}, nil
+}
+
+// applyMergePatch applies a JSON merge patch (RFC 7396) to body. Numbers
+// keep their original text.
+func applyMergePatch(body []byte, patch any) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var target any
+	if err := decoder.Decode(&target); err != nil {
+		return nil, errPatchNeedsJSON
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(mergePatch(target, patch)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+func mergePatch(target, patch any) any {
+	fields, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = make(map[string]any, len(fields))
+	}
+	for key, value := range fields {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergePatch(object[key], value)
+	}
+	return object
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// dlqRetryCacheTTL bounds how long a policy change on another instance takes to apply
+const dlqRetryCacheTTL = 30 * time.Second
+
+type cachedRetryPolicy struct {
+	policy   domain.DLQRetryPolicy
+	loadedAt time.Time
+}
+
+// DLQRetryService manages per-tenant DLQ retry policies and records the
+// attempts of the retry scheduler
+type DLQRetryService struct {
+	db       *repository.Database
+	defaults domain.DLQRetryPolicy
+	// interval is how often each tenant's DLQ is scanned for due messages
+	interval time.Duration
+
+	mu       sync.Mutex
+	policies map[string]cachedRetryPolicy
+}
+
+func NewDLQRetryService(db *repository.Database, enabled bool, schedule []time.Duration, interval time.Duration) *DLQRetryService {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	seconds := make([]int, len(schedule))
+	for i, delay := range schedule {
+		seconds[i] = int(delay.Seconds())
+	}
+	return &DLQRetryService{
+		db:       db,
+		defaults: domain.DLQRetryPolicy{Enabled: enabled, ScheduleSeconds: seconds, Default: true},
+		interval: interval,
+		policies: make(map[string]cachedRetryPolicy),
+	}
+}
+
+// GetPolicy returns the tenant's retry policy, falling back to the config defaults
+func (s *DLQRetryService) GetPolicy(tenantID string) (domain.DLQRetryPolicy, error) {
+	var enabled sql.NullBool
+	var schedule []byte
+	err := s.db.DB.QueryRow(
+		"SELECT dlq_retry_enabled, dlq_retry_schedule FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&enabled, &schedule)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.DLQRetryPolicy{}, err
+	}
+
+	policy := s.defaults
+	if enabled.Valid {
+		policy.Enabled = enabled.Bool
+		policy.Default = false
+	}
+	if schedule != nil {
+		if err := json.Unmarshal(schedule, &policy.ScheduleSeconds); err != nil {
+			return domain.DLQRetryPolicy{}, err
+		}
+		policy.Default = false
+	}
+	return policy, nil
+}
+
+// SetPolicy overrides the tenant's retry policy. Messages already in the DLQ
+// follow the new schedule from the next scan.
+func (s *DLQRetryService) SetPolicy(tenantID string, enabled bool, scheduleSeconds []int) (domain.DLQRetryPolicy, error) {
+	for _, delay := range scheduleSeconds {
+		if delay < 0 {
+			return domain.DLQRetryPolicy{}, fmt.Errorf("schedule delays must not be negative")
+		}
+	}
+	if scheduleSeconds == nil {
+		scheduleSeconds = []int{}
+	}
+	schedule, err := json.Marshal(scheduleSeconds)
+	if err != nil {
+		return domain.DLQRetryPolicy{}, err
+	}
+	_, err = s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, dlq_retry_enabled, dlq_retry_schedule) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET dlq_retry_enabled = EXCLUDED.dlq_retry_enabled, dlq_retry_schedule = EXCLUDED.dlq_retry_schedule
+	`, tenantID, enabled, schedule)
+	if err != nil {
+		return domain.DLQRetryPolicy{}, err
+	}
+
+	policy := domain.DLQRetryPolicy{Enabled: enabled, ScheduleSeconds: scheduleSeconds}
+	s.mu.Lock()
+	s.policies[tenantID] = cachedRetryPolicy{policy: policy, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return policy, nil
+}
+
+func (s *DLQRetryService) policy(tenantID string) (domain.DLQRetryPolicy, error) {
+	s.mu.Lock()
+	cached, ok := s.policies[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < dlqRetryCacheTTL {
+		return cached.policy, nil
+	}
+
+	policy, err := s.GetPolicy(tenantID)
+	if err != nil {
+		return domain.DLQRetryPolicy{}, err
+	}
+	s.mu.Lock()
+	s.policies[tenantID] = cachedRetryPolicy{policy: policy, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return policy, nil
+}
+
+// record stores one retry decision
+func (s *DLQRetryService) record(tenantID, messageID string, attempt int, outcome string) error {
+	_, err := s.db.DB.Exec(`
+		INSERT INTO dlq_retry_attempts (tenant_id, message_id, attempt, outcome) VALUES ($1, $2, $3, $4)
+	`, tenantID, messageID, attempt, outcome)
+	return err
+}
+
+// ListAttempts returns the tenant's most recent retry decisions, optionally
+// only those for one message
+func (s *DLQRetryService) ListAttempts(tenantID, messageID string, limit int) ([]domain.DLQRetryAttempt, error) {
+	query := `
+		SELECT id, tenant_id, message_id, attempt, outcome, created_at FROM dlq_retry_attempts
+		WHERE tenant_id = $1`
+	args := []interface{}{tenantID}
+	if messageID != "" {
+		query += " AND message_id = $2"
+		args = append(args, messageID)
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT %d", limit)
+
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]domain.DLQRetryAttempt, 0)
+	for rows.Next() {
+		var a domain.DLQRetryAttempt
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.MessageID, &a.Attempt, &a.Outcome, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
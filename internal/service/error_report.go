@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/codec"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/filter"
+	"multi-tenant-messaging/pkg/processor"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// errorReportsKey is the runtime config key that turns on error reports for
+// a tenant: "reply_to" answers on the delivery's reply-to queue (falling
+// back to the errors queue), "queue" always uses the errors queue
+const errorReportsKey = "error_reports"
+
+// Error report destinations
+const (
+	ErrorReportsReplyTo = "reply_to"
+	ErrorReportsQueue   = "queue"
+)
+
+// errorReportType is the AMQP type of published error reports
+const errorReportType = "salva.error_report"
+
+// errorsQueueName is the tenant's queue collecting error reports
+func errorsQueueName(tenantID string) string {
+	return fmt.Sprintf("tenant_%s_errors", tenantID)
+}
+
+// permanentFailureReason classifies errors that will not go away on retry;
+// it returns "" for errors worth requeueing
+func permanentFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrSchemaValidation):
+		return domain.ErrorReasonSchemaValidation
+	case errors.Is(err, codec.ErrDecode):
+		return domain.ErrorReasonDecode
+	case errors.Is(err, processor.ErrReject):
+		return domain.ErrorReasonRejected
+	case errors.Is(err, filter.ErrLimitExceeded):
+		return domain.ErrorReasonFilterLimit
+	case errors.Is(err, ErrBlobNotAllowed):
+		return domain.ErrorReasonClaimCheckNotAllowed
+	case errors.Is(err, ErrBlobChecksum):
+		return domain.ErrorReasonClaimCheckChecksum
+	}
+	return ""
+}
+
+// errorReportDestination returns where the tenant wants error reports, or ""
+func (s *TenantService) errorReportDestination(tenantID string) string {
+	raw, err := s.runtime.Get(tenantID, errorReportsKey)
+	if err != nil {
+		return ""
+	}
+	var destination string
+	if json.Unmarshal(raw, &destination) != nil {
+		return ""
+	}
+	if destination != ErrorReportsReplyTo && destination != ErrorReportsQueue {
+		return ""
+	}
+	return destination
+}
+
+// reportError publishes an error report for a message that failed
+// permanently, if the tenant turned error reports on
+func (s *TenantService) reportError(tenantID, channel string, d amqp.Delivery, reason string, cause error) {
+	destination := s.errorReportDestination(tenantID)
+	if destination == "" {
+		return
+	}
+
+	report := domain.ErrorReport{
+		TenantID:    tenantID,
+		MessageID:   d.MessageId,
+		MessageType: d.Type,
+		Channel:     channel,
+		Reason:      reason,
+		Error:       cause.Error(),
+		FailedAt:    time.Now(),
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		slog.Error("Failed to encode error report", "tenant_id", tenantID, "message_id", d.MessageId, "error", err)
+		return
+	}
+
+	queue := d.ReplyTo
+	if destination == ErrorReportsQueue || queue == "" {
+		queue = errorsQueueName(tenantID)
+		if _, err := s.rabbit.Channel().QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			slog.Error("Failed to declare errors queue", "tenant_id", tenantID, "queue", queue, "error", err)
+			return
+		}
+	}
+
+	correlationID := d.CorrelationId
+	if correlationID == "" {
+		correlationID = d.MessageId
+	}
+	err = s.rabbit.Channel().Publish("", queue, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: correlationID,
+		Type:          errorReportType,
+		Timestamp:     report.FailedAt,
+		Body:          body,
+	})
+	if err != nil {
+		slog.Error("Failed to publish error report", "tenant_id", tenantID, "message_id", d.MessageId, "error", err)
+	}
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrExportNotFound is returned when an export job does not exist
+var ErrExportNotFound = errors.New("export job not found")
+
+// ErrInvalidExportFormat is returned for an unknown export format
+var ErrInvalidExportFormat = errors.New("invalid export format, expected ndjson or parquet")
+
+// ErrExportNotReady is returned when an export is downloaded before it completes
+var ErrExportNotReady = errors.New("export job is not completed")
+
+type ExportService struct {
+	db        *repository.Database
+	dir       string
+	chunkSize int
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func NewExportService(db *repository.Database, dir string, chunkSize int) *ExportService {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExportService{
+		ctx:       ctx,
+		cancel:    cancel,
+		db:        db,
+		dir:       dir,
+		chunkSize: chunkSize,
+		running:   make(map[string]bool),
+	}
+}
+
+// CreateExport registers a new export job in the given format and starts it in the background
+func (s *ExportService) CreateExport(tenantID, format string) (*domain.ExportJob, error) {
+	if format == "" {
+		format = domain.ExportFormatNDJSON
+	}
+	if format != domain.ExportFormatNDJSON && format != domain.ExportFormatParquet {
+		return nil, ErrInvalidExportFormat
+	}
+
+	id := uuid.New().String()
+	var tenant interface{}
+	if tenantID != "" {
+		tenant = tenantID
+	}
+
+	_, err := s.db.DB.Exec(
+		"INSERT INTO export_jobs (id, tenant_id, format, status) VALUES ($1, $2, $3, $4)",
+		id, tenant, format, domain.ExportStatusPending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	s.start(id)
+	return s.GetExport(id)
+}
+
+// GetExport returns the current state of an export job
+func (s *ExportService) GetExport(id string) (*domain.ExportJob, error) {
+	var job domain.ExportJob
+	var tenantID, errMsg sql.NullString
+	err := s.db.DB.QueryRow(`
+		SELECT id, tenant_id, format, status, exported_rows, chunk_count, error, created_at, updated_at
+		FROM export_jobs WHERE id = $1
+	`, id).Scan(&job.ID, &tenantID, &job.Format, &job.Status, &job.ExportedRows, &job.ChunkCount, &errMsg, &job.CreatedAt, &job.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.TenantID = tenantID.String
+	job.Error = errMsg.String
+	if job.Status == domain.ExportStatusCompleted {
+		job.DownloadURL = fmt.Sprintf("/exports/%s/download", job.ID)
+	}
+	return &job, nil
+}
+
+// ResumeExport restarts a failed or interrupted export from its last checkpoint
+func (s *ExportService) ResumeExport(id string) (*domain.ExportJob, error) {
+	job, err := s.GetExport(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == domain.ExportStatusCompleted {
+		return job, nil
+	}
+
+	s.start(id)
+	return s.GetExport(id)
+}
+
+// ResumeInterrupted restarts every export left pending or running by a previous process
+func (s *ExportService) ResumeInterrupted() error {
+	rows, err := s.db.DB.Query(
+		"SELECT id FROM export_jobs WHERE status IN ($1, $2)",
+		domain.ExportStatusPending, domain.ExportStatusRunning,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		slog.Info("Resuming export job", "job_id", id)
+		s.start(id)
+	}
+	return nil
+}
+
+// WriteExport streams a completed export to w: NDJSON chunks concatenated,
+// or a zip of the partitioned Parquet files
+func (s *ExportService) WriteExport(id string, w io.Writer) error {
+	job, err := s.GetExport(id)
+	if err != nil {
+		return err
+	}
+	if job.Status != domain.ExportStatusCompleted {
+		return ErrExportNotReady
+	}
+	if job.Format == domain.ExportFormatParquet {
+		return writeParquetZip(filepath.Join(s.dir, id), w)
+	}
+
+	chunks, err := filepath.Glob(filepath.Join(s.dir, id, "chunk_*.ndjson"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(chunks)
+
+	for _, chunk := range chunks {
+		f, err := os.Open(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportStatus describes the export worker for the detailed health report
+type ExportStatus struct {
+	Running int `json:"running"`
+	// LastFailure is the most recently failed export job, if any
+	LastFailure *domain.ExportJob `json:"last_failure,omitempty"`
+}
+
+// Status returns the running jobs and the last failed one. It fails when
+// the export directory is not writable, since no export could complete.
+func (s *ExportService) Status(ctx context.Context) (ExportStatus, error) {
+	s.mu.Lock()
+	status := ExportStatus{Running: len(s.running)}
+	s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return status, fmt.Errorf("export directory: %w", err)
+	}
+	probe, err := os.CreateTemp(s.dir, ".health-*")
+	if err != nil {
+		return status, fmt.Errorf("export directory not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	var job domain.ExportJob
+	var tenantID, jobError sql.NullString
+	err = s.db.DB.QueryRowContext(ctx, `
+		SELECT id, tenant_id, format, status, error, created_at, updated_at
+		FROM export_jobs WHERE status = $1 ORDER BY updated_at DESC LIMIT 1
+	`, domain.ExportStatusFailed).Scan(&job.ID, &tenantID, &job.Format, &job.Status, &jobError, &job.CreatedAt, &job.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	job.TenantID, job.Error = tenantID.String, jobError.String
+	status.LastFailure = &job
+	return status, nil
+}
+
+// Shutdown stops running exports at their next checkpoint and waits for them
+func (s *ExportService) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *ExportService) start(id string) {
+	s.mu.Lock()
+	if s.running[id] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[id] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, id)
+			s.mu.Unlock()
+		}()
+
+		if err := s.run(s.ctx, id); err != nil {
+			if s.ctx.Err() != nil {
+				// Dihentikan saat shutdown, akan dilanjutkan dari checkpoint
+				slog.Warn("Export job interrupted", "job_id", id, "error", err)
+				return
+			}
+			slog.Error("Export job failed", "job_id", id, "error", err)
+			s.db.DB.Exec(
+				"UPDATE export_jobs SET status = $2, error = $3, updated_at = NOW() WHERE id = $1",
+				id, domain.ExportStatusFailed, err.Error(),
+			)
+		}
+	}()
+}
+
+func (s *ExportService) run(ctx context.Context, id string) error {
+	var tenantID sql.NullString
+	var checkpointAt sql.NullTime
+	var checkpointID sql.NullString
+	var chunkCount int
+	var format string
+	err := s.db.DB.QueryRowContext(ctx, `
+		SELECT tenant_id, format, checkpoint_created_at, checkpoint_id, chunk_count
+		FROM export_jobs WHERE id = $1
+	`, id).Scan(&tenantID, &format, &checkpointAt, &checkpointID, &chunkCount)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.DB.ExecContext(ctx,
+		"UPDATE export_jobs SET status = $2, error = NULL, updated_at = NOW() WHERE id = $1",
+		id, domain.ExportStatusRunning,
+	); err != nil {
+		return err
+	}
+
+	jobDir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(jobDir, 0o755); err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		messages, err := fetchMessages(ctx, s.db, tenantID, checkpointAt, checkpointID, s.chunkSize)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		chunkCount++
+		if format == domain.ExportFormatParquet {
+			err = writeParquetChunk(jobDir, chunkCount, messages)
+		} else {
+			err = writeChunk(jobDir, chunkCount, messages)
+		}
+		if err != nil {
+			return err
+		}
+
+		last := messages[len(messages)-1]
+		checkpointAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		checkpointID = sql.NullString{String: last.ID, Valid: true}
+
+		// Simpan checkpoint setelah chunk berhasil ditulis
+		if _, err := s.db.DB.ExecContext(ctx, `
+			UPDATE export_jobs
+			SET checkpoint_created_at = $2, checkpoint_id = $3, chunk_count = $4,
+				exported_rows = exported_rows + $5, updated_at = NOW()
+			WHERE id = $1
+		`, id, checkpointAt, checkpointID, chunkCount, len(messages)); err != nil {
+			return err
+		}
+
+		if len(messages) < s.chunkSize {
+			break
+		}
+	}
+
+	_, err = s.db.DB.ExecContext(ctx,
+		"UPDATE export_jobs SET status = $2, updated_at = NOW() WHERE id = $1",
+		id, domain.ExportStatusCompleted,
+	)
+	return err
+}
+
+// fetchMessages reads up to limit messages after the (created_at, id) keyset position
+func fetchMessages(ctx context.Context, db *repository.Database, tenantID sql.NullString, afterAt sql.NullTime, afterID sql.NullString, limit int) ([]domain.Message, error) {
+	query := "SELECT id, tenant_id, payload, status, message_type, schema_version, tags, channel, created_at FROM messages WHERE 1=1"
+	var args []interface{}
+	if tenantID.Valid {
+		args = append(args, tenantID.String)
+		query += fmt.Sprintf(" AND tenant_id = $%d", len(args))
+	}
+	if afterAt.Valid {
+		args = append(args, afterAt.Time, afterID.String)
+		query += fmt.Sprintf(" AND (created_at, id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at ASC, id ASC LIMIT $%d", len(args))
+
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []domain.Message
+	for rows.Next() {
+		var msg domain.Message
+		if err := rows.Scan(&msg.ID, &msg.TenantID, &msg.Payload, &msg.Status, &msg.MessageType, &msg.SchemaVersion, &msg.Tags, &msg.Channel, &msg.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// writeChunk writes a chunk atomically so a crash never leaves a partial file
+func writeChunk(dir string, index int, messages []domain.Message) error {
+	name := filepath.Join(dir, fmt.Sprintf("chunk_%06d.ndjson", index))
+	tmp := name + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/dynconfig"
+	"multi-tenant-messaging/internal/filter"
+	"multi-tenant-messaging/internal/repository"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrInvalidFilter is returned when a filter rule does not compile
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// filterCacheTTL bounds how long workers may use stale filters after a change on another instance
+const filterCacheTTL = 30 * time.Second
+
+type cachedFilters struct {
+	set      *filter.Set
+	loadedAt time.Time
+}
+
+// FilterService manages per-tenant CEL filter rules and evaluates them on
+// messages before they are stored
+type FilterService struct {
+	db      *repository.Database
+	runtime *dynconfig.Store
+	limits  filter.Limits
+
+	mu    sync.RWMutex
+	cache map[string]cachedFilters
+}
+
+func NewFilterService(db *repository.Database, runtime *dynconfig.Store, timeout time.Duration, costLimit uint64) *FilterService {
+	return &FilterService{
+		db:      db,
+		runtime: runtime,
+		limits:  filter.Limits{Timeout: timeout, CostLimit: costLimit},
+		cache:   make(map[string]cachedFilters),
+	}
+}
+
+// filterRouteQueueName is the queue messages matching a tenant's route rule are published to
+func filterRouteQueueName(tenantID, target string) string {
+	return fmt.Sprintf("tenant_%s_route_%s", tenantID, target)
+}
+
+// GetRules returns the tenant's filter rules in evaluation order
+func (s *FilterService) GetRules(tenantID string) ([]filter.Rule, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT name, expression, action, target FROM tenant_message_filters
+		WHERE tenant_id = $1 ORDER BY position
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]filter.Rule, 0)
+	for rows.Next() {
+		var rule filter.Rule
+		if err := rows.Scan(&rule.Name, &rule.Expression, &rule.Action, &rule.Target); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SetRules replaces the tenant's filter rules. Every rule must compile.
+func (s *FilterService) SetRules(tenantID string, rules []filter.Rule) ([]filter.Rule, error) {
+	set, err := filter.Compile(rules, s.limits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM tenant_message_filters WHERE tenant_id = $1", tenantID); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant_message_filters (tenant_id, position, name, expression, action, target)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, tenantID, i, rule.Name, rule.Expression, rule.Action, rule.Target); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedFilters{set: set, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return s.GetRules(tenantID)
+}
+
+// DryRun evaluates rules, or the tenant's stored rules when rules is nil, on
+// a sample message without storing or routing anything
+func (s *FilterService) DryRun(ctx context.Context, tenantID string, rules []filter.Rule, in filter.Input) (filter.Decision, error) {
+	var set *filter.Set
+	var err error
+	if rules != nil {
+		set, err = filter.Compile(rules, s.limits)
+		if err != nil {
+			return filter.Decision{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+	} else if set, err = s.filters(tenantID); err != nil {
+		return filter.Decision{}, err
+	}
+	if in.Flags == nil {
+		in.Flags = s.runtime.Flags(tenantID)
+	}
+	return set.Evaluate(ctx, in)
+}
+
+// Evaluate runs the tenant's filters on a message
+func (s *FilterService) Evaluate(ctx context.Context, tenantID, messageID, messageType string, headers amqp.Table, body []byte) (filter.Decision, error) {
+	set, err := s.filters(tenantID)
+	if err != nil {
+		return filter.Decision{}, fmt.Errorf("failed to load filters: %w", err)
+	}
+	return set.Evaluate(ctx, filter.Input{
+		ID:      messageID,
+		Type:    messageType,
+		Headers: filterHeaders(headers),
+		Body:    body,
+		Flags:   s.runtime.Flags(tenantID),
+	})
+}
+
+// RouteTargets returns the distinct route targets of the tenant's rules
+func (s *FilterService) RouteTargets(tenantID string) ([]string, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT DISTINCT target FROM tenant_message_filters WHERE tenant_id = $1 AND action = $2
+	`, tenantID, filter.ActionRoute)
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+func (s *FilterService) filters(tenantID string) (*filter.Set, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < filterCacheTTL {
+		return cached.set, nil
+	}
+
+	rules, err := s.GetRules(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	set, err := filter.Compile(rules, s.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedFilters{set: set, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return set, nil
+}
+
+// filterHeaders converts AMQP headers to values CEL understands. Nested
+// tables and arrays are kept; other types are formatted as strings.
+func filterHeaders(headers amqp.Table) map[string]any {
+	converted := make(map[string]any, len(headers))
+	for key, value := range headers {
+		converted[key] = filterHeaderValue(value)
+	}
+	return converted
+}
+
+func filterHeaderValue(value any) any {
+	switch v := value.(type) {
+	case nil, string, bool, float32, float64, int64:
+		return v
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case amqp.Table:
+		return filterHeaders(v)
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = filterHeaderValue(item)
+		}
+		return items
+	default:
+		return fmt.Sprint(v)
+	}
+}
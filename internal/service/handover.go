@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// HandoverService coordinates which instance consumes each tenant through the
+// consumer_instances and tenant_consumer_owners tables. During a blue/green
+// deploy the new version requests tenants one at a time; the old owner drains
+// the tenant's consumer before passing ownership on, so no message is lost or
+// processed by both versions at once.
+type HandoverService struct {
+	db         *repository.Database
+	tenants    *TenantService
+	instanceID string
+	version    string
+	interval   time.Duration
+	takeover   bool
+	drainLimit time.Duration
+}
+
+func NewHandoverService(db *repository.Database, tenants *TenantService, instanceID, version string, interval time.Duration, takeover bool, drainLimit time.Duration) *HandoverService {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if drainLimit <= 0 {
+		drainLimit = 30 * time.Second
+	}
+	return &HandoverService{
+		db:         db,
+		tenants:    tenants,
+		instanceID: instanceID,
+		version:    version,
+		interval:   interval,
+		takeover:   takeover,
+		drainLimit: drainLimit,
+	}
+}
+
+// InstanceID returns the ID this instance registers under
+func (s *HandoverService) InstanceID() string {
+	return s.instanceID
+}
+
+// Run heartbeats and reconciles tenant ownership until ctx is cancelled
+func (s *HandoverService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.reconcile(ctx); err != nil {
+			slog.Error("Consumer handover failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *HandoverService) reconcile(ctx context.Context) error {
+	if err := s.heartbeat(); err != nil {
+		return err
+	}
+	if err := s.claimOrphans(); err != nil {
+		return err
+	}
+	if err := s.releaseRequested(ctx); err != nil {
+		return err
+	}
+	if err := s.syncOwned(ctx); err != nil {
+		return err
+	}
+	if s.takeover {
+		return s.requestNext()
+	}
+	return nil
+}
+
+func (s *HandoverService) heartbeat() error {
+	_, err := s.db.DB.Exec(`
+		INSERT INTO consumer_instances (instance_id, version, heartbeat_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (instance_id) DO UPDATE SET version = EXCLUDED.version, heartbeat_at = NOW()
+	`, s.instanceID, s.version)
+	return err
+}
+
+// staleAfter is how long an instance may miss heartbeats before its tenants
+// are considered orphaned
+func (s *HandoverService) staleAfter() float64 {
+	return (3 * s.interval).Seconds()
+}
+
+// claimOrphans takes ownership of tenants that have no owner or whose owner
+// stopped heartbeating, and drops handover requests from dead instances.
+// Tenants already running here are claimed first so a tenant created on this
+// instance stays on it.
+func (s *HandoverService) claimOrphans() error {
+	_, err := s.db.DB.Exec(`
+		UPDATE tenant_consumer_owners o SET handover_to = NULL, updated_at = NOW()
+		WHERE handover_to IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM consumer_instances i
+			WHERE i.instance_id = o.handover_to AND i.heartbeat_at > NOW() - make_interval(secs => $1)
+		)
+	`, s.staleAfter())
+	if err != nil {
+		return err
+	}
+
+	claim := `
+		INSERT INTO tenant_consumer_owners (tenant_id, instance_id, updated_at)
+		SELECT t.id, $1, NOW() FROM tenants t %s
+		ON CONFLICT (tenant_id) DO UPDATE SET instance_id = EXCLUDED.instance_id, handover_to = NULL, updated_at = NOW()
+		WHERE NOT EXISTS (
+			SELECT 1 FROM consumer_instances i
+			WHERE i.instance_id = tenant_consumer_owners.instance_id AND i.heartbeat_at > NOW() - make_interval(secs => $2)
+		)
+	`
+	if local := s.tenants.tenantManager.TenantIDs(); len(local) > 0 {
+		if _, err := s.db.DB.Exec(fmt.Sprintf(claim, "WHERE t.id::text = ANY($3)"), s.instanceID, s.staleAfter(), local); err != nil {
+			return err
+		}
+	}
+	_, err = s.db.DB.Exec(fmt.Sprintf(claim, ""), s.instanceID, s.staleAfter())
+	return err
+}
+
+// releaseRequested drains every tenant another instance asked for and hands
+// ownership over once its in-flight messages are settled
+func (s *HandoverService) releaseRequested(ctx context.Context) error {
+	rows, err := s.db.DB.Query(`
+		SELECT tenant_id FROM tenant_consumer_owners WHERE instance_id = $1 AND handover_to IS NOT NULL
+	`, s.instanceID)
+	if err != nil {
+		return err
+	}
+	tenantIDs, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenantIDs {
+		drainCtx, cancel := context.WithTimeout(ctx, s.drainLimit)
+		err := s.tenants.DrainTenant(drainCtx, tenantID)
+		cancel()
+		if err != nil && err != ErrTenantNotFound {
+			// Delivery yang belum selesai sudah dikembalikan ke queue
+			slog.Error("Consumer handover: failed to drain tenant", "tenant_id", tenantID, "error", err)
+		}
+
+		_, err = s.db.DB.Exec(`
+			UPDATE tenant_consumer_owners SET instance_id = handover_to, handover_to = NULL, updated_at = NOW()
+			WHERE tenant_id = $1 AND instance_id = $2
+		`, tenantID, s.instanceID)
+		if err != nil {
+			return err
+		}
+		slog.Info("Consumer handover: released tenant", "tenant_id", tenantID)
+	}
+	return nil
+}
+
+// syncOwned attaches the tenants this instance owns and drains the ones it
+// runs but no longer owns or that were migrated to another deployment
+func (s *HandoverService) syncOwned(ctx context.Context) error {
+	rows, err := s.db.DB.Query(`
+		SELECT o.tenant_id FROM tenant_consumer_owners o
+		JOIN tenants t ON t.id = o.tenant_id
+		WHERE o.instance_id = $1 AND t.migrated_to IS NULL
+	`, s.instanceID)
+	if err != nil {
+		return err
+	}
+	owned, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	ownedSet := make(map[string]bool, len(owned))
+	for _, tenantID := range owned {
+		ownedSet[tenantID] = true
+		if err := s.tenants.AttachTenant(tenantID); err != nil {
+			slog.Error("Consumer handover: failed to attach tenant", "tenant_id", tenantID, "error", err)
+		}
+	}
+
+	for _, tenantID := range s.tenants.tenantManager.TenantIDs() {
+		if ownedSet[tenantID] {
+			continue
+		}
+		drainCtx, cancel := context.WithTimeout(ctx, s.drainLimit)
+		if err := s.tenants.DrainTenant(drainCtx, tenantID); err != nil && err != ErrTenantNotFound {
+			slog.Error("Consumer handover: failed to drain tenant", "tenant_id", tenantID, "error", err)
+		}
+		cancel()
+	}
+	return nil
+}
+
+// requestNext asks for one tenant owned by an older instance running another
+// version, but only once the previous request has completed
+func (s *HandoverService) requestNext() error {
+	var pending int
+	err := s.db.DB.QueryRow(`
+		SELECT COUNT(*) FROM tenant_consumer_owners WHERE handover_to = $1
+	`, s.instanceID).Scan(&pending)
+	if err != nil || pending > 0 {
+		return err
+	}
+
+	_, err = s.db.DB.Exec(`
+		UPDATE tenant_consumer_owners SET handover_to = $1, updated_at = NOW()
+		WHERE tenant_id = (
+			SELECT o.tenant_id FROM tenant_consumer_owners o
+			JOIN consumer_instances i ON i.instance_id = o.instance_id
+			WHERE o.instance_id <> $1 AND o.handover_to IS NULL AND i.version <> $2
+				AND i.started_at < (SELECT started_at FROM consumer_instances WHERE instance_id = $1)
+			ORDER BY o.tenant_id
+			LIMIT 1
+			FOR UPDATE OF o SKIP LOCKED
+		)
+	`, s.instanceID, s.version)
+	return err
+}
+
+func scanIDs(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	var tenantIDs []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	return tenantIDs, rows.Err()
+}
+
+// Release drains every tenant this instance runs and gives up ownership so
+// other instances can claim them right away. Called on graceful shutdown.
+func (s *HandoverService) Release(ctx context.Context) error {
+	s.tenants.DrainConsumers(ctx)
+
+	if _, err := s.db.DB.Exec("DELETE FROM tenant_consumer_owners WHERE instance_id = $1", s.instanceID); err != nil {
+		return err
+	}
+	_, err := s.db.DB.Exec("DELETE FROM consumer_instances WHERE instance_id = $1", s.instanceID)
+	return err
+}
+
+// ListOwners returns the current tenant ownership, for operators following a deploy
+func (s *HandoverService) ListOwners() ([]domain.ConsumerOwner, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT o.tenant_id, o.instance_id, COALESCE(i.version, ''), COALESCE(o.handover_to, ''), o.updated_at
+		FROM tenant_consumer_owners o
+		LEFT JOIN consumer_instances i ON i.instance_id = o.instance_id
+		ORDER BY o.tenant_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []domain.ConsumerOwner{}
+	for rows.Next() {
+		var owner domain.ConsumerOwner
+		if err := rows.Scan(&owner.TenantID, &owner.InstanceID, &owner.Version, &owner.HandoverTo, &owner.UpdatedAt); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+	return owners, rows.Err()
+}
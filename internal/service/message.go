@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidMessageQuery is returned for an invalid message filter, cursor or limit
+var ErrInvalidMessageQuery = errors.New("invalid message query")
+
+// ErrForeignTenant is returned when a token bound to one tenant asks for
+// another tenant's messages
+var ErrForeignTenant = errors.New("token is bound to another tenant")
+
+// defaultMessageLimit is the page size when none is given
+const defaultMessageLimit = 10
+
+// MaxMessageLimit is the largest page of messages a request may ask for
+const MaxMessageLimit = 1000
+
+// messageColumns are the columns of a full message, in scan order
+var messageColumns = []string{"id", "tenant_id", "payload", "status", "message_type", "schema_version", "tags", "channel", "content_type", "raw_payload", "deliver_at", "created_at"}
+
+// MessageCursor is a position in the (created_at, id) order of messages. It
+// carries both columns, so a page needs no lookup of the cursor's row and
+// stays valid when that message is deleted.
+type MessageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorOf returns the cursor at msg
+func CursorOf(msg domain.Message) MessageCursor {
+	id, _ := uuid.Parse(msg.ID)
+	return MessageCursor{CreatedAt: msg.CreatedAt, ID: id}
+}
+
+// String encodes the cursor as URL-safe base64 of created_at in
+// microseconds, PostgreSQL's precision, followed by the 16 bytes of the ID
+func (c MessageCursor) String() string {
+	buf := make([]byte, 8, 8+len(c.ID))
+	binary.BigEndian.PutUint64(buf, uint64(c.CreatedAt.UnixMicro()))
+	return base64.RawURLEncoding.EncodeToString(append(buf, c.ID[:]...))
+}
+
+// ParseMessageCursor decodes a cursor returned as next_cursor
+func ParseMessageCursor(s string) (MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) != 8+len(uuid.UUID{}) {
+		return MessageCursor{}, fmt.Errorf("%w: invalid cursor format", ErrInvalidMessageQuery)
+	}
+	cursor := MessageCursor{CreatedAt: time.UnixMicro(int64(binary.BigEndian.Uint64(raw[:8]))).UTC()}
+	copy(cursor.ID[:], raw[8:])
+	return cursor, nil
+}
+
+// MessageFilter holds the WHERE conditions of a messages query with their
+// arguments; each condition has a %s for each of its placeholders
+type MessageFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// NewMessageFilter builds the filter of query. boundTenant is the tenant the
+// caller's token is bound to, if any; the filter is then always restricted to
+// that tenant, even without row-level security.
+func NewMessageFilter(boundTenant string, query domain.MessageQuery) (MessageFilter, error) {
+	var filter MessageFilter
+
+	tenantID := query.TenantID
+	if tenantID != "" {
+		if _, err := uuid.Parse(tenantID); err != nil {
+			return filter, fmt.Errorf("%w: invalid tenant_id", ErrInvalidMessageQuery)
+		}
+	}
+	if boundTenant != "" {
+		if tenantID != "" && tenantID != boundTenant {
+			return filter, ErrForeignTenant
+		}
+		tenantID = boundTenant
+	}
+	if tenantID != "" {
+		filter = filter.With("tenant_id = %s", tenantID)
+	}
+
+	if query.CreatedAfter != nil {
+		filter = filter.With("created_at > %s", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		filter = filter.With("created_at < %s", *query.CreatedBefore)
+	}
+
+	if len(query.Payload) > 0 {
+		if !json.Valid(query.Payload) {
+			return filter, fmt.Errorf("%w: invalid payload filter, expected a JSON document", ErrInvalidMessageQuery)
+		}
+		filter = filter.With("payload @> %s::jsonb", string(query.Payload))
+	}
+	return filter, nil
+}
+
+// With returns a copy of the filter with condition added for values
+func (f MessageFilter) With(condition string, values ...interface{}) MessageFilter {
+	args := slices.Clone(f.args)
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		args = append(args, value)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+	return MessageFilter{
+		conditions: append(slices.Clone(f.conditions), fmt.Sprintf(condition, placeholders...)),
+		args:       args,
+	}
+}
+
+// Query builds the messages query with the filter, order and limit
+func (f MessageFilter) Query(selectClause, order string, limit int) (string, []interface{}) {
+	where := ""
+	if len(f.conditions) > 0 {
+		where = " WHERE " + strings.Join(f.conditions, " AND ")
+	}
+	args := append(slices.Clone(f.args), limit)
+	return fmt.Sprintf("%s FROM messages%s ORDER BY %s LIMIT $%d", selectClause, where, order, len(args)), args
+}
+
+// Before returns a copy of the filter limited to messages before cursor in
+// (created_at, id) order
+func (f MessageFilter) Before(cursor MessageCursor) MessageFilter {
+	return f.With("(created_at, id) < (%s::timestamptz, %s::uuid)", cursor.CreatedAt, cursor.ID.String())
+}
+
+// After returns a copy of the filter limited to messages after cursor in
+// (created_at, id) order
+func (f MessageFilter) After(cursor MessageCursor) MessageFilter {
+	return f.With("(created_at, id) > (%s::timestamptz, %s::uuid)", cursor.CreatedAt, cursor.ID.String())
+}
+
+// MessageService reads stored messages
+type MessageService struct {
+	db *repository.Database
+}
+
+// NewMessageService creates a new MessageService
+func NewMessageService(db *repository.Database) *MessageService {
+	return &MessageService{db: db}
+}
+
+// ListMessages returns a page of the messages matching query, newest first,
+// and the cursor of the next page, which is empty on the last page.
+// boundTenant restricts the page as in NewMessageFilter.
+func (s *MessageService) ListMessages(ctx context.Context, boundTenant string, query domain.MessageQuery) ([]domain.Message, string, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultMessageLimit
+	}
+	if limit < 0 || limit > MaxMessageLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidMessageQuery, MaxMessageLimit)
+	}
+
+	filter, err := NewMessageFilter(boundTenant, query)
+	if err != nil {
+		return nil, "", err
+	}
+	if query.Cursor != "" {
+		cursor, err := ParseMessageCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter = filter.Before(cursor)
+	}
+	sqlQuery, args := filter.Query("SELECT "+strings.Join(messageColumns, ", "), "created_at DESC, id DESC", limit)
+
+	messages := make([]domain.Message, 0)
+	err = s.db.WithTenant(ctx, boundTenant, func(q repository.Querier) error {
+		rows, err := q.QueryContext(ctx, sqlQuery, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var msg domain.Message
+			if err := rows.Scan(&msg.ID, &msg.TenantID, &msg.Payload, &msg.Status, &msg.MessageType, &msg.SchemaVersion, &msg.Tags, &msg.Channel, &msg.ContentType, &msg.RawPayload, &msg.DeliverAt, &msg.CreatedAt); err != nil {
+				return err
+			}
+			messages = append(messages, msg)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(messages) == limit {
+		next = CursorOf(messages[len(messages)-1]).String()
+	}
+	return messages, next, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/repository"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// outboxHeader carries the outbox marker of a relayed message, its message
+// ID, so a message relayed twice is only stored once
+const outboxHeader = "x-salva-outbox-id"
+
+// OutboxService configures the publish outbox. With the outbox enabled
+// published messages are written to the publish_outbox table and the relay
+// publishes them to the broker, at least once and in order.
+type OutboxService struct {
+	db      *repository.Database
+	enabled bool
+	// interval is how often the relay looks for unpublished messages
+	interval  time.Duration
+	batchSize int
+	// retention is how long published messages are kept in the outbox
+	retention time.Duration
+}
+
+func NewOutboxService(db *repository.Database, enabled bool, interval time.Duration, batchSize int, retention time.Duration) *OutboxService {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+	return &OutboxService{db: db, enabled: enabled, interval: interval, batchSize: batchSize, retention: retention}
+}
+
+// Enabled reports whether PublishMessage goes through the outbox
+func (s *OutboxService) Enabled() bool {
+	return s != nil && s.enabled
+}
+
+// PurgePublished deletes the messages published longer than the retention ago
+func (s *OutboxService) PurgePublished(ctx context.Context) (int64, error) {
+	res, err := s.db.DB.ExecContext(ctx,
+		"DELETE FROM publish_outbox WHERE published_at < NOW() - make_interval(secs => $1)", s.retention.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// EnqueueMessage validates req like PublishMessage and writes the message to
+// the outbox through q. Given a transaction, the message is only published
+// if the transaction commits, together with whatever else it wrote.
+func (s *TenantService) EnqueueMessage(ctx context.Context, q repository.Querier, tenantID string, req domain.PublishRequest) (domain.PublishResult, error) {
+	plan, err := s.preparePublish(ctx, q, tenantID, req)
+	if err != nil {
+		return domain.PublishResult{}, err
+	}
+
+	// Trace publisher disimpan supaya relay melanjutkannya
+	headers := amqp.Table{}
+	metrics.InjectHeaders(ctx, headers)
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return domain.PublishResult{}, err
+	}
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO publish_outbox (id, tenant_id, queue, message_type, payload, headers, deliver_at, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, plan.messageID, tenantID, plan.queue, plan.messageType, plan.payload, encoded, plan.deliverAt, plan.priority)
+	if err != nil {
+		return domain.PublishResult{}, fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return plan.result(), nil
+}
+
+// RelayOutbox publishes the messages in the outbox every interval until ctx
+// is done, and purges the published ones after the retention. Run as a
+// singleton it publishes messages in the order they were written.
+func (s *TenantService) RelayOutbox(ctx context.Context) {
+	ticker := time.NewTicker(s.outbox.interval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+
+	for {
+		for {
+			relayed, err := s.relayOutboxBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to relay outbox", "error", err)
+				}
+				break
+			}
+			if relayed < s.outbox.batchSize {
+				break
+			}
+		}
+		if time.Since(lastPurge) > time.Hour {
+			if n, err := s.outbox.PurgePublished(ctx); err != nil {
+				slog.Error("Failed to purge outbox", "error", err)
+			} else if n > 0 {
+				slog.Info("Purged published outbox messages", "count", n)
+			}
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayOutboxBatch publishes the oldest unpublished messages and marks them
+// published in the transaction that locked them. A crash before the commit
+// publishes them again; consumers drop the second copy on its outbox marker.
+func (s *TenantService) relayOutboxBatch(ctx context.Context) (int, error) {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, tenant_id, queue, message_type, payload, headers, deliver_at, priority FROM publish_outbox
+		WHERE published_at IS NULL ORDER BY created_at, id LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, s.outbox.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	type outboxEntry struct {
+		plan    publishPlan
+		headers amqp.Table
+	}
+	var entries []outboxEntry
+	for rows.Next() {
+		var entry outboxEntry
+		var encoded []byte
+		var deliverAt sql.NullTime
+		if err := rows.Scan(&entry.plan.messageID, &entry.plan.tenantID, &entry.plan.queue, &entry.plan.messageType, &entry.plan.payload, &encoded, &deliverAt, &entry.plan.priority); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if err := json.Unmarshal(encoded, &entry.headers); err != nil || entry.headers == nil {
+			entry.headers = amqp.Table{}
+		}
+		if deliverAt.Valid {
+			entry.plan.deliverAt = &deliverAt.Time
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	relayed := 0
+	for _, entry := range entries {
+		entry.headers[outboxHeader] = entry.plan.messageID
+		publishCtx := metrics.ExtractHeaders(ctx, entry.headers)
+		if err := s.deliverPublish(publishCtx, entry.plan, entry.headers); err != nil {
+			// Berhenti di sini supaya urutan pesan tetap terjaga
+			if _, recordErr := tx.ExecContext(ctx,
+				"UPDATE publish_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1", entry.plan.messageID, err.Error(),
+			); recordErr != nil {
+				return relayed, recordErr
+			}
+			if commitErr := tx.Commit(); commitErr != nil {
+				return relayed, commitErr
+			}
+			return relayed, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE publish_outbox SET published_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1", entry.plan.messageID,
+		); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+	return relayed, tx.Commit()
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrPartitionNotFound is returned when a tenant has no messages partition
+var ErrPartitionNotFound = errors.New("partition not found")
+
+const partitionPrefix = "messages_tenant_"
+
+// What DeleteTenant does with the tenant's messages partition
+const (
+	// PartitionOnDeleteDrop drops the partition with its messages, and
+	// the messages archived by retention
+	PartitionOnDeleteDrop = "drop"
+	// PartitionOnDeleteDetach detaches the partition, keeping its messages in
+	// a standalone table for archiving
+	PartitionOnDeleteDetach = "detach"
+	// PartitionOnDeleteKeep leaves the partition attached
+	PartitionOnDeleteKeep = "keep"
+)
+
+type PartitionService struct {
+	db *repository.Database
+}
+
+func NewPartitionService(db *repository.Database) *PartitionService {
+	return &PartitionService{db: db}
+}
+
+// partitionName returns the messages partition table name for a tenant
+func partitionName(tenantID string) string {
+	// Normalize tenantID by replacing hyphens with underscores
+	return partitionPrefix + strings.ReplaceAll(tenantID, "-", "_")
+}
+
+// tenantIDFromPartition reverses partitionName
+func tenantIDFromPartition(name string) string {
+	id := strings.ReplaceAll(strings.TrimPrefix(name, partitionPrefix), "_", "-")
+	if _, err := uuid.Parse(id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// ListPartitions returns attached and detached tenant partitions with their
+// sizes. Row counts are estimates unless exact is set.
+func (s *PartitionService) ListPartitions(exact bool) ([]domain.Partition, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT c.relname,
+			EXISTS (SELECT 1 FROM pg_inherits i WHERE i.inhrelid = c.oid AND i.inhparent = 'messages'::regclass),
+			GREATEST(c.reltuples, 0)::bigint,
+			pg_total_relation_size(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'r'
+			AND n.nspname = current_schema()
+			AND c.relname LIKE $1
+		ORDER BY c.relname
+	`, partitionPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := make([]domain.Partition, 0)
+	for rows.Next() {
+		var p domain.Partition
+		if err := rows.Scan(&p.Name, &p.Attached, &p.EstimatedRows, &p.SizeBytes); err != nil {
+			return nil, err
+		}
+		p.TenantID = tenantIDFromPartition(p.Name)
+		partitions = append(partitions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if exact {
+		for i := range partitions {
+			var count int64
+			if err := s.db.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, partitions[i].Name)).Scan(&count); err != nil {
+				return nil, err
+			}
+			partitions[i].Rows = &count
+		}
+	}
+
+	return partitions, nil
+}
+
+// CreatePartition pre-creates the messages partition of a tenant
+func (s *PartitionService) CreatePartition(tenantID string) error {
+	return createPartition(s.db, tenantID)
+}
+
+// DetachPartition detaches the tenant partition from messages, keeping its data
+func (s *PartitionService) DetachPartition(tenantID string) error {
+	attached, err := partitionAttached(context.Background(), s.db.DB, tenantID)
+	if err != nil {
+		return err
+	}
+	if !attached {
+		return ErrPartitionNotFound
+	}
+
+	_, err = s.db.DB.Exec(fmt.Sprintf(`ALTER TABLE messages DETACH PARTITION "%s"`, partitionName(tenantID)))
+	return err
+}
+
+// partitionAttached reports whether the tenant partition is attached to messages
+func partitionAttached(ctx context.Context, q repository.Querier, tenantID string) (bool, error) {
+	var attached bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE i.inhparent = 'messages'::regclass AND c.relname = $1
+		)
+	`, partitionName(tenantID)).Scan(&attached)
+	return attached, err
+}
+
+// createPartition creates the tenant's messages partition, in one
+// transaction with attaching a table of the same name left detached by an
+// earlier deletion. The indexes of messages are created on the partition.
+func createPartition(db *repository.Database, tenantID string) error {
+	ctx := context.Background()
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Gunakan quoted identifier untuk nama tabel
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS "%s" PARTITION OF messages
+		FOR VALUES IN ('%s')
+	`, partitionName(tenantID), tenantID))
+	if err != nil {
+		return err
+	}
+
+	attached, err := partitionAttached(ctx, tx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !attached {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			ALTER TABLE messages ATTACH PARTITION "%s" FOR VALUES IN ('%s')
+		`, partitionName(tenantID), tenantID))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// removePartition drops or detaches the tenant's messages partition as
+// policy says, within the caller's transaction
+func removePartition(q repository.Querier, tenantID, policy string) error {
+	ctx := context.Background()
+	switch policy {
+	case PartitionOnDeleteKeep:
+		return nil
+	case PartitionOnDeleteDrop:
+		// Pesan yang diarsipkan retention ikut dihapus
+		_, err := q.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS "%s", "%s"`, partitionName(tenantID), archiveTableName(tenantID)))
+		return err
+	default:
+		attached, err := partitionAttached(ctx, q, tenantID)
+		if err != nil || !attached {
+			return err
+		}
+		_, err = q.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE messages DETACH PARTITION "%s"`, partitionName(tenantID)))
+		return err
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/worker"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrInvalidPriority is returned for a priority or max priority outside 0 to 9
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// maxMessagePriority is the highest AMQP priority the worker pool tells apart
+const maxMessagePriority = worker.PriorityLevels - 1
+
+// priorityQueueSuffix marks a priority queue and its max priority in the
+// queue name, e.g. tenant_{id}_queue_p9. Queue arguments cannot change once
+// declared, so a tenant turning priorities on or off moves to another queue.
+const priorityQueueSuffix = "_p"
+
+// priorityQueueName is queue as a priority queue ordering up to maxPriority,
+// or queue itself for 0
+func priorityQueueName(queue string, maxPriority int) string {
+	if maxPriority <= 0 {
+		return queue
+	}
+	return fmt.Sprintf("%s%s%d", queue, priorityQueueSuffix, maxPriority)
+}
+
+// queueMaxPriority is the max priority in the name of a priority queue, 0
+// for any other queue
+func queueMaxPriority(queue string) int {
+	i := strings.LastIndex(queue, priorityQueueSuffix)
+	if i < 0 {
+		return 0
+	}
+	level, err := strconv.Atoi(queue[i+len(priorityQueueSuffix):])
+	if err != nil || level < 1 || level > maxMessagePriority {
+		return 0
+	}
+	return level
+}
+
+// priorityQueueArgs are the arguments declaring queue as a priority queue,
+// nil unless its name marks it as one
+func priorityQueueArgs(queue string) amqp.Table {
+	level := queueMaxPriority(queue)
+	if level == 0 {
+		return nil
+	}
+	return amqp.Table{"x-max-priority": int64(level)}
+}
+
+// maxPriority is the tenant's max priority, 0 when unset or unreadable
+func (s *TenantService) maxPriority(tenantID string) int {
+	var level int
+	s.db.DB.QueryRow("SELECT max_priority FROM tenant_configs WHERE tenant_id = $1", tenantID).Scan(&level)
+	return level
+}
+
+// GetPriority returns the tenant's max priority and the queue it is consumed from
+func (s *TenantService) GetPriority(tenantID string) (domain.PriorityConfig, error) {
+	if err := s.tenantAvailable(tenantID); err != nil {
+		return domain.PriorityConfig{}, err
+	}
+	return domain.PriorityConfig{
+		MaxPriority: s.maxPriority(tenantID),
+		Queue:       s.currentQueueName(tenantID),
+	}, nil
+}
+
+// SetPriority sets the highest priority the tenant's main queue orders
+// messages by, 0 turning priorities off, and moves the tenant to the queue
+// declared for it with RenameQueue. Running it again with the same max
+// priority completes an interrupted move.
+func (s *TenantService) SetPriority(ctx context.Context, tenantID string, maxPriority int) (domain.QueueRename, error) {
+	if maxPriority < 0 || maxPriority > maxMessagePriority {
+		return domain.QueueRename{}, fmt.Errorf("%w: max_priority must be between 0 and %d", ErrInvalidPriority, maxMessagePriority)
+	}
+	if err := s.onRabbitMQ(); err != nil {
+		return domain.QueueRename{}, err
+	}
+	if err := s.tenantAvailable(tenantID); err != nil {
+		return domain.QueueRename{}, err
+	}
+
+	_, err := s.db.DB.ExecContext(ctx, `
+		INSERT INTO tenant_configs (tenant_id, max_priority) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET max_priority = EXCLUDED.max_priority
+	`, tenantID, maxPriority)
+	if err != nil {
+		return domain.QueueRename{}, err
+	}
+	return s.RenameQueue(ctx, tenantID)
+}
+
+// tenantAvailable fails with ErrTenantNotFound for a tenant that does not
+// exist or was migrated away
+func (s *TenantService) tenantAvailable(tenantID string) error {
+	var migrated bool
+	err := s.db.DB.QueryRow("SELECT migrated_to IS NOT NULL FROM tenants WHERE id = $1", tenantID).Scan(&migrated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTenantNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if migrated {
+		return fmt.Errorf("%w: tenant was migrated", ErrTenantNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/repository"
+	"multi-tenant-messaging/pkg/processor"
+)
+
+// ErrUnknownProcessor is returned when a tenant selects a processor that is not registered
+var ErrUnknownProcessor = errors.New("unknown processor")
+
+// ErrInvalidProcessorConfig is returned when a stage's settings are rejected
+// by its processor, or given to a processor that takes none
+var ErrInvalidProcessorConfig = errors.New("invalid processor config")
+
+// processorCacheTTL bounds how long a chain change on another instance takes to apply
+const processorCacheTTL = 30 * time.Second
+
+type cachedChain struct {
+	stages   []processor.Stage
+	loadedAt time.Time
+}
+
+// ProcessorService runs each tenant's chain of registered processors on its messages
+type ProcessorService struct {
+	db *repository.Database
+
+	mu     sync.RWMutex
+	chains map[string]cachedChain
+}
+
+func NewProcessorService(db *repository.Database) *ProcessorService {
+	return &ProcessorService{
+		db:     db,
+		chains: make(map[string]cachedChain),
+	}
+}
+
+// GetProcessors returns the stages of the tenant's chain, in run order
+func (s *ProcessorService) GetProcessors(tenantID string) ([]processor.Stage, error) {
+	var raw []byte
+	err := s.db.DB.QueryRow(
+		"SELECT processors FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return []processor.Stage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stages := []processor.Stage{}
+	if err := json.Unmarshal(raw, &stages); err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// SetProcessors replaces the tenant's processor chain; every processor must
+// be registered and accept its stage's settings
+func (s *ProcessorService) SetProcessors(tenantID string, stages []processor.Stage) error {
+	for _, stage := range stages {
+		p, ok := processor.Get(stage.Name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownProcessor, stage.Name)
+		}
+		configurable, ok := p.(processor.Configurable)
+		if !ok {
+			if stage.Config != nil {
+				return fmt.Errorf("%w: %s takes no config", ErrInvalidProcessorConfig, stage.Name)
+			}
+			continue
+		}
+		if err := configurable.ValidateConfig(stage.Config); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidProcessorConfig, stage.Name, err)
+		}
+	}
+
+	raw, err := json.Marshal(stages)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, processors) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET processors = EXCLUDED.processors
+	`, tenantID, raw)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.chains[tenantID] = cachedChain{stages: stages, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *ProcessorService) chain(tenantID string) ([]processor.Stage, error) {
+	s.mu.RLock()
+	cached, ok := s.chains[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < processorCacheTTL {
+		return cached.stages, nil
+	}
+
+	stages, err := s.GetProcessors(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.chains[tenantID] = cachedChain{stages: stages, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return stages, nil
+}
+
+// Run passes the message through the tenant's processors in order and returns
+// the resulting payload. drop is true when a processor dropped the message.
+// An error wrapping processor.ErrReject means the message must go to the DLQ.
+func (s *ProcessorService) Run(ctx context.Context, tenantID, messageID, messageType string, body []byte) ([]byte, bool, error) {
+	stages, err := s.chain(tenantID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load processors: %w", err)
+	}
+
+	for _, stage := range stages {
+		p, ok := processor.Get(stage.Name)
+		if !ok {
+			// Processor dihapus dari config; jangan lewati diam-diam
+			return nil, false, fmt.Errorf("%w: %s", ErrUnknownProcessor, stage.Name)
+		}
+		result, err := p.Process(ctx, processor.Message{
+			TenantID:  tenantID,
+			MessageID: messageID,
+			Type:      messageType,
+			Body:      body,
+			Config:    stage.Config,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("processor %s: %w", stage.Name, err)
+		}
+		if result.Drop {
+			return nil, true, nil
+		}
+		if result.Body != nil {
+			body = result.Body
+		}
+	}
+	return body, false, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// ErrProvisioningNotFound is returned when a provisioning job does not exist
+var ErrProvisioningNotFound = errors.New("provisioning job not found")
+
+// provisioningStaleAfter is how long a pending job goes without progress
+// before ResumeProvisioning treats its instance as gone and takes it over
+const provisioningStaleAfter = 5 * time.Minute
+
+// CreateTenant registers a provisioning job for the tenant and creates its
+// partition, queues and consumer in the background. The tenant is usable once
+// the job is ready.
+func (s *TenantService) CreateTenant(tenant *domain.Tenant) (*domain.ProvisioningJob, error) {
+	if err := s.schemaReady(); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	_, err := s.db.DB.Exec(
+		"INSERT INTO tenant_provisioning_jobs (id, tenant_id, name, status) VALUES ($1, $2, $3, $4)",
+		id, tenant.ID, tenant.Name, domain.ProvisioningStatusPending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provisioning job: %w", err)
+	}
+
+	job, err := s.GetProvisioningJob(id)
+	if err != nil {
+		return nil, err
+	}
+	go s.provision(*job)
+	return job, nil
+}
+
+// GetProvisioningJob returns the current state of a provisioning job
+func (s *TenantService) GetProvisioningJob(id string) (*domain.ProvisioningJob, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrProvisioningNotFound
+	}
+
+	var job domain.ProvisioningJob
+	var errMsg sql.NullString
+	err := s.db.DB.QueryRow(`
+		SELECT id, tenant_id, name, status, error, created_at, updated_at
+		FROM tenant_provisioning_jobs WHERE id = $1
+	`, id).Scan(&job.ID, &job.TenantID, &job.Name, &job.Status, &errMsg, &job.CreatedAt, &job.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrProvisioningNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Error = errMsg.String
+	return &job, nil
+}
+
+// WatchProvisioning resumes stalled provisioning jobs until ctx is done
+func (s *TenantService) WatchProvisioning(ctx context.Context) {
+	ticker := time.NewTicker(provisioningStaleAfter / 2)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ResumeProvisioning(); err != nil {
+			slog.Error("Failed to resume tenant provisioning", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ResumeProvisioning takes over pending jobs that made no progress for a while,
+// e.g. because the instance provisioning them stopped. Every step is
+// idempotent, so a job is simply run again from the start.
+func (s *TenantService) ResumeProvisioning() error {
+	rows, err := s.db.DB.Query(`
+		UPDATE tenant_provisioning_jobs SET updated_at = NOW()
+		WHERE status = $1 AND updated_at < NOW() - make_interval(secs => $2)
+		RETURNING id, tenant_id, name, status, created_at, updated_at
+	`, domain.ProvisioningStatusPending, provisioningStaleAfter.Seconds())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var jobs []domain.ProvisioningJob
+	for rows.Next() {
+		var job domain.ProvisioningJob
+		if err := rows.Scan(&job.ID, &job.TenantID, &job.Name, &job.Status, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		slog.Info("Resuming tenant provisioning", "tenant_id", job.TenantID, "job_id", job.ID)
+		go s.provision(job)
+	}
+	return nil
+}
+
+// provision runs the job and records whether the tenant is ready or failed
+func (s *TenantService) provision(job domain.ProvisioningJob) {
+	queue, err := s.provisionTenant(job)
+	if err != nil {
+		slog.Error("Tenant provisioning failed", "tenant_id", job.TenantID, "job_id", job.ID, "error", err)
+		s.db.DB.Exec(
+			"UPDATE tenant_provisioning_jobs SET status = $2, error = $3, updated_at = NOW() WHERE id = $1",
+			job.ID, domain.ProvisioningStatusFailed, err.Error(),
+		)
+		return
+	}
+
+	s.db.DB.Exec(
+		"UPDATE tenant_provisioning_jobs SET status = $2, error = NULL, updated_at = NOW() WHERE id = $1",
+		job.ID, domain.ProvisioningStatusReady,
+	)
+	s.events.Emit(events.TypeTenantCreated, job.TenantID, map[string]interface{}{
+		"name":  job.Name,
+		"queue": queue,
+	})
+}
+
+// provisionTenant creates the tenant's partition and queues, starts its
+// consumer and saves the tenant, returning the main queue name
+func (s *TenantService) provisionTenant(job domain.ProvisioningJob) (string, error) {
+	// Create database partition
+	if err := s.createPartition(job.TenantID); err != nil {
+		return "", fmt.Errorf("failed to create partition: %w", err)
+	}
+
+	s.touchProvisioning(job.ID)
+
+	// Create RabbitMQ queues
+	queue := s.queueName(job.TenantID)
+	if err := s.declareTenantQueues(job.TenantID, queue); err != nil {
+		return "", err
+	}
+
+	s.touchProvisioning(job.ID)
+
+	// Start consumer and store in tenant manager
+	config := domain.TenantConfig{
+		TenantID:  job.TenantID,
+		Workers:   3, // Default workers
+		QueueName: queue,
+	}
+	if err := s.startConsumer(config); err != nil {
+		return "", err
+	}
+
+	// Save tenant to database
+	_, err := s.db.DB.Exec(
+		"INSERT INTO tenants (id, name, queue_name, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING",
+		job.TenantID, job.Name, queue, job.CreatedAt,
+	)
+	if err != nil {
+		// Tanpa baris tenant, consumer tidak boleh tetap berjalan
+		s.tenantManager.RemoveTenant(job.TenantID)
+		s.deliveries.Forget(job.TenantID)
+		s.journal.Forget(job.TenantID)
+		return "", err
+	}
+	return queue, nil
+}
+
+// touchProvisioning records progress so the job is not taken over as stalled
+func (s *TenantService) touchProvisioning(id string) {
+	s.db.DB.Exec("UPDATE tenant_provisioning_jobs SET updated_at = NOW() WHERE id = $1", id)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/repository"
+	"multi-tenant-messaging/internal/transport"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrInvalidPublish is returned when a published payload is not a JSON value
+// or its delay is out of range
+var ErrInvalidPublish = errors.New("invalid publish")
+
+const (
+	// maxPublishDelay caps how long a published message can be held back
+	maxPublishDelay = 7 * 24 * time.Hour
+	// scheduledQueueIdle is how long an empty scheduled queue outlives its delay
+	scheduledQueueIdle = time.Minute
+	// deliverAtHeader carries when a scheduled message was due, RFC 3339, so
+	// the consumer stores it with the message
+	deliverAtHeader = "x-salva-deliver-at"
+)
+
+// scheduledQueueName is where messages for queue wait out delay. Every delay
+// has its own queue so a long delay never holds back a shorter one behind it.
+func scheduledQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s_delay_%ds", queue, int(delay.Seconds()))
+}
+
+// publishPlan is a validated publish: the message and the queue it goes to
+type publishPlan struct {
+	tenantID    string
+	messageID   string
+	messageType string
+	queue       string
+	payload     []byte
+	priority    uint8
+	// deliverAt is set for a delayed message
+	deliverAt *time.Time
+}
+
+func (p publishPlan) result() domain.PublishResult {
+	return domain.PublishResult{MessageID: p.messageID, Queue: p.queue, DeliverAt: p.deliverAt}
+}
+
+// PublishMessage publishes a JSON payload to the tenant's main queue, or to
+// one of its channels, and returns the generated message ID. The tenant must
+// exist and have its messages partition attached so the message can be stored.
+// A delayed message waits in a scheduled queue whose TTL dead-letters it into
+// the target queue once the delay has passed. Publishing faster than the
+// tenant's rate limit fails with a *RateLimitError. With the outbox enabled
+// the message is written to the outbox and published by the relay.
+func (s *TenantService) PublishMessage(ctx context.Context, tenantID string, req domain.PublishRequest) (domain.PublishResult, error) {
+	if s.outbox.Enabled() {
+		return s.EnqueueMessage(ctx, s.db.DB, tenantID, req)
+	}
+	plan, err := s.preparePublish(ctx, s.db.DB, tenantID, req)
+	if err != nil {
+		return domain.PublishResult{}, err
+	}
+	if err := s.deliverPublish(ctx, plan, amqp.Table{}); err != nil {
+		return domain.PublishResult{}, err
+	}
+	return plan.result(), nil
+}
+
+// preparePublish validates req and resolves the queue it goes to, reading
+// the tenant through q
+func (s *TenantService) preparePublish(ctx context.Context, q repository.Querier, tenantID string, req domain.PublishRequest) (publishPlan, error) {
+	payload := bytes.TrimSpace(req.Payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("null")) {
+		return publishPlan{}, fmt.Errorf("%w: payload is required", ErrInvalidPublish)
+	}
+	delay := time.Duration(req.DelaySeconds) * time.Second
+	if delay < 0 || delay > maxPublishDelay {
+		return publishPlan{}, fmt.Errorf("%w: delay_seconds must be between 0 and %d", ErrInvalidPublish, int(maxPublishDelay.Seconds()))
+	}
+	if req.DeliverAt != nil {
+		if req.DelaySeconds != 0 {
+			return publishPlan{}, fmt.Errorf("%w: set either delay_seconds or deliver_at", ErrInvalidPublish)
+		}
+		// deliver_at yang sudah lewat dikirim langsung
+		delay = max(time.Until(*req.DeliverAt), 0)
+		if delay > maxPublishDelay {
+			return publishPlan{}, fmt.Errorf("%w: deliver_at must be within %s", ErrInvalidPublish, maxPublishDelay)
+		}
+	}
+	if req.Priority < 0 || req.Priority > maxMessagePriority {
+		return publishPlan{}, fmt.Errorf("%w: priority must be between 0 and %d", ErrInvalidPublish, maxMessagePriority)
+	}
+	// Payload yang tidak valid ditolak di sini daripada berakhir di DLQ
+	if _, err := s.schemas.Validate(tenantID, req.MessageType, payload); err != nil {
+		return publishPlan{}, err
+	}
+
+	var migrated bool
+	err := q.QueryRowContext(ctx, "SELECT migrated_to IS NOT NULL FROM tenants WHERE id = $1", tenantID).Scan(&migrated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return publishPlan{}, ErrTenantNotFound
+	}
+	if err != nil {
+		return publishPlan{}, err
+	}
+	if migrated {
+		// Tenant yang sudah dipindah tidak lagi dikonsumsi dari broker ini
+		return publishPlan{}, fmt.Errorf("%w: tenant was migrated", ErrTenantNotFound)
+	}
+
+	attached, err := partitionAttached(ctx, q, tenantID)
+	if err != nil {
+		return publishPlan{}, err
+	}
+	if !attached {
+		return publishPlan{}, ErrPartitionNotFound
+	}
+	if err := s.checkQuota(ctx, tenantID); err != nil {
+		return publishPlan{}, err
+	}
+
+	if (delay > 0 || req.Channel != "") && s.onRabbitMQ() != nil {
+		return publishPlan{}, fmt.Errorf("%w: delay_seconds and channel need RabbitMQ", ErrInvalidPublish)
+	}
+
+	plan := publishPlan{
+		tenantID:    tenantID,
+		messageID:   uuid.New().String(),
+		messageType: req.MessageType,
+		queue:       s.currentQueueName(tenantID),
+		payload:     payload,
+		priority:    uint8(req.Priority),
+	}
+	if req.Channel != "" {
+		channel, err := s.GetChannel(tenantID, req.Channel)
+		if err != nil {
+			return publishPlan{}, err
+		}
+		plan.queue = channel.QueueName
+	}
+	// Token diambil setelah semua penolakan lain, agar publish yang ditolak tidak menghabiskan rate limit
+	if err := s.rateLimits.Allow(tenantID); err != nil {
+		return publishPlan{}, err
+	}
+	if delay > 0 {
+		deliverAt := time.Now().Add(delay)
+		plan.deliverAt = &deliverAt
+	}
+	return plan, nil
+}
+
+// deliverPublish publishes the message of plan with headers. A delayed
+// message goes to the scheduled queue for the delay still left.
+func (s *TenantService) deliverPublish(ctx context.Context, plan publishPlan, headers amqp.Table) error {
+	target, key := s.transport.Destination(plan.tenantID, plan.queue)
+	if plan.deliverAt != nil {
+		headers[deliverAtHeader] = plan.deliverAt.UTC().Format(time.RFC3339Nano)
+		if delay := time.Until(*plan.deliverAt).Round(time.Second); delay > 0 {
+			target = scheduledQueueName(plan.queue, delay)
+			if err := s.declareScheduledQueue(target, plan.queue, delay); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Konteks trace ikut di header supaya consumer melanjutkan trace yang sama
+	ctx, span := metrics.Tracer().Start(ctx, "publish "+plan.queue,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", s.transport.Kind()),
+			attribute.String("messaging.destination.name", target),
+			attribute.String("messaging.message.id", plan.messageID),
+			attribute.String("tenant_id", plan.tenantID),
+		))
+	metrics.InjectHeaders(ctx, headers)
+
+	err := s.transport.Publish(ctx, target, transport.Message{
+		ID:          plan.messageID,
+		Type:        plan.messageType,
+		ContentType: "application/json",
+		Key:         key,
+		Headers:     headers,
+		Priority:    plan.priority,
+		Timestamp:   time.Now(),
+		Body:        plan.payload,
+	})
+	metrics.EndSpan(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", target, err)
+	}
+	return nil
+}
+
+// declareScheduledQueue declares the queue holding messages for queue during
+// delay. It expires once it has been idle for a while after its last message
+// was due.
+func (s *TenantService) declareScheduledQueue(name, queue string, delay time.Duration) error {
+	_, err := s.rabbit.Channel().QueueDeclare(name, true, false, false, false, amqp.Table{
+		"x-message-ttl":             delay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+		"x-expires":                 (delay + scheduledQueueIdle).Milliseconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare scheduled queue %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"multi-tenant-messaging/internal/codec"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/repository"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrProcessingPanic wraps a panic raised while processing a message
+var ErrProcessingPanic = errors.New("message processing panicked")
+
+// QuarantineService counts the poison failures of messages and quarantines
+// the messages that fail too often
+type QuarantineService struct {
+	db *repository.Database
+	// threshold is how many poison failures quarantine a message, 0 turns quarantine off
+	threshold int
+	// window forgets the failures of a message that has not failed for this long
+	window time.Duration
+	// retention is how long quarantined messages are kept, 0 keeps them
+	retention time.Duration
+}
+
+func NewQuarantineService(db *repository.Database, threshold int, window, retention time.Duration) *QuarantineService {
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &QuarantineService{db: db, threshold: threshold, window: window, retention: retention}
+}
+
+// poisonReason classifies the failures that mark a message as poison when
+// they repeat; it returns "" for any other error
+func poisonReason(err error) string {
+	switch {
+	case errors.Is(err, ErrProcessingPanic):
+		return domain.ErrorReasonPanic
+	case errors.Is(err, ErrSchemaValidation):
+		return domain.ErrorReasonSchemaValidation
+	case errors.Is(err, codec.ErrDecode):
+		return domain.ErrorReasonDecode
+	}
+	return ""
+}
+
+// quarantineKey identifies a message across deliveries: its message ID, or a
+// hash of its payload when the publisher set none
+func quarantineKey(d amqp.Delivery) string {
+	if d.MessageId != "" {
+		return d.MessageId
+	}
+	sum := sha256.Sum256(d.Body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Record counts a poison failure of the delivery and reports whether the
+// message is now quarantined
+func (s *QuarantineService) Record(tenantID, channel string, d amqp.Delivery, reason string, cause error) (bool, error) {
+	if s.threshold < 1 {
+		return false, nil
+	}
+	headers, err := json.Marshal(filterHeaders(d.Headers))
+	if err != nil {
+		return false, err
+	}
+
+	// Kegagalan lama di luar window tidak dihitung lagi
+	var id string
+	var failures int
+	err = s.db.DB.QueryRow(`
+		INSERT INTO message_quarantine (tenant_id, message_key, message_id, channel, message_type, content_type, headers, payload, reason, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (tenant_id, message_key) DO UPDATE SET
+			failures = CASE WHEN message_quarantine.last_failed_at < NOW() - make_interval(secs => $11)
+				THEN 1 ELSE message_quarantine.failures + 1 END,
+			first_failed_at = CASE WHEN message_quarantine.last_failed_at < NOW() - make_interval(secs => $11)
+				THEN NOW() ELSE message_quarantine.first_failed_at END,
+			last_failed_at = NOW(),
+			channel = EXCLUDED.channel,
+			headers = EXCLUDED.headers,
+			reason = EXCLUDED.reason,
+			error = EXCLUDED.error
+		RETURNING id, failures
+	`, tenantID, quarantineKey(d), d.MessageId, channel, d.Type, d.ContentType, headers, d.Body, reason, cause.Error(),
+		s.window.Seconds()).Scan(&id, &failures)
+	if err != nil {
+		return false, err
+	}
+	if failures < s.threshold {
+		return false, nil
+	}
+
+	if _, err := s.db.DB.Exec(
+		"UPDATE message_quarantine SET quarantined_at = COALESCE(quarantined_at, NOW()) WHERE id = $1", id,
+	); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns the tenant's most recently quarantined messages
+func (s *QuarantineService) List(tenantID string, limit int) ([]domain.QuarantinedMessage, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT id, tenant_id, message_id, channel, message_type, content_type, headers, payload, reason, error,
+			failures, first_failed_at, last_failed_at, quarantined_at
+		FROM message_quarantine
+		WHERE tenant_id = $1 AND quarantined_at IS NOT NULL
+		ORDER BY quarantined_at DESC
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []domain.QuarantinedMessage{}
+	for rows.Next() {
+		var msg domain.QuarantinedMessage
+		var headers, payload []byte
+		if err := rows.Scan(&msg.ID, &msg.TenantID, &msg.MessageID, &msg.Channel, &msg.MessageType, &msg.ContentType,
+			&headers, &payload, &msg.Reason, &msg.Error, &msg.Failures, &msg.FirstFailedAt, &msg.LastFailedAt,
+			&msg.QuarantinedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(headers, &msg.Headers); err != nil {
+			return nil, err
+		}
+		if json.Valid(payload) {
+			msg.Payload = payload
+		} else {
+			msg.RawPayload = payload
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// PurgeExpired forgets failures outside the window and quarantined messages
+// past the retention
+func (s *QuarantineService) PurgeExpired() (int64, error) {
+	res, err := s.db.DB.Exec(`
+		DELETE FROM message_quarantine
+		WHERE (quarantined_at IS NULL AND last_failed_at < NOW() - make_interval(secs => $1))
+			OR ($2 > 0 AND quarantined_at < NOW() - make_interval(secs => $2))
+	`, s.window.Seconds(), s.retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunJanitor purges expired quarantine entries every interval until ctx is done
+func (s *QuarantineService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.PurgeExpired(); err != nil {
+				slog.Error("Failed to purge quarantine entries", "error", err)
+			} else if n > 0 {
+				slog.Info("Purged expired quarantine entries", "count", n)
+			}
+		}
+	}
+}
+
+// processSafely processes a delivery, turning a panic into an error
+// wrapping ErrProcessingPanic so a poison message cannot crash the service
+func (s *TenantService) processSafely(ctx context.Context, tenantID, channel string, d amqp.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "Message processing panicked", "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("%w: %v", ErrProcessingPanic, r)
+		}
+	}()
+	return s.processMessage(ctx, tenantID, channel, d.MessageId, d.Type, d.ContentType, d.Headers, d.Body)
+}
+
+// quarantine records a poison failure of the delivery and reports whether the
+// message was quarantined, in which case the caller acks it
+func (s *TenantService) quarantine(ctx context.Context, tenantID, channel string, d amqp.Delivery, err error) bool {
+	reason := poisonReason(err)
+	if reason == "" {
+		return false
+	}
+	quarantined, recordErr := s.quarantines.Record(tenantID, channel, d, reason, err)
+	if recordErr != nil {
+		slog.ErrorContext(ctx, "Failed to record poison failure", "error", recordErr)
+		return false
+	}
+	if quarantined {
+		slog.WarnContext(ctx, "Quarantined poison message", "reason", reason)
+		metrics.ObserveMessageQuarantined(ctx, tenantID, reason)
+	}
+	return quarantined
+}
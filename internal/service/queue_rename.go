@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+)
+
+// renameDeleteAttempts is how often RenameQueue shovels the old queue again
+// when publishers keep sending to it before giving up
+const renameDeleteAttempts = 10
+
+// RenameQueue moves a tenant to the queue name of the current template without
+// dropping messages: it declares the new queue, switches the consumer to it,
+// shovels what is left in the old queue and deletes the old queue once it is
+// empty. The new name is recorded only after that, so an interrupted rename
+// is completed by running it again. Old and new names are equal if nothing changed.
+func (s *TenantService) RenameQueue(ctx context.Context, tenantID string) (domain.QueueRename, error) {
+	rename := domain.QueueRename{
+		TenantID: tenantID,
+		OldName:  s.storedQueueName(tenantID),
+		NewName:  s.queueName(tenantID),
+	}
+	if rename.OldName == rename.NewName {
+		return rename, nil
+	}
+	if err := s.onRabbitMQ(); err != nil {
+		return rename, err
+	}
+
+	if err := s.declareTenantQueues(tenantID, rename.NewName); err != nil {
+		return rename, err
+	}
+
+	// Pindahkan consumer dulu supaya pesan di queue baru langsung diproses
+	if config, active := s.tenantManager.GetConfig(tenantID); active && config.QueueName != rename.NewName {
+		if err := s.DrainTenant(ctx, tenantID); err != nil {
+			slog.Error("Queue rename: failed to drain tenant", "tenant_id", tenantID, "error", err)
+		}
+		config.QueueName = rename.NewName
+		if err := s.startConsumer(config); err != nil {
+			return rename, err
+		}
+		s.emitConsumerRestarted(config, "queue_rename")
+	}
+
+	for attempt := 0; attempt < renameDeleteAttempts; attempt++ {
+		moved, err := shovelQueue(ctx, s.rabbit.Conn(), rename.OldName, s.rabbit.Conn(), rename.NewName, nil)
+		rename.Moved += moved
+		if err != nil {
+			return rename, err
+		}
+
+		deleted, err := deleteQueueIfEmpty(s.rabbit.Conn(), rename.OldName)
+		if err != nil {
+			return rename, err
+		}
+		if deleted {
+			if _, err := s.db.DB.ExecContext(ctx,
+				"UPDATE tenants SET queue_name = $2 WHERE id = $1", tenantID, rename.NewName,
+			); err != nil {
+				return rename, err
+			}
+			rename.Renamed = true
+			return rename, nil
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return rename, ctx.Err()
+		}
+	}
+	return rename, fmt.Errorf("queue %s keeps receiving messages; point publishers at %s and retry", rename.OldName, rename.NewName)
+}
+
+// RenameQueues runs RenameQueue for every tenant whose queue name differs from the current template
+func (s *TenantService) RenameQueues(ctx context.Context) ([]domain.QueueRename, error) {
+	if err := s.onRabbitMQ(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.DB.QueryContext(ctx, "SELECT id FROM tenants WHERE migrated_to IS NULL ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	tenantIDs, err := scanIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	renames := []domain.QueueRename{}
+	for _, tenantID := range tenantIDs {
+		rename, err := s.RenameQueue(ctx, tenantID)
+		if err != nil {
+			rename.Error = err.Error()
+		}
+		if rename.OldName != rename.NewName {
+			renames = append(renames, rename)
+		}
+	}
+	return renames, nil
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/repository"
+)
+
+var (
+	// ErrInvalidQuota is returned for a quota with a negative limit
+	ErrInvalidQuota = errors.New("invalid quota")
+	// ErrQuotaExceeded is returned when publishing to a tenant over its
+	// quota; the error is a *QuotaError naming the quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// quotaCacheTTL bounds how long a quota change on another instance takes to apply
+const quotaCacheTTL = 30 * time.Second
+
+// QuotaError is returned when a tenant is over one of its quotas
+type QuotaError struct {
+	// Quota is domain.QuotaMessages, QuotaBytes or QuotaQueueDepth
+	Quota string
+	Limit int64
+	Usage int64
+	// RetryAfter is when the usage is measured again
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: %s at %d of %d", ErrQuotaExceeded, e.Quota, e.Usage, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// RetryAfterSeconds is RetryAfter rounded up to whole seconds, as in a
+// Retry-After header
+func (e *QuotaError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Storage reports whether the exceeded quota is on stored messages rather
+// than on the queue
+func (e *QuotaError) Storage() bool {
+	return e.Quota != domain.QuotaQueueDepth
+}
+
+type cachedTenantQuota struct {
+	quota    domain.TenantQuota
+	loadedAt time.Time
+}
+
+type cachedQuotaUsage struct {
+	usage    domain.QuotaUsage
+	loadedAt time.Time
+}
+
+// QuotaService manages the per-tenant quotas on stored and queued messages
+// and caches what the tenants use of them
+type QuotaService struct {
+	db       *repository.Database
+	defaults domain.TenantQuota
+	// usageTTL is how long a measured usage is trusted when publishing
+	usageTTL time.Duration
+
+	mu     sync.Mutex
+	quotas map[string]cachedTenantQuota
+	usage  map[string]cachedQuotaUsage
+}
+
+func NewQuotaService(db *repository.Database, maxMessages, maxBytes, maxQueueDepth int64, usageTTL time.Duration) *QuotaService {
+	return &QuotaService{
+		db: db,
+		defaults: domain.TenantQuota{
+			MaxMessages:   maxMessages,
+			MaxBytes:      maxBytes,
+			MaxQueueDepth: maxQueueDepth,
+			Default:       true,
+		},
+		usageTTL: usageTTL,
+		quotas:   make(map[string]cachedTenantQuota),
+		usage:    make(map[string]cachedQuotaUsage),
+	}
+}
+
+// GetQuota returns the tenant's quota, falling back to the config defaults
+func (s *QuotaService) GetQuota(tenantID string) (domain.TenantQuota, error) {
+	var maxMessages, maxBytes, maxQueueDepth sql.NullInt64
+	err := s.db.DB.QueryRow(`
+		SELECT quota_max_messages, quota_max_bytes, quota_max_queue_depth
+		FROM tenant_configs WHERE tenant_id = $1
+	`, tenantID).Scan(&maxMessages, &maxBytes, &maxQueueDepth)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.TenantQuota{}, err
+	}
+	if !maxMessages.Valid {
+		return s.defaults, nil
+	}
+	return domain.TenantQuota{
+		MaxMessages:   maxMessages.Int64,
+		MaxBytes:      maxBytes.Int64,
+		MaxQueueDepth: maxQueueDepth.Int64,
+	}, nil
+}
+
+// SetQuota stores the tenant's own quota; 0 leaves a limit off
+func (s *QuotaService) SetQuota(tenantID string, quota domain.TenantQuota) (domain.TenantQuota, error) {
+	if quota.MaxMessages < 0 || quota.MaxBytes < 0 || quota.MaxQueueDepth < 0 {
+		return domain.TenantQuota{}, fmt.Errorf("%w: limits must not be negative", ErrInvalidQuota)
+	}
+	quota.Default = false
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, quota_max_messages, quota_max_bytes, quota_max_queue_depth)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id) DO UPDATE SET quota_max_messages = EXCLUDED.quota_max_messages,
+			quota_max_bytes = EXCLUDED.quota_max_bytes, quota_max_queue_depth = EXCLUDED.quota_max_queue_depth
+	`, tenantID, quota.MaxMessages, quota.MaxBytes, quota.MaxQueueDepth)
+	if err != nil {
+		return domain.TenantQuota{}, err
+	}
+
+	s.mu.Lock()
+	s.quotas[tenantID] = cachedTenantQuota{quota: quota, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return quota, nil
+}
+
+// ResetQuota drops the tenant's own quota so the config defaults apply again
+func (s *QuotaService) ResetQuota(tenantID string) (domain.TenantQuota, error) {
+	_, err := s.db.DB.Exec(`
+		UPDATE tenant_configs SET quota_max_messages = NULL, quota_max_bytes = NULL, quota_max_queue_depth = NULL
+		WHERE tenant_id = $1
+	`, tenantID)
+	if err != nil {
+		return domain.TenantQuota{}, err
+	}
+
+	s.mu.Lock()
+	s.quotas[tenantID] = cachedTenantQuota{quota: s.defaults, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return s.defaults, nil
+}
+
+// quota returns the tenant's quota from the cache, reloading it after
+// quotaCacheTTL. A quota that cannot be read leaves the tenant unlimited
+// rather than rejecting its publishes.
+func (s *QuotaService) quota(tenantID string) domain.TenantQuota {
+	s.mu.Lock()
+	cached, ok := s.quotas[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < quotaCacheTTL {
+		return cached.quota
+	}
+
+	quota, err := s.GetQuota(tenantID)
+	if err != nil {
+		slog.Warn("Failed to load quota, using the cached one", "tenant_id", tenantID, "error", err)
+		return cached.quota
+	}
+	s.mu.Lock()
+	s.quotas[tenantID] = cachedTenantQuota{quota: quota, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return quota
+}
+
+// cachedUsage returns the tenant's usage measured within usageTTL, or
+// measures it again
+func (s *QuotaService) cachedUsage(tenantID string, measure func() (domain.QuotaUsage, error)) (domain.QuotaUsage, error) {
+	s.mu.Lock()
+	cached, ok := s.usage[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < s.usageTTL {
+		return cached.usage, nil
+	}
+
+	usage, err := measure()
+	if err != nil {
+		return domain.QuotaUsage{}, err
+	}
+	s.storeUsage(tenantID, usage)
+	return usage, nil
+}
+
+func (s *QuotaService) storeUsage(tenantID string, usage domain.QuotaUsage) {
+	s.mu.Lock()
+	s.usage[tenantID] = cachedQuotaUsage{usage: usage, loadedAt: time.Now()}
+	s.mu.Unlock()
+}
+
+// Forget drops the tenant's cached quota and usage
+func (s *QuotaService) Forget(tenantID string) {
+	s.mu.Lock()
+	delete(s.quotas, tenantID)
+	delete(s.usage, tenantID)
+	s.mu.Unlock()
+}
+
+// QuotaUsage measures what the tenant stores in its messages partition and
+// holds in its main queue
+func (s *TenantService) QuotaUsage(ctx context.Context, tenantID string) (domain.QuotaUsage, error) {
+	var usage domain.QuotaUsage
+	partition := partitionName(tenantID)
+	// Partisi dihitung langsung, sama seperti ListPartitions dengan exact
+	err := s.db.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(pg_total_relation_size(to_regclass($1)), 0)", partition,
+	).Scan(&usage.Bytes)
+	if err != nil {
+		return usage, err
+	}
+	if usage.Bytes > 0 {
+		if err := s.db.DB.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, partition)).Scan(&usage.Messages); err != nil {
+			return usage, err
+		}
+	}
+
+	if s.onRabbitMQ() == nil {
+		if q, err := s.queueStats(s.currentQueueName(tenantID)); err == nil {
+			depth := int64(q.Messages)
+			usage.QueueDepth = &depth
+		}
+	}
+	s.quotas.storeUsage(tenantID, usage)
+	return usage, nil
+}
+
+// checkQuota fails with a *QuotaError when the tenant is at one of its
+// limits. Usage is measured at most every quotas.usage_ttl, so a tenant can
+// go over a limit by what it publishes in between.
+func (s *TenantService) checkQuota(ctx context.Context, tenantID string) error {
+	exceeded := s.exceededQuota(ctx, tenantID)
+	if exceeded == nil {
+		return nil
+	}
+	metrics.ObserveQuotaExceeded(tenantID, exceeded.Quota)
+	return exceeded
+}
+
+// exceededQuota returns the first quota the tenant is at, or nil
+func (s *TenantService) exceededQuota(ctx context.Context, tenantID string) *QuotaError {
+	quota := s.quotas.quota(tenantID)
+	if quota.MaxMessages == 0 && quota.MaxBytes == 0 && quota.MaxQueueDepth == 0 {
+		return nil
+	}
+
+	usage, err := s.quotas.cachedUsage(tenantID, func() (domain.QuotaUsage, error) {
+		return s.QuotaUsage(ctx, tenantID)
+	})
+	if err != nil {
+		// Kuota tidak menghalangi publish saat pemakaian tidak bisa diukur
+		slog.Warn("Failed to measure quota usage", "tenant_id", tenantID, "error", err)
+		return nil
+	}
+
+	var exceeded *QuotaError
+	switch {
+	case quota.MaxMessages > 0 && usage.Messages >= quota.MaxMessages:
+		exceeded = &QuotaError{Quota: domain.QuotaMessages, Limit: quota.MaxMessages, Usage: usage.Messages}
+	case quota.MaxBytes > 0 && usage.Bytes >= quota.MaxBytes:
+		exceeded = &QuotaError{Quota: domain.QuotaBytes, Limit: quota.MaxBytes, Usage: usage.Bytes}
+	case quota.MaxQueueDepth > 0 && usage.QueueDepth != nil && *usage.QueueDepth >= quota.MaxQueueDepth:
+		exceeded = &QuotaError{Quota: domain.QuotaQueueDepth, Limit: quota.MaxQueueDepth, Usage: *usage.QueueDepth}
+	default:
+		return nil
+	}
+	exceeded.RetryAfter = s.quotas.usageTTL
+	return exceeded
+}
+
+// waitIntake blocks while the tenant is at its quota of stored messages or
+// bytes, or until ctx is done. The consumer then takes no new deliveries:
+// RabbitMQ stops at the prefetch and the rest waits in the queue instead of
+// being stored over the quota. Channel.Flow is not used for this because
+// RabbitMQ refuses flow requests from clients. Usage is measured again every
+// quotas.usage_ttl.
+func (s *TenantService) waitIntake(ctx context.Context, tenantID string) error {
+	paused := false
+	for {
+		exceeded := s.exceededQuota(ctx, tenantID)
+		if exceeded == nil || !exceeded.Storage() {
+			if paused {
+				slog.Info("Resuming intake, tenant is back under its quota", "tenant_id", tenantID)
+				metrics.SetIntakePaused(tenantID, false)
+			}
+			return nil
+		}
+		if !paused {
+			paused = true
+			slog.Warn("Pausing intake, tenant is at its quota", "tenant_id", tenantID, "quota", exceeded.Quota, "usage", exceeded.Usage, "limit", exceeded.Limit)
+			metrics.SetIntakePaused(tenantID, true)
+		}
+
+		select {
+		case <-ctx.Done():
+			metrics.SetIntakePaused(tenantID, false)
+			return ctx.Err()
+		case <-time.After(exceeded.RetryAfter):
+		}
+	}
+}
+
+// GetTenant returns the tenant as listed by ListTenants, with its quota and
+// current usage
+func (s *TenantService) GetTenant(ctx context.Context, tenantID string) (domain.TenantDetail, error) {
+	tenants, _, err := s.ListTenants(ctx, "", "", tenantID, 1)
+	if err != nil {
+		return domain.TenantDetail{}, err
+	}
+	if len(tenants) == 0 {
+		return domain.TenantDetail{}, ErrTenantNotFound
+	}
+
+	quota, err := s.quotas.GetQuota(tenantID)
+	if err != nil {
+		return domain.TenantDetail{}, err
+	}
+	usage, err := s.QuotaUsage(ctx, tenantID)
+	if err != nil {
+		return domain.TenantDetail{}, err
+	}
+	return domain.TenantDetail{TenantSummary: tenants[0], Quota: quota, Usage: usage}, nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrInvalidRateLimit is returned for a negative rate or burst
+	ErrInvalidRateLimit = errors.New("invalid rate limit")
+	// ErrRateLimited is returned when a tenant publishes faster than its rate
+	// limit; the error is a *RateLimitError with the delay to retry after
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// rateLimitCacheTTL bounds how long a limit change on another instance takes to apply
+const rateLimitCacheTTL = 30 * time.Second
+
+// RateLimitError is returned when a tenant is over its rate limit
+type RateLimitError struct {
+	// RetryAfter is when the next message would be allowed
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RetryAfterSeconds is RetryAfter rounded up to whole seconds, as in a
+// Retry-After header
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// tenantLimiters are a tenant's token buckets; nil when it has no limit
+type tenantLimiters struct {
+	config   domain.RateLimit
+	publish  *rate.Limiter
+	consume  *rate.Limiter
+	loadedAt time.Time
+}
+
+// RateLimitService enforces the tenants' token-bucket rate limits. Buckets
+// are kept per instance, so a tenant may publish up to its rate on every
+// instance behind the load balancer.
+type RateLimitService struct {
+	db *repository.Database
+
+	mu       sync.Mutex
+	limiters map[string]*tenantLimiters
+}
+
+// NewRateLimitService creates a new RateLimitService
+func NewRateLimitService(db *repository.Database) *RateLimitService {
+	return &RateLimitService{db: db, limiters: make(map[string]*tenantLimiters)}
+}
+
+// GetConfig returns the tenant's rate limit; a zero rate means unlimited
+func (s *RateLimitService) GetConfig(tenantID string) (domain.RateLimit, error) {
+	var config domain.RateLimit
+	err := s.db.DB.QueryRow(
+		"SELECT publish_rate, publish_burst, rate_limit_consume FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&config.MessagesPerSecond, &config.Burst, &config.Consume)
+	if err == sql.ErrNoRows {
+		return domain.RateLimit{}, nil
+	}
+	if err != nil {
+		return domain.RateLimit{}, err
+	}
+	return config, nil
+}
+
+// SetConfig updates the tenant's rate limit. A zero rate disables it and a
+// zero burst defaults to one second worth of messages.
+func (s *RateLimitService) SetConfig(tenantID string, config domain.RateLimit) (domain.RateLimit, error) {
+	if config.MessagesPerSecond < 0 || math.IsInf(config.MessagesPerSecond, 0) || math.IsNaN(config.MessagesPerSecond) {
+		return domain.RateLimit{}, fmt.Errorf("%w: messages_per_second must be a non-negative number", ErrInvalidRateLimit)
+	}
+	if config.Burst < 0 {
+		return domain.RateLimit{}, fmt.Errorf("%w: burst must not be negative", ErrInvalidRateLimit)
+	}
+	if config.MessagesPerSecond == 0 {
+		config = domain.RateLimit{}
+	} else if config.Burst == 0 {
+		config.Burst = int(math.Ceil(config.MessagesPerSecond))
+	}
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, publish_rate, publish_burst, rate_limit_consume) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id) DO UPDATE SET publish_rate = EXCLUDED.publish_rate, publish_burst = EXCLUDED.publish_burst,
+			rate_limit_consume = EXCLUDED.rate_limit_consume
+	`, tenantID, config.MessagesPerSecond, config.Burst, config.Consume)
+	if err != nil {
+		return domain.RateLimit{}, err
+	}
+
+	s.mu.Lock()
+	s.limiters[tenantID] = s.refresh(s.limiters[tenantID], config)
+	s.mu.Unlock()
+	return config, nil
+}
+
+// Allow takes a token from the tenant's publish bucket. It returns a
+// *RateLimitError when the bucket is empty; no token is taken then.
+func (s *RateLimitService) Allow(tenantID string) error {
+	limiters, err := s.tenantLimiters(tenantID)
+	if err != nil || limiters.publish == nil {
+		return err
+	}
+	reservation := limiters.publish.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return &RateLimitError{RetryAfter: delay}
+	}
+	return nil
+}
+
+// WaitConsume blocks until the tenant's consumers may take the next message,
+// when the tenant paces its consumers, or until ctx is done
+func (s *RateLimitService) WaitConsume(ctx context.Context, tenantID string) error {
+	limiters, err := s.tenantLimiters(tenantID)
+	if err != nil || limiters.consume == nil {
+		return err
+	}
+	return limiters.consume.Wait(ctx)
+}
+
+// Forget drops the tenant's buckets
+func (s *RateLimitService) Forget(tenantID string) {
+	s.mu.Lock()
+	delete(s.limiters, tenantID)
+	s.mu.Unlock()
+}
+
+func (s *RateLimitService) tenantLimiters(tenantID string) (*tenantLimiters, error) {
+	s.mu.Lock()
+	cached, ok := s.limiters[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < rateLimitCacheTTL {
+		return cached, nil
+	}
+
+	config, err := s.GetConfig(tenantID)
+	if err != nil {
+		if ok {
+			// Tetap pakai limit lama daripada menolak publish saat database bermasalah
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limiters := s.refresh(s.limiters[tenantID], config)
+	s.limiters[tenantID] = limiters
+	return limiters, nil
+}
+
+// refresh returns the buckets for config, keeping the tokens of cached when
+// the config did not change
+func (s *RateLimitService) refresh(cached *tenantLimiters, config domain.RateLimit) *tenantLimiters {
+	if cached != nil && cached.config == config {
+		return &tenantLimiters{config: config, publish: cached.publish, consume: cached.consume, loadedAt: time.Now()}
+	}
+	limiters := &tenantLimiters{config: config, loadedAt: time.Now()}
+	if config.MessagesPerSecond > 0 {
+		burst := max(config.Burst, 1)
+		limiters.publish = rate.NewLimiter(rate.Limit(config.MessagesPerSecond), burst)
+		if config.Consume {
+			limiters.consume = rate.NewLimiter(rate.Limit(config.MessagesPerSecond), burst)
+		}
+	}
+	return limiters
+}
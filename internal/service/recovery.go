@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/worker"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// maxRecoveryWorkers caps the workers a recovery may add
+	maxRecoveryWorkers = 100
+	// recoveryMonitorInterval is how often a running recovery checks its queue
+	recoveryMonitorInterval = 5 * time.Second
+	// recoveryPrefetchPerWorker bounds the unacked deliveries of the recovery
+	// consumer, so the broker keeps handing out the newest messages first
+	recoveryPrefetchPerWorker = 4
+	// recoveryDrainTimeout bounds waiting for in-flight backlog messages when a recovery ends
+	recoveryDrainTimeout = 30 * time.Second
+	// recoveryStatusCheck is how many messages the parking loop moves between
+	// checks whether the recovery was cancelled
+	recoveryStatusCheck = 100
+)
+
+// ErrRecoveryNotFound is returned when a tenant has no (unfinished) recovery
+var ErrRecoveryNotFound = errors.New("recovery not found")
+
+// ErrRecoveryRunning is returned when starting a recovery while another one is unfinished
+var ErrRecoveryRunning = errors.New("a recovery is already running for this tenant")
+
+// ErrNoBacklog is returned when starting a recovery for a tenant whose queue is empty
+var ErrNoBacklog = errors.New("tenant queue has no backlog to recover")
+
+// ErrInvalidRecovery is returned for unknown orders or out of range worker counts
+var ErrInvalidRecovery = errors.New("invalid recovery")
+
+// recoveryQueueName is the queue a tenant's backlog is parked in during a recovery
+func recoveryQueueName(tenantID string) string {
+	return fmt.Sprintf("tenant_%s_recovery", tenantID)
+}
+
+// StartRecovery parks the backlog of the tenant's main queue in its recovery
+// queue and drains it with workers extra workers. oldest_first pauses the main
+// queue until the backlog is cleared; newest_first keeps consuming it and
+// processes the newest backlog messages first. Parking runs in the background;
+// the returned recovery is in the parking status.
+func (s *TenantService) StartRecovery(tenantID, order string, workers int) (domain.Recovery, error) {
+	if order == "" {
+		order = domain.RecoveryOldestFirst
+	}
+	if order != domain.RecoveryOldestFirst && order != domain.RecoveryNewestFirst {
+		return domain.Recovery{}, fmt.Errorf("%w: order must be %q or %q", ErrInvalidRecovery,
+			domain.RecoveryOldestFirst, domain.RecoveryNewestFirst)
+	}
+	if workers < 1 || workers > maxRecoveryWorkers {
+		return domain.Recovery{}, fmt.Errorf("%w: workers must be between 1 and %d", ErrInvalidRecovery, maxRecoveryWorkers)
+	}
+	if err := s.onRabbitMQ(); err != nil {
+		return domain.Recovery{}, err
+	}
+
+	config, active := s.tenantManager.GetConfig(tenantID)
+	if !active {
+		return domain.Recovery{}, ErrTenantNotFound
+	}
+	if config.Ordered {
+		return domain.Recovery{}, ErrOrderedTenant
+	}
+
+	backlog, err := s.queueDepth(config.QueueName)
+	if err != nil {
+		return domain.Recovery{}, err
+	}
+	if backlog == 0 {
+		return domain.Recovery{}, ErrNoBacklog
+	}
+
+	var id string
+	err = s.db.DB.QueryRow(`
+		INSERT INTO tenant_recoveries (tenant_id, order_policy, workers, status, backlog, remaining)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (tenant_id) WHERE status IN ('parking', 'running') DO NOTHING
+		RETURNING id
+	`, tenantID, order, workers, domain.RecoveryStatusParking, backlog).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Recovery{}, ErrRecoveryRunning
+	}
+	if err != nil {
+		return domain.Recovery{}, err
+	}
+
+	s.parking.Store(tenantID, id)
+	go s.parkBacklog(id, config, order, backlog)
+	return s.GetRecovery(tenantID)
+}
+
+// GetRecovery returns the tenant's latest recovery with its progress
+func (s *TenantService) GetRecovery(tenantID string) (domain.Recovery, error) {
+	var r domain.Recovery
+	var errMsg sql.NullString
+	var runningAt, finishedAt sql.NullTime
+	err := s.db.DB.QueryRow(`
+		SELECT id, tenant_id, order_policy, workers, status, backlog, remaining, error, started_at, running_at, finished_at
+		FROM tenant_recoveries WHERE tenant_id = $1
+		ORDER BY started_at DESC LIMIT 1
+	`, tenantID).Scan(&r.ID, &r.TenantID, &r.Order, &r.Workers, &r.Status, &r.Backlog, &r.Remaining,
+		&errMsg, &r.StartedAt, &runningAt, &finishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrRecoveryNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+	r.Error = errMsg.String
+	if runningAt.Valid {
+		r.RunningAt = &runningAt.Time
+	}
+	if finishedAt.Valid {
+		r.FinishedAt = &finishedAt.Time
+	}
+
+	if r.Status == domain.RecoveryStatusRunning {
+		// Baca kedalaman queue langsung supaya progress tidak tertinggal interval monitor
+		if remaining, err := s.queueDepth(recoveryQueueName(tenantID)); err == nil {
+			r.Remaining = remaining
+		}
+	}
+	r.Processed = max(r.Backlog-r.Remaining, 0)
+	if r.Backlog > 0 {
+		r.Progress = float64(r.Processed) / float64(r.Backlog)
+	}
+	if r.RunningAt != nil {
+		end := time.Now()
+		if r.FinishedAt != nil {
+			end = *r.FinishedAt
+		}
+		if elapsed := end.Sub(*r.RunningAt).Seconds(); elapsed > 0 {
+			r.Rate = float64(r.Processed) / elapsed
+		}
+	}
+	if r.Status == domain.RecoveryStatusRunning && r.Rate > 0 {
+		eta := int64(float64(r.Remaining) / r.Rate)
+		r.ETASeconds = &eta
+	}
+	return r, nil
+}
+
+// CancelRecovery stops the tenant's unfinished recovery. Backlog messages not
+// processed yet go back to the end of the tenant's main queue.
+func (s *TenantService) CancelRecovery(tenantID string) (domain.Recovery, error) {
+	var status string
+	err := s.db.DB.QueryRow(`
+		WITH previous AS (
+			SELECT id, status FROM tenant_recoveries
+			WHERE tenant_id = $1 AND status IN ('parking', 'running') FOR UPDATE
+		)
+		UPDATE tenant_recoveries r SET status = $2, finished_at = NOW()
+		FROM previous WHERE r.id = previous.id
+		RETURNING previous.status
+	`, tenantID, domain.RecoveryStatusCancelled).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Recovery{}, ErrRecoveryNotFound
+	}
+	if err != nil {
+		return domain.Recovery{}, err
+	}
+
+	// Recovery yang sedang parking di instance ini diselesaikan oleh parkBacklog sendiri
+	if _, parking := s.parking.Load(tenantID); !parking || status != domain.RecoveryStatusParking {
+		s.finishRecovery(tenantID)
+	}
+	return s.GetRecovery(tenantID)
+}
+
+// runningRecovery returns the tenant's running recovery, nil if there is none
+func (s *TenantService) runningRecovery(tenantID string) (*domain.Recovery, error) {
+	var r domain.Recovery
+	err := s.db.DB.QueryRow(`
+		SELECT id, tenant_id, order_policy, workers, status FROM tenant_recoveries
+		WHERE tenant_id = $1 AND status = $2
+	`, tenantID, domain.RecoveryStatusRunning).Scan(&r.ID, &r.TenantID, &r.Order, &r.Workers, &r.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// recoveryConsumerConfig is the consumer configuration for the tenant's recovery queue
+func recoveryConsumerConfig(config domain.TenantConfig, recovery *domain.Recovery) domain.TenantConfig {
+	return domain.TenantConfig{
+		TenantID:     config.TenantID,
+		Workers:      recovery.Workers,
+		PartitionKey: config.PartitionKey,
+		QueueName:    recoveryQueueName(config.TenantID),
+		Prefetch:     recovery.Workers * recoveryPrefetchPerWorker,
+	}
+}
+
+// parkBacklog moves up to backlog messages from the tenant's main queue to
+// its recovery queue and then restarts the tenant's consumer to drain them.
+// For newest_first every tenth of the backlog gets a higher priority than the
+// one before it, so the recovery queue hands out the newest messages first.
+func (s *TenantService) parkBacklog(id string, config domain.TenantConfig, order string, backlog int64) {
+	defer s.parking.Delete(config.TenantID)
+
+	moved, err := s.moveBacklog(id, config, order, backlog)
+	if err != nil {
+		slog.Error("Recovery failed", "recovery_id", id, "tenant_id", config.TenantID, "error", err)
+		s.db.DB.Exec(`
+			UPDATE tenant_recoveries SET status = $2, error = $3, backlog = $4, remaining = $4, finished_at = NOW()
+			WHERE id = $1 AND status = $5
+		`, id, domain.RecoveryStatusFailed, err.Error(), moved, domain.RecoveryStatusParking)
+		s.finishRecovery(config.TenantID)
+		return
+	}
+
+	result, err := s.db.DB.Exec(`
+		UPDATE tenant_recoveries SET status = $2, backlog = $3, remaining = $3, running_at = NOW()
+		WHERE id = $1 AND status = $4
+	`, id, domain.RecoveryStatusRunning, moved, domain.RecoveryStatusParking)
+	if err != nil {
+		slog.Error("Recovery: failed to record parked backlog", "recovery_id", id, "tenant_id", config.TenantID, "error", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Dibatalkan selama parking
+		s.finishRecovery(config.TenantID)
+		return
+	}
+
+	if current, active := s.tenantManager.GetConfig(config.TenantID); active {
+		if err := s.restartConsumer(current, "recovery"); err != nil {
+			slog.Error("Recovery: failed to restart consumer", "recovery_id", id, "tenant_id", config.TenantID, "error", err)
+		}
+	}
+}
+
+// moveBacklog does the parking of parkBacklog and returns how many messages it moved
+func (s *TenantService) moveBacklog(id string, config domain.TenantConfig, order string, backlog int64) (int64, error) {
+	source, err := s.rabbit.OpenChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+	dest, err := s.rabbit.OpenChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer dest.Close()
+
+	queue := recoveryQueueName(config.TenantID)
+	if err := declareTenantQueueArgs(s.rabbit, config.TenantID, queue, s.messageTTL, amqp.Table{
+		"x-max-priority": int64(worker.PriorityLevels - 1),
+	}); err != nil {
+		return 0, err
+	}
+	if err := dest.Confirm(false); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	var moved int64
+	for moved < backlog {
+		if moved%recoveryStatusCheck == 0 {
+			var status string
+			if err := s.db.DB.QueryRow("SELECT status FROM tenant_recoveries WHERE id = $1", id).Scan(&status); err != nil {
+				return moved, err
+			}
+			if status != domain.RecoveryStatusParking {
+				return moved, nil
+			}
+		}
+
+		d, ok, err := source.Get(config.QueueName, false)
+		if err != nil {
+			return moved, err
+		}
+		if !ok {
+			// Sisa backlog sudah diproses consumer yang masih berjalan
+			return moved, nil
+		}
+
+		d.Priority = 0
+		if order == domain.RecoveryNewestFirst {
+			d.Priority = uint8(moved * worker.PriorityLevels / backlog)
+		}
+		if err := publishConfirmed(ctx, dest, queue, d); err != nil {
+			d.Nack(false, true)
+			return moved, err
+		}
+		if err := d.Ack(false); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// monitorRecovery records the remaining backlog of a running recovery until
+// the recovery queue is empty, then completes the recovery. It also ends the
+// recovery here once another instance completed or cancelled it. It runs for
+// as long as the consumer that ctx belongs to.
+func (s *TenantService) monitorRecovery(ctx context.Context, recovery *domain.Recovery) {
+	ticker := time.NewTicker(recoveryMonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var status string
+		if err := s.db.DB.QueryRow("SELECT status FROM tenant_recoveries WHERE id = $1", recovery.ID).Scan(&status); err != nil {
+			slog.Error("Recovery: failed to read status", "recovery_id", recovery.ID, "tenant_id", recovery.TenantID, "error", err)
+			continue
+		}
+		if status == domain.RecoveryStatusRunning {
+			remaining, err := s.queueDepth(recoveryQueueName(recovery.TenantID))
+			if err != nil {
+				slog.Error("Recovery: failed to inspect recovery queue", "recovery_id", recovery.ID, "tenant_id", recovery.TenantID, "error", err)
+				continue
+			}
+			if _, err := s.db.DB.Exec("UPDATE tenant_recoveries SET remaining = $2 WHERE id = $1", recovery.ID, remaining); err != nil {
+				slog.Error("Recovery: failed to record progress", "recovery_id", recovery.ID, "tenant_id", recovery.TenantID, "error", err)
+			}
+			if remaining > 0 {
+				continue
+			}
+			if _, err := s.db.DB.Exec(`
+				UPDATE tenant_recoveries SET status = $2, finished_at = NOW() WHERE id = $1 AND status = $3
+			`, recovery.ID, domain.RecoveryStatusCompleted, domain.RecoveryStatusRunning); err != nil {
+				slog.Error("Recovery: failed to complete", "recovery_id", recovery.ID, "tenant_id", recovery.TenantID, "error", err)
+				continue
+			}
+		}
+
+		// finishRecovery menghentikan consumer yang memiliki ctx ini, jadi jalankan terpisah
+		go s.finishRecovery(recovery.TenantID)
+		return
+	}
+}
+
+// finishRecovery restarts the tenant's consumer here without the recovery
+// queue, once the backlog messages in flight are settled, and moves whatever
+// is left in the recovery queue back to the main queue
+func (s *TenantService) finishRecovery(tenantID string) {
+	if config, active := s.tenantManager.GetConfig(tenantID); active {
+		ctx, cancel := context.WithTimeout(context.Background(), recoveryDrainTimeout)
+		if err := s.DrainTenant(ctx, tenantID); err != nil {
+			slog.Error("Recovery: failed to drain consumer", "tenant_id", tenantID, "error", err)
+		}
+		cancel()
+		if err := s.startConsumer(config); err != nil {
+			slog.Error("Recovery: failed to restart consumer", "tenant_id", tenantID, "error", err)
+		} else {
+			s.emitConsumerRestarted(config, "recovery")
+		}
+	}
+
+	queue := recoveryQueueName(tenantID)
+	if _, err := shovelQueue(context.Background(), s.rabbit.Conn(), queue, s.rabbit.Conn(), s.currentQueueName(tenantID), nil); err != nil {
+		slog.Error("Recovery: failed to return backlog to main queue", "tenant_id", tenantID, "error", err)
+		return
+	}
+	if _, err := deleteQueueIfEmpty(s.rabbit.Conn(), queue); err != nil {
+		slog.Error("Recovery: failed to delete recovery queue", "tenant_id", tenantID, "error", err)
+	}
+}
+
+// queueDepth returns the number of ready messages in a queue, 0 if it does not exist
+func (s *TenantService) queueDepth(name string) (int64, error) {
+	q, err := s.queueStats(name)
+	return int64(q.Messages), err
+}
+
+// queueStats returns the ready messages and consumers of a queue, zero if it
+// does not exist
+func (s *TenantService) queueStats(name string) (amqp.Queue, error) {
+	if err := s.onRabbitMQ(); err != nil {
+		return amqp.Queue{}, err
+	}
+	// Broker menutup channel jika queue tidak ada, jadi pakai channel terpisah
+	ch, err := s.rabbit.OpenChannel()
+	if err != nil {
+		return amqp.Queue{}, err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(name, true, false, false, false, nil)
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return amqp.Queue{Name: name}, nil
+	}
+	return q, err
+}
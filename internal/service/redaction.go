@@ -0,0 +1,127 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/redact"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// redactionCacheTTL bounds how long workers may use stale rules after a change on another instance
+const redactionCacheTTL = 30 * time.Second
+
+type cachedRules struct {
+	rules    []redact.Rule
+	loadedAt time.Time
+}
+
+// RedactionService manages per-tenant PII redaction rules
+type RedactionService struct {
+	db *repository.Database
+	// secret keys the hash action, see redact.TenantKey
+	secret []byte
+
+	mu    sync.RWMutex
+	cache map[string]cachedRules
+}
+
+func NewRedactionService(db *repository.Database, secret []byte) *RedactionService {
+	return &RedactionService{
+		db:     db,
+		secret: secret,
+		cache:  make(map[string]cachedRules),
+	}
+}
+
+// GetRules returns the tenant's redaction rules
+func (s *RedactionService) GetRules(tenantID string) ([]redact.Rule, error) {
+	rows, err := s.db.DB.Query(
+		"SELECT path, action FROM tenant_redaction_rules WHERE tenant_id = $1 ORDER BY path", tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]redact.Rule, 0)
+	for rows.Next() {
+		var rule redact.Rule
+		if err := rows.Scan(&rule.Path, &rule.Action); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SetRules replaces the tenant's redaction rules
+func (s *RedactionService) SetRules(tenantID string, rules []redact.Rule) ([]redact.Rule, error) {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM tenant_redaction_rules WHERE tenant_id = $1", tenantID); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant_redaction_rules (tenant_id, path, action) VALUES ($1, $2, $3)
+			ON CONFLICT (tenant_id, path) DO UPDATE SET action = EXCLUDED.action
+		`, tenantID, rule.Path, rule.Action); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+
+	return s.GetRules(tenantID)
+}
+
+// Redact applies the tenant's rules to a message body before it is stored
+func (s *RedactionService) Redact(tenantID string, body []byte) ([]byte, error) {
+	rules, err := s.rules(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	redacted, _, err := redact.ApplyJSON(body, rules, redact.TenantKey(s.secret, tenantID))
+	return redacted, err
+}
+
+// Preview applies rules to payload in place as Redact would for the tenant
+// and returns the paths that matched
+func (s *RedactionService) Preview(tenantID string, payload map[string]interface{}, rules []redact.Rule) []string {
+	return redact.Apply(payload, rules, redact.TenantKey(s.secret, tenantID))
+}
+
+func (s *RedactionService) rules(tenantID string) ([]redact.Rule, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < redactionCacheTTL {
+		return cached.rules, nil
+	}
+
+	rules, err := s.GetRules(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedRules{rules: rules, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return rules, nil
+}
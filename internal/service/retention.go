@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+var (
+	// ErrInvalidRetention is returned for a negative max age or max rows
+	ErrInvalidRetention = errors.New("invalid retention policy")
+	// ErrInvalidPurge is returned for a purge that selects no messages
+	ErrInvalidPurge = errors.New("invalid purge")
+)
+
+const archivePrefix = "messages_archive_"
+
+// archiveTableName returns the table a tenant's expired messages are archived to
+func archiveTableName(tenantID string) string {
+	return archivePrefix + strings.ReplaceAll(tenantID, "-", "_")
+}
+
+// RetentionService expires stored messages by the tenants' retention
+// policies. Messages are deleted from the tenant's own partition, so a purge
+// never scans other tenants' messages, in batches that keep locks short.
+type RetentionService struct {
+	db        *repository.Database
+	batchSize int
+}
+
+// NewRetentionService creates a RetentionService deleting up to batchSize
+// messages per statement
+func NewRetentionService(db *repository.Database, batchSize int) *RetentionService {
+	if batchSize <= 0 {
+		batchSize = 10000
+	}
+	return &RetentionService{db: db, batchSize: batchSize}
+}
+
+// GetPolicy returns the tenant's retention policy; zero keeps messages forever
+func (s *RetentionService) GetPolicy(tenantID string) (domain.RetentionPolicy, error) {
+	var policy domain.RetentionPolicy
+	err := s.db.DB.QueryRow(
+		"SELECT retention_max_age_seconds, retention_max_rows, retention_archive FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&policy.MaxAgeSeconds, &policy.MaxRows, &policy.Archive)
+	if err == sql.ErrNoRows {
+		return domain.RetentionPolicy{}, nil
+	}
+	if err != nil {
+		return domain.RetentionPolicy{}, err
+	}
+	return policy, nil
+}
+
+// SetPolicy updates the tenant's retention policy; the janitor applies it on
+// its next run
+func (s *RetentionService) SetPolicy(tenantID string, policy domain.RetentionPolicy) (domain.RetentionPolicy, error) {
+	if policy.MaxAgeSeconds < 0 {
+		return domain.RetentionPolicy{}, fmt.Errorf("%w: max_age_seconds must not be negative", ErrInvalidRetention)
+	}
+	if policy.MaxRows < 0 {
+		return domain.RetentionPolicy{}, fmt.Errorf("%w: max_rows must not be negative", ErrInvalidRetention)
+	}
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, retention_max_age_seconds, retention_max_rows, retention_archive) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id) DO UPDATE SET retention_max_age_seconds = EXCLUDED.retention_max_age_seconds,
+			retention_max_rows = EXCLUDED.retention_max_rows, retention_archive = EXCLUDED.retention_archive
+	`, tenantID, policy.MaxAgeSeconds, policy.MaxRows, policy.Archive)
+	if err != nil {
+		return domain.RetentionPolicy{}, err
+	}
+	return policy, nil
+}
+
+// Purge deletes or archives the tenant's messages selected by req, or
+// expired by its retention policy when req selects none
+func (s *RetentionService) Purge(ctx context.Context, tenantID string, req domain.PurgeRequest) (*domain.PurgeResult, error) {
+	if req.KeepLatest < 0 {
+		return nil, fmt.Errorf("%w: keep_latest must not be negative", ErrInvalidPurge)
+	}
+	var exists bool
+	if err := s.db.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)", tenantID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTenantNotFound
+	}
+
+	policy, err := s.GetPolicy(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	archive := policy.Archive
+	if req.Archive != nil {
+		archive = *req.Archive
+	}
+	if req.Before == nil && req.KeepLatest == 0 {
+		if !policy.Enabled() {
+			return nil, fmt.Errorf("%w: set before or keep_latest, the tenant has no retention policy", ErrInvalidPurge)
+		}
+		req.Before, req.KeepLatest = policyCutoff(policy)
+	}
+	return s.purge(ctx, tenantID, req.Before, req.KeepLatest, archive)
+}
+
+// Enforce applies the retention policies of all tenants once and returns
+// the number of messages expired
+func (s *RetentionService) Enforce(ctx context.Context) (int64, error) {
+	rows, err := s.db.DB.QueryContext(ctx, `
+		SELECT c.tenant_id, c.retention_max_age_seconds, c.retention_max_rows, c.retention_archive
+		FROM tenant_configs c JOIN tenants t ON t.id = c.tenant_id
+		WHERE t.migrated_to IS NULL AND (c.retention_max_age_seconds > 0 OR c.retention_max_rows > 0)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	policies := make(map[string]domain.RetentionPolicy)
+	for rows.Next() {
+		var tenantID string
+		var policy domain.RetentionPolicy
+		if err := rows.Scan(&tenantID, &policy.MaxAgeSeconds, &policy.MaxRows, &policy.Archive); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		policies[tenantID] = policy
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for tenantID, policy := range policies {
+		before, keepLatest := policyCutoff(policy)
+		result, err := s.purge(ctx, tenantID, before, keepLatest, policy.Archive)
+		if errors.Is(err, ErrPartitionNotFound) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return total, ctx.Err()
+			}
+			slog.Error("Failed to expire messages", "tenant_id", tenantID, "error", err)
+			continue
+		}
+		if result.Purged > 0 {
+			slog.Info("Expired messages", "tenant_id", tenantID, "count", result.Purged, "archived", result.Archived)
+		}
+		total += result.Purged
+	}
+	return total, nil
+}
+
+// RunJanitor enforces the retention policies every interval until ctx is done
+func (s *RetentionService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Enforce(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to enforce message retention", "error", err)
+			}
+		}
+	}
+}
+
+// policyCutoff turns policy into the messages a purge selects
+func policyCutoff(policy domain.RetentionPolicy) (*time.Time, int64) {
+	var before *time.Time
+	if policy.MaxAgeSeconds > 0 {
+		cutoff := time.Now().Add(-time.Duration(policy.MaxAgeSeconds) * time.Second)
+		before = &cutoff
+	}
+	return before, policy.MaxRows
+}
+
+// purge expires the tenant's messages stored before before, when set, and
+// all but its keepLatest newest ones, when set
+func (s *RetentionService) purge(ctx context.Context, tenantID string, before *time.Time, keepLatest int64, archive bool) (*domain.PurgeResult, error) {
+	attached, err := partitionAttached(ctx, s.db.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !attached {
+		return nil, ErrPartitionNotFound
+	}
+
+	result := &domain.PurgeResult{TenantID: tenantID, Archived: archive}
+	var columns string
+	if archive {
+		result.ArchiveTable = archiveTableName(tenantID)
+		if columns, err = s.ensureArchive(ctx, result.ArchiveTable); err != nil {
+			return nil, err
+		}
+	}
+
+	partition := partitionName(tenantID)
+	if before != nil {
+		n, err := s.purgeWhere(ctx, partition, result.ArchiveTable, columns, "created_at < $1", *before)
+		result.Purged += n
+		if err != nil {
+			return result, err
+		}
+	}
+	if keepLatest > 0 {
+		// Pesan terbaru ke-(keepLatest+1) dan semua yang lebih lama dihapus
+		var cutoffAt time.Time
+		var cutoffID string
+		err := s.db.DB.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT created_at, id FROM "%s" WHERE created_at IS NOT NULL
+			ORDER BY created_at DESC, id DESC OFFSET $1 LIMIT 1
+		`, partition), keepLatest).Scan(&cutoffAt, &cutoffID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		n, err := s.purgeWhere(ctx, partition, result.ArchiveTable, columns, "(created_at, id) <= ($1, $2)", cutoffAt, cutoffID)
+		result.Purged += n
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// purgeWhere deletes the partition's messages matching condition batch by
+// batch, moving them to archive in the same statement when it is set
+func (s *RetentionService) purgeWhere(ctx context.Context, partition, archive, columns, condition string, args ...interface{}) (int64, error) {
+	expire := fmt.Sprintf(`
+		DELETE FROM "%s" WHERE ctid IN (SELECT ctid FROM "%s" WHERE %s LIMIT %d)
+		RETURNING *
+	`, partition, partition, condition, s.batchSize)
+	query := fmt.Sprintf("WITH expired AS (%s) SELECT COUNT(*) FROM expired", expire)
+	if archive != "" {
+		query = fmt.Sprintf(`
+			WITH expired AS (%s), archived AS (
+				INSERT INTO "%s" (%s) SELECT %s FROM expired RETURNING 1
+			)
+			SELECT COUNT(*) FROM archived
+		`, expire, archive, columns, columns)
+	}
+
+	var total int64
+	for {
+		var n int64
+		if err := s.db.DB.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+			return total, err
+		}
+		total += n
+		if n < int64(s.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// ensureArchive creates the archive table with the columns of messages and
+// returns its column list. Columns added to messages after the table was
+// created are not archived.
+func (s *RetentionService) ensureArchive(ctx context.Context, table string) (string, error) {
+	if _, err := s.db.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (LIKE messages INCLUDING DEFAULTS)`, table)); err != nil {
+		return "", fmt.Errorf("failed to create archive table: %w", err)
+	}
+	var columns string
+	err := s.db.DB.QueryRowContext(ctx, `
+		SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position)
+		FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1
+	`, table).Scan(&columns)
+	return columns, err
+}
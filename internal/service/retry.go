@@ -0,0 +1,145 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+// ErrInvalidRetryPolicy is returned for a policy without attempts or waits,
+// a max backoff below the backoff, or a jitter outside 0 to 1
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// maxRetryAttempts bounds a tenant's max attempts
+const maxRetryAttempts = 100
+
+// retryPolicyCacheTTL bounds how long a policy change on another instance takes to apply
+const retryPolicyCacheTTL = 30 * time.Second
+
+type cachedTenantRetryPolicy struct {
+	policy   domain.RetryPolicy
+	loadedAt time.Time
+}
+
+// RetryService manages the per-tenant policies for retrying messages that
+// failed for a reason that may pass
+type RetryService struct {
+	db       *repository.Database
+	defaults domain.RetryPolicy
+
+	mu       sync.Mutex
+	policies map[string]cachedTenantRetryPolicy
+}
+
+func NewRetryService(db *repository.Database, maxAttempts int, backoff, maxBackoff time.Duration, jitter float64) *RetryService {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if backoff < time.Second {
+		backoff = time.Second
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return &RetryService{
+		db: db,
+		defaults: domain.RetryPolicy{
+			MaxAttempts:       maxAttempts,
+			BackoffSeconds:    int(backoff.Seconds()),
+			MaxBackoffSeconds: int(maxBackoff.Seconds()),
+			Jitter:            jitter,
+			Default:           true,
+		},
+		policies: make(map[string]cachedTenantRetryPolicy),
+	}
+}
+
+// GetPolicy returns the tenant's retry policy, falling back to the config defaults
+func (s *RetryService) GetPolicy(tenantID string) (domain.RetryPolicy, error) {
+	var maxAttempts, backoff, maxBackoff sql.NullInt64
+	var jitter sql.NullFloat64
+	err := s.db.DB.QueryRow(`
+		SELECT retry_max_attempts, retry_backoff_seconds, retry_max_backoff_seconds, retry_jitter
+		FROM tenant_configs WHERE tenant_id = $1
+	`, tenantID).Scan(&maxAttempts, &backoff, &maxBackoff, &jitter)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.RetryPolicy{}, err
+	}
+	if !maxAttempts.Valid {
+		return s.defaults, nil
+	}
+	return domain.RetryPolicy{
+		MaxAttempts:       int(maxAttempts.Int64),
+		BackoffSeconds:    int(backoff.Int64),
+		MaxBackoffSeconds: int(maxBackoff.Int64),
+		Jitter:            jitter.Float64,
+	}, nil
+}
+
+// SetPolicy overrides the tenant's retry policy. Messages already waiting in
+// a backoff queue keep their wait; their next failure follows the new policy.
+func (s *RetryService) SetPolicy(tenantID string, policy domain.RetryPolicy) (domain.RetryPolicy, error) {
+	switch {
+	case policy.MaxAttempts < 1 || policy.MaxAttempts > maxRetryAttempts:
+		return domain.RetryPolicy{}, fmt.Errorf("%w: max_attempts must be between 1 and %d", ErrInvalidRetryPolicy, maxRetryAttempts)
+	case policy.BackoffSeconds < 1:
+		return domain.RetryPolicy{}, fmt.Errorf("%w: backoff_seconds must be at least 1", ErrInvalidRetryPolicy)
+	case policy.MaxBackoffSeconds < policy.BackoffSeconds:
+		return domain.RetryPolicy{}, fmt.Errorf("%w: max_backoff_seconds must not be less than backoff_seconds", ErrInvalidRetryPolicy)
+	case policy.Jitter < 0 || policy.Jitter > 1:
+		return domain.RetryPolicy{}, fmt.Errorf("%w: jitter must be between 0 and 1", ErrInvalidRetryPolicy)
+	}
+	policy.Default = false
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, retry_max_attempts, retry_backoff_seconds, retry_max_backoff_seconds, retry_jitter)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			retry_max_attempts = EXCLUDED.retry_max_attempts,
+			retry_backoff_seconds = EXCLUDED.retry_backoff_seconds,
+			retry_max_backoff_seconds = EXCLUDED.retry_max_backoff_seconds,
+			retry_jitter = EXCLUDED.retry_jitter
+	`, tenantID, policy.MaxAttempts, policy.BackoffSeconds, policy.MaxBackoffSeconds, policy.Jitter)
+	if err != nil {
+		return domain.RetryPolicy{}, err
+	}
+
+	s.mu.Lock()
+	s.policies[tenantID] = cachedTenantRetryPolicy{policy: policy, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return policy, nil
+}
+
+// policy returns the tenant's policy from the cache, or the config defaults
+// when it cannot be loaded so consumers keep retrying
+func (s *RetryService) policy(tenantID string) domain.RetryPolicy {
+	s.mu.Lock()
+	cached, ok := s.policies[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < retryPolicyCacheTTL {
+		return cached.policy
+	}
+
+	policy, err := s.GetPolicy(tenantID)
+	if err != nil {
+		slog.Warn("Failed to load retry policy, using defaults", "tenant_id", tenantID, "error", err)
+		return s.defaults
+	}
+	s.mu.Lock()
+	s.policies[tenantID] = cachedTenantRetryPolicy{policy: policy, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return policy
+}
+
+// Forget drops the tenant's cached policy
+func (s *RetryService) Forget(tenantID string) {
+	s.mu.Lock()
+	delete(s.policies, tenantID)
+	s.mu.Unlock()
+}
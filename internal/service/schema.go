@@ -0,0 +1,269 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ErrInvalidSchema is returned when a registered schema is not a valid JSON Schema
+var ErrInvalidSchema = errors.New("invalid JSON schema")
+
+// ErrSchemaNotFound is returned when a schema version does not exist
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// ErrSchemaValidation is returned when a payload does not match its type's latest schema
+var ErrSchemaValidation = errors.New("payload does not match schema")
+
+// tenantSchemaType is the message type the tenant-wide schema is registered
+// under; it validates messages whose type has no schema of its own
+const tenantSchemaType = ""
+
+// schemaCacheTTL bounds how long workers may validate against an old version after a change on another instance
+const schemaCacheTTL = 30 * time.Second
+
+type cachedSchema struct {
+	version  int
+	schema   *jsonschema.Schema
+	loadedAt time.Time
+}
+
+// SchemaService is a registry of versioned JSON Schemas per tenant and
+// message type. Messages are validated against the latest version of their type.
+type SchemaService struct {
+	db *repository.Database
+
+	mu    sync.RWMutex
+	cache map[string]cachedSchema
+}
+
+func NewSchemaService(db *repository.Database) *SchemaService {
+	return &SchemaService{
+		db:    db,
+		cache: make(map[string]cachedSchema),
+	}
+}
+
+// noRemoteRefs keeps tenant schemas from loading files or URLs through $ref
+type noRemoteRefs struct{}
+
+func (noRemoteRefs) Load(url string) (any, error) {
+	return nil, fmt.Errorf("external $ref %s is not allowed", url)
+}
+
+func compileSchema(raw []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(noRemoteRefs{})
+	if err := compiler.AddResource("urn:tenant-schema", doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	schema, err := compiler.Compile("urn:tenant-schema")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return schema, nil
+}
+
+// RegisterSchema stores schema as the next version for the message type
+func (s *SchemaService) RegisterSchema(tenantID, messageType string, schema json.RawMessage) (*domain.PayloadSchema, error) {
+	if _, err := compileSchema(schema); err != nil {
+		return nil, err
+	}
+
+	registered := domain.PayloadSchema{TenantID: tenantID, MessageType: messageType, Schema: schema}
+	// Versi berikutnya dihitung di dalam INSERT; primary key menolak versi ganda saat bersamaan
+	err := s.db.DB.QueryRow(`
+		INSERT INTO tenant_schemas (tenant_id, message_type, version, schema)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3
+		FROM tenant_schemas WHERE tenant_id = $1 AND message_type = $2
+		RETURNING version, created_at
+	`, tenantID, messageType, []byte(schema)).Scan(&registered.Version, &registered.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, tenantID+"/"+messageType)
+	s.mu.Unlock()
+	return &registered, nil
+}
+
+// ListSchemas returns every schema version of the tenant without the schema bodies
+func (s *SchemaService) ListSchemas(tenantID string) ([]domain.PayloadSchema, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT message_type, version, created_at FROM tenant_schemas
+		WHERE tenant_id = $1 ORDER BY message_type, version
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := []domain.PayloadSchema{}
+	for rows.Next() {
+		schema := domain.PayloadSchema{TenantID: tenantID}
+		if err := rows.Scan(&schema.MessageType, &schema.Version, &schema.CreatedAt); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// GetSchema returns one schema version
+func (s *SchemaService) GetSchema(tenantID, messageType string, version int) (*domain.PayloadSchema, error) {
+	schema := domain.PayloadSchema{TenantID: tenantID, MessageType: messageType, Version: version}
+	var raw []byte
+	err := s.db.DB.QueryRow(`
+		SELECT schema, created_at FROM tenant_schemas
+		WHERE tenant_id = $1 AND message_type = $2 AND version = $3
+	`, tenantID, messageType, version).Scan(&raw, &schema.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSchemaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = raw
+	return &schema, nil
+}
+
+// Validate checks body against the latest schema of the message type, or the
+// tenant's schema if the type has none, and returns the version of the
+// type's schema, or 0 if it was not validated against one
+func (s *SchemaService) Validate(tenantID, messageType string, body []byte) (int, error) {
+	latest, err := s.latest(tenantID, messageType)
+	if err != nil {
+		return 0, err
+	}
+	version, label := latest.version, messageType
+	if latest.schema == nil && messageType != tenantSchemaType {
+		// Versi schema tenant tidak disimpan pada pesan bertipe agar usage per tipe tetap bersih
+		if latest, err = s.latest(tenantID, tenantSchemaType); err != nil {
+			return 0, err
+		}
+		version = 0
+	}
+	if latest.schema == nil {
+		return 0, nil
+	}
+	if label == "" || version == 0 {
+		label = "tenant schema"
+	}
+
+	payload, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return version, fmt.Errorf("%w: %v", ErrSchemaValidation, err)
+	}
+	if err := latest.schema.Validate(payload); err != nil {
+		return version, fmt.Errorf("%w (%s v%d): %v", ErrSchemaValidation, label, latest.version, err)
+	}
+	return version, nil
+}
+
+// SetTenantSchema registers schema as the next version of the tenant's
+// schema, which validates the payloads of every message type without a
+// schema of its own
+func (s *SchemaService) SetTenantSchema(tenantID string, schema json.RawMessage) (*domain.PayloadSchema, error) {
+	return s.RegisterSchema(tenantID, tenantSchemaType, schema)
+}
+
+// GetTenantSchema returns the latest version of the tenant's schema
+func (s *SchemaService) GetTenantSchema(tenantID string) (*domain.PayloadSchema, error) {
+	schema := domain.PayloadSchema{TenantID: tenantID, MessageType: tenantSchemaType}
+	var raw []byte
+	err := s.db.DB.QueryRow(`
+		SELECT version, schema, created_at FROM tenant_schemas
+		WHERE tenant_id = $1 AND message_type = $2
+		ORDER BY version DESC LIMIT 1
+	`, tenantID, tenantSchemaType).Scan(&schema.Version, &raw, &schema.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSchemaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = raw
+	return &schema, nil
+}
+
+func (s *SchemaService) latest(tenantID, messageType string) (cachedSchema, error) {
+	key := tenantID + "/" + messageType
+	s.mu.RLock()
+	cached, ok := s.cache[key]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < schemaCacheTTL {
+		return cached, nil
+	}
+
+	cached = cachedSchema{loadedAt: time.Now()}
+	var raw []byte
+	err := s.db.DB.QueryRow(`
+		SELECT version, schema FROM tenant_schemas
+		WHERE tenant_id = $1 AND message_type = $2
+		ORDER BY version DESC LIMIT 1
+	`, tenantID, messageType).Scan(&cached.version, &raw)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return cachedSchema{}, err
+	}
+	if err == nil {
+		if cached.schema, err = compileSchema(raw); err != nil {
+			return cachedSchema{}, err
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[key] = cached
+	s.mu.Unlock()
+	return cached, nil
+}
+
+// Usage counts the stored messages of a type per period and schema version.
+// bucket is day, week or month.
+func (s *SchemaService) Usage(tenantID, messageType, bucket string, since time.Time) ([]domain.SchemaUsage, error) {
+	switch bucket {
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("invalid bucket %q, expected day, week or month", bucket)
+	}
+
+	var usage []domain.SchemaUsage
+	err := s.db.WithTenant(context.Background(), tenantID, func(q repository.Querier) error {
+		rows, err := q.QueryContext(context.Background(), `
+			SELECT date_trunc($3, created_at) AS period, schema_version, COUNT(*)
+			FROM messages
+			WHERE tenant_id = $1 AND message_type = $2 AND created_at >= $4
+			GROUP BY period, schema_version
+			ORDER BY period, schema_version NULLS FIRST
+		`, tenantID, messageType, bucket, since)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		usage = []domain.SchemaUsage{}
+		for rows.Next() {
+			var row domain.SchemaUsage
+			if err := rows.Scan(&row.Period, &row.Version, &row.Count); err != nil {
+				return err
+			}
+			usage = append(usage, row)
+		}
+		return rows.Err()
+	})
+	return usage, err
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"multi-tenant-messaging/internal/repository"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// shovelQueue moves every message from queue from on src to queue to on dst,
+// which may be another broker. Each message is acked on src only after dst
+// confirmed it, so a failure at any point never drops a message. moved, if
+// set, is called after each message.
+func shovelQueue(ctx context.Context, src *amqp.Connection, from string, dst *amqp.Connection, to string, moved func() error) (int, error) {
+	source, err := repository.OpenChannel(src)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	dest, err := repository.OpenChannel(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer dest.Close()
+	if err := dest.Confirm(false); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		d, ok, err := source.Get(from, false)
+		if err != nil {
+			return count, err
+		}
+		if !ok {
+			return count, nil
+		}
+
+		if err := publishConfirmed(ctx, dest, to, d); err != nil {
+			d.Nack(false, true)
+			return count, err
+		}
+		if err := d.Ack(false); err != nil {
+			return count, err
+		}
+		count++
+		if moved != nil {
+			if err := moved(); err != nil {
+				return count, err
+			}
+		}
+	}
+}
+
+func publishConfirmed(ctx context.Context, ch *amqp.Channel, queue string, d amqp.Delivery) error {
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false, amqp.Publishing{
+		Headers:       d.Headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		Priority:      d.Priority,
+		CorrelationId: d.CorrelationId,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		Body:          d.Body,
+	})
+	if err != nil {
+		return err
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return fmt.Errorf("target broker rejected message %s", d.MessageId)
+	}
+	return nil
+}
+
+// deleteQueueIfEmpty deletes a queue unless it still holds messages. A queue
+// that does not exist counts as deleted.
+func deleteQueueIfEmpty(conn *amqp.Connection, name string) (bool, error) {
+	// Broker menutup channel jika queue tidak kosong, jadi pakai channel terpisah
+	ch, err := repository.OpenChannel(conn)
+	if err != nil {
+		return false, err
+	}
+	defer ch.Close()
+
+	_, err = ch.QueueDelete(name, false, true, false)
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) {
+		switch amqpErr.Code {
+		case amqp.PreconditionFailed:
+			return false, nil
+		case amqp.NotFound:
+			return true, nil
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete queue %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sloCacheTTL bounds how long an objective change on another instance takes to apply
+const sloCacheTTL = 30 * time.Second
+
+// sloFastBurnWindow is the short lookback used to page on a sudden burn
+const sloFastBurnWindow = 5 * time.Minute
+
+type sloBucket struct {
+	minute int64
+	total  int64
+	good   int64
+}
+
+// sloTracker counts a tenant's good and total events in one-minute buckets
+// covering the SLO window
+type sloTracker struct {
+	objective domain.SLOObjective
+	buckets   []sloBucket
+}
+
+func (t *sloTracker) record(now time.Time, good bool) {
+	minute := now.Unix() / 60
+	b := &t.buckets[minute%int64(len(t.buckets))]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.total++
+	if good {
+		b.good++
+	}
+}
+
+// sum returns the events of the last span, rounded up to whole minutes
+func (t *sloTracker) sum(now time.Time, span time.Duration) (total, good int64) {
+	minute := now.Unix() / 60
+	oldest := minute - int64(math.Ceil(span.Minutes())) + 1
+	for _, b := range t.buckets {
+		if b.minute >= oldest && b.minute <= minute {
+			total += b.total
+			good += b.good
+		}
+	}
+	return total, good
+}
+
+type cachedObjective struct {
+	objective domain.SLOObjective
+	loadedAt  time.Time
+}
+
+// SLOService tracks per-tenant latency SLOs in-process: how many messages were
+// persisted within the tenant's threshold, and how fast the error budget is
+// burning. It is also a Prometheus collector exporting the same figures.
+type SLOService struct {
+	db       *repository.Database
+	defaults domain.SLOObjective
+	window   time.Duration
+
+	mu         sync.Mutex
+	trackers   map[string]*sloTracker
+	objectives map[string]cachedObjective
+
+	events *prometheus.CounterVec
+}
+
+var (
+	sloTargetDesc = prometheus.NewDesc("salva_tenant_slo_target",
+		"Share of messages that must be persisted within the tenant's threshold.", []string{"tenant_id"}, nil)
+	sloComplianceDesc = prometheus.NewDesc("salva_tenant_slo_compliance_ratio",
+		"Share of messages persisted within the threshold over the SLO window.", []string{"tenant_id"}, nil)
+	sloBurnRateDesc = prometheus.NewDesc("salva_tenant_slo_burn_rate",
+		"Error budget burn rate per lookback window; 1 spends exactly the budget over the SLO window.", []string{"tenant_id", "window"}, nil)
+)
+
+func NewSLOService(db *repository.Database, target float64, threshold, window time.Duration) *SLOService {
+	if window < sloFastBurnWindow {
+		window = time.Hour
+	}
+	return &SLOService{
+		db:         db,
+		defaults:   domain.SLOObjective{Target: target, ThresholdMs: threshold.Milliseconds(), Default: true},
+		window:     window,
+		trackers:   make(map[string]*sloTracker),
+		objectives: make(map[string]cachedObjective),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "salva_tenant_slo_events_total",
+			Help: "Messages counted against the tenant's SLO, by whether they met the threshold.",
+		}, []string{"tenant_id", "result"}),
+	}
+}
+
+// GetObjective returns the tenant's SLO, falling back to the config defaults
+func (s *SLOService) GetObjective(tenantID string) (domain.SLOObjective, error) {
+	var target sql.NullFloat64
+	var thresholdMs sql.NullInt64
+	err := s.db.DB.QueryRow(
+		"SELECT slo_target, slo_threshold_ms FROM tenant_configs WHERE tenant_id = $1", tenantID,
+	).Scan(&target, &thresholdMs)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.SLOObjective{}, err
+	}
+
+	objective := s.defaults
+	if target.Valid {
+		objective.Target = target.Float64
+		objective.Default = false
+	}
+	if thresholdMs.Valid {
+		objective.ThresholdMs = thresholdMs.Int64
+		objective.Default = false
+	}
+	return objective, nil
+}
+
+// SetObjective overrides the tenant's SLO. Counts already in the window are
+// kept and judged against the new target from now on.
+func (s *SLOService) SetObjective(tenantID string, target float64, threshold time.Duration) (domain.SLOObjective, error) {
+	if target <= 0 || target >= 1 {
+		return domain.SLOObjective{}, fmt.Errorf("target must be between 0 and 1 exclusive")
+	}
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, slo_target, slo_threshold_ms) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET slo_target = EXCLUDED.slo_target, slo_threshold_ms = EXCLUDED.slo_threshold_ms
+	`, tenantID, target, threshold.Milliseconds())
+	if err != nil {
+		return domain.SLOObjective{}, err
+	}
+
+	objective := domain.SLOObjective{Target: target, ThresholdMs: threshold.Milliseconds()}
+	s.mu.Lock()
+	s.objectives[tenantID] = cachedObjective{objective: objective, loadedAt: time.Now()}
+	if t, ok := s.trackers[tenantID]; ok {
+		t.objective = objective
+	}
+	s.mu.Unlock()
+	return objective, nil
+}
+
+func (s *SLOService) objective(tenantID string) domain.SLOObjective {
+	s.mu.Lock()
+	cached, ok := s.objectives[tenantID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < sloCacheTTL {
+		return cached.objective
+	}
+
+	objective, err := s.GetObjective(tenantID)
+	if err != nil {
+		// Pakai nilai terakhir (atau default) daripada kehilangan event
+		if ok {
+			return cached.objective
+		}
+		return s.defaults
+	}
+	s.mu.Lock()
+	s.objectives[tenantID] = cachedObjective{objective: objective, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return objective
+}
+
+// Record counts one processed message. It is good when it was persisted
+// without error within the tenant's threshold of publishedAt.
+func (s *SLOService) Record(tenantID string, publishedAt time.Time, err error) {
+	objective := s.objective(tenantID)
+	now := time.Now()
+	good := err == nil && now.Sub(publishedAt) <= time.Duration(objective.ThresholdMs)*time.Millisecond
+
+	s.mu.Lock()
+	t, ok := s.trackers[tenantID]
+	if !ok {
+		t = &sloTracker{buckets: make([]sloBucket, int(math.Ceil(s.window.Minutes())))}
+		s.trackers[tenantID] = t
+	}
+	t.objective = objective
+	t.record(now, good)
+	s.mu.Unlock()
+
+	result := "good"
+	if !good {
+		result = "bad"
+	}
+	s.events.WithLabelValues(metrics.Tenants.Label("salva_tenant_slo_events_total", tenantID), result).Inc()
+}
+
+// Forget drops the tenant's counts, e.g. after it was deleted
+func (s *SLOService) Forget(tenantID string) {
+	s.mu.Lock()
+	delete(s.trackers, tenantID)
+	delete(s.objectives, tenantID)
+	s.mu.Unlock()
+	s.events.DeletePartialMatch(prometheus.Labels{"tenant_id": tenantID})
+}
+
+// Status returns the tenant's compliance and burn rates over the SLO window
+func (s *SLOService) Status(tenantID string) (*domain.SLOStatus, error) {
+	objective, err := s.GetObjective(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.trackers[tenantID]
+	if !ok {
+		t = &sloTracker{buckets: make([]sloBucket, 1)}
+	}
+	t.objective = objective
+	return s.status(tenantID, t, time.Now()), nil
+}
+
+// status must be called with s.mu held
+func (s *SLOService) status(tenantID string, t *sloTracker, now time.Time) *domain.SLOStatus {
+	total, good := t.sum(now, s.window)
+	status := &domain.SLOStatus{
+		TenantID:             tenantID,
+		Objective:            t.objective,
+		WindowSeconds:        int(s.window.Seconds()),
+		Total:                total,
+		Good:                 good,
+		ErrorBudgetRemaining: 1,
+		BurnRate: map[string]float64{
+			sloWindowLabel(sloFastBurnWindow): burnRate(t, now, sloFastBurnWindow),
+			sloWindowLabel(s.window):          burnRate(t, now, s.window),
+		},
+	}
+	if total > 0 {
+		compliance := float64(good) / float64(total)
+		status.Compliance = &compliance
+		status.ErrorBudgetRemaining = 1 - burnRate(t, now, s.window)
+	}
+	return status
+}
+
+// burnRate is the error ratio over span divided by the ratio the SLO allows
+func burnRate(t *sloTracker, now time.Time, span time.Duration) float64 {
+	total, good := t.sum(now, span)
+	if total == 0 {
+		return 0
+	}
+	return (float64(total-good) / float64(total)) / (1 - t.objective.Target)
+}
+
+func sloWindowLabel(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// Describe implements prometheus.Collector
+func (s *SLOService) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sloTargetDesc
+	ch <- sloComplianceDesc
+	ch <- sloBurnRateDesc
+	s.events.Describe(ch)
+}
+
+// sloSums adds up the events of every tenant sharing a label value
+type sloSums struct {
+	total, bad int64
+	// allowed is the number of bad events the tenants' targets permit
+	allowed float64
+}
+
+func (a *sloSums) add(t *sloTracker, now time.Time, span time.Duration) {
+	total, good := t.sum(now, span)
+	a.total += total
+	a.bad += total - good
+	a.allowed += float64(total) * (1 - t.objective.Target)
+}
+
+// Collect implements prometheus.Collector. Tenants folded into one label value
+// by the tenant label policy are reported as one aggregate.
+func (s *SLOService) Collect(ch chan<- prometheus.Metric) {
+	s.events.Collect(ch)
+
+	type targetGroup struct {
+		target float64
+		mixed  bool
+	}
+	targets := make(map[string]*targetGroup)
+	compliance := make(map[string]*sloSums)
+	burn := make(map[[2]string]*sloSums)
+	windows := []time.Duration{sloFastBurnWindow, s.window}
+
+	now := time.Now()
+	s.mu.Lock()
+	for tenantID, t := range s.trackers {
+		label := metrics.Tenants.Label("salva_tenant_slo_target", tenantID)
+		if g, ok := targets[label]; !ok {
+			targets[label] = &targetGroup{target: t.objective.Target}
+		} else if g.target != t.objective.Target {
+			g.mixed = true
+		}
+
+		label = metrics.Tenants.Label("salva_tenant_slo_compliance_ratio", tenantID)
+		if compliance[label] == nil {
+			compliance[label] = &sloSums{}
+		}
+		compliance[label].add(t, now, s.window)
+
+		label = metrics.Tenants.Label("salva_tenant_slo_burn_rate", tenantID)
+		for _, window := range windows {
+			key := [2]string{label, sloWindowLabel(window)}
+			if burn[key] == nil {
+				burn[key] = &sloSums{}
+			}
+			burn[key].add(t, now, window)
+		}
+	}
+	s.mu.Unlock()
+
+	for label, g := range targets {
+		// Target campuran tidak punya satu nilai yang bermakna
+		if !g.mixed {
+			ch <- prometheus.MustNewConstMetric(sloTargetDesc, prometheus.GaugeValue, g.target, label)
+		}
+	}
+	for label, sums := range compliance {
+		if sums.total > 0 {
+			ch <- prometheus.MustNewConstMetric(sloComplianceDesc, prometheus.GaugeValue,
+				float64(sums.total-sums.bad)/float64(sums.total), label)
+		}
+	}
+	for key, sums := range burn {
+		rate := 0.0
+		if sums.allowed > 0 {
+			rate = float64(sums.bad) / sums.allowed
+		}
+		ch <- prometheus.MustNewConstMetric(sloBurnRateDesc, prometheus.GaugeValue, rate, key[0], key[1])
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"log/slog"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/metrics"
+)
+
+// GetStats returns the depths of the tenant's main queue and DLQ, its
+// workers here and what this instance consumed of it lately
+func (s *TenantService) GetStats(tenantID string) (domain.TenantStats, error) {
+	if err := s.tenantAvailable(tenantID); err != nil {
+		return domain.TenantStats{}, err
+	}
+
+	stats := domain.TenantStats{TenantID: tenantID}
+	if config, active := s.tenantManager.GetConfig(tenantID); active {
+		stats.Workers = config.Workers
+	}
+	for _, d := range s.deliveries.List(tenantID, 0) {
+		if d.State == domain.DeliveryStateProcessing {
+			stats.ActiveWorkers++
+		}
+	}
+
+	// Kedalaman queue hanya tersedia di RabbitMQ
+	if s.onRabbitMQ() == nil {
+		if q, err := s.queueStats(s.currentQueueName(tenantID)); err == nil {
+			depth := int64(q.Messages)
+			stats.QueueDepth = &depth
+			stats.Consumers = &q.Consumers
+		} else {
+			slog.Warn("Failed to inspect queue", "tenant_id", tenantID, "error", err)
+		}
+		if q, err := s.queueStats(dlqName(tenantID)); err == nil {
+			depth := int64(q.Messages)
+			stats.DLQDepth = &depth
+		} else {
+			slog.Warn("Failed to inspect DLQ", "tenant_id", tenantID, "error", err)
+		}
+	}
+
+	consumed := metrics.Consumption(tenantID)
+	stats.Consumed1m = consumed.LastMinute
+	stats.Consumed5m = consumed.Last5Minutes
+	stats.Consumed1h = consumed.LastHour
+	stats.AvgLatencyMs = float64(consumed.AvgLatency.Microseconds()) / 1000
+	if !consumed.LastConsumedAt.IsZero() {
+		stats.LastConsumedAt = &consumed.LastConsumedAt
+	}
+	return stats, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/events"
+)
+
+// suspensionSyncInterval bounds how long other instances keep consuming a
+// tenant after it was suspended, or wait to consume it after a resume
+const suspensionSyncInterval = 10 * time.Second
+
+// SuspendTenant stops consuming the tenant, here right away and on other
+// instances at their next SyncSuspensions, while its queue keeps taking
+// messages and its data is kept. In-flight messages are finished first.
+// Suspending a suspended tenant only updates the reason.
+func (s *TenantService) SuspendTenant(ctx context.Context, tenantID, reason string) (domain.TenantSuspension, error) {
+	if err := s.tenantAvailable(tenantID); err != nil {
+		return domain.TenantSuspension{}, err
+	}
+
+	suspension := domain.TenantSuspension{TenantID: tenantID, Suspended: true, Reason: reason}
+	var suspendedAt time.Time
+	var already bool
+	err := s.db.DB.QueryRowContext(ctx, `
+		UPDATE tenants SET suspended_at = COALESCE(suspended_at, NOW()), suspend_reason = $2
+		WHERE id = $1
+		RETURNING suspended_at, suspended_at < NOW()
+	`, tenantID, reason).Scan(&suspendedAt, &already)
+	if err != nil {
+		return domain.TenantSuspension{}, err
+	}
+	suspension.SuspendedAt = &suspendedAt
+
+	s.suspended.Store(tenantID, true)
+	if err := s.DrainTenant(ctx, tenantID); err != nil && !errors.Is(err, ErrTenantNotFound) {
+		slog.Error("Failed to drain suspended tenant", "tenant_id", tenantID, "error", err)
+	}
+	if !already {
+		slog.Info("Suspended tenant", "tenant_id", tenantID, "reason", reason)
+		s.events.Emit(events.TypeTenantSuspended, tenantID, map[string]interface{}{
+			"reason": reason,
+		})
+	}
+	return suspension, nil
+}
+
+// ResumeTenant consumes a suspended tenant again, starting with the messages
+// its queue took while suspended. Resuming a tenant that is not suspended
+// changes nothing.
+func (s *TenantService) ResumeTenant(ctx context.Context, tenantID string) (domain.TenantSuspension, error) {
+	if err := s.tenantAvailable(tenantID); err != nil {
+		return domain.TenantSuspension{}, err
+	}
+
+	result, err := s.db.DB.ExecContext(ctx,
+		"UPDATE tenants SET suspended_at = NULL, suspend_reason = '' WHERE id = $1 AND suspended_at IS NOT NULL", tenantID,
+	)
+	if err != nil {
+		return domain.TenantSuspension{}, err
+	}
+	resumed, _ := result.RowsAffected()
+
+	if _, parked := s.suspended.LoadAndDelete(tenantID); parked {
+		if err := s.AttachTenant(tenantID); err != nil {
+			return domain.TenantSuspension{}, err
+		}
+	}
+	if resumed > 0 {
+		slog.Info("Resumed tenant", "tenant_id", tenantID)
+		s.events.Emit(events.TypeTenantResumed, tenantID, nil)
+	}
+	return domain.TenantSuspension{TenantID: tenantID}, nil
+}
+
+// GetSuspension reports whether the tenant is suspended
+func (s *TenantService) GetSuspension(tenantID string) (domain.TenantSuspension, error) {
+	suspension := domain.TenantSuspension{TenantID: tenantID}
+	var suspendedAt sql.NullTime
+	err := s.db.DB.QueryRow(
+		"SELECT suspended_at, suspend_reason FROM tenants WHERE id = $1", tenantID,
+	).Scan(&suspendedAt, &suspension.Reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return suspension, ErrTenantNotFound
+	}
+	if err != nil {
+		return suspension, err
+	}
+	if suspendedAt.Valid {
+		suspension.Suspended = true
+		suspension.SuspendedAt = &suspendedAt.Time
+	}
+	return suspension, nil
+}
+
+// SyncSuspensions applies suspensions made on other instances: it drains the
+// suspended tenants consumed here and attaches the tenants it stopped for a
+// suspension once they are resumed
+func (s *TenantService) SyncSuspensions(ctx context.Context) error {
+	rows, err := s.db.DB.QueryContext(ctx, "SELECT id FROM tenants WHERE suspended_at IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+	suspended := make(map[string]bool, len(ids))
+	for _, tenantID := range ids {
+		suspended[tenantID] = true
+		if _, active := s.tenantManager.GetConfig(tenantID); !active {
+			continue
+		}
+		s.suspended.Store(tenantID, true)
+		if err := s.DrainTenant(ctx, tenantID); err != nil && !errors.Is(err, ErrTenantNotFound) {
+			slog.Error("Failed to drain suspended tenant", "tenant_id", tenantID, "error", err)
+		}
+	}
+
+	s.suspended.Range(func(key, _ any) bool {
+		tenantID := key.(string)
+		if suspended[tenantID] {
+			return true
+		}
+		s.suspended.Delete(tenantID)
+		// Tenant yang sudah dihapus tidak perlu dipasang lagi
+		if err := s.AttachTenant(tenantID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("Failed to attach resumed tenant", "tenant_id", tenantID, "error", err)
+			s.suspended.Store(tenantID, true)
+		}
+		return true
+	})
+	return nil
+}
+
+// RunSuspensionSync runs SyncSuspensions until ctx is done. Every instance
+// runs it, as every instance may consume the tenant.
+func (s *TenantService) RunSuspensionSync(ctx context.Context) {
+	ticker := time.NewTicker(suspensionSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.SyncSuspensions(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to sync tenant suspensions", "error", err)
+			}
+		}
+	}
+}
@@ -1,117 +1,538 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
+	"multi-tenant-messaging/internal/codec"
 	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/dynconfig"
+	"multi-tenant-messaging/internal/events"
+	"multi-tenant-messaging/internal/filter"
+	"multi-tenant-messaging/internal/journal"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/migrate"
 	"multi-tenant-messaging/internal/repository"
+	"multi-tenant-messaging/internal/transport"
 	"multi-tenant-messaging/internal/worker"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"multi-tenant-messaging/internal/logging"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrTenantNotFound is returned when a tenant is not active in this instance
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// ErrOrderedTenant is returned when a strictly-ordered tenant is given more than one worker
+var ErrOrderedTenant = errors.New("tenant is in ordered mode and must use exactly one worker")
+
+// ErrInvalidConcurrency is returned for workers outside the tenant's bounds or
+// the instance's budget, or a negative prefetch
+var ErrInvalidConcurrency = errors.New("invalid concurrency")
+
+// defaultPrefetchPerWorker bounds the unacked deliveries of a consumer
+// without a prefetch, so a busy tenant's channel cannot buffer its whole queue
+const defaultPrefetchPerWorker = 4
+
 type TenantService struct {
 	db            *repository.Database
 	rabbit        *repository.RabbitMQ
+	transport     transport.Transport
 	tenantManager *domain.TenantManager
+	deliveries    *DeliveryTracker
+	redactions    *RedactionService
+	dedup         *DedupService
+	rateLimits    *RateLimitService
+	claimChecks   *ClaimCheckResolver
+	codecs        *codec.Registry
+	schemas       *SchemaService
+	slos          *SLOService
+	processors    *ProcessorService
+	filters       *FilterService
+	dlqRetries    *DLQRetryService
+	outbox        *OutboxService
+	webhooks      *WebhookService
+	events        *events.Emitter
+	journal       *journal.Journal
+	runtime       *dynconfig.Store
+	migrations    *migrate.Runner
+	retries       *RetryService
+	quarantines   *QuarantineService
+	concurrency   *ConcurrencyService
+	quotas        *QuotaService
+	messageTTL    time.Duration
+	queueTemplate string
+	// partitionOnDelete is what happens to a deleted tenant's messages partition
+	partitionOnDelete string
+	// parking holds the tenants whose backlog this instance is parking for a recovery
+	parking sync.Map
+	// suspended holds the tenants this instance stopped consuming for a
+	// suspension, to attach again once they are resumed
+	suspended sync.Map
+	// stoppedSince holds when a tenant's consumer was first seen stopped
+	stoppedSince sync.Map
 }
 
-func NewTenantService(db *repository.Database, rabbit *repository.RabbitMQ, tm *domain.TenantManager) *TenantService {
+// TenantServiceDeps are the services and settings a TenantService is built
+// from. Codecs, Events, Journal, Runtime and Migrations may be left nil.
+type TenantServiceDeps struct {
+	DB            *repository.Database
+	RabbitMQ      *repository.RabbitMQ
+	Transport     transport.Transport
+	TenantManager *domain.TenantManager
+	Redactions    *RedactionService
+	Dedup         *DedupService
+	RateLimits    *RateLimitService
+	ClaimChecks   *ClaimCheckResolver
+	Codecs        *codec.Registry
+	Schemas       *SchemaService
+	SLOs          *SLOService
+	Processors    *ProcessorService
+	Filters       *FilterService
+	DLQRetries    *DLQRetryService
+	Outbox        *OutboxService
+	Webhooks      *WebhookService
+	Events        *events.Emitter
+	Journal       *journal.Journal
+	Runtime       *dynconfig.Store
+	Migrations    *migrate.Runner
+	Retries       *RetryService
+	Quarantines   *QuarantineService
+	Concurrency   *ConcurrencyService
+	Quotas        *QuotaService
+
+	// MessageTTL is the queue-level TTL of new tenant queues, 0 for none
+	MessageTTL time.Duration
+	// QueueTemplate names tenant queues, see DefaultQueueNameTemplate
+	QueueTemplate string
+	// PartitionOnDelete is what deleting a tenant does with its messages partition
+	PartitionOnDelete string
+}
+
+func NewTenantService(deps TenantServiceDeps) *TenantService {
 	return &TenantService{
-		db:            db,
-		rabbit:        rabbit,
-		tenantManager: tm,
+		db:            deps.DB,
+		rabbit:        deps.RabbitMQ,
+		transport:     deps.Transport,
+		tenantManager: deps.TenantManager,
+		deliveries:    NewDeliveryTracker(),
+		redactions:    deps.Redactions,
+		dedup:         deps.Dedup,
+		rateLimits:    deps.RateLimits,
+		claimChecks:   deps.ClaimChecks,
+		codecs:        deps.Codecs,
+		schemas:       deps.Schemas,
+		slos:          deps.SLOs,
+		processors:    deps.Processors,
+		filters:       deps.Filters,
+		dlqRetries:    deps.DLQRetries,
+		outbox:        deps.Outbox,
+		webhooks:      deps.Webhooks,
+		events:        deps.Events,
+		journal:       deps.Journal,
+		runtime:       deps.Runtime,
+		migrations:    deps.Migrations,
+		retries:       deps.Retries,
+		quarantines:   deps.Quarantines,
+		concurrency:   deps.Concurrency,
+		quotas:        deps.Quotas,
+		messageTTL:    deps.MessageTTL,
+		queueTemplate: deps.QueueTemplate,
+
+		partitionOnDelete: deps.PartitionOnDelete,
 	}
 }
 
-func (s *TenantService) CreateTenant(tenant *domain.Tenant) error {
-	// Create database partition
-	if err := s.createPartition(tenant.ID); err != nil {
-		return fmt.Errorf("failed to create partition: %w", err)
+func (s *TenantService) DeleteTenant(tenantID string) error {
+	queue := s.currentQueueName(tenantID)
+	s.forgetTenant(tenantID)
+
+	// Delete queues
+	s.deleteTenantQueues(tenantID, queue)
+	s.deleteRouteQueues(tenantID)
+	s.retries.Forget(tenantID)
+
+	// Runtime config di etcd/Consul tidak ikut terhapus oleh cascade
+	if err := s.runtime.DeleteTenant(context.Background(), tenantID); err != nil {
+		slog.Error("Failed to delete runtime config", "tenant_id", tenantID, "error", err)
 	}
 
-	// Create RabbitMQ queue
-	queueName := fmt.Sprintf("tenant_%s_queue", tenant.ID)
-	_, err := s.rabbit.Channel.QueueDeclare(
-		queueName,
-		true,  // durable
-		false, // autoDelete
-		false, // exclusive
-		false, // noWait
-		nil,   // args
-	)
+	// Baris tenant dan partisinya dihapus bersama
+	tx, err := s.db.DB.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
+		return err
 	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM tenants WHERE id = $1", tenantID); err != nil {
+		return err
+	}
+	if err := removePartition(tx, tenantID, s.partitionOnDelete); err != nil {
+		return fmt.Errorf("failed to remove partition: %w", err)
+	}
+	return tx.Commit()
+}
 
-	// Create worker pool
-	ctx, cancel := context.WithCancel(context.Background())
-	pool := worker.NewWorkerPool(3) // Default workers
+// forgetTenant stops consuming the tenant here and drops what this instance
+// keeps about it, once it is deleted
+func (s *TenantService) forgetTenant(tenantID string) {
+	s.tenantManager.RemoveTenant(tenantID)
+	s.deliveries.Forget(tenantID)
+	s.journal.Forget(tenantID)
+	s.slos.Forget(tenantID)
+	s.rateLimits.Forget(tenantID)
+	s.quotas.Forget(tenantID)
+	metrics.ForgetConsumption(tenantID)
+}
 
-	// Start consumer
-	go s.consumeMessages(ctx, pool, queueName, tenant.ID)
+// AuditValues returns the stored tenant values the audit log records before
+// and after an operation changes them, nil for a tenant that does not exist
+// or cannot be read
+func (s *TenantService) AuditValues(tenantID string) map[string]interface{} {
+	var name, reason string
+	var workers, prefetch int
+	var suspended bool
+	err := s.db.DB.QueryRow(`
+		SELECT t.name, COALESCE(c.workers, 3), COALESCE(c.prefetch_count, 0), t.suspended_at IS NOT NULL, t.suspend_reason
+		FROM tenants t
+		LEFT JOIN tenant_configs c ON c.tenant_id = t.id
+		WHERE t.id = $1
+	`, tenantID).Scan(&name, &workers, &prefetch, &suspended, &reason)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("Failed to read tenant for the audit log", "tenant_id", tenantID, "error", err)
+		}
+		return nil
+	}
+	return map[string]interface{}{
+		"name":           name,
+		"workers":        workers,
+		"prefetch_count": prefetch,
+		"suspended":      suspended,
+		"suspend_reason": reason,
+	}
+}
 
-	// Store in tenant manager
-	s.tenantManager.AddTenant(tenant.ID, &domain.TenantContext{
-		CancelFunc: cancel,
-		Config: domain.TenantConfig{
-			TenantID: tenant.ID,
-			Workers:  3,
-		},
-	})
+// UpdateConcurrency stores the tenant's worker count and prefetch, so they
+// survive a restart, and applies them to the consumer here. A prefetch of 0
+// uses defaultPrefetchPerWorker per worker. The worker pool is resized in
+// place so in-flight messages keep running; consumers that cannot be resized,
+// such as keyed lanes, are restarted. Workers must be within the tenant's
+// bounds and what the other tenants leave of the instance's worker budget.
+func (s *TenantService) UpdateConcurrency(tenantID string, workers, prefetch int) error {
+	if workers < 1 {
+		return fmt.Errorf("%w: workers must be at least 1", ErrInvalidConcurrency)
+	}
+	if prefetch < 0 {
+		return fmt.Errorf("%w: prefetch_count must not be negative", ErrInvalidConcurrency)
+	}
+	config, exists := s.tenantManager.GetConfig(tenantID)
+	if exists && config.Ordered && workers != 1 {
+		return ErrOrderedTenant
+	}
+	if err := s.concurrency.checkWorkers(s.tenantManager, tenantID, workers); err != nil {
+		return err
+	}
 
-	// Save tenant to database
-	_, err = s.db.DB.Exec(
-		"INSERT INTO tenants (id, name) VALUES ($1, $2)",
-		tenant.ID, tenant.Name,
-	)
-	return err
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, workers, prefetch_count) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET workers = EXCLUDED.workers, prefetch_count = EXCLUDED.prefetch_count
+	`, tenantID, workers, prefetch)
+	if err != nil {
+		return err
+	}
+
+	if !exists || (config.Workers == workers && config.Prefetch == prefetch) {
+		return nil
+	}
+	err = s.tenantManager.Resize(tenantID, workers, prefetch)
+	if err == nil {
+		slog.Info("Resized consumer", "tenant_id", tenantID, "workers", workers, "prefetch_count", prefetch)
+		s.events.Emit(events.TypeConsumerScaled, tenantID, map[string]interface{}{
+			"workers":        workers,
+			"prefetch_count": prefetch,
+			"previous":       config.Workers,
+		})
+		return nil
+	}
+	if !errors.Is(err, domain.ErrNotResizable) {
+		slog.Warn("Failed to resize consumer, restarting it", "tenant_id", tenantID, "error", err)
+	}
+	config.Workers = workers
+	config.Prefetch = prefetch
+	return s.restartConsumer(config, "concurrency")
 }
 
-func (s *TenantService) DeleteTenant(tenantID string) error {
-	s.tenantManager.RemoveTenant(tenantID)
+// ListDeliveries returns the tenant's unacked deliveries older than minAge
+func (s *TenantService) ListDeliveries(tenantID string, minAge time.Duration) ([]domain.DeliveryInfo, error) {
+	if _, exists := s.tenantManager.GetConfig(tenantID); !exists {
+		return nil, ErrTenantNotFound
+	}
+	return s.deliveries.List(tenantID, minAge), nil
+}
 
-	// Delete queue
-	queueName := fmt.Sprintf("tenant_%s_queue", tenantID)
-	_, err := s.rabbit.Channel.QueueDelete(
-		queueName,
-		false, // ifUnused
-		false, // ifEmpty
-		false, // noWait
-	)
+// SettleStuckDeliveries nacks the tenant's deliveries older than olderThan,
+// either requeueing or discarding them. When tags is not empty only those
+// delivery tags are considered. Deliveries a worker is processing are
+// skipped, since its result would otherwise settle them a second time,
+// unless force is set and the worker started them more than olderThan ago;
+// the worker's own ack or nack is then ignored, so a requeued message may
+// be processed twice. It returns the number of settled and skipped
+// deliveries.
+func (s *TenantService) SettleStuckDeliveries(tenantID string, olderThan time.Duration, tags []uint64, requeue, force bool) (settled, skipped int, err error) {
+	stuck, err := s.ListDeliveries(tenantID, olderThan)
 	if err != nil {
-		log.Printf("Failed to delete queue: %v", err)
+		return 0, 0, err
+	}
+	var startedBefore time.Time
+	if force {
+		startedBefore = time.Now().Add(-olderThan)
 	}
 
-	// Delete from database
-	_, err = s.db.DB.Exec("DELETE FROM tenants WHERE id = $1", tenantID)
-	return err
+	selected := make(map[uint64]bool, len(tags))
+	for _, tag := range tags {
+		selected[tag] = true
+	}
+
+	for _, d := range stuck {
+		if len(selected) > 0 && !selected[d.DeliveryTag] {
+			continue
+		}
+		ok, err := s.deliveries.Settle(tenantID, d.DeliveryTag, requeue, startedBefore)
+		if err != nil {
+			return settled, skipped, fmt.Errorf("failed to nack delivery %d: %w", d.DeliveryTag, err)
+		}
+		if !ok {
+			skipped++
+			continue
+		}
+		settled++
+	}
+	return settled, skipped, nil
 }
 
-func (s *TenantService) UpdateConcurrency(tenantID string, workers int) error {
-	s.tenantManager.UpdateConfig(tenantID, workers)
-	// Actual worker pool update would be handled in the consumer goroutine
-	return nil
+// queueName is the main queue name for the tenant under the current template
+// and its max priority
+func (s *TenantService) queueName(tenantID string) string {
+	return priorityQueueName(renderQueueName(s.queueTemplate, tenantID), s.maxPriority(tenantID))
+}
+
+// currentQueueName is the main queue the tenant is consumed from, which
+// differs from queueName until RenameQueue has moved the tenant
+func (s *TenantService) currentQueueName(tenantID string) string {
+	if config, exists := s.tenantManager.GetConfig(tenantID); exists {
+		return config.QueueName
+	}
+	return s.storedQueueName(tenantID)
+}
+
+// storedQueueName is the queue name recorded for the tenant
+func (s *TenantService) storedQueueName(tenantID string) string {
+	var queue string
+	s.db.DB.QueryRow("SELECT COALESCE(queue_name, '') FROM tenants WHERE id = $1", tenantID).Scan(&queue)
+	if queue == "" {
+		queue = renderQueueName(DefaultQueueNameTemplate, tenantID)
+	}
+	return queue
+}
+
+// schemaReady refuses to start new consumers while the schema is behind this
+// build, since their inserts would fail on missing tables or columns
+func (s *TenantService) schemaReady() error {
+	if s.migrations == nil {
+		return nil
+	}
+	return s.migrations.Ready()
 }
 
 func (s *TenantService) createPartition(tenantID string) error {
-	// Normalize tenantID by replacing hyphens with underscores
-	normalizedID := strings.ReplaceAll(tenantID, "-", "_")
-	partitionName := fmt.Sprintf("messages_tenant_%s", normalizedID)
+	return createPartition(s.db, tenantID)
+}
 
-	// Gunakan quoted identifier untuk nama tabel
-	_, err := s.db.DB.Exec(fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS "%s" PARTITION OF messages
-		FOR VALUES IN ('%s')
-	`, partitionName, tenantID))
+// startConsumer opens a dedicated channel for the tenant, starts consumers for
+// its main queue and each of its channels, the dead-letter router and the DLQ
+// retry scheduler on it and registers the tenant in the manager. During a
+// recovery the recovery queue is consumed too, and an oldest_first recovery
+// pauses the main queue until the backlog is cleared.
+func (s *TenantService) startConsumer(config domain.TenantConfig) error {
+	if s.onRabbitMQ() != nil {
+		return s.startTransportConsumer(config)
+	}
+	channels, err := s.ListChannels(config.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load channels: %w", err)
+	}
+	recovery, err := s.runningRecovery(config.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load recovery: %w", err)
+	}
 
-	return err
+	consumerConfigs := channelConfigs(channels)
+	// Indeks consumer main queue, -1 selama recovery oldest_first menahannya
+	mainConsumer := -1
+	if recovery == nil || recovery.Order != domain.RecoveryOldestFirst {
+		consumerConfigs = append([]domain.TenantConfig{config}, consumerConfigs...)
+		mainConsumer = 0
+	}
+	if recovery != nil {
+		consumerConfigs = append(consumerConfigs, recoveryConsumerConfig(config, recovery))
+	}
+
+	ch, err := s.rabbit.OpenChannel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	// Didaftarkan sebelum consume agar penutupan channel tidak terlewat
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	cancelled := ch.NotifyCancel(make(chan string, len(consumerConfigs)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := func() {
+		cancel()
+		// Menutup channel membatalkan consumer; delivery yang belum di-ack dikembalikan ke queue
+		ch.Close()
+	}
+
+	consumers := make([]queueConsumer, 0, len(consumerConfigs))
+	for _, consumerConfig := range consumerConfigs {
+		consumer, err := s.startQueueConsumer(ctx, ch, consumerConfig)
+		if err != nil {
+			stop()
+			if consumerConfig.Channel != "" {
+				return fmt.Errorf("channel %s: %w", consumerConfig.Channel, err)
+			}
+			return err
+		}
+		consumers = append(consumers, consumer)
+	}
+	go s.routeDeadLetters(ctx, ch, config.TenantID)
+	go s.watchConsumerChannel(ctx, closed, cancelled, config.TenantID)
+	go s.retryDeadLetters(ctx, ch, config.TenantID)
+	if recovery != nil {
+		go s.monitorRecovery(ctx, recovery)
+	}
+
+	// consumersMu melindungi consumers, yang berubah saat consumer main queue di-resize
+	var consumersMu sync.Mutex
+	var draining atomic.Bool
+	s.tenantManager.AddTenant(config.TenantID, &domain.TenantContext{
+		CancelFunc: stop,
+		Drain: func(drainCtx context.Context) error {
+			draining.Store(true)
+			consumersMu.Lock()
+			current := slices.Clone(consumers)
+			consumersMu.Unlock()
+			return s.drainConsumer(drainCtx, ch, current, config.TenantID)
+		},
+		Disconnected: func() bool { return ch.IsClosed() && ctx.Err() == nil },
+		Stopped: func() bool {
+			if ctx.Err() != nil || draining.Load() || ch.IsClosed() {
+				return false
+			}
+			consumersMu.Lock()
+			defer consumersMu.Unlock()
+			for _, consumer := range consumers {
+				select {
+				case <-consumer.consumed:
+					return true
+				default:
+				}
+			}
+			return false
+		},
+		Resize: func(workers, prefetch int) error {
+			if mainConsumer < 0 {
+				return domain.ErrNotResizable
+			}
+			consumersMu.Lock()
+			defer consumersMu.Unlock()
+			resized, err := s.resizeQueueConsumer(ctx, ch, consumers[mainConsumer], workers, prefetch)
+			consumers[mainConsumer] = resized
+			return err
+		},
+		Config: config,
+	})
+	return nil
+}
+
+func channelConfigs(channels []domain.Channel) []domain.TenantConfig {
+	configs := make([]domain.TenantConfig, len(channels))
+	for i, channel := range channels {
+		configs[i] = channel.ConsumerConfig()
+	}
+	return configs
+}
+
+// queueConsumer is one AMQP consumer of a tenant; consumed is closed once it stops
+type queueConsumer struct {
+	tag      string
+	consumed <-chan struct{}
+	config   domain.TenantConfig
+	pool     worker.Dispatcher
+}
+
+// consumerLimits returns the workers and prefetch a consumer runs with.
+// Ordered consumers get a single worker and prefetch 1 so messages are
+// processed strictly in queue order, including after a requeue. Without a
+// prefetch a consumer holds up to defaultPrefetchPerWorker per worker.
+func consumerLimits(config domain.TenantConfig) (workers, prefetch int) {
+	if config.Ordered {
+		return 1, 1
+	}
+	workers = config.Workers
+	prefetch = config.Prefetch
+	if prefetch == 0 {
+		prefetch = workers * defaultPrefetchPerWorker
+	}
+	return workers, prefetch
+}
+
+// startQueueConsumer consumes config.QueueName on ch with its own worker pool.
+// All consumers of a tenant share ch so delivery tags stay unique per tenant.
+func (s *TenantService) startQueueConsumer(ctx context.Context, ch *amqp.Channel, config domain.TenantConfig) (queueConsumer, error) {
+	workers, prefetch := consumerLimits(config)
+
+	// Create worker pool; consumers with a partition key get one lane per worker
+	var pool worker.Dispatcher
+	if config.PartitionKey != "" && !config.Ordered {
+		pool = worker.NewKeyedPool(workers)
+	} else {
+		pool = worker.NewWorkerPool(workers)
+	}
+	go pool.Run(ctx)
+
+	return s.consumeQueue(ctx, ch, config, pool, prefetch)
 }
 
-func (s *TenantService) consumeMessages(ctx context.Context, pool *worker.WorkerPool, queueName, tenantID string) {
-	msgs, err := s.rabbit.Channel.Consume(
-		queueName,
-		"",    // consumer
+// consumeQueue starts an AMQP consumer of config.QueueName on ch with
+// prefetch whose deliveries run on pool
+func (s *TenantService) consumeQueue(ctx context.Context, ch *amqp.Channel, config domain.TenantConfig, pool worker.Dispatcher, prefetch int) (queueConsumer, error) {
+	// Prefetch berlaku per consumer yang dibuat setelah Qos, jadi set ulang untuk tiap consumer
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		return queueConsumer{}, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	consumerTag := fmt.Sprintf("salva-%s-%s", config.TenantID, uuid.NewString())
+	if config.Channel != "" {
+		consumerTag = fmt.Sprintf("salva-%s-%s-%s", config.TenantID, config.Channel, uuid.NewString())
+	}
+	msgs, err := ch.Consume(
+		config.QueueName,
+		consumerTag,
 		false, // autoAck
 		false, // exclusive
 		false, // noLocal
@@ -119,11 +540,506 @@ func (s *TenantService) consumeMessages(ctx context.Context, pool *worker.Worker
 		nil,   // args
 	)
 	if err != nil {
-		log.Printf("Failed to consume messages: %v", err)
+		return queueConsumer{}, fmt.Errorf("failed to consume messages: %w", err)
+	}
+
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		s.consumeMessages(ctx, msgs, pool, config)
+	}()
+	return queueConsumer{tag: consumerTag, consumed: consumed, config: config, pool: pool}, nil
+}
+
+// resizeQueueConsumer changes the workers and prefetch of consumer without
+// stopping it. The pool grows or retires workers once their task is done.
+// As a prefetch only applies to consumers started after it is set, a new
+// prefetch starts a replacement consumer on the same pool before the old one
+// is cancelled; the old one's deliveries still run and are acked on ch.
+// Keyed pools cannot change their lanes without breaking key order, so they
+// return domain.ErrNotResizable.
+func (s *TenantService) resizeQueueConsumer(ctx context.Context, ch *amqp.Channel, consumer queueConsumer, workers, prefetch int) (queueConsumer, error) {
+	config := consumer.config
+	config.Workers = workers
+	config.Prefetch = prefetch
+	oldWorkers, oldPrefetch := consumerLimits(consumer.config)
+	newWorkers, newPrefetch := consumerLimits(config)
+	if newWorkers == oldWorkers && newPrefetch == oldPrefetch {
+		consumer.config = config
+		return consumer, nil
+	}
+	pool, ok := consumer.pool.(worker.Resizer)
+	if !ok {
+		return consumer, domain.ErrNotResizable
+	}
+
+	if newPrefetch != oldPrefetch {
+		next, err := s.consumeQueue(ctx, ch, config, consumer.pool, newPrefetch)
+		if err != nil {
+			return consumer, err
+		}
+		if err := ch.Cancel(consumer.tag, false); err != nil {
+			return next, fmt.Errorf("failed to cancel consumer: %w", err)
+		}
+		consumer = next
+	}
+	pool.SetSize(newWorkers)
+	consumer.config = config
+	return consumer, nil
+}
+
+// drainConsumer cancels the tenant's AMQP consumers so the broker stops
+// sending, lets the deliveries already received run to completion and waits
+// until all of them are acked or nacked
+func (s *TenantService) drainConsumer(ctx context.Context, ch *amqp.Channel, consumers []queueConsumer, tenantID string) error {
+	for _, consumer := range consumers {
+		if err := ch.Cancel(consumer.tag, false); err != nil {
+			return fmt.Errorf("failed to cancel consumer: %w", err)
+		}
+	}
+
+	for _, consumer := range consumers {
+		select {
+		case <-consumer.consumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for len(s.deliveries.List(tenantID, 0)) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// AttachTenant starts consuming an existing tenant with its stored
+// configuration. It is a no-op if the tenant is already active or has been
+// migrated to another deployment. A suspended tenant is attached by
+// SyncSuspensions once it is resumed.
+func (s *TenantService) AttachTenant(tenantID string) error {
+	if _, exists := s.tenantManager.GetConfig(tenantID); exists {
+		return nil
+	}
+
+	if err := s.schemaReady(); err != nil {
+		return err
+	}
+
+	// Tenant yang sudah dipindah ke deployment lain tidak dikonsumsi lagi di sini
+	var migrated, suspended bool
+	var queue string
+	if err := s.db.DB.QueryRow(
+		"SELECT migrated_to IS NOT NULL, suspended_at IS NOT NULL, COALESCE(queue_name, '') FROM tenants WHERE id = $1", tenantID,
+	).Scan(&migrated, &suspended, &queue); err != nil || migrated {
+		return err
+	}
+	if suspended {
+		s.suspended.Store(tenantID, true)
+		return nil
+	}
+	if queue == "" {
+		queue = renderQueueName(DefaultQueueNameTemplate, tenantID)
+	}
+
+	config := domain.TenantConfig{TenantID: tenantID, Workers: 3, QueueName: queue}
+	err := s.db.DB.QueryRow(`
+		SELECT workers, ordered, partition_key, prefetch_count FROM tenant_configs WHERE tenant_id = $1
+	`, tenantID).Scan(&config.Workers, &config.Ordered, &config.PartitionKey, &config.Prefetch)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if config.Ordered {
+		config.Workers = 1
+	}
+	if workers := s.concurrency.clampWorkers(tenantID, config.Workers); workers != config.Workers {
+		slog.Warn("Stored workers exceed the tenant's max, capping them", "tenant_id", tenantID, "workers", config.Workers, "max_workers", workers)
+		config.Workers = workers
+	}
+
+	if err := s.declareTenantQueues(tenantID, queue); err != nil {
+		return err
+	}
+	if err := s.startConsumer(config); err != nil {
+		return err
+	}
+	s.emitConsumerRestarted(config, "attached")
+	return nil
+}
+
+// RestoreTenants starts consuming every stored tenant that was not migrated
+// to another deployment, with its stored configuration, and returns how many
+// it attached. It runs at startup when consumer handover, which attaches the
+// tenants an instance owns itself, is disabled.
+func (s *TenantService) RestoreTenants(ctx context.Context) (int, error) {
+	rows, err := s.db.DB.QueryContext(ctx, "SELECT id FROM tenants WHERE migrated_to IS NULL ORDER BY created_at, id")
+	if err != nil {
+		return 0, err
+	}
+	tenantIDs, err := scanIDs(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for _, tenantID := range tenantIDs {
+		if err := ctx.Err(); err != nil {
+			return restored, err
+		}
+		if err := s.AttachTenant(tenantID); err != nil {
+			slog.Error("Failed to restore consumer", "tenant_id", tenantID, "error", err)
+			continue
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// DrainTenant stops consuming a tenant after its in-flight messages have been
+// processed. Whatever is still unacked when ctx expires goes back to the queue.
+func (s *TenantService) DrainTenant(ctx context.Context, tenantID string) error {
+	active, err := s.tenantManager.DrainTenant(ctx, tenantID)
+	s.deliveries.Forget(tenantID)
+	s.journal.Forget(tenantID)
+	if !active {
+		return ErrTenantNotFound
+	}
+	return err
+}
+
+// SetOrdered switches a tenant into or out of strictly-ordered mode and
+// restarts its consumer with the matching worker count and prefetch
+func (s *TenantService) SetOrdered(tenantID string, ordered bool) error {
+	config, exists := s.tenantManager.GetConfig(tenantID)
+	if !exists {
+		return ErrTenantNotFound
+	}
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, ordered) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET ordered = EXCLUDED.ordered
+	`, tenantID, ordered)
+	if err != nil {
+		return err
+	}
+
+	if config.Ordered == ordered {
+		return nil
+	}
+	config.Ordered = ordered
+	if ordered {
+		config.Workers = 1
+	}
+
+	return s.restartConsumer(config, "ordering")
+}
+
+// SetPartitionKey sets the payload field whose value decides the processing
+// lane of each message (empty disables keyed lanes) and restarts the consumer
+func (s *TenantService) SetPartitionKey(tenantID, field string) error {
+	config, exists := s.tenantManager.GetConfig(tenantID)
+	if !exists {
+		return ErrTenantNotFound
+	}
+
+	_, err := s.db.DB.Exec(`
+		INSERT INTO tenant_configs (tenant_id, partition_key) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET partition_key = EXCLUDED.partition_key
+	`, tenantID, field)
+	if err != nil {
+		return err
+	}
+
+	if config.PartitionKey == field {
+		return nil
+	}
+	config.PartitionKey = field
+
+	return s.restartConsumer(config, "partition_key")
+}
+
+// restartConsumer stops the tenant's consumer and starts it again with config
+func (s *TenantService) restartConsumer(config domain.TenantConfig, reason string) error {
+	s.tenantManager.RemoveTenant(config.TenantID)
+	s.deliveries.Forget(config.TenantID)
+	s.journal.Forget(config.TenantID)
+	if err := s.startConsumer(config); err != nil {
+		return err
+	}
+	s.emitConsumerRestarted(config, reason)
+	return nil
+}
+
+// storedConsumerConfig re-reads the tenant row before its consumer is
+// restarted, as another instance may have changed it since the consumer
+// started. It returns config with the stored main queue, or false once the
+// tenant is no longer consumed here: a deleted tenant is forgotten, a
+// migrated or suspended one is stopped.
+func (s *TenantService) storedConsumerConfig(config domain.TenantConfig) (domain.TenantConfig, bool, error) {
+	tenantID := config.TenantID
+	var migrated, suspended bool
+	var queue string
+	err := s.db.DB.QueryRow(
+		"SELECT migrated_to IS NOT NULL, suspended_at IS NOT NULL, COALESCE(queue_name, '') FROM tenants WHERE id = $1", tenantID,
+	).Scan(&migrated, &suspended, &queue)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		slog.Info("Tenant was deleted, not restarting its consumer", "tenant_id", tenantID)
+		s.forgetTenant(tenantID)
+		return config, false, nil
+	case err != nil:
+		return config, false, err
+	case migrated:
+		slog.Info("Tenant was migrated, not restarting its consumer", "tenant_id", tenantID)
+		s.tenantManager.RemoveTenant(tenantID)
+		return config, false, nil
+	case suspended:
+		slog.Info("Tenant is suspended, not restarting its consumer", "tenant_id", tenantID)
+		s.suspended.Store(tenantID, true)
+		s.tenantManager.RemoveTenant(tenantID)
+		return config, false, nil
+	}
+	if queue == "" {
+		queue = renderQueueName(DefaultQueueNameTemplate, tenantID)
+	}
+	config.QueueName = queue
+	return config, true, nil
+}
+
+// ReconnectConsumers restarts the consumers whose channel went down with a
+// dropped broker connection; tenants on the other pool connections keep
+// running. Like watchConsumerChannel it skips tenants that are no longer
+// consumed here; the main queues are declared again first in case they lived
+// on the node that went away.
+func (s *TenantService) ReconnectConsumers() {
+	for _, tenantID := range s.tenantManager.TenantIDs() {
+		if !s.tenantManager.Disconnected(tenantID) {
+			continue
+		}
+		config, ok := s.tenantManager.GetConfig(tenantID)
+		if !ok {
+			continue
+		}
+		stored, ok, err := s.storedConsumerConfig(config)
+		if err != nil {
+			// Tanpa database consumer tetap dipasang ulang dengan config yang diketahui
+			slog.Warn("Failed to load tenant, restarting with its last config", "tenant_id", tenantID, "error", err)
+		} else if !ok {
+			continue
+		} else {
+			config = stored
+		}
+		if err := s.declareConsumerQueues(config); err != nil {
+			slog.Error("Failed to declare queues after reconnecting", "tenant_id", tenantID, "error", err)
+		}
+		if err := s.restartConsumer(config, "broker_reconnect"); err != nil {
+			slog.Error("Failed to restart consumer after reconnecting", "tenant_id", tenantID, "error", err)
+		}
+	}
+}
+
+// watchConsumerChannel restarts the tenant's consumer when the broker closes
+// its channel with a channel-level error, or cancels one of its consumers
+// (e.g. because the queue was deleted), while the connection stays up. The
+// tenant row is read again first, so a tenant deleted, migrated or suspended
+// on another instance is dropped rather than restarted and a renamed queue is
+// followed. The queues are then declared again, retrying with exponential
+// backoff.
+// Channels lost with their connection are left to ReconnectConsumers, which
+// runs once the connection is replaced. Deliveries in flight on the old
+// channel are requeued by the broker; the journal keeps the processed ones,
+// so their redelivery is only acked.
+func (s *TenantService) watchConsumerChannel(ctx context.Context, closed <-chan *amqp.Error, cancelled <-chan string, tenantID string) {
+	var reason string
+	select {
+	case <-ctx.Done():
 		return
+	case amqpErr, ok := <-closed:
+		// Error koneksi (bukan soft error) ditangani ReconnectConsumers
+		if !ok || amqpErr == nil || !amqpErr.Recover {
+			return
+		}
+		slog.Warn("Broker closed the consumer channel", "tenant_id", tenantID, "error", amqpErr)
+		reason = "channel_closed"
+	case tag, ok := <-cancelled:
+		if !ok {
+			return
+		}
+		slog.Warn("Broker cancelled consumer", "tenant_id", tenantID, "consumer", tag)
+		reason = "consumer_cancelled"
 	}
 
-	go pool.Run(ctx)
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff + time.Duration(rand.Int63n(int64(backoff/2)))):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+
+		config, ok := s.tenantManager.GetConfig(tenantID)
+		if !ok {
+			return
+		}
+		// Instance lain bisa sudah menghapus, memindah, menangguhkan atau mengganti nama queue tenant
+		config, ok, err := s.storedConsumerConfig(config)
+		if err != nil {
+			slog.Warn("Failed to load tenant", "tenant_id", tenantID, "retry_in", backoff, "error", err)
+			continue
+		}
+		if !ok {
+			return
+		}
+		if err := s.declareConsumerQueues(config); err != nil {
+			slog.Warn("Failed to declare queues", "tenant_id", tenantID, "retry_in", backoff, "error", err)
+			continue
+		}
+		if err := s.restartConsumer(config, reason); err != nil {
+			slog.Error("Failed to restart consumer", "tenant_id", tenantID, "error", err)
+		}
+		return
+	}
+}
+
+// declareConsumerQueues declares the tenant's main queues and the queues of
+// its channels
+func (s *TenantService) declareConsumerQueues(config domain.TenantConfig) error {
+	if err := s.declareTenantQueues(config.TenantID, config.QueueName); err != nil {
+		return err
+	}
+	if s.onRabbitMQ() != nil {
+		// Channel hanya ada di RabbitMQ
+		return nil
+	}
+	channels, err := s.ListChannels(config.TenantID)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if err := declareTenantQueue(s.rabbit, config.TenantID, channel.QueueName, s.messageTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StoppedConsumers returns the tenants consumed here and those whose
+// consumer no longer takes messages and was not restarted yet, because it
+// lost its broker connection or its consume loop ended
+func (s *TenantService) StoppedConsumers() (tenants []string, stopped []string) {
+	tenants = s.tenantManager.TenantIDs()
+	now := time.Now()
+	for _, tenantID := range tenants {
+		// Consumer yang koneksinya putus disambung ulang ReconnectConsumers, jadi tidak dihitung macet
+		if s.tenantManager.Stopped(tenantID) {
+			s.stoppedSince.LoadOrStore(tenantID, now)
+		} else {
+			s.stoppedSince.Delete(tenantID)
+		}
+		if s.tenantManager.Disconnected(tenantID) || s.tenantManager.Stopped(tenantID) {
+			stopped = append(stopped, tenantID)
+		}
+	}
+	s.stoppedSince.Range(func(key, _ any) bool {
+		if !slices.Contains(tenants, key.(string)) {
+			s.stoppedSince.Delete(key)
+		}
+		return true
+	})
+	return tenants, stopped
+}
+
+// StalledConsumers returns the tenants whose consume loop ended more than
+// timeout ago, as seen by calls of StoppedConsumers, while the broker
+// connection stayed up. Restarting the process is the fix for those.
+func (s *TenantService) StalledConsumers(timeout time.Duration) []string {
+	s.StoppedConsumers()
+	var stalled []string
+	s.stoppedSince.Range(func(key, since any) bool {
+		if time.Since(since.(time.Time)) > timeout {
+			stalled = append(stalled, key.(string))
+		}
+		return true
+	})
+	return stalled
+}
+
+// RestartConsumers drains every tenant consumed here and starts it again,
+// which moves it onto the broker connections that replaced the old ones
+func (s *TenantService) RestartConsumers(ctx context.Context, reason string) {
+	for _, tenantID := range s.tenantManager.TenantIDs() {
+		config, ok := s.tenantManager.GetConfig(tenantID)
+		if !ok {
+			continue
+		}
+		if _, err := s.tenantManager.DrainTenant(ctx, tenantID); err != nil {
+			slog.Error("Failed to drain consumer", "tenant_id", tenantID, "error", err)
+		}
+		if err := s.restartConsumer(config, reason); err != nil {
+			slog.Error("Failed to restart consumer", "tenant_id", tenantID, "error", err)
+		}
+	}
+}
+
+// DrainConsumers stops every tenant consumer here and waits, until ctx is
+// done, for the messages in flight to be processed and their deliveries
+// settled. Tenants are drained in parallel; deliveries still unsettled when
+// ctx ends go back to their queues.
+func (s *TenantService) DrainConsumers(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, tenantID := range s.tenantManager.TenantIDs() {
+		wg.Add(1)
+		go func(tenantID string) {
+			defer wg.Done()
+			if err := s.DrainTenant(ctx, tenantID); err != nil && !errors.Is(err, ErrTenantNotFound) {
+				slog.Error("Failed to drain consumer", "tenant_id", tenantID, "error", err)
+			}
+		}(tenantID)
+	}
+	wg.Wait()
+}
+
+// emitConsumerRestarted records that the tenant's consumer (re)started on this instance
+func (s *TenantService) emitConsumerRestarted(config domain.TenantConfig, reason string) {
+	s.events.Emit(events.TypeConsumerRestarted, config.TenantID, map[string]interface{}{
+		"reason":  reason,
+		"queue":   config.QueueName,
+		"workers": config.Workers,
+	})
+}
+
+// partitionKeyValue extracts the dotted field path from a JSON body.
+// Bodies without the field all map to the same (empty) key.
+func partitionKeyValue(body []byte, field string) string {
+	var node interface{}
+	if err := json.Unmarshal(body, &node); err != nil {
+		return ""
+	}
+	for _, segment := range strings.Split(field, ".") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		node = object[segment]
+	}
+	if node == nil {
+		return ""
+	}
+	if str, ok := node.(string); ok {
+		return str
+	}
+	encoded, _ := json.Marshal(node)
+	return string(encoded)
+}
+
+func (s *TenantService) consumeMessages(ctx context.Context, msgs <-chan amqp.Delivery, pool worker.Dispatcher, config domain.TenantConfig) {
+	tenantID := config.TenantID
 
 	for {
 		select {
@@ -133,22 +1049,317 @@ func (s *TenantService) consumeMessages(ctx context.Context, pool *worker.Worker
 			if !ok {
 				return
 			}
-			pool.Submit(func() {
-				if err := s.processMessage(tenantID, d.Body); err != nil {
-					log.Printf("Failed to process message: %v", err)
-					d.Nack(false, true) // Requeue
+			if d.Redelivered && s.journal.Recovered(tenantID, d.MessageId) {
+				// Sudah tersimpan sebelum crash tetapi belum sempat di-ack
+				slog.Info("Acking message processed before the last stop", "tenant_id", tenantID, "message_id", d.MessageId)
+				d.Ack(false)
+				continue
+			}
+			// Jangan ambil pesan baru selama primary database berpindah
+			if err := s.db.WaitWritable(ctx); err != nil {
+				return
+			}
+			// Sisa pesan di atas prefetch tetap menunggu di queue selama tenant dibatasi
+			if err := s.rateLimits.WaitConsume(ctx, tenantID); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Warn("Failed to load rate limit", "tenant_id", tenantID, "error", err)
+			}
+			// Tenant yang penuh kuotanya berhenti mengambil pesan, bukan membuangnya
+			if err := s.waitIntake(ctx, tenantID); err != nil {
+				return
+			}
+			receivedAt := time.Now()
+			s.deliveries.Track(tenantID, d)
+			seq := s.journal.Begin(tenantID, d.MessageId, d.DeliveryTag)
+			// Latensi SLO dihitung dari waktu publish, atau waktu diterima jika publisher tidak mengisi timestamp
+			publishedAt := d.Timestamp
+			if publishedAt.IsZero() {
+				publishedAt = time.Now()
+			}
+			key := ""
+			if config.PartitionKey != "" {
+				key = partitionKeyValue(d.Body, config.PartitionKey)
+			}
+			pool.Dispatch(key, d.Priority, func(workerID int) {
+				// Lewati delivery yang sudah di-nack lewat admin API
+				if !s.deliveries.Start(tenantID, d.DeliveryTag, workerID) {
+					s.journal.Done(seq)
+					return
+				}
+				defer s.journal.Done(seq)
+				traceparent, _ := d.Headers[metrics.TraceparentHeader].(string)
+				msgCtx := metrics.WithTraceID(metrics.ExtractHeaders(context.Background(), d.Headers), metrics.ParseTraceparent(traceparent))
+				msgCtx, span := metrics.Tracer().Start(msgCtx, "process "+config.QueueName,
+					trace.WithSpanKind(trace.SpanKindConsumer),
+					trace.WithAttributes(
+						attribute.String("messaging.system", "rabbitmq"),
+						attribute.String("messaging.destination.name", config.QueueName),
+						attribute.String("messaging.message.id", d.MessageId),
+						attribute.String("tenant_id", tenantID),
+					))
+				msgCtx = logging.With(msgCtx, "tenant_id", tenantID, "message_id", d.MessageId)
+				err := metrics.ObserveStage(msgCtx, "process", func() error {
+					return s.processSafely(msgCtx, tenantID, config.Channel, d)
+				})
+				// Saat failover, tunggu primary baru lalu ulangi daripada nack
+				for err != nil && s.db.FailedOver(err) && s.db.WaitWritable(ctx) == nil {
+					metrics.ObserveMessageRetry(msgCtx, tenantID, "failover")
+					err = metrics.ObserveStage(msgCtx, "process", func() error {
+						return s.processSafely(msgCtx, tenantID, config.Channel, d)
+					})
+				}
+				metrics.EndSpan(span, err)
+				metrics.Tenants.Observe(tenantID)
+				s.slos.Record(tenantID, publishedAt, err)
+				if err != nil {
+					slog.ErrorContext(msgCtx, "Failed to process message", "error", err)
+					// Payload yang tidak sesuai schema, tidak bisa di-decode, ditolak processor atau membuat filter melewati batas tidak akan berhasil jika diulang, kirim ke DLQ
+					// Pesan yang berulang kali panic atau tidak valid dikarantina agar tidak berputar terus
+					if s.quarantine(msgCtx, tenantID, config.Channel, d, err) {
+						s.deliveries.Ack(tenantID, d.DeliveryTag)
+						return
+					}
+					reason := permanentFailureReason(err)
+					if reason == "" && config.Ordered {
+						// Tenant ordered diulang di tempat agar urutan pesan tetap terjaga
+						metrics.ObserveMessageRetry(msgCtx, tenantID, "requeue")
+						s.deliveries.Nack(tenantID, d.DeliveryTag, true)
+						return
+					}
+					if reason == "" {
+						retried, retryErr := s.retryLater(tenantID, config.Channel, config.QueueName, d)
+						switch {
+						case retryErr != nil:
+							slog.ErrorContext(msgCtx, "Failed to schedule retry, requeueing", "error", retryErr)
+							metrics.ObserveMessageRetry(msgCtx, tenantID, "requeue")
+							s.deliveries.Nack(tenantID, d.DeliveryTag, true)
+							return
+						case retried:
+							metrics.ObserveMessageRetry(msgCtx, tenantID, "backoff")
+							s.deliveries.Ack(tenantID, d.DeliveryTag)
+							return
+						}
+						reason = domain.ErrorReasonMaxAttempts
+					}
+					metrics.ObserveMessageDeadLettered(msgCtx, tenantID, reason)
+					s.reportError(tenantID, config.Channel, d, reason, err)
+					s.deliveries.Nack(tenantID, d.DeliveryTag, false)
 				} else {
-					d.Ack(false)
+					metrics.ObserveMessageProcessed(msgCtx, tenantID, receivedAt)
+					s.journal.Processed(seq)
+					s.deliveries.Ack(tenantID, d.DeliveryTag)
 				}
 			})
 		}
 	}
 }
 
-func (s *TenantService) processMessage(tenantID string, body []byte) error {
-	_, err := s.db.DB.Exec(`
-		INSERT INTO messages (id, tenant_id, payload) 
-		VALUES (gen_random_uuid(), $1, $2)
-	`, tenantID, body)
-	return err
+func (s *TenantService) processMessage(ctx context.Context, tenantID, channel, messageID, messageType, contentType string, headers amqp.Table, body []byte) error {
+	var duplicate bool
+	var dedupKey string
+	err := metrics.ObserveStage(ctx, "dedup", func() (err error) {
+		dedupKey, err = s.dedup.Key(tenantID, messageID, body)
+		if err != nil {
+			return err
+		}
+		if marker, ok := headers[outboxHeader].(string); ok && dedupKey == "" {
+			dedupKey = outboxKeyPrefix + marker
+		}
+		duplicate, err = s.dedup.IsRecentDuplicate(tenantID, dedupKey)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check dedup window: %w", err)
+	}
+	if duplicate {
+		slog.InfoContext(ctx, "Dropping duplicate message")
+		return nil
+	}
+
+	err = metrics.ObserveStage(ctx, "claim_check", func() (err error) {
+		body, err = s.claimChecks.Resolve(ctx, tenantID, contentType, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Protobuf dan Avro diproses sebagai proyeksi JSON; byte aslinya ikut disimpan
+	var decoded codec.Decoded
+	err = metrics.ObserveStage(ctx, "decode", func() (err error) {
+		decoded, err = s.codecs.Decode(ctx, contentType, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	original := body
+	body = decoded.JSON
+
+	var schemaVersion int
+	err = metrics.ObserveStage(ctx, "validate", func() (err error) {
+		schemaVersion, err = s.schemas.Validate(tenantID, messageType, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var decision filter.Decision
+	err = metrics.ObserveStage(ctx, "filters", func() (err error) {
+		decision, err = s.filters.Evaluate(ctx, tenantID, messageID, messageType, headers, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if decision.Drop {
+		slog.InfoContext(ctx, "Message dropped by filter", "filter", decision.Matched[len(decision.Matched)-1])
+		return nil
+	}
+	if decision.Route != "" {
+		return s.routeFiltered(tenantID, decision.Route, messageID, messageType, contentType, headers, decision.Tags, original)
+	}
+
+	var drop bool
+	err = metrics.ObserveStage(ctx, "processors", func() (err error) {
+		body, drop, err = s.processors.Run(ctx, tenantID, messageID, messageType, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if drop {
+		slog.InfoContext(ctx, "Message dropped by processor")
+		return nil
+	}
+
+	return metrics.ObserveStage(ctx, "store", func() error {
+		return s.storeMessage(ctx, tenantID, channel, messageID, dedupKey, messageType, schemaVersion, decision.Tags, body, decoded, deliverAtOf(headers), domain.MessageStatusProcessed)
+	})
+}
+
+// routeFiltered publishes a message matching a route filter to the tenant's
+// route queue for target instead of storing it
+func (s *TenantService) routeFiltered(tenantID, target, messageID, messageType, contentType string, headers amqp.Table, tags []string, body []byte) error {
+	queue := filterRouteQueueName(tenantID, target)
+	if err := s.transport.Declare(context.Background(), queue); err != nil {
+		return fmt.Errorf("failed to declare route queue %s: %w", queue, err)
+	}
+
+	routed := amqp.Table{}
+	for key, value := range headers {
+		routed[key] = value
+	}
+	if len(tags) > 0 {
+		tagValues := make([]interface{}, len(tags))
+		for i, tag := range tags {
+			tagValues[i] = tag
+		}
+		routed["x-salva-tags"] = tagValues
+	}
+	return s.transport.Publish(context.Background(), queue, transport.Message{
+		ID:          messageID,
+		Type:        messageType,
+		ContentType: contentType,
+		Headers:     routed,
+		Timestamp:   time.Now(),
+		Body:        body,
+	})
+}
+
+// deliverAtOf returns when a scheduled message was due, from its deliver-at
+// header, or nil for a message that was not delayed
+func deliverAtOf(headers amqp.Table) *time.Time {
+	value, _ := headers[deliverAtHeader].(string)
+	deliverAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	return &deliverAt
+}
+
+// storeMessage redacts and inserts a message, honoring the tenant's dedup window
+// for dedupKey (see DedupService.Key). The message is stored under messageID,
+// the ID returned by the publish API, when it is a UUID, so GET /messages/{id}
+// finds it; a redelivered message already stored under it is dropped. Other
+// messages get a new ID. schemaVersion 0 means the payload was not validated.
+// original carries the raw bytes of a protobuf or Avro payload. deliverAt is
+// when a scheduled message was due.
+func (s *TenantService) storeMessage(ctx context.Context, tenantID, channel, messageID, dedupKey, messageType string, schemaVersion int, tags []string, body []byte, original codec.Decoded, deliverAt *time.Time, status string) (err error) {
+	ctx, span := metrics.Tracer().Start(ctx, "insert messages",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("tenant_id", tenantID),
+		))
+	defer func() { metrics.EndSpan(span, err) }()
+
+	redacted, err := s.redactions.Redact(tenantID, body)
+	if err != nil {
+		return fmt.Errorf("failed to redact payload: %w", err)
+	}
+	if original.Raw != nil && !bytes.Equal(redacted, body) {
+		// Byte asli masih berisi field yang diredaksi, jangan disimpan
+		original.Raw = nil
+	}
+	body = redacted
+
+	if parsed, err := uuid.Parse(messageID); err == nil {
+		messageID = parsed.String()
+	} else {
+		messageID = uuid.New().String()
+	}
+
+	duplicate := false
+	err = s.db.WithTenantTx(ctx, tenantID, func(q repository.Querier) error {
+		claimed, err := s.dedup.Claim(ctx, q, tenantID, dedupKey)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			duplicate = true
+			return nil
+		}
+
+		var version interface{}
+		if schemaVersion > 0 {
+			version = schemaVersion
+		}
+		err = q.QueryRowContext(ctx, `
+			INSERT INTO messages (id, tenant_id, payload, status, message_type, schema_version, tags, channel, content_type, raw_payload, deliver_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (id, tenant_id) DO NOTHING
+			RETURNING id
+		`, messageID, tenantID, body, status, messageType, version, domain.Tags(tags), channel, original.ContentType, original.Raw, deliverAt).Scan(&messageID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Pesan dengan ID ini sudah tersimpan, mis. dikirim ulang setelah ack hilang
+			duplicate = true
+			return nil
+		}
+		if err != nil || status != domain.MessageStatusProcessed {
+			return err
+		}
+		// Pengiriman webhook ikut transaksi, jadi hanya terjadi bila pesan tersimpan
+		return s.webhooks.Enqueue(ctx, q, tenantID, messageID, messageType, body)
+	})
+	if err != nil {
+		metrics.ObserveMessageInsertError(ctx, tenantID)
+		return err
+	}
+
+	if duplicate {
+		slog.InfoContext(ctx, "Dropping duplicate message")
+	} else if s.events.Watched(tenantID) {
+		s.events.Notify(events.TypeMessageStored, tenantID, map[string]interface{}{
+			"message_id":   messageID,
+			"message_type": messageType,
+			"channel":      channel,
+			"status":       status,
+		})
+	}
+	s.dedup.Remember(tenantID, dedupKey)
+	return nil
 }
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"multi-tenant-messaging/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTenantList is returned for an invalid tenant list cursor or limit
+var ErrInvalidTenantList = errors.New("invalid tenant list request")
+
+// Tenant list page sizes
+const (
+	defaultTenantListLimit = 20
+	maxTenantListLimit     = 100
+)
+
+// ListTenants returns a page of tenants ordered by name, after the tenant
+// with ID cursor. name filters on a case-insensitive substring of the tenant
+// name and onlyTenant, when set, restricts the list to that tenant. The
+// returned cursor is empty on the last page.
+func (s *TenantService) ListTenants(ctx context.Context, name, cursor, onlyTenant string, limit int) ([]domain.TenantSummary, string, error) {
+	if limit == 0 {
+		limit = defaultTenantListLimit
+	}
+	if limit < 0 || limit > maxTenantListLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidTenantList, maxTenantListLimit)
+	}
+	if cursor != "" {
+		if _, err := uuid.Parse(cursor); err != nil {
+			return nil, "", fmt.Errorf("%w: invalid cursor", ErrInvalidTenantList)
+		}
+	}
+
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	if name != "" {
+		// Karakter wildcard LIKE di-escape agar dicari apa adanya
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(name)
+		args = append(args, "%"+escaped+"%")
+		conditions = append(conditions, fmt.Sprintf("t.name ILIKE $%d", len(args)))
+	}
+	if onlyTenant != "" {
+		args = append(args, onlyTenant)
+		conditions = append(conditions, fmt.Sprintf("t.id = $%d", len(args)))
+	}
+	if cursor != "" {
+		args = append(args, cursor)
+		conditions = append(conditions, fmt.Sprintf("(t.name, t.id) > (SELECT name, id FROM tenants WHERE id = $%d)", len(args)))
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.DB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT t.id, t.name, COALESCE(t.queue_name, ''), t.migrated_to IS NOT NULL, COALESCE(c.workers, 3), t.suspended_at, t.created_at
+		FROM tenants t
+		LEFT JOIN tenant_configs c ON c.tenant_id = t.id
+		WHERE %s
+		ORDER BY t.name, t.id
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	tenants := make([]domain.TenantSummary, 0)
+	for rows.Next() {
+		var t domain.TenantSummary
+		if err := rows.Scan(&t.ID, &t.Name, &t.QueueName, &t.Migrated, &t.Workers, &t.SuspendedAt, &t.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		if t.QueueName == "" {
+			t.QueueName = renderQueueName(DefaultQueueNameTemplate, t.ID)
+		}
+		tenants = append(tenants, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	for i := range tenants {
+		t := &tenants[i]
+		config, active := s.tenantManager.GetConfig(t.ID)
+		if active {
+			t.QueueName = config.QueueName
+			t.Workers = config.Workers
+		}
+		if s.onRabbitMQ() != nil {
+			// Transport lain tidak memberi kedalaman queue, status diambil dari consumer
+			t.Status = domain.TenantStatusIdle
+			if active {
+				t.Status = domain.TenantStatusActive
+			}
+			continue
+		}
+		q, err := s.queueStats(t.QueueName)
+		if err != nil {
+			slog.Warn("Failed to inspect queue", "tenant_id", t.ID, "queue", t.QueueName, "error", err)
+			t.Status = domain.TenantStatusUnknown
+			continue
+		}
+		depth := int64(q.Messages)
+		t.QueueDepth = &depth
+		t.Status = domain.TenantStatusIdle
+		if q.Consumers > 0 {
+			t.Status = domain.TenantStatusActive
+		}
+	}
+	for i := range tenants {
+		// Status dari DB, karena consumer bisa saja belum berhenti di instance lain
+		if tenants[i].SuspendedAt != nil {
+			tenants[i].Status = domain.TenantStatusSuspended
+		}
+	}
+
+	next := ""
+	if len(tenants) == limit {
+		next = tenants[len(tenants)-1].ID
+	}
+	return tenants, next, nil
+}
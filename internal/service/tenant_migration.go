@@ -0,0 +1,731 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/config"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrTenantMigrationNotFound is returned when a tenant migration does not exist
+var ErrTenantMigrationNotFound = errors.New("tenant migration not found")
+
+// ErrUnknownMigrationTarget is returned for a target not listed in tenant_migration.targets
+var ErrUnknownMigrationTarget = errors.New("unknown migration target")
+
+// finalSyncRewind is how far before the data checkpoint the final sync starts
+// again, to pick up rows that committed out of created_at order
+const finalSyncRewind = 5 * time.Minute
+
+// TenantMigrationService moves tenants to other deployments. Each migration
+// is a state machine persisted in tenant_migrations so it can be resumed
+// after a failure or restart:
+//
+//	config     copy the tenant, its settings and partition, declare its queues
+//	data       copy stored messages in checkpointed chunks
+//	cutover    mark the tenant as migrated and stop consuming it here
+//	shovel     move messages still queued here to the target broker
+//	final_sync copy messages stored since the data step and consumer group offsets
+//	verify     compare message counts on both sides
+type TenantMigrationService struct {
+	db        *repository.Database
+	rabbit    *repository.RabbitMQ
+	tenants   *TenantService
+	targets   map[string]config.MigrationTarget
+	chunkSize int
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func NewTenantMigrationService(db *repository.Database, rabbit *repository.RabbitMQ, tenants *TenantService, targets map[string]config.MigrationTarget, chunkSize int) *TenantMigrationService {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TenantMigrationService{
+		db:        db,
+		rabbit:    rabbit,
+		tenants:   tenants,
+		targets:   targets,
+		chunkSize: chunkSize,
+		ctx:       ctx,
+		cancel:    cancel,
+		running:   make(map[string]bool),
+	}
+}
+
+// CreateMigration starts moving a tenant to target in the background. An
+// unfinished migration of the same tenant to the same target is resumed
+// instead of starting over.
+func (s *TenantMigrationService) CreateMigration(tenantID, target string) (*domain.TenantMigration, error) {
+	id, err := s.prepare(tenantID, target)
+	if err != nil {
+		return nil, err
+	}
+	s.start(id)
+	return s.GetMigration(id)
+}
+
+// MigrateTenant runs a migration to completion in the calling goroutine, for the CLI
+func (s *TenantMigrationService) MigrateTenant(ctx context.Context, tenantID, target string) (*domain.TenantMigration, error) {
+	id, err := s.prepare(tenantID, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.run(ctx, id); err != nil {
+		s.fail(id, err)
+	}
+	return s.GetMigration(id)
+}
+
+func (s *TenantMigrationService) prepare(tenantID, target string) (string, error) {
+	if _, ok := s.targets[target]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMigrationTarget, target)
+	}
+	if err := s.tenants.onRabbitMQ(); err != nil {
+		return "", err
+	}
+
+	var exists bool
+	if err := s.db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)", tenantID).Scan(&exists); err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", ErrTenantNotFound
+	}
+
+	var id string
+	err := s.db.DB.QueryRow(`
+		SELECT id FROM tenant_migrations
+		WHERE tenant_id = $1 AND target = $2 AND status <> $3
+		ORDER BY created_at DESC LIMIT 1
+	`, tenantID, target, domain.TenantMigrationStatusCompleted).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	id = uuid.New().String()
+	_, err = s.db.DB.Exec(`
+		INSERT INTO tenant_migrations (id, tenant_id, target, step, status) VALUES ($1, $2, $3, $4, $5)
+	`, id, tenantID, target, domain.TenantMigrationStepConfig, domain.TenantMigrationStatusRunning)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tenant migration: %w", err)
+	}
+	return id, nil
+}
+
+// GetMigration returns the current state of a tenant migration
+func (s *TenantMigrationService) GetMigration(id string) (*domain.TenantMigration, error) {
+	var m domain.TenantMigration
+	var sourceCount, targetCount sql.NullInt64
+	var errMsg sql.NullString
+	err := s.db.DB.QueryRow(`
+		SELECT id, tenant_id, target, step, status, copied_rows, shoveled_messages,
+			source_count, target_count, error, created_at, updated_at
+		FROM tenant_migrations WHERE id = $1
+	`, id).Scan(&m.ID, &m.TenantID, &m.Target, &m.Step, &m.Status, &m.CopiedRows, &m.ShoveledMessages,
+		&sourceCount, &targetCount, &errMsg, &m.CreatedAt, &m.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTenantMigrationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceCount.Valid {
+		m.SourceCount = &sourceCount.Int64
+	}
+	if targetCount.Valid {
+		m.TargetCount = &targetCount.Int64
+	}
+	m.Error = errMsg.String
+	return &m, nil
+}
+
+// ResumeMigration restarts a failed or interrupted migration at its current step
+func (s *TenantMigrationService) ResumeMigration(id string) (*domain.TenantMigration, error) {
+	m, err := s.GetMigration(id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == domain.TenantMigrationStatusCompleted {
+		return m, nil
+	}
+
+	s.start(id)
+	return s.GetMigration(id)
+}
+
+// ResumeInterrupted restarts every migration left running by a previous process
+func (s *TenantMigrationService) ResumeInterrupted() error {
+	rows, err := s.db.DB.Query("SELECT id FROM tenant_migrations WHERE status = $1", domain.TenantMigrationStatusRunning)
+	if err != nil {
+		return err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		slog.Info("Resuming tenant migration", "migration_id", id)
+		s.start(id)
+	}
+	return nil
+}
+
+// Shutdown stops running migrations at their next checkpoint and waits for them
+func (s *TenantMigrationService) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *TenantMigrationService) start(id string) {
+	s.mu.Lock()
+	if s.running[id] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[id] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, id)
+			s.mu.Unlock()
+		}()
+
+		if err := s.run(s.ctx, id); err != nil {
+			if s.ctx.Err() != nil {
+				// Dihentikan saat shutdown, dilanjutkan dari step terakhir
+				slog.Warn("Tenant migration interrupted", "migration_id", id, "error", err)
+				return
+			}
+			s.fail(id, err)
+		}
+	}()
+}
+
+func (s *TenantMigrationService) fail(id string, err error) {
+	slog.Error("Tenant migration failed", "migration_id", id, "error", err)
+	s.db.DB.Exec(
+		"UPDATE tenant_migrations SET status = $2, error = $3, updated_at = NOW() WHERE id = $1",
+		id, domain.TenantMigrationStatusFailed, err.Error(),
+	)
+}
+
+// migrationTarget holds open connections to the target deployment
+type migrationTarget struct {
+	name   string
+	db     *repository.Database
+	rabbit *repository.RabbitMQ
+}
+
+// migrationTargetWait retries connecting to a target database for a few
+// seconds; the target deployment is expected to be running already
+var migrationTargetWait = repository.Backoff{Initial: 2 * time.Second, Max: 2 * time.Second, Deadline: 10 * time.Second}
+
+func (s *TenantMigrationService) connect(name string) (*migrationTarget, error) {
+	cfg, ok := s.targets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMigrationTarget, name)
+	}
+	db, err := repository.NewDatabase(cfg.DatabaseURL, nil, migrationTargetWait)
+	if err != nil {
+		return nil, err
+	}
+	rabbit, err := repository.NewRabbitMQ(cfg.RabbitMQURL)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &migrationTarget{name: name, db: db, rabbit: rabbit}, nil
+}
+
+func (t *migrationTarget) Close() {
+	t.rabbit.Close()
+	t.db.Close()
+}
+
+func (s *TenantMigrationService) run(ctx context.Context, id string) error {
+	// Migrasi yang terputus bisa berasal dari sebelum transport diganti
+	if err := s.tenants.onRabbitMQ(); err != nil {
+		return err
+	}
+	m, err := s.GetMigration(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.DB.ExecContext(ctx,
+		"UPDATE tenant_migrations SET status = $2, error = NULL, updated_at = NOW() WHERE id = $1",
+		id, domain.TenantMigrationStatusRunning,
+	); err != nil {
+		return err
+	}
+
+	target, err := s.connect(m.Target)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	steps := []struct {
+		name string
+		run  func(context.Context, *domain.TenantMigration, *migrationTarget) error
+		next string
+	}{
+		{domain.TenantMigrationStepConfig, s.copyConfig, domain.TenantMigrationStepData},
+		{domain.TenantMigrationStepData, s.copyData, domain.TenantMigrationStepCutover},
+		{domain.TenantMigrationStepCutover, s.cutover, domain.TenantMigrationStepShovel},
+		{domain.TenantMigrationStepShovel, s.shovel, domain.TenantMigrationStepFinalSync},
+		{domain.TenantMigrationStepFinalSync, s.finalSync, domain.TenantMigrationStepVerify},
+		{domain.TenantMigrationStepVerify, s.verify, domain.TenantMigrationStepDone},
+	}
+
+	for _, step := range steps {
+		if m.Step != step.name {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := step.run(ctx, m, target); err != nil {
+			return fmt.Errorf("step %s: %w", step.name, err)
+		}
+
+		m.Step = step.next
+		if _, err := s.db.DB.ExecContext(ctx,
+			"UPDATE tenant_migrations SET step = $2, updated_at = NOW() WHERE id = $1",
+			id, m.Step,
+		); err != nil {
+			return err
+		}
+		slog.Info("Tenant migration step done", "migration_id", id, "step", step.name)
+	}
+
+	_, err = s.db.DB.ExecContext(ctx,
+		"UPDATE tenant_migrations SET status = $2, updated_at = NOW() WHERE id = $1",
+		id, domain.TenantMigrationStatusCompleted,
+	)
+	return err
+}
+
+// copyConfig creates the tenant on the target with its settings, partition and queues
+func (s *TenantMigrationService) copyConfig(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	var name string
+	var createdAt time.Time
+	if err := s.db.DB.QueryRowContext(ctx,
+		"SELECT name, created_at FROM tenants WHERE id = $1", m.TenantID,
+	).Scan(&name, &createdAt); err != nil {
+		return err
+	}
+
+	var workers, prefetch, dedupWindow int
+	var ordered bool
+	var partitionKey, dedupKey string
+	var sloTarget sql.NullFloat64
+	var sloThresholdMs sql.NullInt64
+	var processors, dlqRetrySchedule []byte
+	var dlqRetryEnabled sql.NullBool
+	var publishRate float64
+	var publishBurst int
+	var rateLimitConsume bool
+	err := s.db.DB.QueryRowContext(ctx, `
+		SELECT workers, prefetch_count, ordered, partition_key, dedup_window_seconds, dedup_key, slo_target, slo_threshold_ms, processors,
+			dlq_retry_enabled, dlq_retry_schedule, publish_rate, publish_burst, rate_limit_consume
+		FROM tenant_configs WHERE tenant_id = $1
+	`, m.TenantID).Scan(&workers, &prefetch, &ordered, &partitionKey, &dedupWindow, &dedupKey, &sloTarget, &sloThresholdMs, &processors,
+		&dlqRetryEnabled, &dlqRetrySchedule, &publishRate, &publishBurst, &rateLimitConsume)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	hasConfig := err == nil
+
+	redactionRules, err := s.tenantRows(ctx, "SELECT path, action FROM tenant_redaction_rules WHERE tenant_id = $1", m.TenantID)
+	if err != nil {
+		return err
+	}
+	allowlist, err := s.tenantRows(ctx, "SELECT cidr::text, '' FROM tenant_ip_allowlists WHERE tenant_id = $1", m.TenantID)
+	if err != nil {
+		return err
+	}
+
+	schemas, err := s.tenantSchemas(ctx, m.TenantID)
+	if err != nil {
+		return err
+	}
+	filters, err := s.tenants.filters.GetRules(m.TenantID)
+	if err != nil {
+		return err
+	}
+	channels, err := s.tenants.ListChannels(m.TenantID)
+	if err != nil {
+		return err
+	}
+
+	if err := createPartition(target.db, m.TenantID); err != nil {
+		return fmt.Errorf("failed to create partition: %w", err)
+	}
+
+	tx, err := target.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"INSERT INTO tenants (id, name, created_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING",
+		m.TenantID, name, createdAt,
+	); err != nil {
+		return err
+	}
+	if hasConfig {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant_configs (tenant_id, workers, prefetch_count, ordered, partition_key, dedup_window_seconds, dedup_key, slo_target, slo_threshold_ms, processors,
+				dlq_retry_enabled, dlq_retry_schedule, publish_rate, publish_burst, rate_limit_consume)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			ON CONFLICT (tenant_id) DO UPDATE SET workers = EXCLUDED.workers, prefetch_count = EXCLUDED.prefetch_count, ordered = EXCLUDED.ordered,
+				partition_key = EXCLUDED.partition_key, dedup_window_seconds = EXCLUDED.dedup_window_seconds, dedup_key = EXCLUDED.dedup_key,
+				slo_target = EXCLUDED.slo_target, slo_threshold_ms = EXCLUDED.slo_threshold_ms, processors = EXCLUDED.processors,
+				dlq_retry_enabled = EXCLUDED.dlq_retry_enabled, dlq_retry_schedule = EXCLUDED.dlq_retry_schedule,
+				publish_rate = EXCLUDED.publish_rate, publish_burst = EXCLUDED.publish_burst, rate_limit_consume = EXCLUDED.rate_limit_consume
+		`, m.TenantID, workers, prefetch, ordered, partitionKey, dedupWindow, dedupKey, sloTarget, sloThresholdMs, processors,
+			dlqRetryEnabled, dlqRetrySchedule, publishRate, publishBurst, rateLimitConsume); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM tenant_redaction_rules WHERE tenant_id = $1", m.TenantID); err != nil {
+		return err
+	}
+	for _, rule := range redactionRules {
+		if _, err := tx.Exec(
+			"INSERT INTO tenant_redaction_rules (tenant_id, path, action) VALUES ($1, $2, $3)",
+			m.TenantID, rule[0], rule[1],
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM tenant_ip_allowlists WHERE tenant_id = $1", m.TenantID); err != nil {
+		return err
+	}
+	for _, entry := range allowlist {
+		if _, err := tx.Exec(
+			"INSERT INTO tenant_ip_allowlists (tenant_id, cidr) VALUES ($1, $2)",
+			m.TenantID, entry[0],
+		); err != nil {
+			return err
+		}
+	}
+	for _, schema := range schemas {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant_schemas (tenant_id, message_type, version, schema, created_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (tenant_id, message_type, version) DO NOTHING
+		`, m.TenantID, schema.MessageType, schema.Version, []byte(schema.Schema), schema.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM tenant_message_filters WHERE tenant_id = $1", m.TenantID); err != nil {
+		return err
+	}
+	for i, rule := range filters {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant_message_filters (tenant_id, position, name, expression, action, target)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, m.TenantID, i, rule.Name, rule.Expression, rule.Action, rule.Target); err != nil {
+			return err
+		}
+	}
+	for _, channel := range channels {
+		if _, err := tx.Exec(`
+			INSERT INTO tenant_channels (tenant_id, name, queue_name, workers, ordered, partition_key, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (tenant_id, name) DO UPDATE SET workers = EXCLUDED.workers, ordered = EXCLUDED.ordered,
+				partition_key = EXCLUDED.partition_key
+		`, m.TenantID, channel.Name, channel.QueueName, channel.Workers, channel.Ordered, channel.PartitionKey, channel.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := declareQueues(target.rabbit, m.TenantID, s.tenants.currentQueueName(m.TenantID), s.tenants.messageTTL); err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if err := declareTenantQueue(target.rabbit, m.TenantID, channel.QueueName, s.tenants.messageTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantSchemas reads every payload schema version of a tenant
+func (s *TenantMigrationService) tenantSchemas(ctx context.Context, tenantID string) ([]domain.PayloadSchema, error) {
+	rows, err := s.db.DB.QueryContext(ctx, `
+		SELECT message_type, version, schema, created_at FROM tenant_schemas WHERE tenant_id = $1
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []domain.PayloadSchema
+	for rows.Next() {
+		var schema domain.PayloadSchema
+		if err := rows.Scan(&schema.MessageType, &schema.Version, &schema.Schema, &schema.CreatedAt); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// tenantRows reads two text columns of a tenant's rows
+func (s *TenantMigrationService) tenantRows(ctx context.Context, query, tenantID string) ([][2]string, error) {
+	rows, err := s.db.DB.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result [][2]string
+	for rows.Next() {
+		var row [2]string
+		if err := rows.Scan(&row[0], &row[1]); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// copyData copies the tenant's messages after the checkpoint in chunks,
+// saving the checkpoint after each chunk
+func (s *TenantMigrationService) copyData(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	var checkpointAt sql.NullTime
+	var checkpointID sql.NullString
+	if err := s.db.DB.QueryRowContext(ctx,
+		"SELECT checkpoint_created_at, checkpoint_id FROM tenant_migrations WHERE id = $1", m.ID,
+	).Scan(&checkpointAt, &checkpointID); err != nil {
+		return err
+	}
+	return s.copyFrom(ctx, m, target, checkpointAt, checkpointID)
+}
+
+func (s *TenantMigrationService) copyFrom(ctx context.Context, m *domain.TenantMigration, target *migrationTarget, checkpointAt sql.NullTime, checkpointID sql.NullString) error {
+	tenant := sql.NullString{String: m.TenantID, Valid: true}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		messages, err := fetchMessages(ctx, s.db, tenant, checkpointAt, checkpointID, s.chunkSize)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		copied, err := insertMessages(ctx, target.db, messages)
+		if err != nil {
+			return err
+		}
+
+		last := messages[len(messages)-1]
+		checkpointAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		checkpointID = sql.NullString{String: last.ID, Valid: true}
+		if _, err := s.db.DB.ExecContext(ctx, `
+			UPDATE tenant_migrations
+			SET checkpoint_created_at = $2, checkpoint_id = $3, copied_rows = copied_rows + $4, updated_at = NOW()
+			WHERE id = $1
+		`, m.ID, checkpointAt, checkpointID, copied); err != nil {
+			return err
+		}
+
+		if len(messages) < s.chunkSize {
+			return nil
+		}
+	}
+}
+
+// insertMessages writes messages to the target, skipping ones already copied
+func insertMessages(ctx context.Context, db *repository.Database, messages []domain.Message) (int64, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var copied int64
+	for _, msg := range messages {
+		result, err := tx.Exec(`
+			INSERT INTO messages (id, tenant_id, payload, status, message_type, schema_version, tags, channel, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT DO NOTHING
+		`, msg.ID, msg.TenantID, msg.Payload, msg.Status, msg.MessageType, msg.SchemaVersion, msg.Tags, msg.Channel, msg.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+		affected, _ := result.RowsAffected()
+		copied += affected
+	}
+	return copied, tx.Commit()
+}
+
+// cutover marks the tenant as migrated so no instance attaches it again and
+// stops its consumer here. Instances coordinated through consumer handover
+// drain it on their next reconcile.
+func (s *TenantMigrationService) cutover(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	if _, err := s.db.DB.ExecContext(ctx,
+		"UPDATE tenants SET migrated_to = $2 WHERE id = $1", m.TenantID, target.name,
+	); err != nil {
+		return err
+	}
+
+	err := s.tenants.DrainTenant(ctx, m.TenantID)
+	if err != nil && !errors.Is(err, ErrTenantNotFound) {
+		return err
+	}
+	return nil
+}
+
+// shovel moves messages still queued here, including failed ones in the DLQ
+// and those in channel queues, to the same queues on the target. A backlog
+// parked by a recovery goes to the target's main queue.
+func (s *TenantMigrationService) shovel(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	mainQueue := s.tenants.currentQueueName(m.TenantID)
+	queues := map[string]string{
+		mainQueue:                     mainQueue,
+		dlqName(m.TenantID):           dlqName(m.TenantID),
+		recoveryQueueName(m.TenantID): mainQueue,
+	}
+	channels, err := s.tenants.ListChannels(m.TenantID)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		queues[channel.QueueName] = channel.QueueName
+	}
+
+	for from, to := range queues {
+		// The recovery queue only exists during a recovery, and Get on a missing queue fails
+		if depth, err := s.tenants.queueDepth(from); err != nil || depth == 0 {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := shovelQueue(ctx, s.rabbit.Conn(), from, target.rabbit.Conn(), to, func() error {
+			_, err := s.db.DB.ExecContext(ctx,
+				"UPDATE tenant_migrations SET shoveled_messages = shoveled_messages + 1, updated_at = NOW() WHERE id = $1", m.ID,
+			)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finalSync copies messages stored here since the data step, including the
+// ones consumed while the cutover was in progress, and the consumer group offsets
+func (s *TenantMigrationService) finalSync(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	var checkpointAt sql.NullTime
+	if err := s.db.DB.QueryRowContext(ctx,
+		"SELECT checkpoint_created_at FROM tenant_migrations WHERE id = $1", m.ID,
+	).Scan(&checkpointAt); err != nil {
+		return err
+	}
+	if checkpointAt.Valid {
+		checkpointAt.Time = checkpointAt.Time.Add(-finalSyncRewind)
+	}
+	if err := s.copyFrom(ctx, m, target, checkpointAt, sql.NullString{String: uuid.Nil.String(), Valid: checkpointAt.Valid}); err != nil {
+		return err
+	}
+	return s.copyConsumerGroups(ctx, m, target)
+}
+
+// copyConsumerGroups copies the tenant's consumer groups with their offsets.
+// Message IDs and timestamps are kept by the copy, so the offsets stay valid
+// on the target.
+func (s *TenantMigrationService) copyConsumerGroups(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	rows, err := s.db.DB.QueryContext(ctx, `
+		SELECT name, offset_id, offset_created_at, created_at FROM consumer_groups WHERE tenant_id = $1
+	`, m.TenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var offset groupOffset
+		var createdAt time.Time
+		if err := rows.Scan(&name, &offset.id, &offset.createdAt, &createdAt); err != nil {
+			return err
+		}
+		if _, err := target.db.DB.ExecContext(ctx, `
+			INSERT INTO consumer_groups (tenant_id, name, offset_id, offset_created_at, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (tenant_id, name) DO UPDATE SET offset_id = EXCLUDED.offset_id,
+				offset_created_at = EXCLUDED.offset_created_at, updated_at = NOW()
+		`, m.TenantID, name, offset.id, offset.createdAt, createdAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// verify compares the tenant's message counts here and on the target. On a
+// mismatch the migration goes back to the final sync so a resume retries it.
+func (s *TenantMigrationService) verify(ctx context.Context, m *domain.TenantMigration, target *migrationTarget) error {
+	var sourceCount, targetCount int64
+	if err := s.db.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM messages WHERE tenant_id = $1", m.TenantID,
+	).Scan(&sourceCount); err != nil {
+		return err
+	}
+	if err := target.db.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM messages WHERE tenant_id = $1", m.TenantID,
+	).Scan(&targetCount); err != nil {
+		return err
+	}
+
+	if _, err := s.db.DB.ExecContext(ctx,
+		"UPDATE tenant_migrations SET source_count = $2, target_count = $3, updated_at = NOW() WHERE id = $1",
+		m.ID, sourceCount, targetCount,
+	); err != nil {
+		return err
+	}
+	if targetCount < sourceCount {
+		if _, err := s.db.DB.ExecContext(ctx,
+			"UPDATE tenant_migrations SET step = $2 WHERE id = $1", m.ID, domain.TenantMigrationStepFinalSync,
+		); err != nil {
+			return err
+		}
+		return fmt.Errorf("target has %d messages, source has %d", targetCount, sourceCount)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"multi-tenant-messaging/internal/auth"
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+)
+
+var (
+	// ErrInvalidTokenRequest is returned for missing or unknown scopes and out of range lifetimes
+	ErrInvalidTokenRequest = errors.New("invalid token request")
+	// ErrTokenNotFound is returned when the tenant has no sub-token with the ID
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// defaultTenantTokenTTL is the lifetime of a sub-token minted without one
+const defaultTenantTokenTTL = time.Hour
+
+// TenantTokenService mints, lists and revokes the scoped sub-tokens tenant
+// admins hand out, e.g. to dashboards
+type TenantTokenService struct {
+	db     *repository.Database
+	tokens *auth.TokenService
+	maxTTL time.Duration
+}
+
+func NewTenantTokenService(db *repository.Database, tokens *auth.TokenService, maxTTL time.Duration) *TenantTokenService {
+	return &TenantTokenService{db: db, tokens: tokens, maxTTL: maxTTL}
+}
+
+// Mint issues an access token for tenantID limited to scopes. ttl 0 means
+// one hour, capped at security.sub_token_max_ttl.
+func (s *TenantTokenService) Mint(tenantID, name, createdBy string, scopes []string, ttl time.Duration) (domain.TenantToken, error) {
+	if ttl == 0 {
+		ttl = min(defaultTenantTokenTTL, s.maxTTL)
+	}
+	if ttl < time.Minute || ttl > s.maxTTL {
+		return domain.TenantToken{}, fmt.Errorf("%w: ttl must be between 1m and %s", ErrInvalidTokenRequest, s.maxTTL)
+	}
+	if len(scopes) == 0 {
+		return domain.TenantToken{}, fmt.Errorf("%w: at least one scope is required", ErrInvalidTokenRequest)
+	}
+	// Tanpa RLS, /messages tidak membatasi baris ke tenant token
+	if slices.Contains(scopes, auth.ScopeMessagesRead) && !s.db.RowLevelSecurity() {
+		return domain.TenantToken{}, fmt.Errorf("%w: %s needs database.row_level_security", ErrInvalidTokenRequest, auth.ScopeMessagesRead)
+	}
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	signed, err := s.tokens.IssueScoped(name, tenantID, scopes, ttl)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnknownScope) {
+			return domain.TenantToken{}, fmt.Errorf("%w: %v", ErrInvalidTokenRequest, err)
+		}
+		return domain.TenantToken{}, err
+	}
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return domain.TenantToken{}, err
+	}
+	token := domain.TenantToken{
+		ID:        signed.Claims.ID,
+		TenantID:  tenantID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: signed.Claims.IssuedAt.Time,
+		ExpiresAt: signed.Claims.ExpiresAt.Time,
+		Token:     signed.Token,
+	}
+	if _, err := s.db.DB.Exec(`
+		INSERT INTO tenant_tokens (id, tenant_id, name, scopes, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, token.ID, tenantID, name, scopesJSON, createdBy, token.CreatedAt, token.ExpiresAt); err != nil {
+		return domain.TenantToken{}, err
+	}
+	return token, nil
+}
+
+// List returns the tenant's sub-tokens that have not expired, newest first
+func (s *TenantTokenService) List(tenantID string) ([]domain.TenantToken, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT id, tenant_id, name, scopes, created_by, created_at, expires_at, revoked_at
+		FROM tenant_tokens
+		WHERE tenant_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]domain.TenantToken, 0)
+	for rows.Next() {
+		var token domain.TenantToken
+		var scopes []byte
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&token.ID, &token.TenantID, &token.Name, &scopes, &token.CreatedBy, &token.CreatedAt, &token.ExpiresAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(scopes, &token.Scopes); err != nil {
+			return nil, err
+		}
+		if revokedAt.Valid {
+			token.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke puts the tenant's sub-token on the revocation list
+func (s *TenantTokenService) Revoke(tenantID, tokenID string) error {
+	var expiresAt time.Time
+	err := s.db.DB.QueryRow(`
+		UPDATE tenant_tokens SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING expires_at
+	`, tenantID, tokenID).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return ErrTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.tokens.RevokeID(tokenID, expiresAt)
+}
+
+// PurgeExpired removes sub-tokens that have expired anyway
+func (s *TenantTokenService) PurgeExpired() (int64, error) {
+	res, err := s.db.DB.Exec("DELETE FROM tenant_tokens WHERE expires_at < NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
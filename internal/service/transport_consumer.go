@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/events"
+	"multi-tenant-messaging/internal/logging"
+	"multi-tenant-messaging/internal/metrics"
+	"multi-tenant-messaging/internal/transport"
+	"multi-tenant-messaging/internal/worker"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrUnsupportedTransport is returned for features built on RabbitMQ queues
+// when the deployment runs on another transport
+var ErrUnsupportedTransport = errors.New("not supported by the configured transport")
+
+// onRabbitMQ is the check of features built on RabbitMQ queues: channels,
+// delays, recovery, DLQ inspection and redrive, queue renames, error reports
+// and tenant migration. It fails with ErrUnsupportedTransport otherwise.
+func (s *TenantService) onRabbitMQ() error {
+	if kind := s.transport.Kind(); kind != transport.KindRabbitMQ {
+		return fmt.Errorf("%w: %s", ErrUnsupportedTransport, kind)
+	}
+	return nil
+}
+
+// startTransportConsumer consumes the tenant's main queue through a transport
+// other than RabbitMQ and registers the tenant in the manager. Messages are
+// processed like on RabbitMQ; failures are requeued or dead-lettered through
+// the transport. On a shared topic the messages of other tenants are skipped.
+func (s *TenantService) startTransportConsumer(config domain.TenantConfig) error {
+	tenantID := config.TenantID
+	source, key := s.transport.Destination(tenantID, config.QueueName)
+	workers, prefetch := consumerLimits(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deliveries, err := s.transport.Consume(ctx, source, tenantID, prefetch)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	var pool worker.Dispatcher
+	if config.PartitionKey != "" && !config.Ordered {
+		pool = worker.NewKeyedPool(workers)
+	} else {
+		pool = worker.NewWorkerPool(workers)
+	}
+	go pool.Run(ctx)
+
+	var inflight sync.WaitGroup
+	draining := make(chan struct{})
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		s.consumeTransport(ctx, draining, deliveries, pool, &inflight, config, source, key)
+	}()
+
+	var drainOnce sync.Once
+	s.tenantManager.AddTenant(tenantID, &domain.TenantContext{
+		CancelFunc: cancel,
+		Drain: func(drainCtx context.Context) error {
+			// Berhenti mengambil pesan, lalu tunggu yang sedang diproses agar sempat di-commit
+			drainOnce.Do(func() { close(draining) })
+			select {
+			case <-consumed:
+			case <-drainCtx.Done():
+				return drainCtx.Err()
+			}
+			done := make(chan struct{})
+			go func() {
+				inflight.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-drainCtx.Done():
+				return drainCtx.Err()
+			}
+		},
+		Disconnected: func() bool { return false },
+		Stopped: func() bool {
+			select {
+			case <-draining:
+				return false
+			default:
+			}
+			select {
+			case <-consumed:
+				return ctx.Err() == nil
+			default:
+				return false
+			}
+		},
+		Resize: func(newWorkers, newPrefetch int) error {
+			resized := config
+			resized.Workers = newWorkers
+			resized.Prefetch = newPrefetch
+			nextWorkers, nextPrefetch := consumerLimits(resized)
+			resizer, ok := pool.(worker.Resizer)
+			// Prefetch dipakai reader saat dibuat, jadi perubahannya butuh restart
+			if !ok || nextPrefetch != prefetch {
+				return domain.ErrNotResizable
+			}
+			resizer.SetSize(nextWorkers)
+			return nil
+		},
+		Config: config,
+	})
+	return nil
+}
+
+// consumeTransport dispatches the deliveries of source to pool until ctx is
+// done or draining is closed. key is the tenant's key on a shared topic.
+func (s *TenantService) consumeTransport(ctx context.Context, draining <-chan struct{}, deliveries <-chan transport.Delivery, pool worker.Dispatcher, inflight *sync.WaitGroup, config domain.TenantConfig, source, key string) {
+	tenantID := config.TenantID
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-draining:
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			if key != "" && d.Key != key {
+				// Pesan tenant lain di topic bersama
+				if err := d.Ack(); err != nil {
+					slog.Error("Failed to skip message of another tenant", "tenant_id", tenantID, "error", err)
+				}
+				continue
+			}
+			if err := s.db.WaitWritable(ctx); err != nil {
+				return
+			}
+			if err := s.rateLimits.WaitConsume(ctx, tenantID); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Warn("Failed to load rate limit", "tenant_id", tenantID, "error", err)
+			}
+			// Tenant yang penuh kuotanya berhenti mengambil pesan, bukan membuangnya
+			if err := s.waitIntake(ctx, tenantID); err != nil {
+				return
+			}
+			receivedAt := time.Now()
+			publishedAt := d.Timestamp
+			if publishedAt.IsZero() {
+				publishedAt = receivedAt
+			}
+			lane := ""
+			if config.PartitionKey != "" {
+				lane = partitionKeyValue(d.Body, config.PartitionKey)
+			}
+			inflight.Add(1)
+			pool.Dispatch(lane, d.Priority, func(int) {
+				defer inflight.Done()
+				s.processDelivery(ctx, d, config, source, receivedAt, publishedAt)
+			})
+		}
+	}
+}
+
+// processDelivery processes and settles one delivery of a transport consumer
+func (s *TenantService) processDelivery(ctx context.Context, d transport.Delivery, config domain.TenantConfig, source string, receivedAt, publishedAt time.Time) {
+	tenantID := config.TenantID
+	msgCtx, span := metrics.Tracer().Start(metrics.ExtractHeaders(context.Background(), d.Headers), "process "+source,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", s.transport.Kind()),
+			attribute.String("messaging.destination.name", source),
+			attribute.String("messaging.message.id", d.ID),
+			attribute.String("tenant_id", tenantID),
+		))
+	msgCtx = logging.With(msgCtx, "tenant_id", tenantID, "message_id", d.ID)
+	process := func() error {
+		return s.processMessage(msgCtx, tenantID, "", d.ID, d.Type, d.ContentType, amqp.Table(d.Headers), d.Body)
+	}
+	err := metrics.ObserveStage(msgCtx, "process", process)
+	// Saat failover, tunggu primary baru lalu ulangi daripada nack
+	for err != nil && s.db.FailedOver(err) && s.db.WaitWritable(ctx) == nil {
+		metrics.ObserveMessageRetry(msgCtx, tenantID, "failover")
+		err = metrics.ObserveStage(msgCtx, "process", process)
+	}
+	metrics.EndSpan(span, err)
+	metrics.Tenants.Observe(tenantID)
+	s.slos.Record(tenantID, publishedAt, err)
+
+	var settleErr error
+	if err != nil {
+		slog.ErrorContext(msgCtx, "Failed to process message", "error", err)
+		if reason := permanentFailureReason(err); reason != "" {
+			metrics.ObserveMessageDeadLettered(msgCtx, tenantID, reason)
+			settleErr = s.transport.DeadLetter(context.Background(), source, d, reason)
+			if settleErr == nil {
+				s.events.Emit(events.TypeMessageDeadLettered, tenantID, map[string]interface{}{
+					"message_id": d.ID,
+					"type":       d.Type,
+					"reason":     reason,
+				})
+			}
+		} else {
+			metrics.ObserveMessageRetry(msgCtx, tenantID, "requeue")
+			settleErr = d.Nack(true)
+		}
+	} else {
+		metrics.ObserveMessageProcessed(msgCtx, tenantID, receivedAt)
+		settleErr = d.Ack()
+	}
+	if settleErr != nil {
+		slog.ErrorContext(msgCtx, "Failed to settle message", "error", settleErr)
+	}
+}
@@ -0,0 +1,536 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"multi-tenant-messaging/internal/domain"
+	"multi-tenant-messaging/internal/repository"
+	"multi-tenant-messaging/pkg/webhook"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidWebhook is returned for webhook URLs that are not absolute
+	// http or https URLs and for unknown delivery statuses
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookNotFound is returned when the tenant has no webhook with the ID
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrWebhookAddressBlocked is returned when a webhook URL points at, or
+	// resolves to, a loopback, private, link-local or otherwise internal address
+	ErrWebhookAddressBlocked = errors.New("webhook address not allowed")
+)
+
+// Headers set on every webhook delivery besides the signature headers
+const (
+	webhookDeliveryHeader    = "X-Salva-Delivery-Id"
+	webhookMessageHeader     = "X-Salva-Message-Id"
+	webhookMessageTypeHeader = "X-Salva-Message-Type"
+	webhookTenantHeader      = "X-Salva-Tenant-Id"
+)
+
+const (
+	// webhookSecretPrefix starts every webhook secret
+	webhookSecretPrefix = "whsec_"
+	// webhookCacheTTL bounds how long a new webhook on another instance may go unnoticed
+	webhookCacheTTL = 30 * time.Second
+	// webhookDrainBody is how much of a response is read so the connection can be reused
+	webhookDrainBody = 1 << 20
+)
+
+// WebhookOptions configures webhook delivery
+type WebhookOptions struct {
+	// Timeout bounds one delivery attempt
+	Timeout     time.Duration
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after every
+	// further one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// PollInterval is how often workers look for due deliveries
+	PollInterval time.Duration
+	// Workers is how many deliveries an instance makes at once
+	Workers int
+	// Retention is how long delivered and failed deliveries are kept
+	Retention time.Duration
+	// AllowPrivateNetworks lets webhooks reach loopback, private and
+	// link-local addresses, e.g. for receivers on a development machine
+	AllowPrivateNetworks bool
+}
+
+type cachedWebhooks struct {
+	found    bool
+	loadedAt time.Time
+}
+
+// WebhookService manages tenant webhooks and delivers the messages the
+// tenant's consumer stores to them. Deliveries are written in the transaction
+// that stores the message and POSTed by the workers of every instance, so a
+// message is delivered at least once even across restarts.
+type WebhookService struct {
+	db     *repository.Database
+	opts   WebhookOptions
+	client *http.Client
+
+	mu    sync.RWMutex
+	cache map[string]cachedWebhooks
+}
+
+func NewWebhookService(db *repository.Database, opts WebhookOptions) *WebhookService {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 10 * time.Second
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = opts.Backoff
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 8
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 7 * 24 * time.Hour
+	}
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateNetworks {
+		dialer.Control = webhookDialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Proxy dari environment akan melewati pemeriksaan alamat saat dial
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &WebhookService{
+		db:   db,
+		opts: opts,
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+			// Redirect tidak diikuti, endpoint harus menerima langsung
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		cache: make(map[string]cachedWebhooks),
+	}
+}
+
+// webhookDialControl refuses connections to internal addresses. It runs on
+// the address a name resolved to, right before connecting, so a name that
+// resolves to a public address when the webhook is created and to an
+// internal one later (DNS rebinding) is still refused.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || blockedWebhookAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, host)
+	}
+	return nil
+}
+
+// blockedWebhookAddr reports whether addr is loopback, private, link-local
+// (which holds cloud metadata endpoints such as 169.254.169.254), shared,
+// unspecified or multicast
+func blockedWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr) || thisNetwork.Contains(addr)
+}
+
+var (
+	// sharedAddressSpace is the carrier-grade NAT range of RFC 6598
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	// thisNetwork is 0.0.0.0/8, which some systems route to the local host
+	thisNetwork = netip.MustParsePrefix("0.0.0.0/8")
+)
+
+// Create registers an endpoint for tenantID and returns it with the secret
+// its deliveries are signed with
+func (s *WebhookService) Create(tenantID, endpoint, createdBy string) (domain.Webhook, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return domain.Webhook{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	// Nama host diperiksa lagi saat dial; di sini hanya alamat literal dan localhost
+	if !s.opts.AllowPrivateNetworks {
+		host := parsed.Hostname()
+		if addr, err := netip.ParseAddr(host); (err == nil && blockedWebhookAddr(addr)) || strings.EqualFold(host, "localhost") {
+			return domain.Webhook{}, fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, host)
+		}
+	}
+	var exists bool
+	if err := s.db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)", tenantID).Scan(&exists); err != nil {
+		return domain.Webhook{}, err
+	}
+	if !exists {
+		return domain.Webhook{}, ErrTenantNotFound
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.Webhook{}, err
+	}
+	hook := domain.Webhook{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		URL:       parsed.String(),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		Secret:    webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret),
+	}
+	if _, err := s.db.DB.Exec(`
+		INSERT INTO tenant_webhooks (id, tenant_id, url, secret, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, hook.ID, tenantID, hook.URL, hook.Secret, createdBy, hook.CreatedAt); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cachedWebhooks{found: true, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return hook, nil
+}
+
+// List returns the tenant's webhooks, oldest first. Secrets are not returned.
+func (s *WebhookService) List(tenantID string) ([]domain.Webhook, error) {
+	rows, err := s.db.DB.Query(`
+		SELECT id, tenant_id, url, created_by, created_at FROM tenant_webhooks
+		WHERE tenant_id = $1 ORDER BY created_at, id
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]domain.Webhook, 0)
+	for rows.Next() {
+		var hook domain.Webhook
+		if err := rows.Scan(&hook.ID, &hook.TenantID, &hook.URL, &hook.CreatedBy, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// Delete removes the webhook together with its deliveries, including the
+// ones still pending
+func (s *WebhookService) Delete(tenantID, webhookID string) error {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return ErrWebhookNotFound
+	}
+	res, err := s.db.DB.Exec("DELETE FROM tenant_webhooks WHERE id = $1 AND tenant_id = $2", webhookID, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrWebhookNotFound
+	}
+
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+	return nil
+}
+
+// ListDeliveries returns the newest deliveries of the webhook, only those
+// with status unless it is empty
+func (s *WebhookService) ListDeliveries(tenantID, webhookID, status string, limit int) ([]domain.WebhookDelivery, error) {
+	switch status {
+	case "", domain.WebhookDeliveryPending, domain.WebhookDeliveryDelivered, domain.WebhookDeliveryFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidWebhook, status)
+	}
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	var exists bool
+	if err := s.db.DB.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM tenant_webhooks WHERE id = $1 AND tenant_id = $2)", webhookID, tenantID,
+	).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	rows, err := s.db.DB.Query(`
+		SELECT id, webhook_id, message_id, message_type, status, attempts, next_attempt_at,
+			last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text = '' OR status = $2::text)
+		ORDER BY created_at DESC LIMIT $3
+	`, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var nextAttemptAt, deliveredAt sql.NullTime
+		var statusCode sql.NullInt64
+		var lastError sql.NullString
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.MessageID, &d.MessageType, &d.Status, &d.Attempts, &nextAttemptAt,
+			&statusCode, &lastError, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		if nextAttemptAt.Valid && d.Status == domain.WebhookDeliveryPending {
+			d.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		d.LastStatusCode = int(statusCode.Int64)
+		d.LastError = lastError.String
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Enqueue writes a pending delivery of the message to each of the tenant's
+// webhooks through q, the transaction storing the message
+func (s *WebhookService) Enqueue(ctx context.Context, q repository.Querier, tenantID, messageID, messageType string, payload []byte) error {
+	if s == nil || !s.hasWebhooks(ctx, tenantID) {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, tenant_id, message_id, message_type, payload)
+		SELECT id, tenant_id, $2, $3, $4 FROM tenant_webhooks WHERE tenant_id = $1
+	`, tenantID, messageID, messageType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// hasWebhooks reports whether the tenant has webhooks, so tenants without
+// any skip the insert. A failed lookup reports true; the insert then finds out.
+func (s *WebhookService) hasWebhooks(ctx context.Context, tenantID string) bool {
+	s.mu.RLock()
+	cached, ok := s.cache[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < webhookCacheTTL {
+		return cached.found
+	}
+
+	var found bool
+	if err := s.db.DB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tenant_webhooks WHERE tenant_id = $1)", tenantID,
+	).Scan(&found); err != nil {
+		return true
+	}
+	s.mu.Lock()
+	s.cache[tenantID] = cachedWebhooks{found: found, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return found
+}
+
+// pendingDelivery is a claimed delivery with the endpoint it goes to
+type pendingDelivery struct {
+	id          string
+	tenantID    string
+	messageID   string
+	messageType string
+	payload     []byte
+	attempts    int
+	url         string
+	secret      string
+}
+
+// Run delivers due deliveries every poll interval until ctx is done and
+// purges finished ones after the retention. Every instance runs it; claimed
+// deliveries are leased so instances never deliver the same one at once.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+
+	for {
+		for {
+			claimed, err := s.deliverBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to deliver webhooks", "error", err)
+				}
+				break
+			}
+			if claimed < s.opts.Workers {
+				break
+			}
+		}
+		if time.Since(lastPurge) > time.Hour {
+			if n, err := s.purge(ctx); err != nil {
+				slog.Error("Failed to purge webhook deliveries", "error", err)
+			} else if n > 0 {
+				slog.Info("Purged webhook deliveries", "count", n)
+			}
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverBatch claims up to one due delivery per worker and makes them at
+// once. The claim counts the attempt and moves next_attempt_at past the
+// attempt timeout, so a delivery of a crashed instance is retried after it.
+func (s *WebhookService) deliverBatch(ctx context.Context) (int, error) {
+	rows, err := s.db.DB.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM tenant_webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.tenant_id, d.message_id, d.message_type, d.payload, d.attempts, w.url, w.secret
+	`, s.opts.Workers, (2 * s.opts.Timeout).Seconds())
+	if err != nil {
+		return 0, err
+	}
+	var claimed []pendingDelivery
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.id, &d.tenantID, &d.messageID, &d.messageType, &d.payload, &d.attempts, &d.url, &d.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		claimed = append(claimed, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range claimed {
+		wg.Add(1)
+		go func(d pendingDelivery) {
+			defer wg.Done()
+			statusCode, err := s.post(ctx, d)
+			if recordErr := s.record(ctx, d, statusCode, err); recordErr != nil && ctx.Err() == nil {
+				slog.Error("Failed to record webhook delivery", "delivery_id", d.id, "error", recordErr)
+			}
+		}(d)
+	}
+	wg.Wait()
+	return len(claimed), nil
+}
+
+// post sends the signed payload of d to its endpoint. Any 2xx response
+// accepts the delivery.
+func (s *WebhookService) post(ctx context.Context, d pendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "salva-webhooks")
+	req.Header.Set(webhookDeliveryHeader, d.id)
+	req.Header.Set(webhookMessageHeader, d.messageID)
+	req.Header.Set(webhookTenantHeader, d.tenantID)
+	if d.messageType != "" {
+		req.Header.Set(webhookMessageTypeHeader, d.messageType)
+	}
+	webhook.SetHeaders(req.Header, d.secret, time.Now(), d.payload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Body tidak disimpan agar webhook tidak bisa dipakai membaca endpoint lain; hanya dibuang
+	// supaya koneksi bisa dipakai ulang
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookDrainBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record stores the outcome of an attempt. A failed delivery is retried
+// after the backoff until it runs out of attempts.
+func (s *WebhookService) record(ctx context.Context, d pendingDelivery, statusCode int, deliveryErr error) error {
+	var code interface{}
+	if statusCode != 0 {
+		code = statusCode
+	}
+	if deliveryErr == nil {
+		_, err := s.db.DB.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = 'delivered', delivered_at = NOW(), last_status_code = $2, last_error = NULL
+			WHERE id = $1
+		`, d.id, code)
+		return err
+	}
+
+	if d.attempts >= s.opts.MaxAttempts {
+		slog.Warn("Webhook delivery failed", "tenant_id", d.tenantID, "delivery_id", d.id, "attempts", d.attempts, "error", deliveryErr)
+		_, err := s.db.DB.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = 'failed', last_status_code = $2, last_error = $3
+			WHERE id = $1
+		`, d.id, code, deliveryErr.Error())
+		return err
+	}
+	_, err := s.db.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET next_attempt_at = NOW() + make_interval(secs => $2), last_status_code = $3, last_error = $4
+		WHERE id = $1
+	`, d.id, s.backoff(d.attempts).Seconds(), code, deliveryErr.Error())
+	return err
+}
+
+// backoff is the wait after the given number of failed attempts
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := s.opts.Backoff
+	for i := 1; i < attempts && wait < s.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > s.opts.MaxBackoff {
+		wait = s.opts.MaxBackoff
+	}
+	return wait
+}
+
+// purge deletes the delivered and failed deliveries older than the retention
+func (s *WebhookService) purge(ctx context.Context) (int64, error) {
+	res, err := s.db.DB.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < NOW() - make_interval(secs => $1)", s.opts.Retention.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockedWebhookAddr(t *testing.T) {
+	blocked := []string{
+		"127.0.0.1",
+		"::1",
+		"10.1.2.3",
+		"172.16.0.1",
+		"192.168.1.1",
+		"fd00::1",
+		"169.254.169.254",
+		"fe80::1",
+		"100.64.0.1",
+		"0.0.0.0",
+		"0.1.2.3",
+		"::",
+		"224.0.0.1",
+		"ff02::1",
+		"::ffff:127.0.0.1",
+		"::ffff:169.254.169.254",
+	}
+	for _, addr := range blocked {
+		assert.True(t, blockedWebhookAddr(netip.MustParseAddr(addr)), addr)
+	}
+
+	allowed := []string{
+		"93.184.216.34",
+		"8.8.8.8",
+		"100.128.0.1",
+		"172.32.0.1",
+		"2606:4700::1111",
+		"::ffff:93.184.216.34",
+	}
+	for _, addr := range allowed {
+		assert.False(t, blockedWebhookAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	assert.ErrorIs(t, webhookDialControl("tcp", "169.254.169.254:80", nil), ErrWebhookAddressBlocked)
+	assert.ErrorIs(t, webhookDialControl("tcp6", "[::1]:443", nil), ErrWebhookAddressBlocked)
+	assert.NoError(t, webhookDialControl("tcp", "93.184.216.34:443", nil))
+}
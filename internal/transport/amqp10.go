@@ -0,0 +1,374 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-amqp"
+)
+
+// AMQP 1.0 dead-lettering: a delivery rejected with this condition goes to the
+// queue's dead-letter subqueue on Azure Service Bus, which reads the reason
+// from the error info. ActiveMQ Artemis sends every rejected delivery to the
+// dead-letter address of the queue and ignores the condition.
+const (
+	amqp10DeadLetterCondition amqp.ErrCond = "com.microsoft:dead-letter"
+	amqp10DeadLetterReason                 = "DeadLetterReason"
+	amqp10DeadLetterDetail                 = "DeadLetterErrorDescription"
+	// amqp10EnqueuedTime is the annotation Service Bus stamps on received messages
+	amqp10EnqueuedTime = "x-opt-enqueued-time"
+)
+
+// amqp10MaxBackoff caps the wait between attempts to re-attach a receiver
+const amqp10MaxBackoff = 30 * time.Second
+
+// AMQP10Options configures the AMQP 1.0 transport
+type AMQP10Options struct {
+	// URL is amqp:// or amqps://; user info in it is sent as SASL PLAIN
+	// credentials, e.g. the shared access key name and key on Service Bus
+	URL string
+	// AddressPrefix starts the address of every tenant queue
+	AddressPrefix string
+}
+
+// AMQP10 is the transport over an AMQP 1.0 broker such as Azure Service Bus
+// or ActiveMQ Artemis. Tenant queues are plain queues: Service Bus needs them
+// created beforehand, Artemis creates them on first use. Dead letters use the
+// broker's own dead-lettering, the $deadletterqueue subqueue on Service Bus
+// and the dead-letter address on Artemis, with the reason attached.
+type AMQP10 struct {
+	opts AMQP10Options
+
+	mu      sync.Mutex
+	conn    *amqp.Conn
+	session *amqp.Session
+	senders map[string]*amqp.Sender
+}
+
+// NewAMQP10 connects to the broker at opts.URL. A connection that drops is
+// dialed again on next use.
+func NewAMQP10(ctx context.Context, opts AMQP10Options) (*AMQP10, error) {
+	a := &AMQP10{opts: opts, senders: make(map[string]*amqp.Sender)}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.sessionLocked(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AMQP10) Kind() string {
+	return KindAMQP10
+}
+
+// Destination is the address of the tenant queue
+func (a *AMQP10) Destination(tenantID, queue string) (string, string) {
+	return a.opts.AddressPrefix + queue, ""
+}
+
+// Declare attaches a sender to destination, which fails when the broker has
+// no such queue and does not create queues on demand
+func (a *AMQP10) Declare(ctx context.Context, destination string) error {
+	if _, err := a.sender(ctx, destination); err != nil {
+		return fmt.Errorf("queue %s is not available, create it on the broker: %w", destination, err)
+	}
+	return nil
+}
+
+// Delete only detaches from destination; AMQP 1.0 cannot delete queues, so
+// they are left on the broker
+func (a *AMQP10) Delete(ctx context.Context, destination string) error {
+	a.mu.Lock()
+	sender := a.senders[destination]
+	delete(a.senders, destination)
+	a.mu.Unlock()
+	if sender != nil {
+		sender.Close(ctx)
+	}
+	slog.Info("Queue left on the AMQP 1.0 broker", "address", destination)
+	return nil
+}
+
+// Publish sends msg to destination and waits until the broker accepted it.
+// The ID, type, content type and key travel as message properties, headers
+// as application properties; header values that are not strings are JSON.
+func (a *AMQP10) Publish(ctx context.Context, destination string, msg Message) error {
+	record := &amqp.Message{
+		Header:                &amqp.MessageHeader{Durable: true, Priority: msg.Priority},
+		Properties:            &amqp.MessageProperties{MessageID: msg.ID},
+		ApplicationProperties: make(map[string]any, len(msg.Headers)),
+		Data:                  [][]byte{msg.Body},
+	}
+	if msg.Type != "" {
+		record.Properties.Subject = &msg.Type
+	}
+	if msg.ContentType != "" {
+		record.Properties.ContentType = &msg.ContentType
+	}
+	if msg.Key != "" {
+		record.Properties.GroupID = &msg.Key
+	}
+	if !msg.Timestamp.IsZero() {
+		record.Properties.CreationTime = &msg.Timestamp
+	}
+	for key, value := range msg.Headers {
+		encoded, err := encodeHeader(key, value)
+		if err != nil {
+			return err
+		}
+		record.ApplicationProperties[key] = encoded
+	}
+
+	sender, err := a.sender(ctx, destination)
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(ctx, record, nil); err != nil {
+		a.dropSender(destination, sender, err)
+		return fmt.Errorf("failed to send to %s: %w", destination, err)
+	}
+	return nil
+}
+
+// Consume receives from the queue source with prefetch as link credit, so at
+// most prefetch deliveries are unsettled. Consumers of the same queue compete
+// for its messages; group is not used. A receiver that fails is attached
+// again, and the broker redelivers what it had not settled.
+func (a *AMQP10) Consume(ctx context.Context, source, group string, prefetch int) (<-chan Delivery, error) {
+	if prefetch < 1 {
+		prefetch = 1
+	}
+	receiver, err := a.receiver(ctx, source, prefetch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume %s: %w", source, err)
+	}
+
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		backoff := time.Second
+		for {
+			if receiver == nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				if receiver, err = a.receiver(ctx, source, prefetch); err != nil {
+					slog.Warn("Failed to attach AMQP 1.0 receiver", "address", source, "error", err)
+					backoff = min(backoff*2, amqp10MaxBackoff)
+					continue
+				}
+				backoff = time.Second
+			}
+
+			msg, err := receiver.Receive(ctx, nil)
+			if err != nil {
+				if ctx.Err() != nil {
+					// Pesan yang belum di-settle dikirim ulang oleh broker setelah link ditutup
+					receiver.Close(context.Background())
+					return
+				}
+				slog.Warn("Failed to receive AMQP 1.0 message, attaching again", "address", source, "error", err)
+				a.reset(err)
+				receiver = nil
+				continue
+			}
+
+			delivery := amqp10Delivery(receiver, msg)
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				receiver.ReleaseMessage(context.Background(), msg)
+				receiver.Close(context.Background())
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// DeadLetter rejects d so the broker moves it to the dead-letter queue of
+// source, with reason as the dead-letter reason
+func (a *AMQP10) DeadLetter(ctx context.Context, source string, d Delivery, reason string) error {
+	if d.reject == nil {
+		return fmt.Errorf("delivery of %s was not consumed over AMQP 1.0", source)
+	}
+	return d.reject(ctx, reason)
+}
+
+// Connected reports whether the connection to the broker is up
+func (a *AMQP10) Connected() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.conn != nil && a.conn.Err() == nil
+}
+
+func (a *AMQP10) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn, a.session = nil, nil
+	clear(a.senders)
+	return err
+}
+
+// sessionLocked returns the session of the current connection, dialing the
+// broker again once the connection is gone
+func (a *AMQP10) sessionLocked(ctx context.Context) (*amqp.Session, error) {
+	if a.conn != nil && a.conn.Err() == nil {
+		return a.session, nil
+	}
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn, a.session = nil, nil
+		clear(a.senders)
+	}
+
+	conn, err := amqp.Dial(ctx, a.opts.URL, &amqp.ConnOptions{ContainerID: "salva"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to AMQP 1.0 broker: %w", err)
+	}
+	session, err := conn.NewSession(ctx, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open AMQP 1.0 session: %w", err)
+	}
+	a.conn, a.session = conn, session
+	return session, nil
+}
+
+// sender returns the sender attached to destination, attaching one first
+func (a *AMQP10) sender(ctx context.Context, destination string) (*amqp.Sender, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	session, err := a.sessionLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sender, ok := a.senders[destination]; ok {
+		return sender, nil
+	}
+	sender, err := session.NewSender(ctx, destination, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.senders[destination] = sender
+	return sender, nil
+}
+
+// dropSender detaches a sender that failed, so the next publish attaches a
+// new one, and drops the connection when the failure was the connection's
+func (a *AMQP10) dropSender(destination string, sender *amqp.Sender, err error) {
+	a.mu.Lock()
+	if a.senders[destination] == sender {
+		delete(a.senders, destination)
+	}
+	a.mu.Unlock()
+	sender.Close(context.Background())
+	a.reset(err)
+}
+
+// receiver attaches a receiver to source with credit for prefetch messages
+func (a *AMQP10) receiver(ctx context.Context, source string, prefetch int) (*amqp.Receiver, error) {
+	a.mu.Lock()
+	session, err := a.sessionLocked(ctx)
+	a.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return session.NewReceiver(ctx, source, &amqp.ReceiverOptions{Credit: int32(prefetch)})
+}
+
+// reset closes the connection when err says it or its session is gone, so
+// the next use dials again
+func (a *AMQP10) reset(err error) {
+	var connErr *amqp.ConnError
+	var sessionErr *amqp.SessionError
+	if !errors.As(err, &connErr) && !errors.As(err, &sessionErr) {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn, a.session = nil, nil
+		clear(a.senders)
+	}
+}
+
+// amqp10Delivery converts a received message into a Delivery settled on
+// receiver. A requeued delivery counts as a failed attempt, so the broker's
+// own maximum delivery count still dead-letters it eventually.
+func amqp10Delivery(receiver *amqp.Receiver, msg *amqp.Message) Delivery {
+	d := Delivery{
+		Message: Message{
+			Headers: make(map[string]interface{}, len(msg.ApplicationProperties)),
+			Body:    msg.GetData(),
+		},
+	}
+	if d.Body == nil {
+		switch value := msg.Value.(type) {
+		case []byte:
+			d.Body = value
+		case string:
+			d.Body = []byte(value)
+		}
+	}
+	if header := msg.Header; header != nil {
+		d.Priority = header.Priority
+		d.Redelivered = header.DeliveryCount > 0
+	}
+	if props := msg.Properties; props != nil {
+		if props.MessageID != nil {
+			d.ID = fmt.Sprint(props.MessageID)
+		}
+		if props.Subject != nil {
+			d.Type = *props.Subject
+		}
+		if props.ContentType != nil {
+			d.ContentType = *props.ContentType
+		}
+		if props.GroupID != nil {
+			d.Key = *props.GroupID
+		}
+		if props.CreationTime != nil {
+			d.Timestamp = *props.CreationTime
+		}
+	}
+	if enqueued, ok := msg.Annotations[amqp10EnqueuedTime].(time.Time); ok && d.Timestamp.IsZero() {
+		d.Timestamp = enqueued
+	}
+	for key, value := range msg.ApplicationProperties {
+		d.Headers[key] = value
+	}
+
+	d.ack = func() error {
+		return receiver.AcceptMessage(context.Background(), msg)
+	}
+	d.reject = func(ctx context.Context, reason string) error {
+		return receiver.RejectMessage(ctx, msg, &amqp.Error{
+			Condition:   amqp10DeadLetterCondition,
+			Description: reason,
+			Info: map[string]any{
+				amqp10DeadLetterReason: reason,
+				amqp10DeadLetterDetail: "dead-lettered by salva",
+				DeadLetterReasonHeader: reason,
+			},
+		})
+	}
+	d.nack = func(requeue bool) error {
+		if requeue {
+			return receiver.ModifyMessage(context.Background(), msg, &amqp.ModifyMessageOptions{DeliveryFailed: true})
+		}
+		return d.reject(context.Background(), "rejected")
+	}
+	return d
+}
@@ -0,0 +1,313 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka topic strategies, as set in transport.kafka.topic_strategy
+const (
+	// TopicPerTenant gives every tenant queue a topic of its own
+	TopicPerTenant = "tenant_topic"
+	// TopicPartitionKey publishes every tenant to one shared topic keyed by
+	// tenant ID, so a tenant's messages stay on one partition and in order
+	TopicPartitionKey = "partition_key"
+)
+
+// KafkaOptions configures the Kafka transport
+type KafkaOptions struct {
+	Brokers []string
+	// TopicStrategy is TopicPerTenant or TopicPartitionKey
+	TopicStrategy string
+	// TopicPrefix starts the topic names of TopicPerTenant
+	TopicPrefix string
+	// SharedTopic is the topic of TopicPartitionKey
+	SharedTopic string
+	// GroupPrefix starts the name of every consumer group
+	GroupPrefix string
+	// Partitions and ReplicationFactor are used for topics Declare creates
+	Partitions        int
+	ReplicationFactor int
+}
+
+// Kafka is the transport over a Kafka cluster. Nacked messages are
+// republished to their topic to be retried, dead letters go to the topic with
+// the .dlq suffix. Offsets are committed in order, so a message is only
+// committed once every message before it on its partition was settled.
+type Kafka struct {
+	opts   KafkaOptions
+	client *kafka.Client
+	writer *kafka.Writer
+}
+
+// NewKafka creates a transport on the brokers of opts. Connections are made
+// on first use.
+func NewKafka(opts KafkaOptions) *Kafka {
+	addr := kafka.TCP(opts.Brokers...)
+	return &Kafka{
+		opts:   opts,
+		client: &kafka.Client{Addr: addr, Timeout: 10 * time.Second},
+		writer: &kafka.Writer{
+			Addr:         addr,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+func (k *Kafka) Kind() string {
+	return KindKafka
+}
+
+// Destination is the tenant's own topic, or the shared topic keyed by tenant
+// ID with the partition_key strategy. Channel queues keep their queue name as
+// suffix so they stay apart from the main queue.
+func (k *Kafka) Destination(tenantID, queue string) (string, string) {
+	if k.opts.TopicStrategy == TopicPartitionKey {
+		return k.opts.SharedTopic, tenantID
+	}
+	return k.opts.TopicPrefix + queue, ""
+}
+
+func (k *Kafka) Declare(ctx context.Context, destination string) error {
+	topics := []kafka.TopicConfig{}
+	for _, topic := range []string{destination, destination + deadLetterSuffix} {
+		topics = append(topics, kafka.TopicConfig{
+			Topic:             topic,
+			NumPartitions:     k.opts.Partitions,
+			ReplicationFactor: k.opts.ReplicationFactor,
+		})
+	}
+	res, err := k.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("failed to create topic %s: %w", destination, err)
+	}
+	for topic, err := range res.Errors {
+		if err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+			return fmt.Errorf("failed to create topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+func (k *Kafka) Delete(ctx context.Context, destination string) error {
+	res, err := k.client.DeleteTopics(ctx, &kafka.DeleteTopicsRequest{
+		Topics: []string{destination, destination + deadLetterSuffix},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete topic %s: %w", destination, err)
+	}
+	for topic, err := range res.Errors {
+		if err != nil && !errors.Is(err, kafka.UnknownTopicOrPartition) {
+			return fmt.Errorf("failed to delete topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// Publish writes msg to the topic destination. The ID, type and content type
+// travel as record headers; header values that are not strings are JSON.
+func (k *Kafka) Publish(ctx context.Context, destination string, msg Message) error {
+	record := kafka.Message{
+		Topic: destination,
+		Value: msg.Body,
+		Time:  msg.Timestamp,
+		Headers: []kafka.Header{
+			{Key: idHeader, Value: []byte(msg.ID)},
+			{Key: typeHeader, Value: []byte(msg.Type)},
+			{Key: contentTypeHeader, Value: []byte(msg.ContentType)},
+		},
+	}
+	if msg.Key != "" {
+		record.Key = []byte(msg.Key)
+	}
+	for key, value := range msg.Headers {
+		encoded, err := encodeHeader(key, value)
+		if err != nil {
+			return err
+		}
+		record.Headers = append(record.Headers, kafka.Header{Key: key, Value: []byte(encoded)})
+	}
+	return k.writer.WriteMessages(ctx, record)
+}
+
+// Consume reads the topic source in the consumer group. A requeued message
+// is published again to the end of source, with Redelivered set.
+func (k *Kafka) Consume(ctx context.Context, source, group string, prefetch int) (<-chan Delivery, error) {
+	if prefetch < 1 {
+		prefetch = 1
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  k.opts.Brokers,
+		GroupID:  k.opts.GroupPrefix + group,
+		Topic:    source,
+		MinBytes: 1,
+		MaxBytes: 10 << 20,
+	})
+
+	deliveries := make(chan Delivery)
+	offsets := newOffsetTracker(reader)
+	// Slot menahan fetch selama prefetch delivery belum selesai
+	slots := make(chan struct{}, prefetch)
+	go func() {
+		defer close(deliveries)
+		defer reader.Close()
+		for {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			record, err := reader.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to fetch Kafka message", "topic", source, "error", err)
+				}
+				return
+			}
+			offsets.fetched(record)
+
+			var once sync.Once
+			settle := func() error {
+				var err error
+				once.Do(func() {
+					<-slots
+					err = offsets.settled(record)
+				})
+				return err
+			}
+			delivery := Delivery{
+				Message:     kafkaMessage(record),
+				Redelivered: headerValue(record, redeliveredHeader) != "",
+				ack:         settle,
+			}
+			delivery.nack = func(requeue bool) error {
+				if requeue {
+					msg := delivery.Message
+					msg.Headers = withHeader(msg.Headers, redeliveredHeader, "true")
+					if err := k.Publish(context.Background(), source, msg); err != nil {
+						return err
+					}
+					return settle()
+				}
+				return k.DeadLetter(context.Background(), source, delivery, "rejected")
+			}
+
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// DeadLetter publishes d to the dead-letter topic of source with reason in
+// its headers and commits it
+func (k *Kafka) DeadLetter(ctx context.Context, source string, d Delivery, reason string) error {
+	msg := d.Message
+	msg.Headers = withHeader(msg.Headers, DeadLetterReasonHeader, reason)
+	if err := k.Publish(ctx, source+deadLetterSuffix, msg); err != nil {
+		return err
+	}
+	return d.ack()
+}
+
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
+
+// kafkaMessage converts a consumed record back into a Message
+func kafkaMessage(record kafka.Message) Message {
+	msg := Message{
+		ID:          headerValue(record, idHeader),
+		Type:        headerValue(record, typeHeader),
+		ContentType: headerValue(record, contentTypeHeader),
+		Key:         string(record.Key),
+		Headers:     make(map[string]interface{}, len(record.Headers)),
+		Timestamp:   record.Time,
+		Body:        record.Value,
+	}
+	for _, header := range record.Headers {
+		switch header.Key {
+		case idHeader, typeHeader, contentTypeHeader, redeliveredHeader:
+		default:
+			msg.Headers[header.Key] = string(header.Value)
+		}
+	}
+	return msg
+}
+
+func headerValue(record kafka.Message, key string) string {
+	for _, header := range record.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// offsetTracker commits the offsets of a reader in order. Kafka commits a
+// position, not single messages, so a message settled before an earlier one
+// on its partition is only committed together with it.
+type offsetTracker struct {
+	reader *kafka.Reader
+
+	mu sync.Mutex
+	// pending holds the fetched offsets of each partition not yet committed, in order
+	pending map[int][]int64
+	// done holds the settled offsets still waiting for an earlier one
+	done map[int]map[int64]kafka.Message
+}
+
+func newOffsetTracker(reader *kafka.Reader) *offsetTracker {
+	return &offsetTracker{
+		reader:  reader,
+		pending: make(map[int][]int64),
+		done:    make(map[int]map[int64]kafka.Message),
+	}
+}
+
+func (t *offsetTracker) fetched(record kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[record.Partition] = append(t.pending[record.Partition], record.Offset)
+}
+
+// settled marks record as processed and commits every settled offset at the
+// front of its partition. The commit is made under the lock so commits of a
+// partition never overtake each other.
+func (t *offsetTracker) settled(record kafka.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	partition := record.Partition
+	if t.done[partition] == nil {
+		t.done[partition] = make(map[int64]kafka.Message)
+	}
+	t.done[partition][record.Offset] = record
+
+	var last *kafka.Message
+	pending := t.pending[partition]
+	for len(pending) > 0 {
+		settled, ok := t.done[partition][pending[0]]
+		if !ok {
+			break
+		}
+		delete(t.done[partition], pending[0])
+		pending = pending[1:]
+		last = &settled
+	}
+	t.pending[partition] = pending
+
+	if last == nil {
+		return nil
+	}
+	return t.reader.CommitMessages(context.Background(), *last)
+}
@@ -0,0 +1,222 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSOptions configures the NATS JetStream transport
+type NATSOptions struct {
+	URL string
+	// SubjectPrefix starts the subject of every tenant queue
+	SubjectPrefix string
+	// StreamPrefix starts the name of every tenant stream
+	StreamPrefix string
+	// DurablePrefix starts the name of every durable consumer
+	DurablePrefix string
+	// Replicas is used for streams Declare creates
+	Replicas int
+	// AckWait is how long a delivery may stay unsettled before it is redelivered
+	AckWait time.Duration
+}
+
+// NATS is the transport over NATS JetStream. Every tenant queue has a stream
+// of its own holding its subject and the subject with the .dlq suffix, which
+// dead letters are published to. Each tenant consumes through a durable pull
+// consumer, so unacked messages survive restarts.
+type NATS struct {
+	opts NATSOptions
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// NewNATS connects to the server at opts.URL. The connection is re-established
+// by the client when it drops.
+func NewNATS(opts NATSOptions) (*NATS, error) {
+	conn, err := nats.Connect(opts.URL, nats.Name("salva"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	return &NATS{opts: opts, conn: conn, js: js}, nil
+}
+
+func (n *NATS) Kind() string {
+	return KindNATS
+}
+
+// Destination is the subject of the tenant queue
+func (n *NATS) Destination(tenantID, queue string) (string, string) {
+	return n.opts.SubjectPrefix + queue, ""
+}
+
+// Declare creates the stream of destination, or updates its replicas
+func (n *NATS) Declare(ctx context.Context, destination string) error {
+	_, err := n.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     n.streamName(destination),
+		Subjects: []string{destination, destination + deadLetterSuffix},
+		Storage:  jetstream.FileStorage,
+		Replicas: n.opts.Replicas,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stream for %s: %w", destination, err)
+	}
+	return nil
+}
+
+func (n *NATS) Delete(ctx context.Context, destination string) error {
+	err := n.js.DeleteStream(ctx, n.streamName(destination))
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("failed to delete stream for %s: %w", destination, err)
+	}
+	return nil
+}
+
+// Publish stores msg in the stream of destination. The ID, type and content
+// type travel as headers; header values that are not strings are JSON.
+func (n *NATS) Publish(ctx context.Context, destination string, msg Message) error {
+	record := nats.NewMsg(destination)
+	record.Data = msg.Body
+	record.Header.Set(idHeader, msg.ID)
+	record.Header.Set(typeHeader, msg.Type)
+	record.Header.Set(contentTypeHeader, msg.ContentType)
+	for key, value := range msg.Headers {
+		encoded, err := encodeHeader(key, value)
+		if err != nil {
+			return err
+		}
+		record.Header.Set(key, encoded)
+	}
+	_, err := n.js.PublishMsg(ctx, record)
+	return err
+}
+
+// Consume pulls the subject source through the durable consumer of group.
+// At most prefetch messages are unacked; a requeued message is redelivered
+// by the server.
+func (n *NATS) Consume(ctx context.Context, source, group string, prefetch int) (<-chan Delivery, error) {
+	if prefetch < 1 {
+		prefetch = 1
+	}
+	consumer, err := n.js.CreateOrUpdateConsumer(ctx, n.streamName(source), jetstream.ConsumerConfig{
+		Durable:       n.opts.DurablePrefix + group,
+		FilterSubject: source,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       n.opts.AckWait,
+		MaxAckPending: prefetch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer for %s: %w", source, err)
+	}
+	messages, err := consumer.Messages(jetstream.PullMaxMessages(prefetch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume %s: %w", source, err)
+	}
+
+	deliveries := make(chan Delivery)
+	go func() {
+		<-ctx.Done()
+		messages.Stop()
+	}()
+	go func() {
+		defer close(deliveries)
+		for {
+			msg, err := messages.Next()
+			if err != nil {
+				if errors.Is(err, jetstream.ErrMsgIteratorClosed) || ctx.Err() != nil {
+					return
+				}
+				// Heartbeat yang hilang saat reconnect, iterator tetap jalan
+				slog.Warn("Failed to pull NATS message", "subject", source, "error", err)
+				continue
+			}
+
+			delivery := natsDelivery(msg)
+			delivery.nack = func(requeue bool) error {
+				if requeue {
+					return msg.Nak()
+				}
+				return n.DeadLetter(context.Background(), source, delivery, "rejected")
+			}
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				// Belum diproses, kirim ulang segera daripada menunggu AckWait
+				msg.Nak()
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// DeadLetter publishes d to the dead-letter subject of source with reason in
+// its headers and acks it
+func (n *NATS) DeadLetter(ctx context.Context, source string, d Delivery, reason string) error {
+	msg := d.Message
+	msg.Headers = withHeader(msg.Headers, DeadLetterReasonHeader, reason)
+	if err := n.Publish(ctx, source+deadLetterSuffix, msg); err != nil {
+		return err
+	}
+	return d.ack()
+}
+
+// Connected reports whether the connection to the server is up
+func (n *NATS) Connected() bool {
+	return n.conn.IsConnected()
+}
+
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
+
+// streamName is the stream of subject; stream names cannot contain dots,
+// wildcards, slashes or whitespace
+func (n *NATS) streamName(subject string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, strings.TrimSuffix(subject, deadLetterSuffix))
+	return n.opts.StreamPrefix + name
+}
+
+// natsDelivery converts a consumed message into a Delivery that acks it
+func natsDelivery(msg jetstream.Msg) Delivery {
+	headers := msg.Headers()
+	d := Delivery{
+		Message: Message{
+			ID:          headers.Get(idHeader),
+			Type:        headers.Get(typeHeader),
+			ContentType: headers.Get(contentTypeHeader),
+			Headers:     make(map[string]interface{}, len(headers)),
+			Body:        msg.Data(),
+		},
+		ack: msg.Ack,
+	}
+	for key := range headers {
+		switch key {
+		case idHeader, typeHeader, contentTypeHeader:
+		default:
+			d.Headers[key] = headers.Get(key)
+		}
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		d.Timestamp = meta.Timestamp
+		d.Redelivered = meta.NumDelivered > 1
+	}
+	return d
+}
@@ -0,0 +1,125 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"multi-tenant-messaging/internal/repository"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitMQ is the transport over the broker pool. Destinations are queues on
+// the default exchange; tenant queues dead-letter through their own
+// dead-letter exchange arguments, which the service declares.
+type RabbitMQ struct {
+	rabbit *repository.RabbitMQ
+}
+
+// NewRabbitMQ creates a transport on rabbit
+func NewRabbitMQ(rabbit *repository.RabbitMQ) *RabbitMQ {
+	return &RabbitMQ{rabbit: rabbit}
+}
+
+func (r *RabbitMQ) Kind() string {
+	return KindRabbitMQ
+}
+
+// Destination is queue itself; a queue belongs to a single tenant
+func (r *RabbitMQ) Destination(tenantID, queue string) (string, string) {
+	return queue, ""
+}
+
+// Declare declares destination as a durable queue without arguments
+func (r *RabbitMQ) Declare(ctx context.Context, destination string) error {
+	if _, err := r.rabbit.Channel().QueueDeclare(destination, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", destination, err)
+	}
+	return nil
+}
+
+func (r *RabbitMQ) Delete(ctx context.Context, destination string) error {
+	if _, err := r.rabbit.Channel().QueueDelete(destination, false, false, false); err != nil {
+		return fmt.Errorf("failed to delete queue %s: %w", destination, err)
+	}
+	return nil
+}
+
+// Publish publishes msg persistently to the queue destination
+func (r *RabbitMQ) Publish(ctx context.Context, destination string, msg Message) error {
+	return r.rabbit.Channel().PublishWithContext(ctx, "", destination, false, false, amqp.Publishing{
+		Headers:      amqp.Table(msg.Headers),
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.ID,
+		Type:         msg.Type,
+		Priority:     msg.Priority,
+		Timestamp:    msg.Timestamp,
+		Body:         msg.Body,
+	})
+}
+
+// Consume consumes the queue source on a channel of its own; group is not
+// needed as every consumer of a queue shares its messages
+func (r *RabbitMQ) Consume(ctx context.Context, source, group string, prefetch int) (<-chan Delivery, error) {
+	ch, err := r.rabbit.OpenChannel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+	msgs, err := ch.Consume(source, "", false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to consume messages: %w", err)
+	}
+
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		defer ch.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-msgs:
+				if !ok {
+					return
+				}
+				delivery := Delivery{
+					Message: Message{
+						ID:          d.MessageId,
+						Type:        d.Type,
+						ContentType: d.ContentType,
+						Headers:     d.Headers,
+						Priority:    d.Priority,
+						Timestamp:   d.Timestamp,
+						Body:        d.Body,
+					},
+					Redelivered: d.Redelivered,
+					ack:         func() error { return d.Ack(false) },
+					nack:        func(requeue bool) error { return d.Nack(false, requeue) },
+				}
+				select {
+				case deliveries <- delivery:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// DeadLetter rejects d so the queue's dead-letter exchange routes it; the
+// broker records the reason in the x-death header
+func (r *RabbitMQ) DeadLetter(ctx context.Context, source string, d Delivery, reason string) error {
+	return d.Nack(false)
+}
+
+// Close leaves the broker pool open; it is closed by its owner
+func (r *RabbitMQ) Close() error {
+	return nil
+}
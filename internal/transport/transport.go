@@ -0,0 +1,120 @@
+// Package transport abstracts the broker tenant messages are published to and
+// consumed from, so a deployment can run on RabbitMQ, Kafka, NATS JetStream or an AMQP 1.0
+// broker such as Azure Service Bus or ActiveMQ Artemis.
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Transport kinds, as set in transport.kind
+const (
+	KindRabbitMQ = "rabbitmq"
+	KindKafka    = "kafka"
+	KindNATS     = "nats"
+	KindAMQP10   = "amqp10"
+)
+
+// Message is a message as published to or consumed from a transport
+type Message struct {
+	ID          string
+	Type        string
+	ContentType string
+	// Key keeps messages in order: messages with the same key are consumed in
+	// the order they were published. Transports without keys ignore it.
+	Key     string
+	Headers map[string]interface{}
+	// Priority from 0 to 9 orders messages on RabbitMQ priority queues and in
+	// the tenant's worker pool. Transports without priorities ignore it.
+	Priority  uint8
+	Timestamp time.Time
+	Body      []byte
+}
+
+// Delivery is a consumed message. Exactly one of Ack, Nack and
+// Transport.DeadLetter must be called for it.
+type Delivery struct {
+	Message
+	// Redelivered is set when the message may have been consumed before
+	Redelivered bool
+
+	ack  func() error
+	nack func(requeue bool) error
+	// reject dead-letters the delivery on brokers that do it natively
+	reject func(ctx context.Context, reason string) error
+}
+
+// Ack settles the delivery as processed
+func (d Delivery) Ack() error {
+	return d.ack()
+}
+
+// Nack settles the delivery as failed; requeue puts it back to be consumed
+// again, otherwise it goes to the dead-letter destination
+func (d Delivery) Nack(requeue bool) error {
+	return d.nack(requeue)
+}
+
+// Transport publishes and consumes messages on named destinations: queues on
+// RabbitMQ, topics on Kafka, subjects on NATS, addresses on AMQP 1.0
+type Transport interface {
+	// Kind is KindRabbitMQ, KindKafka, KindNATS or KindAMQP10
+	Kind() string
+	// Destination names where the tenant's messages for queue go and the key
+	// that keeps them in order, empty when the destination is the tenant's own
+	Destination(tenantID, queue string) (name, key string)
+	// Declare creates destination and its dead-letter destination if missing
+	Declare(ctx context.Context, destination string) error
+	// Delete removes destination, its dead-letter destination and their messages
+	Delete(ctx context.Context, destination string) error
+	Publish(ctx context.Context, destination string, msg Message) error
+	// Consume delivers the messages of source to the consumer group until ctx
+	// is done, then closes the channel. At most prefetch deliveries are
+	// unsettled at a time.
+	Consume(ctx context.Context, source, group string, prefetch int) (<-chan Delivery, error)
+	// DeadLetter moves d to the dead-letter destination of source with reason
+	// and settles it
+	DeadLetter(ctx context.Context, source string, d Delivery, reason string) error
+	Close() error
+}
+
+// DeadLetterReasonHeader carries why a message was dead-lettered
+const DeadLetterReasonHeader = "x-salva-dead-letter-reason"
+
+// Headers carrying the message properties AMQP has fields for, on transports
+// without them
+const (
+	idHeader          = "message-id"
+	typeHeader        = "type"
+	contentTypeHeader = "content-type"
+	redeliveredHeader = "x-salva-redelivered"
+)
+
+// deadLetterSuffix names the dead-letter destination of a topic or subject
+const deadLetterSuffix = ".dlq"
+
+// encodeHeader returns a header value as a string; values that are not
+// strings are JSON
+func encodeHeader(key string, value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid header %s: %w", key, err)
+	}
+	return string(encoded), nil
+}
+
+// withHeader returns a copy of headers with key set to value
+func withHeader(headers map[string]interface{}, key, value string) map[string]interface{} {
+	copied := make(map[string]interface{}, len(headers)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
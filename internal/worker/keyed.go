@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// Dispatcher runs tasks for a tenant consumer
+type Dispatcher interface {
+	// Dispatch queues a task; key and priority are hints the dispatcher may use
+	Dispatch(key string, priority uint8, task Task)
+	Run(ctx context.Context)
+}
+
+// Dispatch implements Dispatcher; the key is ignored and priority is honored
+func (p *WorkerPool) Dispatch(key string, priority uint8, task Task) {
+	p.SubmitTaskPriority(priority, task)
+}
+
+// KeyedPool hashes each task's key to one of a fixed number of lanes. Every
+// lane has a single worker, so tasks with the same key run in submission
+// order while different keys run in parallel.
+type KeyedPool struct {
+	lanes []chan Task
+	done  chan struct{}
+}
+
+func NewKeyedPool(lanes int) *KeyedPool {
+	if lanes < 1 {
+		lanes = 1
+	}
+	pool := &KeyedPool{lanes: make([]chan Task, lanes), done: make(chan struct{})}
+	for i := range pool.lanes {
+		pool.lanes[i] = make(chan Task, 1024)
+		go pool.lane(i+1, pool.lanes[i])
+	}
+	return pool
+}
+
+func (p *KeyedPool) lane(id int, tasks chan Task) {
+	for {
+		select {
+		case <-p.done:
+			return
+		case task := <-tasks:
+			task(id)
+		}
+	}
+}
+
+// Dispatch implements Dispatcher; priority is ignored to preserve key order.
+// Tasks dispatched once the pool is stopped are dropped.
+func (p *KeyedPool) Dispatch(key string, priority uint8, task Task) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	select {
+	case p.lanes[h.Sum32()%uint32(len(p.lanes))] <- task:
+	case <-p.done:
+	}
+}
+
+// Run stops the lanes once ctx is done; the tasks they run finish first
+func (p *KeyedPool) Run(ctx context.Context) {
+	<-ctx.Done()
+	close(p.done)
+}
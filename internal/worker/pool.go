@@ -2,18 +2,47 @@ package worker
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 )
 
+// PriorityLevels is the number of task priority levels (AMQP priorities 0-9)
+const PriorityLevels = 10
+
+// Task is a unit of work that receives the ID of the worker running it
+type Task func(workerID int)
+
+// Resizer is a Dispatcher whose number of workers can change while it runs
+type Resizer interface {
+	SetSize(size int)
+}
+
+// WorkerPool runs tasks on a resizable set of workers. Tasks are queued per
+// priority level and workers always take the highest priority task available.
+// Once Run returns, queued tasks are dropped and new ones are ignored.
 type WorkerPool struct {
-	taskChan    chan func()
-	workerCount int32
+	queueMu sync.Mutex
+	queues  [PriorityLevels][]Task
+	// ready holds one token per queued task
+	ready chan struct{}
+	// retire tells as many workers to exit as it receives tokens
+	retire chan struct{}
+	done   chan struct{}
+	nextID int32
+
+	mu   sync.Mutex
+	size int
 }
 
 func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
 	pool := &WorkerPool{
-		taskChan:    make(chan func(), 1024),
-		workerCount: int32(size),
+		ready:  make(chan struct{}, 1024*PriorityLevels),
+		retire: make(chan struct{}),
+		done:   make(chan struct{}),
+		size:   size,
 	}
 
 	for i := 0; i < size; i++ {
@@ -24,33 +53,102 @@ func NewWorkerPool(size int) *WorkerPool {
 }
 
 func (p *WorkerPool) worker() {
-	for task := range p.taskChan {
-		task()
+	id := int(atomic.AddInt32(&p.nextID, 1))
+	for {
+		// Worker hanya berhenti di antara task, jadi task yang berjalan selalu selesai
+		select {
+		case <-p.retire:
+			return
+		case <-p.done:
+			return
+		case <-p.ready:
+			p.next()(id)
+		}
 	}
 }
 
+// next takes the highest priority queued task. Every token taken from ready
+// stands for a task queued before it, so there always is one.
+func (p *WorkerPool) next() Task {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	for level := PriorityLevels - 1; level >= 0; level-- {
+		if queue := p.queues[level]; len(queue) > 0 {
+			task := queue[0]
+			queue[0] = nil
+			p.queues[level] = queue[1:]
+			return task
+		}
+	}
+	return func(int) {}
+}
+
 func (p *WorkerPool) Submit(task func()) {
-	p.taskChan <- task
+	p.SubmitTaskPriority(0, func(int) { task() })
+}
+
+// SubmitTask queues a task that needs to know which worker runs it
+func (p *WorkerPool) SubmitTask(task Task) {
+	p.SubmitTaskPriority(0, task)
 }
 
+// SubmitTaskPriority queues a task at the given priority; higher runs first.
+// It blocks while ready is full and returns without queueing once the pool
+// is stopped, so submitting may race the consumer's cancellation.
+func (p *WorkerPool) SubmitTaskPriority(priority uint8, task Task) {
+	level := int(priority)
+	if level >= PriorityLevels {
+		level = PriorityLevels - 1
+	}
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	p.queueMu.Lock()
+	p.queues[level] = append(p.queues[level], task)
+	p.queueMu.Unlock()
+	select {
+	case p.ready <- struct{}{}:
+	case <-p.done:
+	}
+}
+
+// SetSize grows or shrinks the pool to size workers, at least one, without
+// stopping the others. New workers start right away; retired workers finish
+// the task they are running before they exit.
 func (p *WorkerPool) SetSize(size int) {
-	current := atomic.LoadInt32(&p.workerCount)
-	delta := size - int(current)
+	if size < 1 {
+		size = 1
+	}
+	p.mu.Lock()
+	delta := size - p.size
+	p.size = size
+	p.mu.Unlock()
 
-	if delta > 0 {
-		for i := 0; i < delta; i++ {
-			atomic.AddInt32(&p.workerCount, 1)
-			go p.worker()
-		}
-	} else if delta < 0 {
-		for i := 0; i < -delta; i++ {
-			atomic.AddInt32(&p.workerCount, -1)
-			p.taskChan <- func() { panic("exit") } // Graceful exit
-		}
+	for i := 0; i < delta; i++ {
+		go p.worker()
 	}
+	for i := 0; i < -delta; i++ {
+		go func() {
+			select {
+			case p.retire <- struct{}{}:
+			case <-p.done:
+			}
+		}()
+	}
+}
+
+// Size is the number of workers the pool is sized to
+func (p *WorkerPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
 }
 
+// Run stops the workers once ctx is done; the tasks they run finish first
 func (p *WorkerPool) Run(ctx context.Context) {
 	<-ctx.Done()
-	close(p.taskChan)
+	close(p.done)
 }
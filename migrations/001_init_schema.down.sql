@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tenant_configs;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,13 @@
+-- Export jobs with resumable checkpoints
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id UUID PRIMARY KEY,
+    tenant_id UUID,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    checkpoint_created_at TIMESTAMPTZ,
+    checkpoint_id UUID,
+    exported_rows BIGINT NOT NULL DEFAULT 0,
+    chunk_count INT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
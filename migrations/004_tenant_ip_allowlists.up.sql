@@ -0,0 +1,7 @@
+-- Source IP allowlists for tenant-scoped endpoints (empty = allow all)
+CREATE TABLE IF NOT EXISTS tenant_ip_allowlists (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    cidr CIDR NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, cidr)
+);
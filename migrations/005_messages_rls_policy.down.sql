@@ -0,0 +1 @@
+DROP POLICY IF EXISTS tenant_isolation ON messages;
@@ -0,0 +1,14 @@
+-- Row-level security policy on messages. The policy only takes effect when
+-- RLS is enabled on the table (database.row_level_security: true).
+-- Sessions without app.tenant_id (system context) see every row; sessions
+-- with it set only see and insert rows of that tenant.
+DROP POLICY IF EXISTS tenant_isolation ON messages;
+CREATE POLICY tenant_isolation ON messages
+    USING (
+        COALESCE(current_setting('app.tenant_id', true), '') = ''
+        OR tenant_id = current_setting('app.tenant_id', true)::uuid
+    )
+    WITH CHECK (
+        COALESCE(current_setting('app.tenant_id', true), '') = ''
+        OR tenant_id = current_setting('app.tenant_id', true)::uuid
+    );
@@ -0,0 +1,8 @@
+-- Per-tenant PII redaction rules applied before messages are stored
+CREATE TABLE IF NOT EXISTS tenant_redaction_rules (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, path)
+);
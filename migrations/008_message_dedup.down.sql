@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS message_dedup;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS dedup_window_seconds;
@@ -0,0 +1,12 @@
+-- Per-tenant deduplication window (0 = disabled)
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS dedup_window_seconds INT NOT NULL DEFAULT 0;
+
+-- Message IDs seen per tenant, backing the in-memory dedup cache
+CREATE TABLE IF NOT EXISTS message_dedup (
+    tenant_id UUID NOT NULL,
+    message_id VARCHAR(255) NOT NULL,
+    seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_dedup_seen_at ON message_dedup (seen_at);
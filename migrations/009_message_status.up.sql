@@ -0,0 +1,2 @@
+-- Message status: processed by a worker, or expired in the queue before processing
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'processed';
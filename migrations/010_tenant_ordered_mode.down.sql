@@ -0,0 +1 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS ordered;
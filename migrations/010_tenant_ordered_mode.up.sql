@@ -0,0 +1,2 @@
+-- Strictly-ordered processing mode per tenant
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS ordered BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Payload field used to hash messages to ordered processing lanes
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS partition_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tenant_consumer_owners;
+DROP TABLE IF EXISTS consumer_instances;
@@ -0,0 +1,16 @@
+-- Instances consuming tenant queues, kept alive by heartbeats
+CREATE TABLE IF NOT EXISTS consumer_instances (
+    instance_id TEXT PRIMARY KEY,
+    version TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Which instance consumes each tenant; handover_to is set by an instance
+-- asking the owner to drain the tenant and pass it on
+CREATE TABLE IF NOT EXISTS tenant_consumer_owners (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    instance_id TEXT NOT NULL,
+    handover_to TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
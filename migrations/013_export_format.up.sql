@@ -0,0 +1,2 @@
+-- Output format of an export job: ndjson or parquet
+ALTER TABLE export_jobs ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'ndjson';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tenant_migrations;
+ALTER TABLE tenants DROP COLUMN IF EXISTS migrated_to;
@@ -0,0 +1,20 @@
+-- Deployment a tenant was moved to; such tenants are no longer consumed here
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS migrated_to TEXT;
+
+-- Resumable moves of a tenant to another deployment
+CREATE TABLE IF NOT EXISTS tenant_migrations (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    target TEXT NOT NULL,
+    step VARCHAR(32) NOT NULL DEFAULT 'config',
+    status VARCHAR(32) NOT NULL DEFAULT 'running',
+    checkpoint_created_at TIMESTAMPTZ,
+    checkpoint_id UUID,
+    copied_rows BIGINT NOT NULL DEFAULT 0,
+    shoveled_messages BIGINT NOT NULL DEFAULT 0,
+    source_count BIGINT,
+    target_count BIGINT,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+-- Main queue each tenant is consumed from; NULL means tenant_<id>_queue
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS queue_name TEXT;
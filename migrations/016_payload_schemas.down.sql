@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_type_created_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS schema_version;
+ALTER TABLE messages DROP COLUMN IF EXISTS message_type;
+DROP TABLE IF EXISTS tenant_schemas;
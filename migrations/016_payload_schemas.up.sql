@@ -0,0 +1,14 @@
+-- Versioned JSON Schemas per tenant and message type
+CREATE TABLE IF NOT EXISTS tenant_schemas (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    message_type TEXT NOT NULL,
+    version INT NOT NULL,
+    schema JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, message_type, version)
+);
+
+-- Message type (AMQP type property) and the schema version the payload validated against
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS message_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS schema_version INT;
+CREATE INDEX IF NOT EXISTS idx_messages_type_created_at ON messages (tenant_id, message_type, created_at);
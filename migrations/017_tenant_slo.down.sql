@@ -0,0 +1,2 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS slo_threshold_ms;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS slo_target;
@@ -0,0 +1,3 @@
+-- Per-tenant SLO overrides; NULL uses the slo.* config defaults
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS slo_target DOUBLE PRECISION;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS slo_threshold_ms INT;
@@ -0,0 +1,2 @@
+-- Ordered chain of processor names run on each of the tenant's messages
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS processors JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS tags;
+DROP TABLE IF EXISTS tenant_message_filters;
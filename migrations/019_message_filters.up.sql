@@ -0,0 +1,14 @@
+-- Per-tenant CEL filter rules evaluated in order before messages are stored
+CREATE TABLE IF NOT EXISTS tenant_message_filters (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    name TEXT NOT NULL,
+    expression TEXT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, position)
+);
+
+-- Tags added by matching filter rules
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS dlq_retry_attempts;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS dlq_retry_schedule;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS dlq_retry_enabled;
@@ -0,0 +1,15 @@
+-- Per-tenant DLQ retry overrides; NULL uses the dlq_retry.* config defaults
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS dlq_retry_enabled BOOLEAN;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS dlq_retry_schedule JSONB;
+
+-- Every retry or give-up decision of the DLQ retry scheduler
+CREATE TABLE IF NOT EXISTS dlq_retry_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    message_id TEXT NOT NULL DEFAULT '',
+    attempt INT NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dlq_retry_attempts_tenant ON dlq_retry_attempts (tenant_id, created_at DESC);
@@ -0,0 +1,12 @@
+-- Structured system events per tenant, read by seq as a cursor
+CREATE TABLE IF NOT EXISTS system_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    type VARCHAR(64) NOT NULL,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_system_events_tenant_seq ON system_events (tenant_id, seq);
+CREATE INDEX IF NOT EXISTS idx_system_events_created_at ON system_events (created_at);
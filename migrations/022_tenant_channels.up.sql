@@ -0,0 +1,14 @@
+-- Named channels of a tenant, each consumed from its own queue
+CREATE TABLE IF NOT EXISTS tenant_channels (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(63) NOT NULL,
+    queue_name TEXT NOT NULL,
+    workers INT NOT NULL DEFAULT 3,
+    ordered BOOLEAN NOT NULL DEFAULT FALSE,
+    partition_key TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, name)
+);
+
+-- Channel a message arrived on; empty for the tenant's main queue
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_tenant_cursor;
+DROP TABLE IF EXISTS consumer_groups;
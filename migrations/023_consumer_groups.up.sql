@@ -0,0 +1,13 @@
+-- Consumer groups read a tenant's stored messages oldest first, each from its
+-- own committed offset (the created_at, id cursor of the last message read)
+CREATE TABLE IF NOT EXISTS consumer_groups (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(63) NOT NULL,
+    offset_id UUID,
+    offset_created_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_tenant_cursor ON messages (tenant_id, created_at, id);
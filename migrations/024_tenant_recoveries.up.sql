@@ -0,0 +1,20 @@
+-- Backlog recoveries: the backlog of a tenant's main queue is parked in a
+-- recovery queue and drained with boosted workers in the chosen order
+CREATE TABLE IF NOT EXISTS tenant_recoveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    order_policy VARCHAR(16) NOT NULL,
+    workers INT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    backlog BIGINT NOT NULL DEFAULT 0,
+    remaining BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ DEFAULT NOW(),
+    running_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+-- At most one unfinished recovery per tenant
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_recoveries_active ON tenant_recoveries (tenant_id)
+    WHERE status IN ('parking', 'running');
+CREATE INDEX IF NOT EXISTS idx_tenant_recoveries_tenant ON tenant_recoveries (tenant_id, started_at DESC);
@@ -0,0 +1,14 @@
+-- Tenant provisioning jobs: POST /tenants creates the partition, queues and
+-- consumer in the background; the tenants row is written once they are ready
+CREATE TABLE IF NOT EXISTS tenant_provisioning_jobs (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_provisioning_jobs_pending ON tenant_provisioning_jobs (updated_at)
+    WHERE status = 'pending';
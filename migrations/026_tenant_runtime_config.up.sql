@@ -0,0 +1,9 @@
+-- Per-tenant runtime config and feature flags, used when dynamic_config.backend
+-- is postgres; changes are announced on the tenant_runtime_config channel
+CREATE TABLE IF NOT EXISTS tenant_runtime_config (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    key VARCHAR(128) NOT NULL,
+    value JSONB NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, key)
+);
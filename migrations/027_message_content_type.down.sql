@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS raw_payload;
+ALTER TABLE messages DROP COLUMN IF EXISTS content_type;
@@ -0,0 +1,4 @@
+-- Protobuf and Avro payloads keep their original bytes next to the JSON
+-- projection stored in payload
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS raw_payload BYTEA;
@@ -0,0 +1,14 @@
+-- Scoped sub-tokens minted by tenant admins, kept for listing and revocation
+-- until they expire
+CREATE TABLE IF NOT EXISTS tenant_tokens (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(128) NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_tokens_tenant ON tenant_tokens (tenant_id, expires_at);
@@ -0,0 +1,4 @@
+-- GET /messages filters on payload containment (payload @> ...). An index on
+-- the partitioned messages table is created on every tenant partition,
+-- including partitions attached later.
+CREATE INDEX IF NOT EXISTS idx_messages_payload ON messages USING GIN (payload jsonb_path_ops);
@@ -0,0 +1,4 @@
+-- GET /messages pages with a (created_at, id) row comparison against the
+-- values carried in the cursor. Listing across tenants needs the pair
+-- indexed without the tenant_id prefix of idx_messages_tenant_cursor.
+CREATE INDEX IF NOT EXISTS idx_messages_created_id ON messages (created_at, id);
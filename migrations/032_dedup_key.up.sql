@@ -0,0 +1,3 @@
+-- What a tenant's messages are deduplicated on: the AMQP message-id or a
+-- SHA-256 of the payload. Payload hashes are stored in message_dedup.message_id.
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS dedup_key TEXT NOT NULL DEFAULT 'message_id';
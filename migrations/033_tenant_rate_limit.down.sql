@@ -0,0 +1,3 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS rate_limit_consume;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS publish_burst;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS publish_rate;
@@ -0,0 +1,5 @@
+-- Token-bucket limit on a tenant's published messages; a rate of 0 disables
+-- it. With rate_limit_consume the tenant's consumers are paced as well.
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS publish_rate DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS publish_burst INT NOT NULL DEFAULT 0;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS rate_limit_consume BOOLEAN NOT NULL DEFAULT FALSE;
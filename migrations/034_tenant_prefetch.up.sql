@@ -0,0 +1,2 @@
+-- Unacked deliveries a tenant's consumer may hold; 0 uses a default per worker
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS prefetch_count INT NOT NULL DEFAULT 0;
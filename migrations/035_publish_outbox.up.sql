@@ -0,0 +1,18 @@
+-- Messages published through the outbox, written in the publisher's
+-- transaction and published to the broker by the relay
+CREATE TABLE IF NOT EXISTS publish_outbox (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    queue TEXT NOT NULL,
+    message_type TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    deliver_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_publish_outbox_pending ON publish_outbox (created_at, id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_publish_outbox_published ON publish_outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,33 @@
+-- Endpoints the messages a tenant's consumer stores are POSTed to. secret
+-- signs every delivery (see pkg/webhook)
+CREATE TABLE IF NOT EXISTS tenant_webhooks (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_webhooks_tenant ON tenant_webhooks (tenant_id);
+
+-- One row per message and webhook, written with the message and retried by
+-- the delivery workers until the endpoint accepts it
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES tenant_webhooks(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL,
+    message_id UUID NOT NULL,
+    message_type TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at DESC);
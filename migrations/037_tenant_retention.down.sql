@@ -0,0 +1,3 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retention_archive;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retention_max_rows;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retention_max_age_seconds;
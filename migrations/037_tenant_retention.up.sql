@@ -0,0 +1,6 @@
+-- How long a tenant's stored messages are kept and how many of them; 0
+-- keeps them forever. Expired messages are deleted, or with
+-- retention_archive moved to the tenant's messages_archive_* table.
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retention_max_age_seconds INT NOT NULL DEFAULT 0;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retention_max_rows BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retention_archive BOOLEAN NOT NULL DEFAULT FALSE;
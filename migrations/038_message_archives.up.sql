@@ -0,0 +1,19 @@
+-- Manifest of the gzipped NDJSON objects the archiver moved a tenant's
+-- messages to, one per tenant and day. columns lists the keys of each line.
+CREATE TABLE IF NOT EXISTS message_archives (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    day DATE NOT NULL,
+    bucket TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    message_count BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    columns JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    restored_at TIMESTAMPTZ,
+    restored_count BIGINT NOT NULL DEFAULT 0,
+    -- Restored messages are not archived again before this
+    held_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_archives_tenant_day ON message_archives (tenant_id, day DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retry_jitter;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retry_max_backoff_seconds;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retry_backoff_seconds;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS retry_max_attempts;
@@ -0,0 +1,6 @@
+-- Per-tenant retry policy for transient failures; NULL uses the retry.*
+-- config defaults
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retry_max_attempts INT;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retry_backoff_seconds INT;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retry_max_backoff_seconds INT;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS retry_jitter DOUBLE PRECISION;
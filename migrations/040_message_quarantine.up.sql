@@ -0,0 +1,25 @@
+-- Poison failures of a message (a panic, an undecodable payload or one that
+-- fails schema validation), one row per tenant and message. Once a message
+-- reaches quarantine.threshold failures quarantined_at is set and it is
+-- taken off the broker.
+CREATE TABLE IF NOT EXISTS message_quarantine (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    -- message_key is the AMQP message ID, or a hash of the payload without one
+    message_key TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    channel TEXT NOT NULL DEFAULT '',
+    message_type TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT '',
+    headers JSONB NOT NULL DEFAULT '{}',
+    payload BYTEA,
+    reason VARCHAR(32) NOT NULL,
+    error TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    quarantined_at TIMESTAMPTZ,
+    UNIQUE (tenant_id, message_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_quarantine_quarantined ON message_quarantine (tenant_id, quarantined_at DESC) WHERE quarantined_at IS NOT NULL;
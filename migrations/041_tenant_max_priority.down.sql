@@ -0,0 +1,2 @@
+ALTER TABLE publish_outbox DROP COLUMN IF EXISTS priority;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS max_priority;
@@ -0,0 +1,6 @@
+-- Highest AMQP priority a tenant's main queue orders messages by; 0 is a
+-- plain queue
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS max_priority INT NOT NULL DEFAULT 0;
+
+-- Priority the message is published with
+ALTER TABLE publish_outbox ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS max_workers;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS min_workers;
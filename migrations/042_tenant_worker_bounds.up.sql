@@ -0,0 +1,4 @@
+-- Per-tenant worker bounds set by an admin; NULL uses 1 and
+-- concurrency.max_tenant_workers
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS min_workers INT;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS max_workers INT;
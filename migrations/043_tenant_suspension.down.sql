@@ -0,0 +1,2 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS suspend_reason;
+ALTER TABLE tenants DROP COLUMN IF EXISTS suspended_at;
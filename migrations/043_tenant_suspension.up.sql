@@ -0,0 +1,3 @@
+-- Suspended tenants are not consumed; their queue and data are kept
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspend_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS quota_max_queue_depth;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS quota_max_bytes;
+ALTER TABLE tenant_configs DROP COLUMN IF EXISTS quota_max_messages;
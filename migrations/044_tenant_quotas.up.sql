@@ -0,0 +1,5 @@
+-- Per-tenant quotas set by an admin; NULL uses the quotas.* config defaults
+-- and 0 leaves a limit off
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS quota_max_messages BIGINT;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS quota_max_bytes BIGINT;
+ALTER TABLE tenant_configs ADD COLUMN IF NOT EXISTS quota_max_queue_depth BIGINT;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_audit_logs_actor;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS new_values;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS old_values;
@@ -0,0 +1,6 @@
+-- Values an audited operation changed, and indexes for the GET /audit filters
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS old_values JSONB;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS new_values JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_id ON audit_logs (tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs (actor, created_at DESC);
@@ -0,0 +1,10 @@
+DROP POLICY IF EXISTS tenant_isolation ON messages;
+CREATE POLICY tenant_isolation ON messages
+    USING (
+        COALESCE(current_setting('app.tenant_id', true), '') = ''
+        OR tenant_id = current_setting('app.tenant_id', true)::uuid
+    )
+    WITH CHECK (
+        COALESCE(current_setting('app.tenant_id', true), '') = ''
+        OR tenant_id = current_setting('app.tenant_id', true)::uuid
+    );
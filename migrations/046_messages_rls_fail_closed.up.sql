@@ -0,0 +1,15 @@
+-- The tenant_isolation policy no longer lets sessions without app.tenant_id
+-- see every row. Such sessions now see none, unless they opt in as a system
+-- session with app.rls_bypass = 'on' (the service sets it on its own
+-- connections and turns it off again for tenant-scoped transactions) or
+-- connect as a role with BYPASSRLS.
+DROP POLICY IF EXISTS tenant_isolation ON messages;
+CREATE POLICY tenant_isolation ON messages
+    USING (
+        current_setting('app.rls_bypass', true) = 'on'
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid
+    )
+    WITH CHECK (
+        current_setting('app.rls_bypass', true) = 'on'
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid
+    );
@@ -0,0 +1,2 @@
+-- When a scheduled message was due, set for messages published with a delay or deliver_at
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deliver_at TIMESTAMPTZ;
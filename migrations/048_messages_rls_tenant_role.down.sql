@@ -0,0 +1,16 @@
+DROP POLICY IF EXISTS tenant_isolation ON messages;
+CREATE POLICY tenant_isolation ON messages
+    USING (
+        current_setting('app.rls_bypass', true) = 'on'
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid
+    )
+    WITH CHECK (
+        current_setting('app.rls_bypass', true) = 'on'
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid
+    );
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE ALL ON TABLES FROM salva_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE ALL ON SEQUENCES FROM salva_tenant;
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM salva_tenant;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM salva_tenant;
+DROP ROLE IF EXISTS salva_tenant;
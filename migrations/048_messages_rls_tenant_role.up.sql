@@ -0,0 +1,22 @@
+-- Tenant-scoped transactions switch to the salva_tenant role, which has no
+-- BYPASSRLS, instead of turning off an app.rls_bypass setting that any
+-- session could turn back on. The policy no longer looks at that setting:
+-- only app.tenant_id grants rows, and system jobs see every row because the
+-- service connects as a role with BYPASSRLS (or a superuser).
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'salva_tenant') THEN
+        CREATE ROLE salva_tenant NOLOGIN NOBYPASSRLS;
+    END IF;
+END
+$$;
+GRANT salva_tenant TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO salva_tenant;
+GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA public TO salva_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO salva_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO salva_tenant;
+
+DROP POLICY IF EXISTS tenant_isolation ON messages;
+CREATE POLICY tenant_isolation ON messages
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL schema migrations so the server binary can
+// apply them and report schema status without the files on disk.
+package migrations
+
+import "embed"
+
+// FS holds the NNN_name.up.sql migration files and the NNN_name.down.sql
+// files reverting them
+//
+//go:embed *.up.sql *.down.sql
+var FS embed.FS
@@ -0,0 +1,163 @@
+// Package consumergroup reads a tenant's processed messages through a
+// consumer group of the messaging API.
+//
+// Every consumer group keeps its own committed offset, so several
+// applications can read the same messages independently:
+//
+//	c := consumergroup.NewClient("https://salva.example.com", tenantID, "billing", token)
+//	err := c.Consume(ctx, func(ctx context.Context, msgs []consumergroup.Message) error {
+//		// A returned error stops Consume without committing, so the batch is read again
+//		return handle(msgs)
+//	})
+//
+// The group must exist; create it with POST /tenants/{id}/consumer-groups.
+package consumergroup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Message is a processed message read by a consumer group
+type Message struct {
+	ID            string          `json:"id"`
+	TenantID      string          `json:"tenant_id"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	MessageType   string          `json:"message_type"`
+	SchemaVersion *int            `json:"schema_version"`
+	Tags          []string        `json:"tags"`
+	Channel       string          `json:"channel"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Batch is the response of a fetch
+type Batch struct {
+	Data []Message `json:"data"`
+	// NextOffset is the offset to commit once Data is processed
+	NextOffset string `json:"next_offset"`
+	Committed  bool   `json:"committed"`
+}
+
+// Client reads messages of one consumer group
+type Client struct {
+	baseURL  string
+	tenantID string
+	group    string
+	token    string
+
+	// HTTPClient sends the requests; http.DefaultClient if nil
+	HTTPClient *http.Client
+	// BatchSize is the number of messages fetched at once (default 100)
+	BatchSize int
+	// PollInterval is how long Consume waits after an empty fetch (default 1s)
+	PollInterval time.Duration
+}
+
+// NewClient creates a Client for the group of a tenant. token is the bearer
+// token sent with every request; leave it empty if authentication is disabled.
+func NewClient(baseURL, tenantID, group, token string) *Client {
+	return &Client{
+		baseURL:      baseURL,
+		tenantID:     tenantID,
+		group:        group,
+		token:        token,
+		BatchSize:    100,
+		PollInterval: time.Second,
+	}
+}
+
+// Fetch returns the next batch after the group's offset without committing it
+func (c *Client) Fetch(ctx context.Context) (*Batch, error) {
+	query := url.Values{"limit": {strconv.Itoa(c.BatchSize)}}
+	var batch Batch
+	if err := c.do(ctx, http.MethodGet, "/messages?"+query.Encode(), nil, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// Commit moves the group's offset to the given message ID
+func (c *Client) Commit(ctx context.Context, offset string) error {
+	return c.do(ctx, http.MethodPost, "/commit", map[string]string{"offset": offset}, nil)
+}
+
+// Consume fetches batches, passes them to handle and commits each one after
+// handle returns nil, until ctx is done or handle or a request fails.
+// Messages are delivered at least once: a batch whose commit fails is read again.
+func (c *Client) Consume(ctx context.Context, handle func(ctx context.Context, msgs []Message) error) error {
+	for {
+		batch, err := c.Fetch(ctx)
+		if err != nil {
+			return err
+		}
+		if len(batch.Data) == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.PollInterval):
+			}
+			continue
+		}
+
+		if err := handle(ctx, batch.Data); err != nil {
+			return err
+		}
+		if err := c.Commit(ctx, batch.NextOffset); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	endpoint := fmt.Sprintf("%s/tenants/%s/consumer-groups/%s%s",
+		c.baseURL, url.PathEscape(c.tenantID), url.PathEscape(c.group), path)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("consumer group %s: %s %s: %d %s", c.group, method, path, resp.StatusCode, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,173 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("strip_fields", stripFields{})
+	Register("ingest_metadata", ingestMetadata{})
+	Register("flatten", flatten{})
+}
+
+// decodeObject decodes a JSON object payload keeping numbers as written. ok
+// is false for any other payload, which the built-in stages pass through.
+func decodeObject(body []byte) (object map[string]any, ok bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&object) != nil || object == nil {
+		return nil, false
+	}
+	return object, true
+}
+
+// decodeConfig decodes the stage settings into v; no settings leave v as is
+func decodeConfig(config json.RawMessage, v any) error {
+	if len(config) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// stripFields removes fields, e.g. PII, from the payload. Settings:
+// {"fields": ["customer.email", "ssn"]}, nested fields as dot paths.
+type stripFields struct{}
+
+type stripFieldsConfig struct {
+	Fields []string `json:"fields"`
+}
+
+func (stripFields) ValidateConfig(config json.RawMessage) error {
+	var c stripFieldsConfig
+	if err := decodeConfig(config, &c); err != nil {
+		return err
+	}
+	if len(c.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+	for _, field := range c.Fields {
+		if field == "" || strings.Contains(field, "..") {
+			return fmt.Errorf("invalid field path %q", field)
+		}
+	}
+	return nil
+}
+
+func (stripFields) Process(_ context.Context, msg Message) (Result, error) {
+	var c stripFieldsConfig
+	if err := decodeConfig(msg.Config, &c); err != nil {
+		return Result{}, err
+	}
+	object, ok := decodeObject(msg.Body)
+	if !ok {
+		return Result{}, nil
+	}
+	for _, field := range c.Fields {
+		segments := strings.Split(field, ".")
+		parent := object
+		for _, segment := range segments[:len(segments)-1] {
+			if parent, ok = parent[segment].(map[string]any); !ok {
+				break
+			}
+		}
+		if parent != nil {
+			delete(parent, segments[len(segments)-1])
+		}
+	}
+	body, err := json.Marshal(object)
+	return Result{Body: body}, err
+}
+
+// ingestMetadata adds the tenant ID, message ID, type and processing time of
+// the message to the payload. Settings: {"field": "_ingest"}, the default.
+type ingestMetadata struct{}
+
+type ingestMetadataConfig struct {
+	Field string `json:"field"`
+}
+
+func (ingestMetadata) ValidateConfig(config json.RawMessage) error {
+	c := ingestMetadataConfig{Field: "_ingest"}
+	if err := decodeConfig(config, &c); err != nil {
+		return err
+	}
+	if c.Field == "" {
+		return errors.New("field must not be empty")
+	}
+	return nil
+}
+
+func (ingestMetadata) Process(_ context.Context, msg Message) (Result, error) {
+	c := ingestMetadataConfig{Field: "_ingest"}
+	if err := decodeConfig(msg.Config, &c); err != nil {
+		return Result{}, err
+	}
+	object, ok := decodeObject(msg.Body)
+	if !ok {
+		return Result{}, nil
+	}
+	object[c.Field] = map[string]any{
+		"tenant_id":    msg.TenantID,
+		"message_id":   msg.MessageID,
+		"type":         msg.Type,
+		"processed_at": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	body, err := json.Marshal(object)
+	return Result{Body: body}, err
+}
+
+// flatten turns nested objects into top-level fields joined by a separator,
+// e.g. {"a": {"b": 1}} into {"a.b": 1}; arrays are kept as they are.
+// Settings: {"separator": "."}, the default.
+type flatten struct{}
+
+type flattenConfig struct {
+	Separator string `json:"separator"`
+}
+
+func (flatten) ValidateConfig(config json.RawMessage) error {
+	c := flattenConfig{Separator: "."}
+	if err := decodeConfig(config, &c); err != nil {
+		return err
+	}
+	if c.Separator == "" {
+		return errors.New("separator must not be empty")
+	}
+	return nil
+}
+
+func (flatten) Process(_ context.Context, msg Message) (Result, error) {
+	c := flattenConfig{Separator: "."}
+	if err := decodeConfig(msg.Config, &c); err != nil {
+		return Result{}, err
+	}
+	object, ok := decodeObject(msg.Body)
+	if !ok {
+		return Result{}, nil
+	}
+	flat := make(map[string]any, len(object))
+	flattenInto(flat, "", c.Separator, object)
+	body, err := json.Marshal(flat)
+	return Result{Body: body}, err
+}
+
+func flattenInto(flat map[string]any, prefix, separator string, object map[string]any) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + separator + key
+		}
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenInto(flat, key, separator, nested)
+			continue
+		}
+		flat[key] = value
+	}
+}
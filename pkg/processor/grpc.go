@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// processMethod is the full gRPC method name sidecars serve. Messages are
+// JSON encoded (content-subtype "json"), so sidecars in other languages only
+// need a JSON codec, not generated stubs.
+const processMethod = "/salva.processor.v1.Processor/Process"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+// GRPCProcessor calls a processor running as a sidecar
+type GRPCProcessor struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// NewGRPCProcessor connects lazily to a sidecar at address, usually on
+// localhost. timeout bounds each call; zero means no limit beyond the caller's.
+func NewGRPCProcessor(address string, timeout time.Duration) (*GRPCProcessor, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCProcessor{conn: conn, timeout: timeout}, nil
+}
+
+func (p *GRPCProcessor) Process(ctx context.Context, msg Message) (Result, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	var result Result
+	err := p.conn.Invoke(ctx, processMethod, &msg, &result, grpc.ForceCodec(jsonCodec{}))
+	if status.Code(err) == codes.FailedPrecondition {
+		return Result{}, fmt.Errorf("%w: %s", ErrReject, status.Convert(err).Message())
+	}
+	return result, err
+}
+
+// Close closes the connection to the sidecar
+func (p *GRPCProcessor) Close() error {
+	return p.conn.Close()
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "salva.processor.v1.Processor",
+	HandlerType: (*Processor)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Process",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			var msg Message
+			if err := dec(&msg); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				result, err := srv.(Processor).Process(ctx, *req.(*Message))
+				if errors.Is(err, ErrReject) {
+					// Klien menambahkan ErrReject lagi, kirim alasannya saja
+					reason := strings.TrimPrefix(err.Error(), ErrReject.Error()+": ")
+					return nil, status.Error(codes.FailedPrecondition, reason)
+				}
+				return &result, err
+			}
+			if interceptor == nil {
+				return handler(ctx, &msg)
+			}
+			return interceptor(ctx, &msg, &grpc.UnaryServerInfo{Server: srv, FullMethod: processMethod}, handler)
+		},
+	}},
+}
+
+// Serve runs p as a sidecar on lis until the listener fails. Returning an
+// error wrapping ErrReject sends the message to the tenant's DLQ.
+func Serve(lis net.Listener, p Processor) error {
+	server := grpc.NewServer(grpc.ForceServerCodec(jsonCodec{}))
+	server.RegisterService(&serviceDesc, p)
+	return server.Serve(lis)
+}
@@ -0,0 +1,131 @@
+// Package processor lets custom business logic run in the consume path,
+// selected per tenant, without forking the service.
+//
+// A processor sees each message after claim-check resolution and schema
+// validation and before redaction and storage. It may rewrite the payload,
+// drop the message, or reject it to the tenant's DLQ. Processors are either
+// registered in-process under a name:
+//
+//	func init() {
+//		processor.Register("enrich", processor.Func(enrich))
+//	}
+//
+// or run as a gRPC sidecar (see Serve) and listed in the processors.sidecars
+// config, which needs no rebuild of the service.
+//
+// A tenant's chain is a list of Stages. A stage may carry settings for its
+// processor, passed in Message.Config; processors that take settings
+// implement Configurable so bad ones are rejected when the chain is set. The
+// built-in stages strip_fields, ingest_metadata and flatten are registered
+// by this package.
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrReject marks a message that must not be retried; it goes to the tenant's
+// DLQ. Wrap it to give a reason: fmt.Errorf("%w: missing customer", ErrReject).
+// Any other error requeues the message.
+var ErrReject = errors.New("message rejected by processor")
+
+// Message is what a processor receives
+type Message struct {
+	TenantID  string `json:"tenant_id"`
+	MessageID string `json:"message_id"`
+	// Type is the AMQP type property
+	Type string `json:"type"`
+	Body []byte `json:"body"`
+	// Config holds the settings of this stage in the tenant's chain, nil if none
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Result is what a processor returns. A nil Body keeps the payload unchanged;
+// Drop acks the message without storing it.
+type Result struct {
+	Body []byte `json:"body,omitempty"`
+	Drop bool   `json:"drop,omitempty"`
+}
+
+// Processor transforms or filters one message
+type Processor interface {
+	Process(ctx context.Context, msg Message) (Result, error)
+}
+
+// Configurable is implemented by processors that take settings per tenant.
+// ValidateConfig is called when a tenant selects the processor.
+type Configurable interface {
+	ValidateConfig(config json.RawMessage) error
+}
+
+// Stage is a processor in a tenant's chain with its settings. In JSON a stage
+// without settings is just the processor's name.
+type Stage struct {
+	Name   string          `json:"name"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+func (s Stage) MarshalJSON() ([]byte, error) {
+	if s.Config == nil {
+		return json.Marshal(s.Name)
+	}
+	type stage Stage
+	return json.Marshal(stage(s))
+}
+
+func (s *Stage) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Name); err == nil {
+		s.Config = nil
+		return nil
+	}
+	type stage Stage
+	return json.Unmarshal(data, (*stage)(s))
+}
+
+// Func adapts a function to a Processor
+type Func func(ctx context.Context, msg Message) (Result, error)
+
+func (f Func) Process(ctx context.Context, msg Message) (Result, error) {
+	return f(ctx, msg)
+}
+
+var (
+	mu         sync.RWMutex
+	processors = make(map[string]Processor)
+)
+
+// Register makes a processor available to tenants under name. It panics if
+// the name is already taken, like database/sql.Register.
+func Register(name string, p Processor) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := processors[name]; exists {
+		panic(fmt.Sprintf("processor: %q registered twice", name))
+	}
+	processors[name] = p
+}
+
+// Get returns the processor registered under name
+func Get(name string) (Processor, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := processors[name]
+	return p, ok
+}
+
+// Names returns the registered processor names, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(processors))
+	for name := range processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
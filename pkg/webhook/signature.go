@@ -0,0 +1,102 @@
+// Package webhook signs webhook deliveries and lets receivers verify them.
+//
+// Every delivery carries two headers:
+//
+//	X-Salva-Timestamp: unix seconds when the delivery was signed
+//	X-Salva-Signature: v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// Receivers should call Verify with the endpoint secret; deliveries whose
+// timestamp is outside the tolerance window are rejected to prevent replays.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimestampHeader = "X-Salva-Timestamp"
+	SignatureHeader = "X-Salva-Signature"
+
+	// DefaultTolerance is the maximum accepted age of a delivery
+	DefaultTolerance = 5 * time.Minute
+
+	signatureVersion = "v1"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing webhook signature headers")
+	ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+	ErrTimestampExpired = errors.New("webhook timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Sign computes the signature header value for body signed at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+// SetHeaders signs body and sets the timestamp and signature headers on h
+func SetHeaders(h http.Header, secret string, timestamp time.Time, body []byte) {
+	h.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	h.Set(SignatureHeader, Sign(secret, timestamp, body))
+}
+
+// Verify checks the signature headers of a delivery against body. A zero
+// tolerance uses DefaultTolerance.
+func Verify(secret string, h http.Header, body []byte, tolerance time.Duration) error {
+	return VerifyAt(secret, h, body, tolerance, time.Now())
+}
+
+// VerifyAt is Verify with an explicit current time
+func VerifyAt(secret string, h http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	tsHeader := h.Get(TimestampHeader)
+	sigHeader := h.Get(SignatureHeader)
+	if tsHeader == "" || sigHeader == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrTimestampExpired
+	}
+
+	expected := mac(secret, ts, body)
+	// Header dapat berisi beberapa signature saat rotasi secret
+	for _, part := range strings.Split(sigHeader, ",") {
+		version, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || version != signatureVersion {
+			continue
+		}
+		sig, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.", timestamp)
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signedHeaders(secret string, at time.Time, body []byte) http.Header {
+	h := http.Header{}
+	SetHeaders(h, secret, at, body)
+	return h
+}
+
+func TestVerifyAcceptsSignedDelivery(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"message_id":"42"}`)
+	h := signedHeaders("secret", now, body)
+
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), h.Get(TimestampHeader))
+	assert.Equal(t, Sign("secret", now, body), h.Get(SignatureHeader))
+	assert.NoError(t, VerifyAt("secret", h, body, time.Minute, now))
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"message_id":"42"}`)
+
+	assert.ErrorIs(t, VerifyAt("other", signedHeaders("secret", now, body), body, time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyAt("secret", signedHeaders("secret", now, body), []byte(`{"message_id":"43"}`), time.Minute, now), ErrInvalidSignature)
+
+	// A fresh timestamp does not make an old signature valid
+	h := signedHeaders("secret", now.Add(-time.Hour), body)
+	h.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	assert.ErrorIs(t, VerifyAt("secret", h, body, time.Minute, now), ErrInvalidSignature)
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte("{}")
+
+	tests := []struct {
+		name      string
+		signedAt  time.Time
+		tolerance time.Duration
+		want      error
+	}{
+		{"within tolerance", now.Add(-59 * time.Second), time.Minute, nil},
+		{"at the edge", now.Add(-time.Minute), time.Minute, nil},
+		{"replayed after tolerance", now.Add(-61 * time.Second), time.Minute, ErrTimestampExpired},
+		{"from the future", now.Add(2 * time.Minute), time.Minute, ErrTimestampExpired},
+		{"default tolerance", now.Add(-DefaultTolerance + time.Second), 0, nil},
+		{"past default tolerance", now.Add(-DefaultTolerance - time.Second), 0, ErrTimestampExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyAt("secret", signedHeaders("secret", tt.signedAt, body), body, tt.tolerance, now)
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyHeaders(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte("{}")
+
+	assert.ErrorIs(t, VerifyAt("secret", http.Header{}, body, 0, now), ErrMissingSignature)
+
+	h := signedHeaders("secret", now, body)
+	h.Set(TimestampHeader, "yesterday")
+	assert.ErrorIs(t, VerifyAt("secret", h, body, 0, now), ErrInvalidTimestamp)
+
+	// During a secret rotation the header carries both signatures
+	h = signedHeaders("new", now, body)
+	h.Set(SignatureHeader, "v0=abc, v1=zz, "+Sign("old", now, body)+", "+h.Get(SignatureHeader))
+	assert.NoError(t, VerifyAt("new", h, body, 0, now))
+	assert.NoError(t, VerifyAt("old", h, body, 0, now))
+}